@@ -1,6 +1,6 @@
 /*
 Golang并发编程学习Demo - 困难级别
-文件：01_distributed_worker.go
+文件：cmd/hard/01_distributed_worker/main.go
 主题：分布式工作者系统
 
 本示例演示：
@@ -9,12 +9,14 @@ Golang并发编程学习Demo - 困难级别
 3. 工作者节点的动态管理
 4. 任务路由和负载均衡
 5. 节点故障处理和恢复
+6. 管理器多副本通过领导者选举实现主备切换
 
 核心技术：
-- 一致性哈希：解决分布式系统中的数据分布问题
+- 一致性哈希：解决分布式系统中的数据分布问题（pkg/hashring）
 - 虚拟节点：提高哈希环的平衡性
 - 任务路由：根据任务ID路由到对应工作者
 - 并发处理：多个工作者并发处理任务
+- 领导者选举：同一时刻只有一个管理器副本分发任务（pkg/election）
 
 应用场景：
 - 分布式计算系统
@@ -28,17 +30,21 @@ Golang并发编程学习Demo - 困难级别
 - 学习节点管理和故障处理
 - 了解虚拟节点的作用
 
-运行方式：go run hard/01_distributed_worker.go
+运行方式：go run ./cmd/hard/01_distributed_worker [-replicas=3] [-tasks=20]
 */
 
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"hash/crc32"
-	"sort"
 	"sync"
 	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/election"
+	"github.com/klsakura/day1/pkg/hashring"
 )
 
 // Task 表示一个需要处理的任务
@@ -135,135 +141,18 @@ func (w *DistributedWorker) SetHealthy(healthy bool) {
 	fmt.Printf("工作者 %s 状态变更为: %s\n", w.id, status)
 }
 
-// ConsistentHash 一致性哈希环实现
-type ConsistentHash struct {
-	replicas   int               // 虚拟节点数量
-	ring       map[uint32]string // 哈希环：哈希值 -> 节点ID
-	sortedKeys []uint32          // 排序的哈希值列表
-	workers    map[string]Worker // 工作者映射：节点ID -> Worker
-	mu         sync.RWMutex      // 保护并发访问
-}
-
-// NewConsistentHash 创建一致性哈希环
-func NewConsistentHash(replicas int) *ConsistentHash {
-	return &ConsistentHash{
-		replicas: replicas,
-		ring:     make(map[uint32]string),
-		workers:  make(map[string]Worker),
-	}
-}
-
-// hashFunction 哈希函数：将字符串映射为uint32
-func (ch *ConsistentHash) hashFunction(data string) uint32 {
-	return crc32.ChecksumIEEE([]byte(data))
-}
-
-// AddWorker 添加工作者到哈希环
-func (ch *ConsistentHash) AddWorker(worker Worker) {
-	ch.mu.Lock()
-	defer ch.mu.Unlock()
-
-	workerID := worker.GetID()
-	ch.workers[workerID] = worker
-
-	// 为每个工作者创建多个虚拟节点
-	for i := 0; i < ch.replicas; i++ {
-		// 创建虚拟节点ID
-		virtualNode := fmt.Sprintf("%s#%d", workerID, i)
-		hash := ch.hashFunction(virtualNode)
-
-		// 添加到哈希环
-		ch.ring[hash] = workerID
-		ch.sortedKeys = append(ch.sortedKeys, hash)
-	}
-
-	// 对哈希值进行排序，维护环的有序性
-	sort.Slice(ch.sortedKeys, func(i, j int) bool {
-		return ch.sortedKeys[i] < ch.sortedKeys[j]
-	})
-
-	fmt.Printf("工作者 %s 已添加到哈希环 (虚拟节点数: %d)\n", workerID, ch.replicas)
-}
-
-// RemoveWorker 从哈希环移除工作者
-func (ch *ConsistentHash) RemoveWorker(workerID string) {
-	ch.mu.Lock()
-	defer ch.mu.Unlock()
-
-	// 移除所有虚拟节点
-	for i := 0; i < ch.replicas; i++ {
-		virtualNode := fmt.Sprintf("%s#%d", workerID, i)
-		hash := ch.hashFunction(virtualNode)
-		delete(ch.ring, hash)
-
-		// 从排序列表中移除
-		for j, key := range ch.sortedKeys {
-			if key == hash {
-				ch.sortedKeys = append(ch.sortedKeys[:j], ch.sortedKeys[j+1:]...)
-				break
-			}
-		}
-	}
-
-	// 移除工作者
-	delete(ch.workers, workerID)
-	fmt.Printf("工作者 %s 已从哈希环移除\n", workerID)
-}
-
-// GetWorker 根据任务ID获取对应的工作者
-func (ch *ConsistentHash) GetWorker(taskID string) (Worker, error) {
-	ch.mu.RLock()
-	defer ch.mu.RUnlock()
-
-	if len(ch.ring) == 0 {
-		return nil, fmt.Errorf("no workers available")
-	}
-
-	// 计算任务的哈希值
-	hash := ch.hashFunction(taskID)
-
-	// 在哈希环上找到第一个大于等于该哈希值的节点
-	// 如果找不到，则选择第一个节点（环形特性）
-	idx := sort.Search(len(ch.sortedKeys), func(i int) bool {
-		return ch.sortedKeys[i] >= hash
-	})
-
-	// 如果超出范围，回到环的开始
-	if idx == len(ch.sortedKeys) {
-		idx = 0
-	}
-
-	// 获取对应的工作者ID和工作者实例
-	workerID := ch.ring[ch.sortedKeys[idx]]
-	worker := ch.workers[workerID]
-
-	return worker, nil
-}
-
-// GetAllWorkers 获取所有工作者
-func (ch *ConsistentHash) GetAllWorkers() []Worker {
-	ch.mu.RLock()
-	defer ch.mu.RUnlock()
-
-	workers := make([]Worker, 0, len(ch.workers))
-	for _, worker := range ch.workers {
-		workers = append(workers, worker)
-	}
-	return workers
-}
-
 // WorkerManager 工作者管理器
 type WorkerManager struct {
-	hash     *ConsistentHash // 一致性哈希环
-	taskChan chan Task       // 任务队列
-	wg       sync.WaitGroup  // 等待组
-	stopChan chan bool       // 停止信号
+	hash     *hashring.Ring[Worker] // 一致性哈希环
+	taskChan chan Task              // 任务队列
+	wg       sync.WaitGroup         // 等待组
+	stopChan chan bool              // 停止信号
 }
 
 // NewWorkerManager 创建工作者管理器
 func NewWorkerManager(replicas int) *WorkerManager {
 	return &WorkerManager{
-		hash:     NewConsistentHash(replicas),
+		hash:     hashring.New[Worker](replicas),
 		taskChan: make(chan Task, 100), // 缓冲队列
 		stopChan: make(chan bool),
 	}
@@ -271,12 +160,14 @@ func NewWorkerManager(replicas int) *WorkerManager {
 
 // AddWorker 添加工作者
 func (wm *WorkerManager) AddWorker(worker Worker) {
-	wm.hash.AddWorker(worker)
+	wm.hash.Add(worker.GetID(), worker)
+	fmt.Printf("工作者 %s 已添加到哈希环 (虚拟节点数: %d)\n", worker.GetID(), wm.hash.Replicas())
 }
 
 // RemoveWorker 移除工作者
 func (wm *WorkerManager) RemoveWorker(workerID string) {
-	wm.hash.RemoveWorker(workerID)
+	wm.hash.Remove(workerID)
+	fmt.Printf("工作者 %s 已从哈希环移除\n", workerID)
 }
 
 // SubmitTask 提交任务
@@ -312,7 +203,7 @@ func (wm *WorkerManager) taskProcessor() {
 		select {
 		case task := <-wm.taskChan:
 			// 根据任务ID路由到对应工作者
-			worker, err := wm.hash.GetWorker(task.ID)
+			worker, err := wm.hash.Get(task.ID)
 			if err != nil {
 				fmt.Printf("获取工作者失败: %v\n", err)
 				continue
@@ -340,7 +231,7 @@ func (wm *WorkerManager) taskProcessor() {
 
 // GetStats 获取统计信息
 func (wm *WorkerManager) GetStats() map[string]interface{} {
-	workers := wm.hash.GetAllWorkers()
+	workers := wm.hash.Nodes()
 	stats := make(map[string]interface{})
 
 	totalProcessed := int64(0)
@@ -369,12 +260,78 @@ func (wm *WorkerManager) GetStats() map[string]interface{} {
 	return stats
 }
 
+// runReplicatedManagers 运行多个管理器副本，通过领导者选举保证同一时刻只有主管理器分发任务
+// 主管理器与协调服务失联后，其余副本在租约过期后接管
+func runReplicatedManagers(workers []*DistributedWorker) {
+	store := election.NewStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	var electors []*election.Elector
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("manager-%d", i)
+		e := election.New(election.Config{
+			ID:    id,
+			Store: store,
+			TTL:   300 * time.Millisecond,
+			OnElected: func(ctx context.Context, term uint64) {
+				fmt.Printf("%s 成为主管理器 (term %d)，开始分发任务\n", id, term)
+				manager := NewWorkerManager(3)
+				for _, w := range workers {
+					manager.AddWorker(w)
+				}
+				manager.Start()
+				defer manager.Stop()
+
+				for n := 1; ; n++ {
+					select {
+					case <-ctx.Done():
+						fmt.Printf("%s 失去主管理器身份，停止分发\n", id)
+						return
+					case <-time.After(100 * time.Millisecond):
+						manager.SubmitTask(Task{
+							ID:      fmt.Sprintf("ha-t%d-%02d", term, n),
+							Payload: id,
+							Created: time.Now(),
+						})
+					}
+				}
+			},
+		})
+		electors = append(electors, e)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.Run(ctx)
+		}()
+	}
+
+	time.Sleep(time.Second)
+	for i, e := range electors {
+		if e.IsLeader() {
+			fmt.Printf("\n--- manager-%d 与协调服务失联 ---\n", i+1)
+			store.Partition(fmt.Sprintf("manager-%d", i+1), true)
+			break
+		}
+	}
+	time.Sleep(time.Second)
+
+	cancel()
+	wg.Wait()
+	time.Sleep(500 * time.Millisecond) // 等待已分发的任务处理完
+}
+
 func main() {
+	replicas := flag.Int("replicas", 3, "每个工作者在哈希环上的虚拟节点数量")
+	numTasks := flag.Int("tasks", 20, "第一批提交的任务数，故障后和恢复后各再提交10个")
+	demoflag.Parse()
+
 	fmt.Println("=== 分布式工作者系统演示 ===")
 	fmt.Println("演示一致性哈希在分布式任务调度中的应用")
 
-	// 创建工作者管理器（每个工作者3个虚拟节点）
-	manager := NewWorkerManager(3)
+	// 创建工作者管理器（默认每个工作者3个虚拟节点）
+	manager := NewWorkerManager(*replicas)
 
 	// 创建多个工作者，模拟不同的处理能力
 	workers := []*DistributedWorker{
@@ -396,7 +353,7 @@ func main() {
 
 	// 提交一批任务
 	fmt.Println("\n--- 提交任务 ---")
-	for i := 1; i <= 20; i++ {
+	for i := 1; i <= *numTasks; i++ {
 		task := Task{
 			ID:      fmt.Sprintf("task-%02d", i),
 			Payload: fmt.Sprintf("任务数据 %d", i),
@@ -420,7 +377,7 @@ func main() {
 
 	// 继续提交任务
 	fmt.Println("\n--- 故障后继续提交任务 ---")
-	for i := 21; i <= 30; i++ {
+	for i := *numTasks + 1; i <= *numTasks+10; i++ {
 		task := Task{
 			ID:      fmt.Sprintf("task-%02d", i),
 			Payload: fmt.Sprintf("任务数据 %d", i),
@@ -445,7 +402,7 @@ func main() {
 
 	// 最后一批任务
 	fmt.Println("\n--- 最后一批任务 ---")
-	for i := 31; i <= 40; i++ {
+	for i := *numTasks + 11; i <= *numTasks+20; i++ {
 		task := Task{
 			ID:      fmt.Sprintf("task-%02d", i),
 			Payload: fmt.Sprintf("任务数据 %d", i),
@@ -459,6 +416,10 @@ func main() {
 	// 等待所有任务完成
 	time.Sleep(3 * time.Second)
 
+	// 管理器高可用：多个副本选主
+	fmt.Println("\n--- 管理器主备切换 ---")
+	runReplicatedManagers(workers)
+
 	// 打印最终统计
 	fmt.Println("\n=== 最终统计 ===")
 	stats := manager.GetStats()
@@ -475,4 +436,5 @@ func main() {
 	fmt.Println("2. 虚拟节点提高负载均衡效果")
 	fmt.Println("3. 工作者故障时的处理策略")
 	fmt.Println("4. 动态添加/移除工作者的影响")
+	fmt.Println("5. 主管理器失联后，新的主管理器在租约过期后接管，任务ID中的任期可以区分是谁分发的")
 }
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/registry"
 )
 
 // 负载均衡器演示
@@ -201,51 +205,38 @@ func (wrr *WeightedRoundRobinStrategy) GetName() string {
 
 // 负载均衡器
 type LoadBalancer struct {
-	servers  []*Server
+	// 服务器列表是写时复制的：每个请求只做一次原子加载拿到快照，增删服务器时复制并替换整个列表
+	servers  *registry.Registry[int, *Server]
 	strategy LoadBalanceStrategy
 	stats    struct {
 		totalRequests  int64
 		failedRequests int64
 	}
-	mu sync.RWMutex
 }
 
 func NewLoadBalancer(strategy LoadBalanceStrategy) *LoadBalancer {
 	return &LoadBalancer{
-		servers:  make([]*Server, 0),
+		servers:  registry.New[int, *Server](),
 		strategy: strategy,
 	}
 }
 
 func (lb *LoadBalancer) AddServer(server *Server) {
-	lb.mu.Lock()
-	defer lb.mu.Unlock()
-	lb.servers = append(lb.servers, server)
+	lb.servers.Put(server.ID, server)
 	fmt.Printf("添加服务器: %d (%s) 权重=%d\n", server.ID, server.Address, server.Weight)
 }
 
 func (lb *LoadBalancer) RemoveServer(serverID int) {
-	lb.mu.Lock()
-	defer lb.mu.Unlock()
-
-	for i, server := range lb.servers {
-		if server.ID == serverID {
-			lb.servers = append(lb.servers[:i], lb.servers[i+1:]...)
-			fmt.Printf("移除服务器: %d\n", serverID)
-			break
-		}
+	if lb.servers.Delete(serverID) {
+		fmt.Printf("移除服务器: %d\n", serverID)
 	}
 }
 
 func (lb *LoadBalancer) ProcessRequest(requestID string) error {
 	atomic.AddInt64(&lb.stats.totalRequests, 1)
 
-	lb.mu.RLock()
-	servers := make([]*Server, len(lb.servers))
-	copy(servers, lb.servers)
-	lb.mu.RUnlock()
-
-	server := lb.strategy.Select(servers)
+	// 快照中的切片是共享的，策略只能读取不能修改
+	server := lb.strategy.Select(lb.servers.Load().Values())
 	if server == nil {
 		atomic.AddInt64(&lb.stats.failedRequests, 1)
 		return fmt.Errorf("no healthy server available")
@@ -261,15 +252,12 @@ func (lb *LoadBalancer) ProcessRequest(requestID string) error {
 }
 
 func (lb *LoadBalancer) PrintStats() {
-	lb.mu.RLock()
-	defer lb.mu.RUnlock()
-
 	fmt.Printf("\n=== 负载均衡器统计 (策略: %s) ===\n", lb.strategy.GetName())
 	fmt.Printf("总请求数: %d\n", atomic.LoadInt64(&lb.stats.totalRequests))
 	fmt.Printf("失败请求数: %d\n", atomic.LoadInt64(&lb.stats.failedRequests))
 
 	fmt.Println("\n服务器统计:")
-	for _, server := range lb.servers {
+	for _, server := range lb.servers.Load().Values() {
 		active, total, failed := server.GetStats()
 		health := "健康"
 		if !server.IsHealthy() {
@@ -287,12 +275,7 @@ func (lb *LoadBalancer) StartHealthCheck() {
 		defer ticker.Stop()
 
 		for range ticker.C {
-			lb.mu.RLock()
-			servers := make([]*Server, len(lb.servers))
-			copy(servers, lb.servers)
-			lb.mu.RUnlock()
-
-			for _, server := range servers {
+			for _, server := range lb.servers.Load().Values() {
 				// 模拟健康检查：10%概率变为不健康，20%概率恢复
 				if server.IsHealthy() {
 					if rand.Float32() < 0.1 {
@@ -309,6 +292,10 @@ func (lb *LoadBalancer) StartHealthCheck() {
 }
 
 func main() {
+	numRequests := flag.Int("requests", 20, "每种策略下发送的请求数")
+	interval := flag.Duration("interval", 50*time.Millisecond, "相邻请求的发送间隔")
+	demoflag.Parse()
+
 	fmt.Println("=== 负载均衡器演示 ===")
 
 	rand.Seed(time.Now().UnixNano())
@@ -336,9 +323,8 @@ func main() {
 
 		// 模拟并发请求
 		var wg sync.WaitGroup
-		numRequests := 20
 
-		for i := 1; i <= numRequests; i++ {
+		for i := 1; i <= *numRequests; i++ {
 			wg.Add(1)
 			go func(reqID int) {
 				defer wg.Done()
@@ -349,7 +335,7 @@ func main() {
 			}(i)
 
 			// 错开请求时间
-			time.Sleep(50 * time.Millisecond)
+			time.Sleep(*interval)
 		}
 
 		wg.Wait()
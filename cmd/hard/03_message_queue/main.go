@@ -0,0 +1,418 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/03_message_queue/main.go
+主题：消息队列系统实现
+
+本示例演示：
+1. 完整的消息队列系统架构（队列核心在pkg/msgqueue）
+2. 发布-订阅模式的实现
+3. 消息重试机制和死信队列
+4. 并发消费者管理
+5. 消息持久化和可靠性保证
+
+核心功能：
+- 主题订阅：支持多个消费者订阅同一主题，支持 "orders.*"、"notifications.#" 等通配符
+- 消息重试：投递失败时按指数退避加抖动重试（pkg/retry），只重投给失败的那个消费者
+- 死信队列：超过重试次数或不可重试的消息进入死信队列
+- 并发处理：多个消费者并发处理消息
+- 消息统计：提供详细的消息处理统计
+- 桥接适配：把轻量级发布订阅（medium/04）的消息转入可靠队列，或反向推送
+
+应用场景：
+- 微服务架构中的异步通信
+- 事件驱动架构
+- 任务队列系统
+- 日志收集和处理
+
+技术要点：
+- 生产者-消费者模式
+- 消息路由和分发
+- 错误处理和重试策略
+- 并发安全的队列操作
+
+运行方式：go run ./cmd/hard/03_message_queue [-consumers=3] [-retries=3] [-orders=10] [-notifications=8]
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/contextkeys"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/msgqueue"
+	"github.com/klsakura/day1/pkg/pubsub"
+	"github.com/klsakura/day1/pkg/retry"
+)
+
+// newQueue 创建内存消息队列，把订阅、重试和死信等事件打印出来
+func newQueue(maxRetries int) *msgqueue.InMemory {
+	mq := msgqueue.NewInMemory(maxRetries)
+	mq.SetLogf(func(format string, args ...any) {
+		fmt.Printf(format, args...)
+	})
+	return mq
+}
+
+// SimpleConsumer 简单消费者实现
+type SimpleConsumer struct {
+	ID           string        // 消费者唯一标识
+	ProcessTime  time.Duration // 模拟处理时间
+	SuccessRate  float64       // 成功处理概率（0.0-1.0）
+	MessageCount int64         // 已处理消息计数
+}
+
+// NewSimpleConsumer 创建简单消费者
+func NewSimpleConsumer(id string, processTime time.Duration, successRate float64) *SimpleConsumer {
+	return &SimpleConsumer{
+		ID:          id,
+		ProcessTime: processTime,
+		SuccessRate: successRate,
+	}
+}
+
+// GetID 实现Consumer接口 - 获取消费者ID
+func (c *SimpleConsumer) GetID() string {
+	return c.ID
+}
+
+// Consume 实现Consumer接口 - 处理消息
+func (c *SimpleConsumer) Consume(message msgqueue.Message) error {
+	// 原子增加消息计数
+	atomic.AddInt64(&c.MessageCount, 1)
+
+	// 模拟消息处理时间
+	time.Sleep(c.ProcessTime)
+
+	// 根据成功率随机决定处理结果
+	prefix := contextkeys.LogPrefix(message.Context())
+	if rand.Float64() < c.SuccessRate {
+		fmt.Printf("%s消费者 %s 成功处理消息: %s (主题: %s)\n",
+			prefix, c.ID, message.ID, message.Topic)
+		return nil
+	} else {
+		fmt.Printf("%s消费者 %s 处理消息失败: %s (主题: %s)\n",
+			prefix, c.ID, message.ID, message.Topic)
+		return fmt.Errorf("processing failed")
+	}
+}
+
+// GetMessageCount 获取已处理消息数量
+func (c *SimpleConsumer) GetMessageCount() int64 {
+	return atomic.LoadInt64(&c.MessageCount)
+}
+
+// MessageProducer 消息生产者
+type MessageProducer struct {
+	queue msgqueue.Queue // 消息队列引用
+	id    string         // 生产者ID
+}
+
+// NewMessageProducer 创建消息生产者
+func NewMessageProducer(id string, queue msgqueue.Queue) *MessageProducer {
+	return &MessageProducer{
+		id:    id,
+		queue: queue,
+	}
+}
+
+// SendMessage 发送消息到指定主题
+func (p *MessageProducer) SendMessage(topic string, payload interface{}, priority int) error {
+	return p.SendMessageCtx(context.Background(), topic, payload, priority)
+}
+
+// SendMessageCtx 发送消息，并把ctx中的请求ID写入消息，消费、重试和死信日志都会带上它
+func (p *MessageProducer) SendMessageCtx(ctx context.Context, topic string, payload interface{}, priority int) error {
+	requestID, _ := contextkeys.RequestIDFrom(ctx)
+
+	// 构造消息
+	message := msgqueue.Message{
+		ID:        fmt.Sprintf("%s-%d", p.id, rand.Intn(10000)), // 生成唯一ID
+		Topic:     topic,
+		Payload:   payload,
+		Timestamp: time.Now(),
+		Priority:  priority,
+		Retries:   0, // 初始重试次数为0
+		RequestID: requestID,
+	}
+
+	fmt.Printf("%s生产者 %s 发送消息: %s 到主题 %s\n", contextkeys.LogPrefix(ctx), p.id, message.ID, topic)
+	return p.queue.Publish(topic, message)
+}
+
+// PubSubToQueueBridge 把进程内发布订阅的消息转发到可靠消息队列
+// 发布订阅"发出即忘"，转入队列后即可获得重试和死信等可靠性保证
+type PubSubToQueueBridge[T any] struct {
+	publisher *pubsub.Publisher[T]
+	sub       *pubsub.Subscriber[T]
+	queue     msgqueue.Queue
+	forwarded int64 // 成功转发数
+	failed    int64 // 转发失败数（如队列中没有消费者）
+	done      chan struct{}
+}
+
+// NewPubSubToQueueBridge 创建桥接器：订阅publisher上匹配pattern的消息并转发到queue
+// 桥接订阅者使用Block策略，宁可让发布者等待也不丢消息
+func NewPubSubToQueueBridge[T any](publisher *pubsub.Publisher[T], bridgeID int, pattern string, queue msgqueue.Queue) *PubSubToQueueBridge[T] {
+	b := &PubSubToQueueBridge[T]{
+		publisher: publisher,
+		sub:       pubsub.NewSubscriber[T](bridgeID, 100, pubsub.Block),
+		queue:     queue,
+		done:      make(chan struct{}),
+	}
+	b.sub.Subscribe(pattern)
+	publisher.AddSubscriber(b.sub)
+
+	go b.forward()
+	return b
+}
+
+func (b *PubSubToQueueBridge[T]) forward() {
+	defer close(b.done)
+
+	for msg := range b.sub.Messages() {
+		n := atomic.AddInt64(&b.forwarded, 1)
+		queueMsg := msgqueue.Message{
+			ID:        fmt.Sprintf("bridge-%d-%d", b.sub.ID, n),
+			Topic:     msg.Topic,
+			Payload:   msg.Payload,
+			Timestamp: msg.Time,
+		}
+
+		fmt.Printf("桥接器转发: 发布订阅 [%s] -> 消息队列 %s\n", msg.Topic, queueMsg.ID)
+		if err := b.queue.Publish(msg.Topic, queueMsg); err != nil {
+			atomic.AddInt64(&b.forwarded, -1)
+			atomic.AddInt64(&b.failed, 1)
+			fmt.Printf("桥接器转发失败: %v\n", err)
+		}
+	}
+}
+
+// Stop 从发布者移除桥接订阅者，并等待已接收的消息转发完毕
+func (b *PubSubToQueueBridge[T]) Stop() (forwarded, failed int64) {
+	b.publisher.RemoveSubscriber(b.sub.ID)
+	<-b.done
+	return atomic.LoadInt64(&b.forwarded), atomic.LoadInt64(&b.failed)
+}
+
+// QueueToPubSubBridge 作为队列消费者，把队列消息推送回进程内发布订阅
+// 负载类型不匹配的消息重试也不会成功，返回不可重试的错误，直接进入死信队列
+type QueueToPubSubBridge[T any] struct {
+	id        string
+	publisher *pubsub.Publisher[T]
+}
+
+// NewQueueToPubSubBridge 创建反向桥接器
+func NewQueueToPubSubBridge[T any](id string, publisher *pubsub.Publisher[T]) *QueueToPubSubBridge[T] {
+	return &QueueToPubSubBridge[T]{id: id, publisher: publisher}
+}
+
+// GetID 实现Consumer接口
+func (b *QueueToPubSubBridge[T]) GetID() string {
+	return b.id
+}
+
+// Consume 实现Consumer接口 - 把消息重新发布到发布订阅
+func (b *QueueToPubSubBridge[T]) Consume(message msgqueue.Message) error {
+	payload, ok := message.Payload.(T)
+	if !ok {
+		return retry.Permanent(fmt.Errorf("bridge %s: unexpected payload type %T", b.id, message.Payload))
+	}
+
+	results := b.publisher.Publish(message.Topic, payload)
+	fmt.Printf("桥接器 %s 推送: 消息队列 %s -> 发布订阅 [%s] (%d 个订阅者)\n",
+		b.id, message.ID, message.Topic, len(results))
+	return nil
+}
+
+func main() {
+	numConsumers := flag.Int("consumers", 3, "消费者数量，按三种处理速度和订阅方式轮流创建")
+	maxRetries := flag.Int("retries", 3, "投递失败后的最大重试次数")
+	numOrders := flag.Int("orders", 10, "生产者1发送的订单消息数")
+	numNotifications := flag.Int("notifications", 8, "生产者2发送的通知消息数")
+	demoflag.Parse()
+
+	fmt.Println("=== 消息队列实现演示 ===")
+	fmt.Println("演示完整的消息队列系统：发布订阅、重试机制、死信队列")
+
+	rand.Seed(time.Now().UnixNano())
+
+	// 创建消息队列（默认最多重试3次）
+	mq := newQueue(*maxRetries)
+	defer mq.Close()
+
+	// 创建不同性能的消费者，consumer-i使用profiles[(i-1)%3]
+	profiles := []struct {
+		processTime time.Duration
+		successRate float64
+	}{
+		{100 * time.Millisecond, 0.8}, // 80%成功率，快速处理
+		{200 * time.Millisecond, 0.6}, // 60%成功率，中等处理
+		{150 * time.Millisecond, 0.9}, // 90%成功率，中等处理
+	}
+	consumers := make([]*SimpleConsumer, *numConsumers)
+	for i := range consumers {
+		p := profiles[i%len(profiles)]
+		consumers[i] = NewSimpleConsumer(fmt.Sprintf("consumer-%d", i+1), p.processTime, p.successRate)
+	}
+
+	// 建立订阅关系，同样按consumer1、consumer2、consumer3的方式轮流订阅
+	fmt.Println("\n--- 建立订阅关系 ---")
+	for i, c := range consumers {
+		switch i % 3 {
+		case 0:
+			mq.Subscribe("orders", c) // 订单主题
+		case 1:
+			mq.Subscribe("orders", c)        // 订单主题（多个消费者）
+			mq.Subscribe("notifications", c) // 通知主题
+		case 2:
+			mq.Subscribe("notifications.#", c) // 通知主题（通配符，含子主题）
+		}
+	}
+
+	// 创建消息生产者
+	producer1 := NewMessageProducer("producer-1", mq)
+	producer2 := NewMessageProducer("producer-2", mq)
+
+	// 并发发送消息
+	var wg sync.WaitGroup
+
+	// 生产者1：发送订单消息
+	fmt.Println("\n--- 发送订单消息 ---")
+	for i := 1; i <= *numOrders; i++ {
+		wg.Add(1)
+		go func(orderID int) {
+			defer wg.Done()
+
+			// 构造订单数据
+			payload := map[string]interface{}{
+				"orderID":   orderID,
+				"amount":    rand.Float64() * 1000,
+				"userID":    rand.Intn(1000),
+				"timestamp": time.Now().Unix(),
+			}
+
+			// 每个订单请求一个请求ID，可以据此在日志中追踪它的消费、重试和死信
+			ctx := contextkeys.WithRequestID(context.Background(), contextkeys.NewRequestID())
+			err := producer1.SendMessageCtx(ctx, "orders", payload, rand.Intn(5))
+			if err != nil {
+				fmt.Printf("发送订单消息失败: %v\n", err)
+			}
+
+			time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
+		}(i)
+	}
+
+	// 生产者2：发送通知消息
+	fmt.Println("\n--- 发送通知消息 ---")
+	for i := 1; i <= *numNotifications; i++ {
+		wg.Add(1)
+		go func(notificationID int) {
+			defer wg.Done()
+
+			// 构造通知数据
+			payload := map[string]interface{}{
+				"notificationID": notificationID,
+				"message":        fmt.Sprintf("通知消息 %d", notificationID),
+				"userID":         rand.Intn(1000),
+				"type":           "info",
+			}
+
+			err := producer2.SendMessage("notifications", payload, rand.Intn(3))
+			if err != nil {
+				fmt.Printf("发送通知消息失败: %v\n", err)
+			}
+
+			time.Sleep(time.Duration(rand.Intn(300)) * time.Millisecond)
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Publish只等待第一次投递，失败的投递还在后台重试，等它们结束后再统计
+	mq.Wait()
+
+	// 打印统计信息
+	fmt.Println("\n=== 消息队列统计 ===")
+	published, consumed, failed, retried, deadLetterCount := mq.GetStats()
+	fmt.Printf("发布消息数: %d\n", published)
+	fmt.Printf("成功消费数: %d\n", consumed)
+	fmt.Printf("失败消费数: %d\n", failed)
+	fmt.Printf("重试次数: %d\n", retried)
+	fmt.Printf("死信消息数: %d\n", deadLetterCount)
+
+	// 打印消费者统计
+	fmt.Println("\n=== 消费者统计 ===")
+	for i, c := range consumers {
+		fmt.Printf("消费者%d处理消息数: %d\n", i+1, c.GetMessageCount())
+	}
+
+	// 显示死信队列内容
+	deadLetters := mq.GetDeadLetters()
+	if len(deadLetters) > 0 {
+		fmt.Println("\n=== 死信队列 ===")
+		for _, msg := range deadLetters {
+			fmt.Printf("死信消息: %s (主题: %s, 重试次数: %d)\n",
+				msg.ID, msg.Topic, msg.Retries)
+		}
+	}
+
+	// 测试取消订阅功能
+	fmt.Println("\n=== 取消订阅测试 ===")
+	mq.Unsubscribe("orders", "consumer-1")
+
+	// 再发送一条消息验证取消订阅效果
+	fmt.Println("发送测试消息验证取消订阅...")
+	producer1.SendMessage("orders", map[string]interface{}{"test": "after unsubscribe"}, 1)
+
+	time.Sleep(1 * time.Second)
+
+	// 桥接：把进程内发布订阅升级为可靠队列
+	fmt.Println("\n=== 发布订阅与消息队列桥接 ===")
+	events := pubsub.NewPublisher[interface{}]()
+
+	// 方向1：发布订阅 -> 消息队列，orders主题的事件获得重试和死信保障
+	toQueue := NewPubSubToQueueBridge(events, 900, "orders.#", mq)
+
+	// 方向2：消息队列 -> 发布订阅，把通知推送给进程内的看板订阅者
+	dashboardEvents := pubsub.NewPublisher[map[string]interface{}]()
+	dashboard := pubsub.NewSubscriber[map[string]interface{}](901, 10, pubsub.DropOldest)
+	dashboard.Subscribe("notifications.#")
+	dashboardEvents.AddSubscriber(dashboard)
+	mq.Subscribe("notifications", NewQueueToPubSubBridge("bridge-out", dashboardEvents))
+
+	var dashboardWg sync.WaitGroup
+	dashboardWg.Add(1)
+	go func() {
+		defer dashboardWg.Done()
+		for msg := range dashboard.Messages() {
+			fmt.Printf("看板收到通知 [%s]: %v\n", msg.Topic, msg.Payload["message"])
+		}
+	}()
+
+	events.Publish("orders", map[string]interface{}{"orderID": 9001, "source": "pubsub"})
+	events.Publish("orders", map[string]interface{}{"orderID": 9002, "source": "pubsub"})
+	producer2.SendMessage("notifications", map[string]interface{}{"message": "桥接测试通知"}, 1)
+
+	forwarded, failedForward := toQueue.Stop()
+	fmt.Printf("桥接统计: 转发成功 %d, 转发失败 %d\n", forwarded, failedForward)
+
+	time.Sleep(1 * time.Second)
+	dashboardEvents.Close()
+	dashboardWg.Wait()
+
+	fmt.Println("\n消息队列演示完成！")
+	fmt.Println("观察要点：")
+	fmt.Println("1. 多个消费者可订阅同一主题")
+	fmt.Println("2. 失败的投递按指数退避加抖动重试，只重投给失败的消费者")
+	fmt.Println("3. 超过重试次数进入死信队列")
+	fmt.Println("4. 消费者可动态取消订阅")
+	fmt.Println("5. 系统提供详细的处理统计")
+	fmt.Println("6. 桥接器让发布订阅平滑升级到可靠队列")
+}
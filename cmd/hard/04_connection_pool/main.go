@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/connpool"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/retry"
+)
+
+// 连接池演示：连接池在pkg/connpool中，这里实现模拟的数据库连接和客户端
+
+// 模拟数据库连接
+type DBConnection struct {
+	ID          string
+	connected   bool
+	createdTime time.Time
+	lastUsed    time.Time
+	queries     int64
+	mu          sync.RWMutex
+}
+
+func NewDBConnection(id string) *DBConnection {
+	return &DBConnection{
+		ID:          id,
+		createdTime: time.Now(),
+		lastUsed:    time.Now(),
+	}
+}
+
+func (c *DBConnection) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connected {
+		return fmt.Errorf("connection %s already connected", c.ID)
+	}
+
+	// 模拟连接时间
+	time.Sleep(time.Duration(rand.Intn(100)+50) * time.Millisecond)
+
+	// 模拟连接失败（5%概率）
+	if rand.Float32() < 0.05 {
+		return fmt.Errorf("failed to connect %s", c.ID)
+	}
+
+	c.connected = true
+	fmt.Printf("连接 %s 已建立\n", c.ID)
+	return nil
+}
+
+func (c *DBConnection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.connected {
+		return fmt.Errorf("connection %s not connected", c.ID)
+	}
+
+	c.connected = false
+	fmt.Printf("连接 %s 已关闭\n", c.ID)
+	return nil
+}
+
+func (c *DBConnection) Execute(query string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.connected {
+		return nil, fmt.Errorf("connection %s not connected", c.ID)
+	}
+
+	// 模拟查询执行时间
+	time.Sleep(time.Duration(rand.Intn(200)+50) * time.Millisecond)
+
+	// 模拟查询失败（10%概率）
+	if rand.Float32() < 0.1 {
+		return nil, fmt.Errorf("query failed on connection %s", c.ID)
+	}
+
+	atomic.AddInt64(&c.queries, 1)
+	c.lastUsed = time.Now()
+
+	result := fmt.Sprintf("Result from %s: %s", c.ID, query)
+	return result, nil
+}
+
+func (c *DBConnection) IsAlive() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// 模拟连接检查失败（3%概率）
+	if rand.Float32() < 0.03 {
+		c.connected = false
+		return false
+	}
+
+	return c.connected
+}
+
+func (c *DBConnection) GetID() string {
+	return c.ID
+}
+
+func (c *DBConnection) GetCreatedTime() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.createdTime
+}
+
+func (c *DBConnection) GetLastUsed() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastUsed
+}
+
+func (c *DBConnection) SetLastUsed(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastUsed = t
+}
+
+func (c *DBConnection) GetQueries() int64 {
+	return atomic.LoadInt64(&c.queries)
+}
+
+// 数据库客户端
+type DBClient struct {
+	pool    *connpool.Pool
+	policy  retry.Policy
+	retries int64
+}
+
+// NewDBClient 创建客户端，查询失败时按policy重试，每次重试重新借一个连接
+// 连接池已关闭和等待连接超时的错误不重试，只重试查询本身的失败
+func NewDBClient(pool *connpool.Pool, policy retry.Policy) *DBClient {
+	c := &DBClient{pool: pool, policy: policy}
+	c.policy.Retryable = func(err error) bool {
+		return !errors.Is(err, connpool.ErrPoolClosed) && !errors.Is(err, connpool.ErrPoolTimeout)
+	}
+	c.policy.OnAttempt = func(a retry.Attempt) {
+		if a.Retry {
+			atomic.AddInt64(&c.retries, 1)
+		}
+	}
+	return c
+}
+
+func (c *DBClient) Query(query string) (interface{}, error) {
+	return retry.DoValue(context.Background(), c.policy, func(ctx context.Context) (interface{}, error) {
+		conn, err := c.pool.BorrowConnection()
+		if err != nil {
+			return nil, err
+		}
+		defer c.pool.ReturnConnection(conn)
+
+		return conn.Execute(query)
+	})
+}
+
+// Retries 查询的累计重试次数
+func (c *DBClient) Retries() int64 {
+	return atomic.LoadInt64(&c.retries)
+}
+
+func main() {
+	minConns := flag.Int("min", 3, "连接池的最小连接数")
+	maxConns := flag.Int("max", 10, "连接池的最大连接数")
+	numClients := flag.Int("clients", 20, "并发客户端数量")
+	queriesPerClient := flag.Int("queries", 5, "每个客户端执行的查询次数")
+	demoflag.Parse()
+
+	fmt.Println("=== 连接池演示 ===")
+
+	rand.Seed(time.Now().UnixNano())
+
+	// 创建连接池配置
+	config := connpool.Config{
+		MinConnections:    *minConns,
+		MaxConnections:    *maxConns,
+		MaxIdleTime:       5 * time.Second,
+		ConnectionTimeout: 3 * time.Second,
+		HealthCheckPeriod: 2 * time.Second,
+		// 建立连接偶尔失败（5%），重试2次，等待时间在[25ms, 50ms]、[50ms, 100ms]中随机
+		ConnectRetry: retry.Policy{MaxAttempts: 3, InitialDelay: 50 * time.Millisecond, Jitter: retry.EqualJitter},
+		Logf: func(format string, args ...any) {
+			fmt.Printf(format, args...)
+		},
+	}
+
+	// 创建连接池
+	pool, err := connpool.New(config, func(id string) connpool.Connection {
+		return NewDBConnection(id)
+	})
+	if err != nil {
+		fmt.Printf("创建连接池失败: %v\n", err)
+		return
+	}
+	defer pool.Close()
+
+	// 创建数据库客户端
+	// 查询失败（10%）最多重试3次；FullJitter让同时失败的客户端错开重试
+	client := NewDBClient(pool, retry.Policy{
+		MaxAttempts:  4,
+		InitialDelay: 20 * time.Millisecond,
+		MaxDelay:     200 * time.Millisecond,
+		Jitter:       retry.FullJitter,
+	})
+
+	fmt.Printf("连接池创建成功，配置: 最小=%d, 最大=%d, 空闲超时=%v\n",
+		config.MinConnections, config.MaxConnections, config.MaxIdleTime)
+
+	// 并发测试
+	var wg sync.WaitGroup
+
+	fmt.Printf("\n启动 %d 个并发客户端，每个执行 %d 次查询\n", *numClients, *queriesPerClient)
+
+	for i := 1; i <= *numClients; i++ {
+		wg.Add(1)
+		go func(clientID int) {
+			defer wg.Done()
+
+			for j := 1; j <= *queriesPerClient; j++ {
+				query := fmt.Sprintf("SELECT * FROM table WHERE client=%d AND seq=%d", clientID, j)
+
+				result, err := client.Query(query)
+				if err != nil {
+					fmt.Printf("客户端 %d 查询 %d 失败: %v\n", clientID, j, err)
+				} else {
+					fmt.Printf("客户端 %d 查询 %d 成功: %v\n", clientID, j, result)
+				}
+
+				// 随机等待时间
+				time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
+			}
+
+			fmt.Printf("客户端 %d 完成所有查询\n", clientID)
+		}(i)
+	}
+
+	// 在测试期间定期打印统计信息
+	go func() {
+		for i := 0; i < 10; i++ {
+			time.Sleep(2 * time.Second)
+			stats := pool.GetStats()
+			fmt.Printf("\n--- 连接池统计 (第%d次) ---\n", i+1)
+			for key, value := range stats {
+				fmt.Printf("%s: %v\n", key, value)
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	// 最终统计
+	fmt.Println("\n=== 最终统计 ===")
+	fmt.Printf("客户端查询重试次数: %d\n", client.Retries())
+	stats := pool.GetStats()
+	for key, value := range stats {
+		fmt.Printf("%s: %v\n", key, value)
+	}
+
+	// 等待一段时间观察空闲连接清理
+	fmt.Println("\n等待空闲连接清理...")
+	time.Sleep(6 * time.Second)
+
+	finalStats := pool.GetStats()
+	fmt.Println("\n=== 清理后统计 ===")
+	for key, value := range finalStats {
+		fmt.Printf("%s: %v\n", key, value)
+	}
+
+	fmt.Println("\n连接池演示完成！")
+}
@@ -0,0 +1,397 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/05_distributed_lock/main.go
+主题：基于租约的分布式锁
+
+本示例演示：
+1. 进程内模拟的锁服务：每把锁是一个带TTL的租约，每次授予锁都分配递增的fencing token
+2. 客户端在后台goroutine中定期续约，续约失败或本地租约到期时通过context通知业务代码停止
+3. 多个工作者竞争同一把锁，对共享存储做"读-改-写"
+4. 经典的正确性问题：持锁者暂停（GC、换页、网络延迟）超过TTL后锁被别人拿走，
+   醒来后仍然写入 —— 没有fencing时产生丢失更新，有fencing时存储拒绝旧token的写入
+
+核心概念：
+- 租约：锁服务不能无限期等待崩溃的持有者，所以锁必须会过期
+- 过期意味着"我以为我持有锁"不一定成立，客户端无法仅靠自己保证互斥
+- fencing token：存储端记录见过的最大token，拒绝更小token的写入，把互斥的最终检查放在资源一侧
+- 续约间隔通常取TTL的1/3，留出重试余地；本地判断租约到期时要扣掉时钟误差余量
+
+运行方式：go run ./cmd/hard/05_distributed_lock [-workers=4] [-rounds=10]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+var (
+	ErrLockHeld     = errors.New("锁已被其他客户端持有")
+	ErrLeaseExpired = errors.New("租约已过期或已被他人获取")
+	ErrStaleToken   = errors.New("fencing token已过期，拒绝写入")
+	ErrNetwork      = errors.New("网络不可达")
+)
+
+// Lease 锁服务授予的租约
+type Lease struct {
+	Name    string
+	Owner   string
+	Token   uint64
+	Expires time.Time
+}
+
+// LockServer 进程内模拟的锁服务
+type LockServer struct {
+	mu        sync.Mutex
+	leases    map[string]Lease
+	nextToken uint64
+
+	granted int64
+	expired int64 // 因过期被他人接管的次数
+}
+
+func NewLockServer() *LockServer {
+	return &LockServer{leases: make(map[string]Lease)}
+}
+
+// Acquire 锁空闲或上一个租约已过期时授予新租约，并分配更大的fencing token
+func (s *LockServer) Acquire(name, owner string, ttl time.Duration) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if cur, ok := s.leases[name]; ok {
+		if now.Before(cur.Expires) {
+			return Lease{}, ErrLockHeld
+		}
+		s.expired++
+	}
+
+	s.nextToken++
+	lease := Lease{Name: name, Owner: owner, Token: s.nextToken, Expires: now.Add(ttl)}
+	s.leases[name] = lease
+	s.granted++
+	return lease, nil
+}
+
+// Renew 延长租约；租约已过期（哪怕还没有被别人获取）或token不匹配时失败
+func (s *LockServer) Renew(lease Lease, ttl time.Duration) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.leases[lease.Name]
+	now := time.Now()
+	if !ok || cur.Token != lease.Token || !now.Before(cur.Expires) {
+		return Lease{}, ErrLeaseExpired
+	}
+	cur.Expires = now.Add(ttl)
+	s.leases[lease.Name] = cur
+	return cur, nil
+}
+
+// Release 释放租约，只有当前持有者可以释放
+func (s *LockServer) Release(lease Lease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.leases[lease.Name]
+	if !ok || cur.Token != lease.Token {
+		return ErrLeaseExpired
+	}
+	delete(s.leases, lease.Name)
+	return nil
+}
+
+// Storage 被锁保护的共享资源；fenced为true时检查fencing token
+type Storage struct {
+	mu        sync.Mutex
+	fenced    bool
+	value     int
+	maxToken  uint64
+	writes    int
+	rejected  int
+	lastOwner string
+}
+
+func (st *Storage) Read() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.value
+}
+
+func (st *Storage) Write(token uint64, owner string, value int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.fenced && token < st.maxToken {
+		st.rejected++
+		return fmt.Errorf("%w: token %d < %d", ErrStaleToken, token, st.maxToken)
+	}
+	if token > st.maxToken {
+		st.maxToken = token
+	}
+	st.value = value
+	st.writes++
+	st.lastOwner = owner
+	return nil
+}
+
+// LockClient 锁服务的客户端，负责获取锁和后台续约
+type LockClient struct {
+	id          string
+	server      *LockServer
+	ttl         time.Duration
+	partitioned atomic.Bool // 模拟与锁服务之间的网络分区
+}
+
+func NewLockClient(id string, server *LockServer, ttl time.Duration) *LockClient {
+	return &LockClient{id: id, server: server, ttl: ttl}
+}
+
+func (c *LockClient) acquire(name string) (Lease, error) {
+	if c.partitioned.Load() {
+		return Lease{}, ErrNetwork
+	}
+	return c.server.Acquire(name, c.id, c.ttl)
+}
+
+func (c *LockClient) renew(lease Lease) (Lease, error) {
+	if c.partitioned.Load() {
+		return Lease{}, ErrNetwork
+	}
+	return c.server.Renew(lease, c.ttl)
+}
+
+// Session 一次持锁会话；Context在租约丢失时被取消
+type Session struct {
+	Lease  Lease
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	client *LockClient
+	done   chan struct{}
+}
+
+func (s *Session) Context() context.Context { return s.ctx }
+
+// Unlock 停止续约并释放锁
+func (s *Session) Unlock() error {
+	s.cancel(nil)
+	<-s.done
+	if s.client.partitioned.Load() {
+		return ErrNetwork
+	}
+	return s.client.server.Release(s.Lease)
+}
+
+// Lock 不断重试直到获得锁或ctx结束；renew为false时不启动续约（用来模拟续约goroutine被卡住）
+func (c *LockClient) Lock(ctx context.Context, name string, renew bool) (*Session, error) {
+	for {
+		lease, err := c.acquire(name)
+		if err == nil {
+			sctx, cancel := context.WithCancelCause(ctx)
+			s := &Session{Lease: lease, ctx: sctx, cancel: cancel, client: c, done: make(chan struct{})}
+			if renew {
+				go c.keepAlive(s, lease)
+			} else {
+				close(s.done)
+			}
+			return s, nil
+		}
+
+		select {
+		case <-time.After(c.ttl / 10):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// keepAlive 每TTL/3续约一次；续约失败时继续重试，直到本地估计的租约到期
+// 本地估计扣掉10%的余量，保证客户端比服务端更早认为租约失效
+func (c *LockClient) keepAlive(s *Session, lease Lease) {
+	defer close(s.done)
+
+	localExpiry := time.Now().Add(c.ttl * 9 / 10)
+	ticker := time.NewTicker(c.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if time.Now().After(localExpiry) {
+			s.cancel(ErrLeaseExpired)
+			return
+		}
+
+		start := time.Now()
+		renewed, err := c.renew(lease)
+		if err != nil {
+			if errors.Is(err, ErrLeaseExpired) {
+				s.cancel(err)
+				return
+			}
+			continue // 网络错误：在本地到期之前继续重试
+		}
+		lease = renewed
+		localExpiry = start.Add(c.ttl * 9 / 10)
+	}
+}
+
+// incrementUnderLock 持锁做一次读-改-写；pause模拟读和写之间的长时间停顿
+func incrementUnderLock(c *LockClient, st *Storage, renew bool, pause time.Duration) error {
+	s, err := c.Lock(context.Background(), "counter", renew)
+	if err != nil {
+		return err
+	}
+
+	v := st.Read()
+	if pause > 0 {
+		time.Sleep(pause)
+	}
+	werr := st.Write(s.Lease.Token, c.id, v+1)
+
+	if err := s.Unlock(); err != nil && werr == nil {
+		werr = fmt.Errorf("写入成功但释放失败: %w", err)
+	}
+	return werr
+}
+
+// demoCompetingWorkers 正常情况：workers个工作者各加锁rounds次竞争同一把锁，计数器结果正确
+func demoCompetingWorkers(workers, rounds int) {
+	server := NewLockServer()
+	st := &Storage{fenced: true}
+
+	var wg sync.WaitGroup
+	for i := 1; i <= workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			c := NewLockClient(fmt.Sprintf("worker-%d", id), server, 100*time.Millisecond)
+			for r := 0; r < rounds; r++ {
+				if err := incrementUnderLock(c, st, true, time.Millisecond); err != nil {
+					fmt.Printf("%s 写入失败: %v\n", c.id, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	fmt.Printf("计数器 = %d (期望 %d), 授予锁 %d 次, 过期接管 %d 次, 拒绝写入 %d 次\n",
+		st.Read(), workers*rounds, server.granted, server.expired, st.rejected)
+}
+
+// demoPausedHolder 持锁者在读和写之间暂停超过TTL，另一个工作者接管锁并完成写入
+func demoPausedHolder(fenced bool) {
+	server := NewLockServer()
+	st := &Storage{fenced: fenced}
+	ttl := 100 * time.Millisecond
+
+	slow := NewLockClient("slow", server, ttl)
+	fast := NewLockClient("fast", server, ttl)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// 续约goroutine也随进程一起暂停（如STW GC），所以这里不续约
+		err := incrementUnderLock(slow, st, false, 3*ttl)
+		fmt.Printf("  slow: 暂停 %v 后写入: %v\n", 3*ttl, errOrOK(err))
+	}()
+	go func() {
+		defer wg.Done()
+		time.Sleep(ttl / 2) // 让slow先拿到锁
+		err := incrementUnderLock(fast, st, true, 0)
+		fmt.Printf("  fast: 等待slow的租约过期后获得锁并写入: %v\n", errOrOK(err))
+	}()
+	wg.Wait()
+
+	fmt.Printf("  计数器 = %d (两次自增，期望 2), 成功写入 %d 次, 拒绝 %d 次, 最后写入者 %s\n",
+		st.Read(), st.writes, st.rejected, st.lastOwner)
+}
+
+// demoPartition 长任务依靠续约持有锁超过TTL；网络分区后续约失败，
+// 客户端在本地租约到期前取消任务，而不是继续以为自己持有锁
+func demoPartition() {
+	server := NewLockServer()
+	ttl := 100 * time.Millisecond
+	c := NewLockClient("long-task", server, ttl)
+
+	s, err := c.Lock(context.Background(), "job", true)
+	if err != nil {
+		fmt.Println("获取锁失败:", err)
+		return
+	}
+
+	start := time.Now()
+	partitionAt := 5 * ttl
+	steps := 0
+	for {
+		if time.Since(start) > partitionAt && !c.partitioned.Load() {
+			fmt.Printf("  %v: 发生网络分区，续约请求无法到达锁服务\n", time.Since(start).Round(10*time.Millisecond))
+			c.partitioned.Store(true)
+		}
+
+		select {
+		case <-s.Context().Done():
+			fmt.Printf("  %v: 任务在第 %d 步停止，原因: %v\n",
+				time.Since(start).Round(10*time.Millisecond), steps, context.Cause(s.Context()))
+			fmt.Printf("  释放锁: %v\n", errOrOK(s.Unlock()))
+
+			c.partitioned.Store(false)
+			other := NewLockClient("other", server, ttl)
+			otherSession, err := other.Lock(context.Background(), "job", false)
+			if err == nil {
+				fmt.Printf("  分区恢复后other获得锁, token=%d (long-task的token=%d)\n", otherSession.Lease.Token, s.Lease.Token)
+				otherSession.Unlock()
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+			steps++
+		}
+	}
+}
+
+func errOrOK(err error) string {
+	if err == nil {
+		return "成功"
+	}
+	return err.Error()
+}
+
+func main() {
+	workers := flag.Int("workers", 4, "场景1中竞争锁的工作者数量")
+	rounds := flag.Int("rounds", 10, "场景1中每个工作者加锁写入的次数")
+	demoflag.Parse()
+
+	fmt.Println("=== 基于租约的分布式锁演示 ===")
+
+	fmt.Printf("\n1. %d个工作者竞争同一把锁（带续约和fencing）:\n", *workers)
+	demoCompetingWorkers(*workers, *rounds)
+
+	fmt.Println("\n2. 持锁者暂停超过TTL，存储不检查fencing token:")
+	demoPausedHolder(false)
+
+	fmt.Println("\n3. 同样的场景，存储检查fencing token:")
+	demoPausedHolder(true)
+
+	fmt.Println("\n4. 长任务续约与网络分区:")
+	demoPartition()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 场景2中两个工作者都\"持有过锁\"并写入成功，但计数器只增加了1：过期租约导致丢失更新")
+	fmt.Println("2. 场景3中存储已见过fast的更大token，slow的旧token写入被拒绝，丢失更新变成了可见的错误")
+	fmt.Println("3. 续约让任务可以持锁超过TTL；续约失败时客户端在本地到期前主动停止，缩小两个持有者重叠的窗口")
+	fmt.Println("4. 仅靠客户端无法完全避免重叠（暂停可能发生在任何一行代码之后），fencing token把最终检查交给资源本身")
+}
@@ -0,0 +1,221 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/06_leader_election/main.go
+主题：基于租约的领导者选举
+
+本示例演示：
+1. 5个节点goroutine通过 pkg/election 竞争同一个租约，拿到租约的节点成为领导者
+2. 领导者与协调服务失联（网络分区/崩溃）时，租约过期后其余节点自动重新选举
+3. 失联的旧领导者在本地租约到期前主动退位，恢复后作为跟随者重新加入
+4. 领导者正常退出时主动让出租约，故障转移不必等待TTL
+5. 检查任意时刻最多只有一个节点认为自己是领导者
+
+核心概念：
+- IsLeader()：随时查询当前节点是否为领导者
+- OnElected(ctx, term)：当选后执行领导者专属的工作，ctx在失去领导权时取消
+- OnNewLeader(id, term)：观察到领导者变化，跟随者可以据此更新路由
+- 任期(term)每次换届递增，可以作为fencing token交给下游资源
+
+运行方式：go run ./cmd/hard/06_leader_election [-nodes=5] [-ttl=300ms]
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/election"
+)
+
+// eventLog 带相对时间戳的事件日志
+type eventLog struct {
+	mu    sync.Mutex
+	start time.Time
+}
+
+func (l *eventLog) printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Printf("[%6v] ", time.Since(l.start).Round(10*time.Millisecond))
+	fmt.Printf(format+"\n", args...)
+}
+
+// leadership 记录当选时间和同时在任的领导者数量
+type leadership struct {
+	mu         sync.Mutex
+	active     int
+	maxActive  int
+	terms      []string
+	lastElects time.Time
+}
+
+func (s *leadership) elected(id string, term uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active++
+	if s.active > s.maxActive {
+		s.maxActive = s.active
+	}
+	s.terms = append(s.terms, fmt.Sprintf("term %d: %s", term, id))
+	s.lastElects = time.Now()
+}
+
+func (s *leadership) stepped() {
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+}
+
+// waitNewLeader 等待since之后有新的领导者当选，返回花费的时间
+func (s *leadership) waitNewLeader(since time.Time, timeout time.Duration) time.Duration {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		t := s.lastElects
+		s.mu.Unlock()
+		if t.After(since) {
+			return t.Sub(since)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return -1
+}
+
+type node struct {
+	id      string
+	elector *election.Elector
+	cancel  context.CancelFunc
+	done    chan struct{}
+	work    int64 // 作为领导者完成的工作量
+}
+
+func startNode(id string, store *election.Store, ttl time.Duration, log *eventLog, stats *leadership) *node {
+	n := &node{id: id, done: make(chan struct{})}
+	n.elector = election.New(election.Config{
+		ID:    id,
+		Store: store,
+		TTL:   ttl,
+		OnElected: func(ctx context.Context, term uint64) {
+			stats.elected(id, term)
+			defer stats.stepped()
+			log.printf("%s 当选领导者 (term %d)", id, term)
+
+			// 领导者专属工作：例如分配任务、执行定时作业
+			ticker := time.NewTicker(20 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					atomic.AddInt64(&n.work, 1)
+				}
+			}
+		},
+		OnDemoted: func(term uint64) {
+			log.printf("%s 退位 (term %d)", id, term)
+		},
+		OnNewLeader: func(leader string, term uint64) {
+			if leader != id {
+				log.printf("%s 观察到新领导者 %s (term %d)", id, leader, term)
+			}
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n.cancel = cancel
+	go func() {
+		defer close(n.done)
+		n.elector.Run(ctx)
+	}()
+	return n
+}
+
+func (n *node) stop() {
+	n.cancel()
+	<-n.done
+}
+
+func currentLeader(nodes []*node) *node {
+	for _, n := range nodes {
+		if n.elector.IsLeader() {
+			return n
+		}
+	}
+	return nil
+}
+
+func main() {
+	numNodes := flag.Int("nodes", 5, "参与选举的节点数量")
+	ttlFlag := flag.Duration("ttl", 300*time.Millisecond, "租约TTL，节点每TTL/3尝试获取或续约一次")
+	demoflag.Parse()
+
+	fmt.Println("=== 基于租约的领导者选举演示 ===")
+
+	ttl := *ttlFlag
+	store := election.NewStore()
+	log := &eventLog{start: time.Now()}
+	stats := &leadership{}
+
+	fmt.Printf("%d个节点, 租约TTL %v, 每 %v 尝试获取/续约一次\n\n", *numNodes, ttl, ttl/3)
+
+	var nodes []*node
+	for i := 1; i <= *numNodes; i++ {
+		nodes = append(nodes, startNode(fmt.Sprintf("node-%d", i), store, ttl, log, stats))
+	}
+	time.Sleep(time.Second)
+
+	// 1. 领导者与协调服务失联：必须等租约过期，其他节点才能当选
+	old := currentLeader(nodes)
+	if old == nil {
+		fmt.Println("没有选出领导者")
+		return
+	}
+	log.printf("--- %s 发生网络分区 ---", old.id)
+	partitionAt := time.Now()
+	store.Partition(old.id, true)
+	failover := stats.waitNewLeader(partitionAt, 3*ttl)
+	time.Sleep(500 * time.Millisecond)
+
+	log.printf("--- %s 网络恢复 ---", old.id)
+	store.Partition(old.id, false)
+	time.Sleep(500 * time.Millisecond)
+
+	// 2. 领导者正常退出：主动让出租约，不必等待过期
+	leader := currentLeader(nodes)
+	log.printf("--- %s 正常退出 ---", leader.id)
+	stopAt := time.Now()
+	leader.stop()
+	handover := stats.waitNewLeader(stopAt, 3*ttl)
+	time.Sleep(500 * time.Millisecond)
+
+	for _, n := range nodes {
+		if n != leader {
+			n.stop()
+		}
+	}
+
+	fmt.Println("\n=== 统计 ===")
+	for _, t := range stats.terms {
+		fmt.Println(" ", t)
+	}
+	for _, n := range nodes {
+		fmt.Printf("  %s 作为领导者完成工作 %d 次\n", n.id, atomic.LoadInt64(&n.work))
+	}
+	fmt.Printf("网络分区后的故障转移耗时: %v (受TTL限制)\n", failover.Round(10*time.Millisecond))
+	fmt.Printf("主动退出后的交接耗时:     %v (不超过一次重试间隔)\n", handover.Round(10*time.Millisecond))
+	fmt.Printf("同时在任的领导者最多 %d 个\n", stats.maxActive)
+	fmt.Printf("检查结果: %v\n", stats.maxActive == 1 && failover > 0 && handover > 0 && handover < failover)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 分区的旧领导者在Store的租约过期之前就主动退位，新领导者当选时旧领导者已经停止工作")
+	fmt.Println("2. 网络恢复后旧领导者发现租约属于别人，作为跟随者重新加入，不会抢回领导权")
+	fmt.Println("3. 主动让出租约的交接远快于等待租约过期，所以进程退出时应该调用Resign（Run退出时自动完成）")
+	fmt.Println("4. 每次换届任期加一，下游可以用任期拒绝旧领导者迟到的请求")
+}
@@ -0,0 +1,746 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/07_raft/main.go
+主题：简化版Raft共识算法
+
+本示例演示：
+1. 5个进程内节点，每个节点是一个事件循环goroutine，节点之间只通过channel收发消息
+2. 领导者选举：随机选举超时、按任期投票、日志较新的候选者才能获得选票
+3. 日志复制：领导者通过AppendEntries复制日志，多数节点确认后提交并应用到状态机
+4. 网络分区：旧领导者被隔离后仍接受写入但无法提交，恢复后它的未提交日志被覆盖
+5. 混沌模式：随机丢弃和延迟消息，检查各节点应用的日志始终一致
+
+核心概念：
+- 任期(term)：每次选举任期加一，收到更大任期的消息立即变为跟随者
+- 选举安全：一个任期内最多一个领导者（每个节点每个任期只投一票，且需要多数票）
+- 日志匹配：领导者发送前一条日志的下标和任期，跟随者不匹配时拒绝，领导者回退重试
+- 提交规则：领导者只直接提交自己任期内的日志，所以当选后先追加一条no-op
+- 状态机安全：所有节点在同一下标应用的命令相同
+
+简化之处：没有持久化、快照和成员变更，节点状态只保存在内存中
+
+运行方式：go run ./cmd/hard/07_raft [-chaos=true] [-drop=0.2] [-delay=30ms]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+const (
+	heartbeatInterval  = 50 * time.Millisecond
+	electionTimeoutMin = 150 * time.Millisecond
+	electionTimeoutMax = 300 * time.Millisecond
+	maxEntriesPerSend  = 50
+)
+
+type MsgType int
+
+const (
+	MsgVote MsgType = iota
+	MsgVoteReply
+	MsgAppend
+	MsgAppendReply
+)
+
+// Message 节点之间的所有RPC共用一个消息结构
+type Message struct {
+	Type MsgType
+	From int
+	To   int
+	Term int
+
+	// RequestVote
+	LastLogIndex int
+	LastLogTerm  int
+	VoteGranted  bool
+
+	// AppendEntries
+	PrevLogIndex int
+	PrevLogTerm  int
+	Entries      []Entry
+	LeaderCommit int
+	Success      bool
+	MatchIndex   int // 成功时为已匹配的最后下标；失败时为跟随者的日志长度，帮助领导者快速回退
+}
+
+// Entry 一条日志
+type Entry struct {
+	Term    int
+	Command string
+}
+
+// Network 模拟节点之间的网络：支持分区、随机丢包和延迟
+type Network struct {
+	mu       sync.Mutex
+	inboxes  []chan Message
+	cut      map[[2]int]bool
+	dropRate float64
+	maxDelay time.Duration
+	rng      *rand.Rand
+
+	sent    int
+	dropped int
+}
+
+func NewNetwork(n int) *Network {
+	net := &Network{
+		cut: make(map[[2]int]bool),
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for i := 0; i < n; i++ {
+		net.inboxes = append(net.inboxes, make(chan Message, 256))
+	}
+	return net
+}
+
+func (n *Network) Send(m Message) {
+	n.mu.Lock()
+	n.sent++
+	if n.cut[[2]int{m.From, m.To}] || n.rng.Float64() < n.dropRate {
+		n.dropped++
+		n.mu.Unlock()
+		return
+	}
+	var delay time.Duration
+	if n.maxDelay > 0 {
+		delay = time.Duration(n.rng.Int63n(int64(n.maxDelay)))
+	}
+	n.mu.Unlock()
+
+	deliver := func() {
+		select {
+		case n.inboxes[m.To] <- m:
+		default: // 收件箱满了，相当于丢包
+		}
+	}
+	if delay > 0 {
+		time.AfterFunc(delay, deliver)
+	} else {
+		deliver()
+	}
+}
+
+// Isolate 把group中的节点与其余节点隔开
+func (n *Network) Isolate(group ...int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	in := make(map[int]bool)
+	for _, id := range group {
+		in[id] = true
+	}
+	for a := range n.inboxes {
+		for b := range n.inboxes {
+			if in[a] != in[b] {
+				n.cut[[2]int{a, b}] = true
+			}
+		}
+	}
+}
+
+func (n *Network) Heal() {
+	n.mu.Lock()
+	n.cut = make(map[[2]int]bool)
+	n.mu.Unlock()
+}
+
+func (n *Network) SetChaos(dropRate float64, maxDelay time.Duration) {
+	n.mu.Lock()
+	n.dropRate, n.maxDelay = dropRate, maxDelay
+	n.mu.Unlock()
+}
+
+func (n *Network) Stats() (sent, dropped int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.sent, n.dropped
+}
+
+type Role int
+
+const (
+	Follower Role = iota
+	Candidate
+	Leader
+)
+
+func (r Role) String() string {
+	return [...]string{"Follower", "Candidate", "Leader"}[r]
+}
+
+// Status 节点状态快照，通过事件循环读取，不需要加锁
+type Status struct {
+	ID          int
+	Role        Role
+	Term        int
+	LogLen      int
+	CommitIndex int
+	LastApplied int
+	Log         []Entry
+}
+
+type proposal struct {
+	command string
+	reply   chan proposeResult
+}
+
+type proposeResult struct {
+	index, term int
+	ok          bool
+}
+
+// Node 一个Raft节点；除了channel以外的字段只在run goroutine中访问
+type Node struct {
+	id    int
+	peers int
+	net   *Network
+	inbox chan Message
+	apply func(id, index int, e Entry)
+	elect func(id, term int)
+
+	role        Role
+	term        int
+	votedFor    int
+	votes       int
+	log         []Entry // log[0]是占位，真实日志从下标1开始
+	commitIndex int
+	lastApplied int
+	nextIndex   []int
+	matchIndex  []int
+
+	electionDeadline time.Time
+	lastHeartbeat    time.Time
+	rng              *rand.Rand
+
+	proposeCh chan proposal
+	statusCh  chan chan Status
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewNode(id, peers int, net *Network, checker *Checker) *Node {
+	n := &Node{
+		id:        id,
+		peers:     peers,
+		net:       net,
+		inbox:     net.inboxes[id],
+		apply:     checker.OnApply,
+		elect:     checker.OnLeader,
+		votedFor:  -1,
+		log:       []Entry{{}},
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano() + int64(id))),
+		proposeCh: make(chan proposal),
+		statusCh:  make(chan chan Status),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	n.resetElectionTimer()
+	go n.run()
+	return n
+}
+
+func (n *Node) run() {
+	defer close(n.done)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			return
+		case m := <-n.inbox:
+			n.handle(m)
+		case p := <-n.proposeCh:
+			p.reply <- n.propose(p.command)
+		case ch := <-n.statusCh:
+			ch <- n.status()
+		case <-ticker.C:
+			n.tick()
+		}
+	}
+}
+
+func (n *Node) Stop() {
+	close(n.stop)
+	<-n.done
+}
+
+// Propose 向节点提交命令；节点不是领导者时ok为false
+// 返回的下标只说明命令进入了领导者的日志，是否提交要看之后是否被应用
+func (n *Node) Propose(command string) (index, term int, ok bool) {
+	reply := make(chan proposeResult, 1)
+	n.proposeCh <- proposal{command: command, reply: reply}
+	r := <-reply
+	return r.index, r.term, r.ok
+}
+
+func (n *Node) Status() Status {
+	ch := make(chan Status, 1)
+	n.statusCh <- ch
+	return <-ch
+}
+
+func (n *Node) status() Status {
+	return Status{
+		ID:          n.id,
+		Role:        n.role,
+		Term:        n.term,
+		LogLen:      len(n.log) - 1,
+		CommitIndex: n.commitIndex,
+		LastApplied: n.lastApplied,
+		Log:         append([]Entry(nil), n.log[1:]...),
+	}
+}
+
+func (n *Node) resetElectionTimer() {
+	timeout := electionTimeoutMin + time.Duration(n.rng.Int63n(int64(electionTimeoutMax-electionTimeoutMin)))
+	n.electionDeadline = time.Now().Add(timeout)
+}
+
+func (n *Node) lastLog() (index, term int) {
+	return len(n.log) - 1, n.log[len(n.log)-1].Term
+}
+
+func (n *Node) send(m Message) {
+	m.From = n.id
+	m.Term = n.term
+	n.net.Send(m)
+}
+
+func (n *Node) tick() {
+	if n.role == Leader {
+		if time.Since(n.lastHeartbeat) >= heartbeatInterval {
+			n.broadcastAppend()
+		}
+		return
+	}
+	if time.Now().After(n.electionDeadline) {
+		n.startElection()
+	}
+}
+
+func (n *Node) startElection() {
+	n.role = Candidate
+	n.term++
+	n.votedFor = n.id
+	n.votes = 1
+	n.resetElectionTimer()
+
+	lastIndex, lastTerm := n.lastLog()
+	for p := 0; p < n.peers; p++ {
+		if p != n.id {
+			n.send(Message{Type: MsgVote, To: p, LastLogIndex: lastIndex, LastLogTerm: lastTerm})
+		}
+	}
+}
+
+func (n *Node) becomeFollower(term int) {
+	n.role = Follower
+	n.term = term
+	n.votedFor = -1
+}
+
+func (n *Node) becomeLeader() {
+	n.role = Leader
+	n.nextIndex = make([]int, n.peers)
+	n.matchIndex = make([]int, n.peers)
+	for p := range n.nextIndex {
+		n.nextIndex[p] = len(n.log)
+	}
+	n.elect(n.id, n.term)
+
+	// 当选后追加一条本任期的no-op，提交它的同时也提交了之前任期遗留的日志
+	n.log = append(n.log, Entry{Term: n.term, Command: "no-op"})
+	n.broadcastAppend()
+}
+
+func (n *Node) propose(command string) proposeResult {
+	if n.role != Leader {
+		return proposeResult{}
+	}
+	n.log = append(n.log, Entry{Term: n.term, Command: command})
+	n.broadcastAppend()
+	return proposeResult{index: len(n.log) - 1, term: n.term, ok: true}
+}
+
+func (n *Node) broadcastAppend() {
+	n.lastHeartbeat = time.Now()
+	for p := 0; p < n.peers; p++ {
+		if p == n.id {
+			continue
+		}
+		prev := n.nextIndex[p] - 1
+		end := len(n.log)
+		if end-n.nextIndex[p] > maxEntriesPerSend {
+			end = n.nextIndex[p] + maxEntriesPerSend
+		}
+		// 复制一份，避免接收方和之后的截断共享底层数组
+		entries := append([]Entry(nil), n.log[n.nextIndex[p]:end]...)
+		n.send(Message{
+			Type:         MsgAppend,
+			To:           p,
+			PrevLogIndex: prev,
+			PrevLogTerm:  n.log[prev].Term,
+			Entries:      entries,
+			LeaderCommit: n.commitIndex,
+		})
+	}
+}
+
+func (n *Node) handle(m Message) {
+	if m.Term > n.term {
+		n.becomeFollower(m.Term)
+	}
+
+	switch m.Type {
+	case MsgVote:
+		lastIndex, lastTerm := n.lastLog()
+		upToDate := m.LastLogTerm > lastTerm || (m.LastLogTerm == lastTerm && m.LastLogIndex >= lastIndex)
+		granted := m.Term == n.term && (n.votedFor == -1 || n.votedFor == m.From) && upToDate
+		if granted {
+			n.votedFor = m.From
+			n.resetElectionTimer()
+		}
+		n.send(Message{Type: MsgVoteReply, To: m.From, VoteGranted: granted})
+
+	case MsgVoteReply:
+		if n.role == Candidate && m.Term == n.term && m.VoteGranted {
+			n.votes++
+			if n.votes > n.peers/2 {
+				n.becomeLeader()
+			}
+		}
+
+	case MsgAppend:
+		if m.Term < n.term {
+			n.send(Message{Type: MsgAppendReply, To: m.From, Success: false, MatchIndex: len(n.log)})
+			return
+		}
+		// 同一任期的候选者收到领导者的消息也要退回跟随者
+		n.role = Follower
+		n.resetElectionTimer()
+
+		if m.PrevLogIndex >= len(n.log) || n.log[m.PrevLogIndex].Term != m.PrevLogTerm {
+			n.send(Message{Type: MsgAppendReply, To: m.From, Success: false, MatchIndex: len(n.log)})
+			return
+		}
+
+		for i, e := range m.Entries {
+			idx := m.PrevLogIndex + 1 + i
+			if idx < len(n.log) {
+				if n.log[idx].Term == e.Term {
+					continue
+				}
+				// 冲突：删除这条及之后的所有日志。已提交的日志不会冲突，这里只会删掉未提交的
+				n.log = n.log[:idx]
+			}
+			n.log = append(n.log, e)
+		}
+
+		match := m.PrevLogIndex + len(m.Entries)
+		if m.LeaderCommit > n.commitIndex {
+			// 延迟到达的旧AppendEntries携带的日志较少，match可能小于已提交的位置，commitIndex不能回退
+			n.commitIndex = max(n.commitIndex, min(m.LeaderCommit, match))
+			n.applyCommitted()
+		}
+		n.send(Message{Type: MsgAppendReply, To: m.From, Success: true, MatchIndex: match})
+
+	case MsgAppendReply:
+		if n.role != Leader || m.Term != n.term {
+			return
+		}
+		if m.Success {
+			if m.MatchIndex > n.matchIndex[m.From] {
+				n.matchIndex[m.From] = m.MatchIndex
+			}
+			n.nextIndex[m.From] = n.matchIndex[m.From] + 1
+			n.advanceCommit()
+		} else {
+			// 回退nextIndex，下次心跳时重发更早的日志
+			n.nextIndex[m.From] = max(1, min(n.nextIndex[m.From]-1, m.MatchIndex))
+		}
+	}
+}
+
+// advanceCommit 找到被多数节点复制的、本任期内最大的下标
+func (n *Node) advanceCommit() {
+	for idx := len(n.log) - 1; idx > n.commitIndex; idx-- {
+		if n.log[idx].Term != n.term {
+			break
+		}
+		count := 1
+		for p := 0; p < n.peers; p++ {
+			if p != n.id && n.matchIndex[p] >= idx {
+				count++
+			}
+		}
+		if count > n.peers/2 {
+			n.commitIndex = idx
+			n.applyCommitted()
+			return
+		}
+	}
+}
+
+func (n *Node) applyCommitted() {
+	for n.lastApplied < n.commitIndex {
+		n.lastApplied++
+		n.apply(n.id, n.lastApplied, n.log[n.lastApplied])
+	}
+}
+
+// Checker 从外部观察所有节点，检查Raft的安全性质
+type Checker struct {
+	mu         sync.Mutex
+	leaders    map[int]int     // 任期 -> 领导者
+	applied    [][]Entry       // 每个节点按顺序应用的日志
+	commands   map[string]bool // 已被应用的命令
+	violations []string
+}
+
+func NewChecker(n int) *Checker {
+	return &Checker{
+		leaders:  make(map[int]int),
+		applied:  make([][]Entry, n),
+		commands: make(map[string]bool),
+	}
+}
+
+func (c *Checker) OnLeader(id, term int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if other, ok := c.leaders[term]; ok && other != id {
+		c.violations = append(c.violations, fmt.Sprintf("任期 %d 出现两个领导者: %d 和 %d", term, other, id))
+	}
+	c.leaders[term] = id
+	fmt.Printf("  节点 %d 当选领导者 (term %d)\n", id, term)
+}
+
+func (c *Checker) OnApply(id, index int, e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index != len(c.applied[id])+1 {
+		c.violations = append(c.violations, fmt.Sprintf("节点 %d 跳过了下标 %d", id, len(c.applied[id])+1))
+	}
+	for other, log := range c.applied {
+		if other != id && len(log) >= index && log[index-1] != e {
+			c.violations = append(c.violations,
+				fmt.Sprintf("下标 %d: 节点 %d 应用 %v, 节点 %d 应用 %v", index, id, e, other, log[index-1]))
+		}
+	}
+	c.applied[id] = append(c.applied[id], e)
+	c.commands[e.Command] = true
+}
+
+func (c *Checker) Applied(command string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commands[command]
+}
+
+func (c *Checker) Violations() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.violations...)
+}
+
+// Cluster 节点集合和客户端辅助方法
+type Cluster struct {
+	nodes   []*Node
+	net     *Network
+	checker *Checker
+}
+
+func NewCluster(n int) *Cluster {
+	c := &Cluster{net: NewNetwork(n), checker: NewChecker(n)}
+	for i := 0; i < n; i++ {
+		c.nodes = append(c.nodes, NewNode(i, n, c.net, c.checker))
+	}
+	return c
+}
+
+// leader 返回自认为是领导者且任期最大的节点（被隔离的旧领导者任期较小）
+func (c *Cluster) leader() *Node {
+	var best *Node
+	bestTerm := -1
+	for _, n := range c.nodes {
+		s := n.Status()
+		if s.Role == Leader && s.Term > bestTerm {
+			best, bestTerm = n, s.Term
+		}
+	}
+	return best
+}
+
+// Submit 像客户端一样提交命令：找到领导者提交，超时未应用就重试
+// 重试可能导致同一命令被提交两次，真实系统会给命令加唯一ID去重
+func (c *Cluster) Submit(command string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		l := c.leader()
+		if l == nil {
+			time.Sleep(20 * time.Millisecond)
+			continue
+		}
+		if _, _, ok := l.Propose(command); !ok {
+			continue
+		}
+		wait := time.Now().Add(500 * time.Millisecond)
+		for time.Now().Before(wait) {
+			if c.checker.Applied(command) {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	return false
+}
+
+// WaitConverged 等待所有节点应用到相同的下标
+func (c *Cluster) WaitConverged(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		applied := make(map[int]bool)
+		for _, n := range c.nodes {
+			applied[n.Status().LastApplied] = true
+		}
+		if len(applied) == 1 {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+func (c *Cluster) PrintStatus() {
+	for _, n := range c.nodes {
+		s := n.Status()
+		fmt.Printf("  节点 %d: %-9v term=%-2d 日志=%-3d 已提交=%-3d 已应用=%d\n",
+			s.ID, s.Role, s.Term, s.LogLen, s.CommitIndex, s.LastApplied)
+	}
+}
+
+func (c *Cluster) Stop() {
+	for _, n := range c.nodes {
+		n.Stop()
+	}
+}
+
+func submitBatch(c *Cluster, prefix string, count int) (ok int) {
+	for i := 1; i <= count; i++ {
+		if c.Submit(fmt.Sprintf("%s-%d", prefix, i), 3*time.Second) {
+			ok++
+		}
+	}
+	return ok
+}
+
+func main() {
+	chaos := flag.Bool("chaos", true, "是否运行混沌阶段（随机丢包和延迟）")
+	dropRate := flag.Float64("drop", 0.2, "混沌阶段的丢包率")
+	maxDelay := flag.Duration("delay", 30*time.Millisecond, "混沌阶段的最大消息延迟")
+	demoflag.Parse()
+
+	fmt.Println("=== 简化版Raft演示 ===")
+	cluster := NewCluster(5)
+	defer cluster.Stop()
+
+	fmt.Println("\n1. 启动5个节点，等待选举:")
+	time.Sleep(500 * time.Millisecond)
+	ok := submitBatch(cluster, "set-a", 5)
+	fmt.Printf("  提交 5 条命令，成功 %d 条\n", ok)
+	cluster.WaitConverged(2 * time.Second)
+	cluster.PrintStatus()
+
+	fmt.Println("\n2. 隔离当前领导者:")
+	old := cluster.leader()
+	cluster.net.Isolate(old.id)
+	fmt.Printf("  节点 %d 被隔离（少数派）\n", old.id)
+	staleIndex, staleTerm, _ := old.Propose("stale-write")
+	fmt.Printf("  旧领导者仍接受写入: stale-write 位于下标 %d (term %d)，但无法得到多数确认\n", staleIndex, staleTerm)
+
+	time.Sleep(500 * time.Millisecond)
+	ok = submitBatch(cluster, "set-b", 5)
+	fmt.Printf("  多数派选出新领导者并提交 5 条命令，成功 %d 条\n", ok)
+	cluster.PrintStatus()
+
+	fmt.Println("\n3. 恢复网络:")
+	cluster.net.Heal()
+	cluster.WaitConverged(2 * time.Second)
+	time.Sleep(200 * time.Millisecond)
+	cluster.PrintStatus()
+	oldLog := old.Status().Log
+	if staleIndex-1 < len(oldLog) {
+		fmt.Printf("  旧领导者下标 %d 处现在是: %+v\n", staleIndex, oldLog[staleIndex-1])
+	}
+
+	if *chaos {
+		fmt.Printf("\n4. 混沌模式: 丢包率 %.0f%%, 最大延迟 %v，期间随机隔离一个节点:\n", *dropRate*100, *maxDelay)
+		cluster.net.SetChaos(*dropRate, *maxDelay)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				time.Sleep(400 * time.Millisecond)
+				victim := rand.Intn(len(cluster.nodes))
+				cluster.net.Heal()
+				cluster.net.Isolate(victim)
+			}
+			time.Sleep(400 * time.Millisecond)
+			cluster.net.Heal()
+		}()
+		ok = submitBatch(cluster, "set-c", 10)
+		wg.Wait()
+		fmt.Printf("  提交 10 条命令，成功 %d 条\n", ok)
+		cluster.net.SetChaos(0, 0)
+		cluster.WaitConverged(3 * time.Second)
+		cluster.PrintStatus()
+	}
+
+	fmt.Println("\n=== 安全性检查 ===")
+	violations := cluster.checker.Violations()
+	for _, v := range violations {
+		fmt.Println("  ✗", v)
+	}
+	check := func(ok bool, desc string) {
+		mark := "✓"
+		if !ok {
+			mark = "✗"
+		}
+		fmt.Printf("  %s %s\n", mark, desc)
+	}
+	check(len(violations) == 0, "每个任期最多一个领导者，所有节点在同一下标应用相同的命令")
+	check(!cluster.checker.Applied("stale-write"), "被隔离的旧领导者接受的写入从未被应用")
+
+	var logs []string
+	for _, n := range cluster.nodes {
+		var cmds []string
+		for _, e := range n.Status().Log[:n.Status().LastApplied] {
+			cmds = append(cmds, e.Command)
+		}
+		logs = append(logs, strings.Join(cmds, ","))
+	}
+	sort.Strings(logs)
+	check(logs[0] == logs[len(logs)-1], "恢复后所有节点的已应用日志完全相同")
+
+	sent, dropped := cluster.net.Stats()
+	fmt.Printf("  网络共发送 %d 条消息，丢弃 %d 条\n", sent, dropped)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 被隔离的旧领导者还以为自己是领导者，但它的写入得不到多数确认，永远不会提交")
+	fmt.Println("2. 网络恢复后旧领导者收到更大任期的消息，退回跟随者，未提交的日志被新领导者的日志覆盖")
+	fmt.Println("3. 丢包和延迟只影响可用性（提交变慢、需要重试），不影响安全性：已应用的日志在各节点始终一致")
+	fmt.Println("4. 每个新领导者都会先追加一条no-op，这是提交之前任期遗留日志的关键")
+	fmt.Println("5. 客户端超时重试可能让同一命令出现两次，需要在命令中携带唯一ID去重")
+}
@@ -0,0 +1,388 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/08_saga/main.go
+主题：Saga编排与补偿
+
+本示例演示：
+1. 一个跨三个服务的分布式事务：预留库存 -> 扣款 -> 发货
+2. 任一步骤失败时，按相反顺序对已完成的步骤执行补偿（释放库存、退款）
+3. 步骤超时的结果是"未知"：扣款可能已经生效，所以超时的步骤也要补偿
+4. 补偿操作可能临时失败，需要重试，因此补偿必须是幂等的
+5. 用 pkg/pool 的任务池并发执行多个订单的Saga，结果按订单顺序输出
+6. 最后核对各服务的状态：每个订单要么三步全部生效，要么全部撤销
+
+核心概念：
+- Saga：没有全局锁和两阶段提交，每一步都是本地事务，用补偿代替回滚
+- 编排(orchestration)：由一个协调者按顺序调用各服务并负责补偿
+- 幂等：同一个订单重复执行补偿不会多退款、多释放库存
+- 中间状态对外可见：库存在预留之后、补偿之前是被占用的
+
+运行方式：go run ./cmd/hard/08_saga [-orders=20] [-concurrency=4]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/pool"
+)
+
+var (
+	ErrOutOfStock        = errors.New("库存不足")
+	ErrInsufficientFunds = errors.New("余额不足")
+	ErrTransient         = errors.New("服务暂时不可用")
+)
+
+// Order 一个订单
+type Order struct {
+	ID       string
+	User     string
+	Item     string
+	Quantity int
+	Amount   int
+}
+
+// chaos 各服务共用的随机故障和延迟
+type chaos struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (c *chaos) fail(rate float64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < rate
+}
+
+func (c *chaos) latency(max time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rng.Int63n(int64(max)))
+}
+
+var faults = &chaos{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// sleepCtx 模拟网络往返
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// InventoryService 库存服务，预留记录按订单ID保存，保证幂等
+type InventoryService struct {
+	mu       sync.Mutex
+	stock    map[string]int
+	reserved map[string]int // 订单ID -> 数量
+}
+
+func (s *InventoryService) Reserve(ctx context.Context, o *Order) error {
+	if err := sleepCtx(ctx, faults.latency(20*time.Millisecond)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.reserved[o.ID]; ok {
+		return nil
+	}
+	if s.stock[o.Item] < o.Quantity {
+		return fmt.Errorf("%w: %s 剩余 %d", ErrOutOfStock, o.Item, s.stock[o.Item])
+	}
+	s.stock[o.Item] -= o.Quantity
+	s.reserved[o.ID] = o.Quantity
+	return nil
+}
+
+func (s *InventoryService) Release(ctx context.Context, o *Order) error {
+	if faults.fail(0.2) {
+		return ErrTransient
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if qty, ok := s.reserved[o.ID]; ok {
+		s.stock[o.Item] += qty
+		delete(s.reserved, o.ID)
+	}
+	return nil
+}
+
+// PaymentService 支付服务
+type PaymentService struct {
+	mu       sync.Mutex
+	balances map[string]int
+	charges  map[string]int // 订单ID -> 金额
+}
+
+// Charge 扣款在服务端生效之后才返回响应；响应慢于调用方超时时，调用方看到的是超时，但钱已经扣了
+func (s *PaymentService) Charge(ctx context.Context, o *Order) error {
+	if faults.fail(0.1) {
+		return ErrTransient
+	}
+	s.mu.Lock()
+	if _, ok := s.charges[o.ID]; !ok {
+		if s.balances[o.User] < o.Amount {
+			s.mu.Unlock()
+			return fmt.Errorf("%w: %s 余额 %d", ErrInsufficientFunds, o.User, s.balances[o.User])
+		}
+		s.balances[o.User] -= o.Amount
+		s.charges[o.ID] = o.Amount
+	}
+	s.mu.Unlock()
+
+	return sleepCtx(ctx, faults.latency(60*time.Millisecond))
+}
+
+func (s *PaymentService) Refund(ctx context.Context, o *Order) error {
+	if faults.fail(0.3) {
+		return ErrTransient
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if amount, ok := s.charges[o.ID]; ok {
+		s.balances[o.User] += amount
+		delete(s.charges, o.ID)
+	}
+	return nil
+}
+
+// ShippingService 物流服务；发货是最后一步，不需要补偿
+type ShippingService struct {
+	mu        sync.Mutex
+	shipments map[string]bool
+}
+
+func (s *ShippingService) Ship(ctx context.Context, o *Order) error {
+	if err := sleepCtx(ctx, faults.latency(20*time.Millisecond)); err != nil {
+		return err
+	}
+	if faults.fail(0.15) {
+		return errors.New("物流公司拒绝揽收")
+	}
+	s.mu.Lock()
+	s.shipments[o.ID] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// Step Saga中的一步；Compensate为nil表示这一步不需要补偿
+type Step struct {
+	Name       string
+	Action     func(ctx context.Context, o *Order) error
+	Compensate func(ctx context.Context, o *Order) error
+}
+
+// Saga 按顺序执行步骤，失败时按相反顺序补偿
+type Saga struct {
+	Steps             []Step
+	StepTimeout       time.Duration
+	CompensateRetries int
+}
+
+// SagaResult 一个订单的执行结果
+type SagaResult struct {
+	Order       *Order
+	Completed   bool
+	FailedStep  string
+	Err         error
+	Compensated []string
+	Retries     int   // 补偿重试次数
+	CompErr     error // 补偿最终仍失败时需要人工介入
+}
+
+func (s *Saga) Execute(ctx context.Context, o *Order) SagaResult {
+	result := SagaResult{Order: o}
+	var done []Step
+
+	for _, step := range s.Steps {
+		stepCtx, cancel := context.WithTimeout(ctx, s.StepTimeout)
+		err := step.Action(stepCtx, o)
+		cancel()
+
+		if err == nil {
+			done = append(done, step)
+			continue
+		}
+
+		result.FailedStep, result.Err = step.Name, err
+		// 超时说明结果未知，这一步可能已经生效，也需要补偿
+		if errors.Is(err, context.DeadlineExceeded) {
+			done = append(done, step)
+		}
+		s.compensate(ctx, o, done, &result)
+		return result
+	}
+
+	result.Completed = true
+	return result
+}
+
+func (s *Saga) compensate(ctx context.Context, o *Order, done []Step, result *SagaResult) {
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		if step.Compensate == nil {
+			continue
+		}
+
+		var err error
+		for attempt := 0; attempt <= s.CompensateRetries; attempt++ {
+			if attempt > 0 {
+				result.Retries++
+				time.Sleep(time.Duration(attempt) * 5 * time.Millisecond)
+			}
+			if err = step.Compensate(ctx, o); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			result.CompErr = fmt.Errorf("补偿 %s 失败: %w", step.Name, err)
+			return
+		}
+		result.Compensated = append(result.Compensated, step.Name)
+	}
+}
+
+func clone(m map[string]int) map[string]int {
+	c := make(map[string]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func main() {
+	numOrders := flag.Int("orders", 20, "执行Saga的订单数量")
+	concurrency := flag.Int("concurrency", 4, "同时执行的订单数量")
+	demoflag.Parse()
+
+	fmt.Println("=== Saga编排与补偿演示 ===")
+
+	initialStock := map[string]int{"键盘": 10, "鼠标": 15}
+	initialBalance := map[string]int{"alice": 800, "bob": 400, "carol": 1000}
+
+	inventory := &InventoryService{stock: clone(initialStock), reserved: make(map[string]int)}
+	payment := &PaymentService{balances: clone(initialBalance), charges: make(map[string]int)}
+	shipping := &ShippingService{shipments: make(map[string]bool)}
+
+	saga := &Saga{
+		Steps: []Step{
+			{Name: "预留库存", Action: inventory.Reserve, Compensate: inventory.Release},
+			{Name: "扣款", Action: payment.Charge, Compensate: payment.Refund},
+			{Name: "发货", Action: shipping.Ship},
+		},
+		StepTimeout:       50 * time.Millisecond,
+		CompensateRetries: 5,
+	}
+
+	users := []string{"alice", "bob", "carol"}
+	items := []string{"键盘", "鼠标"}
+	var orders []*Order
+	for i := 1; i <= *numOrders; i++ {
+		orders = append(orders, &Order{
+			ID:       fmt.Sprintf("order-%02d", i),
+			User:     users[i%len(users)],
+			Item:     items[i%len(items)],
+			Quantity: 1 + i%3,
+			Amount:   50 * (1 + i%4),
+		})
+	}
+
+	// 用任务池并发执行Saga，默认4个订单同时进行
+	p := pool.New(*concurrency, len(orders), func(ctx context.Context, o *Order) (SagaResult, error) {
+		return saga.Execute(ctx, o), nil
+	})
+	for _, o := range orders {
+		p.Submit(o)
+	}
+
+	var results []SagaResult
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range p.Results() {
+			results = append(results, r.Value)
+		}
+	}()
+	p.Stop(context.Background())
+	<-done
+
+	fmt.Println()
+	completed, retries := 0, 0
+	for _, r := range results {
+		o := r.Order
+		line := fmt.Sprintf("%s %-5s %s×%d ¥%-3d ", o.ID, o.User, o.Item, o.Quantity, o.Amount)
+		if r.Completed {
+			completed++
+			fmt.Println(line + "✓ 完成")
+			continue
+		}
+		retries += r.Retries
+		comp := "无需补偿"
+		if len(r.Compensated) > 0 {
+			comp = "已补偿: " + strings.Join(r.Compensated, " -> ")
+		}
+		fmt.Printf("%s✗ %s失败(%v)，%s", line, r.FailedStep, r.Err, comp)
+		if r.Retries > 0 {
+			fmt.Printf("（重试 %d 次）", r.Retries)
+		}
+		if r.CompErr != nil {
+			fmt.Printf("，%v，需要人工处理", r.CompErr)
+		}
+		fmt.Println()
+	}
+
+	// 核对：每个订单要么全部生效，要么全部撤销；库存和余额守恒
+	fmt.Println("\n=== 一致性核对 ===")
+	consistent := true
+	spent := make(map[string]int)
+	used := make(map[string]int)
+	for _, r := range results {
+		o := r.Order
+		_, reserved := inventory.reserved[o.ID]
+		_, charged := payment.charges[o.ID]
+		shipped := shipping.shipments[o.ID]
+		if r.CompErr != nil {
+			continue // 补偿失败的订单等待人工处理，不参与核对
+		}
+		if r.Completed != reserved || r.Completed != charged || r.Completed != shipped {
+			consistent = false
+			fmt.Printf("  ✗ %s: 完成=%v 预留=%v 扣款=%v 发货=%v\n", o.ID, r.Completed, reserved, charged, shipped)
+		}
+		if r.Completed {
+			spent[o.User] += o.Amount
+			used[o.Item] += o.Quantity
+		}
+	}
+	for item, qty := range initialStock {
+		if inventory.stock[item]+used[item] != qty {
+			consistent = false
+			fmt.Printf("  ✗ %s 库存不守恒: 剩余 %d + 售出 %d != %d\n", item, inventory.stock[item], used[item], qty)
+		}
+	}
+	for user, balance := range initialBalance {
+		if payment.balances[user]+spent[user] != balance {
+			consistent = false
+			fmt.Printf("  ✗ %s 余额不守恒: 剩余 %d + 消费 %d != %d\n", user, payment.balances[user], spent[user], balance)
+		}
+	}
+	fmt.Printf("  完成 %d / %d 个订单，补偿重试 %d 次\n", completed, len(results), retries)
+	fmt.Printf("  剩余库存 %v，余额 %v\n", inventory.stock, payment.balances)
+	fmt.Printf("  检查结果: %v\n", consistent)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 失败的订单只补偿它之前已经完成的步骤，补偿顺序与执行顺序相反")
+	fmt.Println("2. 扣款超时的订单也会退款：超时时钱可能已经扣了，依靠Refund的幂等性安全地补偿")
+	fmt.Println("3. 补偿遇到临时故障会重试；重试耗尽时只能记录下来交给人工处理，不能假装成功")
+	fmt.Println("4. 并发执行时，被预留后又释放的库存会让其他订单短暂看到\"库存不足\"，这是Saga缺少隔离性的表现")
+}
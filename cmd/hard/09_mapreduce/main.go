@@ -0,0 +1,204 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/09_mapreduce/main.go
+主题：进程内MapReduce
+
+本示例演示：
+1. 用 pkg/mapreduce 的泛型框架实现单词计数：切分输入 -> 并发Map -> 按key shuffle -> 并发Reduce
+2. 模拟一台"慢机器"：某个map任务和某个reduce任务的首次执行特别慢（落后任务）
+3. 框架在有空闲槽位时为落后任务启动备份执行，先完成的结果生效，另一个被取消
+4. 模拟一次worker崩溃：任务失败后被重新排队执行
+5. 对比启用/关闭备份执行的总耗时，并与单线程计数结果核对
+
+核心概念：
+- 分区(Partition)：同一个key总是被分到同一个reduce任务
+- Shuffle：reduce开始前必须等待所有map任务完成，所以一个落后的map任务会拖慢整个作业
+- 备份执行(speculative execution)：要求任务是确定性的、无副作用的，重复执行才安全
+- 尝试信息通过context传递，任务函数可以据此感知自己是第几次、是否为备份
+
+运行方式：go run ./cmd/hard/09_mapreduce [-lines=2000]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/mapreduce"
+)
+
+var vocabulary = strings.Fields(`goroutine channel select mutex context deadline cancel
+	worker pool pipeline fan in out buffer close range wait group once atomic
+	scheduler leak race lock unlock signal broadcast timer ticker the a of and to`)
+
+// Line 一行输入，No用于模拟特定任务的故障
+type Line struct {
+	No   int
+	Text string
+}
+
+func generateCorpus(lines int) []Line {
+	r := rand.New(rand.NewSource(42))
+	corpus := make([]Line, lines)
+	for i := range corpus {
+		words := make([]string, 5+r.Intn(10))
+		for j := range words {
+			words[j] = vocabulary[r.Intn(len(vocabulary))]
+			if r.Intn(5) == 0 {
+				words[j] = strings.ToUpper(words[j][:1]) + words[j][1:] + ","
+			}
+		}
+		corpus[i] = Line{No: i, Text: strings.Join(words, " ")}
+	}
+	return corpus
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func normalize(word string) string {
+	return strings.ToLower(strings.Trim(word, ",.!?;:"))
+}
+
+const (
+	slowMapTask    = 3
+	crashMapTask   = 5
+	slowReduceTask = 1
+)
+
+func wordCountJob(backups bool, log func(format string, args ...interface{})) mapreduce.Job[Line, string, int, int] {
+	job := mapreduce.Job[Line, string, int, int]{
+		Map: func(ctx context.Context, line Line, emit func(string, int)) error {
+			a, _ := mapreduce.AttemptFrom(ctx)
+
+			// 每个任务处理第一行时决定是否模拟故障
+			if line.No%100 == 0 {
+				switch {
+				case a.Task == crashMapTask && a.Number == 1:
+					log("  map任务 %d 第 %d 次尝试: worker崩溃", a.Task, a.Number)
+					return errors.New("worker崩溃")
+				case a.Task == slowMapTask && !a.Backup:
+					log("  map任务 %d 第 %d 次尝试: 运行在慢机器上", a.Task, a.Number)
+				case a.Backup:
+					log("  map任务 %d 启动备份执行", a.Task)
+				}
+			}
+
+			delay := time.Millisecond
+			if a.Task == slowMapTask && !a.Backup {
+				delay = 15 * time.Millisecond
+			}
+			if err := sleepCtx(ctx, delay); err != nil {
+				return err
+			}
+			for _, w := range strings.Fields(line.Text) {
+				emit(normalize(w), 1)
+			}
+			return nil
+		},
+		Reduce: func(ctx context.Context, word string, counts []int) (int, error) {
+			a, _ := mapreduce.AttemptFrom(ctx)
+			if a.Task == slowReduceTask && !a.Backup {
+				if err := sleepCtx(ctx, 100*time.Millisecond); err != nil {
+					return 0, err
+				}
+			}
+			sum := 0
+			for _, c := range counts {
+				sum += c
+			}
+			return sum, nil
+		},
+		Mappers:   4,
+		Reducers:  4,
+		SplitSize: 100,
+	}
+	if backups {
+		job.StragglerTimeout = 300 * time.Millisecond
+	}
+	return job
+}
+
+func run(corpus []Line, backups bool) (map[string]int, mapreduce.Stats, time.Duration) {
+	log := func(format string, args ...interface{}) { fmt.Printf(format+"\n", args...) }
+	start := time.Now()
+	counts, stats, err := mapreduce.Run(context.Background(), wordCountJob(backups, log), corpus)
+	if err != nil {
+		fmt.Println("作业失败:", err)
+	}
+	return counts, stats, time.Since(start)
+}
+
+func main() {
+	lines := flag.Int("lines", 2000, "输入语料的行数")
+	demoflag.Parse()
+
+	fmt.Println("=== 进程内MapReduce演示：单词计数 ===")
+
+	corpus := generateCorpus(*lines)
+	fmt.Printf("输入 %d 行，每个map任务 %d 行，4个mapper，4个reducer\n", len(corpus), 100)
+	fmt.Printf("map任务 %d 在慢机器上运行，map任务 %d 首次执行会崩溃，reduce任务 %d 首次执行很慢\n",
+		slowMapTask, crashMapTask, slowReduceTask)
+
+	fmt.Println("\n1. 关闭备份执行:")
+	_, plainStats, plainTime := run(corpus, false)
+	fmt.Printf("  Map:    %v\n", plainStats.Map)
+	fmt.Printf("  Reduce: %v\n", plainStats.Reduce)
+
+	fmt.Println("\n2. 启用备份执行（任务运行超过300ms时启动备份）:")
+	counts, stats, elapsed := run(corpus, true)
+	fmt.Printf("  Map:    %v\n", stats.Map)
+	fmt.Printf("  Reduce: %v\n", stats.Reduce)
+
+	fmt.Printf("\n总耗时: 关闭备份 %v, 启用备份 %v\n", plainTime.Round(time.Millisecond), elapsed.Round(time.Millisecond))
+
+	// 与单线程计数核对
+	expected := make(map[string]int)
+	for _, line := range corpus {
+		for _, w := range strings.Fields(line.Text) {
+			expected[normalize(w)]++
+		}
+	}
+	match := len(expected) == len(counts)
+	for w, c := range expected {
+		if counts[w] != c {
+			match = false
+		}
+	}
+	fmt.Printf("与单线程计数核对: %d 个单词, 检查结果: %v\n", len(counts), match)
+
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	fmt.Println("出现次数最多的5个单词:")
+	for _, w := range words[:5] {
+		fmt.Printf("  %-10s %d\n", w, counts[w])
+	}
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 一个慢的map任务会拖住整个作业：reduce必须等所有map完成后才能开始")
+	fmt.Println("2. 备份执行让总耗时取决于较快的那次尝试，代价是多消耗一些计算资源")
+	fmt.Println("3. 崩溃的任务被重新排队，作业结果不受影响；重试和备份都要求Map/Reduce没有副作用")
+	fmt.Println("4. 被取消的尝试的输出会被丢弃，不会重复计数")
+}
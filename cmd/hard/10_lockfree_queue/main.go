@@ -0,0 +1,559 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/10_lockfree_queue/main.go
+主题：无锁多生产者多消费者有界队列
+
+本示例演示：
+1. pkg/lockfree 中基于CAS和槽位序号的MPMC有界队列
+2. 正确性检查：多个生产者写入不重复的值，多个消费者读出后核对每个值恰好出现一次
+3. 与互斥锁保护的环形缓冲区、带缓冲的channel在不同生产者/消费者数量下的性能对比
+   （main中直接计时；同样的对比也写成了基准测试：go test -bench Transfer ./pkg/lockfree）
+4. 安全内存回收：Michael-Scott无界链表队列把出队的节点放回空闲链表复用。
+   插桩让一个消费者在读到节点之后、CAS之前暂停，立即复用节点时发生ABA，一个值被读出两次、另一个丢失；
+   用pkg/lockfree.Reclaimer做纪元回收后，节点要等所有可能持有它的读者离开才复用
+5. 纪元回收的代价：一个Pin着不动的goroutine让所有待回收节点都积压下来
+
+核心概念：
+- CAS(CompareAndSwap)：只有当前值等于期望值时才写入，失败就重新读取再试
+- 槽位序号同时解决了两个问题：判断槽位状态（空/满）和避免ABA（每一圈序号都不同）
+- 原子Store/Load建立happens-before关系，写入value后再Store序号，读到序号的消费者一定能看到value
+- 伪共享：head和tail放在不同的缓存行，生产者和消费者互不干扰
+- 无锁不等于更快：竞争激烈时CAS失败重试也有代价，需要用基准测试说话
+- 有GC时只要不复用节点就不会有ABA；一旦自己管理节点（空闲链表、对象池），就需要纪元回收或hazard pointer
+
+运行方式：go run ./cmd/hard/10_lockfree_queue [-benchtime=1s]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/lockfree"
+)
+
+// Queue 三种实现共同的非阻塞接口
+type Queue[T any] interface {
+	TryEnqueue(v T) bool
+	TryDequeue() (T, bool)
+}
+
+// MutexQueue 互斥锁保护的环形缓冲区
+type MutexQueue[T any] struct {
+	mu         sync.Mutex
+	buf        []T
+	head, size int
+}
+
+func NewMutexQueue[T any](capacity int) *MutexQueue[T] {
+	return &MutexQueue[T]{buf: make([]T, capacity)}
+}
+
+func (q *MutexQueue[T]) TryEnqueue(v T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size == len(q.buf) {
+		return false
+	}
+	q.buf[(q.head+q.size)%len(q.buf)] = v
+	q.size++
+	return true
+}
+
+func (q *MutexQueue[T]) TryDequeue() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var zero T
+	if q.size == 0 {
+		return zero, false
+	}
+	v := q.buf[q.head]
+	q.buf[q.head] = zero
+	q.head = (q.head + 1) % len(q.buf)
+	q.size--
+	return v, true
+}
+
+// ChanQueue 带缓冲的channel，用select default实现非阻塞
+type ChanQueue[T any] struct {
+	ch chan T
+}
+
+func NewChanQueue[T any](capacity int) *ChanQueue[T] {
+	return &ChanQueue[T]{ch: make(chan T, capacity)}
+}
+
+func (q *ChanQueue[T]) TryEnqueue(v T) bool {
+	select {
+	case q.ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *ChanQueue[T]) TryDequeue() (T, bool) {
+	select {
+	case v := <-q.ch:
+		return v, true
+	default:
+		var zero T
+		return zero, false
+	}
+}
+
+// transfer 让producers个生产者共写入total个值，consumers个消费者读出，返回读出的值
+// 队列满或空时调用runtime.Gosched让出CPU，而不是忙等
+func transfer(q Queue[int], producers, consumers, total int) []int {
+	var produced, consumed int64
+	out := make([][]int, consumers)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v := int(atomic.AddInt64(&produced, 1)) - 1
+				if v >= total {
+					return
+				}
+				for !q.TryEnqueue(v) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for atomic.LoadInt64(&consumed) < int64(total) {
+				v, ok := q.TryDequeue()
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+				atomic.AddInt64(&consumed, 1)
+				out[c] = append(out[c], v)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	var all []int
+	for _, vs := range out {
+		all = append(all, vs...)
+	}
+	return all
+}
+
+// checkExactlyOnce 每个值恰好被读出一次
+func checkExactlyOnce(values []int, total int) bool {
+	seen := make([]bool, total)
+	for _, v := range values {
+		if v < 0 || v >= total || seen[v] {
+			return false
+		}
+		seen[v] = true
+	}
+	return len(values) == total
+}
+
+// measure 在d时间内一批一批地用transfer传递值，返回传递的总数和实际用时
+func measure(q Queue[int], producers, consumers int, d time.Duration) (n int, elapsed time.Duration) {
+	const batch = 10000
+	start := time.Now()
+	for elapsed < d {
+		transfer(q, producers, consumers, batch)
+		n += batch
+		elapsed = time.Since(start)
+	}
+	return n, elapsed
+}
+
+// ---------- 链表队列与安全内存回收 ----------
+
+type reclaimMode int
+
+const (
+	reclaimGC        reclaimMode = iota // 不复用节点，交给GC
+	reclaimImmediate                    // 出队后立即放回空闲链表（错误的做法）
+	reclaimEpoch                        // 纪元回收后再放回空闲链表
+)
+
+func (m reclaimMode) String() string {
+	switch m {
+	case reclaimGC:
+		return "不复用(GC)"
+	case reclaimImmediate:
+		return "立即复用"
+	default:
+		return "纪元回收"
+	}
+}
+
+type node struct {
+	value int
+	next  atomic.Pointer[node]
+}
+
+// LinkedQueue Michael-Scott无界无锁队列：head指向哑节点，出队时head前移，旧的哑节点被摘下
+type LinkedQueue struct {
+	head, tail atomic.Pointer[node]
+	mode       reclaimMode
+	reclaimer  *lockfree.Reclaimer[*node]
+
+	// 空闲链表本身用互斥锁实现，这里关注的是节点什么时候可以放回来
+	mu     sync.Mutex
+	free   []*node
+	allocs atomic.Int64 // 新分配的节点数
+}
+
+func NewLinkedQueue(mode reclaimMode) *LinkedQueue {
+	q := &LinkedQueue{mode: mode}
+	q.reclaimer = lockfree.NewReclaimer(q.release, 64)
+	dummy := &node{}
+	q.head.Store(dummy)
+	q.tail.Store(dummy)
+	return q
+}
+
+func (q *LinkedQueue) release(n *node) {
+	q.mu.Lock()
+	q.free = append(q.free, n)
+	q.mu.Unlock()
+}
+
+func (q *LinkedQueue) alloc(v int) *node {
+	q.mu.Lock()
+	var n *node
+	if k := len(q.free); k > 0 {
+		n, q.free = q.free[k-1], q.free[:k-1]
+	}
+	q.mu.Unlock()
+	if n == nil {
+		n = &node{}
+		q.allocs.Add(1)
+	}
+	n.value = v
+	n.next.Store(nil)
+	return n
+}
+
+// Handle 每个goroutine一个，持有自己的纪元Guard
+type Handle struct {
+	q     *LinkedQueue
+	guard *lockfree.Guard[*node]
+	// afterRead 插桩：出队时读到值之后、CAS之前调用
+	afterRead func()
+}
+
+func (q *LinkedQueue) Handle() *Handle {
+	h := &Handle{q: q}
+	if q.mode == reclaimEpoch {
+		h.guard = q.reclaimer.Register()
+	}
+	return h
+}
+
+func (h *Handle) pin() {
+	if h.guard != nil {
+		h.guard.Pin()
+	}
+}
+
+func (h *Handle) unpin() {
+	if h.guard != nil {
+		h.guard.Unpin()
+	}
+}
+
+// Close 注销Guard，没回收完的节点交给Reclaimer
+func (h *Handle) Close() {
+	if h.guard != nil {
+		h.guard.Unregister()
+	}
+}
+
+func (h *Handle) Enqueue(v int) {
+	q := h.q
+	n := q.alloc(v)
+	h.pin()
+	defer h.unpin()
+	for {
+		tail := q.tail.Load()
+		next := tail.next.Load()
+		if tail != q.tail.Load() {
+			continue
+		}
+		if next != nil {
+			q.tail.CompareAndSwap(tail, next) // 帮助落后的tail前移
+			continue
+		}
+		if tail.next.CompareAndSwap(nil, n) {
+			q.tail.CompareAndSwap(tail, n)
+			return
+		}
+	}
+}
+
+func (h *Handle) Dequeue() (int, bool) {
+	q := h.q
+	h.pin()
+	defer h.unpin()
+	for {
+		head := q.head.Load()
+		tail := q.tail.Load()
+		next := head.next.Load()
+		if head != q.head.Load() {
+			continue
+		}
+		if next == nil {
+			return 0, false
+		}
+		if head == tail {
+			q.tail.CompareAndSwap(tail, next)
+			continue
+		}
+		v := next.value
+		if h.afterRead != nil {
+			h.afterRead()
+		}
+		// head还是原来那个地址就认为没人动过：如果这期间head被摘下、复用、又回到了head的位置，CAS照样成功
+		if q.head.CompareAndSwap(head, next) {
+			switch q.mode {
+			case reclaimImmediate:
+				q.release(head)
+			case reclaimEpoch:
+				h.guard.Retire(head)
+			}
+			return v, true
+		}
+	}
+}
+
+// abaScenario 固定的交错：A读到队首的值1后暂停，B出队1、入队2、出队2、入队3，然后A继续
+func abaScenario(mode reclaimMode) (gotA int, gotB []int, left []int, allocs int64) {
+	q := NewLinkedQueue(mode)
+	a, b := q.Handle(), q.Handle()
+	defer a.Close()
+	defer b.Close()
+	b.Enqueue(1)
+
+	paused, resume := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	a.afterRead = func() {
+		once.Do(func() {
+			close(paused)
+			<-resume
+		})
+	}
+	result := make(chan int)
+	go func() {
+		v, _ := a.Dequeue()
+		result <- v
+	}()
+
+	<-paused
+	v, _ := b.Dequeue()
+	gotB = append(gotB, v)
+	b.Enqueue(2)
+	v, _ = b.Dequeue()
+	gotB = append(gotB, v)
+	b.Enqueue(3)
+	close(resume)
+	gotA = <-result
+
+	for {
+		v, ok := b.Dequeue()
+		if !ok {
+			break
+		}
+		left = append(left, v)
+	}
+	return gotA, gotB, left, q.allocs.Load()
+}
+
+// linkedTransfer 与transfer相同的压力测试，队列中最多约1024个值；stall为true时另有一个goroutine全程Pin着不动
+func linkedTransfer(q *LinkedQueue, producers, consumers, total int, stall bool) (values []int, peakPending int64) {
+	var produced, consumed int64
+	out := make([][]int, consumers)
+
+	var staller *Handle
+	if stall {
+		staller = q.Handle()
+		staller.pin()
+	}
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := q.Handle()
+			defer h.Close()
+			for {
+				v := int(atomic.AddInt64(&produced, 1)) - 1
+				if v >= total {
+					return
+				}
+				// 链表队列是无界的，限制生产者最多领先1024个值，否则队列本身的长度就决定了节点数
+				for int64(v)-atomic.LoadInt64(&consumed) > 1024 {
+					runtime.Gosched()
+				}
+				h.Enqueue(v)
+			}
+		}()
+	}
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			h := q.Handle()
+			defer h.Close()
+			// 每隔几次在读到值之后让出CPU，让其他goroutine插进"读到节点"和"CAS"之间
+			n := 0
+			h.afterRead = func() {
+				if n++; n%7 == 0 {
+					runtime.Gosched()
+				}
+			}
+			for atomic.LoadInt64(&consumed) < int64(total) {
+				v, ok := h.Dequeue()
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+				atomic.AddInt64(&consumed, 1)
+				out[c] = append(out[c], v)
+				if p := q.reclaimer.Pending(); p > atomic.LoadInt64(&peakPending) {
+					atomic.StoreInt64(&peakPending, p) // 只是观察用的近似峰值
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	if staller != nil {
+		staller.unpin()
+		staller.Close()
+	}
+	for _, vs := range out {
+		values = append(values, vs...)
+	}
+	return values, peakPending
+}
+
+// pad 按显示宽度补齐空格，中文字符占两列
+func pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return s + strings.Repeat(" ", max(width-w, 0))
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+func demoReclamation() {
+	fmt.Println("  固定交错：A读到值1后暂停；B出队1、入队2、出队2、入队3；A继续")
+	for _, mode := range []reclaimMode{reclaimImmediate, reclaimEpoch} {
+		gotA, gotB, left, allocs := abaScenario(mode)
+		fmt.Printf("  %s A读出 %d，B读出 %v，队列剩余 %v，分配节点 %d 个\n", pad(mode.String(), 10), gotA, gotB, left, allocs)
+		all := append(append([]int{gotA}, gotB...), left...)
+		ok := checkExactlyOnce(minusOne(all), 3)
+		if mode == reclaimImmediate {
+			check("立即复用：A的CAS看到的head地址没变（ABA），读出的1已经被B读过，3丢失", !ok)
+		} else {
+			check("纪元回收：A还Pin着，B摘下的节点不会被复用，A的CAS失败后重试读到3", ok)
+		}
+	}
+
+	fmt.Println("\n  压力测试：4个生产者、4个消费者传递100000个值，队列中最多约1024个值")
+	for _, mode := range []reclaimMode{reclaimGC, reclaimEpoch} {
+		q := NewLinkedQueue(mode)
+		values, _ := linkedTransfer(q, 4, 4, 100000, false)
+		r := q.reclaimer
+		fmt.Printf("  %s 读出 %d 个值，分配节点 %d 个，Retire %d，已回收 %d，纪元 %d\n",
+			pad(mode.String(), 10), len(values), q.allocs.Load(), r.Retired(), r.Reclaimed(), r.Epoch())
+		check(fmt.Sprintf("%s: 每个值恰好一次", mode), checkExactlyOnce(values, 100000))
+		if mode == reclaimEpoch {
+			check("纪元回收：节点不断被复用，分配的节点数远小于值的数量", q.allocs.Load() < 100000/10)
+		}
+	}
+
+	q := NewLinkedQueue(reclaimEpoch)
+	values, peak := linkedTransfer(q, 4, 4, 100000, true)
+	fmt.Printf("  有一个goroutine全程Pin着：待回收节点最多 %d 个，分配节点 %d 个\n", peak, q.allocs.Load())
+	check("停滞的读者让纪元无法推进，几乎每个出队的节点都积压着，退化成每次都分配", checkExactlyOnce(values, 100000) && q.allocs.Load() > 100000*9/10)
+}
+
+// minusOne 把1..n的值转换成0..n-1，方便复用checkExactlyOnce
+func minusOne(vs []int) []int {
+	out := make([]int, len(vs))
+	for i, v := range vs {
+		out[i] = v - 1
+	}
+	return out
+}
+
+func main() {
+	benchtime := flag.Duration("benchtime", time.Second, "每个基准测试的运行时间")
+	demoflag.Parse()
+
+	fmt.Println("=== 无锁MPMC有界队列演示 ===")
+	fmt.Printf("GOMAXPROCS=%d\n", runtime.GOMAXPROCS(0))
+
+	impls := []struct {
+		name string
+		new  func(capacity int) Queue[int]
+	}{
+		{"lockfree.MPMC", func(n int) Queue[int] { return lockfree.NewMPMC[int](n) }},
+		{"Mutex环形缓冲", func(n int) Queue[int] { return NewMutexQueue[int](n) }},
+		{"带缓冲channel", func(n int) Queue[int] { return NewChanQueue[int](n) }},
+	}
+
+	fmt.Println("\n1. 正确性检查: 4个生产者、4个消费者传递100000个值，容量只有8，频繁出现满和空")
+	for _, impl := range impls {
+		values := transfer(impl.new(8), 4, 4, 100000)
+		fmt.Printf("  %-14s 读出 %d 个值, 每个值恰好一次: %v\n", impl.name, len(values), checkExactlyOnce(values, 100000))
+	}
+
+	fmt.Println("\n2. 基准测试（每次操作 = 一个值从生产者到消费者）:")
+	for _, shape := range [][2]int{{1, 1}, {4, 4}, {8, 1}, {1, 8}} {
+		fmt.Printf("  %d个生产者 / %d个消费者:\n", shape[0], shape[1])
+		for _, impl := range impls {
+			n, elapsed := measure(impl.new(1024), shape[0], shape[1], *benchtime)
+			fmt.Printf("    %-14s %10d 次  %8.1f ns/op\n", impl.name, n, float64(elapsed.Nanoseconds())/float64(n))
+		}
+	}
+
+	fmt.Println("\n3. 链表队列与安全内存回收:")
+	demoReclamation()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 无锁队列在满和空的边界上依然正确，靠的是槽位序号而不是锁")
+	fmt.Println("2. 单核机器上goroutine不会真正同时运行，三种实现的差距主要来自每次操作的固定开销")
+	fmt.Println("3. 多核且竞争激烈时，互斥锁会让goroutine休眠和唤醒，CAS只是失败重试，差距会拉开")
+	fmt.Println("4. channel还提供了阻塞等待、close和select，无锁队列要自己处理满/空时的等待策略")
+	fmt.Println("5. 用 go run -race 运行可以确认：只要value的读写被序号的原子操作隔开，就不存在数据竞争")
+	fmt.Println("6. 复用节点时CAS只比较地址，地址相同不代表还是原来那个节点；纪元回收保证有读者可能持有旧指针时节点不会被复用")
+	fmt.Println("7. 纪元回收的读者开销只有Pin/Unpin两次原子写，但一个停滞的读者会让所有节点都无法回收")
+}
@@ -0,0 +1,199 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/11_disruptor/main.go
+主题：Disruptor风格的环形缓冲区
+
+本示例演示：
+1. pkg/disruptor 的单生产者多消费者环形缓冲区：预分配槽位、序号发布、每个消费者看到全部事件
+2. 三个消费者并行处理同一事件流：记录延迟、累加求和、统计批次大小
+3. 两种等待策略：忙等（BusySpin）和阻塞等待（Blocking）
+4. 与"每个消费者一个带缓冲channel"的实现对比吞吐量和端到端延迟
+   - 满速场景：生产者尽可能快地发布，比较吞吐量
+   - 限速场景：每100µs发布一个事件，比较消费者被唤醒的延迟
+
+核心概念：
+- 序号(Sequence)：生产者的cursor表示已发布到哪里，消费者的序号表示已处理到哪里
+- 生产者不能超过最慢消费者一整圈，否则会覆盖还没被读取的事件
+- 批处理：消费者落后时一次处理多个事件，只更新一次序号，摊薄同步开销
+- 忙等省去了休眠和唤醒，延迟最低，代价是等待时占满CPU；单核上它甚至会和生产者抢处理器
+
+运行方式：go run ./cmd/hard/11_disruptor [-events=500000]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/disruptor"
+)
+
+// Event 预分配在环形缓冲区中的事件
+type Event struct {
+	Value     int64
+	Published int64 // 发布时间，UnixNano
+}
+
+// runResult 一次运行的统计
+type runResult struct {
+	elapsed   time.Duration
+	latencies []int64
+	sum       int64
+	batches   int64
+}
+
+func (r runResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), r.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return time.Duration(sorted[int(float64(len(sorted)-1)*p)])
+}
+
+func (r runResult) report(name string, events int) {
+	avgBatch := float64(events) / float64(max(r.batches, 1))
+	fmt.Printf("  %-18s %10.0f 事件/秒  延迟 p50=%-9v p99=%-9v 平均批次 %.1f\n",
+		name, float64(events)/r.elapsed.Seconds(),
+		r.percentile(0.5).Round(100*time.Nanosecond), r.percentile(0.99).Round(100*time.Nanosecond), avgBatch)
+}
+
+// runRing 用环形缓冲区传递events个事件，interval>0时每个事件之间间隔interval
+func runRing(wait disruptor.WaitStrategy, events int, interval time.Duration) runResult {
+	ring := disruptor.New[Event](1024, wait)
+	res := runResult{latencies: make([]int64, 0, events)}
+
+	consumers := []*disruptor.Consumer[Event]{
+		ring.NewConsumer(func(seq int64, e *Event, endOfBatch bool) {
+			res.latencies = append(res.latencies, time.Now().UnixNano()-e.Published)
+		}),
+		ring.NewConsumer(func(seq int64, e *Event, endOfBatch bool) {
+			res.sum += e.Value
+		}),
+		ring.NewConsumer(func(seq int64, e *Event, endOfBatch bool) {
+			if endOfBatch {
+				res.batches++
+			}
+		}),
+	}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, c := range consumers {
+		wg.Add(1)
+		go func(c *disruptor.Consumer[Event]) {
+			defer wg.Done()
+			c.Run()
+		}(c)
+	}
+
+	for i := 0; i < events; i++ {
+		ring.Publish(func(e *Event) {
+			e.Value = int64(i)
+			e.Published = time.Now().UnixNano()
+		})
+		if interval > 0 {
+			time.Sleep(interval)
+		}
+	}
+	ring.Close()
+	wg.Wait()
+	res.elapsed = time.Since(start)
+	return res
+}
+
+// runChannels 每个消费者一个带缓冲channel，生产者把每个事件发送给所有channel
+func runChannels(events int, interval time.Duration) runResult {
+	res := runResult{latencies: make([]int64, 0, events)}
+	chans := make([]chan Event, 3)
+	for i := range chans {
+		chans[i] = make(chan Event, 1024)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for e := range chans[0] {
+			res.latencies = append(res.latencies, time.Now().UnixNano()-e.Published)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for e := range chans[1] {
+			res.sum += e.Value
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		// channel没有批次的概念，每个事件都是一次独立的接收
+		for range chans[2] {
+			res.batches++
+		}
+	}()
+
+	start := time.Now()
+	for i := 0; i < events; i++ {
+		e := Event{Value: int64(i), Published: time.Now().UnixNano()}
+		for _, ch := range chans {
+			ch <- e
+		}
+		if interval > 0 {
+			time.Sleep(interval)
+		}
+	}
+	for _, ch := range chans {
+		close(ch)
+	}
+	wg.Wait()
+	res.elapsed = time.Since(start)
+	return res
+}
+
+func main() {
+	events := flag.Int("events", 500000, "满速场景的事件数")
+	demoflag.Parse()
+
+	fmt.Println("=== Disruptor风格环形缓冲区演示 ===")
+	fmt.Printf("GOMAXPROCS=%d, 3个消费者，每个消费者看到全部事件\n", runtime.GOMAXPROCS(0))
+
+	expectedSum := int64(*events) * int64(*events-1) / 2
+
+	fmt.Printf("\n1. 满速发布 %d 个事件:\n", *events)
+	results := []struct {
+		name string
+		run  func() runResult
+	}{
+		{"ring + BusySpin", func() runResult { return runRing(disruptor.BusySpin{}, *events, 0) }},
+		{"ring + Blocking", func() runResult { return runRing(disruptor.NewBlocking(), *events, 0) }},
+		{"channels", func() runResult { return runChannels(*events, 0) }},
+	}
+	allCorrect := true
+	for _, r := range results {
+		res := r.run()
+		res.report(r.name, *events)
+		if res.sum != expectedSum || len(res.latencies) != *events {
+			allCorrect = false
+		}
+	}
+	fmt.Printf("  每个消费者都收到全部事件且求和正确: %v\n", allCorrect)
+
+	const paced = 2000
+	interval := 100 * time.Microsecond
+	fmt.Printf("\n2. 限速发布 %d 个事件，间隔 %v（消费者大部分时间在等待）:\n", paced, interval)
+	runRing(disruptor.BusySpin{}, paced, interval).report("ring + BusySpin", paced)
+	runRing(disruptor.NewBlocking(), paced, interval).report("ring + Blocking", paced)
+	runChannels(paced, interval).report("channels", paced)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 满速时消费者经常落后，一次处理一整批事件，平均批次越大，每个事件分摊的同步开销越小")
+	fmt.Println("2. channel方案中生产者要把每个事件发送3次，每次发送都要加锁；环形缓冲区只发布一次序号")
+	fmt.Println("3. 限速时忙等的消费者一直在检查序号，事件一发布就能看到；阻塞等待需要唤醒goroutine")
+	fmt.Println("4. 忙等的消费者会与生产者争抢处理器（这里每自旋64次让出一次），生产环境只在消费者独占CPU核时使用忙等")
+}
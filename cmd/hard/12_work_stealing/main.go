@@ -0,0 +1,245 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/12_work_stealing/main.go
+主题：工作窃取调度器与fork/join
+
+本示例演示：
+1. pkg/workstealing 的调度器：每个工作者一个双端队列，本地LIFO执行，空闲时随机窃取
+2. fork/join：任务用SubmitChild派生子任务，再用Join等待；等待期间工作者继续干活
+3. 共享队列的线程池上做阻塞式join为什么会死锁
+4. 并行快速排序：工作窃取调度器 vs 共享队列的WorkerPool（pkg/workerpool）vs 串行sort.Ints
+   （main中直接计时；同样的对比也写成了基准测试：go test -bench Quicksort ./pkg/workstealing）
+
+核心概念：
+- 递归任务天然会不断产生子任务，放进全局共享队列意味着每次提交和获取都要竞争同一把锁
+- 本地LIFO：刚分出来的子数组还在缓存中，立刻处理它局部性最好
+- 窃取FIFO：队列顶部是最早分出来的、最大的子数组，偷一次就能拿到一大块工作，窃取次数少
+- 帮助式join：等待子任务的工作者自己去执行别的任务，工作者数量固定也不会因为都在等待而卡死
+
+运行方式：go run ./cmd/hard/12_work_stealing [-n=200000] [-benchtime=1s]
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/workerpool"
+	"github.com/klsakura/day1/pkg/workstealing"
+)
+
+// cutoff 子数组小于这个长度时直接串行排序，任务太小时调度开销会超过并行收益
+const cutoff = 2048
+
+// newPool 共享队列的线程池：仓库中的WorkerPool（pkg/workerpool），所有工作者从同一个队列取任务。
+// 队列不限容量，工作者在执行任务时可以继续提交任务而不会因为队列满而阻塞
+func newPool(workers int) *workerpool.WorkerPool {
+	p := workerpool.New(workerpool.Config{Workers: workers})
+	p.Start()
+	return p
+}
+
+// poolTask 把fn包装成WorkerPool的任务，不使用Data和结果
+func poolTask(fn func()) workerpool.Task {
+	return workerpool.Task{Process: func(context.Context, []int) (int, error) {
+		fn()
+		return 0, nil
+	}}
+}
+
+// partition 以中间元素为基准划分，返回基准的最终位置
+func partition(a []int) int {
+	mid := len(a) / 2
+	a[mid], a[len(a)-1] = a[len(a)-1], a[mid]
+	pivot := a[len(a)-1]
+	i := 0
+	for j := 0; j < len(a)-1; j++ {
+		if a[j] < pivot {
+			a[i], a[j] = a[j], a[i]
+			i++
+		}
+	}
+	a[i], a[len(a)-1] = a[len(a)-1], a[i]
+	return i
+}
+
+// quicksortForkJoin 左半部分作为子任务派生出去，右半部分自己处理，最后Join
+func quicksortForkJoin(ctx *workstealing.Ctx, a []int) {
+	if len(a) <= cutoff {
+		sort.Ints(a)
+		return
+	}
+	p := partition(a)
+	left := ctx.SubmitChild(func(ctx *workstealing.Ctx) { quicksortForkJoin(ctx, a[:p]) })
+	quicksortForkJoin(ctx, a[p+1:])
+	ctx.Join(left)
+}
+
+// quicksortPool WorkerPool上不能阻塞等待子任务，只能把两半都提交出去，用WaitGroup统计全部完成
+func quicksortPool(pool *workerpool.WorkerPool, wg *sync.WaitGroup, a []int) {
+	defer wg.Done()
+	if len(a) <= cutoff {
+		sort.Ints(a)
+		return
+	}
+	p := partition(a)
+	wg.Add(2)
+	pool.Submit(poolTask(func() { quicksortPool(pool, wg, a[:p]) }))
+	pool.Submit(poolTask(func() { quicksortPool(pool, wg, a[p+1:]) }))
+}
+
+// measure 反复调用fn直到用时达到d，返回调用次数和实际用时
+func measure(fn func(), d time.Duration) (n int, elapsed time.Duration) {
+	start := time.Now()
+	for elapsed < d {
+		fn()
+		n++
+		elapsed = time.Since(start)
+	}
+	return n, elapsed
+}
+
+func randomInts(n int, seed int64) []int {
+	rng := rand.New(rand.NewSource(seed))
+	a := make([]int, n)
+	for i := range a {
+		a[i] = rng.Intn(n * 10)
+	}
+	return a
+}
+
+// demoJoinDeadlock 2个工作者的共享队列池上，父任务阻塞等待子任务：
+// 两个工作者都被等待中的父任务占住，子任务排在队列里永远没人执行
+func demoJoinDeadlock() {
+	fmt.Println("\n1. 阻塞式join与帮助式join（2个工作者，3层二叉递归）:")
+
+	pool := newPool(2)
+	var spawn func(depth int) <-chan struct{}
+	spawn = func(depth int) <-chan struct{} {
+		done := make(chan struct{})
+		pool.Submit(poolTask(func() {
+			if depth > 0 {
+				l, r := spawn(depth-1), spawn(depth-1)
+				<-l
+				<-r
+			}
+			close(done)
+		}))
+		return done
+	}
+	select {
+	case <-spawn(3):
+		fmt.Println("  共享队列 + 阻塞等待: 完成")
+	case <-time.After(500 * time.Millisecond):
+		// 卡住的工作者无法回收，演示中直接放弃这个池
+		fmt.Println("  共享队列 + 阻塞等待: 500ms后仍未完成，工作者都在等待子任务，死锁")
+	}
+
+	s := workstealing.New(2)
+	var tree func(ctx *workstealing.Ctx, depth int)
+	var leaves int64
+	var mu sync.Mutex
+	tree = func(ctx *workstealing.Ctx, depth int) {
+		if depth == 0 {
+			mu.Lock()
+			leaves++
+			mu.Unlock()
+			return
+		}
+		l := ctx.SubmitChild(func(ctx *workstealing.Ctx) { tree(ctx, depth-1) })
+		r := ctx.SubmitChild(func(ctx *workstealing.Ctx) { tree(ctx, depth-1) })
+		ctx.Join(l, r)
+	}
+	s.Submit(func(ctx *workstealing.Ctx) { tree(ctx, 3) })
+	s.Wait()
+	s.Close()
+	fmt.Printf("  工作窃取 + 帮助式Join: 完成，叶子任务 %d 个\n", leaves)
+}
+
+func main() {
+	n := flag.Int("n", 200000, "排序的元素个数")
+	benchtime := flag.Duration("benchtime", time.Second, "每个基准测试的运行时间")
+	demoflag.Parse()
+
+	workers := max(runtime.GOMAXPROCS(0), 4)
+	fmt.Println("=== 工作窃取调度器演示 ===")
+	fmt.Printf("GOMAXPROCS=%d, 工作者数=%d, 串行阈值=%d\n", runtime.GOMAXPROCS(0), workers, cutoff)
+
+	demoJoinDeadlock()
+
+	fmt.Printf("\n2. 并行快速排序 %d 个元素，检查结果并查看各工作者统计:\n", *n)
+	data := randomInts(*n, 1)
+	s := workstealing.New(workers)
+	a := append([]int(nil), data...)
+	s.Submit(func(ctx *workstealing.Ctx) { quicksortForkJoin(ctx, a) })
+	s.Wait()
+	stats := s.Stats()
+	s.Close()
+
+	var total, stolen int64
+	for i, st := range stats {
+		fmt.Printf("  工作者%d: 执行 %4d 个任务，其中窃取 %3d 个\n", i, st.Executed, st.Stolen)
+		total += st.Executed
+		stolen += st.Stolen
+	}
+	fmt.Printf("  共 %d 个任务，窃取 %d 次 (%.1f%%)，结果有序: %v\n",
+		total, stolen, 100*float64(stolen)/float64(max(total, 1)), sort.IntsAreSorted(a))
+
+	b := append([]int(nil), data...)
+	pool := newPool(workers)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	pool.Submit(poolTask(func() { quicksortPool(pool, &wg, b) }))
+	wg.Wait()
+	pool.Close()
+	pool.Wait()
+	fmt.Printf("  WorkerPool结果有序: %v\n", sort.IntsAreSorted(b))
+
+	fmt.Println("\n3. 基准测试（每次操作 = 复制并排序一次全部数据）:")
+	buf := make([]int, *n)
+	ws := workstealing.New(workers)
+	shared := newPool(workers)
+	benches := []struct {
+		name string
+		fn   func()
+	}{
+		{"串行sort.Ints", func() {
+			copy(buf, data)
+			sort.Ints(buf)
+		}},
+		{"工作窃取fork/join", func() {
+			copy(buf, data)
+			ws.Submit(func(ctx *workstealing.Ctx) { quicksortForkJoin(ctx, buf) })
+			ws.Wait()
+		}},
+		{"共享队列WorkerPool", func() {
+			copy(buf, data)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			shared.Submit(poolTask(func() { quicksortPool(shared, &wg, buf) }))
+			wg.Wait()
+		}},
+	}
+	for _, bench := range benches {
+		n, elapsed := measure(bench.fn, *benchtime)
+		fmt.Printf("  %-18s %6d 次  %10v/op\n", bench.name, n, (elapsed / time.Duration(n)).Round(time.Microsecond))
+	}
+	ws.Close()
+	shared.Close()
+	shared.Wait()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 阻塞式join在固定大小的线程池里会把工作者全部占住；帮助式Join边等边干活，不会死锁")
+	fmt.Println("2. 窃取次数远少于任务总数：大部分任务在产生它的工作者上以LIFO顺序执行")
+	fmt.Println("3. 每次窃取拿走的是队列顶部最早分出的大块子数组，偷到后又会在本地继续拆分")
+	fmt.Println("4. 共享队列的每次提交和获取都要竞争同一把锁；工作窃取只在窃取时才访问别人的队列")
+	fmt.Println("5. 单核机器上并行版本不会比串行快，差距只反映调度开销；多核时工作窃取的优势才明显")
+}
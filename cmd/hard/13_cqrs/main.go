@@ -0,0 +1,501 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/13_cqrs/main.go
+主题：CQRS与事件溯源
+
+本示例演示：
+1. 写端：一个命令处理goroutine串行处理开户、存款、取款命令，校验后把事件追加到 pkg/eventstore
+2. 读端：多个投影器(projector)各自订阅事件流，并发地维护自己的读模型（余额表、统计表）
+3. 最终一致：命令返回后读模型可能还没更新，用投影器的检查点实现"读己之写"
+4. 追赶订阅：系统运行一段时间后新增一个投影器，它先重放历史事件，再无缝切换到实时事件
+5. 乐观并发：带着过期的流版本写入会被事件存储拒绝
+6. 最后核对：各读模型与从事件日志完整重放的结果一致
+
+核心概念：
+- CQRS：写模型负责校验业务规则，读模型按查询需要组织数据，两者通过事件连接
+- 事件溯源：事件日志是唯一的事实来源，账户当前状态由重放事件得到
+- 检查点：投影器记录自己处理到的全局位置，重启或新建时从这里继续
+- 单写者：所有命令经过同一个goroutine，同一账户的校验和写入不会交错
+
+运行方式：go run ./cmd/hard/13_cqrs [-accounts=20] [-clients=8] [-commands=150]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/eventstore"
+)
+
+var (
+	ErrAccountExists     = errors.New("账户已存在")
+	ErrAccountNotFound   = errors.New("账户不存在")
+	ErrInsufficientFunds = errors.New("余额不足")
+)
+
+// 事件数据
+type AccountOpened struct{ Owner string }
+type MoneyDeposited struct{ Amount int }
+type MoneyWithdrawn struct{ Amount int }
+
+// 命令
+type OpenAccount struct{ ID, Owner string }
+type Deposit struct {
+	ID     string
+	Amount int
+}
+type Withdraw struct {
+	ID     string
+	Amount int
+}
+
+// CommandResult 命令处理结果，Position为写入的最后一个事件的全局位置
+type CommandResult struct {
+	Position int64
+	Err      error
+}
+
+type commandRequest struct {
+	cmd   any
+	reply chan CommandResult
+}
+
+// Account 写端的聚合，由重放账户流中的事件得到
+type Account struct {
+	Exists  bool
+	Balance int
+}
+
+func (a *Account) apply(e eventstore.Event) {
+	switch d := e.Data.(type) {
+	case AccountOpened:
+		a.Exists = true
+	case MoneyDeposited:
+		a.Balance += d.Amount
+	case MoneyWithdrawn:
+		a.Balance -= d.Amount
+	}
+}
+
+// CommandHandler 写端：唯一的写入goroutine
+type CommandHandler struct {
+	store    *eventstore.Store
+	requests chan commandRequest
+	rejected atomic.Int64
+}
+
+func NewCommandHandler(store *eventstore.Store) *CommandHandler {
+	return &CommandHandler{store: store, requests: make(chan commandRequest)}
+}
+
+// Send 发送命令并等待结果
+func (h *CommandHandler) Send(ctx context.Context, cmd any) CommandResult {
+	req := commandRequest{cmd: cmd, reply: make(chan CommandResult, 1)}
+	select {
+	case h.requests <- req:
+	case <-ctx.Done():
+		return CommandResult{Err: ctx.Err()}
+	}
+	select {
+	case r := <-req.reply:
+		return r
+	case <-ctx.Done():
+		return CommandResult{Err: ctx.Err()}
+	}
+}
+
+func (h *CommandHandler) Run(ctx context.Context) {
+	for {
+		select {
+		case req := <-h.requests:
+			r := h.handle(req.cmd)
+			if r.Err != nil {
+				h.rejected.Add(1)
+			}
+			req.reply <- r
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// load 重放账户流，返回账户状态和流版本
+func (h *CommandHandler) load(id string) (Account, int) {
+	events, version := h.store.Load("account-" + id)
+	var a Account
+	for _, e := range events {
+		a.apply(e)
+	}
+	return a, version
+}
+
+func (h *CommandHandler) handle(cmd any) CommandResult {
+	var id string
+	var event eventstore.NewEvent
+	switch c := cmd.(type) {
+	case OpenAccount:
+		if a, _ := h.load(c.ID); a.Exists {
+			return CommandResult{Err: ErrAccountExists}
+		}
+		id, event = c.ID, eventstore.NewEvent{Type: "AccountOpened", Data: AccountOpened{Owner: c.Owner}}
+	case Deposit:
+		if a, _ := h.load(c.ID); !a.Exists {
+			return CommandResult{Err: ErrAccountNotFound}
+		}
+		id, event = c.ID, eventstore.NewEvent{Type: "MoneyDeposited", Data: MoneyDeposited{Amount: c.Amount}}
+	case Withdraw:
+		a, _ := h.load(c.ID)
+		if !a.Exists {
+			return CommandResult{Err: ErrAccountNotFound}
+		}
+		if a.Balance < c.Amount {
+			return CommandResult{Err: ErrInsufficientFunds}
+		}
+		id, event = c.ID, eventstore.NewEvent{Type: "MoneyWithdrawn", Data: MoneyWithdrawn{Amount: c.Amount}}
+	default:
+		return CommandResult{Err: fmt.Errorf("未知命令 %T", cmd)}
+	}
+
+	// 单写者下版本不会冲突，这里仍然带上期望版本，多个写者时由事件存储兜底
+	_, version := h.store.Load("account-" + id)
+	pos, err := h.store.Append("account-"+id, version, event)
+	return CommandResult{Position: pos, Err: err}
+}
+
+// Projector 订阅事件流，用apply维护读模型，并记录检查点
+type Projector struct {
+	name       string
+	apply      func(e eventstore.Event)
+	delay      time.Duration // 模拟写入读库的耗时
+	checkpoint atomic.Int64
+
+	mu       sync.Mutex
+	progress chan struct{} // 检查点前进时关闭并替换
+}
+
+func NewProjector(name string, delay time.Duration, apply func(e eventstore.Event)) *Projector {
+	return &Projector{name: name, apply: apply, delay: delay, progress: make(chan struct{})}
+}
+
+// Run 从检查点之后开始订阅，caughtUp在追赶阶段结束时被调用
+func (p *Projector) Run(ctx context.Context, store *eventstore.Store, caughtUp func()) {
+	sub := store.Subscribe(ctx, p.checkpoint.Load())
+	go func() {
+		select {
+		case <-sub.CaughtUp():
+			if caughtUp != nil {
+				caughtUp()
+			}
+		case <-ctx.Done():
+		}
+	}()
+	for e := range sub.Events() {
+		if p.delay > 0 {
+			time.Sleep(p.delay)
+		}
+		p.apply(e)
+		p.checkpoint.Store(e.Position)
+
+		p.mu.Lock()
+		close(p.progress)
+		p.progress = make(chan struct{})
+		p.mu.Unlock()
+	}
+}
+
+// WaitFor 等待读模型至少处理到位置pos
+func (p *Projector) WaitFor(ctx context.Context, pos int64) error {
+	for {
+		p.mu.Lock()
+		progress := p.progress
+		p.mu.Unlock()
+		if p.checkpoint.Load() >= pos {
+			return nil
+		}
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// BalanceView 读模型：账户余额表
+type BalanceView struct {
+	mu       sync.RWMutex
+	owners   map[string]string
+	balances map[string]int
+}
+
+func NewBalanceView() *BalanceView {
+	return &BalanceView{owners: make(map[string]string), balances: make(map[string]int)}
+}
+
+func (v *BalanceView) Apply(e eventstore.Event) {
+	id := e.StreamID[len("account-"):]
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	switch d := e.Data.(type) {
+	case AccountOpened:
+		v.owners[id] = d.Owner
+	case MoneyDeposited:
+		v.balances[id] += d.Amount
+	case MoneyWithdrawn:
+		v.balances[id] -= d.Amount
+	}
+}
+
+func (v *BalanceView) Balance(id string) int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.balances[id]
+}
+
+func (v *BalanceView) Snapshot() map[string]int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	out := make(map[string]int, len(v.balances))
+	for k, b := range v.balances {
+		out[k] = b
+	}
+	return out
+}
+
+// StatsView 读模型：按事件类型统计次数和金额
+type StatsView struct {
+	mu      sync.RWMutex
+	counts  map[string]int
+	amounts map[string]int
+}
+
+func NewStatsView() *StatsView {
+	return &StatsView{counts: make(map[string]int), amounts: make(map[string]int)}
+}
+
+func (v *StatsView) Apply(e eventstore.Event) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.counts[e.Type]++
+	switch d := e.Data.(type) {
+	case MoneyDeposited:
+		v.amounts[e.Type] += d.Amount
+	case MoneyWithdrawn:
+		v.amounts[e.Type] += d.Amount
+	}
+}
+
+func (v *StatsView) String() string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return fmt.Sprintf("开户 %d, 存款 %d 笔/%d, 取款 %d 笔/%d",
+		v.counts["AccountOpened"], v.counts["MoneyDeposited"], v.amounts["MoneyDeposited"],
+		v.counts["MoneyWithdrawn"], v.amounts["MoneyWithdrawn"])
+}
+
+// LargeTxView 读模型：大额交易（金额>=400）列表，系统上线后才新增
+type LargeTxView struct {
+	mu  sync.RWMutex
+	txs []int64 // 事件位置
+}
+
+func (v *LargeTxView) Apply(e eventstore.Event) {
+	amount := 0
+	switch d := e.Data.(type) {
+	case MoneyDeposited:
+		amount = d.Amount
+	case MoneyWithdrawn:
+		amount = d.Amount
+	}
+	if amount >= 400 {
+		v.mu.Lock()
+		v.txs = append(v.txs, e.Position)
+		v.mu.Unlock()
+	}
+}
+
+func (v *LargeTxView) Positions() []int64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return append([]int64(nil), v.txs...)
+}
+
+// replayAll 同步地从头重放全部事件，作为核对的基准
+func replayAll(store *eventstore.Store, apply func(e eventstore.Event)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	head := store.Head()
+	sub := store.Subscribe(ctx, 0)
+	for e := range sub.Events() {
+		apply(e)
+		if e.Position == head {
+			return
+		}
+	}
+}
+
+func equalMaps(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func main() {
+	numAccounts := flag.Int("accounts", 20, "开设的账户数量，至少8个（第2部分使用账户7）")
+	numClients := flag.Int("clients", 8, "并发发送命令的客户端数量")
+	commands := flag.Int("commands", 150, "每个客户端发送的存取款命令数")
+	demoflag.Parse()
+	if *numAccounts < 8 {
+		fmt.Fprintln(os.Stderr, "accounts至少为8")
+		os.Exit(2)
+	}
+
+	fmt.Println("=== CQRS与事件溯源演示 ===")
+
+	store := eventstore.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := NewCommandHandler(store)
+	go handler.Run(ctx)
+
+	balances := NewBalanceView()
+	stats := NewStatsView()
+	balanceProj := NewProjector("balances", 20*time.Microsecond, balances.Apply)
+	statsProj := NewProjector("stats", 50*time.Microsecond, stats.Apply) // 慢投影器
+	var projectors sync.WaitGroup
+	for _, p := range []*Projector{balanceProj, statsProj} {
+		projectors.Add(1)
+		go func(p *Projector) {
+			defer projectors.Done()
+			p.Run(ctx, store, nil)
+		}(p)
+	}
+
+	accounts := *numAccounts
+	fmt.Printf("\n1. 开设 %d 个账户，%d个客户端并发发送存取款命令\n", accounts, *numClients)
+	for i := 0; i < accounts; i++ {
+		handler.Send(ctx, OpenAccount{ID: fmt.Sprint(i), Owner: fmt.Sprintf("user-%d", i)})
+	}
+	if r := handler.Send(ctx, OpenAccount{ID: "0", Owner: "dup"}); r.Err != nil {
+		fmt.Printf("  重复开户被写端拒绝: %v\n", r.Err)
+	}
+
+	var maxLag atomic.Int64
+	var clients sync.WaitGroup
+	for c := 0; c < *numClients; c++ {
+		clients.Add(1)
+		go func(c int) {
+			defer clients.Done()
+			rng := rand.New(rand.NewSource(int64(c)))
+			for i := 0; i < *commands; i++ {
+				id := fmt.Sprint(rng.Intn(accounts))
+				amount := (rng.Intn(10) + 1) * 50
+				if rng.Intn(3) == 0 {
+					handler.Send(ctx, Withdraw{ID: id, Amount: amount})
+				} else {
+					handler.Send(ctx, Deposit{ID: id, Amount: amount})
+				}
+				lag := store.Head() - statsProj.checkpoint.Load()
+				for cur := maxLag.Load(); lag > cur && !maxLag.CompareAndSwap(cur, lag); cur = maxLag.Load() {
+				}
+			}
+		}(c)
+	}
+	clients.Wait()
+	fmt.Printf("  写入 %d 个事件，余额不足等原因拒绝 %d 个命令\n", store.Head(), handler.rejected.Load())
+	fmt.Printf("  慢投影器stats最多落后日志末尾 %d 个事件\n", maxLag.Load())
+
+	fmt.Println("\n2. 读己之写：存款后立即查询（结果取决于投影器是否恰好已处理），再对比等待检查点后查询")
+	for _, wait := range []bool{false, true} {
+		balanceProj.WaitFor(ctx, store.Head())
+		before := balances.Balance("7")
+		r := handler.Send(ctx, Deposit{ID: "7", Amount: 1000})
+		if wait {
+			balanceProj.WaitFor(ctx, r.Position)
+		}
+		after := balances.Balance("7")
+		fmt.Printf("  等待检查点=%-5v 存款前 %d, 查询到 %d, 已包含这笔存款: %v\n", wait, before, after, after == before+1000)
+	}
+
+	fmt.Println("\n3. 新增大额交易投影器，从位置0开始追赶")
+	large := &LargeTxView{}
+	largeProj := NewProjector("large-tx", 0, large.Apply)
+	start := time.Now()
+	caughtUp := make(chan struct{})
+	projectors.Add(1)
+	go func() {
+		defer projectors.Done()
+		largeProj.Run(ctx, store, func() { close(caughtUp) })
+	}()
+	<-caughtUp
+	fmt.Printf("  %v 内重放了 %d 个历史事件，找到 %d 笔大额交易，切换到实时阶段\n",
+		time.Since(start).Round(time.Microsecond), largeProj.checkpoint.Load(), len(large.Positions()))
+	r := handler.Send(ctx, Deposit{ID: "3", Amount: 900})
+	largeProj.WaitFor(ctx, r.Position)
+	positions := large.Positions()
+	fmt.Printf("  实时收到新的大额存款(位置%d): %v\n", r.Position, positions[len(positions)-1] == r.Position)
+
+	fmt.Println("\n4. 乐观并发：另一个写者带着过期的版本写入")
+	_, version := store.Load("account-5")
+	handler.Send(ctx, Deposit{ID: "5", Amount: 50})
+	_, err := store.Append("account-5", version, eventstore.NewEvent{Type: "MoneyWithdrawn", Data: MoneyWithdrawn{Amount: 50}})
+	fmt.Printf("  期望版本 %d: err=%v\n", version, err)
+
+	fmt.Println("\n5. 核对读模型:")
+	head := store.Head()
+	for _, p := range []*Projector{balanceProj, statsProj, largeProj} {
+		p.WaitFor(ctx, head)
+	}
+	expectedBalances := NewBalanceView()
+	replayAll(store, expectedBalances.Apply)
+	expectedLarge := &LargeTxView{}
+	replayAll(store, expectedLarge.Apply)
+
+	writeSide := make(map[string]int)
+	for i := 0; i < accounts; i++ {
+		a, _ := handler.load(fmt.Sprint(i))
+		if a.Balance != 0 {
+			writeSide[fmt.Sprint(i)] = a.Balance
+		}
+	}
+	snapshot := balances.Snapshot()
+	for id, b := range snapshot {
+		if b == 0 {
+			delete(snapshot, id)
+		}
+	}
+	fmt.Printf("  stats: %s\n", stats)
+	fmt.Printf("  余额读模型 = 完整重放: %v, = 写端聚合: %v\n", equalMaps(balances.Snapshot(), expectedBalances.Snapshot()), equalMaps(snapshot, writeSide))
+	got, want := large.Positions(), expectedLarge.Positions()
+	same := len(got) == len(want)
+	for i := 0; same && i < len(got); i++ {
+		same = got[i] == want[i]
+	}
+	fmt.Printf("  追赶投影器 = 完整重放: %v（%d 笔，无遗漏无重复）\n", same, len(got))
+
+	cancel()
+	projectors.Wait()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 写端只负责校验和追加事件，读端各自按需要组织数据，可以独立扩展和重建")
+	fmt.Println("2. 慢投影器会落后，命令成功不代表读模型已更新；需要时按命令返回的位置等待检查点")
+	fmt.Println("3. 新投影器从日志重放历史即可得到完整的读模型，不需要迁移旧数据")
+	fmt.Println("4. 追赶和实时读的是同一个日志，切换时不会遗漏或重复事件")
+	fmt.Println("5. 单写者避免了冲突，多写者时期望版本让并发修改同一账户的写入失败，由调用方重试")
+}
@@ -0,0 +1,437 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/14_stream_windowing/main.go
+主题：流式窗口聚合（滚动、滑动、会话窗口）与事件时间水位线
+
+本示例演示：
+1. pkg/window 的窗口引擎：一个goroutine消费带时间戳的事件channel，按键和窗口聚合，输出结果channel
+2. 三种窗口：滚动窗口求和、滑动窗口计数、会话窗口（乱序到达的事件把两个会话连成一个）
+3. 可插拔的聚合函数：Sum、Count、Mean、MinMax，会话合并时用Merge合并部分结果
+4. 用FakeClock手动推进处理时间，每个场景的输出都是确定的，逐项核对 ✓/✗
+5. 真实时钟下多个传感器goroutine并发上报，按200ms滚动窗口求最低和最高温度；迟到的读数被丢弃
+6. 同一条乱序到达的点击流分别用处理时间和事件时间处理，与按事件时间统计的真实值对比：
+   - 处理时间：时钟到了就输出，传输慢的事件错过窗口
+   - 事件时间 + 水位线：等待乱序事件，太晚的事件进入旁路输出
+   - 再加上允许迟到：迟到事件更新已输出的结果，最终值与真实值一致
+7. 真实时钟下传感器读数经过随机网络延迟乱序到达，事件时间引擎处理，迟到读数由另一个goroutine从旁路输出读取
+
+核心概念：
+- 窗口边界由事件时间戳决定，窗口何时输出由处理时间决定
+- 滑动窗口里一个事件属于多个窗口，窗口状态数是滚动窗口的size/slide倍
+- 会话窗口没有固定边界，新事件可能让两个会话合并，所以聚合必须支持Merge
+- 平均值不能直接合并，要保存(和, 个数)这样可合并的中间状态
+- 时钟抽象成接口后，定时逻辑可以被确定地检查，不需要sleep
+- 水位线 = 最大事件时间 - 允许乱序：表示"早于它的事件应该都到了"，是完整性和延迟之间的权衡
+- 事件时间的结果只取决于数据本身，与处理快慢、重放与否无关
+
+运行方式：go run ./cmd/hard/14_stream_windowing [-sensors=3] [-window=200ms] [-max-delay=150ms] [-out-of-orderness=50ms]
+
+场景1~3和5使用固定的数据逐项核对，参数只影响真实时钟下的场景4和6；
+-out-of-orderness不小于-max-delay时场景6没有迟到读数
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/window"
+)
+
+var t0 = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func at(sec int) time.Time { return t0.Add(time.Duration(sec) * time.Second) }
+
+// span 把窗口显示为相对t0的秒数
+func span(w window.Window) string {
+	return fmt.Sprintf("[%ds,%ds)", int(w.Start.Sub(t0).Seconds()), int(w.End.Sub(t0).Seconds()))
+}
+
+// take 从结果channel读取n个结果，超时说明引擎没有输出预期的结果
+func take[A any](out <-chan window.Result[A], n int) []window.Result[A] {
+	var rs []window.Result[A]
+	for len(rs) < n {
+		select {
+		case r := <-out:
+			rs = append(rs, r)
+		case <-time.After(time.Second):
+			return rs
+		}
+	}
+	return rs
+}
+
+// none 确认没有多余的结果
+func none[A any](out <-chan window.Result[A]) bool {
+	select {
+	case <-out:
+		return false
+	case <-time.After(20 * time.Millisecond):
+		return true
+	}
+}
+
+func describe[A any](rs []window.Result[A], format func(A) string) string {
+	s := ""
+	for _, r := range rs {
+		s += fmt.Sprintf(" %s%s=%s", r.Key, span(r.Window), format(r.Value))
+	}
+	return s
+}
+
+var passed, failed int
+
+func check(name string, ok bool, detail string) {
+	mark := "✓"
+	if ok {
+		passed++
+	} else {
+		mark = "✗"
+		failed++
+	}
+	fmt.Printf("  %s %s:%s\n", mark, name, detail)
+}
+
+func fakeTumbling() {
+	clock := window.NewFakeClock(t0)
+	engine := window.New(window.Config{Spec: window.Tumbling(10 * time.Second), Clock: clock, Tick: time.Second}, window.Sum[int]())
+	in := make(chan window.Event[int])
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := engine.Run(ctx, in)
+	itoa := func(v int) string { return fmt.Sprint(v) }
+
+	in <- window.Event[int]{Key: "a", Time: at(1), Value: 1}
+	in <- window.Event[int]{Key: "b", Time: at(3), Value: 10}
+	in <- window.Event[int]{Key: "a", Time: at(4), Value: 2}
+	in <- window.Event[int]{Key: "a", Time: at(12), Value: 5}
+
+	clock.Advance(9 * time.Second)
+	check("时钟到9s，[0s,10s)还没结束", none(out), "")
+
+	clock.Advance(time.Second)
+	rs := take(out, 2)
+	check("时钟到10s，输出a和b的[0s,10s)", len(rs) == 2 && rs[0].Key == "a" && rs[0].Value == 3 && rs[0].Count == 2 && rs[1].Key == "b" && rs[1].Value == 10,
+		describe(rs, itoa))
+
+	in <- window.Event[int]{Key: "a", Time: at(5), Value: 100}
+	check("迟到的事件(5s)所属窗口已输出，被丢弃", engine.Dropped() == 1, fmt.Sprintf(" 丢弃 %d", engine.Dropped()))
+
+	clock.Advance(10 * time.Second)
+	rs = take(out, 1)
+	check("时钟到20s，输出a的[10s,20s)", len(rs) == 1 && rs[0].Value == 5 && none(out), describe(rs, itoa))
+}
+
+func fakeSliding() {
+	clock := window.NewFakeClock(t0)
+	engine := window.New(window.Config{Spec: window.Sliding(10*time.Second, 5*time.Second), Clock: clock, Tick: time.Second}, window.Count[int]())
+	in := make(chan window.Event[int])
+	out := engine.Run(context.Background(), in)
+	itoa := func(v int) string { return fmt.Sprint(v) }
+
+	for _, sec := range []int{1, 6, 11} {
+		in <- window.Event[int]{Key: "a", Time: at(sec)}
+	}
+
+	clock.Advance(10 * time.Second)
+	rs := take(out, 2)
+	check("每个事件属于两个窗口，时钟到10s输出[-5s,5s)和[0s,10s)",
+		len(rs) == 2 && rs[0].Value == 1 && rs[1].Value == 2, describe(rs, itoa))
+
+	// 关闭输入时剩余的窗口全部输出
+	close(in)
+	rs = take(out, 2)
+	check("关闭输入，输出剩余的[5s,15s)和[10s,20s)",
+		len(rs) == 2 && rs[0].Value == 2 && rs[1].Value == 1, describe(rs, itoa))
+}
+
+func fakeSession() {
+	clock := window.NewFakeClock(t0)
+	engine := window.New(window.Config{Spec: window.Session(5 * time.Second), Clock: clock, Tick: time.Second}, window.Mean[int]())
+	in := make(chan window.Event[int])
+	out := engine.Run(context.Background(), in)
+	mean := func(m window.MeanAcc) string { return fmt.Sprintf("%.1f(n=%d)", m.Value(), m.N) }
+
+	in <- window.Event[int]{Key: "u", Time: at(0), Value: 1}
+	in <- window.Event[int]{Key: "u", Time: at(10), Value: 3}
+	in <- window.Event[int]{Key: "v", Time: at(2), Value: 7}
+	// 乱序到达的5s事件：它的会话[5s,10s)和两边都相连，三段合并为一个会话
+	in <- window.Event[int]{Key: "u", Time: at(5), Value: 8}
+
+	clock.Advance(7 * time.Second)
+	rs := take(out, 1)
+	check("v的会话在7s结束", len(rs) == 1 && rs[0].Key == "v" && rs[0].Window.End.Equal(at(7)), describe(rs, mean))
+
+	clock.Advance(8 * time.Second)
+	rs = take(out, 1)
+	check("u的三个事件合并成一个会话[0s,15s)，平均值用Merge合并",
+		len(rs) == 1 && rs[0].Window == window.Window{Start: at(0), End: at(15)} && rs[0].Value.N == 3 && rs[0].Value.Value() == 4,
+		describe(rs, mean))
+	close(in)
+}
+
+// realTimeSensors 真实时钟：sensors个传感器每10ms上报一次，size滚动窗口求最低和最高温度
+func realTimeSensors(sensors int, size time.Duration) {
+	engine := window.New(window.Config{Spec: window.Tumbling(size), Tick: size / 10}, window.MinMax[float64]())
+	in := make(chan window.Event[float64], 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := engine.Run(ctx, in)
+
+	var wg sync.WaitGroup
+	for i := 0; i < sensors; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(i)))
+			key := fmt.Sprintf("sensor-%d", i)
+			base := 20 + float64(i)*5
+			for j := 0; j < 60; j++ {
+				ts := time.Now()
+				if i == sensors-1 && j == 40 {
+					// 网络抖动：这条读数在路上耽搁了1.5个窗口，它所属的窗口早已输出
+					ts = ts.Add(-size * 3 / 2)
+				}
+				in <- window.Event[float64]{Key: key, Time: ts, Value: base + rng.Float64()*2}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(in)
+	}()
+
+	start := time.Now().Truncate(size)
+	for r := range out {
+		when := fmt.Sprintf("窗口结束后 %v 输出", time.Since(r.Window.End).Round(time.Millisecond))
+		if time.Now().Before(r.Window.End) {
+			when = "输入关闭，提前输出"
+		}
+		fmt.Printf("  %s [%4dms,%4dms) 读数 %2d 个, 最低 %.2f, 最高 %.2f, %s\n",
+			r.Key, r.Window.Start.Sub(start).Milliseconds(), r.Window.End.Sub(start).Milliseconds(),
+			r.Count, r.Value.Min, r.Value.Max, when)
+	}
+	fmt.Printf("  丢弃迟到读数 %d 个\n", engine.Dropped())
+}
+
+// click 一次点击：事件时间和到达（处理）时间，单位秒
+type click struct{ event, arrival int }
+
+// clickStream 按到达顺序排列，其中几个点击在路上耽搁了较长时间
+var clickStream = []click{
+	{1, 2}, {4, 5}, {7, 8}, {12, 13}, {8, 14}, {15, 16}, {19, 21},
+	{22, 23}, {3, 25}, {27, 28}, {18, 29}, {33, 34}, {41, 42},
+}
+
+// truth 按事件时间统计的真实点击数
+func truth() map[string]int {
+	counts := make(map[string]int)
+	for _, c := range clickStream {
+		start := c.event / 10 * 10
+		counts[fmt.Sprintf("[%ds,%ds)", start, start+10)]++
+	}
+	return counts
+}
+
+// runClicks 把点击流按到达顺序送入引擎；处理时间模式下先把FakeClock推进到到达时间
+// 返回按输出顺序排列的结果描述、每个窗口最终的计数和旁路输出的事件
+func runClicks(cfg window.Config, clock *window.FakeClock) (lines []string, final map[string]int, late []int) {
+	engine := window.New(cfg, window.Count[int]())
+	lateCh := make(chan window.Event[int])
+	engine.LateTo(lateCh)
+	in := make(chan window.Event[int])
+	out := engine.Run(context.Background(), in)
+
+	final = make(map[string]int)
+	results := make(chan struct{})
+	go func() {
+		defer close(results)
+		for r := range out {
+			mark := ""
+			if r.Update {
+				mark = "(更新)"
+			}
+			lines = append(lines, fmt.Sprintf("%s=%d%s", span(r.Window), r.Value, mark))
+			final[span(r.Window)] = r.Value
+		}
+	}()
+	lateDone := make(chan struct{})
+	go func() {
+		defer close(lateDone)
+		for ev := range lateCh {
+			late = append(late, int(ev.Time.Sub(t0).Seconds()))
+		}
+	}()
+
+	for _, c := range clickStream {
+		if clock != nil {
+			clock.Advance(at(c.arrival).Sub(clock.Now()))
+		}
+		in <- window.Event[int]{Key: "clicks", Time: at(c.event)}
+	}
+	close(in)
+	// 结果channel关闭说明引擎已退出，之后不会再向旁路输出发送
+	<-results
+	close(lateCh)
+	<-lateDone
+	return lines, final, late
+}
+
+func eventTimeVsProcessingTime() {
+	want := truth()
+	fmt.Printf("  按事件时间的真实值: [0s,10s)=%d [10s,20s)=%d [20s,30s)=%d [30s,40s)=%d [40s,50s)=%d\n",
+		want["[0s,10s)"], want["[10s,20s)"], want["[20s,30s)"], want["[30s,40s)"], want["[40s,50s)"])
+
+	modes := []struct {
+		name  string
+		cfg   window.Config
+		clock bool
+	}{
+		{"处理时间", window.Config{Spec: window.Tumbling(10 * time.Second), Mode: window.ProcessingTime, Tick: time.Second}, true},
+		{"事件时间(乱序5s)", window.Config{Spec: window.Tumbling(10 * time.Second), Mode: window.EventTime, MaxOutOfOrderness: 5 * time.Second}, false},
+		{"事件时间(乱序5s)+允许迟到15s", window.Config{Spec: window.Tumbling(10 * time.Second), Mode: window.EventTime,
+			MaxOutOfOrderness: 5 * time.Second, AllowedLateness: 15 * time.Second}, false},
+	}
+	for _, m := range modes {
+		var clock *window.FakeClock
+		if m.clock {
+			clock = window.NewFakeClock(t0)
+			m.cfg.Clock = clock
+		}
+		lines, final, late := runClicks(m.cfg, clock)
+		matches := len(final) == len(want)
+		total := len(late)
+		for k, v := range want {
+			matches = matches && final[k] == v
+		}
+		for _, v := range final {
+			total += v
+		}
+		fmt.Printf("  %s:\n    输出 %v\n    旁路输出(事件时间) %v, 最终结果与真实值一致: %v\n", m.name, lines, late, matches)
+		check(m.name+": 计入窗口的 + 旁路输出的 = 全部点击", total == len(clickStream), fmt.Sprintf(" %d", total))
+		if m.cfg.AllowedLateness > 0 {
+			check(m.name+": 最终结果与真实值一致", matches, "")
+		}
+	}
+}
+
+// realTimeOutOfOrder 读数经过[0, maxDelay)的随机网络延迟乱序到达，事件时间引擎按100ms窗口计数，允许乱序outOfOrderness
+func realTimeOutOfOrder(sensors int, maxDelay, outOfOrderness time.Duration) {
+	engine := window.New(window.Config{Spec: window.Tumbling(100 * time.Millisecond), Mode: window.EventTime,
+		MaxOutOfOrderness: outOfOrderness}, window.Count[int]())
+	lateCh := make(chan window.Event[int], 16)
+	engine.LateTo(lateCh)
+	in := make(chan window.Event[int], 64)
+	out := engine.Run(context.Background(), in)
+
+	var lateWG sync.WaitGroup
+	lateWG.Add(1)
+	var maxLateDelay time.Duration
+	lateCount := 0
+	go func() {
+		defer lateWG.Done()
+		// 旁路输出通常写入单独的存储，稍后离线修正；这里只统计
+		for ev := range lateCh {
+			lateCount++
+			maxLateDelay = max(maxLateDelay, time.Since(ev.Time))
+		}
+	}()
+
+	counted, windows := 0, 0
+	results := make(chan struct{})
+	go func() {
+		defer close(results)
+		for r := range out {
+			counted += r.Value
+			windows++
+		}
+	}()
+
+	var senders sync.WaitGroup
+	var sent atomic.Int64
+	for i := 0; i < sensors; i++ {
+		senders.Add(1)
+		go func(i int) {
+			defer senders.Done()
+			rng := rand.New(rand.NewSource(int64(i) + 42))
+			key := fmt.Sprintf("sensor-%d", i)
+			for j := 0; j < 40; j++ {
+				ts := time.Now()
+				delay := time.Duration(rng.Int63n(int64(maxDelay/time.Millisecond))) * time.Millisecond
+				senders.Add(1)
+				sent.Add(1)
+				// 每条读数由独立的goroutine延迟发送，模拟网络把顺序打乱
+				go func() {
+					defer senders.Done()
+					time.Sleep(delay)
+					in <- window.Event[int]{Key: key, Time: ts}
+				}()
+				time.Sleep(10 * time.Millisecond)
+			}
+		}(i)
+	}
+	senders.Wait()
+	close(in)
+
+	<-results
+	close(lateCh)
+	lateWG.Wait()
+	total := int(sent.Load())
+	fmt.Printf("  发送 %d 条，计入 %d 个窗口结果共 %d 条，旁路输出 %d 条（最晚的一条到达时已产生 %v）\n",
+		total, windows, counted, lateCount, maxLateDelay.Round(time.Millisecond))
+	check("每条读数要么计入窗口，要么进入旁路输出", counted+lateCount == total, fmt.Sprintf(" %d+%d=%d", counted, lateCount, total))
+}
+
+func main() {
+	sensors := flag.Int("sensors", 3, "场景4和6中并发上报的传感器数量")
+	size := flag.Duration("window", 200*time.Millisecond, "场景4的滚动窗口大小")
+	maxDelay := flag.Duration("max-delay", 150*time.Millisecond, "场景6中读数的最大网络延迟")
+	outOfOrderness := flag.Duration("out-of-orderness", 50*time.Millisecond, "场景6中水位线允许的乱序时间")
+	demoflag.Parse()
+	switch {
+	case *sensors < 1:
+		fmt.Fprintln(os.Stderr, "sensors至少为1")
+		os.Exit(2)
+	case *size < 10*time.Millisecond || *maxDelay < time.Millisecond || *outOfOrderness < 0:
+		fmt.Fprintln(os.Stderr, "window至少为10ms，max-delay至少为1ms，out-of-orderness不能为负数")
+		os.Exit(2)
+	}
+
+	fmt.Println("=== 流式窗口聚合演示 ===")
+
+	fmt.Println("\n1. 滚动窗口(10s)求和，FakeClock:")
+	fakeTumbling()
+	fmt.Println("\n2. 滑动窗口(10s，每5s)计数，FakeClock:")
+	fakeSliding()
+	fmt.Println("\n3. 会话窗口(间隔5s)求平均，FakeClock:")
+	fakeSession()
+
+	fmt.Printf("\n4. 真实时钟，%d个传感器并发上报，%v滚动窗口（最后一个窗口在输入关闭时输出）:\n", *sensors, *size)
+	realTimeSensors(*sensors, *size)
+
+	fmt.Println("\n5. 乱序点击流，10s滚动窗口计数（处理时间模式用FakeClock按到达时间推进）:")
+	eventTimeVsProcessingTime()
+
+	fmt.Printf("\n6. 真实时钟，读数经过0~%v随机延迟乱序到达，事件时间100ms窗口，允许乱序%v:\n", *maxDelay, *outOfOrderness)
+	realTimeOutOfOrder(*sensors, *maxDelay, *outOfOrderness)
+	fmt.Printf("\n检查结果: %d 通过, %d 失败\n", passed, failed)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 窗口结果在时钟越过窗口结束时间后的下一次检查时输出，延迟最多一个Tick")
+	fmt.Println("2. 滑动窗口的每个事件被计入size/slide个窗口，状态和输出都随之成倍增加")
+	fmt.Println("3. 会话窗口合并依赖Merge，Mean保存(和, 个数)而不是平均值，合并结果才正确")
+	fmt.Println("4. 用处理时间决定窗口何时关闭，传输慢的事件就会错过窗口，结果取决于网络和处理速度")
+	fmt.Println("5. FakeClock让定时逻辑可以确定地验证：Advance返回时引擎已经收到tick")
+	fmt.Println("6. 事件时间模式下水位线只随数据前进：乱序5s以内的事件都能等到，代价是窗口晚5s输出")
+	fmt.Println("7. 允许迟到让结果先输出、再更新，下游要能处理同一窗口的多次输出；超过期限的事件进入旁路输出而不是悄悄丢失")
+	fmt.Println("8. 没有新事件时水位线不会前进，最后的窗口要等输入关闭才输出；生产系统会为空闲的数据源单独推进水位线")
+}
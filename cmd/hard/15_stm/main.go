@@ -0,0 +1,256 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/15_stm/main.go
+主题：软件事务内存（STM）与银行转账
+
+本示例演示：
+1. pkg/stm：事务变量TVar、乐观读、提交时校验、冲突自动重试
+2. 经典银行转账：8个goroutine在10个账户之间随机转账，同时有审计goroutine在事务中读取全部余额，
+   每次审计看到的总额都不变；代码里没有一把显式的锁
+3. 对照：每个账户单独用原子操作，转账分两步扣款和入账，审计会看到"钱凭空消失"
+4. 余额不足时事务函数返回错误，已经做的修改全部丢弃
+5. 组合：两笔转账放进同一个事务，第二笔失败时第一笔也不生效——用锁很难做到这一点
+
+核心概念：
+- 乐观并发：先不加锁地执行，提交时检查读过的变量有没有被别人改过，改过就从头再来
+- 全局版本时钟：事务开始时记下读版本，读到比它新的数据说明快照已经不一致，立即重试
+- 事务函数可能被执行多次，里面不能有I/O、打印等副作用
+- 与锁相比：不用考虑加锁顺序、不会死锁、可以组合；代价是冲突多时大量重试（活锁风险）
+
+运行方式：go run ./cmd/hard/15_stm [-workers=8] [-transfers=3000]
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/stm"
+)
+
+const (
+	numAccounts    = 10
+	initialBalance = 1000
+	total          = numAccounts * initialBalance
+)
+
+var errInsufficientFunds = errors.New("余额不足")
+
+// Bank 每个账户的余额是一个事务变量
+type Bank struct {
+	accounts []*stm.TVar[int]
+}
+
+func NewBank() *Bank {
+	b := &Bank{}
+	for i := 0; i < numAccounts; i++ {
+		b.accounts = append(b.accounts, stm.NewVar(initialBalance))
+	}
+	return b
+}
+
+// transfer 在事务tx中转账，可以和其他操作组合进同一个事务
+func (b *Bank) transfer(tx *stm.Tx, from, to, amount int) error {
+	fromBal := b.accounts[from].Get(tx)
+	if fromBal < amount {
+		return fmt.Errorf("账户%d %w: %d < %d", from, errInsufficientFunds, fromBal, amount)
+	}
+	b.accounts[from].Set(tx, fromBal-amount)
+	b.accounts[to].Set(tx, b.accounts[to].Get(tx)+amount)
+	return nil
+}
+
+func (b *Bank) Transfer(from, to, amount int) error {
+	return stm.Atomically(func(tx *stm.Tx) error { return b.transfer(tx, from, to, amount) })
+}
+
+// Audit 在一个只读事务中读取全部余额
+func (b *Bank) Audit() int {
+	sum := 0
+	stm.Atomically(func(tx *stm.Tx) error {
+		sum = 0 // 事务可能重试，每次都从头累加
+		for _, acc := range b.accounts {
+			sum += acc.Get(tx)
+		}
+		return nil
+	})
+	return sum
+}
+
+// AtomicBank 对照组：每个账户单独是原子的，但转账的两步之间没有保护
+type AtomicBank struct {
+	accounts []atomic.Int64
+}
+
+func NewAtomicBank() *AtomicBank {
+	b := &AtomicBank{accounts: make([]atomic.Int64, numAccounts)}
+	for i := range b.accounts {
+		b.accounts[i].Store(initialBalance)
+	}
+	return b
+}
+
+func (b *AtomicBank) Transfer(from, to, amount int) error {
+	if b.accounts[from].Add(-int64(amount)) < 0 {
+		b.accounts[from].Add(int64(amount))
+		return errInsufficientFunds
+	}
+	b.accounts[to].Add(int64(amount))
+	return nil
+}
+
+func (b *AtomicBank) Audit() int {
+	sum := 0
+	for i := range b.accounts {
+		sum += int(b.accounts[i].Load())
+	}
+	return sum
+}
+
+type bank interface {
+	Transfer(from, to, amount int) error
+	Audit() int
+}
+
+// runTransfers workers个goroutine各随机转账perWorker次，同时审计；返回审计次数和总额不对的次数
+func runTransfers(b bank, workers, perWorker int) (audits, bad int, failed int64) {
+	var wg sync.WaitGroup
+	var failedTransfers atomic.Int64
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < perWorker; i++ {
+				from, to := rng.Intn(numAccounts), rng.Intn(numAccounts)
+				if from == to {
+					continue
+				}
+				if b.Transfer(from, to, 1+rng.Intn(200)) != nil {
+					failedTransfers.Add(1)
+				}
+			}
+		}(w)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return audits, bad, failedTransfers.Load()
+		default:
+		}
+		audits++
+		if b.Audit() != total {
+			bad++
+		}
+	}
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+func demoTransfers(workers, perWorker int) {
+	b := NewBank()
+	c0, a0 := stm.Stats()
+	audits, bad, failed := runTransfers(b, workers, perWorker)
+	c1, a1 := stm.Stats()
+	fmt.Printf("  STM: 提交 %d 个事务, 冲突重试 %d 次, 余额不足 %d 次; 审计 %d 次, 总额不对 %d 次\n",
+		c1-c0, a1-a0, failed, audits, bad)
+	check("所有审计看到的总额都是10000", bad == 0)
+	check(fmt.Sprintf("转账结束后总额: %d", b.Audit()), b.Audit() == total)
+	nonNegative := true
+	for _, acc := range b.accounts {
+		nonNegative = nonNegative && acc.Load() >= 0
+	}
+	check("没有账户余额为负", nonNegative)
+
+	ab := NewAtomicBank()
+	audits, bad, _ = runTransfers(ab, workers, perWorker)
+	fmt.Printf("  对照（逐个账户原子操作）: 审计 %d 次, 总额不对 %d 次, 结束后总额 %d\n", audits, bad, ab.Audit())
+	fmt.Println("  （单核机器上goroutine切换的时机有限，对照组的不一致次数可能很少，多核时更明显）")
+}
+
+func demoInsufficientFunds() {
+	b := NewBank()
+	err := stm.Atomically(func(tx *stm.Tx) error {
+		// 先给账户1存钱，再从账户0转出超过余额的金额
+		b.accounts[1].Set(tx, b.accounts[1].Get(tx)+500)
+		return b.transfer(tx, 0, 2, 5000)
+	})
+	fmt.Printf("  事务返回: %v\n", err)
+	check("返回的错误可以用errors.Is判断", errors.Is(err, errInsufficientFunds))
+	check("事务中已经做的修改（账户1存款）被丢弃", b.accounts[1].Load() == initialBalance)
+}
+
+func demoComposition() {
+	b := NewBank()
+	// 账户0 -> 账户1 转800，账户1 -> 账户2 转2500：第二笔余额不足
+	err := stm.Atomically(func(tx *stm.Tx) error {
+		if err := b.transfer(tx, 0, 1, 800); err != nil {
+			return err
+		}
+		return b.transfer(tx, 1, 2, 2500)
+	})
+	fmt.Printf("  两笔转账组合成一个事务: %v\n", err)
+	check("第二笔失败，第一笔也没有生效", b.accounts[0].Load() == initialBalance && b.accounts[1].Load() == initialBalance)
+
+	// 方向相反的转账同时进行：用两把锁时按参数顺序加锁会死锁，事务不需要考虑加锁顺序
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				if w == 0 {
+					b.Transfer(3, 4, 1)
+				} else {
+					b.Transfer(4, 3, 1)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	// 一方跑得快时会出现余额不足的失败，所以只检查两个账户的总额
+	check(fmt.Sprintf("相反方向各2000次转账完成，没有死锁，两个账户总额不变: %d + %d", b.accounts[3].Load(), b.accounts[4].Load()),
+		b.accounts[3].Load()+b.accounts[4].Load() == 2*initialBalance)
+}
+
+func main() {
+	workers := flag.Int("workers", 8, "并发转账的goroutine数量")
+	perWorker := flag.Int("transfers", 3000, "每个goroutine的转账次数")
+	demoflag.Parse()
+
+	fmt.Println("=== 软件事务内存演示 ===")
+
+	fmt.Printf("\n1. 并发转账与审计（%d个账户，每个%d元，%d个goroutine各转账%d次）\n", numAccounts, initialBalance, *workers, *perWorker)
+	demoTransfers(*workers, *perWorker)
+
+	fmt.Println("\n2. 余额不足：事务中途返回错误")
+	demoInsufficientFunds()
+
+	fmt.Println("\n3. 组合事务")
+	demoComposition()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 转账和审计都没有显式加锁，审计却总能看到一致的快照：读到比读版本新的数据就重试")
+	fmt.Println("2. 冲突重试次数反映了竞争程度，账户越少、事务越长，重试越多")
+	fmt.Println("3. 事务返回错误时写集合直接丢弃，不需要写回滚代码")
+	fmt.Println("4. transfer接收tx参数，可以像普通函数一样组合进更大的事务，整体仍然是原子的")
+	fmt.Println("5. 真实的STM（Clojure、Haskell）还支持retry阻塞等待条件满足，这里只实现了冲突重试")
+}
@@ -0,0 +1,460 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/16_coalescing_proxy/main.go
+主题：请求合并的读穿透缓存代理（stale-while-revalidate）
+
+本示例演示：
+1. 慢速KV源站前面的缓存代理：命中直接返回，未命中时读穿透到源站并写入缓存
+2. 请求合并：同一个键的并发未命中只访问源站一次（pkg/singleflight），对照组每个请求都打到源站
+3. 新鲜期（TTL）内直接命中；过期后的宽限期内立即返回旧值，同时在后台刷新（stale-while-revalidate）
+4. 后台刷新由固定数量的刷新工作者执行，同一个键同时只排队一次，刷新队列满时丢弃、下次访问再触发
+5. 源站故障时刷新失败，代理继续返回旧值（stale-if-error），超过宽限期才把错误返回给调用方
+6. 大量键同时过期：源站的并发请求数被刷新工作者数量限制住
+
+核心概念：
+- 缓存中保存(值, 取回时间)，条目在缓存里的存活时间 = TTL + 宽限期，是否新鲜由取回时间判断
+- 请求合并解决惊群：热点键过期瞬间的N个请求变成1次源站请求
+- stale-while-revalidate把刷新的延迟从请求路径上移走：调用方永远不等源站，代价是可能读到稍旧的数据
+- 后台刷新必须有界：工作者数量限制源站压力，去重避免同一个键被刷新多次
+- 用手动推进的时钟控制TTL，每个场景的结果都是确定的
+
+运行方式：go run ./cmd/hard/16_coalescing_proxy [-latency=30ms] [-callers=100]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/cache"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/singleflight"
+)
+
+var originLatency = 30 * time.Millisecond // 可以用-latency修改
+
+var errOriginDown = errors.New("源站不可用")
+
+// clock 只有Advance才会前进的时钟，控制缓存的新鲜度；源站延迟仍然是真实时间
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Origin 慢速的KV源站，记录请求次数和最大并发
+type Origin struct {
+	mu       sync.Mutex
+	data     map[string]int // 键 -> 版本号
+	down     bool
+	calls    int
+	inflight int
+	peak     int
+}
+
+func NewOrigin() *Origin {
+	return &Origin{data: make(map[string]int)}
+}
+
+func (o *Origin) Get(ctx context.Context, key string) (string, error) {
+	o.mu.Lock()
+	o.calls++
+	o.inflight++
+	o.peak = max(o.peak, o.inflight)
+	o.mu.Unlock()
+	defer func() {
+		o.mu.Lock()
+		o.inflight--
+		o.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(originLatency):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.down {
+		return "", errOriginDown
+	}
+	return fmt.Sprintf("%s@v%d", key, o.data[key]), nil
+}
+
+// Update 源站数据变化，版本号加一
+func (o *Origin) Update(key string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.data[key]++
+}
+
+func (o *Origin) SetDown(down bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.down = down
+}
+
+// Stats 返回请求次数和最大并发，并清零
+func (o *Origin) Stats() (calls, peak int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	calls, peak = o.calls, o.peak
+	o.calls, o.peak = 0, 0
+	return
+}
+
+// Source 一次Get的结果来源
+type Source string
+
+const (
+	SourceFresh  Source = "新鲜命中"
+	SourceStale  Source = "旧值命中"
+	SourceOrigin Source = "源站"
+)
+
+// ProxyConfig 代理配置
+type ProxyConfig struct {
+	TTL            time.Duration // 新鲜期
+	StaleWindow    time.Duration // 过期后仍可返回旧值的宽限期
+	RefreshWorkers int           // 后台刷新工作者数量
+	RefreshQueue   int           // 刷新队列容量，满了丢弃
+	Coalesce       bool          // 是否合并同一个键的并发未命中，关闭时作为对照组
+}
+
+// item 缓存中的条目：值和从源站取回的时间
+type item struct {
+	val     string
+	fetched time.Time
+}
+
+// ProxyStats 代理统计
+type ProxyStats struct {
+	Fresh, Stale, Misses     int64
+	Refreshes, RefreshErrors int64
+	RefreshDropped           int64
+}
+
+// Proxy 读穿透缓存代理
+type Proxy struct {
+	cfg    ProxyConfig
+	origin *Origin
+	clock  *clock
+	cache  *cache.LRUCache[string, item]
+	flight *singleflight.Group[string, item]
+
+	refreshQ   chan string
+	refreshing sync.Map // 已在刷新队列中或正在刷新的键
+	workers    sync.WaitGroup
+
+	fresh, stale, misses                   atomic.Int64
+	refreshes, refreshErrors, refreshDrops atomic.Int64
+}
+
+func NewProxy(origin *Origin, clk *clock, cfg ProxyConfig) *Proxy {
+	p := &Proxy{
+		cfg:    cfg,
+		origin: origin,
+		clock:  clk,
+		cache: cache.New(cache.Config[string, item]{
+			MaxSize: 1024,
+			TTL:     cfg.TTL + cfg.StaleWindow, // 宽限期结束后条目才真正从缓存中过期
+			Now:     clk.Now,
+		}),
+		flight:   singleflight.New[string, item](singleflight.Config{}),
+		refreshQ: make(chan string, cfg.RefreshQueue),
+	}
+	for i := 0; i < cfg.RefreshWorkers; i++ {
+		p.workers.Add(1)
+		go p.refreshWorker()
+	}
+	return p
+}
+
+// Get 读取键：新鲜时直接返回；宽限期内返回旧值并触发后台刷新；否则同步读源站
+func (p *Proxy) Get(ctx context.Context, key string) (string, Source, error) {
+	if it, ok := p.cache.Get(key); ok {
+		if p.clock.Now().Sub(it.fetched) < p.cfg.TTL {
+			p.fresh.Add(1)
+			return it.val, SourceFresh, nil
+		}
+		p.stale.Add(1)
+		p.scheduleRefresh(key)
+		return it.val, SourceStale, nil
+	}
+
+	p.misses.Add(1)
+	if !p.cfg.Coalesce {
+		it, err := p.fetch(ctx, key)
+		return it.val, SourceOrigin, err
+	}
+	// 合并的加载不受某一个调用方取消的影响，用独立的ctx
+	it, err, _ := p.flight.Do(key, func() (item, error) {
+		return p.fetch(context.WithoutCancel(ctx), key)
+	})
+	return it.val, SourceOrigin, err
+}
+
+// fetch 读源站，成功时写入缓存
+func (p *Proxy) fetch(ctx context.Context, key string) (item, error) {
+	v, err := p.origin.Get(ctx, key)
+	if err != nil {
+		return item{}, err
+	}
+	it := item{val: v, fetched: p.clock.Now()}
+	p.cache.Set(key, it)
+	return it, nil
+}
+
+// scheduleRefresh 把键放入刷新队列；已经在队列中的键不重复放入，队列满时丢弃
+func (p *Proxy) scheduleRefresh(key string) {
+	if _, loaded := p.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	select {
+	case p.refreshQ <- key:
+	default:
+		p.refreshing.Delete(key)
+		p.refreshDrops.Add(1)
+	}
+}
+
+func (p *Proxy) refreshWorker() {
+	defer p.workers.Done()
+	for key := range p.refreshQ {
+		// 与同步未命中共用singleflight，刷新和读穿透不会同时访问源站
+		_, err, _ := p.flight.Do(key, func() (item, error) {
+			return p.fetch(context.Background(), key)
+		})
+		if err != nil {
+			p.refreshErrors.Add(1) // 旧值留在缓存中，宽限期内继续返回
+		} else {
+			p.refreshes.Add(1)
+		}
+		p.refreshing.Delete(key)
+	}
+}
+
+// WaitRefresh 等待刷新队列清空，演示中用来确定后台刷新已经完成
+func (p *Proxy) WaitRefresh(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		empty := true
+		p.refreshing.Range(func(_, _ any) bool {
+			empty = false
+			return false
+		})
+		if empty {
+			return true
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	return false
+}
+
+func (p *Proxy) Close() {
+	close(p.refreshQ)
+	p.workers.Wait()
+}
+
+func (p *Proxy) Stats() ProxyStats {
+	return ProxyStats{
+		Fresh: p.fresh.Load(), Stale: p.stale.Load(), Misses: p.misses.Load(),
+		Refreshes: p.refreshes.Load(), RefreshErrors: p.refreshErrors.Load(), RefreshDropped: p.refreshDrops.Load(),
+	}
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// burst n个goroutine同时读同一个键，返回各来源的次数、读到的值的集合和最长耗时
+func burst(p *Proxy, key string, n int) (sources map[Source]int, values map[string]int, slowest time.Duration) {
+	var mu sync.Mutex
+	sources, values = make(map[Source]int), make(map[string]int)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			t := time.Now()
+			v, src, err := p.Get(context.Background(), key)
+			d := time.Since(t)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				v = "错误: " + err.Error()
+			}
+			sources[src]++
+			values[v]++
+			slowest = max(slowest, d)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return
+}
+
+func newSetup(coalesce bool) (*Origin, *clock, *Proxy) {
+	origin := NewOrigin()
+	clk := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	p := NewProxy(origin, clk, ProxyConfig{
+		TTL:            time.Minute,
+		StaleWindow:    5 * time.Minute,
+		RefreshWorkers: 2,
+		RefreshQueue:   4,
+		Coalesce:       coalesce,
+	})
+	return origin, clk, p
+}
+
+// demoCoalescing n个调用方同时请求同一个冷键，对比合并与不合并
+func demoCoalescing(n int) {
+	origin, _, naive := newSetup(false)
+	defer naive.Close()
+	burst(naive, "user:1", n)
+	naiveCalls, naivePeak := origin.Stats()
+	fmt.Printf("  不合并: %d个并发未命中 -> 源站请求 %d 次（最大并发 %d）\n", n, naiveCalls, naivePeak)
+
+	origin, _, p := newSetup(true)
+	defer p.Close()
+	sources, values, slowest := burst(p, "user:1", n)
+	calls, _ := origin.Stats()
+	fmt.Printf("  合并:   %d个并发未命中 -> 源站请求 %d 次，最长耗时 %v，结果 %v\n", n, calls, slowest.Round(time.Millisecond), values)
+	check("合并后源站只被请求1次", calls == 1)
+	check(fmt.Sprintf("%d个调用方都拿到了同一个值", n), values["user:1@v0"] == n && sources[SourceOrigin] == n)
+	check("不合并时源站被请求多次", naiveCalls > 1)
+
+	sources, _, slowest = burst(p, "user:1", n)
+	calls, _ = origin.Stats()
+	check(fmt.Sprintf("新鲜期内再读%d次: 全部命中、源站0次、最长耗时 %v", n, slowest.Round(time.Millisecond)), sources[SourceFresh] == n && calls == 0)
+}
+
+func demoStaleWhileRevalidate() {
+	origin, clk, p := newSetup(true)
+	defer p.Close()
+	p.Get(context.Background(), "product:7")
+	origin.Stats()
+
+	origin.Update("product:7")
+	clk.Advance(2 * time.Minute) // 超过TTL，还在宽限期内
+	sources, values, slowest := burst(p, "product:7", 50)
+	fmt.Printf("  过期后50个并发读: %v，最长耗时 %v\n", values, slowest.Round(time.Millisecond))
+	check("全部立即返回旧值v0，没有调用方等待源站", sources[SourceStale] == 50 && values["product:7@v0"] == 50 && slowest < originLatency)
+
+	check("后台刷新完成", p.WaitRefresh(time.Second))
+	calls, _ := origin.Stats()
+	check(fmt.Sprintf("50次旧值命中只触发了 %d 次后台刷新", calls), calls == 1 && p.Stats().Refreshes == 1)
+	v, src, _ := p.Get(context.Background(), "product:7")
+	check(fmt.Sprintf("刷新后读到新值: %s (%s)", v, src), v == "product:7@v1" && src == SourceFresh)
+
+	clk.Advance(10 * time.Minute) // 超过TTL+宽限期，条目从缓存中过期
+	t := time.Now()
+	v, src, _ = p.Get(context.Background(), "product:7")
+	check(fmt.Sprintf("超过宽限期后同步读源站: %s (%s)，耗时 %v", v, src, time.Since(t).Round(time.Millisecond)), src == SourceOrigin)
+}
+
+func demoStaleIfError() {
+	origin, clk, p := newSetup(true)
+	defer p.Close()
+	p.Get(context.Background(), "config")
+
+	origin.SetDown(true)
+	clk.Advance(2 * time.Minute)
+	v, src, err := p.Get(context.Background(), "config")
+	p.WaitRefresh(time.Second)
+	fmt.Printf("  源站故障，宽限期内: %s (%s) err=%v; 后台刷新失败 %d 次\n", v, src, err, p.Stats().RefreshErrors)
+	v2, src2, _ := p.Get(context.Background(), "config")
+	p.WaitRefresh(time.Second)
+	check("刷新失败不影响返回旧值，下一次访问会再次尝试刷新",
+		err == nil && src == SourceStale && src2 == SourceStale && v2 == v && p.Stats().RefreshErrors == 2)
+
+	clk.Advance(10 * time.Minute)
+	_, _, err = p.Get(context.Background(), "config")
+	check(fmt.Sprintf("超过宽限期后错误返回给调用方: %v", err), errors.Is(err, errOriginDown))
+
+	origin.SetDown(false)
+	v, src, err = p.Get(context.Background(), "config")
+	check(fmt.Sprintf("源站恢复后: %s (%s)", v, src), err == nil && src == SourceOrigin)
+}
+
+func demoRefreshLimit() {
+	origin, clk, p := newSetup(true)
+	defer p.Close()
+	const keys = 20
+	for i := 0; i < keys; i++ {
+		p.Get(context.Background(), fmt.Sprintf("item:%d", i))
+	}
+	origin.Stats()
+
+	clk.Advance(2 * time.Minute) // 20个键同时过期
+	for i := 0; i < keys; i++ {
+		p.Get(context.Background(), fmt.Sprintf("item:%d", i))
+	}
+	p.WaitRefresh(time.Second)
+	calls, peak := origin.Stats()
+	s := p.Stats()
+	fmt.Printf("  20个键同时过期: 刷新 %d 个，队列满丢弃 %d 个，源站请求 %d 次，最大并发 %d\n", s.Refreshes, s.RefreshDropped, calls, peak)
+	check("源站并发不超过刷新工作者数量2", peak <= 2)
+	check("每个过期的键要么被刷新要么被丢弃", s.Refreshes+s.RefreshDropped == keys)
+
+	// 被丢弃的键下次访问仍然返回旧值，并再次尝试刷新
+	for round := 0; round < 10 && p.Stats().Refreshes < keys; round++ {
+		for i := 0; i < keys; i++ {
+			p.Get(context.Background(), fmt.Sprintf("item:%d", i))
+		}
+		p.WaitRefresh(time.Second)
+	}
+	check(fmt.Sprintf("再访问几轮后全部刷新完成: %d/%d", p.Stats().Refreshes, keys), p.Stats().Refreshes == keys)
+}
+
+func main() {
+	flag.DurationVar(&originLatency, "latency", originLatency, "源站每次请求的延迟")
+	callers := flag.Int("callers", 100, "第1部分并发请求同一个键的调用方数量")
+	demoflag.Parse()
+
+	fmt.Println("=== 请求合并的读穿透缓存代理演示 ===")
+	fmt.Printf("源站延迟 %v，TTL 1分钟，宽限期 5分钟（手动推进的时钟），2个刷新工作者，刷新队列容量4\n", originLatency)
+
+	fmt.Printf("\n1. 请求合并：冷启动时%d个并发请求同一个键\n", *callers)
+	demoCoalescing(*callers)
+
+	fmt.Println("\n2. stale-while-revalidate")
+	demoStaleWhileRevalidate()
+
+	fmt.Println("\n3. 源站故障时返回旧值")
+	demoStaleIfError()
+
+	fmt.Println("\n4. 大量键同时过期：有界的后台刷新")
+	demoRefreshLimit()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 不合并时并发未命中全部穿透到源站，热点键过期的瞬间源站压力放大N倍")
+	fmt.Println("2. 合并后N个调用方共享一次源站请求，但它们仍然都要等源站的延迟")
+	fmt.Println("3. 宽限期内调用方完全不等源站，刷新在后台进行；这是用一致性换延迟")
+	fmt.Println("4. 源站故障时旧值继续可用，宽限期的长度决定了能容忍多久的故障")
+	fmt.Println("5. 后台刷新受工作者数量和队列容量限制，被丢弃的刷新会在下一次访问时重新触发")
+}
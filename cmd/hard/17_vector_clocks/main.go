@@ -0,0 +1,442 @@
+/*
+Golang并发编程学习Demo - 困难级别
+文件：cmd/hard/17_vector_clocks/main.go
+主题：向量时钟与因果关系检测
+
+本示例演示：
+1. pkg/vclock：Tick、Merge、Compare，判断两个事件是"之前/之后"还是"并发"
+2. 三个进程（goroutine）按固定脚本收发消息，打印每个事件的向量时钟和Lamport时钟，
+   列出所有并发的事件对
+3. 四个进程随机收发消息，根据程序顺序和消息边计算真实的happens-before关系，
+   逐对验证向量时钟的判断与之完全一致；Lamport时钟则把大量并发事件误排成先后关系
+4. 多副本KV存储：用向量时钟给值打版本，两个客户端在不同副本上并发写同一个键时保留两个"兄弟"版本，
+   读到兄弟版本的客户端合并后写回，新版本覆盖两者
+
+核心概念：
+- happens-before：同一进程内的先后、发送先于对应的接收，以及它们的传递闭包
+- 向量时钟的大小与进程数成正比，但能精确刻画因果关系：a -> b 当且仅当 VC(a) < VC(b)
+- Lamport时钟只有一个整数，a -> b 能推出 L(a) < L(b)，反过来不成立
+- 并发写不是错误而是需要解决的冲突：按墙上时钟"后写者胜"会悄悄丢掉一个写入，
+  向量时钟能发现冲突并交给应用合并（Dynamo、Riak的做法）
+
+运行方式：go run ./cmd/hard/17_vector_clocks [-procs=4] [-steps=25]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/vclock"
+)
+
+// event 一个已发生的事件
+type event struct {
+	seq     int // 全局编号，用于计算真实的因果关系
+	name    string
+	proc    string
+	vc      vclock.Clock
+	lamport int
+	prev    int // 同一进程的上一个事件，-1表示没有
+	from    int // 接收事件对应的发送事件，-1表示不是接收
+}
+
+// recorder 所有进程共享的事件记录
+type recorder struct {
+	mu     sync.Mutex
+	events []*event
+}
+
+func (r *recorder) add(e *event) *event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.seq = len(r.events)
+	r.events = append(r.events, e)
+	return e
+}
+
+type message struct {
+	vc      vclock.Clock
+	lamport int
+	send    int // 发送事件的编号
+	body    string
+}
+
+// process 一个进程：只在自己的goroutine中修改时钟
+type process struct {
+	id      string
+	vc      vclock.Clock
+	lamport int
+	last    int
+	inbox   chan message
+	rec     *recorder
+}
+
+func newProcess(id string, rec *recorder) *process {
+	return &process{id: id, vc: vclock.New(), last: -1, inbox: make(chan message, 100), rec: rec}
+}
+
+func (p *process) record(name string, from int) *event {
+	e := p.rec.add(&event{name: name, proc: p.id, vc: p.vc.Copy(), lamport: p.lamport, prev: p.last, from: from})
+	p.last = e.seq
+	return e
+}
+
+func (p *process) local(name string) {
+	p.vc.Tick(p.id)
+	p.lamport++
+	p.record(name, -1)
+}
+
+func (p *process) send(to *process, name string) {
+	p.vc.Tick(p.id)
+	p.lamport++
+	e := p.record(name, -1)
+	to.inbox <- message{vc: p.vc.Copy(), lamport: p.lamport, send: e.seq, body: name}
+}
+
+// receive 阻塞接收一条消息：先合并再Tick
+func (p *process) receive(name string) {
+	m := <-p.inbox
+	p.deliver(name, m)
+}
+
+func (p *process) deliver(name string, m message) {
+	p.vc.Merge(m.vc)
+	p.vc.Tick(p.id)
+	p.lamport = max(p.lamport, m.lamport) + 1
+	p.record(name, m.send)
+}
+
+// pad 按显示宽度补齐，中文字符占两列
+func pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return s + strings.Repeat(" ", max(width-w, 0))
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// ---------- 1. 固定脚本 ----------
+
+func demoScript() {
+	rec := &recorder{}
+	a, b, c := newProcess("A", rec), newProcess("B", rec), newProcess("C", rec)
+
+	var wg sync.WaitGroup
+	run := func(steps func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			steps()
+		}()
+	}
+	run(func() {
+		a.local("a1")
+		a.send(b, "a2(发给B)")
+		a.local("a3")
+		a.receive("a4(收到C)")
+	})
+	run(func() {
+		b.receive("b1(收到A)")
+		b.send(c, "b2(发给C)")
+		b.local("b3")
+	})
+	run(func() {
+		c.local("c1")
+		c.receive("c2(收到B)")
+		c.send(a, "c3(发给A)")
+	})
+	wg.Wait()
+
+	byName := make(map[string]*event)
+	fmt.Printf("  %s%s%s\n", pad("事件", 14), pad("向量时钟", 18), "Lamport")
+	events := append([]*event(nil), rec.events...)
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].proc != events[j].proc {
+			return events[i].proc < events[j].proc
+		}
+		return events[i].vc[events[i].proc] < events[j].vc[events[j].proc]
+	})
+	for _, e := range events {
+		byName[strings.SplitN(e.name, "(", 2)[0]] = e
+		fmt.Printf("  %s%s%d\n", pad(e.name, 14), pad(e.vc.String(), 18), e.lamport)
+	}
+
+	var concurrent []string
+	for i, x := range events {
+		for _, y := range events[i+1:] {
+			if x.vc.ConcurrentWith(y.vc) {
+				concurrent = append(concurrent, fmt.Sprintf("%s||%s", strings.SplitN(x.name, "(", 2)[0], strings.SplitN(y.name, "(", 2)[0]))
+			}
+		}
+	}
+	fmt.Printf("  并发的事件对: %s\n", strings.Join(concurrent, " "))
+
+	check("a1 -> b3：经由 a2 -> b1 的消息传递", byName["a1"].vc.HappenedBefore(byName["b3"].vc))
+	check("c1 -> a4：c1在C上先于c3，c3的消息被a4接收", byName["c1"].vc.HappenedBefore(byName["a4"].vc))
+	check("a3 || c2：两个进程各自发生，之间没有消息链", byName["a3"].vc.ConcurrentWith(byName["c2"].vc))
+	check("b3 || a4：b3之后B没有再发消息", byName["b3"].vc.ConcurrentWith(byName["a4"].vc))
+	l1, l2 := byName["c1"].lamport, byName["a3"].lamport
+	check(fmt.Sprintf("Lamport时钟: L(c1)=%d < L(a3)=%d，但c1和a3其实是并发的", l1, l2),
+		l1 < l2 && byName["c1"].vc.ConcurrentWith(byName["a3"].vc))
+}
+
+// ---------- 2. 随机收发与验证 ----------
+
+// happensBefore 根据程序顺序边和消息边计算传递闭包：hb[i][j]表示事件i -> 事件j
+func happensBefore(events []*event) [][]bool {
+	n := len(events)
+	hb := make([][]bool, n)
+	for i := range hb {
+		hb[i] = make([]bool, n)
+	}
+	// 事件编号按记录顺序分配，每条边都从编号小的指向编号大的，按编号顺序传播即可
+	for _, e := range events {
+		for _, p := range []int{e.prev, e.from} {
+			if p < 0 {
+				continue
+			}
+			hb[p][e.seq] = true
+			for k := 0; k < n; k++ {
+				if hb[k][p] {
+					hb[k][e.seq] = true
+				}
+			}
+		}
+	}
+	return hb
+}
+
+// demoRandom procs个进程各执行steps步随机的本地事件、发送和接收
+func demoRandom(procs, steps int) {
+	rec := &recorder{}
+	ps := make([]*process, procs)
+	for i := range ps {
+		ps[i] = newProcess(string(rune('A'+i)), rec)
+	}
+
+	var wg sync.WaitGroup
+	for i, p := range ps {
+		wg.Add(1)
+		go func(i int, p *process) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(i)))
+			for s := 0; s < steps; s++ {
+				switch r := rng.Intn(10); {
+				case r < 3:
+					p.local(fmt.Sprintf("%s本地%d", p.id, s))
+				case r < 6:
+					to := ps[(i+1+rng.Intn(procs-1))%procs]
+					p.send(to, fmt.Sprintf("%s发给%s", p.id, to.id))
+				default:
+					select {
+					case m := <-p.inbox:
+						p.deliver(fmt.Sprintf("%s收到%s", p.id, m.body), m)
+					default:
+						p.local(fmt.Sprintf("%s空闲%d", p.id, s))
+					}
+				}
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	events := rec.events
+	hb := happensBefore(events)
+	var pairs, ordered, concurrent, mismatches, lamportWrong int
+	for i, x := range events {
+		for j, y := range events {
+			if i >= j {
+				continue
+			}
+			pairs++
+			order := x.vc.Compare(y.vc)
+			truth := vclock.Concurrent
+			if hb[i][j] {
+				truth = vclock.Before
+			} else if hb[j][i] {
+				truth = vclock.After
+			}
+			if order != truth {
+				mismatches++
+			}
+			if truth == vclock.Concurrent {
+				concurrent++
+				if x.lamport != y.lamport {
+					lamportWrong++ // Lamport时钟给出了先后，但两者没有因果关系
+				}
+			} else {
+				ordered++
+			}
+		}
+	}
+	fmt.Printf("  %d个进程共 %d 个事件，%d 对事件中有因果关系的 %d 对，并发的 %d 对\n", procs, len(events), pairs, ordered, concurrent)
+	check(fmt.Sprintf("向量时钟的判断与真实happens-before关系完全一致（不一致 %d 对）", mismatches), mismatches == 0)
+	check(fmt.Sprintf("Lamport时钟把 %d/%d 对并发事件排成了先后关系", lamportWrong, concurrent), lamportWrong > 0)
+}
+
+// ---------- 3. 多副本KV与冲突检测 ----------
+
+type version struct {
+	val string
+	vc  vclock.Clock
+}
+
+// replica 一个副本，同一个键可能同时保存多个并发的兄弟版本
+type replica struct {
+	id       string
+	mu       sync.Mutex
+	versions []version
+}
+
+// Get 返回所有兄弟版本的值和合并后的上下文时钟，客户端写回时带上这个上下文
+func (r *replica) Get() ([]string, vclock.Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ctx := vclock.New()
+	var vals []string
+	for _, v := range r.versions {
+		vals = append(vals, v.val)
+		ctx.Merge(v.vc)
+	}
+	sort.Strings(vals)
+	return vals, ctx
+}
+
+// Put 客户端基于ctx（它读到的版本）写入新值
+func (r *replica) Put(val string, ctx vclock.Clock) vclock.Clock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	vc := ctx.Copy()
+	vc.Merge(r.ownClock()) // 保证本副本的分量单调递增
+	vc.Tick(r.id)
+	r.store(version{val: val, vc: vc})
+	return vc
+}
+
+// ownClock 本副本已经用过的最大计数，调用方需持有r.mu
+func (r *replica) ownClock() vclock.Clock {
+	c := vclock.New()
+	for _, v := range r.versions {
+		c[r.id] = max(c[r.id], v.vc[r.id])
+	}
+	return c
+}
+
+// store 写入一个版本：被它覆盖（之前或相等）的旧版本删除，并发的保留为兄弟；调用方需持有r.mu
+func (r *replica) store(nv version) {
+	kept := r.versions[:0]
+	for _, v := range r.versions {
+		switch v.vc.Compare(nv.vc) {
+		case vclock.After, vclock.Equal:
+			return // 已经有更新或相同的版本
+		case vclock.Concurrent:
+			kept = append(kept, v)
+		}
+	}
+	r.versions = append(kept, nv)
+}
+
+// SyncFrom 反熵：把other的所有版本合并进来
+func (r *replica) SyncFrom(other *replica) {
+	other.mu.Lock()
+	incoming := make([]version, len(other.versions))
+	for i, v := range other.versions {
+		incoming[i] = version{val: v.val, vc: v.vc.Copy()}
+	}
+	other.mu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range incoming {
+		r.store(v)
+	}
+}
+
+func demoKV() {
+	a, b := &replica{id: "A"}, &replica{id: "B"}
+	show := func(step string) {
+		va, ca := a.Get()
+		vb, cb := b.Get()
+		fmt.Printf("  %s副本A: %s副本B: %v %s\n", pad(step, 32), pad(fmt.Sprintf("%v %s", va, ca), 28), vb, cb)
+	}
+
+	a.Put("v1", vclock.New())
+	b.SyncFrom(a)
+	show("Alice在A写v1并同步到B")
+
+	_, ctxA := a.Get()
+	a.Put("alice", ctxA)
+	_, ctxB := b.Get()
+	b.Put("bob", ctxB)
+	show("Alice在A、Bob在B并发写")
+
+	a.SyncFrom(b)
+	b.SyncFrom(a)
+	show("双向同步后")
+	va, _ := a.Get()
+	vb, _ := b.Get()
+	check("两个并发写都被保留为兄弟版本，没有一个被悄悄覆盖", len(va) == 2 && len(vb) == 2)
+
+	vals, ctx := b.Get()
+	b.Put(strings.Join(vals, "+"), ctx)
+	a.SyncFrom(b)
+	show("Carol读到两个版本，合并后写回")
+	va, _ = a.Get()
+	vb, _ = b.Get()
+	check(fmt.Sprintf("合并后的版本覆盖了两个兄弟版本: %v", va), len(va) == 1 && va[0] == "alice+bob" && len(vb) == 1)
+
+	// 基于旧上下文的写入与合并版本并发，又会产生兄弟版本
+	a.Put("stale", ctxA)
+	b.SyncFrom(a)
+	vb, _ = b.Get()
+	check(fmt.Sprintf("基于旧版本的写入不会覆盖更新的版本，而是成为兄弟: %v", vb), len(vb) == 2)
+}
+
+func main() {
+	procs := flag.Int("procs", 4, "第2部分随机收发消息的进程数（2到26）")
+	steps := flag.Int("steps", 25, "第2部分每个进程执行的步数")
+	demoflag.Parse()
+	if *procs < 2 || *procs > 26 {
+		fmt.Fprintln(os.Stderr, "procs必须在2到26之间")
+		os.Exit(2)
+	}
+
+	fmt.Println("=== 向量时钟演示 ===")
+
+	fmt.Println("\n1. 三个进程按脚本收发消息")
+	demoScript()
+
+	fmt.Printf("\n2. %d个进程随机收发消息，验证向量时钟\n", *procs)
+	demoRandom(*procs, *steps)
+
+	fmt.Println("\n3. 多副本KV：用向量时钟检测并发写")
+	demoKV()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 接收消息时先Merge再Tick，发送方之前的所有事件因此都\"发生在\"接收之前")
+	fmt.Println("2. 向量时钟的比较结果与按消息边计算的happens-before关系逐对一致")
+	fmt.Println("3. Lamport时钟对并发事件也给出了大小，只能用来做全序排序，不能用来判断因果")
+	fmt.Println("4. 副本用向量时钟区分\"新版本覆盖旧版本\"和\"两个并发写\"，后者保留兄弟版本交给应用合并")
+	fmt.Println("5. 客户端写入时必须带上读到的上下文，否则服务端无法知道这次写入是基于哪些版本")
+}
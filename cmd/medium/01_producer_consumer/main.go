@@ -0,0 +1,471 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/poisonpill"
+)
+
+// 生产者消费者模式演示
+type Product struct {
+	ID    int
+	Name  string
+	Price float64
+}
+
+func producer(id, items int, products chan<- Product) {
+	for i := 1; i <= items; i++ {
+		product := Product{
+			ID:    id*100 + i,
+			Name:  fmt.Sprintf("产品-%d-%d", id, i),
+			Price: rand.Float64() * 100,
+		}
+
+		fmt.Printf("生产者 %d 生产: %+v\n", id, product)
+		products <- product
+
+		// 随机生产间隔
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
+	}
+
+	fmt.Printf("生产者 %d 完成生产\n", id)
+}
+
+func consumer(id int, product Product) {
+	fmt.Printf("消费者 %d 消费: ID=%d, Name=%s, Price=%.2f\n",
+		id, product.ID, product.Name, product.Price)
+
+	// 模拟消费时间
+	time.Sleep(time.Duration(200+rand.Intn(300)) * time.Millisecond)
+}
+
+// ===== 协调器：停止信号、限时排空与吞吐统计 =====
+
+// workerStats 单个生产者/消费者的计数
+type workerStats struct {
+	count int64
+}
+
+// Coordinator 管理生产者和消费者的生命周期
+// 停止流程：关闭stop通知生产者退出 -> 关闭products -> 消费者在截止时间内排空剩余产品
+type Coordinator struct {
+	products chan Product
+	stop     chan struct{} // 关闭后生产者停止生产
+	abort    chan struct{} // 排空超时后关闭，消费者放弃剩余产品
+	stopOnce sync.Once
+
+	producerWg sync.WaitGroup
+	consumerWg sync.WaitGroup
+
+	producers []*workerStats
+	consumers []*workerStats
+	start     time.Time
+}
+
+func NewCoordinator(bufferSize, numProducers, numConsumers int) *Coordinator {
+	c := &Coordinator{
+		products:  make(chan Product, bufferSize),
+		stop:      make(chan struct{}),
+		abort:     make(chan struct{}),
+		producers: make([]*workerStats, numProducers),
+		consumers: make([]*workerStats, numConsumers),
+	}
+	for i := range c.producers {
+		c.producers[i] = &workerStats{}
+	}
+	for i := range c.consumers {
+		c.consumers[i] = &workerStats{}
+	}
+	return c
+}
+
+// Start 启动所有生产者和消费者
+func (c *Coordinator) Start() {
+	c.start = time.Now()
+
+	for i := range c.producers {
+		c.producerWg.Add(1)
+		go c.produce(i + 1)
+	}
+	for i := range c.consumers {
+		c.consumerWg.Add(1)
+		go c.consume(i + 1)
+	}
+}
+
+// produce 持续生产直到收到停止信号
+func (c *Coordinator) produce(id int) {
+	defer c.producerWg.Done()
+	stats := c.producers[id-1]
+
+	for i := 1; ; i++ {
+		product := Product{
+			ID:    id*1000 + i,
+			Name:  fmt.Sprintf("产品-%d-%d", id, i),
+			Price: rand.Float64() * 100,
+		}
+
+		// 缓冲区满时也要能响应停止信号
+		select {
+		case c.products <- product:
+			atomic.AddInt64(&stats.count, 1)
+		case <-c.stop:
+			fmt.Printf("生产者 %d 收到停止信号\n", id)
+			return
+		}
+
+		select {
+		case <-time.After(time.Duration(rand.Intn(200)) * time.Millisecond):
+		case <-c.stop:
+			fmt.Printf("生产者 %d 收到停止信号\n", id)
+			return
+		}
+	}
+}
+
+// consume 消费直到products被关闭并排空，或排空超时
+func (c *Coordinator) consume(id int) {
+	defer c.consumerWg.Done()
+	stats := c.consumers[id-1]
+
+	for {
+		select {
+		case <-c.abort:
+			fmt.Printf("消费者 %d 排空超时，放弃剩余产品\n", id)
+			return
+		case _, ok := <-c.products:
+			if !ok {
+				fmt.Printf("消费者 %d 已排空全部产品\n", id)
+				return
+			}
+			time.Sleep(time.Duration(100+rand.Intn(100)) * time.Millisecond)
+			atomic.AddInt64(&stats.count, 1)
+		}
+	}
+}
+
+// Stop 发出停止信号并在drainTimeout内排空缓冲区，返回被丢弃的产品数
+func (c *Coordinator) Stop(drainTimeout time.Duration) int {
+	c.stopOnce.Do(func() { close(c.stop) })
+
+	// 生产者全部退出后才能安全关闭products
+	c.producerWg.Wait()
+	fmt.Printf("生产者全部停止，缓冲区剩余 %d 个产品，开始排空（期限 %v）\n", len(c.products), drainTimeout)
+	close(c.products)
+
+	done := make(chan struct{})
+	go func() {
+		c.consumerWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(drainTimeout):
+		close(c.abort)
+		<-done
+	}
+
+	// 消费者已全部退出，剩下的都是被丢弃的
+	dropped := 0
+	for range c.products {
+		dropped++
+	}
+	return dropped
+}
+
+// PrintStats 打印每个生产者和消费者的数量与吞吐
+func (c *Coordinator) PrintStats() {
+	elapsed := time.Since(c.start).Seconds()
+
+	var produced, consumed int64
+	for i, s := range c.producers {
+		n := atomic.LoadInt64(&s.count)
+		produced += n
+		fmt.Printf("  生产者 %d: 生产 %3d 个, %.1f 个/秒\n", i+1, n, float64(n)/elapsed)
+	}
+	for i, s := range c.consumers {
+		n := atomic.LoadInt64(&s.count)
+		consumed += n
+		fmt.Printf("  消费者 %d: 消费 %3d 个, %.1f 个/秒\n", i+1, n, float64(n)/elapsed)
+	}
+	fmt.Printf("  合计: 生产 %d, 消费 %d, 耗时 %.2f秒\n", produced, consumed, elapsed)
+}
+
+// demoCoordinator 运行一段时间后停止，分别演示排空成功与排空超时
+func demoCoordinator(drainTimeout time.Duration) {
+	c := NewCoordinator(20, 3, 2)
+	c.Start()
+
+	time.Sleep(2 * time.Second)
+	fmt.Println("发出停止信号...")
+	dropped := c.Stop(drainTimeout)
+	fmt.Printf("停止完成，丢弃 %d 个产品\n", dropped)
+	c.PrintStats()
+}
+
+// ===== 毒丸（poison pill）关闭协议 =====
+
+// demoPoisonPill 用毒丸逐个停止消费者（pkg/poisonpill，测试见 go test -race ./pkg/poisonpill）
+// 1. 运行中发一个毒丸，只停掉一个消费者（缩容），channel和其余消费者不受影响
+// 2. 所有生产者结束后，为剩余的每个消费者各发一个毒丸
+func demoPoisonPill() {
+	const numProducers = 2
+	const numConsumers = 3
+
+	var produced, consumed int64
+	consumers := poisonpill.New(10,
+		func(int, Product) {
+			time.Sleep(time.Duration(50+rand.Intn(50)) * time.Millisecond)
+			atomic.AddInt64(&consumed, 1)
+		},
+		func(id int) { fmt.Printf("消费者 %d 收到毒丸，退出\n", id) })
+	consumers.Add(numConsumers)
+
+	var producerWg sync.WaitGroup
+	for i := 1; i <= numProducers; i++ {
+		producerWg.Add(1)
+		go func(id int) {
+			defer producerWg.Done()
+			for j := 1; j <= 10; j++ {
+				consumers.Send(Product{ID: id*100 + j, Name: fmt.Sprintf("产品-%d-%d", id, j)})
+				atomic.AddInt64(&produced, 1)
+				time.Sleep(time.Duration(rand.Intn(60)) * time.Millisecond)
+			}
+		}(i)
+	}
+
+	// 缩容：毒丸排在已入队的产品之后，之前的产品仍会被处理
+	time.Sleep(200 * time.Millisecond)
+	fmt.Println("缩容: 发送1个毒丸")
+	consumers.Stop(1)
+
+	// 毒丸必须在所有生产者结束之后发送，否则排在毒丸后面的产品可能没人消费
+	producerWg.Wait()
+	fmt.Printf("生产者全部完成，发送 %d 个毒丸\n", numConsumers-1)
+	consumers.StopAll()
+	fmt.Printf("生产 %d 个, 消费 %d 个, channel剩余 %d 个\n",
+		atomic.LoadInt64(&produced), atomic.LoadInt64(&consumed), consumers.Len())
+}
+
+// ===== 优先级通道 =====
+
+type Priority int
+
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+)
+
+func (p Priority) String() string {
+	return [...]string{"高", "中", "低"}[p]
+}
+
+type laneItem struct {
+	product  Product
+	enqueued time.Time
+}
+
+// laneStats 每条通道的公平性计数
+type laneStats struct {
+	consumed  int64
+	totalWait int64 // 纳秒
+	maxWait   int64 // 纳秒
+}
+
+// PriorityLanes 高/中/低三条独立的通道，消费者总是优先取高优先级的产品
+type PriorityLanes struct {
+	lanes [3]chan laneItem
+	stats [3]laneStats
+}
+
+func NewPriorityLanes(bufferSize int) *PriorityLanes {
+	l := &PriorityLanes{}
+	for i := range l.lanes {
+		l.lanes[i] = make(chan laneItem, bufferSize)
+	}
+	return l
+}
+
+func (l *PriorityLanes) Send(priority Priority, product Product) {
+	l.lanes[priority] <- laneItem{product: product, enqueued: time.Now()}
+}
+
+// Close 关闭所有通道，消费者取完剩余产品后退出
+func (l *PriorityLanes) Close() {
+	for _, lane := range l.lanes {
+		close(lane)
+	}
+}
+
+// next 带偏向的select：先只看高优先级，再看高+中，都没有时才阻塞等待任意通道
+// 已关闭的通道置为nil，select不会再选中它
+func next(lanes *[3]chan laneItem) (laneItem, Priority, bool) {
+	for lanes[PriorityHigh] != nil || lanes[PriorityNormal] != nil || lanes[PriorityLow] != nil {
+		var (
+			item     laneItem
+			ok       bool
+			priority Priority
+		)
+
+		select {
+		case item, ok = <-lanes[PriorityHigh]:
+			priority = PriorityHigh
+		default:
+			select {
+			case item, ok = <-lanes[PriorityHigh]:
+				priority = PriorityHigh
+			case item, ok = <-lanes[PriorityNormal]:
+				priority = PriorityNormal
+			default:
+				select {
+				case item, ok = <-lanes[PriorityHigh]:
+					priority = PriorityHigh
+				case item, ok = <-lanes[PriorityNormal]:
+					priority = PriorityNormal
+				case item, ok = <-lanes[PriorityLow]:
+					priority = PriorityLow
+				}
+			}
+		}
+
+		if !ok {
+			lanes[priority] = nil
+			continue
+		}
+		return item, priority, true
+	}
+	return laneItem{}, 0, false
+}
+
+func (l *PriorityLanes) consume(order chan<- Priority, wg *sync.WaitGroup) {
+	defer wg.Done()
+	lanes := l.lanes // 每个消费者持有自己的副本，便于把已关闭的通道置nil
+
+	for {
+		item, priority, ok := next(&lanes)
+		if !ok {
+			return
+		}
+
+		wait := int64(time.Since(item.enqueued))
+		stats := &l.stats[priority]
+		atomic.AddInt64(&stats.consumed, 1)
+		atomic.AddInt64(&stats.totalWait, wait)
+		for {
+			cur := atomic.LoadInt64(&stats.maxWait)
+			if wait <= cur || atomic.CompareAndSwapInt64(&stats.maxWait, cur, wait) {
+				break
+			}
+		}
+
+		order <- priority
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func (l *PriorityLanes) PrintStats() {
+	for p := PriorityHigh; p <= PriorityLow; p++ {
+		s := &l.stats[p]
+		n := atomic.LoadInt64(&s.consumed)
+		var avg time.Duration
+		if n > 0 {
+			avg = time.Duration(atomic.LoadInt64(&s.totalWait) / n)
+		}
+		fmt.Printf("  %s优先级: 消费 %2d 个, 平均等待 %-8v 最长等待 %v\n",
+			p, n, avg.Round(time.Millisecond), time.Duration(atomic.LoadInt64(&s.maxWait)).Round(time.Millisecond))
+	}
+}
+
+// demoPriorityLanes 三个生产者分别向三条通道生产，消费速度跟不上时高优先级先被消费
+func demoPriorityLanes() {
+	const perLane = 15
+
+	lanes := NewPriorityLanes(perLane)
+	order := make(chan Priority, 3*perLane)
+
+	var producerWg, consumerWg sync.WaitGroup
+	for p := PriorityHigh; p <= PriorityLow; p++ {
+		producerWg.Add(1)
+		go func(p Priority) {
+			defer producerWg.Done()
+			for i := 1; i <= perLane; i++ {
+				lanes.Send(p, Product{ID: int(p)*100 + i, Name: fmt.Sprintf("%s-%d", p, i)})
+				time.Sleep(10 * time.Millisecond)
+			}
+		}(p)
+	}
+
+	for i := 1; i <= 2; i++ {
+		consumerWg.Add(1)
+		go lanes.consume(order, &consumerWg)
+	}
+
+	producerWg.Wait()
+	lanes.Close()
+	consumerWg.Wait()
+	close(order)
+
+	fmt.Print("消费顺序: ")
+	for p := range order {
+		fmt.Print(p)
+	}
+	fmt.Println()
+	lanes.PrintStats()
+}
+
+func main() {
+	bufferSize := flag.Int("buffer", 10, "产品channel的缓冲大小")
+	numProducers := flag.Int("producers", 3, "生产者数量")
+	numConsumers := flag.Int("consumers", 2, "消费者数量")
+	items := flag.Int("items", 5, "每个生产者生产的产品数量")
+	demoflag.Parse()
+
+	fmt.Println("=== 生产者消费者模式演示 ===")
+
+	rand.Seed(time.Now().UnixNano())
+
+	// 所有生产者完成后关闭channel，消费者range读取直到channel关闭并排空
+	poisonpill.Closing[Product]{
+		Buffer:    *bufferSize,
+		Producers: *numProducers,
+		Consumers: *numConsumers,
+		Produce: func(id int, out chan<- Product) {
+			producer(id, *items, out)
+		},
+		Handle: consumer,
+		OnClosed: func() {
+			fmt.Println("所有生产者完成，产品channel已关闭")
+		},
+		OnExit: func(id int) {
+			fmt.Printf("消费者 %d 结束消费\n", id)
+		},
+	}.Run()
+	fmt.Println("所有消费者完成！")
+
+	fmt.Println("\n=== 协调器: 充足的排空期限 ===")
+	demoCoordinator(5 * time.Second)
+
+	fmt.Println("\n=== 协调器: 排空期限不足 ===")
+	demoCoordinator(300 * time.Millisecond)
+
+	fmt.Println("\n=== 毒丸关闭协议 ===")
+	demoPoisonPill()
+
+	fmt.Println("\n两种关闭方式的选择：")
+	fmt.Println("1. 关闭channel：所有消费者一起退出；要求只有一方（生产者侧）负责关闭，且关闭后不能再发送")
+	fmt.Println("2. 毒丸：可以只停掉一部分消费者，channel保持可用；适合动态缩容、channel不归生产者所有等场景")
+	fmt.Println("3. 毒丸需要知道消费者数量，并且必须在最后一个产品之后发送")
+	fmt.Println("4. 用 go run -race 运行本文件可以验证两种方式都没有数据竞争")
+
+	fmt.Println("\n=== 优先级通道 ===")
+	demoPriorityLanes()
+	fmt.Println("注意: 高优先级持续繁忙时低优先级会一直等待（饥饿），最长等待时间可以用来发现这种情况")
+}
@@ -0,0 +1,664 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/chanx"
+	"github.com/klsakura/day1/pkg/contextkeys"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/future"
+	"github.com/klsakura/day1/pkg/workerpool"
+)
+
+// newPool 创建演示用的工作池，把工作池的事件打印出来
+// 模拟处理时间：优先级高的任务处理更快
+func newPool(numWorkers, queueSize int, agingInterval time.Duration) *workerpool.WorkerPool {
+	return workerpool.New(workerpool.Config{
+		Workers:       numWorkers,
+		QueueSize:     queueSize,
+		AgingInterval: agingInterval,
+		Delay: func(task workerpool.Task) time.Duration {
+			return time.Duration(500-task.Priority*100) * time.Millisecond
+		},
+		OnDispatch: func(ctx context.Context, task workerpool.Task, effective int, waited time.Duration) {
+			if effective > task.Priority {
+				fmt.Printf("%s调度任务 %d (优先级: %d, 老化后: %d, 已等待: %v)\n",
+					contextkeys.LogPrefix(ctx), task.ID, task.Priority, effective, waited.Round(time.Millisecond))
+			} else {
+				fmt.Printf("%s调度任务 %d (优先级: %d)\n", contextkeys.LogPrefix(ctx), task.ID, task.Priority)
+			}
+		},
+		OnStart: func(ctx context.Context, worker int, task workerpool.Task) {
+			fmt.Printf("%s工作者 %d 开始处理任务 %d (优先级: %d)\n",
+				contextkeys.LogPrefix(ctx), worker, task.ID, task.Priority)
+		},
+		OnFinish: func(ctx context.Context, worker int, result workerpool.TaskResult) {
+			prefix := contextkeys.LogPrefix(ctx)
+			if result.Err != nil {
+				fmt.Printf("%s工作者 %d 处理任务 %d 失败: %v\n", prefix, worker, result.TaskID, result.Err)
+			} else {
+				fmt.Printf("%s工作者 %d 完成任务 %d，结果: %d\n", prefix, worker, result.TaskID, result.Sum)
+			}
+		},
+		OnExit: func(worker int) {
+			fmt.Printf("工作者 %d 退出\n", worker)
+		},
+		OnReject: func(task workerpool.Task, err error) {
+			fmt.Printf("拒绝任务 %d: %v\n", task.ID, err)
+		},
+		OnCallerRuns: func(task workerpool.Task) {
+			fmt.Printf("队列已满，任务 %d 由提交者执行\n", task.ID)
+		},
+	})
+}
+
+// startReporter 启动后台协程，每隔interval打印一次工作池的运行状态，返回停止函数
+func startReporter(pool *workerpool.WorkerPool, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Printf("[监控] %v\n", pool.GetStats())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func main() {
+	workers := flag.Int("workers", 2, "工作者数量")
+	aging := flag.Duration("aging", 500*time.Millisecond, "任务每等待多久有效优先级+1")
+	demoflag.Parse()
+
+	fmt.Println("=== 高级工作池演示 ===")
+
+	// 默认2个工作者，队列不限容量（全部任务在启动前提交），任务每等待500毫秒有效优先级+1
+	pool := newPool(*workers, 0, *aging)
+
+	// 提交不同优先级的任务
+	tasks := []workerpool.Task{
+		{ID: 1, Data: []int{1, 2, 3, 4}, Priority: 1},
+		{ID: 2, Data: []int{5, 6, 7, 8}, Priority: 3},
+		{ID: 3, Data: []int{9, 10, 11}, Priority: 2},
+		{ID: 4, Data: []int{12, 13, 14, 15}, Priority: 1},
+		{ID: 5, Data: []int{16, 17}, Priority: 3},
+		{ID: 6, Data: []int{18, 19}, Priority: 4},
+		{ID: 7, Data: []int{20, 21, 22}, Priority: 2},
+		// 返回错误的任务
+		{ID: 8, Data: []int{}, Priority: 2, Process: func(ctx context.Context, data []int) (int, error) {
+			if len(data) == 0 {
+				return 0, fmt.Errorf("task data is empty")
+			}
+			return workerpool.SumData(ctx, data)
+		}},
+		// 发生panic的任务：越界访问，工作者会recover并继续处理后续任务
+		{ID: 9, Data: []int{1, 2}, Priority: 2, Process: func(ctx context.Context, data []int) (int, error) {
+			return data[5], nil
+		}},
+		// 超时的任务：模拟处理需要300毫秒，时限只有100毫秒
+		{ID: 10, Data: []int{1, 1}, Priority: 2, Timeout: 100 * time.Millisecond},
+		// 不响应ctx的任务：工作者在时限到达后放弃等待，不会被它卡住
+		{ID: 11, Data: []int{2, 2}, Priority: 2, Timeout: 400 * time.Millisecond,
+			Process: func(ctx context.Context, data []int) (int, error) {
+				time.Sleep(time.Second)
+				return workerpool.SumData(ctx, data)
+			}},
+	}
+
+	// 先提交全部任务再启动，观察任务按优先级而不是提交顺序出队
+	// 每个任务携带一个请求ID，调度和执行日志都会带上它
+	var futures []*workerpool.TaskFuture
+	for _, task := range tasks {
+		ctx := contextkeys.WithRequestID(context.Background(), contextkeys.NewRequestID())
+		tf, err := pool.SubmitCtx(ctx, task)
+		if err != nil {
+			fmt.Printf("%s提交任务 %d 失败: %v\n", contextkeys.LogPrefix(ctx), task.ID, err)
+			continue
+		}
+		futures = append(futures, tf)
+	}
+
+	pool.Start()
+
+	// 每秒打印一次运行状态，观察队列深度和工作者饱和情况
+	stopReporter := startReporter(pool, time.Second)
+
+	// 运行过程中持续提交高优先级任务，老化机制保证低优先级任务最终也会被调度
+	lateFutures := make(chan *workerpool.TaskFuture, 4)
+	go func() {
+		defer close(lateFutures)
+		for i := 0; i < 4; i++ {
+			time.Sleep(300 * time.Millisecond)
+			lateFutures <- pool.Submit(workerpool.Task{ID: 100 + i, Data: []int{i, i}, Priority: 3})
+		}
+		pool.Close()
+	}()
+
+	// 单独等待优先级最低的任务1，用Await配合带超时的ctx设置等待上限
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), time.Second)
+	if result, err := futures[0].Await(waitCtx); err == nil {
+		fmt.Printf("\n任务1已完成: 和=%d\n", result.Sum)
+	} else {
+		fmt.Println("\n任务1在1秒内还未完成，继续等待其他任务")
+	}
+	cancelWait()
+
+	// 用future.Then在任务结果上继续计算，不需要额外的goroutine和channel
+	doubled := future.Then(futures[1].Future, func(r workerpool.TaskResult) (int, error) {
+		return r.Sum * 2, nil
+	})
+
+	for tf := range lateFutures {
+		futures = append(futures, tf)
+	}
+
+	// 按提交顺序逐个获取结果，不需要再按TaskID关联
+	fmt.Println("\n任务执行结果:")
+	for _, tf := range futures {
+		result := tf.Result()
+		if result.Err != nil {
+			fmt.Printf("任务 %d: 失败 (工作者 %d): %v\n", result.TaskID, result.Worker, result.Err)
+			continue
+		}
+		fmt.Printf("任务 %d: 和=%d (由工作者 %d 完成)\n",
+			result.TaskID, result.Sum, result.Worker)
+	}
+
+	if v, err := doubled.Get(); err == nil {
+		fmt.Printf("\n任务 %d 的和的两倍 (future.Then): %d\n", futures[1].TaskID(), v)
+	}
+
+	// future.All：全部成功才成功，这批任务中有失败的，所以返回第一个错误
+	all := make([]*future.Future[workerpool.TaskResult], len(futures))
+	for i, f := range futures {
+		all[i] = f.Future
+	}
+	if _, err := future.All(all...).Get(); err != nil {
+		fmt.Printf("future.All: 存在失败的任务: %v\n", err)
+	}
+
+	pool.Wait()
+	stopReporter()
+
+	fmt.Printf("\n最终状态: %v\n", pool.GetStats())
+
+	demoContextShutdown()
+
+	demoRejectionPolicies()
+	demoPriorityChannels()
+	demoWorkStealing()
+
+	fmt.Println("所有任务完成！")
+}
+
+// demoContextShutdown 演示带context的提交和限时关闭
+func demoContextShutdown() {
+	fmt.Println("\n=== Context提交与限时关闭 ===")
+
+	// 1个工作者，队列容量为3
+	pool := newPool(1, 3, 0)
+	pool.Start()
+
+	// 提交超过队列容量的任务，队列满时SubmitCtx阻塞，200毫秒后超时放弃
+	var futures []*workerpool.TaskFuture
+	for i := 1; i <= 8; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		tf, err := pool.SubmitCtx(ctx, workerpool.Task{ID: 200 + i, Data: []int{i}, Priority: 1})
+		cancel()
+		if err != nil {
+			fmt.Printf("提交任务 %d 失败: %v\n", 200+i, err)
+			continue
+		}
+		futures = append(futures, tf)
+	}
+
+	// 最多等待500毫秒，剩余排队任务被丢弃
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	discarded, err := pool.Shutdown(ctx)
+	fmt.Printf("关闭结果: 丢弃排队任务 %d 个, err=%v\n", discarded, err)
+
+	// 关闭后提交会被拒绝
+	if _, err := pool.SubmitCtx(context.Background(), workerpool.Task{ID: 299}); err != nil {
+		fmt.Printf("关闭后提交任务 299: %v\n", err)
+	}
+
+	// 被丢弃任务的future以ErrTaskDiscarded完成
+	for _, tf := range futures {
+		if err := tf.Result().Err; err != nil {
+			fmt.Printf("任务 %d: %v\n", tf.TaskID(), err)
+		}
+	}
+
+	// 等待执行中的任务结束，避免与下一段输出交错
+	pool.Wait()
+}
+
+// demoRejectionPolicies 演示过载时不同拒绝策略的准入控制效果
+func demoRejectionPolicies() {
+	fmt.Println("\n=== 队列满时的拒绝策略 ===")
+
+	for _, policy := range []workerpool.RejectionPolicy{workerpool.RejectBlock, workerpool.RejectDrop, workerpool.RejectCallerRuns} {
+		fmt.Printf("\n--- 策略: %v ---\n", policy)
+
+		// 1个工作者，队列容量2，快速提交6个任务造成过载
+		pool := newPool(1, 2, 0)
+		pool.SetRejectionPolicy(policy)
+		pool.Start()
+
+		start := time.Now()
+		var futures []*workerpool.TaskFuture
+		for i := 1; i <= 6; i++ {
+			futures = append(futures, pool.Submit(workerpool.Task{ID: 300 + i, Data: []int{i}, Priority: 4}))
+		}
+		submitCost := time.Since(start)
+
+		for _, tf := range futures {
+			tf.Result()
+		}
+		pool.Close()
+		pool.Wait()
+
+		fmt.Printf("策略 %v: 提交耗时 %v, 总耗时 %v, %v\n", policy,
+			submitCost.Round(time.Millisecond), time.Since(start).Round(time.Millisecond), pool.GetStats())
+	}
+
+	// TrySubmit不会阻塞，由调用者自己决定队列满时怎么办
+	pool := newPool(1, 1, 0)
+	pool.Start()
+	accepted := 0
+	for i := 1; i <= 5; i++ {
+		if _, ok := pool.TrySubmit(workerpool.Task{ID: 400 + i, Data: []int{i}, Priority: 4}); ok {
+			accepted++
+		}
+	}
+	fmt.Printf("\nTrySubmit: 5个任务中接受了 %d 个\n", accepted)
+	pool.Close()
+	pool.Wait()
+}
+
+// ===== 工作窃取模式 =====
+
+// stealTask 工作窃取演示用的轻量任务，cost为模拟的执行时间
+type stealTask struct {
+	id        int
+	cost      time.Duration
+	submitted time.Time
+}
+
+// workDeque 每个工作者私有的双端队列
+// 所有者从头部取最早的任务，窃取者从尾部拿走最新的任务，减少两者争抢同一个任务
+type workDeque struct {
+	mu    sync.Mutex
+	items []stealTask
+}
+
+func (d *workDeque) pushBack(t stealTask) {
+	d.mu.Lock()
+	d.items = append(d.items, t)
+	d.mu.Unlock()
+}
+
+func (d *workDeque) popFront() (stealTask, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.items) == 0 {
+		return stealTask{}, false
+	}
+	t := d.items[0]
+	d.items = d.items[1:]
+	return t, true
+}
+
+func (d *workDeque) popBack() (stealTask, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := len(d.items)
+	if n == 0 {
+		return stealTask{}, false
+	}
+	t := d.items[n-1]
+	d.items = d.items[:n-1]
+	return t, true
+}
+
+func (d *workDeque) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.items)
+}
+
+// StealingPool 每个工作者拥有自己的队列，任务按ID分配到固定的工作者
+// 开启窃取后，自己队列为空的工作者会从其他工作者队列的尾部拿任务
+type StealingPool struct {
+	deques   []*workDeque
+	stealing bool
+	wg       sync.WaitGroup
+	closed   int32
+	steals   int64
+
+	latencyMu sync.Mutex
+	latencies []time.Duration
+}
+
+func NewStealingPool(numWorkers int, stealing bool) *StealingPool {
+	sp := &StealingPool{stealing: stealing}
+	for i := 0; i < numWorkers; i++ {
+		sp.deques = append(sp.deques, &workDeque{})
+	}
+	return sp
+}
+
+// Submit 按任务ID把任务放入对应工作者的队列
+func (sp *StealingPool) Submit(t stealTask) {
+	t.submitted = time.Now()
+	sp.deques[t.id%len(sp.deques)].pushBack(t)
+}
+
+func (sp *StealingPool) Start() {
+	for i := range sp.deques {
+		sp.wg.Add(1)
+		go sp.worker(i)
+	}
+}
+
+// Close 通知工作者在没有可执行的任务后退出
+func (sp *StealingPool) Close() {
+	atomic.StoreInt32(&sp.closed, 1)
+}
+
+func (sp *StealingPool) Wait() {
+	sp.wg.Wait()
+}
+
+func (sp *StealingPool) worker(id int) {
+	defer sp.wg.Done()
+
+	own := sp.deques[id]
+	for {
+		t, ok := own.popFront()
+		if !ok && sp.stealing {
+			t, ok = sp.steal(id)
+		}
+		if !ok {
+			if atomic.LoadInt32(&sp.closed) == 1 && !sp.hasWork(id) {
+				return
+			}
+			time.Sleep(100 * time.Microsecond)
+			continue
+		}
+
+		time.Sleep(t.cost)
+		sp.recordLatency(time.Since(t.submitted))
+	}
+}
+
+// steal 从随机起点开始依次尝试其他工作者队列的尾部
+func (sp *StealingPool) steal(thief int) (stealTask, bool) {
+	n := len(sp.deques)
+	start := rand.Intn(n)
+	for i := 0; i < n; i++ {
+		victim := (start + i) % n
+		if victim == thief {
+			continue
+		}
+		if t, ok := sp.deques[victim].popBack(); ok {
+			atomic.AddInt64(&sp.steals, 1)
+			return t, true
+		}
+	}
+	return stealTask{}, false
+}
+
+// hasWork 判断工作者是否还有任务可做：自己的队列，或开启窃取时任意队列
+func (sp *StealingPool) hasWork(id int) bool {
+	if !sp.stealing {
+		return sp.deques[id].len() > 0
+	}
+	for _, d := range sp.deques {
+		if d.len() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (sp *StealingPool) recordLatency(d time.Duration) {
+	sp.latencyMu.Lock()
+	sp.latencies = append(sp.latencies, d)
+	sp.latencyMu.Unlock()
+}
+
+// Latencies 返回所有任务从提交到完成的耗时
+func (sp *StealingPool) Latencies() []time.Duration {
+	sp.latencyMu.Lock()
+	defer sp.latencyMu.Unlock()
+	return append([]time.Duration(nil), sp.latencies...)
+}
+
+// Steals 返回窃取成功的次数
+func (sp *StealingPool) Steals() int64 {
+	return atomic.LoadInt64(&sp.steals)
+}
+
+// runSharedChannel 对照组：所有工作者从同一个channel取任务
+func runSharedChannel(numWorkers int, tasks []stealTask) []time.Duration {
+	ch := make(chan stealTask, len(tasks))
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies []time.Duration
+	)
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range ch {
+				time.Sleep(t.cost)
+				mu.Lock()
+				latencies = append(latencies, time.Since(t.submitted))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, t := range tasks {
+		t.submitted = time.Now()
+		ch <- t
+	}
+	close(ch)
+	wg.Wait()
+
+	return latencies
+}
+
+// percentile 返回排序后耗时的第p百分位
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+func printBenchmark(name string, elapsed time.Duration, latencies []time.Duration, extra string) {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	throughput := float64(len(latencies)) / elapsed.Seconds()
+	fmt.Printf("%-10s 总耗时=%-8v 吞吐=%6.1f 任务/秒 p50=%-8v p99=%-8v %s\n",
+		name, elapsed.Round(time.Millisecond), throughput,
+		percentile(latencies, 0.50).Round(time.Millisecond),
+		percentile(latencies, 0.99).Round(time.Millisecond), extra)
+}
+
+// levelNames 按优先级分channel时各channel的名字，下标0优先级最高
+var levelNames = []string{"高", "中", "低"}
+
+// priorityChannels 每个优先级一个channel，各预先放入n个任务后关闭
+func priorityChannels(n int) []<-chan workerpool.Task {
+	chans := make([]<-chan workerpool.Task, len(levelNames))
+	for level := range levelNames {
+		ch := make(chan workerpool.Task, n)
+		for i := 0; i < n; i++ {
+			ch <- workerpool.Task{ID: level*1000 + i, Priority: len(levelNames) - level}
+		}
+		close(ch)
+		chans[level] = ch
+	}
+	return chans
+}
+
+func formatLevels(counts []int) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s=%3d", levelNames[i], c)
+	}
+	return fmt.Sprint(parts)
+}
+
+// demoPriorityChannels 任务按优先级放进不同的channel时，select在多个case就绪时随机选择，
+// 表达不了优先级；用chanx.PriorityMux合并成一个channel再交给工作者
+func demoPriorityChannels() {
+	fmt.Println("\n=== 按优先级分channel：select vs PriorityMux ===")
+
+	const perLevel, take = 100, 60
+	fmt.Printf("三个channel各有%d个任务，都处于就绪状态，取前%d个:\n", perLevel, take)
+
+	chans := priorityChannels(perLevel)
+	counts := make([]int, len(chans))
+	for i := 0; i < take; i++ {
+		select {
+		case t := <-chans[0]:
+			counts[len(levelNames)-t.Priority]++
+		case t := <-chans[1]:
+			counts[len(levelNames)-t.Priority]++
+		case t := <-chans[2]:
+			counts[len(levelNames)-t.Priority]++
+		}
+	}
+	fmt.Printf("  直接select: %s （随机选择，三者大致相同）\n", formatLevels(counts))
+
+	for _, mode := range []struct {
+		name string
+		prio chanx.Priority
+	}{
+		{"严格优先级", chanx.Strict()},
+		{"加权优先级 6:3:1", chanx.Weighted(6, 3, 1)},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		merged := chanx.PriorityMux(ctx, mode.prio, priorityChannels(perLevel)...)
+		counts := make([]int, len(levelNames))
+		for i := 0; i < take; i++ {
+			t := <-merged
+			counts[len(levelNames)-t.Priority]++
+		}
+		cancel()
+		fmt.Printf("  %s: %s\n", mode.name, formatLevels(counts))
+	}
+
+	// 持续过载：高、低优先级生产者各每1ms提交一个任务，2个工作者每个任务耗时2ms，处理能力跟不上
+	fmt.Println("持续过载200ms（高、低优先级各每1ms提交一个任务，2个工作者每个任务2ms）:")
+	for _, mode := range []struct {
+		name string
+		prio chanx.Priority
+	}{
+		{"严格优先级", chanx.Strict()},
+		{"加权优先级 4:1", chanx.Weighted(4, 1)},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		high, low := make(chan workerpool.Task), make(chan workerpool.Task)
+		produce := func(ch chan<- workerpool.Task, priority int) {
+			defer close(ch)
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			for i := 0; ; i++ {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case ch <- workerpool.Task{ID: i, Priority: priority}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		go produce(high, 2)
+		go produce(low, 1)
+
+		merged := chanx.PriorityMux(ctx, mode.prio, high, low)
+		var processed [2]int64
+		var wg sync.WaitGroup
+		for w := 0; w < 2; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for t := range merged {
+					time.Sleep(2 * time.Millisecond)
+					atomic.AddInt64(&processed[2-t.Priority], 1)
+				}
+			}()
+		}
+		wg.Wait()
+		cancel()
+		fmt.Printf("  %s: 处理 高=%3d 低=%3d\n", mode.name, processed[0], processed[1])
+	}
+	fmt.Println("  严格优先级在过载时会饿死低优先级；加权优先级保证低优先级也能得到约1/5的处理能力")
+}
+
+// demoWorkStealing 对比私有队列（不窃取/窃取）与共享channel在负载倾斜时的表现
+func demoWorkStealing() {
+	fmt.Println("\n=== 工作窃取 vs 共享channel ===")
+
+	const numWorkers = 4
+
+	// 负载倾斜：ID为8的倍数的任务耗时20ms，且都被分配到工作者0
+	var tasks []stealTask
+	for i := 0; i < 200; i++ {
+		cost := time.Millisecond
+		if i%8 == 0 {
+			cost = 20 * time.Millisecond
+		}
+		tasks = append(tasks, stealTask{id: i, cost: cost})
+	}
+
+	for _, stealing := range []bool{false, true} {
+		pool := NewStealingPool(numWorkers, stealing)
+		start := time.Now()
+		for _, t := range tasks {
+			pool.Submit(t)
+		}
+		pool.Start()
+		pool.Close()
+		pool.Wait()
+
+		name := "私有队列"
+		if stealing {
+			name = "工作窃取"
+		}
+		printBenchmark(name, time.Since(start), pool.Latencies(), fmt.Sprintf("窃取次数=%d", pool.Steals()))
+	}
+
+	start := time.Now()
+	latencies := runSharedChannel(numWorkers, tasks)
+	printBenchmark("共享channel", time.Since(start), latencies, "")
+}
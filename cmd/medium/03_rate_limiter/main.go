@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/downstream"
+	"github.com/klsakura/day1/pkg/ratelimit"
+)
+
+// demoAdaptiveLimiter 用不稳定服务演示AIMD：服务恶化时速率下降，恢复后逐渐回升
+func demoAdaptiveLimiter() {
+	fmt.Println("\n=== AIMD自适应限流 ===")
+
+	svc := downstream.NewUnstableService(0.05)
+	svc.SetQuiet(true)
+
+	limiter := ratelimit.NewAdaptive(5, 2, 20, 300*time.Millisecond)
+
+	done := make(chan struct{})
+	var (
+		workers           sync.WaitGroup
+		countMu           sync.Mutex
+		succeeded, failed int64
+	)
+	for i := 1; i <= 4; i++ {
+		workers.Add(1)
+		go func(id int) {
+			defer workers.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				limiter.Wait()
+				start := time.Now()
+				err := svc.Call(fmt.Sprintf("w%d-%d", id, n))
+				limiter.Report(err, time.Since(start))
+
+				countMu.Lock()
+				if err != nil {
+					failed++
+				} else {
+					succeeded++
+				}
+				countMu.Unlock()
+			}
+		}(i)
+	}
+
+	// 每500ms打印一次速率；2秒后服务恶化，4秒后恢复
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for tick := 1; tick <= 16; tick++ {
+		<-ticker.C
+		switch tick {
+		case 4:
+			svc.SetFailureRate(0.6)
+		case 8:
+			svc.SetFailureRate(0.05)
+		}
+		countMu.Lock()
+		fmt.Printf("[%4.1fs] 速率=%5.2f/s 成功=%d 失败=%d\n",
+			float64(tick)/2, limiter.Rate(), succeeded, failed)
+		countMu.Unlock()
+	}
+
+	close(done)
+	workers.Wait()
+}
+
+// newDemoHandler 被限流保护的示例接口
+func newDemoHandler(l ratelimit.Limiter) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello at %s\n", time.Now().Format("15:04:05.000"))
+	})
+	return ratelimit.Middleware(l)(mux)
+}
+
+// serveHTTP 以独立服务运行，便于用curl体验：每秒1个请求，允许3个突发
+func serveHTTP(addr string) {
+	limiter := ratelimit.NewTokenBucket(1, 3)
+	defer limiter.Close()
+
+	fmt.Printf("限流HTTP服务监听 %s，试试: curl -i http://%s/hello\n", addr, addr)
+	if err := http.ListenAndServe(addr, newDemoHandler(limiter)); err != nil {
+		fmt.Println("服务退出:", err)
+	}
+}
+
+// demoHTTPMiddleware 启动本地服务并快速发送请求，观察429和Retry-After
+func demoHTTPMiddleware() {
+	fmt.Println("\n=== HTTP限流中间件 ===")
+
+	// 每2秒1个请求，允许3个突发
+	limiter := ratelimit.NewTokenBucket(0.5, 3)
+	defer limiter.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Println("监听失败:", err)
+		return
+	}
+	srv := &http.Server{Handler: newDemoHandler(limiter)}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	url := "http://" + ln.Addr().String() + "/hello"
+	for i := 1; i <= 6; i++ {
+		resp, err := http.Get(url)
+		if err != nil {
+			fmt.Printf("请求 %d 失败: %v\n", i, err)
+			continue
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			fmt.Printf("请求 %d: %d, Retry-After: %ss\n", i, resp.StatusCode, resp.Header.Get("Retry-After"))
+		} else {
+			fmt.Printf("请求 %d: %d\n", i, resp.StatusCode)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// demoSetRate 运行中调整速率：逐步提高再降低，观察每个阶段的通过数
+func demoSetRate() {
+	fmt.Println("\n=== 运行时调整速率 ===")
+
+	limiter := ratelimit.NewTokenBucket(2, 2)
+	defer limiter.Close()
+	goroutinesBefore := runtime.NumGoroutine()
+
+	for _, rate := range []float64{2, 5, 10, 5, 1} {
+		limiter.SetRate(rate)
+
+		// 每10ms尝试一次，持续1秒
+		allowed := 0
+		for i := 0; i < 100; i++ {
+			if limiter.Allow() {
+				allowed++
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		fmt.Printf("速率 %4.1f/s: 1秒内通过 %d 个\n", rate, allowed)
+	}
+
+	// 多次调整后只有一个补充协程
+	fmt.Printf("协程数: 调整前 %d, 多次调整后 %d\n", goroutinesBefore, runtime.NumGoroutine())
+}
+
+// demoKeyedLimiter 模拟按客户端IP限流：少数热点客户端持续请求，大量客户端只访问一次
+func demoKeyedLimiter() {
+	fmt.Println("\n=== 按key限流与空闲回收 ===")
+
+	// 每个客户端每秒3个请求，空闲300ms后回收
+	kl := ratelimit.NewKeyed(func() ratelimit.Limiter { return ratelimit.NewTokenBucket(3, 3) },
+		300*time.Millisecond, 100*time.Millisecond)
+	defer kl.Close()
+
+	hot := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed = make(map[string]int)
+		denied  = make(map[string]int)
+	)
+
+	// 热点客户端：每个在1秒内发起20个请求
+	for _, ip := range hot {
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				ok := kl.Allow(ip)
+				mu.Lock()
+				if ok {
+					allowed[ip]++
+				} else {
+					denied[ip]++
+				}
+				mu.Unlock()
+				time.Sleep(50 * time.Millisecond)
+			}
+		}(ip)
+	}
+
+	// 一次性客户端：前200ms内出现100个不同IP
+	for i := 0; i < 100; i++ {
+		kl.Allow(fmt.Sprintf("192.168.1.%d", i))
+		time.Sleep(2 * time.Millisecond)
+	}
+	keys, created, evicted := kl.Stats()
+	fmt.Printf("一次性客户端到达后: 当前key=%d, 累计创建=%d, 已回收=%d\n", keys, created, evicted)
+
+	wg.Wait()
+
+	for _, ip := range hot {
+		fmt.Printf("客户端 %s: 通过 %d, 拒绝 %d\n", ip, allowed[ip], denied[ip])
+	}
+
+	keys, created, evicted = kl.Stats()
+	fmt.Printf("热点客户端结束时: 当前key=%d, 累计创建=%d, 已回收=%d\n", keys, created, evicted)
+
+	time.Sleep(500 * time.Millisecond)
+	keys, created, evicted = kl.Stats()
+	fmt.Printf("全部空闲500ms后: 当前key=%d, 累计创建=%d, 已回收=%d\n", keys, created, evicted)
+}
+
+// demoReserve 演示提前预订令牌并按返回的延迟安排执行
+func demoReserve() {
+	fmt.Println("\n=== Reserve预订令牌 ===")
+
+	// 每秒4个令牌，桶容量4
+	limiter := ratelimit.NewTokenBucket(4, 4)
+	defer limiter.Close()
+
+	// 依次预订3批令牌：第一批可以立即执行，后面的需要等待补充
+	start := time.Now()
+	var reservations []*ratelimit.Reservation
+	for i, n := range []int{3, 2, 3} {
+		r := limiter.Reserve(n)
+		reservations = append(reservations, r)
+		fmt.Printf("批次 %d 预订 %d 个令牌: 需等待 %v\n", i+1, n, r.Delay().Round(10*time.Millisecond))
+	}
+
+	// 超过桶容量的预订永远无法满足
+	fmt.Printf("预订 10 个令牌: OK=%v\n", limiter.Reserve(10).OK())
+
+	// 取消最后一批，归还的欠账让新的预订等待更短
+	reservations[2].Cancel()
+	r := limiter.Reserve(1)
+	fmt.Printf("取消批次 3 后预订 1 个令牌: 需等待 %v\n", r.Delay().Round(10*time.Millisecond))
+	reservations[2] = r
+
+	// 按各自的延迟预先排好执行时间
+	var wg sync.WaitGroup
+	for i, r := range reservations {
+		wg.Add(1)
+		go func(i int, r *ratelimit.Reservation) {
+			defer wg.Done()
+			time.Sleep(r.Delay())
+			fmt.Printf("预订 %d 在 %v 执行\n", i+1, time.Since(start).Round(10*time.Millisecond))
+		}(i, r)
+	}
+	wg.Wait()
+}
+
+// demoBurstAndRate 演示突发容量与补充速率分离，以及小数速率
+func demoBurstAndRate() {
+	fmt.Println("\n=== 突发容量与补充速率 ===")
+
+	// 平时每秒2个，但允许积攒10个应对突发
+	bursty := ratelimit.NewTokenBucket(2, 10)
+	line, allowed := timeline(bursty, 50*time.Millisecond, 30)
+	bursty.Close()
+	fmt.Printf("rate=2 burst=10  %s 通过 %d\n", line, allowed)
+
+	// 每2秒1个请求
+	slow := ratelimit.NewTokenBucket(0.5, 1)
+	defer slow.Close()
+	start := time.Now()
+	for i := 1; i <= 3; i++ {
+		slow.Wait()
+		fmt.Printf("rate=0.5 burst=1 第%d个请求在 %v 通过\n", i, time.Since(start).Round(100*time.Millisecond))
+	}
+}
+
+// demoWaitCtx 演示可取消的等待
+func demoWaitCtx() {
+	fmt.Println("\n=== 可取消的Wait ===")
+
+	// 每秒1个令牌，先把初始令牌用掉
+	limiter := ratelimit.NewTokenBucket(1, 1)
+	limiter.Allow()
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	report := func(name string, err error) {
+		fmt.Printf("%s: %v 后返回, err=%v\n", name, time.Since(start).Round(10*time.Millisecond), err)
+	}
+
+	// 调用者只愿意等300ms
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		report("超时请求", limiter.WaitCtx(ctx))
+	}()
+
+	// 调用者在100ms时主动取消（例如客户端断开连接）
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		report("取消请求", limiter.WaitCtx(ctx))
+	}()
+
+	// 愿意等2秒，能等到下一个令牌
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ok := limiter.WaitTimeout(2 * time.Second)
+		fmt.Printf("WaitTimeout(2s): %v 后返回, 拿到令牌=%v\n", time.Since(start).Round(10*time.Millisecond), ok)
+	}()
+
+	wg.Wait()
+
+	// 限流器关闭后，等待者会收到ErrLimiterClosed而不是永远阻塞
+	limiter.Allow()
+	time.AfterFunc(200*time.Millisecond, limiter.Close)
+	report("关闭时的等待者", limiter.WaitCtx(context.Background()))
+}
+
+// timeline 每隔interval尝试一次请求，用字符画出通过(█)和被拒绝(·)的时间线
+func timeline(limiter ratelimit.Limiter, interval time.Duration, attempts int) (string, int) {
+	var line []rune
+	allowed := 0
+	for i := 0; i < attempts; i++ {
+		if limiter.Allow() {
+			line = append(line, '█')
+			allowed++
+		} else {
+			line = append(line, '·')
+		}
+		time.Sleep(interval)
+	}
+	return string(line), allowed
+}
+
+// compareBurst 对比令牌桶和滑动窗口的突发行为
+func compareBurst() {
+	fmt.Println("\n=== 突发行为对比: 令牌桶 vs 滑动窗口 vs 漏桶 ===")
+	fmt.Println("每50ms尝试一次，共2秒；都限制为每秒5个请求")
+
+	bucket := ratelimit.NewTokenBucket(5, 5)
+	defer bucket.Close()
+	window := ratelimit.NewSlidingWindow(5, time.Second)
+	leaky := ratelimit.NewLeakyBucket(5)
+
+	limiters := []struct {
+		name    string
+		limiter ratelimit.Limiter
+	}{
+		{"令牌桶  ", bucket},
+		{"滑动窗口", window},
+		{"漏桶    ", leaky},
+	}
+
+	for _, l := range limiters {
+		line, allowed := timeline(l.limiter, 50*time.Millisecond, 40)
+		fmt.Printf("%s %s 通过 %d\n", l.name, line, allowed)
+	}
+
+	fmt.Println("令牌桶: 开头用完积攒的令牌后，按补充速度每200ms放行一个")
+	fmt.Println("滑动窗口: 开头一次放行5个，之后要等最早的请求滑出窗口才再次放行，呈周期性突发")
+	fmt.Println("漏桶: 从第一个请求起就严格每200ms放行一个，没有突发")
+
+	comparePacing()
+}
+
+// comparePacing 8个协程同时调用Wait，观察各自通过的时间点
+func comparePacing() {
+	fmt.Println("\n=== Wait放行节奏: 令牌桶 vs 漏桶 (每秒5个，8个并发请求) ===")
+
+	bucket := ratelimit.NewTokenBucket(5, 5)
+	defer bucket.Close()
+
+	limiters := []struct {
+		name    string
+		limiter ratelimit.Limiter
+	}{
+		{"令牌桶", bucket},
+		{"漏桶  ", ratelimit.NewLeakyBucket(5)},
+	}
+
+	for _, l := range limiters {
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			times []time.Duration
+		)
+		start := time.Now()
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.limiter.Wait()
+				mu.Lock()
+				times = append(times, time.Since(start).Round(10*time.Millisecond))
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		fmt.Printf("%s 通过时间: %v\n", l.name, times)
+	}
+}
+
+func worker(id, requests int, limiter *ratelimit.TokenBucket, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for i := 1; i <= requests; i++ {
+		if limiter.Allow() {
+			fmt.Printf("工作者 %d: 请求 %d 通过 (时间: %s)\n",
+				id, i, time.Now().Format("15:04:05.000"))
+		} else {
+			fmt.Printf("工作者 %d: 请求 %d 被限制，等待中...\n", id, i)
+			limiter.Wait()
+			fmt.Printf("工作者 %d: 请求 %d 重试成功 (时间: %s)\n",
+				id, i, time.Now().Format("15:04:05.000"))
+		}
+
+		// 模拟处理时间
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	fmt.Printf("工作者 %d 完成所有请求\n", id)
+}
+
+func main() {
+	serve := flag.String("serve", "", "以HTTP服务方式运行限流中间件，例如 -serve=127.0.0.1:8080")
+	rate := flag.Float64("rate", 5, "每秒生成的令牌数")
+	burst := flag.Int("burst", 5, "令牌桶容量，即允许的突发请求数")
+	workers := flag.Int("workers", 3, "并发发送请求的工作者数量")
+	requests := flag.Int("requests", 10, "每个工作者发送的请求数")
+	demoflag.Parse()
+
+	if *serve != "" {
+		serveHTTP(*serve)
+		return
+	}
+
+	fmt.Println("=== 速率限制器演示 ===")
+	fmt.Printf("限制: 每秒最多%g个请求\n", *rate)
+
+	// 默认每秒5个请求，允许5个突发
+	limiter := ratelimit.NewTokenBucket(*rate, *burst)
+	defer limiter.Close()
+
+	var wg sync.WaitGroup
+
+	// 默认启动3个工作者，每个尝试发送10个请求
+	for i := 1; i <= *workers; i++ {
+		wg.Add(1)
+		go worker(i, *requests, limiter, &wg)
+	}
+
+	wg.Wait()
+	fmt.Println("所有工作者完成！")
+
+	compareBurst()
+	demoKeyedLimiter()
+	demoWaitCtx()
+	demoBurstAndRate()
+	demoReserve()
+	demoAdaptiveLimiter()
+	demoSetRate()
+	demoHTTPMiddleware()
+}
@@ -0,0 +1,907 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/pubsub"
+	"github.com/klsakura/day1/pkg/topicmatch"
+)
+
+// OrderEvent 泛型发布订阅演示中使用的强类型消息负载
+type OrderEvent struct {
+	OrderID int
+	Amount  float64
+	Status  string
+}
+
+// 发布订阅模式演示
+type Message struct {
+	ID      int64
+	Topic   string
+	Content string
+	Time    time.Time
+	Replay  bool // 是否为订阅时回放的历史消息
+	Attempt int  // 投递次数，开启确认模式后大于1表示重投
+
+	reply chan<- bool // 同步发布时用于回执，订阅者通过Confirm/Reject回复
+}
+
+// Confirm 告知同步发布者消息已被接收处理，异步发布的消息调用无副作用
+func (m Message) Confirm() {
+	m.respond(true)
+}
+
+// Reject 告知同步发布者订阅者明确拒绝了消息
+func (m Message) Reject() {
+	m.respond(false)
+}
+
+func (m Message) respond(ok bool) {
+	if m.reply == nil {
+		return
+	}
+	select {
+	case m.reply <- ok:
+	default:
+	}
+}
+
+// SyncResult 同步发布的结果
+type SyncResult struct {
+	Matched  int           // 匹配的订阅者数
+	Received int           // 确认接收的订阅者数
+	Rejected int           // 拒绝或未能入队的订阅者数
+	TimedOut int           // 超时未回复的订阅者数
+	Latency  time.Duration // 从发布到全部回复的耗时
+}
+
+// Subscriber 订阅者：订阅模式集合、投递用的邮箱与泛型版本共用 pkg/pubsub 的实现
+type Subscriber struct {
+	ID       int
+	Messages chan Message
+	Policy   pubsub.OverflowPolicy
+
+	topics  pubsub.Topics[Message]   // 订阅模式和内容过滤条件
+	mailbox *pubsub.Mailbox[Message] // 按溢出策略向Messages投递
+}
+
+func NewSubscriber(id int) *Subscriber {
+	return NewSubscriberWithPolicy(id, 10, pubsub.DropNewest)
+}
+
+func NewSubscriberWithPolicy(id int, bufferSize int, policy pubsub.OverflowPolicy) *Subscriber {
+	messages := make(chan Message, bufferSize)
+	return &Subscriber{
+		ID:       id,
+		Messages: messages,
+		Policy:   policy,
+		mailbox:  pubsub.NewMailbox(messages, policy),
+	}
+}
+
+// Subscribe 订阅主题，支持通配符模式，如 "sports.*"、"news.#"
+func (s *Subscriber) Subscribe(pattern string) {
+	// 普通订阅接收该模式下的全部消息，会清除之前的过滤条件
+	if !s.topics.Subscribe(pattern) {
+		fmt.Printf("订阅者 %d 的订阅模式不合法: %s\n", s.ID, pattern)
+		return
+	}
+	fmt.Printf("订阅者 %d 订阅了主题: %s\n", s.ID, pattern)
+}
+
+// SubscribeFunc 带内容过滤条件的订阅，只接收predicate返回true的消息
+// 过滤在发布者侧进行，不满足条件的消息不会占用订阅者的队列
+func (s *Subscriber) SubscribeFunc(pattern string, predicate func(Message) bool) {
+	if !s.topics.SubscribeFunc(pattern, predicate) {
+		fmt.Printf("订阅者 %d 的订阅模式不合法: %s\n", s.ID, pattern)
+		return
+	}
+	fmt.Printf("订阅者 %d 订阅了主题: %s (带内容过滤)\n", s.ID, pattern)
+}
+
+// Unsubscribe 取消订阅主题（需与订阅时的模式完全一致）
+func (s *Subscriber) Unsubscribe(pattern string) bool {
+	if !s.topics.Unsubscribe(pattern) {
+		return false
+	}
+	fmt.Printf("订阅者 %d 取消订阅主题: %s\n", s.ID, pattern)
+	return true
+}
+
+// IsSubscribed 检查主题是否匹配该订阅者的任一订阅模式
+func (s *Subscriber) IsSubscribed(topic string) bool {
+	return s.topics.Matches(topic)
+}
+
+// Accepts 检查消息是否匹配任一订阅：主题匹配且满足该订阅的过滤条件
+func (s *Subscriber) Accepts(msg Message) bool {
+	return s.topics.Accepts(msg.Topic, msg)
+}
+
+// deliver 按订阅者的溢出策略投递消息，返回消息是否进入队列
+func (s *Subscriber) deliver(msg Message) bool {
+	switch s.mailbox.Deliver(msg) {
+	case pubsub.Delivered:
+		return true
+	case pubsub.Dropped:
+		fmt.Printf("订阅者 %d 的消息队列已满，跳过消息\n", s.ID)
+	case pubsub.Disconnected:
+		fmt.Printf("订阅者 %d 处理过慢，已被断开\n", s.ID)
+	}
+	return false
+}
+
+// SubscriberID 实现pubsub.Member，订阅者列表按ID移除
+func (s *Subscriber) SubscriberID() int {
+	return s.ID
+}
+
+// Close 关闭订阅者的消息channel，可重复调用；由发布者在移除或关闭时调用
+func (s *Subscriber) Close() {
+	s.mailbox.Close()
+}
+
+// Dropped 返回被丢弃的消息数
+func (s *Subscriber) Dropped() int64 {
+	return s.mailbox.Dropped()
+}
+
+// Handle 默认的消息处理函数：打印消息并模拟处理时间
+func (s *Subscriber) Handle(msg Message) error {
+	tag := ""
+	if msg.Replay {
+		tag = "[回放]"
+	}
+	fmt.Printf("订阅者 %d 收到消息%s [%s]: %s (时间: %s)\n",
+		s.ID, tag, msg.Topic, msg.Content, msg.Time.Format("15:04:05"))
+	time.Sleep(100 * time.Millisecond) // 模拟处理时间
+	return nil
+}
+
+func (s *Subscriber) Listen() {
+	for msg := range s.Messages {
+		s.Handle(msg)
+		msg.Confirm()
+	}
+	fmt.Printf("订阅者 %d 停止监听\n", s.ID)
+}
+
+type Publisher struct {
+	// 订阅者列表：发布时持有读锁遍历，添加和移除持有写锁，与泛型版本共用 pkg/pubsub 的实现
+	subscribers pubsub.Registry[*Subscriber]
+
+	// 每个主题保留最近historySize条消息，用于新订阅者回放
+	history     map[string][]Message
+	historySize int
+	historyMu   sync.Mutex
+
+	// 至少一次投递：跟踪每个订阅者未确认的消息，超时后重投
+	nextID      int64
+	ackTimeout  time.Duration
+	maxAttempts int
+	pending     map[pendingKey]*pendingDelivery
+	ackMu       sync.Mutex
+	ackStop     chan struct{}
+	ackDone     chan struct{}
+	ackStats    struct {
+		acked       int64 // 已确认
+		redelivered int64 // 重投次数
+		expired     int64 // 超过最大投递次数后放弃
+	}
+
+	// 异步/同步两种发布模式的延迟统计
+	latencyMu sync.Mutex
+	latency   map[string]*latencyStat
+
+	// 由发布者统一管理的订阅者监听协程
+	listenerMu sync.Mutex
+	listeners  map[int]chan struct{} // 订阅者ID -> 监听协程退出信号
+	listenerWg sync.WaitGroup
+	closeOnce  sync.Once
+}
+
+type latencyStat struct {
+	count int
+	total time.Duration
+	max   time.Duration
+}
+
+type pendingKey struct {
+	subID int
+	msgID int64
+}
+
+type pendingDelivery struct {
+	sub      *Subscriber
+	msg      Message
+	deadline time.Time
+}
+
+func NewPublisher(historySize int) *Publisher {
+	return &Publisher{
+		history:     make(map[string][]Message),
+		historySize: historySize,
+		pending:     make(map[pendingKey]*pendingDelivery),
+		latency:     make(map[string]*latencyStat),
+		listeners:   make(map[int]chan struct{}),
+	}
+}
+
+// StartSubscriber 注册订阅者并由发布者启动其监听协程
+// handler返回nil时确认消息，返回error时拒绝消息；handler发生panic不会终止监听协程
+func (p *Publisher) StartSubscriber(sub *Subscriber, handler func(Message) error) {
+	p.listenerMu.Lock()
+	defer p.listenerMu.Unlock()
+
+	if _, running := p.listeners[sub.ID]; running {
+		fmt.Printf("订阅者 %d 已在运行\n", sub.ID)
+		return
+	}
+
+	p.subscribers.Add(sub)
+
+	done := make(chan struct{})
+	p.listeners[sub.ID] = done
+	p.listenerWg.Add(1)
+
+	go func() {
+		defer p.listenerWg.Done()
+		defer close(done)
+
+		for msg := range sub.Messages {
+			if err := safeHandle(handler, msg); err != nil {
+				fmt.Printf("订阅者 %d 处理消息失败: %v\n", sub.ID, err)
+				msg.Reject()
+				continue
+			}
+			msg.Confirm()
+		}
+		fmt.Printf("订阅者 %d 停止监听\n", sub.ID)
+	}()
+
+	fmt.Printf("启动订阅者 %d\n", sub.ID)
+}
+
+// safeHandle 调用handler并把panic转换为error
+func safeHandle(handler func(Message) error, msg Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return handler(msg)
+}
+
+// StopSubscriber 移除订阅者，并等待其监听协程处理完队列中剩余消息后退出
+func (p *Publisher) StopSubscriber(id int) bool {
+	p.listenerMu.Lock()
+	done, running := p.listeners[id]
+	delete(p.listeners, id)
+	p.listenerMu.Unlock()
+
+	if !p.RemoveSubscriber(id) {
+		return false
+	}
+	if running {
+		<-done
+	}
+	return true
+}
+
+// EnableAcks 开启至少一次投递模式：订阅者需调用Ack确认消息，
+// 超过timeout未确认的消息会被重投，最多投递maxAttempts次
+func (p *Publisher) EnableAcks(timeout time.Duration, maxAttempts int) {
+	p.ackMu.Lock()
+	defer p.ackMu.Unlock()
+
+	if p.ackStop != nil {
+		return
+	}
+	p.ackTimeout = timeout
+	p.maxAttempts = maxAttempts
+	p.ackStop = make(chan struct{})
+	p.ackDone = make(chan struct{})
+
+	go p.redeliveryLoop()
+}
+
+// Ack 确认订阅者已处理消息
+func (p *Publisher) Ack(subID int, msgID int64) bool {
+	p.ackMu.Lock()
+	defer p.ackMu.Unlock()
+
+	key := pendingKey{subID: subID, msgID: msgID}
+	if _, ok := p.pending[key]; !ok {
+		return false
+	}
+	delete(p.pending, key)
+	p.ackStats.acked++
+	return true
+}
+
+// trackDelivery 记录一次待确认的投递
+// 必须在deliver之前调用：订阅者可能在deliver返回前就处理完并Ack，晚登记会让这次Ack落空而被重投
+func (p *Publisher) trackDelivery(sub *Subscriber, msg Message) {
+	p.ackMu.Lock()
+	defer p.ackMu.Unlock()
+
+	if p.ackStop == nil {
+		return
+	}
+	p.pending[pendingKey{subID: sub.ID, msgID: msg.ID}] = &pendingDelivery{
+		sub:      sub,
+		msg:      msg,
+		deadline: time.Now().Add(p.ackTimeout),
+	}
+}
+
+// untrackDelivery 撤销trackDelivery的登记，用于消息没有进入订阅者队列的情况
+func (p *Publisher) untrackDelivery(sub *Subscriber, msg Message) {
+	p.ackMu.Lock()
+	defer p.ackMu.Unlock()
+	delete(p.pending, pendingKey{subID: sub.ID, msgID: msg.ID})
+}
+
+// redeliveryLoop 定期扫描超时未确认的投递并重投
+func (p *Publisher) redeliveryLoop() {
+	defer close(p.ackDone)
+
+	ticker := time.NewTicker(p.ackTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.redeliverExpired()
+		case <-p.ackStop:
+			return
+		}
+	}
+}
+
+func (p *Publisher) redeliverExpired() {
+	now := time.Now()
+
+	p.ackMu.Lock()
+	var due []*pendingDelivery
+	for key, pd := range p.pending {
+		if now.Before(pd.deadline) {
+			continue
+		}
+		if pd.msg.Attempt >= p.maxAttempts {
+			delete(p.pending, key)
+			p.ackStats.expired++
+			fmt.Printf("消息 %d 投递给订阅者 %d 共 %d 次仍未确认，放弃投递\n",
+				pd.msg.ID, pd.sub.ID, pd.msg.Attempt)
+			continue
+		}
+		pd.msg.Attempt++
+		pd.deadline = now.Add(p.ackTimeout)
+		due = append(due, pd)
+	}
+	p.ackMu.Unlock()
+
+	// 在锁外投递，避免Block策略的订阅者阻塞Ack
+	for _, pd := range due {
+		fmt.Printf("重投消息 %d 给订阅者 %d (第 %d 次投递)\n", pd.msg.ID, pd.sub.ID, pd.msg.Attempt)
+		if pd.sub.deliver(pd.msg) {
+			p.ackMu.Lock()
+			p.ackStats.redelivered++
+			p.ackMu.Unlock()
+		}
+	}
+}
+
+// AckStats 返回确认模式统计：已确认、重投次数、放弃数、仍待确认数
+func (p *Publisher) AckStats() (int64, int64, int64, int) {
+	p.ackMu.Lock()
+	defer p.ackMu.Unlock()
+	return p.ackStats.acked, p.ackStats.redelivered, p.ackStats.expired, len(p.pending)
+}
+
+func (p *Publisher) AddSubscriber(sub *Subscriber) {
+	if p.subscribers.Add(sub) {
+		fmt.Printf("添加订阅者 %d\n", sub.ID)
+	}
+}
+
+// RemoveSubscriber 移除订阅者并关闭其消息channel
+// 移除持有写锁，会等待正在进行的Publish完成，之后的Publish不会再看到该订阅者，
+// 因此关闭channel不会与发送产生竞争；并发场景的测试见 pkg/pubsub 的TestPublishWhileRemoving
+func (p *Publisher) RemoveSubscriber(id int) bool {
+	if !p.subscribers.Remove(id) {
+		return false
+	}
+	fmt.Printf("移除订阅者 %d\n", id)
+	return true
+}
+
+func (p *Publisher) Publish(topic, content string) {
+	start := time.Now()
+	defer func() {
+		p.recordLatency("async", time.Since(start))
+	}()
+
+	msg := Message{
+		ID:      atomic.AddInt64(&p.nextID, 1),
+		Topic:   topic,
+		Content: content,
+		Time:    time.Now(),
+		Attempt: 1,
+	}
+
+	p.subscribers.Read(func(subs []*Subscriber) {
+		fmt.Printf("发布消息到主题 [%s]: %s\n", topic, content)
+
+		p.recordHistory(msg)
+
+		// 发送给所有订阅了该主题的订阅者
+		for _, sub := range subs {
+			if !sub.Accepts(msg) {
+				continue
+			}
+			p.trackDelivery(sub, msg)
+			if !sub.deliver(msg) {
+				p.untrackDelivery(sub, msg)
+			}
+		}
+	})
+}
+
+// PublishSync 同步发布：等待每个匹配的订阅者确认接收或明确拒绝，最多等待timeout
+// 队列已满被丢弃或订阅者被断开的投递计为拒绝
+func (p *Publisher) PublishSync(topic, content string, timeout time.Duration) SyncResult {
+	start := time.Now()
+
+	var (
+		replies chan bool
+		result  SyncResult
+	)
+	p.subscribers.Read(func(subs []*Subscriber) {
+		replies = make(chan bool, len(subs))
+		msg := Message{
+			ID:      atomic.AddInt64(&p.nextID, 1),
+			Topic:   topic,
+			Content: content,
+			Time:    start,
+			Attempt: 1,
+			reply:   replies,
+		}
+
+		fmt.Printf("同步发布消息到主题 [%s]: %s\n", topic, content)
+		p.recordHistory(msg)
+
+		for _, sub := range subs {
+			if !sub.Accepts(msg) {
+				continue
+			}
+			result.Matched++
+			p.trackDelivery(sub, msg)
+			if !sub.deliver(msg) {
+				p.untrackDelivery(sub, msg)
+				msg.Reject()
+			}
+		}
+	})
+
+	// 在锁外等待回执，避免阻塞订阅者管理操作
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for i := 0; i < result.Matched; i++ {
+		select {
+		case ok := <-replies:
+			if ok {
+				result.Received++
+			} else {
+				result.Rejected++
+			}
+		case <-timer.C:
+			result.TimedOut = result.Matched - i
+			i = result.Matched
+		}
+	}
+
+	result.Latency = time.Since(start)
+	p.recordLatency("sync", result.Latency)
+	return result
+}
+
+func (p *Publisher) recordLatency(mode string, d time.Duration) {
+	p.latencyMu.Lock()
+	defer p.latencyMu.Unlock()
+
+	stat, ok := p.latency[mode]
+	if !ok {
+		stat = &latencyStat{}
+		p.latency[mode] = stat
+	}
+	stat.count++
+	stat.total += d
+	if d > stat.max {
+		stat.max = d
+	}
+}
+
+// PrintLatencyStats 打印异步与同步发布的延迟对比
+func (p *Publisher) PrintLatencyStats() {
+	p.latencyMu.Lock()
+	defer p.latencyMu.Unlock()
+
+	for _, mode := range []string{"async", "sync"} {
+		stat, ok := p.latency[mode]
+		if !ok || stat.count == 0 {
+			continue
+		}
+		fmt.Printf("%-5s 发布: 次数=%d, 平均延迟=%v, 最大延迟=%v\n",
+			mode, stat.count, stat.total/time.Duration(stat.count), stat.max)
+	}
+}
+
+// recordHistory 保存消息到主题历史，只保留最近historySize条
+func (p *Publisher) recordHistory(msg Message) {
+	if p.historySize <= 0 {
+		return
+	}
+
+	p.historyMu.Lock()
+	defer p.historyMu.Unlock()
+
+	h := append(p.history[msg.Topic], msg)
+	if len(h) > p.historySize {
+		h = h[len(h)-p.historySize:]
+	}
+	p.history[msg.Topic] = h
+}
+
+// SubscribeWithReplay 订阅主题并立即回放匹配的历史消息
+// 持有订阅者列表的写锁期间完成订阅和回放，保证历史消息一定先于之后的实时消息到达
+func (p *Publisher) SubscribeWithReplay(sub *Subscriber, pattern string) {
+	p.subscribers.AddThen(sub, func(added bool) {
+		if added {
+			fmt.Printf("添加订阅者 %d\n", sub.ID)
+		}
+		p.replay(sub, pattern)
+	})
+}
+
+// replay 订阅并回放历史消息，调用者持有订阅者列表的写锁
+func (p *Publisher) replay(sub *Subscriber, pattern string) {
+	sub.Subscribe(pattern)
+
+	// 收集所有匹配主题的历史消息，按时间排序后回放
+	p.historyMu.Lock()
+	var replay []Message
+	for topic, msgs := range p.history {
+		if topicmatch.Match(pattern, topic) {
+			replay = append(replay, msgs...)
+		}
+	}
+	p.historyMu.Unlock()
+
+	sort.Slice(replay, func(i, j int) bool {
+		return replay[i].Time.Before(replay[j].Time)
+	})
+
+	fmt.Printf("订阅者 %d 回放 %d 条历史消息 (模式: %s)\n", sub.ID, len(replay), pattern)
+	for _, msg := range replay {
+		msg.Replay = true
+		sub.deliver(msg)
+	}
+}
+
+// PrintDropStats 打印每个订阅者的溢出策略和丢弃数量
+func (p *Publisher) PrintDropStats() {
+	p.subscribers.Read(func(subs []*Subscriber) {
+		for _, sub := range subs {
+			fmt.Printf("订阅者 %d: 策略=%s, 丢弃=%d\n", sub.ID, sub.Policy, sub.Dropped())
+		}
+	})
+}
+
+// Close 关闭发布者：停止重投、关闭所有订阅者channel，并等待托管的监听协程退出
+// 可以安全地重复调用
+func (p *Publisher) Close() {
+	p.closeOnce.Do(func() {
+		// 先停止重投协程，再关闭订阅者channel
+		p.ackMu.Lock()
+		stop, done := p.ackStop, p.ackDone
+		p.ackMu.Unlock()
+		if stop != nil {
+			close(stop)
+			<-done
+		}
+
+		p.subscribers.CloseAll()
+	})
+
+	p.listenerWg.Wait()
+}
+
+func main() {
+	history := flag.Int("history", 3, "每个主题保留的最近消息条数，新订阅者可以回放")
+	demoflag.Parse()
+
+	fmt.Println("=== 发布订阅模式演示 ===")
+
+	publisher := NewPublisher(*history) // 默认每个主题保留最近3条消息
+
+	// 创建订阅者
+	sub1 := NewSubscriber(1)
+	sub2 := NewSubscriber(2)
+	sub3 := NewSubscriber(3)
+
+	// 添加订阅者到发布者
+	publisher.AddSubscriber(sub1)
+	publisher.AddSubscriber(sub2)
+	publisher.AddSubscriber(sub3)
+
+	// 订阅者订阅不同主题（支持层级通配符）
+	sub1.Subscribe("tech.*")
+	sub1.Subscribe("news")
+	sub2.Subscribe("tech.go")
+	sub2.Subscribe("sports.#")
+	sub3.Subscribe("news")
+	sub3.Subscribe("sports.*.final")
+	sub3.SubscribeFunc("tech.*", func(msg Message) bool {
+		return strings.Contains(msg.Content, "并发") // 只关心并发相关的技术消息
+	})
+
+	// 由发布者统一启动订阅者的监听协程
+	publisher.StartSubscriber(sub1, sub1.Handle)
+	publisher.StartSubscriber(sub2, sub2.Handle)
+	publisher.StartSubscriber(sub3, sub3.Handle)
+
+	// 发布消息
+	time.Sleep(500 * time.Millisecond)
+
+	publisher.Publish("tech.go", "Go 1.21 发布了新特性")
+	time.Sleep(200 * time.Millisecond)
+
+	publisher.Publish("news", "今日重要新闻")
+	time.Sleep(200 * time.Millisecond)
+
+	publisher.Publish("sports.football.final", "世界杯决赛结果")
+	time.Sleep(200 * time.Millisecond)
+
+	publisher.Publish("tech.rust", "新的并发编程模式")
+	time.Sleep(200 * time.Millisecond)
+
+	publisher.Publish("sports.basketball", "常规赛战报")
+	time.Sleep(200 * time.Millisecond)
+
+	// 订阅者1取消订阅news后，不再收到新闻消息
+	sub1.Unsubscribe("news")
+	publisher.Publish("news", "晚间新闻")
+	time.Sleep(200 * time.Millisecond)
+
+	// 迟到的订阅者：先收到历史消息，再收到实时消息
+	fmt.Println("\n--- 迟到订阅者回放历史消息 ---")
+	sub4 := NewSubscriber(4)
+	publisher.SubscribeWithReplay(sub4, "tech.*")
+	publisher.StartSubscriber(sub4, sub4.Handle)
+
+	publisher.Publish("tech.go", "Go 1.22 循环变量语义调整")
+	time.Sleep(500 * time.Millisecond)
+
+	// 单独停止订阅者2：等待其处理完剩余消息后返回
+	fmt.Println("\n--- 停止单个订阅者 ---")
+	publisher.StopSubscriber(2)
+	publisher.Publish("sports.football.final", "加时赛结果")
+	time.Sleep(200 * time.Millisecond)
+
+	// 关闭发布者并等待所有托管的监听协程退出，重复关闭是安全的
+	publisher.Close()
+	publisher.Close()
+
+	// 并发发布的同时移除订阅者：channel由发布者统一关闭，不会出现向已关闭channel发送的panic
+	// 订阅者列表和订阅模式集合与泛型版本共用同一份实现，
+	// 同样场景下断言投递数量的测试：go test -race -run PublishWhileRemoving ./pkg/pubsub
+	fmt.Println("\n--- 并发发布时取消订阅与移除订阅者 ---")
+	churnPublisher := NewPublisher(0)
+
+	var churnWg sync.WaitGroup
+	var received int64
+	for i := 20; i < 25; i++ {
+		sub := NewSubscriber(i)
+		churnPublisher.AddSubscriber(sub)
+		sub.Subscribe("churn")
+
+		churnWg.Add(1)
+		go func() {
+			defer churnWg.Done()
+			for range sub.Messages {
+				atomic.AddInt64(&received, 1)
+			}
+		}()
+	}
+
+	var publishWg sync.WaitGroup
+	for p := 0; p < 3; p++ {
+		publishWg.Add(1)
+		go func(publisherID int) {
+			defer publishWg.Done()
+			for i := 0; i < 20; i++ {
+				churnPublisher.Publish("churn", fmt.Sprintf("P%d-%d", publisherID, i))
+				time.Sleep(5 * time.Millisecond)
+			}
+		}(p)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	churnPublisher.RemoveSubscriber(21)
+	churnPublisher.RemoveSubscriber(23)
+	if !churnPublisher.RemoveSubscriber(23) {
+		fmt.Println("重复移除订阅者 23 被安全忽略")
+	}
+
+	publishWg.Wait()
+	churnPublisher.Close()
+	churnWg.Wait()
+	fmt.Printf("并发移除测试完成，共投递 %d 条消息\n", atomic.LoadInt64(&received))
+
+	// 溢出策略对比：4个慢订阅者，队列容量为3，突发发布10条消息
+	fmt.Println("\n--- 慢订阅者溢出策略对比 ---")
+	burstPublisher := NewPublisher(0)
+	policies := []pubsub.OverflowPolicy{pubsub.DropNewest, pubsub.DropOldest, pubsub.Block, pubsub.Disconnect}
+
+	var burstWg sync.WaitGroup
+	for i, policy := range policies {
+		sub := NewSubscriberWithPolicy(10+i, 3, policy)
+		burstPublisher.AddSubscriber(sub)
+		sub.Subscribe("burst")
+
+		burstWg.Add(1)
+		go func() {
+			defer burstWg.Done()
+			sub.Listen()
+		}()
+	}
+
+	for i := 1; i <= 10; i++ {
+		burstPublisher.Publish("burst", fmt.Sprintf("突发消息 %d", i))
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	burstPublisher.Close()
+	burstWg.Wait()
+
+	fmt.Println("\n丢弃统计:")
+	burstPublisher.PrintDropStats()
+
+	// 至少一次投递：不可靠的订阅者第一次收到消息时不确认，发布者超时后重投
+	fmt.Println("\n--- 确认与重投（至少一次投递） ---")
+	ackPublisher := NewPublisher(0)
+	ackPublisher.EnableAcks(300*time.Millisecond, 3)
+
+	reliable := NewSubscriber(40)
+	flaky := NewSubscriber(41)
+	broken := NewSubscriber(42)
+	for _, sub := range []*Subscriber{reliable, flaky, broken} {
+		ackPublisher.AddSubscriber(sub)
+		sub.Subscribe("payments")
+	}
+
+	var ackWg sync.WaitGroup
+	ackWg.Add(3)
+	go func() {
+		defer ackWg.Done()
+		for msg := range reliable.Messages {
+			fmt.Printf("订阅者 %d 处理消息 %d 并确认\n", reliable.ID, msg.ID)
+			ackPublisher.Ack(reliable.ID, msg.ID)
+		}
+	}()
+	go func() {
+		defer ackWg.Done()
+		for msg := range flaky.Messages {
+			// 首次投递时"崩溃"，不发送确认
+			if msg.Attempt == 1 {
+				fmt.Printf("订阅者 %d 处理消息 %d 时失败，未确认\n", flaky.ID, msg.ID)
+				continue
+			}
+			fmt.Printf("订阅者 %d 在第 %d 次投递时处理消息 %d 并确认\n", flaky.ID, msg.Attempt, msg.ID)
+			ackPublisher.Ack(flaky.ID, msg.ID)
+		}
+	}()
+	go func() {
+		defer ackWg.Done()
+		for msg := range broken.Messages {
+			fmt.Printf("订阅者 %d 收到消息 %d (第 %d 次)，始终无法处理\n", broken.ID, msg.ID, msg.Attempt)
+		}
+	}()
+
+	ackPublisher.Publish("payments", "支付成功: 订单1001")
+	ackPublisher.Publish("payments", "支付成功: 订单1002")
+	time.Sleep(1500 * time.Millisecond)
+
+	acked, redelivered, expired, pending := ackPublisher.AckStats()
+	fmt.Printf("确认统计: 已确认=%d, 重投=%d, 放弃=%d, 待确认=%d\n", acked, redelivered, expired, pending)
+
+	ackPublisher.Close()
+	ackWg.Wait()
+
+	// 同步发布与异步发布对比：同步发布会等待每个订阅者处理完成或拒绝
+	fmt.Println("\n--- 同步发布 vs 异步发布 ---")
+	syncPublisher := NewPublisher(0)
+	mailer := NewSubscriber(50)
+	moderator := NewSubscriber(51)
+	for _, sub := range []*Subscriber{mailer, moderator} {
+		syncPublisher.AddSubscriber(sub)
+		sub.Subscribe("comments")
+	}
+
+	var syncWg sync.WaitGroup
+	syncWg.Add(2)
+	go func() {
+		defer syncWg.Done()
+		mailer.Listen() // Listen处理完每条消息后自动Confirm
+	}()
+	go func() {
+		defer syncWg.Done()
+		for msg := range moderator.Messages {
+			if strings.Contains(msg.Content, "广告") {
+				fmt.Printf("订阅者 %d 拒绝消息: %s\n", moderator.ID, msg.Content)
+				msg.Reject()
+				continue
+			}
+			time.Sleep(50 * time.Millisecond)
+			msg.Confirm()
+		}
+	}()
+
+	for _, content := range []string{"写得很好", "点击领取广告红包"} {
+		syncPublisher.Publish("comments", content)
+		result := syncPublisher.PublishSync("comments", content, time.Second)
+		fmt.Printf("同步发布结果: 匹配=%d, 确认=%d, 拒绝=%d, 超时=%d, 耗时=%v\n",
+			result.Matched, result.Received, result.Rejected, result.TimedOut,
+			result.Latency.Round(time.Millisecond))
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	syncPublisher.PrintLatencyStats()
+	syncPublisher.Close()
+	syncWg.Wait()
+
+	// 泛型版本：消息负载是强类型的OrderEvent，无需字符串解析或类型断言
+	// 主题路由（通配符）和溢出策略与上面的字符串版本共用同一套实现
+	fmt.Println("\n--- 泛型强类型发布订阅 ---")
+	orderPublisher := pubsub.NewPublisher[OrderEvent]()
+	billing := pubsub.NewSubscriber[OrderEvent](30, 5, pubsub.Block)
+	billing.Subscribe("orders.*")
+	audit := pubsub.NewSubscriber[OrderEvent](31, 5, pubsub.DropOldest)
+	audit.Subscribe("orders.#")
+	orderPublisher.AddSubscriber(billing)
+	orderPublisher.AddSubscriber(audit)
+
+	var orderWg sync.WaitGroup
+	for _, sub := range []*pubsub.Subscriber[OrderEvent]{billing, audit} {
+		orderWg.Add(1)
+		go func(s *pubsub.Subscriber[OrderEvent]) {
+			defer orderWg.Done()
+			total := 0.0
+			for msg := range s.Messages() {
+				total += msg.Payload.Amount // 直接访问强类型字段
+				fmt.Printf("订阅者 %d 收到订单事件 [%s]: 订单%d %s 金额%.2f\n",
+					s.ID, msg.Topic, msg.Payload.OrderID, msg.Payload.Status, msg.Payload.Amount)
+			}
+			fmt.Printf("订阅者 %d 累计金额: %.2f, 丢弃: %d\n", s.ID, total, s.Dropped())
+		}(sub)
+	}
+
+	orderPublisher.Publish("orders.created", OrderEvent{OrderID: 1, Amount: 99.5, Status: "created"})
+	orderPublisher.Publish("orders.paid", OrderEvent{OrderID: 1, Amount: 99.5, Status: "paid"})
+	orderPublisher.Publish("orders.refund.partial", OrderEvent{OrderID: 2, Amount: 20, Status: "refund"})
+	time.Sleep(100 * time.Millisecond)
+
+	orderPublisher.Close()
+	orderWg.Wait()
+
+	fmt.Println("发布订阅演示完成！")
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/errgroup"
+)
+
+// Context取消演示
+func longRunningTask(ctx context.Context, id int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for i := 1; i <= 10; i++ {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("任务 %d 被取消: %v\n", id, ctx.Err())
+			return
+		default:
+			fmt.Printf("任务 %d 执行步骤 %d\n", id, i)
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	fmt.Printf("任务 %d 正常完成\n", id)
+}
+
+func taskWithTimeout(ctx context.Context, id int, duration time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	fmt.Printf("任务 %d 开始，超时时间: %v\n", id, duration)
+
+	select {
+	case <-time.After(2 * time.Second):
+		fmt.Printf("任务 %d 完成工作\n", id)
+	case <-ctx.Done():
+		fmt.Printf("任务 %d 超时: %v\n", id, ctx.Err())
+	}
+}
+
+// fetchService 模拟调用一个下游服务，耗时delay；fail为true时返回错误
+// 等待期间监听ctx，被取消时立即返回
+func fetchService(ctx context.Context, name string, delay time.Duration, fail bool) error {
+	fmt.Printf("  请求 %s...\n", name)
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		fmt.Printf("  %s 被取消: %v\n", name, context.Cause(ctx))
+		return ctx.Err()
+	}
+
+	if fail {
+		fmt.Printf("  %s 返回错误\n", name)
+		return fmt.Errorf("%s unavailable", name)
+	}
+
+	fmt.Printf("  %s 完成\n", name)
+	return nil
+}
+
+// errGroupDemo 并发请求多个服务，任一失败立即取消其余请求
+func errGroupDemo() {
+	type service struct {
+		name  string
+		delay time.Duration
+		fail  bool
+	}
+	services := []service{
+		{"用户服务", 300 * time.Millisecond, false},
+		{"订单服务", 500 * time.Millisecond, true},
+		{"库存服务", 2 * time.Second, false},
+		{"推荐服务", 3 * time.Second, false},
+	}
+
+	start := time.Now()
+	g, ctx := errgroup.WithContext(context.Background())
+	for _, svc := range services {
+		svc := svc
+		g.Go(func() error {
+			return fetchService(ctx, svc.name, svc.delay, svc.fail)
+		})
+	}
+
+	err := g.Wait()
+	fmt.Printf("  Wait返回: %v (耗时 %v，没有等待最慢的推荐服务)\n", err, time.Since(start).Round(10*time.Millisecond))
+	fmt.Printf("  errors.Is(context.Cause(ctx), err) = %v\n", errors.Is(context.Cause(ctx), err))
+}
+
+// TracedContext 记录派生关系的context，用于打印context树及各节点的取消原因
+type TracedContext struct {
+	context.Context
+	name     string
+	parent   *TracedContext
+	cancel   context.CancelCauseFunc
+	mu       sync.Mutex
+	children []*TracedContext
+}
+
+// NewTracedRoot 创建context树的根节点
+func NewTracedRoot(name string) *TracedContext {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	return &TracedContext{Context: ctx, name: name, cancel: cancel}
+}
+
+// Derive 派生一个可以带原因取消的子节点
+func (t *TracedContext) Derive(name string) *TracedContext {
+	ctx, cancel := context.WithCancelCause(t.Context)
+	return t.addChild(name, ctx, cancel)
+}
+
+// DeriveWithTimeout 派生一个超时后以cause取消的子节点
+func (t *TracedContext) DeriveWithTimeout(name string, timeout time.Duration, cause error) *TracedContext {
+	ctx, cancel := context.WithCancelCause(t.Context)
+	ctx, stop := context.WithTimeoutCause(ctx, timeout, cause)
+	return t.addChild(name, ctx, func(err error) {
+		cancel(err)
+		stop()
+	})
+}
+
+func (t *TracedContext) addChild(name string, ctx context.Context, cancel context.CancelCauseFunc) *TracedContext {
+	child := &TracedContext{Context: ctx, name: name, parent: t, cancel: cancel}
+	t.mu.Lock()
+	t.children = append(t.children, child)
+	t.mu.Unlock()
+	return child
+}
+
+// Cancel 以指定原因取消该节点及其所有子孙节点
+func (t *TracedContext) Cancel(cause error) {
+	t.cancel(cause)
+}
+
+// status 节点状态描述：原因与父节点相同说明是被父节点连带取消的
+func (t *TracedContext) status() string {
+	if t.Err() == nil {
+		return "运行中"
+	}
+	cause := context.Cause(t.Context)
+	if t.parent != nil && t.parent.Err() != nil && context.Cause(t.parent.Context) == cause {
+		return fmt.Sprintf("已取消(随父节点): %v", cause)
+	}
+	return fmt.Sprintf("已取消: %v", cause)
+}
+
+// Print 打印以该节点为根的context树
+func (t *TracedContext) Print() {
+	fmt.Printf("  %s [%s]\n", t.name, t.status())
+	t.printChildren("  ")
+}
+
+func (t *TracedContext) printChildren(indent string) {
+	t.mu.Lock()
+	children := append([]*TracedContext(nil), t.children...)
+	t.mu.Unlock()
+
+	for i, child := range children {
+		branch, next := "├─ ", "│  "
+		if i == len(children)-1 {
+			branch, next = "└─ ", "   "
+		}
+		fmt.Printf("%s%s%s [%s]\n", indent, branch, child.name, child.status())
+		child.printChildren(indent + next)
+	}
+}
+
+// tracedWorker 一直工作到ctx被取消，退出时打印取消原因
+func tracedWorker(ctx *TracedContext, wg *sync.WaitGroup) {
+	defer wg.Done()
+	<-ctx.Done()
+	fmt.Printf("  %s 停止，原因: %v\n", ctx.name, context.Cause(ctx))
+}
+
+// causeTreeDemo 一个请求派生出多个子任务，分别因不同原因被取消
+func causeTreeDemo() {
+	request := NewTracedRoot("HTTP请求")
+	db := request.Derive("数据库查询")
+	tx := db.Derive("事务")
+	cache := request.Derive("缓存读取")
+	rpc := request.DeriveWithTimeout("RPC调用", 300*time.Millisecond, errors.New("rpc deadline 300ms exceeded"))
+
+	var wg sync.WaitGroup
+	for _, ctx := range []*TracedContext{tx, cache, rpc} {
+		wg.Add(1)
+		go tracedWorker(ctx, &wg)
+	}
+
+	fmt.Println("初始状态:")
+	request.Print()
+
+	// RPC超时：只有它自己被取消
+	time.Sleep(400 * time.Millisecond)
+	// 数据库连接出错：数据库查询及其事务被取消
+	db.Cancel(errors.New("db connection reset"))
+	time.Sleep(100 * time.Millisecond)
+
+	fmt.Println("RPC超时、数据库出错后:")
+	request.Print()
+
+	// 客户端断开：剩余的缓存读取随请求一起取消
+	request.Cancel(errors.New("client disconnected"))
+	wg.Wait()
+
+	fmt.Println("客户端断开后:")
+	request.Print()
+	fmt.Println("注意: 已经取消的节点保留最初的原因，不会被父节点后来的取消覆盖")
+}
+
+func main() {
+	numTasks := flag.Int("tasks", 3, "示例1中长时间运行的任务数量")
+	cancelAfter := flag.Duration("cancel-after", 2*time.Second, "示例1中多久后手动取消")
+	demoflag.Parse()
+
+	fmt.Println("=== Context取消演示 ===")
+
+	// 示例1: 手动取消
+	fmt.Println("\n1. 手动取消演示:")
+	ctx1, cancel1 := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+
+	// 启动多个长时间运行的任务（默认3个）
+	for i := 1; i <= *numTasks; i++ {
+		wg.Add(1)
+		go longRunningTask(ctx1, i, &wg)
+	}
+
+	// 默认2秒后取消所有任务
+	time.Sleep(*cancelAfter)
+	fmt.Println("取消所有任务...")
+	cancel1()
+
+	wg.Wait()
+
+	// 示例2: 超时取消
+	fmt.Println("\n2. 超时取消演示:")
+
+	// 启动几个不同超时时间的任务
+	go taskWithTimeout(context.Background(), 1, 1*time.Second) // 会超时
+	go taskWithTimeout(context.Background(), 2, 3*time.Second) // 会完成
+
+	time.Sleep(4 * time.Second)
+
+	// 示例3: 带deadline的取消
+	fmt.Println("\n3. Deadline取消演示:")
+	deadline := time.Now().Add(1500 * time.Millisecond)
+	ctx3, cancel3 := context.WithDeadline(context.Background(), deadline)
+	defer cancel3()
+
+	var wg2 sync.WaitGroup
+	wg2.Add(1)
+	go longRunningTask(ctx3, 99, &wg2)
+
+	wg2.Wait()
+
+	// 示例4: errgroup - 第一个错误取消其余任务
+	fmt.Println("\n4. errgroup首错取消演示:")
+	errGroupDemo()
+
+	// 示例5: 取消原因与context树
+	fmt.Println("\n5. 取消原因追踪演示:")
+	causeTreeDemo()
+
+	fmt.Println("Context演示完成！")
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/errgroup"
+	"github.com/klsakura/day1/pkg/fanin"
+)
+
+// 扇入扇出模式演示
+type Task struct {
+	ID   int
+	Data int
+	Key  string // 分片键，fanOutByKey按它选择工作者
+}
+
+type Result struct {
+	TaskID int
+	Result int
+}
+
+// 生成器：生成任务
+func taskGenerator(tasks []Task) <-chan Task {
+	out := make(chan Task)
+	go func() {
+		defer close(out)
+		for _, task := range tasks {
+			out <- task
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	return out
+}
+
+// 扇出：将任务分发给多个工作者
+func fanOut(input <-chan Task, numWorkers int) []<-chan Result {
+	workers := make([]<-chan Result, numWorkers)
+
+	for i := 0; i < numWorkers; i++ {
+		worker := make(chan Result)
+		workers[i] = worker
+
+		go func(workerID int, input <-chan Task, output chan<- Result) {
+			defer close(output)
+			for task := range input {
+				// 模拟处理时间
+				processingTime := time.Duration(rand.Intn(500)+100) * time.Millisecond
+				time.Sleep(processingTime)
+
+				// 计算结果（这里简单地平方）
+				result := Result{
+					TaskID: task.ID,
+					Result: task.Data * task.Data,
+				}
+
+				fmt.Printf("工作者 %d 处理任务 %d: %d -> %d\n",
+					workerID, task.ID, task.Data, result.Result)
+
+				output <- result
+			}
+			fmt.Printf("工作者 %d 完成所有任务\n", workerID)
+		}(i+1, input, worker)
+	}
+
+	return workers
+}
+
+// ===== 根据积压自动扩缩容的扇出 =====
+
+type scaleSample struct {
+	at      time.Duration
+	workers int
+	backlog int
+}
+
+// AutoscaleFanOut 从minWorkers个工作者开始，积压增长时逐个扩容到maxWorkers，
+// 工作者空闲超过idleTimeout后退出（不少于minWorkers）
+type AutoscaleFanOut struct {
+	minWorkers  int
+	maxWorkers  int
+	idleTimeout time.Duration
+
+	queue   chan Task // 内部缓冲，len(queue)即为积压量
+	out     chan Result
+	workers int64 // 当前工作者数量
+	nextID  int64
+	wg      sync.WaitGroup
+
+	start   time.Time
+	samples []scaleSample // 只由扩缩容goroutine写入，结束后读取
+}
+
+func fanOutAutoscale(input <-chan Task, minWorkers, maxWorkers int, idleTimeout time.Duration) *AutoscaleFanOut {
+	a := &AutoscaleFanOut{
+		minWorkers:  minWorkers,
+		maxWorkers:  maxWorkers,
+		idleTimeout: idleTimeout,
+		queue:       make(chan Task, 100),
+		out:         make(chan Result),
+		start:       time.Now(),
+	}
+
+	for i := 0; i < minWorkers; i++ {
+		a.spawn()
+	}
+
+	fed := make(chan struct{})
+	go func() {
+		defer close(fed)
+		for task := range input {
+			a.queue <- task
+		}
+		close(a.queue)
+	}()
+
+	stopScaler := make(chan struct{})
+	scalerDone := make(chan struct{})
+	go a.scale(stopScaler, scalerDone)
+
+	go func() {
+		<-fed
+		// 先停止扩缩容，保证wg.Wait期间不会再有新的工作者加入
+		close(stopScaler)
+		<-scalerDone
+		a.wg.Wait()
+		close(a.out)
+	}()
+
+	return a
+}
+
+func (a *AutoscaleFanOut) Results() <-chan Result {
+	return a.out
+}
+
+func (a *AutoscaleFanOut) spawn() {
+	atomic.AddInt64(&a.workers, 1)
+	id := int(atomic.AddInt64(&a.nextID, 1))
+	a.wg.Add(1)
+	go a.work(id)
+}
+
+// scale 定期检查积压：积压超过当前工作者数量的2倍时扩容一个工作者
+func (a *AutoscaleFanOut) scale(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		backlog := len(a.queue)
+		workers := int(atomic.LoadInt64(&a.workers))
+		if backlog > 2*workers && workers < a.maxWorkers {
+			a.spawn()
+			workers++
+			fmt.Printf("  扩容: 积压 %d，工作者增加到 %d\n", backlog, workers)
+		}
+		a.samples = append(a.samples, scaleSample{at: time.Since(a.start), workers: workers, backlog: backlog})
+	}
+}
+
+func (a *AutoscaleFanOut) work(id int) {
+	defer a.wg.Done()
+	idle := time.NewTimer(a.idleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case task, ok := <-a.queue:
+			if !ok {
+				atomic.AddInt64(&a.workers, -1)
+				return
+			}
+			time.Sleep(time.Duration(rand.Intn(200)+100) * time.Millisecond)
+			a.out <- Result{TaskID: task.ID, Result: task.Data * task.Data}
+			// 处理任务期间计时器可能已经触发，先停止并取走残留的值再Reset，
+			// 否则下一轮select会立刻读到过期的触发而误判为空闲
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(a.idleTimeout)
+
+		case <-idle.C:
+			// 只有数量大于下限时才退出，用CAS避免多个工作者同时退出导致低于下限
+			n := atomic.LoadInt64(&a.workers)
+			if n > int64(a.minWorkers) && atomic.CompareAndSwapInt64(&a.workers, n, n-1) {
+				fmt.Printf("  缩容: 工作者 %d 空闲退出，剩余 %d\n", id, n-1)
+				return
+			}
+			// 刚从idle.C读出触发值，channel已空，可以直接Reset
+			idle.Reset(a.idleTimeout)
+		}
+	}
+}
+
+// PrintReport 打印工作者数量随时间的变化，需在Results关闭后调用
+func (a *AutoscaleFanOut) PrintReport() {
+	for i, s := range a.samples {
+		// 每0.5秒打印一行
+		if i%5 != 0 {
+			continue
+		}
+		fmt.Printf("  %5.1fs 积压=%-3d 工作者=%d %s\n",
+			s.at.Seconds(), s.backlog, s.workers, strings.Repeat("█", s.workers))
+	}
+}
+
+// burstyGenerator 先突发大量任务，暂停后再缓慢产生少量任务
+func burstyGenerator(burst, trickle int) <-chan Task {
+	out := make(chan Task)
+	go func() {
+		defer close(out)
+		id := 1
+		for i := 0; i < burst; i++ {
+			out <- Task{ID: id, Data: id}
+			id++
+		}
+		time.Sleep(2 * time.Second)
+		for i := 0; i < trickle; i++ {
+			out <- Task{ID: id, Data: id}
+			id++
+			time.Sleep(400 * time.Millisecond)
+		}
+	}()
+	return out
+}
+
+func demoAutoscale() {
+	fo := fanOutAutoscale(burstyGenerator(40, 5), 1, 6, 500*time.Millisecond)
+
+	count := 0
+	for range fo.Results() {
+		count++
+	}
+	fmt.Printf("共处理 %d 个任务，工作者数量变化:\n", count)
+	fo.PrintReport()
+}
+
+// ===== 首个错误即终止的扇出扇入 =====
+
+// generateCtx 生成任务，ctx取消后停止生成
+func generateCtx(ctx context.Context, g *errgroup.Group, tasks []Task) <-chan Task {
+	out := make(chan Task)
+	g.Go(func() error {
+		defer close(out)
+		for _, task := range tasks {
+			select {
+			case out <- task:
+			case <-ctx.Done():
+				fmt.Printf("  生成器停止，未发出的任务从 %d 开始\n", task.ID)
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// fanOutCtx 与fanOut相同，但任一工作者返回错误都会通过errgroup取消ctx，
+// 生成器和其余工作者随之退出
+func fanOutCtx(ctx context.Context, g *errgroup.Group, input <-chan Task, numWorkers int,
+	process func(Task) (int, error)) []<-chan Result {
+	workers := make([]<-chan Result, numWorkers)
+
+	for i := 0; i < numWorkers; i++ {
+		output := make(chan Result)
+		workers[i] = output
+		workerID := i + 1
+
+		g.Go(func() error {
+			defer close(output)
+			for task := range input {
+				value, err := process(task)
+				if err != nil {
+					return fmt.Errorf("工作者 %d 处理任务 %d: %w", workerID, task.ID, err)
+				}
+
+				select {
+				case output <- Result{TaskID: task.ID, Result: value}:
+				case <-ctx.Done():
+					fmt.Printf("  工作者 %d 取消，丢弃任务 %d 的结果\n", workerID, task.ID)
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+
+	return workers
+}
+
+// fanInCtx 合并结果直到所有工作者退出，返回已收集的结果和第一个错误
+func fanInCtx(g *errgroup.Group, inputs []<-chan Result) ([]Result, error) {
+	var results []Result
+	for result := range fanin.Merge(inputs...) {
+		results = append(results, result)
+	}
+	return results, g.Wait()
+}
+
+func demoFailFast() {
+	var tasks []Task
+	for i := 1; i <= 12; i++ {
+		tasks = append(tasks, Task{ID: i, Data: i})
+	}
+
+	process := func(task Task) (int, error) {
+		time.Sleep(time.Duration(rand.Intn(200)+100) * time.Millisecond)
+		if task.Data == 5 {
+			return 0, errors.New("数据 5 无效")
+		}
+		return task.Data * task.Data, nil
+	}
+
+	start := time.Now()
+	g, ctx := errgroup.WithContext(context.Background())
+	results, err := fanInCtx(g, fanOutCtx(ctx, g, generateCtx(ctx, g, tasks), 3, process))
+
+	fmt.Printf("  返回错误: %v\n", err)
+	fmt.Printf("  已完成 %d/%d 个任务，耗时 %v\n", len(results), len(tasks), time.Since(start).Round(10*time.Millisecond))
+	fmt.Printf("  context.Cause: %v\n", context.Cause(ctx))
+}
+
+// ===== 按键亲和的分片扇出 =====
+
+// fanOutByKey 按Key的哈希把任务路由到固定的工作者：
+// 同一个Key的任务总是由同一个工作者按顺序串行处理，不同Key之间并行
+func fanOutByKey(input <-chan Task, numWorkers int, process func(workerID int, task Task) Result) []<-chan Result {
+	shards := make([]chan Task, numWorkers)
+	outputs := make([]<-chan Result, numWorkers)
+
+	for i := range shards {
+		shard := make(chan Task, 10)
+		output := make(chan Result)
+		shards[i], outputs[i] = shard, output
+
+		go func(workerID int) {
+			defer close(output)
+			for task := range shard {
+				output <- process(workerID, task)
+			}
+		}(i + 1)
+	}
+
+	// 路由goroutine：输入结束后关闭所有分片
+	go func() {
+		defer func() {
+			for _, shard := range shards {
+				close(shard)
+			}
+		}()
+		for task := range input {
+			h := fnv.New32a()
+			h.Write([]byte(task.Key))
+			shards[h.Sum32()%uint32(numWorkers)] <- task
+		}
+	}()
+
+	return outputs
+}
+
+func demoFanOutByKey() {
+	keys := []string{"alice", "bob", "carol", "dave", "erin"}
+	var tasks []Task
+	for i := 1; i <= 20; i++ {
+		tasks = append(tasks, Task{ID: i, Data: i, Key: keys[rand.Intn(len(keys))]})
+	}
+
+	var mu sync.Mutex
+	active := make(map[string]bool)     // 正在处理的Key
+	processed := make(map[string][]int) // 每个Key的处理顺序
+	owner := make(map[string]int)       // 每个Key由哪个工作者处理
+	overlaps := 0
+
+	process := func(workerID int, task Task) Result {
+		mu.Lock()
+		if active[task.Key] {
+			overlaps++
+		}
+		active[task.Key] = true
+		owner[task.Key] = workerID
+		mu.Unlock()
+
+		time.Sleep(time.Duration(rand.Intn(50)+20) * time.Millisecond)
+
+		mu.Lock()
+		active[task.Key] = false
+		processed[task.Key] = append(processed[task.Key], task.ID)
+		mu.Unlock()
+		return Result{TaskID: task.ID, Result: task.Data * task.Data}
+	}
+
+	input := make(chan Task)
+	go func() {
+		defer close(input)
+		for _, task := range tasks {
+			input <- task
+		}
+	}()
+
+	count := 0
+	for range fanin.Merge(fanOutByKey(input, 3, process)...) {
+		count++
+	}
+
+	for _, key := range keys {
+		ids := processed[key]
+		inOrder := sort.IntsAreSorted(ids)
+		fmt.Printf("  %-5s -> 工作者 %d, 处理顺序 %v, 保持提交顺序: %v\n", key, owner[key], ids, inOrder)
+	}
+	fmt.Printf("  共处理 %d 个任务，同一Key并发处理的次数: %d\n", count, overlaps)
+	fmt.Println("  注意: 工作者负载取决于Key的哈希分布，Key少或有热点Key时会不均衡")
+}
+
+func main() {
+	numWorkers := flag.Int("workers", 3, "扇出的工作者数量")
+	numTasks := flag.Int("tasks", 8, "需要处理的任务数量")
+	demoflag.Parse()
+
+	fmt.Println("=== 扇入扇出模式演示 ===")
+
+	rand.Seed(time.Now().UnixNano())
+
+	// 创建任务：每个任务的数据为ID+1
+	tasks := make([]Task, *numTasks)
+	for i := range tasks {
+		tasks[i] = Task{ID: i + 1, Data: i + 2}
+	}
+
+	fmt.Printf("总共 %d 个任务需要处理\n", len(tasks))
+
+	// 创建任务流
+	taskStream := taskGenerator(tasks)
+
+	// 扇出：将任务分发给多个工作者（默认3个）
+	fmt.Printf("启动 %d 个工作者\n", *numWorkers)
+	workerChannels := fanOut(taskStream, *numWorkers)
+
+	// 扇入：合并工作者的结果
+	results := fanin.Merge(workerChannels...)
+
+	// 收集最终结果
+	fmt.Println("\n最终结果:")
+	var totalResults []Result
+	for result := range results {
+		totalResults = append(totalResults, result)
+	}
+
+	fmt.Print("\n到达顺序: ")
+	for _, result := range totalResults {
+		fmt.Printf("任务%d ", result.TaskID)
+	}
+	fmt.Printf("\n处理完成！共处理 %d 个任务\n", len(totalResults))
+
+	// 有序扇入：结果按任务ID依次输出，先完成的后续任务在重排缓冲区中等待
+	fmt.Println("\n=== 有序扇入 ===")
+	ordered := fanin.MergeOrdered(1, func(r Result) int { return r.TaskID },
+		fanOut(taskGenerator(tasks), *numWorkers)...)
+	for result := range ordered {
+		fmt.Printf("按序输出 任务 %d: %d\n", result.TaskID, result.Result)
+	}
+
+	fmt.Println("\n=== 根据积压自动扩缩容 ===")
+	demoAutoscale()
+
+	fmt.Println("\n=== 首个错误即终止 ===")
+	demoFailFast()
+
+	fmt.Println("\n=== 按键亲和分片 ===")
+	demoFanOutByKey()
+}
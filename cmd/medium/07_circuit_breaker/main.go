@@ -0,0 +1,159 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/07_circuit_breaker/main.go
+主题：熔断器模式
+
+本示例演示：
+1. 熔断器的三种状态：CLOSED、OPEN、HALF_OPEN
+2. 失败率阈值触发熔断
+3. 超时后的自动恢复机制
+4. 半开状态的试探性调用
+5. 保护不稳定服务的策略
+
+核心概念：
+- CLOSED：正常状态，请求正常通过
+- OPEN：熔断状态，快速失败，不调用服务
+- HALF_OPEN：半开状态，允许少量请求试探服务是否恢复
+
+应用场景：
+- 微服务架构中的服务保护
+- 防止故障服务拖垮整个系统
+- 提供快速失败机制
+
+运行方式：go run ./cmd/medium/07_circuit_breaker [-ratio=0.5] [-min-requests=10] [-reset=3s] [-fail-rate=0.7]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/breaker"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/downstream"
+)
+
+// printTransition 打印熔断器的状态变化
+func printTransition(t breaker.Transition) {
+	if t.From == breaker.StateClosed && t.To == breaker.StateOpen {
+		fmt.Printf("熔断器状态: %s -> %s (失败率: %.2f%%)\n", t.From, t.To, t.FailureRatio()*100)
+		return
+	}
+	fmt.Printf("熔断器状态: %s -> %s\n", t.From, t.To)
+}
+
+func main() {
+	resetTimeout := flag.Duration("reset", 3*time.Second, "从OPEN到HALF_OPEN的等待时间")
+	failureRatio := flag.Float64("ratio", 0.5, "触发熔断的失败率阈值（0-1）")
+	minRequests := flag.Int("min-requests", 10, "至少多少个请求后才考虑熔断")
+	failRate := flag.Float64("fail-rate", 0.7, "第一阶段下游服务的失败率（0-1）")
+	recoverRate := flag.Float64("recover-rate", 0.2, "第二阶段起下游服务的失败率（0-1）")
+	demoflag.Parse()
+
+	fmt.Println("=== 熔断器模式演示 ===")
+	fmt.Println("演示熔断器如何保护不稳定的服务调用")
+
+	rand.Seed(time.Now().UnixNano())
+
+	// 创建熔断器配置，默认3秒后尝试恢复、50%失败率触发熔断、至少10个请求后才考虑熔断
+	config := breaker.Config{
+		ResetTimeout:    *resetTimeout,
+		FailureRatio:    *failureRatio,
+		MinRequestCount: *minRequests,
+		OnStateChange:   printTransition,
+	}
+
+	// 创建熔断器和不稳定服务
+	circuitBreaker := breaker.New(config)
+	service := downstream.NewUnstableService(*failRate) // 默认初始70%失败率
+
+	fmt.Printf("熔断器配置: 失败率阈值=%.0f%%, 最小请求数=%d, 重置超时=%v\n",
+		config.FailureRatio*100, config.MinRequestCount, config.ResetTimeout)
+
+	// 模拟客户端并发请求
+	var wg sync.WaitGroup
+	requestCount := 50
+
+	// 第一阶段：高失败率，触发熔断
+	fmt.Println("\n=== 第一阶段：高失败率测试 ===")
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(reqID int) {
+			defer wg.Done()
+
+			// 通过熔断器调用服务
+			err := circuitBreaker.Call(func() error {
+				return service.Call(fmt.Sprintf("req-%d", reqID))
+			})
+
+			if err != nil {
+				fmt.Printf("请求 req-%d 失败: %v\n", reqID, err)
+			}
+
+			// 定期打印统计信息
+			requests, failures, state := circuitBreaker.Stats()
+			if reqID%5 == 0 {
+				fmt.Printf("当前统计: 请求=%d, 失败=%d, 状态=%s\n",
+					requests, failures, state)
+			}
+		}(i)
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	wg.Wait()
+	time.Sleep(1 * time.Second)
+
+	// 第二阶段：等待熔断器恢复
+	fmt.Println("\n=== 第二阶段：等待熔断器恢复 ===")
+	service.SetFailureRate(*recoverRate) // 默认降低失败率到20%
+
+	fmt.Println("等待熔断器重置...")
+	time.Sleep(*resetTimeout + time.Second) // 等待超过重置时间
+
+	// 第三阶段：低失败率，熔断器恢复
+	fmt.Println("\n=== 第三阶段：低失败率测试 ===")
+	for i := 21; i <= requestCount; i++ {
+		wg.Add(1)
+		go func(reqID int) {
+			defer wg.Done()
+
+			err := circuitBreaker.Call(func() error {
+				return service.Call(fmt.Sprintf("req-%d", reqID))
+			})
+
+			if err != nil {
+				fmt.Printf("请求 req-%d 失败: %v\n", reqID, err)
+			}
+
+			requests, failures, state := circuitBreaker.Stats()
+			if reqID%10 == 0 {
+				fmt.Printf("当前统计: 请求=%d, 失败=%d, 状态=%s\n",
+					requests, failures, state)
+			}
+		}(i)
+
+		time.Sleep(150 * time.Millisecond)
+	}
+
+	wg.Wait()
+
+	// 最终统计
+	fmt.Println("\n=== 最终统计 ===")
+	requests, failures, state := circuitBreaker.Stats()
+	fmt.Printf("总请求数: %d\n", requests)
+	fmt.Printf("总失败数: %d\n", failures)
+	fmt.Printf("失败率: %.2f%%\n", float64(failures)/float64(requests)*100)
+	fmt.Printf("最终状态: %s\n", state)
+
+	fmt.Println("\n熔断器演示完成！")
+	fmt.Println("观察要点：")
+	fmt.Println("1. 失败率达到阈值时自动熔断")
+	fmt.Println("2. 熔断期间快速失败，保护系统")
+	fmt.Println("3. 超时后自动尝试恢复")
+	fmt.Println("4. 半开状态的试探机制")
+}
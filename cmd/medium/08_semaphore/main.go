@@ -0,0 +1,382 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// 信号量实现演示
+type Semaphore struct {
+	ch chan struct{}
+}
+
+func NewSemaphore(capacity int) *Semaphore {
+	return &Semaphore{
+		ch: make(chan struct{}, capacity),
+	}
+}
+
+func (s *Semaphore) Acquire() {
+	s.ch <- struct{}{}
+}
+
+func (s *Semaphore) Release() {
+	<-s.ch
+}
+
+func (s *Semaphore) TryAcquire() bool {
+	select {
+	case s.ch <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// TryAcquireFor 在d时间内尝试获取许可，超时则放弃并返回false
+func (s *Semaphore) TryAcquireFor(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case s.ch <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (s *Semaphore) Available() int {
+	return cap(s.ch) - len(s.ch)
+}
+
+// 调试信号量：检测常见的信号量误用
+// 1. Release次数多于Acquire（重复释放）
+// 2. 等待者等待时间过长（可能出现饥饿）
+type DebugSemaphore struct {
+	sem           *Semaphore
+	name          string
+	waitThreshold time.Duration // 等待超过该时间时发出警告
+	panicOnMisuse bool          // true: 误用时panic；false: 仅报告
+	held          int64         // 当前已获取未释放的许可数
+	misuses       int64         // 重复释放次数
+	slowWaits     int64         // 等待超时警告次数
+}
+
+func NewDebugSemaphore(name string, capacity int, waitThreshold time.Duration, panicOnMisuse bool) *DebugSemaphore {
+	return &DebugSemaphore{
+		sem:           NewSemaphore(capacity),
+		name:          name,
+		waitThreshold: waitThreshold,
+		panicOnMisuse: panicOnMisuse,
+	}
+}
+
+// Acquire 获取许可，等待超过阈值时立即打印警告（不必等到获取成功）
+func (d *DebugSemaphore) Acquire(who string) {
+	start := time.Now()
+	warning := time.AfterFunc(d.waitThreshold, func() {
+		atomic.AddInt64(&d.slowWaits, 1)
+		fmt.Printf("[%s 警告] %s 已等待超过 %v，可能存在饥饿或许可泄露\n",
+			d.name, who, d.waitThreshold)
+	})
+
+	d.sem.Acquire()
+	warning.Stop()
+	atomic.AddInt64(&d.held, 1)
+
+	if waited := time.Since(start); waited > d.waitThreshold {
+		fmt.Printf("[%s] %s 最终获得许可，共等待 %v\n", d.name, who, waited.Round(time.Millisecond))
+	}
+}
+
+// Release 释放许可，没有匹配的Acquire时报告或panic，而不是阻塞在空channel上
+func (d *DebugSemaphore) Release(who string) {
+	for {
+		held := atomic.LoadInt64(&d.held)
+		if held <= 0 {
+			atomic.AddInt64(&d.misuses, 1)
+			msg := fmt.Sprintf("[%s 错误] %s 调用Release但没有匹配的Acquire（重复释放）", d.name, who)
+			if d.panicOnMisuse {
+				panic(msg)
+			}
+			fmt.Println(msg)
+			return
+		}
+		if atomic.CompareAndSwapInt64(&d.held, held, held-1) {
+			break
+		}
+	}
+
+	d.sem.Release()
+}
+
+// Report 返回诊断统计：当前持有数、重复释放次数、慢等待次数
+func (d *DebugSemaphore) Report() (int64, int64, int64) {
+	return atomic.LoadInt64(&d.held), atomic.LoadInt64(&d.misuses), atomic.LoadInt64(&d.slowWaits)
+}
+
+// 资源池演示
+type Resource struct {
+	ID   int
+	Name string
+}
+
+type ResourcePool struct {
+	resources []Resource
+	semaphore *Semaphore
+	mu        sync.Mutex
+	available []Resource
+	inUse     map[int]Resource
+}
+
+func NewResourcePool(resources []Resource) *ResourcePool {
+	pool := &ResourcePool{
+		resources: resources,
+		semaphore: NewSemaphore(len(resources)),
+		available: make([]Resource, len(resources)),
+		inUse:     make(map[int]Resource),
+	}
+
+	copy(pool.available, resources)
+	return pool
+}
+
+func (rp *ResourcePool) AcquireResource() (*Resource, error) {
+	// 获取信号量
+	rp.semaphore.Acquire()
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if len(rp.available) == 0 {
+		rp.semaphore.Release()
+		return nil, fmt.Errorf("no resources available")
+	}
+
+	// 取出一个资源
+	resource := rp.available[0]
+	rp.available = rp.available[1:]
+	rp.inUse[resource.ID] = resource
+
+	return &resource, nil
+}
+
+func (rp *ResourcePool) ReleaseResource(resource *Resource) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if _, exists := rp.inUse[resource.ID]; exists {
+		delete(rp.inUse, resource.ID)
+		rp.available = append(rp.available, *resource)
+		rp.semaphore.Release()
+	}
+}
+
+func (rp *ResourcePool) GetStats() (int, int, int) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	return len(rp.available), len(rp.inUse), rp.semaphore.Available()
+}
+
+// 限制并发连接数的例子
+type ConnectionManager struct {
+	semaphore *Semaphore
+	active    int64
+	mu        sync.Mutex
+}
+
+func NewConnectionManager(maxConnections int) *ConnectionManager {
+	return &ConnectionManager{
+		semaphore: NewSemaphore(maxConnections),
+	}
+}
+
+func (cm *ConnectionManager) HandleConnection(clientID int) error {
+	fmt.Printf("客户端 %d 尝试连接...\n", clientID)
+
+	// 尝试获取连接许可
+	if !cm.semaphore.TryAcquire() {
+		fmt.Printf("客户端 %d 连接被拒绝 (连接数已满)\n", clientID)
+		return fmt.Errorf("connection limit reached")
+	}
+
+	cm.mu.Lock()
+	cm.active++
+	currentActive := cm.active
+	cm.mu.Unlock()
+
+	fmt.Printf("客户端 %d 连接成功 (当前活跃连接: %d)\n", clientID, currentActive)
+
+	// 模拟连接处理时间
+	time.Sleep(time.Duration(clientID%3+1) * time.Second)
+
+	// 释放连接
+	cm.mu.Lock()
+	cm.active--
+	currentActive = cm.active
+	cm.mu.Unlock()
+
+	cm.semaphore.Release()
+	fmt.Printf("客户端 %d 断开连接 (当前活跃连接: %d)\n", clientID, currentActive)
+
+	return nil
+}
+
+func main() {
+	numResources := flag.Int("resources", 3, "示例1中资源池的资源数量")
+	numWorkers := flag.Int("workers", 5, "示例1中竞争资源的工作者数量")
+	maxConns := flag.Int("max-conns", 3, "示例2中允许的最大并发连接数")
+	numClients := flag.Int("clients", 8, "示例2中尝试连接的客户端数量")
+	demoflag.Parse()
+
+	fmt.Println("=== 信号量实现演示 ===")
+
+	// 示例1: 资源池管理
+	fmt.Println("\n1. 资源池管理演示:")
+
+	resources := make([]Resource, *numResources)
+	for i := range resources {
+		resources[i] = Resource{ID: i + 1, Name: fmt.Sprintf("数据库连接%d", i+1)}
+	}
+
+	pool := NewResourcePool(resources)
+	var wg sync.WaitGroup
+
+	// 默认启动5个工作者竞争3个资源
+	for i := 1; i <= *numWorkers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+
+			fmt.Printf("工作者 %d 请求资源\n", workerID)
+			resource, err := pool.AcquireResource()
+			if err != nil {
+				fmt.Printf("工作者 %d 获取资源失败: %v\n", workerID, err)
+				return
+			}
+
+			fmt.Printf("工作者 %d 获得资源: %s\n", workerID, resource.Name)
+
+			// 模拟使用资源
+			time.Sleep(time.Duration(workerID) * 500 * time.Millisecond)
+
+			pool.ReleaseResource(resource)
+			fmt.Printf("工作者 %d 释放资源: %s\n", workerID, resource.Name)
+
+			available, inUse, semAvail := pool.GetStats()
+			fmt.Printf("资源状态 - 可用: %d, 使用中: %d, 信号量可用: %d\n",
+				available, inUse, semAvail)
+		}(i)
+	}
+
+	wg.Wait()
+
+	// 示例2: 连接数限制
+	fmt.Println("\n2. 连接数限制演示:")
+
+	connManager := NewConnectionManager(*maxConns) // 默认最多3个并发连接
+
+	// 默认启动8个客户端尝试连接
+	for i := 1; i <= *numClients; i++ {
+		wg.Add(1)
+		go func(clientID int) {
+			defer wg.Done()
+			connManager.HandleConnection(clientID)
+		}(i)
+
+		time.Sleep(200 * time.Millisecond) // 错开连接时间
+	}
+
+	wg.Wait()
+
+	// 示例3: 批量处理限制
+	fmt.Println("\n3. 批量处理限制演示:")
+
+	processSemaphore := NewSemaphore(2) // 最多2个并发处理
+
+	tasks := []string{"任务A", "任务B", "任务C", "任务D", "任务E"}
+
+	var processed, skipped int64
+	var statsMu sync.Mutex
+
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(taskName string) {
+			defer wg.Done()
+
+			fmt.Printf("%s 等待处理许可 (最多等待1.5秒)...\n", taskName)
+			// 限时等待许可，避免在竞争激烈时无限阻塞
+			if !processSemaphore.TryAcquireFor(1500 * time.Millisecond) {
+				fmt.Printf("%s 等待超时，本批次跳过\n", taskName)
+				statsMu.Lock()
+				skipped++
+				statsMu.Unlock()
+				return
+			}
+
+			fmt.Printf("%s 开始处理 (可用许可: %d)\n", taskName, processSemaphore.Available())
+
+			// 模拟处理时间
+			time.Sleep(2 * time.Second)
+
+			fmt.Printf("%s 处理完成\n", taskName)
+			processSemaphore.Release()
+
+			statsMu.Lock()
+			processed++
+			statsMu.Unlock()
+		}(task)
+
+		time.Sleep(300 * time.Millisecond)
+	}
+
+	wg.Wait()
+
+	fmt.Printf("批量处理结果: 完成 %d 个, 超时跳过 %d 个\n", processed, skipped)
+
+	// 示例4: 信号量误用检测
+	fmt.Println("\n4. 信号量误用检测演示:")
+
+	debugSem := NewDebugSemaphore("debug-sem", 1, 500*time.Millisecond, false)
+
+	// 误用1: 持有者长时间不释放，其他等待者出现饥饿
+	debugSem.Acquire("持有者")
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		debugSem.Acquire("等待者")
+		debugSem.Release("等待者")
+	}()
+
+	time.Sleep(1 * time.Second)
+	debugSem.Release("持有者")
+	wg.Wait()
+
+	// 误用2: 重复释放（普通Semaphore会在这里永久阻塞）
+	debugSem.Acquire("工作者")
+	debugSem.Release("工作者")
+	debugSem.Release("工作者")
+
+	held, misuses, slowWaits := debugSem.Report()
+	fmt.Printf("诊断统计 - 持有中: %d, 重复释放: %d, 慢等待警告: %d\n", held, misuses, slowWaits)
+
+	// panic模式：适合在测试中尽早暴露问题
+	strictSem := NewDebugSemaphore("strict-sem", 1, time.Second, true)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("捕获到panic: %v\n", r)
+			}
+		}()
+		strictSem.Release("错误调用者")
+	}()
+
+	fmt.Println("\n信号量演示完成！")
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/actor"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/future"
+)
+
+// Actor模型演示：Actor、邮箱和Actor系统在pkg/actor中，这里实现具体的Actor
+
+// printf 把Actor的事件打印出来
+func printf(format string, args ...any) {
+	fmt.Printf(format, args...)
+}
+
+// newActor 创建打印事件的基础Actor
+func newActor(address string, mailboxSize int) *actor.BaseActor {
+	a := actor.NewBaseActor(address, mailboxSize)
+	a.SetLogf(printf)
+	return a
+}
+
+// registerActor 注册Actor并打印
+func registerActor(system *actor.System, a actor.Actor) {
+	system.RegisterActor(a)
+	fmt.Printf("注册Actor: %s\n", a.GetAddress())
+}
+
+// 计算器Actor
+type CalculatorActor struct {
+	*actor.BaseActor
+	result float64
+}
+
+func NewCalculatorActor(address string) *CalculatorActor {
+	calc := &CalculatorActor{
+		BaseActor: newActor(address, 100),
+		result:    0,
+	}
+
+	// 注册计算器特定的处理器
+	calc.RegisterHandler("ADD", calc.handleAdd)
+	calc.RegisterHandler("MULTIPLY", calc.handleMultiply)
+	calc.RegisterHandler("QUERY", calc.handleQuery)
+
+	return calc
+}
+
+type AddMessage struct {
+	Value float64
+}
+
+func (m AddMessage) GetType() string { return "ADD" }
+
+type MultiplyMessage struct {
+	Value float64
+}
+
+func (m MultiplyMessage) GetType() string { return "MULTIPLY" }
+
+func (c *CalculatorActor) handleAdd(msg actor.Message) {
+	addMsg := msg.(AddMessage)
+	c.result += addMsg.Value
+	fmt.Printf("Calculator %s: 加法操作 +%.2f, 结果=%.2f\n",
+		c.GetAddress(), addMsg.Value, c.result)
+}
+
+func (c *CalculatorActor) handleMultiply(msg actor.Message) {
+	mulMsg := msg.(MultiplyMessage)
+	c.result *= mulMsg.Value
+	fmt.Printf("Calculator %s: 乘法操作 *%.2f, 结果=%.2f\n",
+		c.GetAddress(), mulMsg.Value, c.result)
+}
+
+func (c *CalculatorActor) handleQuery(msg actor.Message) {
+	queryMsg := msg.(actor.QueryMessage)
+	if queryMsg.Query == "result" {
+		queryMsg.Reply.Resolve(c.result)
+		fmt.Printf("Calculator %s: 查询结果=%.2f\n", c.GetAddress(), c.result)
+	}
+}
+
+// 日志Actor
+type LoggerActor struct {
+	*actor.BaseActor
+	logs []string
+}
+
+func NewLoggerActor(address string) *LoggerActor {
+	logger := &LoggerActor{
+		BaseActor: newActor(address, 1000),
+		logs:      make([]string, 0),
+	}
+
+	logger.RegisterHandler("LOG", logger.handleLog)
+	logger.RegisterHandler("QUERY", logger.handleQuery)
+
+	return logger
+}
+
+type LogMessage struct {
+	Level   string
+	Content string
+}
+
+func (m LogMessage) GetType() string { return "LOG" }
+
+func (l *LoggerActor) handleLog(msg actor.Message) {
+	logMsg := msg.(LogMessage)
+	logEntry := fmt.Sprintf("[%s] %s: %s",
+		time.Now().Format("15:04:05"), logMsg.Level, logMsg.Content)
+	l.logs = append(l.logs, logEntry)
+	fmt.Printf("Logger %s: %s\n", l.GetAddress(), logEntry)
+}
+
+func (l *LoggerActor) handleQuery(msg actor.Message) {
+	queryMsg := msg.(actor.QueryMessage)
+	if queryMsg.Query == "count" {
+		queryMsg.Reply.Resolve(len(l.logs))
+	} else if queryMsg.Query == "logs" {
+		queryMsg.Reply.Resolve(append([]string(nil), l.logs...))
+	}
+}
+
+func main() {
+	numClients := flag.Int("clients", 5, "并发向计算器发送请求的客户端数量")
+	demoflag.Parse()
+
+	fmt.Println("=== Actor模型演示 ===")
+
+	rand.Seed(time.Now().UnixNano())
+
+	// 创建Actor系统
+	system := actor.NewSystem()
+
+	// 创建Actors
+	calc1 := NewCalculatorActor("calculator-1")
+	calc2 := NewCalculatorActor("calculator-2")
+	logger := NewLoggerActor("logger")
+
+	// 注册Actors
+	registerActor(system, calc1)
+	registerActor(system, calc2)
+	registerActor(system, logger)
+
+	// 启动所有Actors
+	system.StartAll()
+
+	time.Sleep(500 * time.Millisecond)
+
+	// 向日志Actor发送日志消息
+	logger.Send(LogMessage{Level: "INFO", Content: "系统启动"})
+
+	// 向计算器Actor发送计算消息
+	calc1.Send(AddMessage{Value: 10})
+	calc1.Send(AddMessage{Value: 5})
+	calc1.Send(MultiplyMessage{Value: 2})
+
+	calc2.Send(AddMessage{Value: 20})
+	calc2.Send(MultiplyMessage{Value: 3})
+
+	// 并发发送更多消息
+	var wg sync.WaitGroup
+
+	// 模拟多个客户端向计算器发送请求（默认5个）
+	for i := 1; i <= *numClients; i++ {
+		wg.Add(1)
+		go func(clientID int) {
+			defer wg.Done()
+
+			calcAddr := "calculator-1"
+			if clientID%2 == 0 {
+				calcAddr = "calculator-2"
+			}
+
+			calc := system.GetActor(calcAddr)
+			if calc != nil {
+				calc.Send(AddMessage{Value: float64(clientID)})
+				time.Sleep(100 * time.Millisecond)
+				calc.Send(MultiplyMessage{Value: 1.5})
+			}
+
+			// 记录日志
+			logger.Send(LogMessage{
+				Level:   "INFO",
+				Content: fmt.Sprintf("客户端 %d 完成计算", clientID),
+			})
+		}(i)
+	}
+
+	wg.Wait()
+	time.Sleep(1 * time.Second)
+
+	// 查询结果
+	fmt.Println("\n=== 查询Actor状态 ===")
+
+	// 同时向两个计算器提问，用future.All等待两个回复
+	var asks []*future.Future[interface{}]
+	addrs := []string{"calculator-1", "calculator-2"}
+	for _, calcAddr := range addrs {
+		if calc := system.GetActor(calcAddr); calc != nil {
+			asks = append(asks, actor.Ask(calc, "result", time.Second))
+		}
+	}
+	if results, err := future.All(asks...).Get(); err != nil {
+		fmt.Printf("查询计算器失败: %v\n", err)
+	} else {
+		for i, result := range results {
+			fmt.Printf("%s 的最终结果: %.2f\n", addrs[i], result)
+		}
+	}
+
+	// 查询日志数量
+	if count, err := actor.Ask(logger, "count", time.Second).Get(); err != nil {
+		fmt.Printf("日志查询失败: %v\n", err)
+	} else {
+		fmt.Printf("日志记录数量: %d\n", count)
+	}
+
+	// 不被支持的查询不会得到回复，Ask在超时后失败
+	if _, err := actor.Ask(logger, "unknown", 200*time.Millisecond).Get(); err != nil {
+		fmt.Printf("未知查询: %v\n", err)
+	}
+
+	// 停止所有Actors
+	fmt.Println("\n=== 停止Actor系统 ===")
+	system.StopAll()
+
+	time.Sleep(1 * time.Second)
+	fmt.Println("Actor模型演示完成！")
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/klsakura/day1/pkg/chanx"
+	"github.com/klsakura/day1/pkg/contextkeys"
+	"github.com/klsakura/day1/pkg/demoflag"
 )
 
 // 流水线处理演示
@@ -16,7 +22,7 @@ type DataItem struct {
 
 // 管道阶段接口
 type PipelineStage interface {
-	Process(input <-chan DataItem) <-chan DataItem
+	Process(ctx context.Context, input <-chan DataItem) <-chan DataItem // ctx携带请求ID，用于串联各阶段日志
 	GetName() string
 }
 
@@ -33,7 +39,8 @@ func NewDataGeneratorStage(count int) *DataGeneratorStage {
 	}
 }
 
-func (g *DataGeneratorStage) Process(input <-chan DataItem) <-chan DataItem {
+func (g *DataGeneratorStage) Process(ctx context.Context, input <-chan DataItem) <-chan DataItem {
+	prefix := contextkeys.LogPrefix(ctx)
 	output := make(chan DataItem)
 
 	go func() {
@@ -46,13 +53,17 @@ func (g *DataGeneratorStage) Process(input <-chan DataItem) <-chan DataItem {
 				Stage: g.name,
 			}
 
-			fmt.Printf("%s: 生成数据 ID=%d, Value=%d\n", g.name, item.ID, item.Value)
-			output <- item
+			fmt.Printf("%s%s: 生成数据 ID=%d, Value=%d\n", prefix, g.name, item.ID, item.Value)
+			select {
+			case output <- item:
+			case <-ctx.Done():
+				return
+			}
 
 			time.Sleep(100 * time.Millisecond) // 模拟生成时间
 		}
 
-		fmt.Printf("%s: 完成数据生成\n", g.name)
+		fmt.Printf("%s%s: 完成数据生成\n", prefix, g.name)
 	}()
 
 	return output
@@ -75,26 +86,22 @@ func NewFilterStage(name string, predicate func(DataItem) bool) *FilterStage {
 	}
 }
 
-func (f *FilterStage) Process(input <-chan DataItem) <-chan DataItem {
-	output := make(chan DataItem)
-
-	go func() {
-		defer close(output)
+func (f *FilterStage) Process(ctx context.Context, input <-chan DataItem) <-chan DataItem {
+	prefix := contextkeys.LogPrefix(ctx)
 
-		for item := range input {
-			if f.predicate(item) {
-				item.Stage = f.name
-				fmt.Printf("%s: 通过过滤 ID=%d, Value=%d\n", f.name, item.ID, item.Value)
-				output <- item
-			} else {
-				fmt.Printf("%s: 被过滤掉 ID=%d, Value=%d\n", f.name, item.ID, item.Value)
-			}
+	passed := chanx.Filter(ctx, input, func(item DataItem) bool {
+		if f.predicate(item) {
+			fmt.Printf("%s%s: 通过过滤 ID=%d, Value=%d\n", prefix, f.name, item.ID, item.Value)
+			return true
 		}
-
-		fmt.Printf("%s: 完成过滤处理\n", f.name)
-	}()
-
-	return output
+		fmt.Printf("%s%s: 被过滤掉 ID=%d, Value=%d\n", prefix, f.name, item.ID, item.Value)
+		return false
+	})
+
+	return chanx.Map(ctx, passed, func(item DataItem) DataItem {
+		item.Stage = f.name
+		return item
+	})
 }
 
 func (f *FilterStage) GetName() string {
@@ -114,28 +121,19 @@ func NewTransformStage(name string, transformer func(DataItem) DataItem) *Transf
 	}
 }
 
-func (t *TransformStage) Process(input <-chan DataItem) <-chan DataItem {
-	output := make(chan DataItem)
-
-	go func() {
-		defer close(output)
+func (t *TransformStage) Process(ctx context.Context, input <-chan DataItem) <-chan DataItem {
+	prefix := contextkeys.LogPrefix(ctx)
 
-		for item := range input {
-			transformed := t.transformer(item)
-			transformed.Stage = t.name
+	return chanx.Map(ctx, input, func(item DataItem) DataItem {
+		transformed := t.transformer(item)
+		transformed.Stage = t.name
 
-			fmt.Printf("%s: 转换数据 ID=%d, %d -> %d\n",
-				t.name, item.ID, item.Value, transformed.Value)
+		fmt.Printf("%s%s: 转换数据 ID=%d, %d -> %d\n",
+			prefix, t.name, item.ID, item.Value, transformed.Value)
 
-			output <- transformed
-
-			time.Sleep(50 * time.Millisecond) // 模拟转换时间
-		}
-
-		fmt.Printf("%s: 完成转换处理\n", t.name)
-	}()
-
-	return output
+		time.Sleep(50 * time.Millisecond) // 模拟转换时间
+		return transformed
+	})
 }
 
 func (t *TransformStage) GetName() string {
@@ -155,7 +153,8 @@ func NewAggregateStage(name string, batchSize int) *AggregateStage {
 	}
 }
 
-func (a *AggregateStage) Process(input <-chan DataItem) <-chan DataItem {
+func (a *AggregateStage) Process(ctx context.Context, input <-chan DataItem) <-chan DataItem {
+	prefix := contextkeys.LogPrefix(ctx)
 	output := make(chan DataItem)
 
 	go func() {
@@ -164,7 +163,7 @@ func (a *AggregateStage) Process(input <-chan DataItem) <-chan DataItem {
 		batch := make([]DataItem, 0, a.size)
 		batchID := 1
 
-		for item := range input {
+		for item := range chanx.OrDone(ctx, input) {
 			batch = append(batch, item)
 
 			if len(batch) >= a.size {
@@ -180,8 +179,8 @@ func (a *AggregateStage) Process(input <-chan DataItem) <-chan DataItem {
 					Stage: a.name,
 				}
 
-				fmt.Printf("%s: 聚合批次 %d, 包含 %d 项, 总和=%d\n",
-					a.name, batchID, len(batch), sum)
+				fmt.Printf("%s%s: 聚合批次 %d, 包含 %d 项, 总和=%d\n",
+					prefix, a.name, batchID, len(batch), sum)
 
 				output <- aggregated
 
@@ -203,13 +202,13 @@ func (a *AggregateStage) Process(input <-chan DataItem) <-chan DataItem {
 				Stage: a.name,
 			}
 
-			fmt.Printf("%s: 聚合最后批次 %d, 包含 %d 项, 总和=%d\n",
-				a.name, batchID, len(batch), sum)
+			fmt.Printf("%s%s: 聚合最后批次 %d, 包含 %d 项, 总和=%d\n",
+				prefix, a.name, batchID, len(batch), sum)
 
 			output <- aggregated
 		}
 
-		fmt.Printf("%s: 完成聚合处理\n", a.name)
+		fmt.Printf("%s%s: 完成聚合处理\n", prefix, a.name)
 	}()
 
 	return output
@@ -234,7 +233,8 @@ func NewParallelStage(name string, workers int, workerFunc func(DataItem) DataIt
 	}
 }
 
-func (p *ParallelStage) Process(input <-chan DataItem) <-chan DataItem {
+func (p *ParallelStage) Process(ctx context.Context, input <-chan DataItem) <-chan DataItem {
+	prefix := contextkeys.LogPrefix(ctx)
 	output := make(chan DataItem)
 
 	var wg sync.WaitGroup
@@ -245,14 +245,18 @@ func (p *ParallelStage) Process(input <-chan DataItem) <-chan DataItem {
 		go func(workerID int) {
 			defer wg.Done()
 
-			for item := range input {
+			for item := range chanx.OrDone(ctx, input) {
 				processed := p.workerFunc(item)
 				processed.Stage = fmt.Sprintf("%s-Worker%d", p.name, workerID)
 
-				fmt.Printf("%s 工作者%d: 处理 ID=%d, %d -> %d\n",
-					p.name, workerID, item.ID, item.Value, processed.Value)
+				fmt.Printf("%s%s 工作者%d: 处理 ID=%d, %d -> %d\n",
+					prefix, p.name, workerID, item.ID, item.Value, processed.Value)
 
-				output <- processed
+				select {
+				case output <- processed:
+				case <-ctx.Done():
+					return
+				}
 
 				time.Sleep(time.Duration(rand.Intn(200)+100) * time.Millisecond)
 			}
@@ -263,7 +267,7 @@ func (p *ParallelStage) Process(input <-chan DataItem) <-chan DataItem {
 	go func() {
 		wg.Wait()
 		close(output)
-		fmt.Printf("%s: 所有工作者完成\n", p.name)
+		fmt.Printf("%s%s: 所有工作者完成\n", prefix, p.name)
 	}()
 
 	return output
@@ -291,29 +295,36 @@ func (p *Pipeline) AddStage(stage PipelineStage) *Pipeline {
 	return p
 }
 
-func (p *Pipeline) Execute() <-chan DataItem {
+// Execute 连接并启动所有阶段，ctx中的请求ID会传给每个阶段
+func (p *Pipeline) Execute(ctx context.Context) <-chan DataItem {
 	if len(p.stages) == 0 {
 		output := make(chan DataItem)
 		close(output)
 		return output
 	}
 
-	fmt.Printf("=== 启动管道: %s ===\n", p.name)
+	prefix := contextkeys.LogPrefix(ctx)
+	fmt.Printf("%s=== 启动管道: %s ===\n", prefix, p.name)
 
 	// 从第一个阶段开始
-	var current <-chan DataItem = p.stages[0].Process(nil)
+	var current <-chan DataItem = p.stages[0].Process(ctx, nil)
 
 	// 连接所有阶段
 	for i := 1; i < len(p.stages); i++ {
 		stage := p.stages[i]
-		fmt.Printf("连接阶段: %s\n", stage.GetName())
-		current = stage.Process(current)
+		fmt.Printf("%s连接阶段: %s\n", prefix, stage.GetName())
+		current = stage.Process(ctx, current)
 	}
 
 	return current
 }
 
 func main() {
+	numItems := flag.Int("items", 10, "生成的数据项数量")
+	parallel := flag.Int("parallel", 3, "并行阶段的工作者数量")
+	batch := flag.Int("batch", 3, "聚合阶段每批聚合的数据项数量")
+	demoflag.Parse()
+
 	fmt.Println("=== 流水线处理演示 ===")
 
 	rand.Seed(time.Now().UnixNano())
@@ -323,7 +334,7 @@ func main() {
 
 	// 添加各个阶段
 	pipeline.
-		AddStage(NewDataGeneratorStage(10)).                      // 生成10个数据项
+		AddStage(NewDataGeneratorStage(*numItems)).               // 生成数据项
 		AddStage(NewFilterStage("过滤器", func(item DataItem) bool { // 过滤偶数
 			return item.Value%2 == 0
 		})).
@@ -331,32 +342,33 @@ func main() {
 			item.Value = item.Value * item.Value
 			return item
 		})).
-		AddStage(NewParallelStage("并行处理器", 3, func(item DataItem) DataItem { // 并行加10
+		AddStage(NewParallelStage("并行处理器", *parallel, func(item DataItem) DataItem { // 并行加10
 			item.Value = item.Value + 10
 			return item
 		})).
-		AddStage(NewAggregateStage("聚合器", 3)) // 每3个聚合一次
+		AddStage(NewAggregateStage("聚合器", *batch)) // 每batch个聚合一次
+
+	// 执行管道，本次运行的所有日志都带上同一个请求ID
+	ctx := contextkeys.WithRequestID(context.Background(), contextkeys.NewRequestID())
+	result := pipeline.Execute(ctx)
 
-	// 执行管道
-	result := pipeline.Execute()
+	// 用Tee把结果同时交给展示和统计两个消费者
+	display, stats := chanx.Tee(ctx, result)
+	totalSum := make(chan int)
+	go func() {
+		totalSum <- chanx.Reduce(ctx, stats, 0, func(acc int, item DataItem) int { return acc + item.Value })
+	}()
 
 	// 收集最终结果
 	fmt.Println("\n=== 最终结果 ===")
 	var finalResults []DataItem
 
-	for item := range result {
+	for item := range display {
 		finalResults = append(finalResults, item)
 		fmt.Printf("最终输出: 批次ID=%d, 总和=%d, 来源=%s\n",
 			item.ID, item.Value, item.Stage)
 	}
 
 	fmt.Printf("\n管道处理完成！共产生 %d 个最终结果\n", len(finalResults))
-
-	// 计算总体统计
-	totalSum := 0
-	for _, item := range finalResults {
-		totalSum += item.Value
-	}
-
-	fmt.Printf("所有批次总和: %d\n", totalSum)
+	fmt.Printf("所有批次总和: %d\n", <-totalSum)
 }
@@ -0,0 +1,362 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/11_distributed_rate_limiter/main.go
+主题：分布式限流
+
+本示例演示：
+1. 多个进程通过共享的计数服务实现全局限流
+2. 每次请求都询问计数服务（精确，但每次判断都要付出一次网络往返）
+3. 客户端批量租用令牌后本地扣减（调用少、延迟低，但会有令牌闲置）
+4. 一致性与延迟之间的取舍
+
+核心概念：
+- 固定窗口计数：计数服务在每个窗口内最多发放limit个令牌
+- 远程模式：每个请求调用一次 /take?n=1
+- 租约模式：一次租用batch个令牌，在窗口结束前本地使用，过期作废
+
+运行方式：go run ./cmd/medium/11_distributed_rate_limiter
+（程序会在本进程内启动计数服务，再以子进程方式启动多个限流客户端）
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// ===== 计数服务 =====
+
+// CounterServer 固定窗口的全局令牌计数服务
+type CounterServer struct {
+	limit   int
+	window  time.Duration
+	latency time.Duration // 模拟的网络/处理延迟
+
+	mu          sync.Mutex
+	windowStart time.Time
+	used        int
+	history     []int // 已结束窗口中发放的令牌数
+	calls       int64
+}
+
+func NewCounterServer(limit int, window, latency time.Duration) *CounterServer {
+	return &CounterServer{
+		limit:       limit,
+		window:      window,
+		latency:     latency,
+		windowStart: time.Now(),
+	}
+}
+
+// take 在当前窗口内申请n个令牌，返回实际发放的数量和距窗口结束的时间
+func (cs *CounterServer) take(n int) (int, time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	now := time.Now()
+	for now.Sub(cs.windowStart) >= cs.window {
+		cs.history = append(cs.history, cs.used)
+		cs.windowStart = cs.windowStart.Add(cs.window)
+		cs.used = 0
+	}
+
+	granted := cs.limit - cs.used
+	if granted > n {
+		granted = n
+	}
+	cs.used += granted
+
+	return granted, cs.windowStart.Add(cs.window).Sub(now)
+}
+
+type takeResponse struct {
+	Granted int   `json:"granted"`
+	ResetMs int64 `json:"reset_ms"`
+}
+
+func (cs *CounterServer) handleTake(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&cs.calls, 1)
+	time.Sleep(cs.latency)
+
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 {
+		http.Error(w, "invalid n", http.StatusBadRequest)
+		return
+	}
+
+	granted, reset := cs.take(n)
+	json.NewEncoder(w).Encode(takeResponse{Granted: granted, ResetMs: reset.Milliseconds()})
+}
+
+// Stats 返回服务被调用的次数和各窗口的发放记录（最后一个为当前窗口）
+func (cs *CounterServer) Stats() (int64, []int) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return atomic.LoadInt64(&cs.calls), append(append([]int(nil), cs.history...), cs.used)
+}
+
+// Serve 在随机端口上启动HTTP服务，返回服务地址和关闭函数
+func (cs *CounterServer) Serve() (string, func(), error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/take", cs.handleTake)
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+
+	return "http://" + ln.Addr().String(), func() { srv.Close() }, nil
+}
+
+// ===== 限流客户端 =====
+
+// DistributedLimiter 客户端限流接口
+type DistributedLimiter interface {
+	Allow() bool
+	Calls() int64 // 调用计数服务的次数
+}
+
+// counterClient 计数服务的HTTP客户端
+type counterClient struct {
+	url   string
+	http  *http.Client
+	calls int64
+}
+
+func (c *counterClient) take(n int) (takeResponse, error) {
+	atomic.AddInt64(&c.calls, 1)
+
+	var tr takeResponse
+	resp, err := c.http.Get(fmt.Sprintf("%s/take?n=%d", c.url, n))
+	if err != nil {
+		return tr, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&tr)
+	return tr, err
+}
+
+func (c *counterClient) Calls() int64 {
+	return atomic.LoadInt64(&c.calls)
+}
+
+// RemoteLimiter 每次判断都询问计数服务：全局精确，但每个请求都要等一次往返
+type RemoteLimiter struct {
+	*counterClient
+}
+
+func (rl *RemoteLimiter) Allow() bool {
+	tr, err := rl.take(1)
+	// 计数服务不可用时拒绝请求（fail closed）
+	return err == nil && tr.Granted == 1
+}
+
+// LeasedLimiter 一次向计数服务租用batch个令牌，窗口结束前在本地扣减
+// 调用次数少、判断延迟低；代价是租到但没用完的令牌会随窗口过期而浪费
+type LeasedLimiter struct {
+	*counterClient
+	batch int
+
+	mu        sync.Mutex
+	tokens    int
+	expires   time.Time
+	exhausted bool // 服务端本窗口的令牌已发完，窗口结束前不必再请求
+}
+
+func (ll *LeasedLimiter) Allow() bool {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(ll.expires) {
+		if ll.tokens > 0 {
+			ll.tokens--
+			return true
+		}
+		if ll.exhausted {
+			return false
+		}
+	}
+
+	tr, err := ll.take(ll.batch)
+	if err != nil {
+		return false
+	}
+	ll.tokens = tr.Granted
+	ll.exhausted = tr.Granted < ll.batch
+	ll.expires = now.Add(time.Duration(tr.ResetMs) * time.Millisecond)
+
+	if ll.tokens > 0 {
+		ll.tokens--
+		return true
+	}
+	return false
+}
+
+// clientResult 子进程汇报给父进程的统计
+type clientResult struct {
+	ID         int     `json:"id"`
+	Allowed    int     `json:"allowed"`
+	Denied     int     `json:"denied"`
+	Calls      int64   `json:"calls"`
+	AvgDecisUs float64 `json:"avg_decision_us"`
+}
+
+// runClient 子进程入口：按固定间隔发起请求，结束时输出一行RESULT
+func runClient(id int, mode, url string, duration, interval time.Duration, batch int) {
+	base := &counterClient{url: url, http: &http.Client{Timeout: time.Second}}
+
+	var limiter DistributedLimiter
+	if mode == "lease" {
+		limiter = &LeasedLimiter{counterClient: base, batch: batch}
+	} else {
+		limiter = &RemoteLimiter{counterClient: base}
+	}
+
+	result := clientResult{ID: id}
+	var decision time.Duration
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		start := time.Now()
+		ok := limiter.Allow()
+		decision += time.Since(start)
+
+		if ok {
+			result.Allowed++
+		} else {
+			result.Denied++
+		}
+		time.Sleep(interval)
+	}
+
+	result.Calls = limiter.Calls()
+	if total := result.Allowed + result.Denied; total > 0 {
+		result.AvgDecisUs = float64(decision.Microseconds()) / float64(total)
+	}
+
+	data, _ := json.Marshal(result)
+	fmt.Printf("RESULT %s\n", data)
+}
+
+// ===== 协调进程 =====
+
+// spawnClient 以子进程方式运行限流客户端，解析其RESULT输出
+func spawnClient(id int, mode, url string, duration, interval time.Duration, batch int) (clientResult, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return clientResult{}, err
+	}
+
+	cmd := exec.Command(exe,
+		"-role=client",
+		"-id="+strconv.Itoa(id),
+		"-mode="+mode,
+		"-server="+url,
+		"-duration="+duration.String(),
+		"-interval="+interval.String(),
+		"-batch="+strconv.Itoa(batch),
+	)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return clientResult{}, err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "RESULT ") {
+			var r clientResult
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "RESULT ")), &r)
+			return r, err
+		}
+	}
+	return clientResult{}, fmt.Errorf("client %d: no result", id)
+}
+
+func runScenario(mode string, limit int, duration time.Duration, batch int) {
+	server := NewCounterServer(limit, time.Second, 2*time.Millisecond)
+	url, shutdown, err := server.Serve()
+	if err != nil {
+		fmt.Println("启动计数服务失败:", err)
+		return
+	}
+	defer shutdown()
+
+	// 两个高频客户端和一个低频客户端
+	intervals := []time.Duration{5 * time.Millisecond, 5 * time.Millisecond, 200 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	results := make([]clientResult, len(intervals))
+	for i, interval := range intervals {
+		wg.Add(1)
+		go func(i int, interval time.Duration) {
+			defer wg.Done()
+			r, err := spawnClient(i+1, mode, url, duration, interval, batch)
+			if err != nil {
+				fmt.Printf("客户端 %d 运行失败: %v\n", i+1, err)
+			}
+			results[i] = r
+		}(i, interval)
+	}
+	wg.Wait()
+
+	totalAllowed := 0
+	for i, r := range results {
+		totalAllowed += r.Allowed
+		fmt.Printf("  客户端 %d (间隔 %-5v): 通过=%-3d 拒绝=%-4d 服务调用=%-4d 平均判断耗时=%.0fµs\n",
+			r.ID, intervals[i], r.Allowed, r.Denied, r.Calls, r.AvgDecisUs)
+	}
+
+	calls, history := server.Stats()
+	fmt.Printf("  合计通过 %d 个（上限 %d/秒 × %v），计数服务共被调用 %d 次，各窗口发放: %v\n",
+		totalAllowed, limit, duration, calls, history)
+}
+
+func main() {
+	role := flag.String("role", "coordinator", "coordinator 或 client")
+	id := flag.Int("id", 0, "客户端编号")
+	mode := flag.String("mode", "remote", "remote 或 lease")
+	server := flag.String("server", "", "计数服务地址")
+	duration := flag.Duration("duration", 3*time.Second, "客户端运行时长")
+	interval := flag.Duration("interval", 5*time.Millisecond, "客户端请求间隔")
+	batch := flag.Int("batch", 10, "租约模式每次租用的令牌数")
+	demoflag.Parse()
+
+	if *role == "client" {
+		runClient(*id, *mode, *server, *duration, *interval, *batch)
+		return
+	}
+
+	fmt.Println("=== 分布式限流演示 ===")
+	fmt.Println("3个客户端进程共享全局限额: 每秒30个请求")
+
+	fmt.Println("\n--- 远程模式: 每个请求询问计数服务 ---")
+	runScenario("remote", 30, *duration, *batch)
+
+	fmt.Printf("\n--- 租约模式: 每次租用 %d 个令牌本地使用 ---\n", *batch)
+	runScenario("lease", 30, *duration, *batch)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 远程模式每个窗口恰好发放上限个令牌，但每次判断都要等待一次网络往返")
+	fmt.Println("2. 租约模式调用次数少得多，判断几乎是本地操作")
+	fmt.Println("3. 低频客户端租到的令牌用不完，随窗口过期浪费，高频客户端因此拿到的更少")
+	fmt.Println("4. 计数服务是单点：不可用时客户端只能选择全部拒绝或全部放行")
+}
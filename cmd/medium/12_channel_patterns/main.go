@@ -0,0 +1,218 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/12_channel_patterns/main.go
+主题：or-done、tee、bridge通道模式
+
+本示例演示：
+1. or-done：读取方可以随时放弃，不必把channel读完
+2. tee：把一个channel复制成两个，分别交给两个消费者
+3. bridge：把"channel的channel"展开成一个连续的流
+4. 消费者中途放弃时，不带取消的写法会让上游goroutine永远阻塞（泄漏）
+
+核心概念：
+- 三个模式都实现在 pkg/chanx 中（OrDone、Tee、Bridge），每个发送都同时监听ctx
+- 消费者放弃时调用cancel，整条链上的goroutine都会退出
+- 用runtime.NumGoroutine对比放弃前后的goroutine数量来发现泄漏
+
+运行方式：go run ./cmd/medium/12_channel_patterns [-take=3] [-pages=3] [-page-size=3]
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/klsakura/day1/pkg/chanx"
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// naiveCounter 不监听取消的无限生成器：没人读取时会永远阻塞在发送上
+func naiveCounter() <-chan int {
+	out := make(chan int)
+	go func() {
+		for i := 1; ; i++ {
+			out <- i
+		}
+	}()
+	return out
+}
+
+// counter 可取消的无限生成器
+func counter(ctx context.Context) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for i := 1; ; i++ {
+			select {
+			case out <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// naiveTee 不监听取消的tee：任一输出不再被读取，tee就会永远阻塞
+func naiveTee(in <-chan int) (<-chan int, <-chan int) {
+	out1, out2 := make(chan int), make(chan int)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range in {
+			out1 <- v
+			out2 <- v
+		}
+	}()
+	return out1, out2
+}
+
+// goroutines 等待已取消的goroutine退出后，返回当前goroutine数量
+func goroutines() int {
+	time.Sleep(50 * time.Millisecond)
+	return runtime.NumGoroutine()
+}
+
+// leaked 返回比base多出的goroutine数量
+func leaked(base int) int {
+	return goroutines() - base
+}
+
+// demoOrDone 消费者读了take个值后放弃
+func demoOrDone(take int) {
+	fmt.Println("=== or-done ===")
+
+	base := goroutines()
+	for v := range naiveCounter() {
+		if v == take {
+			break // 放弃读取，生成器阻塞在下一次发送上
+		}
+	}
+	fmt.Printf("不可取消: 读了%d个后放弃，泄漏 %d 个goroutine\n", take, leaked(base))
+
+	base = goroutines()
+	ctx, cancel := context.WithCancel(context.Background())
+	for v := range chanx.OrDone(ctx, counter(ctx)) {
+		if v == take {
+			break
+		}
+	}
+	cancel()
+	fmt.Printf("OrDone+取消: 读了%d个后放弃，泄漏 %d 个goroutine\n", take, leaked(base))
+}
+
+// demoTee 两个消费者各读take+2个值，然后消费者B放弃
+func demoTee(take int) {
+	fmt.Println("\n=== tee ===")
+
+	// 两个消费者都读取时，每个值都会送到两边
+	ctx, cancel := context.WithCancel(context.Background())
+	left, right := chanx.Tee(ctx, chanx.Take(ctx, counter(ctx), take+2))
+	done := make(chan []int)
+	go func() {
+		var got []int
+		for v := range right {
+			got = append(got, v)
+		}
+		done <- got
+	}()
+	var got []int
+	for v := range left {
+		got = append(got, v)
+	}
+	fmt.Printf("消费者A: %v, 消费者B: %v\n", got, <-done)
+	cancel()
+
+	// 消费者B读了一个就放弃
+	base := goroutines()
+	a, b := naiveTee(naiveCounter())
+	<-a
+	<-b
+	<-a // tee在等待b读取第2个值之前不会继续，A也只能读到这里
+	fmt.Printf("不可取消: 消费者B放弃后，泄漏 %d 个goroutine\n", leaked(base))
+
+	base = goroutines()
+	ctx, cancel = context.WithCancel(context.Background())
+	a, b = chanx.Tee(ctx, counter(ctx))
+	<-a
+	<-b
+	<-a
+	cancel()
+	fmt.Printf("Tee+取消: 消费者B放弃后，泄漏 %d 个goroutine\n", leaked(base))
+}
+
+// pages 模拟分页接口：每一页是一个channel，按页依次产出
+func pages(ctx context.Context, numPages, pageSize int) <-chan (<-chan int) {
+	out := make(chan (<-chan int))
+	go func() {
+		defer close(out)
+		for p := 0; p < numPages; p++ {
+			page := make(chan int, pageSize)
+			for i := 1; i <= pageSize; i++ {
+				page <- p*100 + i
+			}
+			close(page)
+
+			select {
+			case out <- page:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// demoBridge 展开numPages页 × 每页pageSize条，再只取前take+1条
+func demoBridge(take, numPages, pageSize int) {
+	fmt.Println("\n=== bridge ===")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fmt.Printf("%d页 × 每页%d条，展开后: ", numPages, pageSize)
+	for v := range chanx.Bridge(ctx, pages(ctx, numPages, pageSize)) {
+		fmt.Printf("%d ", v)
+	}
+	fmt.Println()
+	cancel()
+
+	// 消费者只需要前几条：取消后分页生成器和bridge都会退出
+	base := goroutines()
+	ctx, cancel = context.WithCancel(context.Background())
+	var first []int
+	for v := range chanx.Bridge(ctx, pages(ctx, 1000, pageSize)) {
+		first = append(first, v)
+		if len(first) == take+1 {
+			break
+		}
+	}
+	cancel()
+	fmt.Printf("只取前%d条 %v 后放弃，泄漏 %d 个goroutine\n", take+1, first, leaked(base))
+}
+
+func main() {
+	take := flag.Int("take", 3, "消费者读了多少个值后放弃")
+	numPages := flag.Int("pages", 3, "bridge演示的页数")
+	pageSize := flag.Int("page-size", 3, "bridge演示中每页的条数")
+	demoflag.Parse()
+	if *take < 1 || *numPages < 1 || *pageSize < 1 {
+		fmt.Fprintln(os.Stderr, "take、pages和page-size至少为1")
+		os.Exit(2)
+	}
+
+	fmt.Println("=== 通道模式: or-done、tee、bridge ===")
+
+	demoOrDone(*take)
+	demoTee(*take)
+	demoBridge(*take, *numPages, *pageSize)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 不可取消的写法里，消费者放弃后上游goroutine永远阻塞在发送上")
+	fmt.Println("2. 可取消的写法里，每个发送都同时监听ctx，cancel后整条链都会退出")
+	fmt.Println("3. tee的两个输出必须同时被读取，否则较快的一方也会被拖住")
+	fmt.Println("4. bridge让消费者不必关心数据分成了多少个channel")
+}
@@ -0,0 +1,135 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/13_goroutine_leak/main.go
+主题：goroutine泄漏检测
+
+本示例演示：
+1. 典型泄漏：调用方超时返回后，后台goroutine永远阻塞在无人接收的发送上
+2. 用 pkg/leakcheck 在demo开始和结束时对比goroutine快照，找出泄漏的goroutine及其创建位置
+3. 两种修复方式：带缓冲的结果channel，以及通过context让后台任务提前退出
+
+核心概念：
+- 泄漏的goroutine不会报错，只会让内存和goroutine数量持续增长
+- runtime.Stack(buf, true) 可以拿到所有goroutine的状态和栈
+- leakcheck.Check 会等待一小段时间再判定，避免把正在退出的goroutine误报为泄漏
+- 测试中可以在开头 base := leakcheck.Take()，结尾 defer leakcheck.Verify(t, base)
+
+运行方式：go run ./cmd/medium/13_goroutine_leak [-timeout=50ms] [-queries=3]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/leakcheck"
+)
+
+var errTimeout = errors.New("查询超时")
+
+// slowQuery 模拟一个耗时的查询
+func slowQuery(id int) int {
+	time.Sleep(200 * time.Millisecond)
+	return id * 10
+}
+
+// leakyFetch 错误示范：超时后没人再从result接收，后台goroutine阻塞在发送上永远无法退出
+func leakyFetch(id int, timeout time.Duration) (int, error) {
+	result := make(chan int)
+	go func() {
+		result <- slowQuery(id)
+	}()
+
+	select {
+	case v := <-result:
+		return v, nil
+	case <-time.After(timeout):
+		return 0, errTimeout
+	}
+}
+
+// bufferedFetch 修复1：结果channel容量为1，发送不会阻塞，后台goroutine完成后自然退出
+func bufferedFetch(id int, timeout time.Duration) (int, error) {
+	result := make(chan int, 1)
+	go func() {
+		result <- slowQuery(id)
+	}()
+
+	select {
+	case v := <-result:
+		return v, nil
+	case <-time.After(timeout):
+		return 0, errTimeout
+	}
+}
+
+// ctxFetch 修复2：后台任务监听ctx，调用方放弃后它也立即放弃，不再白白占用资源
+func ctxFetch(ctx context.Context, id int) (int, error) {
+	result := make(chan int)
+	go func() {
+		select {
+		case <-time.After(200 * time.Millisecond): // 模拟可中断的查询
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case result <- id * 10:
+		case <-ctx.Done():
+		}
+	}()
+
+	select {
+	case v := <-result:
+		return v, nil
+	case <-ctx.Done():
+		return 0, errTimeout
+	}
+}
+
+// runScenario 发起3次超时的查询，然后用leakcheck检查是否有goroutine残留
+func runScenario(name string, queries int, fetch func(id int) (int, error)) {
+	fmt.Printf("\n--- %s ---\n", name)
+	base := leakcheck.Take()
+
+	for id := 1; id <= queries; id++ {
+		_, err := fetch(id)
+		fmt.Printf("查询 %d: %v\n", id, err)
+	}
+
+	// 给后台goroutine足够的时间完成查询（查询需要200ms）
+	fmt.Println(leakcheck.Report(leakcheck.Check(base, 500*time.Millisecond), false))
+}
+
+func main() {
+	// 查询需要200ms，超时时间不短于200ms时第一个场景也不会泄漏
+	timeout := flag.Duration("timeout", 50*time.Millisecond, "每次查询的超时时间")
+	queries := flag.Int("queries", 3, "每个场景执行的查询次数")
+	demoflag.Parse()
+
+	fmt.Println("=== Goroutine泄漏检测演示 ===")
+
+	runScenario("无缓冲结果channel（泄漏）", *queries, func(id int) (int, error) {
+		return leakyFetch(id, *timeout)
+	})
+
+	runScenario("修复1: 容量为1的结果channel", *queries, func(id int) (int, error) {
+		return bufferedFetch(id, *timeout)
+	})
+
+	runScenario("修复2: context取消后台任务", *queries, func(id int) (int, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		return ctxFetch(ctx, id)
+	})
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 泄漏的goroutine状态为 chan send，创建位置指向leakyFetch")
+	fmt.Println("2. 修复1的后台查询仍会执行完，只是结果被丢弃；修复2可以让查询本身提前停止")
+	fmt.Println("3. 泄漏检测只能发现已经发生的泄漏，设计时就要考虑每个goroutine如何退出")
+}
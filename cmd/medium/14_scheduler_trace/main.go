@@ -0,0 +1,197 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/14_scheduler_trace/main.go
+主题：观察Go调度器
+
+本示例演示：
+1. 在不同GOMAXPROCS下同时运行CPU密集型和IO密集型goroutine
+2. 测量调度延迟：goroutine从创建到开始运行、从定时唤醒到真正运行各等了多久
+3. 用runtime/trace记录整个过程，之后可以用 go tool trace 查看每个P上的调度情况
+
+核心概念：
+- GOMAXPROCS决定同时执行Go代码的P（逻辑处理器）数量，默认等于CPU核数
+- CPU密集型goroutine会占满P，IO密集型goroutine醒来后要排队等待空闲的P
+- Go 1.14起支持异步抢占，死循环也会在约10ms后被抢占，所以延迟有上限
+- 可运行的goroutine越多、P越少，调度延迟越高
+
+运行方式：go run ./cmd/medium/14_scheduler_trace [-trace=文件路径] [-cpu=4] [-io=20]
+查看trace：go tool trace <trace文件>
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/trace"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// latencyStats 一组延迟样本
+type latencyStats []time.Duration
+
+func (l latencyStats) percentile(p float64) time.Duration {
+	if len(l) == 0 {
+		return 0
+	}
+	sorted := append(latencyStats(nil), l...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+func (l latencyStats) String() string {
+	return fmt.Sprintf("p50=%-9v p99=%-9v max=%v",
+		l.percentile(0.5).Round(time.Microsecond),
+		l.percentile(0.99).Round(time.Microsecond),
+		l.percentile(1).Round(time.Microsecond))
+}
+
+// cpuBound 纯计算的goroutine，直到stop被关闭
+func cpuBound(stop <-chan struct{}, work *int64) {
+	x := 0
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		// 一小段纯计算，不做任何可能让出P的操作
+		for i := 0; i < 100000; i++ {
+			x += i % 7
+		}
+		atomic.AddInt64(work, 1)
+	}
+}
+
+// ioBound 模拟IO：每次睡眠1ms，记录实际醒来比预期晚了多少
+func ioBound(rounds int) latencyStats {
+	var lat latencyStats
+	for i := 0; i < rounds; i++ {
+		start := time.Now()
+		time.Sleep(time.Millisecond)
+		lat = append(lat, time.Since(start)-time.Millisecond)
+	}
+	return lat
+}
+
+type scenarioResult struct {
+	procs      int
+	spawn      latencyStats // 创建到开始运行
+	wakeup     latencyStats // 睡眠结束到开始运行
+	cpuWork    int64
+	elapsed    time.Duration
+	goroutines int
+}
+
+func runScenario(procs, cpuGoroutines, ioGoroutines int) scenarioResult {
+	prev := runtime.GOMAXPROCS(procs)
+	defer runtime.GOMAXPROCS(prev)
+
+	// 用trace.Region标记每个场景，在trace界面中可以按区域查看
+	region := trace.StartRegion(context.Background(), fmt.Sprintf("GOMAXPROCS=%d", procs))
+	defer region.End()
+
+	result := scenarioResult{procs: procs}
+	start := time.Now()
+
+	stop := make(chan struct{})
+	var cpuWg sync.WaitGroup
+	for i := 0; i < cpuGoroutines; i++ {
+		cpuWg.Add(1)
+		go func() {
+			defer cpuWg.Done()
+			cpuBound(stop, &result.cpuWork)
+		}()
+	}
+
+	var mu sync.Mutex
+	var ioWg sync.WaitGroup
+	for i := 0; i < ioGoroutines; i++ {
+		ioWg.Add(1)
+		created := time.Now()
+		go func() {
+			defer ioWg.Done()
+			spawn := time.Since(created)
+			wakeup := ioBound(20)
+
+			mu.Lock()
+			result.spawn = append(result.spawn, spawn)
+			result.wakeup = append(result.wakeup, wakeup...)
+			mu.Unlock()
+		}()
+	}
+
+	result.goroutines = runtime.NumGoroutine()
+	ioWg.Wait()
+	close(stop)
+	cpuWg.Wait()
+	result.elapsed = time.Since(start)
+	return result
+}
+
+func main() {
+	traceFile := flag.String("trace", filepath.Join(os.TempDir(), "scheduler.trace"), "trace输出文件")
+	cpuGoroutines := flag.Int("cpu", 4, "CPU密集型goroutine数量")
+	ioGoroutines := flag.Int("io", 20, "IO密集型goroutine数量")
+	demoflag.Parse()
+
+	fmt.Println("=== Go调度器观察 ===")
+	fmt.Printf("CPU核数: %d, 默认GOMAXPROCS: %d\n", runtime.NumCPU(), runtime.GOMAXPROCS(0))
+	fmt.Printf("每个场景: %d个CPU密集型 + %d个IO密集型goroutine（每个睡眠1ms × 20次）\n\n",
+		*cpuGoroutines, *ioGoroutines)
+
+	f, err := os.Create(*traceFile)
+	if err != nil {
+		fmt.Println("创建trace文件失败:", err)
+		return
+	}
+	defer f.Close()
+
+	if err := trace.Start(f); err != nil {
+		fmt.Println("启动trace失败:", err)
+		return
+	}
+
+	procsList := []int{1, 2, runtime.NumCPU()}
+	if runtime.NumCPU() <= 2 {
+		procsList = []int{1, 2, 4}
+	}
+
+	var results []scenarioResult
+	// 基线：没有CPU密集型goroutine时的唤醒延迟
+	results = append(results, runScenario(1, 0, *ioGoroutines))
+	for _, procs := range procsList {
+		results = append(results, runScenario(procs, *cpuGoroutines, *ioGoroutines))
+	}
+	trace.Stop()
+
+	for i, r := range results {
+		label := fmt.Sprintf("GOMAXPROCS=%d", r.procs)
+		if i == 0 {
+			label += " (无CPU负载)"
+		}
+		fmt.Printf("%s:\n", label)
+		fmt.Printf("  启动延迟  %v\n", r.spawn)
+		fmt.Printf("  唤醒延迟  %v\n", r.wakeup)
+		fmt.Printf("  CPU工作量 %d 单位, 耗时 %v, goroutine数 %d\n\n",
+			r.cpuWork, r.elapsed.Round(time.Millisecond), r.goroutines)
+	}
+
+	fmt.Printf("trace已写入: %s\n", *traceFile)
+	fmt.Printf("查看: go tool trace %s\n", *traceFile)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 没有CPU负载时，唤醒延迟主要来自定时器精度")
+	fmt.Println("2. CPU密集型goroutine占满P时，IO goroutine醒来后要等待抢占，可运行的goroutine越多延迟越高，可达数十毫秒")
+	fmt.Println("3. GOMAXPROCS超过实际CPU核数不会带来更多并行，只是让操作系统在线程间切换")
+	fmt.Println("4. 在trace的Goroutine analysis中可以看到每个goroutine的Scheduler wait时间")
+}
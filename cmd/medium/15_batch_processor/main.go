@@ -0,0 +1,266 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/15_batch_processor/main.go
+主题：批量合并处理器
+
+本示例演示：
+1. pkg/batch 的 Processor[T]：逐个提交的元素被合并成批次，一次批量写入数据库
+2. 逐条写入 vs 批量写入：相同的200条记录，比较数据库调用次数和总耗时
+3. 两种刷新条件：流量大时攒满MaxSize立即刷新，流量小时等待MaxDelay后刷新
+4. 每个元素一个Future：批量写入失败时，同一批次的所有调用方都会收到错误
+5. 溢出背压：数据库变慢、队列填满后，Block策略让调用方等待，Reject策略立即拒绝
+
+核心概念：
+- 批量操作摊薄每次调用的固定开销（网络往返、事务提交），吞吐量大幅提升
+- MaxDelay是延迟上限：流量再小，一个元素也不会无限期等待凑批
+- 有界队列 + 溢出策略：下游变慢时把压力传回上游，而不是无限堆积在内存里
+
+运行方式：go run ./cmd/medium/15_batch_processor [-writers=20] [-per-writer=10] [-max-size=20] [-max-delay=5ms]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/batch"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/future"
+)
+
+// Row 要写入的一条记录
+type Row struct {
+	ID   int
+	Name string
+}
+
+// FakeDB 模拟只有一个连接的数据库：调用依次执行，每次调用有固定开销，每行再加一点时间
+type FakeDB struct {
+	callCost time.Duration
+	rowCost  time.Duration
+
+	conn   sync.Mutex // 同一时间只有一个调用在执行
+	mu     sync.Mutex
+	calls  int
+	rows   int
+	failOn func(rows []Row) bool // 返回true时本次批量写入失败
+}
+
+var errConstraint = errors.New("唯一约束冲突")
+
+func (db *FakeDB) InsertBatch(ctx context.Context, rows []Row) error {
+	db.conn.Lock()
+	defer db.conn.Unlock()
+	select {
+	case <-time.After(db.callCost + time.Duration(len(rows))*db.rowCost):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.calls++
+	if db.failOn != nil && db.failOn(rows) {
+		return errConstraint
+	}
+	db.rows += len(rows)
+	return nil
+}
+
+func (db *FakeDB) counts() (calls, rows int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.calls, db.rows
+}
+
+// demoRowByRow writers个goroutine各写perWriter条，每条一次数据库调用
+func demoRowByRow(writers, perWriter int) {
+	db := &FakeDB{callCost: 2 * time.Millisecond, rowCost: 20 * time.Microsecond}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				db.InsertBatch(context.Background(), []Row{{ID: g*perWriter + i}})
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	calls, rows := db.counts()
+	fmt.Printf("  逐条写入: %d 次调用, %d 行, 耗时 %v\n", calls, rows, time.Since(start).Round(time.Millisecond))
+}
+
+// demoBatched 同样的写入经过批处理器，每批最多maxSize条，最多等待maxDelay
+func demoBatched(writers, perWriter, maxSize int, maxDelay time.Duration) {
+	db := &FakeDB{callCost: 2 * time.Millisecond, rowCost: 20 * time.Microsecond}
+	p := batch.New(batch.Config{MaxSize: maxSize, MaxDelay: maxDelay, QueueSize: 100}, db.InsertBatch)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				// 与逐条写入一样，每条都等待写入完成后再写下一条
+				f, err := p.Submit(context.Background(), Row{ID: g*perWriter + i})
+				if err == nil {
+					f.Get()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	p.Close(context.Background())
+
+	calls, rows := db.counts()
+	s := p.Stats()
+	fmt.Printf("  批量写入: %d 次调用, %d 行, 耗时 %v (攒满刷新 %d 次, 超时刷新 %d 次)\n",
+		calls, rows, elapsed.Round(time.Millisecond), s.BySize, s.ByDelay)
+}
+
+// demoFlushReasons 突发流量攒满即刷新，稀疏流量按MaxDelay刷新
+func demoFlushReasons() {
+	db := &FakeDB{callCost: time.Millisecond}
+	p := batch.New(batch.Config{MaxSize: 10, MaxDelay: 20 * time.Millisecond}, db.InsertBatch)
+
+	var fs []*future.Future[batch.Flushed]
+	submit := func(id int) {
+		f, err := p.Submit(context.Background(), Row{ID: id})
+		if err == nil {
+			fs = append(fs, f)
+		}
+	}
+
+	// 突发：一次提交25个
+	for i := 0; i < 25; i++ {
+		submit(i)
+	}
+	// 稀疏：每8ms一个
+	for i := 25; i < 30; i++ {
+		time.Sleep(8 * time.Millisecond)
+		submit(i)
+	}
+	p.Close(context.Background())
+
+	last := int64(0)
+	for _, f := range fs {
+		info, _ := f.Get()
+		if info.Batch != last {
+			fmt.Printf("  批次%d: %2d 个元素, 刷新原因 %s\n", info.Batch, info.Size, info.Reason)
+			last = info.Batch
+		}
+	}
+}
+
+// demoFailure 含有重复记录的批次整体失败，该批所有元素的Future都得到错误
+func demoFailure() {
+	db := &FakeDB{callCost: time.Millisecond, failOn: func(rows []Row) bool {
+		for _, r := range rows {
+			if r.Name == "duplicate" {
+				return true
+			}
+		}
+		return false
+	}}
+	p := batch.New(batch.Config{MaxSize: 5, MaxDelay: 10 * time.Millisecond}, db.InsertBatch)
+
+	var fs []*future.Future[batch.Flushed]
+	for i := 0; i < 15; i++ {
+		name := "ok"
+		if i == 7 {
+			name = "duplicate"
+		}
+		f, _ := p.Submit(context.Background(), Row{ID: i, Name: name})
+		fs = append(fs, f)
+	}
+	p.Close(context.Background())
+
+	for i, f := range fs {
+		info, err := f.Get()
+		if err != nil {
+			fmt.Printf("  元素%2d (批次%d): ✗ %v\n", i, info.Batch, err)
+		}
+	}
+	s := p.Stats()
+	fmt.Printf("  共 %d 批，失败 %d 批；失败批次中的每个调用方都收到了错误\n", s.Batches, s.Failed)
+}
+
+// demoBackpressure 数据库很慢，10个goroutine持续提交，比较两种溢出策略
+func demoBackpressure(policy batch.Overflow, name string) {
+	db := &FakeDB{callCost: 20 * time.Millisecond}
+	p := batch.New(batch.Config{MaxSize: 10, MaxDelay: 5 * time.Millisecond, QueueSize: 20, Overflow: policy}, db.InsertBatch)
+
+	var accepted, rejected atomic.Int64
+	var maxWait atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+				start := time.Now()
+				_, err := p.Submit(ctx, Row{ID: g*20 + i})
+				cancel()
+				wait := int64(time.Since(start))
+				for cur := maxWait.Load(); wait > cur && !maxWait.CompareAndSwap(cur, wait); cur = maxWait.Load() {
+				}
+				if err != nil {
+					rejected.Add(1)
+					time.Sleep(2 * time.Millisecond) // 被拒绝后稍等再试下一条
+					continue
+				}
+				accepted.Add(1)
+			}
+		}(g)
+	}
+	wg.Wait()
+	p.Close(context.Background())
+
+	_, rows := db.counts()
+	fmt.Printf("  %-7s 接受 %3d, 拒绝 %3d, 写入 %3d 行, Submit最长等待 %v\n",
+		name, accepted.Load(), rejected.Load(), rows, time.Duration(maxWait.Load()).Round(time.Millisecond))
+}
+
+func main() {
+	writers := flag.Int("writers", 20, "第1部分的并发写入方数量")
+	perWriter := flag.Int("per-writer", 10, "第1部分每个写入方写入的记录数")
+	maxSize := flag.Int("max-size", 20, "第1部分批量写入的批大小上限")
+	maxDelay := flag.Duration("max-delay", 5*time.Millisecond, "第1部分批次攒不满时的最长等待时间")
+	demoflag.Parse()
+
+	fmt.Println("=== 批量合并处理器演示 ===")
+
+	records := *writers * *perWriter
+	fmt.Printf("\n1. %d条记录，%d个并发写入方，数据库单连接（每次调用2ms + 每行20µs）:\n", records, *writers)
+	demoRowByRow(*writers, *perWriter)
+	demoBatched(*writers, *perWriter, *maxSize, *maxDelay)
+
+	fmt.Println("\n2. 刷新条件（MaxSize=10, MaxDelay=20ms，先突发25个，再每8ms一个）:")
+	demoFlushReasons()
+
+	fmt.Println("\n3. 批量写入失败:")
+	demoFailure()
+
+	fmt.Println("\n4. 溢出背压（每批20ms，队列容量20，Submit最多等待200ms）:")
+	demoBackpressure(batch.Block, "Block")
+	demoBackpressure(batch.Reject, "Reject")
+
+	fmt.Println("\n观察要点：")
+	fmt.Printf("1. 批量写入把%d次调用合并为几次，总耗时主要由调用次数决定\n", records)
+	fmt.Println("2. 突发流量下批次总是攒满才刷新，稀疏流量下由MaxDelay兜底，延迟有上限")
+	fmt.Println("3. 一个批次共享一次写入的结果，失败时整批的调用方都要处理错误（可以拆开重试）")
+	fmt.Println("4. Block把调用方拖慢到数据库的速度；Reject让调用方立即知道系统过载，自己决定降级或重试")
+}
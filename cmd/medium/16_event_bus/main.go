@@ -0,0 +1,196 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/16_event_bus/main.go
+主题：进程内强类型事件总线与中间件
+
+本示例演示：
+1. pkg/eventbus 按事件的Go类型路由，事件类型需要先登记，发布未登记的类型会报错
+2. 同步处理器：在发布方goroutine中执行，错误返回给发布方（例如库存不足让下单失败）
+3. 异步处理器：每个处理器独立的队列和goroutine，慢处理器（发邮件）不拖慢发布方
+4. 中间件链：指标 -> 日志 -> panic恢复，包裹每一次处理器调用
+5. 处理器内部再次发布事件、取消订阅、关闭时等待异步队列处理完
+
+核心概念：
+- 与04_publish_subscribe.go的主题字符串不同，事件类型在编译期确定，处理器直接拿到具体类型
+- 同步处理器适合必须成功的业务校验，异步处理器适合通知、统计等可以延后的副作用
+- 中间件把横切关注点（日志、指标、容错）从业务处理器中分离出来
+- Recovery必须在最内层附近：一个处理器panic不能让发布方或异步goroutine崩溃
+
+运行方式：go run ./cmd/medium/16_event_bus [-stock=3] [-email-delay=30ms] [-queue=16]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/eventbus"
+)
+
+// OrderPlaced 下单事件
+type OrderPlaced struct {
+	OrderID string
+	SKU     string
+	Qty     int
+}
+
+// StockReserved 库存预留成功后由库存处理器发布
+type StockReserved struct {
+	OrderID string
+	Left    int
+}
+
+// UserRegistered 注册事件，用来演示处理器panic
+type UserRegistered struct {
+	Name string
+}
+
+// unknownEvent 没有登记的事件类型
+type unknownEvent struct{}
+
+var errOutOfStock = errors.New("库存不足")
+
+// Inventory 库存服务，同步处理下单事件
+type Inventory struct {
+	mu    sync.Mutex
+	stock map[string]int
+	bus   *eventbus.Bus
+}
+
+func (inv *Inventory) OnOrderPlaced(ctx context.Context, e OrderPlaced) error {
+	inv.mu.Lock()
+	left := inv.stock[e.SKU] - e.Qty
+	if left < 0 {
+		inv.mu.Unlock()
+		return fmt.Errorf("%s 需要 %d 件: %w", e.SKU, e.Qty, errOutOfStock)
+	}
+	inv.stock[e.SKU] = left
+	inv.mu.Unlock()
+
+	// 在处理器中发布后续事件，总线不持有锁调用处理器，所以不会死锁
+	return eventbus.Publish(ctx, inv.bus, StockReserved{OrderID: e.OrderID, Left: left})
+}
+
+func main() {
+	stock := flag.Int("stock", 3, "book的初始库存，前两个订单共需要3件")
+	emailDelay := flag.Duration("email-delay", 30*time.Millisecond, "异步邮件处理器每封邮件的耗时")
+	queue := flag.Int("queue", 16, "每个异步处理器的队列长度")
+	demoflag.Parse()
+	if *stock < 0 || *queue < 1 {
+		fmt.Fprintln(os.Stderr, "stock不能为负数，queue至少为1")
+		os.Exit(2)
+	}
+
+	fmt.Println("=== 强类型事件总线演示 ===")
+
+	var logMu sync.Mutex
+	logf := func(format string, args ...any) {
+		logMu.Lock()
+		defer logMu.Unlock()
+		fmt.Printf("    [log] "+format+"\n", args...)
+	}
+
+	bus := eventbus.New(func(env eventbus.Envelope, err error) {
+		logf("异步处理器 %s 出错: %v", env.Handler, err)
+	})
+	metrics := eventbus.NewMetrics()
+	bus.Use(metrics.Middleware(), eventbus.Logging(logf), eventbus.Recovery())
+
+	eventbus.Register[OrderPlaced](bus)
+	eventbus.Register[StockReserved](bus)
+	eventbus.Register[UserRegistered](bus)
+
+	inv := &Inventory{stock: map[string]int{"book": *stock}, bus: bus}
+	eventbus.Subscribe(bus, "inventory", inv.OnOrderPlaced)
+
+	var auditMu sync.Mutex
+	var audit []string
+	unsubscribeAudit, _ := eventbus.Subscribe(bus, "audit", func(ctx context.Context, e OrderPlaced) error {
+		auditMu.Lock()
+		defer auditMu.Unlock()
+		audit = append(audit, e.OrderID)
+		return nil
+	})
+
+	var emails sync.WaitGroup
+	eventbus.SubscribeAsync(bus, "email", *queue, func(ctx context.Context, e StockReserved) error {
+		defer emails.Done()
+		time.Sleep(*emailDelay) // 慢的外部调用
+		return nil
+	})
+	// placeOrder 发布下单事件；库存预留成功才会发布StockReserved，才需要等待一封邮件
+	placeOrder := func(o OrderPlaced) error {
+		emails.Add(1)
+		err := eventbus.Publish(context.Background(), bus, o)
+		if err != nil {
+			emails.Done()
+		}
+		return err
+	}
+
+	eventbus.Subscribe(bus, "welcome", func(ctx context.Context, e UserRegistered) error {
+		if e.Name == "" {
+			var profile map[string]string
+			profile["name"] = e.Name // 向nil map写入，触发panic
+		}
+		return nil
+	})
+	eventbus.SubscribeAsync(bus, "analytics", *queue, func(ctx context.Context, e UserRegistered) error {
+		if e.Name == "" {
+			panic("空用户名")
+		}
+		return nil
+	})
+
+	fmt.Println("\n1. 下单：库存处理器同步执行，再发布StockReserved交给异步的邮件处理器")
+	for i, qty := range []int{2, 1} {
+		start := time.Now()
+		err := placeOrder(OrderPlaced{OrderID: fmt.Sprintf("order-%d", i+1), SKU: "book", Qty: qty})
+		fmt.Printf("  order-%d 发布返回 %v, err=%v（没有等待%v的邮件发送）\n", i+1, time.Since(start).Round(time.Millisecond), err, *emailDelay)
+	}
+
+	fmt.Println("\n2. 库存不足：同步处理器的错误返回给发布方")
+	err := placeOrder(OrderPlaced{OrderID: "order-3", SKU: "book", Qty: 5})
+	fmt.Printf("  order-3 err=%v, 是库存不足: %v\n", err, errors.Is(err, errOutOfStock))
+
+	fmt.Println("\n3. 处理器panic：Recovery把panic转换为错误，同步返回给发布方，异步交给onAsyncError")
+	err = eventbus.Publish(context.Background(), bus, UserRegistered{})
+	var panicErr *eventbus.PanicError
+	fmt.Printf("  发布返回 PanicError: %v\n", errors.As(err, &panicErr))
+
+	fmt.Println("\n4. 发布未登记的事件类型:")
+	fmt.Printf("  err=%v\n", eventbus.Publish(context.Background(), bus, unknownEvent{}))
+
+	fmt.Println("\n5. 取消audit订阅后再下单:")
+	unsubscribeAudit()
+	placeOrder(OrderPlaced{OrderID: "order-4", SKU: "book", Qty: 0})
+	auditMu.Lock()
+	fmt.Printf("  audit记录: %v（没有order-4）\n", audit)
+	auditMu.Unlock()
+
+	emails.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	fmt.Printf("\n6. 关闭总线，等待异步队列处理完: err=%v\n", bus.Close(ctx))
+	fmt.Printf("  关闭后发布: err=%v\n", eventbus.Publish(context.Background(), bus, OrderPlaced{}))
+
+	fmt.Println("\n指标:")
+	for _, m := range metrics.Snapshot() {
+		fmt.Printf("  %-10s 调用 %d 次, 错误 %d 次, 平均 %v, 最长 %v\n",
+			m.Handler, m.Calls, m.Errors, (m.Total / time.Duration(m.Calls)).Round(time.Microsecond), m.Max.Round(time.Microsecond))
+	}
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 发布方只等待同步处理器；异步的邮件处理器在自己的goroutine里慢慢处理")
+	fmt.Println("2. 同步处理器的错误会被汇总（errors.Join），用errors.Is/As可以判断具体原因")
+	fmt.Println("3. 中间件的顺序很重要：指标在Recovery外层，panic也会被计为一次错误")
+	fmt.Println("4. 日志中的嵌套调用：inventory里发布StockReserved，所以它的耗时包含了入队时间")
+	fmt.Println("5. 关闭总线时先停止接收，再等异步队列清空，已发布的事件不会丢失")
+}
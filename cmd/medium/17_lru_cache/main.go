@@ -0,0 +1,250 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/17_lru_cache/main.go
+主题：分片并发LRU缓存（TTL + 合并加载）
+
+本示例演示：
+1. pkg/cache 的 LRUCache[K, V]：分片锁、容量淘汰、每个条目的过期时间、命中/未命中/淘汰统计
+2. 缓存击穿：100个goroutine同时请求同一个冷键，只触发一次后端加载，其余调用方共享结果
+3. 热点分布（Zipf）的请求经过容量有限的缓存，统计命中率和节省的后端调用
+4. 用手动推进的时钟检查TTL过期，加载失败的结果不会被缓存
+5. 等待加载的调用方超时离开，不影响其他等待者拿到结果
+6. 1个分片与16个分片在并发读写下的吞吐对比
+
+核心概念：
+- LRU：链表记录访问顺序，命中时移到前端，满了从尾部淘汰；读操作也要修改链表，所以要加互斥锁而不是读锁
+- 分片：一把全局锁会让所有goroutine排队，按键哈希分片后只有落在同一分片的请求才会竞争
+- 合并加载：同一键的并发未命中登记在loading表里，后来者等待第一个加载的结果
+- 加载与调用方的取消解耦：用context.WithoutCancel，谁先放弃都不影响加载本身
+
+运行方式：go run ./cmd/medium/17_lru_cache [-callers=100] [-shards=16]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/cache"
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// User 后端返回的数据
+type User struct {
+	ID   int
+	Name string
+}
+
+// Backend 慢速后端，记录被调用的次数
+type Backend struct {
+	latency time.Duration
+	calls   atomic.Int64
+	failIDs sync.Map // 这些ID的第一次加载失败
+}
+
+var errBackend = errors.New("后端超时")
+
+func (b *Backend) LoadUser(ctx context.Context, id int) (User, error) {
+	b.calls.Add(1)
+	select {
+	case <-time.After(b.latency):
+	case <-ctx.Done():
+		return User{}, ctx.Err()
+	}
+	if _, fail := b.failIDs.LoadAndDelete(id); fail {
+		return User{}, errBackend
+	}
+	return User{ID: id, Name: fmt.Sprintf("user-%d", id)}, nil
+}
+
+// manualClock 手动推进的时钟
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func intHash(id int) uint64 { return uint64(id) * 0x9E3779B97F4A7C15 }
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+func demoStampede(callers int) {
+	backend := &Backend{latency: 50 * time.Millisecond}
+	c := cache.New(cache.Config[int, User]{MaxSize: 100, TTL: time.Minute, Loader: backend.LoadUser, Hash: intHash})
+
+	var wg sync.WaitGroup
+	var ok atomic.Int64
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if u, err := c.GetOrLoad(context.Background(), 1); err == nil && u.ID == 1 {
+				ok.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	s := c.Stats()
+	fmt.Printf("  %d个请求全部拿到结果: %d, 后端调用 %d 次, 共享加载结果 %d 次\n", callers, ok.Load(), backend.calls.Load(), s.Shared)
+}
+
+func demoZipf() {
+	backend := &Backend{latency: time.Millisecond}
+	c := cache.New(cache.Config[int, User]{MaxSize: 128, TTL: time.Minute, Loader: backend.LoadUser, Hash: intHash})
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for g := 0; g < 20; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(g)))
+			zipf := rand.NewZipf(rng, 1.1, 1, 999)
+			for i := 0; i < 500; i++ {
+				c.GetOrLoad(context.Background(), int(zipf.Uint64()))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	s := c.Stats()
+	total := s.Hits + s.Misses
+	fmt.Printf("  10000次请求, 1000个键, 容量128: 命中率 %.1f%%, 后端调用 %d 次, 淘汰 %d 次, 耗时 %v\n",
+		100*float64(s.Hits)/float64(total), backend.calls.Load(), s.Evictions, time.Since(start).Round(time.Millisecond))
+}
+
+func demoTTLAndErrors() {
+	clock := &manualClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	backend := &Backend{}
+	c := cache.New(cache.Config[int, User]{MaxSize: 16, Shards: 4, TTL: time.Minute, Loader: backend.LoadUser, Hash: intHash, Now: clock.Now})
+	ctx := context.Background()
+
+	c.GetOrLoad(ctx, 7)
+	c.SetWithTTL(8, User{ID: 8, Name: "短期"}, 10*time.Second)
+	clock.Advance(30 * time.Second)
+	_, hit7 := c.Get(7)
+	_, hit8 := c.Get(8)
+	check("30秒后: 默认TTL(1分钟)的条目还在", hit7)
+	check("30秒后: 单独设置TTL=10秒的条目已过期", !hit8)
+	clock.Advance(31 * time.Second)
+	_, hit7 = c.Get(7)
+	check("61秒后: 默认TTL的条目也过期了", !hit7)
+	c.GetOrLoad(ctx, 7)
+	check("过期后重新加载，后端被调用第2次", backend.calls.Load() == 2)
+
+	backend.failIDs.Store(9, true)
+	_, err1 := c.GetOrLoad(ctx, 9)
+	u, err2 := c.GetOrLoad(ctx, 9)
+	check(fmt.Sprintf("加载失败(%v)不写入缓存，下一次重新加载成功: %s", err1, u.Name), err1 != nil && err2 == nil)
+
+	s := c.Stats()
+	fmt.Printf("  统计: 命中 %d, 未命中 %d, 过期 %d, 加载 %d, 加载失败 %d\n", s.Hits, s.Misses, s.Expirations, s.Loads, s.LoadErrors)
+}
+
+func demoCallerTimeout() {
+	backend := &Backend{latency: 50 * time.Millisecond}
+	c := cache.New(cache.Config[int, User]{MaxSize: 16, Loader: backend.LoadUser, Hash: intHash})
+
+	var wg sync.WaitGroup
+	var impatientErr, patientErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// 第一个调用方触发加载后10ms就放弃
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, impatientErr = c.GetOrLoad(ctx, 42)
+	}()
+	go func() {
+		defer wg.Done()
+		time.Sleep(time.Millisecond)
+		_, patientErr = c.GetOrLoad(context.Background(), 42)
+	}()
+	wg.Wait()
+	check(fmt.Sprintf("先放弃的调用方得到 %v", impatientErr), errors.Is(impatientErr, context.DeadlineExceeded))
+	check("继续等待的调用方拿到结果，后端只调用1次", patientErr == nil && backend.calls.Load() == 1)
+}
+
+func benchShards(shards int) float64 {
+	c := cache.New(cache.Config[int, User]{MaxSize: 1024, Shards: shards, Hash: intHash})
+	for i := 0; i < 1024; i++ {
+		c.Set(i, User{ID: i})
+	}
+
+	const goroutines, ops = 8, 50000
+	var wg sync.WaitGroup
+	start := time.Now()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < ops; i++ {
+				k := rng.Intn(1024)
+				if i%10 == 0 {
+					c.Set(k, User{ID: k})
+				} else {
+					c.Get(k)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	return float64(goroutines*ops) / time.Since(start).Seconds()
+}
+
+func main() {
+	callers := flag.Int("callers", 100, "第1部分同时请求冷键的goroutine数量")
+	shards := flag.Int("shards", 16, "第5部分与单分片对比的分片数")
+	demoflag.Parse()
+
+	fmt.Println("=== 分片并发LRU缓存演示 ===")
+
+	fmt.Printf("\n1. 缓存击穿：%d个goroutine同时请求同一个冷键（后端50ms）\n", *callers)
+	demoStampede(*callers)
+
+	fmt.Println("\n2. Zipf热点分布，20个goroutine并发请求（后端1ms）")
+	demoZipf()
+
+	fmt.Println("\n3. TTL过期与加载失败（手动推进的时钟）")
+	demoTTLAndErrors()
+
+	fmt.Println("\n4. 等待加载的调用方超时")
+	demoCallerTimeout()
+
+	fmt.Println("\n5. 分片数对吞吐的影响（8个goroutine，90%读10%写）")
+	for _, shards := range []int{1, *shards} {
+		fmt.Printf("  %2d个分片: %10.0f 次/秒\n", shards, benchShards(shards))
+	}
+
+	fmt.Println("\n观察要点：")
+	fmt.Printf("1. 击穿场景下只有一次后端调用，其余%d个请求等待并共享这次结果\n", *callers-1)
+	fmt.Println("2. 热点分布下少量的键承担了大部分请求，容量远小于键总数也能有很高的命中率")
+	fmt.Println("3. 过期的条目在被访问时才删除，Len可能包含还没被访问到的过期条目")
+	fmt.Println("4. 失败的加载不缓存，否则一次后端抖动会让错误被缓存到TTL结束")
+	fmt.Println("5. 单核机器上goroutine不会真正并行，分片的收益很小；多核时单分片的锁会成为瓶颈")
+}
@@ -0,0 +1,335 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/18_write_behind/main.go
+主题：写回缓存（异步批量写入 + 重试 + 背压）
+
+本示例演示：
+1. pkg/cache 的 WriteBehind[K, V]：Set只更新内存并记为脏数据，后台goroutine定时或攒够一批后写入慢速数据库
+2. 与直写（每次Set同步写数据库）对比写入延迟和数据库调用次数
+3. 同一个键在写入前被多次修改，只写入最后的值（合并写）
+4. 数据库间歇失败：一批写入带指数退避重试，重试用尽后放回脏数据，下一轮再写，不丢数据
+5. 数据库长时间不可用：脏条目达到上限后写入者阻塞（背压），带超时的写入者按时放弃
+6. Close把剩余脏数据写完，最终数据库内容与缓存一致
+
+核心概念：
+- 写回(write-behind)：用"数据库落后于缓存一小段时间"换取写入延迟和数据库负载
+- 脏条目上限：没有上限时数据库一挂，内存里的脏数据会无限增长；有上限时压力传回写入者
+- 放回脏数据时不能覆盖更新的值：写入期间同一个键可能又被修改了
+- 用"关闭并替换"的channel广播空间变化，等待者可以同时select ctx.Done()，sync.Cond做不到
+
+运行方式：go run ./cmd/medium/18_write_behind [-keys=20] [-per-writer=100] [-batch=50] [-flush=20ms]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/cache"
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// FakeDB 慢速数据库：每次写入有固定开销加每行开销，只有一个连接
+type FakeDB struct {
+	perCall, perRow time.Duration
+
+	conn  sync.Mutex // 同一时间只处理一个写入请求
+	mu    sync.Mutex
+	rows  map[string]int
+	calls atomic.Int64
+	fails atomic.Int64 // 接下来失败的次数
+	down  atomic.Bool  // 不可用期间所有写入都失败
+}
+
+var errDB = errors.New("数据库写入失败")
+
+func NewFakeDB(perCall, perRow time.Duration) *FakeDB {
+	return &FakeDB{perCall: perCall, perRow: perRow, rows: make(map[string]int)}
+}
+
+func (db *FakeDB) WriteBatch(ctx context.Context, batch map[string]int) error {
+	db.conn.Lock()
+	defer db.conn.Unlock()
+	db.calls.Add(1)
+	time.Sleep(db.perCall + time.Duration(len(batch))*db.perRow)
+	if db.down.Load() {
+		return errDB
+	}
+	if db.fails.Load() > 0 && db.fails.Add(-1) >= 0 {
+		return errDB
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for k, v := range batch {
+		db.rows[k] = v
+	}
+	return nil
+}
+
+func (db *FakeDB) Get(key string) (int, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	v, ok := db.rows[key]
+	return v, ok
+}
+
+func (db *FakeDB) Len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.rows)
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// latencies 记录写入延迟
+type latencies struct {
+	mu sync.Mutex
+	ds []time.Duration
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	l.ds = append(l.ds, d)
+	l.mu.Unlock()
+}
+
+func (l *latencies) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sort.Slice(l.ds, func(i, j int) bool { return l.ds[i] < l.ds[j] })
+	p := func(q float64) time.Duration { return l.ds[int(q*float64(len(l.ds)-1))].Round(time.Microsecond) }
+	return fmt.Sprintf("p50=%v p99=%v max=%v", p(0.5), p(0.99), p(1))
+}
+
+// consistent 检查数据库与缓存中每个键的值相同
+func consistent(db *FakeDB, wb *cache.WriteBehind[string, int], keys []string) bool {
+	for _, k := range keys {
+		cv, _ := wb.Get(k)
+		dv, ok := db.Get(k)
+		if !ok || cv != dv {
+			return false
+		}
+	}
+	return true
+}
+
+func keyNames(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("counter-%02d", i)
+	}
+	return keys
+}
+
+// runWriters 4个goroutine各自对keys轮流写入递增的值
+func runWriters(keys []string, perWriter int, set func(key string, v int) error) *latencies {
+	lat := &latencies{}
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				start := time.Now()
+				set(keys[(g*7+i)%len(keys)], g*perWriter+i)
+				lat.add(time.Since(start))
+			}
+		}(g)
+	}
+	wg.Wait()
+	return lat
+}
+
+// demoWriteThroughVsBehind 4个写入方各写perWriter次，写回缓存每批最多batchSize个键、每flush刷新一次
+func demoWriteThroughVsBehind(numKeys, perWriter, batchSize int, flush time.Duration) {
+	keys := keyNames(numKeys)
+
+	db := NewFakeDB(2*time.Millisecond, 20*time.Microsecond)
+	start := time.Now()
+	lat := runWriters(keys, perWriter, func(k string, v int) error {
+		return db.WriteBatch(context.Background(), map[string]int{k: v})
+	})
+	fmt.Printf("  直写: %d次写入耗时 %v, 数据库调用 %d 次, 写入延迟 %s\n", 4*perWriter,
+		time.Since(start).Round(time.Millisecond), db.calls.Load(), lat)
+
+	db = NewFakeDB(2*time.Millisecond, 20*time.Microsecond)
+	wb := cache.NewWriteBehind(cache.WriteBehindConfig{MaxDirty: 100, BatchSize: batchSize, FlushInterval: flush}, db.WriteBatch)
+	start = time.Now()
+	lat = runWriters(keys, perWriter, func(k string, v int) error {
+		return wb.Set(context.Background(), k, v)
+	})
+	elapsed := time.Since(start)
+	wb.Close(context.Background())
+	s := wb.Stats()
+	fmt.Printf("  写回: %d次写入耗时 %v, 数据库调用 %d 次, 写入延迟 %s\n", 4*perWriter,
+		elapsed.Round(time.Millisecond), db.calls.Load(), lat)
+	fmt.Printf("  合并写 %d 次, 写入数据库 %d 批共 %d 行\n", s.Coalesced, s.Flushes, s.FlushedEntries)
+	check("Close之后数据库与缓存一致", consistent(db, wb, keys))
+}
+
+func demoRetry() {
+	keys := keyNames(30)
+	db := NewFakeDB(time.Millisecond, 0)
+	db.fails.Store(5) // 接下来的5次写入失败
+	wb := cache.NewWriteBehind(cache.WriteBehindConfig{
+		MaxDirty: 100, BatchSize: 10, FlushInterval: 10 * time.Millisecond,
+		MaxRetries: 2, RetryBackoff: 5 * time.Millisecond,
+	}, db.WriteBatch)
+
+	for i, k := range keys {
+		wb.Set(context.Background(), k, i)
+	}
+	// 重试期间再修改一个键：无论它是否在正在写入的那批里，放回脏数据时都不能用旧值覆盖新值
+	time.Sleep(3 * time.Millisecond)
+	wb.Set(context.Background(), keys[0], 1000)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := wb.Flush(ctx)
+	s := wb.Stats()
+	fmt.Printf("  数据库调用 %d 次, 重试 %d 次, 重试用尽放回脏数据 %d 批, 成功 %d 批\n",
+		db.calls.Load(), s.Retries, s.FailedFlushes, s.Flushes)
+	check("Flush返回时全部写入成功", err == nil && wb.DirtyLen() == 0)
+	v, _ := db.Get(keys[0])
+	check(fmt.Sprintf("写入期间被修改的键，数据库中是新值: %d", v), v == 1000)
+	check("数据库与缓存一致", consistent(db, wb, keys))
+	wb.Close(context.Background())
+}
+
+func demoBackpressure() {
+	db := NewFakeDB(time.Millisecond, 0)
+	db.down.Store(true)
+	const maxDirty = 20
+	wb := cache.NewWriteBehind(cache.WriteBehindConfig{
+		MaxDirty: maxDirty, BatchSize: 10, FlushInterval: 5 * time.Millisecond,
+		MaxRetries: 1, RetryBackoff: 5 * time.Millisecond,
+	}, db.WriteBatch)
+
+	// 采样脏条目数，检查从不超过上限
+	var maxSeen atomic.Int64
+	stopSampling := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			select {
+			case <-stopSampling:
+				return
+			case <-time.After(time.Millisecond):
+				if n := int64(wb.DirtyLen()); n > maxSeen.Load() {
+					maxSeen.Store(n)
+				}
+			}
+		}
+	}()
+
+	outage := 200 * time.Millisecond
+	time.AfterFunc(outage, func() { db.down.Store(false) })
+
+	keys := keyNames(60)
+	start := time.Now()
+	lat := &latencies{}
+	var wg sync.WaitGroup
+	for g := 0; g < 3; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				t := time.Now()
+				wb.Set(context.Background(), keys[g*20+i], i)
+				lat.add(time.Since(t))
+			}
+		}(g)
+	}
+
+	// 一个只愿意等30ms的写入者
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	impatientErr := wb.Set(ctx, "impatient", 1)
+	cancel()
+
+	wg.Wait()
+	elapsed := time.Since(start)
+	wb.Flush(context.Background())
+	close(stopSampling)
+	<-sampled
+
+	s := wb.Stats()
+	fmt.Printf("  数据库不可用 %v: 60次写入耗时 %v, 写入延迟 %s\n", outage, elapsed.Round(time.Millisecond), lat)
+	fmt.Printf("  阻塞过的写入 %d 次, 失败批次 %d, 重试 %d 次\n", s.BlockedWrites, s.FailedFlushes, s.Retries)
+	check(fmt.Sprintf("脏条目最多 %d 个，没有超过上限 %d", maxSeen.Load(), maxDirty), maxSeen.Load() <= maxDirty)
+	check("写入者被阻塞到数据库恢复", s.BlockedWrites > 0 && elapsed >= outage)
+	check(fmt.Sprintf("只等30ms的写入者放弃: %v", impatientErr), errors.Is(impatientErr, context.DeadlineExceeded))
+	_, written := db.Get("impatient")
+	check("放弃的写入没有进入缓存和数据库", !written)
+	check("数据库恢复后60个键全部写入", db.Len() == 60 && consistent(db, wb, keys))
+	wb.Close(context.Background())
+}
+
+func demoClose() {
+	db := NewFakeDB(5*time.Millisecond, 0)
+	// 定时刷新间隔很长，Close时脏数据都还在内存中
+	wb := cache.NewWriteBehind(cache.WriteBehindConfig{MaxDirty: 100, BatchSize: 100, FlushInterval: time.Hour}, db.WriteBatch)
+	keys := keyNames(25)
+	for i, k := range keys {
+		wb.Set(context.Background(), k, i)
+	}
+	fmt.Printf("  Close前: 缓存中脏条目 %d 个, 数据库 %d 行\n", wb.DirtyLen(), db.Len())
+	err := wb.Close(context.Background())
+	fmt.Printf("  Close后: 缓存中脏条目 %d 个, 数据库 %d 行\n", wb.DirtyLen(), db.Len())
+	check("Close把剩余脏数据写完", err == nil && consistent(db, wb, keys))
+	err = wb.Set(context.Background(), "late", 1)
+	check(fmt.Sprintf("Close之后的写入返回 %v", err), errors.Is(err, cache.ErrWriteBehindClosed))
+
+	// 数据库一直不可用时，Close报告没写进去的条目数
+	down := NewFakeDB(time.Millisecond, 0)
+	down.down.Store(true)
+	wb = cache.NewWriteBehind(cache.WriteBehindConfig{MaxDirty: 10, BatchSize: 10, FlushInterval: time.Hour}, down.WriteBatch)
+	for i := 0; i < 3; i++ {
+		wb.Set(context.Background(), fmt.Sprint(i), i)
+	}
+	err = wb.Close(context.Background())
+	check(fmt.Sprintf("数据库不可用时Close返回: %v", err), err != nil)
+}
+
+func main() {
+	numKeys := flag.Int("keys", 20, "第1部分写入的键数量")
+	perWriter := flag.Int("per-writer", 100, "第1部分每个goroutine的写入次数")
+	batchSize := flag.Int("batch", 50, "第1部分写回缓存每批写入数据库的键数上限")
+	flush := flag.Duration("flush", 20*time.Millisecond, "第1部分写回缓存的刷新间隔")
+	demoflag.Parse()
+
+	fmt.Println("=== 写回缓存演示 ===")
+
+	fmt.Printf("\n1. 直写与写回对比（4个goroutine向%d个键写入%d次，数据库每次调用2ms）\n", *numKeys, 4*(*perWriter))
+	demoWriteThroughVsBehind(*numKeys, *perWriter, *batchSize, *flush)
+
+	fmt.Println("\n2. 数据库间歇失败：接下来5次写入失败，每批最多重试2次")
+	demoRetry()
+
+	fmt.Println("\n3. 数据库不可用200ms，脏条目上限20")
+	demoBackpressure()
+
+	fmt.Println("\n4. Close")
+	demoClose()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 写回的写入延迟只是一次加锁写map，数据库调用次数由批大小和刷新间隔决定，而不是写入次数")
+	fmt.Println("2. 热点键在两次刷新之间被反复修改，只有最后的值被写入数据库")
+	fmt.Println("3. 重试用尽的一批放回脏数据而不是丢弃，但如果这期间键被改过，保留新值")
+	fmt.Println("4. 数据库不可用时脏条目停在上限，写入者的延迟接近故障时长：背压把故障暴露给了调用方")
+	fmt.Println("5. 进程退出前必须Close，否则内存中的脏数据会丢失；写回缓存适合能容忍少量数据丢失的场景")
+}
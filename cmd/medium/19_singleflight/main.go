@@ -0,0 +1,231 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/19_singleflight/main.go
+主题：Singleflight请求合并
+
+本示例演示：
+1. pkg/singleflight 的 Group[K, V]：Do(key, fn) 对同一个键的并发调用只执行一次fn，其余调用方共享结果
+2. 惊群(thundering herd)：热点数据失效的瞬间1000个请求同时打到只能并发处理10个请求的慢服务
+3. Remember：完成后的结果再共享一小段时间，挡住紧随其后的一波请求
+4. ForgetOnError：失败的结果立即忘记，不在Remember期间反复返回同一个错误
+5. fn的panic转换成*PanicError返回给所有等待者；Forget让后来者不再共享进行中的调用
+
+核心概念：
+- 合并的是"进行中"的调用，不是缓存：默认fn返回后就忘记，下一次Do重新执行
+- 一个慢调用会拖住所有共享它的调用方，失败也会被所有人共享
+- 与LRU缓存的合并加载相比，singleflight不关心结果存在哪里，可以套在任何调用外面
+
+运行方式：go run ./cmd/medium/19_singleflight [-requests=1000] [-limit=10] [-latency=20ms]
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/singleflight"
+)
+
+// SlowService 同时只能处理capacity个请求的慢服务
+type SlowService struct {
+	latency time.Duration
+	slots   chan struct{}
+	calls   atomic.Int64
+	fail    atomic.Bool
+}
+
+var errUnavailable = errors.New("服务暂时不可用")
+
+func NewSlowService(latency time.Duration, capacity int) *SlowService {
+	return &SlowService{latency: latency, slots: make(chan struct{}, capacity)}
+}
+
+func (s *SlowService) GetPrice(item string) (int, error) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+	n := s.calls.Add(1)
+	time.Sleep(s.latency)
+	if s.fail.Load() {
+		return 0, errUnavailable
+	}
+	return 100 + int(n), nil
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// herd n个goroutine同时调用get，返回总耗时和最慢一个请求的延迟
+func herd(n int, get func() (int, error)) (elapsed, slowest time.Duration, errs int64) {
+	var wg sync.WaitGroup
+	var maxNanos, errCount atomic.Int64
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t := time.Now()
+			if _, err := get(); err != nil {
+				errCount.Add(1)
+			}
+			d := int64(time.Since(t))
+			for {
+				cur := maxNanos.Load()
+				if d <= cur || maxNanos.CompareAndSwap(cur, d) {
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return time.Since(start), time.Duration(maxNanos.Load()), errCount.Load()
+}
+
+// demoThunderingHerd requests个请求同时查询同一个键，服务最多limit个并发、每次耗时latency
+func demoThunderingHerd(requests, limit int, latency time.Duration) {
+	svc := NewSlowService(latency, limit)
+	elapsed, slowest, _ := herd(requests, func() (int, error) { return svc.GetPrice("iphone") })
+	fmt.Printf("  不合并: 服务调用 %4d 次, 总耗时 %v, 最慢请求 %v\n",
+		svc.calls.Load(), elapsed.Round(time.Millisecond), slowest.Round(time.Millisecond))
+
+	svc = NewSlowService(latency, limit)
+	g := singleflight.New[string, int](singleflight.Config{})
+	var shared atomic.Int64
+	elapsed, slowest, _ = herd(requests, func() (int, error) {
+		v, err, sh := g.Do("iphone", func() (int, error) { return svc.GetPrice("iphone") })
+		if sh {
+			shared.Add(1)
+		}
+		return v, err
+	})
+	fmt.Printf("  合并:   服务调用 %4d 次, 总耗时 %v, 最慢请求 %v, 共享结果的请求 %d 个\n",
+		svc.calls.Load(), elapsed.Round(time.Millisecond), slowest.Round(time.Millisecond), shared.Load())
+	fmt.Println("  （goroutine启动有先后，晚到的请求可能赶上第二次调用，所以服务调用次数可能略大于1）")
+}
+
+// waves 每隔interval发起一波10个并发请求，共waveCount波
+func waves(g *singleflight.Group[string, int], svc *SlowService, waveCount int, interval time.Duration) (errs int64) {
+	for w := 0; w < waveCount; w++ {
+		_, _, e := herd(10, func() (int, error) {
+			v, err, _ := g.Do("iphone", func() (int, error) { return svc.GetPrice("iphone") })
+			return v, err
+		})
+		errs += e
+		time.Sleep(interval)
+	}
+	return errs
+}
+
+func demoRemember() {
+	for _, remember := range []time.Duration{0, 50 * time.Millisecond} {
+		svc := NewSlowService(5*time.Millisecond, 10)
+		g := singleflight.New[string, int](singleflight.Config{Remember: remember})
+		waves(g, svc, 10, 10*time.Millisecond)
+		fmt.Printf("  Remember=%-6v 10波请求(每隔约10ms一波): 服务调用 %d 次\n", remember, svc.calls.Load())
+	}
+}
+
+func demoForgetOnError() {
+	for _, forget := range []bool{false, true} {
+		svc := NewSlowService(5*time.Millisecond, 10)
+		svc.fail.Store(true)
+		g := singleflight.New[string, int](singleflight.Config{Remember: 200 * time.Millisecond, ForgetOnError: forget})
+		// 第一波失败后服务马上恢复
+		_, _, first := herd(10, func() (int, error) {
+			v, err, _ := g.Do("iphone", func() (int, error) { return svc.GetPrice("iphone") })
+			return v, err
+		})
+		svc.fail.Store(false)
+		later := waves(g, svc, 5, 10*time.Millisecond)
+		fmt.Printf("  ForgetOnError=%-5v 第一波失败 %d 个; 服务恢复后的5波中仍然失败 %d 个, 服务调用 %d 次\n",
+			forget, first, later, svc.calls.Load())
+	}
+}
+
+func demoPanicAndForget() {
+	g := singleflight.New[string, int](singleflight.Config{})
+	var wg sync.WaitGroup
+	var panics atomic.Int64
+	release := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err, _ := g.Do("bad", func() (int, error) {
+				<-release
+				panic("价格表损坏")
+			})
+			var pe *singleflight.PanicError
+			if errors.As(err, &pe) {
+				panics.Add(1)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	check(fmt.Sprintf("fn panic，5个调用方都收到*PanicError: %d", panics.Load()), panics.Load() == 5)
+
+	// Forget：进行中的调用读到的是旧价格，Forget之后的调用方重新读取
+	price := atomic.Int64{}
+	price.Store(100)
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	var oldResult int
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		oldResult, _, _ = g.Do("iphone", func() (int, error) {
+			v := int(price.Load())
+			close(started)
+			<-finish
+			return v, nil
+		})
+	}()
+	<-started
+	price.Store(120) // 价格已经更新，进行中的调用读到的是旧值
+	g.Forget("iphone")
+	newResult, _, shared := g.Do("iphone", func() (int, error) { return int(price.Load()), nil })
+	close(finish)
+	wg.Wait()
+	check(fmt.Sprintf("Forget之后的调用不共享旧调用，读到新价格 %d（旧调用返回 %d）", newResult, oldResult),
+		!shared && newResult == 120 && oldResult == 100)
+}
+
+func main() {
+	requests := flag.Int("requests", 1000, "第1部分同时查询的请求数")
+	limit := flag.Int("limit", 10, "第1部分服务的最大并发数")
+	latency := flag.Duration("latency", 20*time.Millisecond, "第1部分每次服务调用的耗时")
+	demoflag.Parse()
+
+	fmt.Println("=== Singleflight请求合并演示 ===")
+
+	fmt.Printf("\n1. 惊群：%d个请求同时查询同一个商品价格（服务%v，最多并发%d个）\n", *requests, *latency, *limit)
+	demoThunderingHerd(*requests, *limit, *latency)
+
+	fmt.Println("\n2. Remember：结果完成后继续共享一段时间")
+	demoRemember()
+
+	fmt.Println("\n3. ForgetOnError：Remember=200ms，第一波请求时服务故障")
+	demoForgetOnError()
+
+	fmt.Println("\n4. panic与Forget")
+	demoPanicAndForget()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 不合并时请求在服务的并发上限前排队，最慢的请求要等前面所有批次；合并后所有人只等一次调用")
+	fmt.Println("2. 默认只合并同时在进行的调用，Remember把紧随其后的请求也挡住，代价是结果最多旧Remember这么久")
+	fmt.Println("3. 记住失败的结果等于把故障延长到Remember结束，通常应该只记住成功的结果")
+	fmt.Println("4. panic必须在执行fn的goroutine里恢复，否则等待者会永远阻塞在done上")
+	fmt.Println("5. 合并调用把一个请求的失败和延迟传播给所有共享者，只适合结果对所有调用方都相同的读操作")
+}
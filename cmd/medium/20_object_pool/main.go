@@ -0,0 +1,232 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/20_object_pool/main.go
+主题：对象池（sync.Pool与自定义有界池对比）
+
+本示例演示：
+1. 高并发下每个请求都申请一块64KB的缓冲区：pkg/bufpool 中的不复用、sync.Pool、基于channel的有界池三种方式
+2. 用runtime.MemStats统计分配的总字节数、分配次数、GC次数和GC暂停时间
+3. 每个请求的延迟分布（p50/p99/max）
+4. 并发处理请求一段时间，输出ns/op、B/op、allocs/op（同样的对比也写成了基准测试：go test -bench Pools ./pkg/bufpool）
+5. sync.Pool在GC时被清空：对象先进入victim缓存，再经过一次GC才真正丢弃
+
+核心概念：
+- 大对象的频繁分配会推高堆的增长速度，GC更频繁，每次GC都要占用CPU并在标记阶段拖慢分配
+- sync.Pool：每个P一个本地缓存，Get/Put几乎无锁；但池中对象随时可能被GC回收，数量不受控制
+- 有界池：用带缓冲的channel存放空闲对象，空闲对象数量有上限，满了就丢弃，不会被GC清空
+- 放进池的应该是*[]byte而不是[]byte：把切片头转换成interface{}本身就要分配
+- 归还前检查容量：被扩容过的超大缓冲区放回池中会一直占着内存
+
+运行方式：go run ./cmd/medium/20_object_pool [-size=65536] [-requests=2000] [-benchtime=1s]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/bufpool"
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// handle 模拟一次请求：取缓冲区，填充数据并计算校验和，归还缓冲区
+func handle(p bufpool.Pool) uint32 {
+	bp := p.Get()
+	b := *bp
+	for i := 0; i < len(b); i += 64 {
+		b[i] = byte(i)
+	}
+	var sum uint32
+	for i := 0; i < len(b); i += 256 {
+		sum += uint32(b[i])
+	}
+	p.Put(bp)
+	return sum
+}
+
+type loadResult struct {
+	elapsed       time.Duration
+	p50, p99, max time.Duration
+	totalAlloc    uint64
+	mallocs       uint64
+	numGC         uint32
+	pauseTotal    time.Duration
+}
+
+// runLoad goroutines个goroutine各处理requests个请求，统计延迟和内存分配
+func runLoad(p bufpool.Pool, goroutines, requests int) loadResult {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	lats := make([][]time.Duration, goroutines)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ds := make([]time.Duration, 0, requests)
+			for i := 0; i < requests; i++ {
+				t := time.Now()
+				handle(p)
+				ds = append(ds, time.Since(t))
+			}
+			lats[g] = ds
+		}(g)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	var all []time.Duration
+	for _, ds := range lats {
+		all = append(all, ds...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	pct := func(q float64) time.Duration { return all[int(q*float64(len(all)-1))] }
+	return loadResult{
+		elapsed:    elapsed,
+		p50:        pct(0.5),
+		p99:        pct(0.99),
+		max:        all[len(all)-1],
+		totalAlloc: after.TotalAlloc - before.TotalAlloc,
+		mallocs:    after.Mallocs - before.Mallocs,
+		numGC:      after.NumGC - before.NumGC,
+		pauseTotal: time.Duration(after.PauseTotalNs - before.PauseTotalNs),
+	}
+}
+
+type benchResult struct {
+	n           int64
+	perOp       time.Duration
+	bytesPerOp  uint64
+	allocsPerOp uint64
+}
+
+// measure GOMAXPROCS个goroutine并发处理请求，持续d时间；与runLoad不同，这里只关心平均每个请求的开销
+func measure(p bufpool.Pool, d time.Duration) benchResult {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var n atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(d)
+	for g := 0; g < runtime.GOMAXPROCS(0); g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				handle(p)
+				n.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	total := max(n.Load(), 1)
+	return benchResult{
+		n:           total,
+		perOp:       elapsed / time.Duration(total),
+		bytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / uint64(total),
+		allocsPerOp: (after.Mallocs - before.Mallocs) / uint64(total),
+	}
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// demoVictim sync.Pool中的对象经过一次GC还在（victim缓存），两次GC后被清空
+func demoVictim(size int) {
+	p := bufpool.NewSyncPool(size)
+	p.Put(p.Get())
+	runtime.GC()
+	b := p.Get()
+	check(fmt.Sprintf("Put后经过1次GC，Get仍然拿到池中的对象（New调用 %d 次）", p.News()), p.News() == 1)
+
+	p.Put(b)
+	runtime.GC()
+	runtime.GC()
+	p.Get()
+	check(fmt.Sprintf("Put后经过2次GC，池已被清空，Get重新调用New（New调用 %d 次）", p.News()), p.News() == 2)
+
+	bounded := bufpool.NewBoundedPool(size, 4)
+	bounded.Put(bounded.Get())
+	runtime.GC()
+	runtime.GC()
+	bounded.Get()
+	hits, _, _ := bounded.Stats()
+	check("有界池中的对象不受GC影响，2次GC后仍然命中", hits == 1)
+
+	big := make([]byte, size*16)
+	bounded.Put(&big)
+	_, _, drops := bounded.Stats()
+	check("容量不对的缓冲区不放回有界池", drops == 1)
+}
+
+func main() {
+	size := flag.Int("size", 64<<10, "每个缓冲区的字节数")
+	requests := flag.Int("requests", 2000, "每个goroutine处理的请求数")
+	benchtime := flag.Duration("benchtime", time.Second, "每个基准测试的运行时间")
+	demoflag.Parse()
+
+	const goroutines = 8
+	fmt.Println("=== 对象池演示 ===")
+	fmt.Printf("GOMAXPROCS=%d, 缓冲区 %dKB, %d个goroutine各处理 %d 个请求\n",
+		runtime.GOMAXPROCS(0), *size>>10, goroutines, *requests)
+
+	fmt.Println("\n1. 负载下的内存分配、GC与延迟:")
+	bounded := bufpool.NewBoundedPool(*size, goroutines)
+	syncPool := bufpool.NewSyncPool(*size)
+	pools := []struct {
+		name string
+		pool bufpool.Pool
+	}{
+		{"不复用", bufpool.NoPool{Size: *size}},
+		{"sync.Pool", syncPool},
+		{"有界池", bounded},
+	}
+	for _, p := range pools {
+		r := runLoad(p.pool, goroutines, *requests)
+		fmt.Printf("  %-10s 耗时 %7v  分配 %8.1fMB %6d次  GC %4d次 暂停 %8v  延迟 p50=%v p99=%v max=%v\n",
+			p.name, r.elapsed.Round(time.Millisecond),
+			float64(r.totalAlloc)/(1<<20), r.mallocs, r.numGC, r.pauseTotal.Round(time.Microsecond),
+			r.p50.Round(time.Microsecond), r.p99.Round(time.Microsecond), r.max.Round(time.Microsecond))
+	}
+	hits, misses, drops := bounded.Stats()
+	fmt.Printf("  sync.Pool调用New %d 次; 有界池命中 %d 次, 未命中 %d 次, 丢弃 %d 次\n",
+		syncPool.News(), hits, misses, drops)
+
+	fmt.Println("\n2. 基准测试（每次操作 = 处理一个请求，GOMAXPROCS个goroutine并发执行）:")
+	for _, p := range pools {
+		r := measure(p.pool, *benchtime)
+		fmt.Printf("  %-10s %8d 次  %8v/op  %8d B/op  %3d allocs/op\n",
+			p.name, r.n, r.perOp, r.bytesPerOp, r.allocsPerOp)
+	}
+
+	fmt.Println("\n3. sync.Pool与GC:")
+	demoVictim(*size)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 不复用时每个请求分配64KB，GC次数和暂停时间随请求数增长；两种池的分配几乎为0")
+	fmt.Println("2. 新分配的64KB要先清零，p50就已经更高；GC标记期间正在分配的goroutine还要协助标记，拉长尾部")
+	fmt.Println("3. sync.Pool无锁且按P分片，适合短期复用的临时对象；它不是连接池，对象可能随GC消失")
+	fmt.Println("4. 有界池的空闲对象数量有上限、不受GC影响，但每次Get/Put都要经过channel的锁")
+	fmt.Println("5. 单核机器上没有真正的并发竞争，多核时sync.Pool的按P缓存比共享channel更有优势")
+	fmt.Println("6. 用-race运行时sync.Pool会故意随机丢弃一部分Put的对象，New调用次数会明显增多")
+}
@@ -0,0 +1,287 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/21_cyclic_barrier/main.go
+主题：循环屏障（CyclicBarrier）
+
+本示例演示：
+1. pkg/barrier 的 CyclicBarrier：Await(ctx)、每一代执行一次的屏障动作、可重复使用
+2. 多轮并行模拟：一维热传导，4个工作者各自计算一段，每轮在屏障处同步，
+   屏障动作交换新旧缓冲区并判断是否收敛；结果与串行计算逐位相同
+3. 屏障损坏：一个工作者卡住，其他工作者等待超时，整代都返回错误而不是永远等待；Reset后继续使用
+4. 屏障动作返回错误（数值发散），所有工作者收到ErrBrokenBarrier并停止
+5. 100轮复用：每一代的到达序号恰好是0..parties-1的一个排列
+
+核心概念：
+- 屏障 vs WaitGroup：WaitGroup是一次性的"等别人做完"，屏障是参与者互相等待，并且每轮自动重置
+- 屏障动作在所有人到达之后、放行之前执行，正好用来做"两轮之间"的串行工作（交换缓冲区、检查收敛）
+- 放行通过关闭channel实现，动作中写入的数据对被放行的goroutine可见（happens-before）
+- 损坏语义：只要一个参与者退出，其他参与者就不可能等齐，必须让所有人都知道
+
+运行方式：go run ./cmd/medium/21_cyclic_barrier [-cells=200] [-epsilon=0.001]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/barrier"
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// Rod 一维热传导：两端温度固定，每轮 next[i] = cur[i] + alpha*(cur[i-1] - 2*cur[i] + cur[i+1])
+type Rod struct {
+	alpha     float64
+	cur, next []float64
+}
+
+func NewRod(n int, alpha float64) *Rod {
+	r := &Rod{alpha: alpha, cur: make([]float64, n), next: make([]float64, n)}
+	r.cur[0], r.next[0] = 100, 100 // 左端加热，右端保持0度
+	return r
+}
+
+// step 计算[lo, hi)这一段，返回本段最大的变化量
+func (r *Rod) step(lo, hi int) float64 {
+	lo, hi = max(lo, 1), min(hi, len(r.cur)-1)
+	var delta float64
+	for i := lo; i < hi; i++ {
+		r.next[i] = r.cur[i] + r.alpha*(r.cur[i-1]-2*r.cur[i]+r.cur[i+1])
+		delta = max(delta, math.Abs(r.next[i]-r.cur[i]))
+	}
+	return delta
+}
+
+func (r *Rod) swap() { r.cur, r.next = r.next, r.cur }
+
+// 模拟参数，cells和epsilon可以用-cells、-epsilon修改
+var (
+	cells   = 200
+	epsilon = 1e-3
+)
+
+const maxRounds = 100000
+
+// simulateSequential 串行版本，作为对照
+func simulateSequential(alpha float64) ([]float64, int) {
+	r := NewRod(cells, alpha)
+	for round := 1; round <= maxRounds; round++ {
+		delta := r.step(0, cells)
+		r.swap()
+		if delta < epsilon {
+			return r.cur, round
+		}
+	}
+	return r.cur, maxRounds
+}
+
+// simulateParallel workers个工作者各负责一段，每轮在屏障处同步
+func simulateParallel(alpha float64, workers int) ([]float64, int, []error) {
+	r := NewRod(cells, alpha)
+	deltas := make([]float64, workers)
+	rounds, done := 0, false
+
+	b := barrier.New(workers, func() error {
+		// 所有工作者都算完了这一轮，由最后到达的工作者执行
+		rounds++
+		r.swap()
+		var delta float64
+		for _, d := range deltas {
+			delta = max(delta, d)
+		}
+		for _, v := range r.cur {
+			if math.IsNaN(v) || math.Abs(v) > 1e6 {
+				return fmt.Errorf("第%d轮数值发散(%.3g)，alpha过大", rounds, v)
+			}
+		}
+		done = delta < epsilon || rounds == maxRounds
+		return nil
+	})
+
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	chunk := (cells + workers - 1) / workers
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				deltas[w] = r.step(w*chunk, (w+1)*chunk)
+				if _, err := b.Await(context.Background()); err != nil {
+					errs[w] = err
+					return
+				}
+				// done由屏障动作在放行前写入，这里读取是安全的
+				if done {
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	return r.cur, rounds, errs
+}
+
+func demoSimulation() {
+	seq, seqRounds := simulateSequential(0.4)
+	par, parRounds, errs := simulateParallel(0.4, 4)
+	same := len(seq) == len(par)
+	for i := range seq {
+		same = same && seq[i] == par[i]
+	}
+	fmt.Printf("  串行: %d轮收敛; 并行(4个工作者): %d轮收敛\n", seqRounds, parRounds)
+	fmt.Printf("  温度分布(每隔40格): ")
+	for i := 0; i < cells; i += 40 {
+		fmt.Printf("%.1f ", par[i])
+	}
+	fmt.Println()
+	check("并行结果与串行逐位相同", same && seqRounds == parRounds && errors.Join(errs...) == nil)
+}
+
+func demoBroken() {
+	const workers = 4
+	b := barrier.New(workers, nil)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			if w == 3 {
+				time.Sleep(100 * time.Millisecond) // 卡住的工作者，到达时屏障早已损坏
+			}
+			// 每个工作者最多等20ms，第一个超时的工作者弄坏屏障
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(20+10*w)*time.Millisecond)
+			defer cancel()
+			_, errs[w] = b.Await(ctx)
+		}(w)
+	}
+	wg.Wait()
+	for w, err := range errs {
+		fmt.Printf("  工作者%d: %v\n", w, err)
+	}
+	check("第一个超时的工作者返回context.DeadlineExceeded", errors.Is(errs[0], context.DeadlineExceeded) && !errors.Is(errs[0], barrier.ErrBrokenBarrier))
+	check("其余等待者返回ErrBrokenBarrier，原因是超时",
+		errors.Is(errs[1], barrier.ErrBrokenBarrier) && errors.Is(errs[1], context.DeadlineExceeded) && errors.Is(errs[2], barrier.ErrBrokenBarrier))
+	check("卡住后才到达的工作者立即返回ErrBrokenBarrier", errors.Is(errs[3], barrier.ErrBrokenBarrier))
+
+	b.Reset()
+	ok := 0
+	var mu sync.Mutex
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.Await(context.Background()); err == nil {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	check(fmt.Sprintf("Reset后屏障恢复可用，%d个工作者都通过", ok), ok == workers && !b.Broken())
+}
+
+func demoActionError() {
+	_, rounds, errs := simulateParallel(0.6, 4)
+	fmt.Printf("  alpha=0.6: 运行 %d 轮后停止\n", rounds)
+	fmt.Printf("  工作者0: %v\n", errs[0])
+	all := true
+	for _, err := range errs {
+		all = all && errors.Is(err, barrier.ErrBrokenBarrier)
+	}
+	check("屏障动作返回错误，4个工作者都收到ErrBrokenBarrier并退出", all)
+}
+
+func demoReuse() {
+	const workers, rounds = 5, 100
+	seen := make([]bool, workers)
+	generations, bad := 0, 0
+	var mu sync.Mutex
+
+	// 每一代的序号在放行之后才写入seen，所以第k+1代的屏障动作检查的是第k代
+	b := barrier.New(workers, func() error {
+		generations++
+		if generations == 1 {
+			return nil
+		}
+		for i := range seen {
+			if !seen[i] {
+				bad++
+			}
+			seen[i] = false
+		}
+		return nil
+	})
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				idx, err := b.Await(context.Background())
+				if err != nil {
+					return
+				}
+				// 下一代的屏障动作要等所有人再次到达，这里写入seen不会与动作冲突
+				mu.Lock()
+				if seen[idx] {
+					bad++
+				}
+				seen[idx] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// 最后一代之后没有动作再检查seen，在这里检查
+	for _, s := range seen {
+		if !s {
+			bad++
+		}
+	}
+	check(fmt.Sprintf("%d代，每一代的到达序号都是0..%d的排列（异常 %d 次）", generations, workers-1, bad),
+		generations == rounds && bad == 0)
+}
+
+func main() {
+	flag.IntVar(&cells, "cells", cells, "一维热传导的格数")
+	flag.Float64Var(&epsilon, "epsilon", epsilon, "最大变化量小于它时认为收敛")
+	demoflag.Parse()
+
+	fmt.Println("=== 循环屏障演示 ===")
+
+	fmt.Printf("\n1. 一维热传导（%d格，每轮同步一次，最大变化量小于%g时收敛）\n", cells, epsilon)
+	demoSimulation()
+
+	fmt.Println("\n2. 屏障损坏：工作者3卡住100ms，其他工作者最多等20~40ms")
+	demoBroken()
+
+	fmt.Println("\n3. 屏障动作返回错误")
+	demoActionError()
+
+	fmt.Println("\n4. 复用同一个屏障100轮")
+	demoReuse()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 每轮计算时只读cur、只写自己那段next，不需要加锁；交换缓冲区放在屏障动作里串行执行")
+	fmt.Println("2. 屏障动作写入的done在放行后被所有工作者读取，关闭channel提供了happens-before保证")
+	fmt.Println("3. 一个参与者超时会让整代损坏：等待者拿到的错误里带着损坏的原因")
+	fmt.Println("4. 损坏后的屏障在Reset之前一直拒绝Await，防止剩下的参与者凑不齐人而永远等待")
+	fmt.Println("5. 单核机器上并行版本不会更快，这里关注的是每轮同步的正确性")
+}
@@ -0,0 +1,273 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/22_deadlock_detector/main.go
+主题：运行时死锁与阻塞goroutine检测
+
+本示例演示：
+1. 经典的加锁顺序死锁：一个goroutine先锁A再锁B，另一个先锁B再锁A
+2. 局部channel死锁：消费者出错提前返回，生产者永远阻塞在发送上，等待它们的goroutine也跟着卡住
+3. 用 pkg/deadlock 定期采样goroutine栈，找出阻塞超过阈值的goroutine并打印疑似死锁报告
+4. 误报与排除：空闲等待任务的工作者也会被报告，可以用Ignore排除
+5. 短暂的锁竞争不会被报告：只有连续多次采样栈都没变才算阻塞
+
+核心概念：
+- Go运行时只在"所有goroutine都睡眠"时报 fatal error: all goroutines are asleep - deadlock!
+  程序里还有别的goroutine在运行（这里就是检测器自己）时，局部死锁不会被发现
+- runtime.Stack(buf, true) 输出中方括号里的状态：chan send、chan receive、select、sync.Mutex.Lock 等
+- 栈上看不出锁被谁持有，报告只能给出"谁在哪里等"，互相等待的关系要结合阻塞位置判断
+- 检测是启发式的：阈值太短会把正常等待当成死锁，太长则发现得晚
+
+运行方式：go run ./cmd/medium/22_deadlock_detector [-interval=20ms] [-threshold=150ms]
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/deadlock"
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/leakcheck"
+)
+
+// 检测参数，可以用-interval、-threshold修改
+var (
+	interval  = 20 * time.Millisecond
+	threshold = 150 * time.Millisecond
+)
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// watch 启动检测器，只关注base之后创建的goroutine（前面场景故意留下的死锁不再重复报告）
+// 返回第一份报告，wait内没有报告时返回false
+func watch(base leakcheck.Snapshot, ignore func(deadlock.Blocked) bool, wait time.Duration) (deadlock.Report, bool) {
+	reports := make(chan deadlock.Report, 1)
+	d := deadlock.Start(deadlock.Config{
+		Interval:  interval,
+		Threshold: threshold,
+		Ignore: func(b deadlock.Blocked) bool {
+			if _, old := base[b.Goroutine.ID]; old {
+				return true
+			}
+			return ignore != nil && ignore(b)
+		},
+		OnReport: func(r deadlock.Report) {
+			select {
+			case reports <- r:
+			default:
+			}
+		},
+	})
+	defer d.Stop()
+
+	select {
+	case r := <-reports:
+		return r, true
+	case <-time.After(wait):
+		return deadlock.Report{}, false
+	}
+}
+
+// find 报告中阻塞位置包含fn的goroutine
+func find(r deadlock.Report, fn string) []deadlock.Blocked {
+	var found []deadlock.Blocked
+	for _, b := range r.Blocked {
+		if strings.Contains(b.Site, fn) {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+func printReport(r deadlock.Report) {
+	for _, line := range strings.Split(r.String(), "\n") {
+		fmt.Println("  |", line)
+	}
+}
+
+// Account 带锁的账户
+type Account struct {
+	mu      sync.Mutex
+	name    string
+	balance int
+}
+
+// transfer 错误示范：按参数顺序加锁，两个方向相反的转账同时进行就会死锁
+func transfer(from, to *Account, amount int) {
+	from.mu.Lock()
+	defer from.mu.Unlock()
+	time.Sleep(10 * time.Millisecond) // 放大两次加锁之间的窗口，让死锁必然发生
+	to.mu.Lock()
+	defer to.mu.Unlock()
+	from.balance -= amount
+	to.balance += amount
+}
+
+func demoLockOrder() {
+	base := leakcheck.Take()
+	a, b := &Account{name: "A", balance: 100}, &Account{name: "B", balance: 100}
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); transfer(a, b, 10) }()
+		go func() { defer wg.Done(); transfer(b, a, 20) }()
+		wg.Wait()
+		close(done)
+	}()
+
+	r, ok := watch(base, nil, time.Second)
+	check("检测器在1秒内给出了报告", ok)
+	if !ok {
+		return
+	}
+	printReport(r)
+	check("两个转账goroutine都阻塞在transfer中的加锁上", len(find(r, "main.transfer")) == 2)
+	check("等待它们的goroutine阻塞在wg.Wait上", len(find(r, "main.demoLockOrder.func1")) == 1)
+	select {
+	case <-done:
+		check("转账意外完成", false)
+	default:
+		fmt.Println("  （这几个goroutine永远不会结束；修复方法是所有地方都按固定顺序加锁，例如按账户名排序）")
+	}
+}
+
+var errBadRecord = errors.New("坏记录")
+
+// consume 错误示范：遇到坏记录直接返回，没有继续排空channel，也没有通知生产者
+func consume(records <-chan int) error {
+	for r := range records {
+		if r == 3 {
+			return errBadRecord
+		}
+	}
+	return nil
+}
+
+func produce(records chan<- int) {
+	defer close(records)
+	for i := 1; i <= 10; i++ {
+		records <- i
+	}
+}
+
+func demoChannel() {
+	base := leakcheck.Take()
+	records := make(chan int)
+	errc := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); produce(records) }()
+	go func() { errc <- consume(records) }()
+	go func() {
+		wg.Wait() // 等待生产者结束后再汇总，生产者永远结束不了
+		fmt.Println("  生产者已结束")
+	}()
+
+	fmt.Printf("  消费者返回: %v\n", <-errc)
+	r, ok := watch(base, nil, time.Second)
+	check("检测器在1秒内给出了报告", ok)
+	if !ok {
+		return
+	}
+	printReport(r)
+	producer := find(r, "main.produce")
+	check("生产者阻塞在chan send上", len(producer) == 1 && producer[0].Goroutine.State == "chan send")
+	check("汇总goroutine阻塞在wg.Wait上", len(find(r, "main.demoChannel.func3")) == 1)
+	fmt.Println("  （修复：消费者出错时通过ctx通知生产者停止，或者继续把channel读完）")
+}
+
+// idleWorker 正常的工作者：没有任务时阻塞在接收上，这不是死锁
+func idleWorker(jobs <-chan func()) {
+	for job := range jobs {
+		job()
+	}
+}
+
+func demoIgnore() {
+	jobs := make(chan func())
+	defer close(jobs)
+
+	base := leakcheck.Take()
+	for i := 0; i < 3; i++ {
+		go idleWorker(jobs)
+	}
+	r, ok := watch(base, nil, time.Second)
+	idle := len(find(r, "main.idleWorker"))
+	check(fmt.Sprintf("不排除时，3个空闲工作者被报告为阻塞: %d", idle), ok && idle == 3)
+
+	_, ok = watch(base, func(b deadlock.Blocked) bool {
+		return strings.Contains(b.Site, "main.idleWorker")
+	}, 400*time.Millisecond)
+	check("用Ignore排除idleWorker后没有报告", !ok)
+}
+
+func demoContention() {
+	base := leakcheck.Take()
+	var mu sync.Mutex
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				mu.Lock()
+				time.Sleep(3 * time.Millisecond) // 持锁期间其他goroutine在等锁
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond) // 锁外的工作
+			}
+		}()
+	}
+
+	_, ok := watch(base, nil, 500*time.Millisecond)
+	close(stop)
+	wg.Wait()
+	check("4个goroutine竞争同一把锁500ms，但没有一个被报告", !ok)
+}
+
+func main() {
+	flag.DurationVar(&interval, "interval", interval, "采样goroutine栈的间隔")
+	flag.DurationVar(&threshold, "threshold", threshold, "阻塞超过多久报告")
+	demoflag.Parse()
+
+	fmt.Println("=== 死锁与阻塞goroutine检测演示 ===")
+	fmt.Printf("采样间隔 %v，阻塞阈值 %v\n", interval, threshold)
+
+	fmt.Println("\n1. 加锁顺序死锁（A->B 与 B->A）")
+	demoLockOrder()
+
+	fmt.Println("\n2. 局部channel死锁（消费者提前返回）")
+	demoChannel()
+
+	fmt.Println("\n3. 空闲工作者与Ignore")
+	demoIgnore()
+
+	fmt.Println("\n4. 短暂的锁竞争")
+	demoContention()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 前两个场景的goroutine永远卡住，但main一直在运行，Go运行时不会报deadlock")
+	fmt.Println("2. 报告按阻塞位置分组：两个goroutine卡在同一个transfer的加锁上，是加锁顺序问题的典型特征")
+	fmt.Println("3. 栈里只有\"在等什么\"，没有\"被谁占着\"；sync.Mutex不记录持有者，要靠阻塞位置推断")
+	fmt.Println("4. 阻塞不等于死锁：空闲工作者、长轮询都会长时间阻塞，用Ignore或更长的阈值避免误报")
+	fmt.Println("5. 竞争中的goroutine在两次等锁之间会运行，采样到非阻塞状态时计时重新开始，所以不会被报告")
+	fmt.Println("6. 局限：一个goroutine如果每次被采样时恰好都阻塞在同一行，就无法和一直阻塞区分开")
+}
@@ -0,0 +1,247 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/23_multi_tenant_pool/main.go
+主题：多租户goroutine池与吵闹邻居隔离
+
+本示例演示：
+1. 对照：所有租户共用一个FIFO队列，一个租户突发提交大量任务，其他租户的任务排在后面等很久
+2. pkg/pool.TenantPool：任务带租户ID，空闲工作者在各租户之间公平选择，小租户几乎不受影响
+3. 配额：MaxWorkers限制一个租户最多占用几个工作者，MaxQueue限制排队数，超出时只拒绝这个租户
+4. 权重：付费租户权重3、免费租户权重1，都满载时完成的任务数约为3:1
+5. 每个租户的统计：提交、拒绝、完成、峰值并发、平均和最长排队时间；Stop超时取消排队任务
+
+核心概念：
+- 吵闹的邻居（noisy neighbor）：共享资源时，一个用户的负载拖慢所有人
+- 隔离的两个层次：配额限制一个租户最多能用多少，公平调度决定资源紧张时先给谁
+- 公平调度：选择"正在执行数/权重"最小的租户，相同时选最久没被调度的
+- 排队上限按租户计算，拒绝也按租户发生，突发流量的代价由制造它的租户承担
+
+运行方式：go run ./cmd/medium/23_multi_tenant_pool [-workers=4] [-task-time=10ms]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/pool"
+)
+
+// 池参数，可以用-workers、-task-time修改
+var (
+	workers  = 4
+	taskTime = 10 * time.Millisecond
+)
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// waits 记录每个租户任务从提交到开始执行的等待时间
+type waits struct {
+	mu sync.Mutex
+	m  map[string][]time.Duration
+}
+
+func (w *waits) record(tenant string, d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.m == nil {
+		w.m = make(map[string][]time.Duration)
+	}
+	w.m[tenant] = append(w.m[tenant], d)
+}
+
+func (w *waits) avg(tenant string) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ds := w.m[tenant]
+	if len(ds) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	return sum / time.Duration(len(ds))
+}
+
+// task 模拟一个耗时taskTime的请求，开始执行时记录等待时间
+func task(w *waits, tenant string) func(context.Context) error {
+	submitted := time.Now()
+	return func(ctx context.Context) error {
+		w.record(tenant, time.Since(submitted))
+		select {
+		case <-time.After(taskTime):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// submitter 对照组和多租户池的统一提交接口
+type submitter func(tenant string, fn func(context.Context) error) error
+
+// workload 吵闹的租户noisy突发提交120个任务，20ms后小租户a、b各提交8个
+func workload(submit submitter, w *waits) {
+	for i := 0; i < 120; i++ {
+		submit("noisy", task(w, "noisy"))
+	}
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 8; i++ {
+		submit("a", task(w, "a"))
+		submit("b", task(w, "b"))
+	}
+}
+
+// runFIFO 对照组：所有租户共用一个FIFO队列
+func runFIFO() *waits {
+	w := &waits{}
+	queue := make(chan func(context.Context) error, 1000)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fn := range queue {
+				fn(context.Background())
+			}
+		}()
+	}
+	workload(func(_ string, fn func(context.Context) error) error {
+		queue <- fn
+		return nil
+	}, w)
+	close(queue)
+	wg.Wait()
+	return w
+}
+
+func printStats(p *pool.TenantPool) {
+	stats := p.Stats()
+	fmt.Println("  租户     提交   拒绝   完成 峰值并发   平均排队   最长排队")
+	for _, id := range p.Tenants() {
+		s := stats[id]
+		fmt.Printf("  %-6s %6d %6d %6d %8d %10v %10v\n", id, s.Submitted, s.Rejected, s.Completed, s.PeakRun,
+			s.AvgWait().Round(time.Millisecond), s.MaxWait.Round(time.Millisecond))
+	}
+}
+
+func demoNoisyNeighbor() {
+	fifo := runFIFO()
+	fmt.Printf("  共享FIFO队列: noisy平均排队 %v, a %v, b %v\n",
+		fifo.avg("noisy").Round(time.Millisecond), fifo.avg("a").Round(time.Millisecond), fifo.avg("b").Round(time.Millisecond))
+
+	w := &waits{}
+	p := pool.NewTenantPool(workers, pool.TenantConfig{})
+	p.SetTenant("noisy", pool.TenantConfig{MaxWorkers: 2})
+	workload(p.Submit, w)
+	p.Stop(context.Background())
+	fmt.Printf("  多租户池:     noisy平均排队 %v, a %v, b %v\n",
+		w.avg("noisy").Round(time.Millisecond), w.avg("a").Round(time.Millisecond), w.avg("b").Round(time.Millisecond))
+	printStats(p)
+
+	check("多租户池中小租户a的平均排队时间不到共享队列的1/4", w.avg("a") < fifo.avg("a")/4)
+	check("noisy最多同时占用2个工作者", p.Stats()["noisy"].PeakRun <= 2)
+}
+
+func demoQuota() {
+	w := &waits{}
+	p := pool.NewTenantPool(workers, pool.TenantConfig{MaxQueue: 100})
+	p.SetTenant("noisy", pool.TenantConfig{MaxWorkers: 2, MaxQueue: 30})
+
+	var rejected int
+	for i := 0; i < 120; i++ {
+		if errors.Is(p.Submit("noisy", task(w, "noisy")), pool.ErrTenantQueueFull) {
+			rejected++
+		}
+	}
+	for i := 0; i < 20; i++ {
+		p.Submit("a", task(w, "a"))
+	}
+	p.Stop(context.Background())
+	printStats(p)
+
+	stats := p.Stats()
+	check(fmt.Sprintf("noisy排队满后被拒绝 %d 个，返回ErrTenantQueueFull", rejected), rejected > 0 && stats["noisy"].Rejected == int64(rejected))
+	check("租户a没有被拒绝，全部完成", stats["a"].Rejected == 0 && stats["a"].Completed == 20)
+}
+
+func demoWeights() {
+	p := pool.NewTenantPool(workers, pool.TenantConfig{})
+	p.SetTenant("gold", pool.TenantConfig{Weight: 3})
+	p.SetTenant("free", pool.TenantConfig{Weight: 1})
+	w := &waits{}
+	for i := 0; i < 200; i++ {
+		p.Submit("gold", task(w, "gold"))
+		p.Submit("free", task(w, "free"))
+	}
+
+	// 两个租户都有大量排队时观察一段时间，然后取消剩余任务
+	time.Sleep(300 * time.Millisecond)
+	stats := p.Stats()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	p.Stop(ctx)
+
+	gold, free := stats["gold"].Completed, stats["free"].Completed
+	fmt.Printf("  300ms内完成: gold %d, free %d (峰值并发 gold %d, free %d)\n", gold, free, stats["gold"].PeakRun, stats["free"].PeakRun)
+	ratio := float64(gold) / float64(max(free, 1))
+	check(fmt.Sprintf("完成数之比约为权重之比3:1，实际 %.2f", ratio), ratio > 2.5 && ratio < 3.5)
+	check("免费租户没有被饿死", free > 0)
+}
+
+func demoStop() {
+	p := pool.NewTenantPool(2, pool.TenantConfig{})
+	w := &waits{}
+	for i := 0; i < 50; i++ {
+		p.Submit("batch", task(w, "batch"))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := p.Stop(ctx)
+	s := p.Stats()["batch"]
+	fmt.Printf("  Stop: %v; 完成 %d（其中被取消的执行 %d 个）, 没有执行就取消 %d\n", err, s.Completed, s.Failed, s.Canceled)
+	check("Stop超时返回context.DeadlineExceeded", errors.Is(err, context.DeadlineExceeded))
+	check("每个任务要么执行了要么被取消", s.Completed+s.Canceled == 50)
+	check("停止后提交返回ErrPoolStopped", errors.Is(p.Submit("batch", task(w, "batch")), pool.ErrPoolStopped))
+}
+
+func main() {
+	flag.IntVar(&workers, "workers", workers, "池中的工作者数量")
+	flag.DurationVar(&taskTime, "task-time", taskTime, "每个任务的耗时")
+	demoflag.Parse()
+
+	fmt.Println("=== 多租户goroutine池演示 ===")
+	fmt.Printf("%d个工作者，每个任务耗时 %v\n", workers, taskTime)
+
+	fmt.Println("\n1. 吵闹的邻居：noisy突发提交120个任务，20ms后a、b各提交8个")
+	demoNoisyNeighbor()
+
+	fmt.Println("\n2. 配额：noisy最多2个工作者、最多排队30个")
+	demoQuota()
+
+	fmt.Println("\n3. 权重：gold权重3，free权重1，各提交200个任务")
+	demoWeights()
+
+	fmt.Println("\n4. 超时停止")
+	demoStop()
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 共享FIFO中a、b的任务排在noisy的120个任务后面，等待时间由别人的负载决定")
+	fmt.Println("2. 多租户池里a、b一到就能拿到空闲工作者：noisy受配额限制，空出的工作者留给了其他租户")
+	fmt.Println("3. 只设配额不设公平调度时，空闲工作者可能总是先挑到noisy；只设公平调度不设配额时，noisy仍可能占满所有工作者")
+	fmt.Println("4. 拒绝按租户发生：noisy的排队满了，a照常提交和执行")
+	fmt.Println("5. 权重决定资源紧张时的分配比例，权重低的租户变慢但不会被饿死")
+}
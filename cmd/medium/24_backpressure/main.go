@@ -0,0 +1,365 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/24_backpressure/main.go
+主题：端到端背压与拉模式（基于信用的流控）
+
+本示例演示：
+1. 三级流水线：数据源 -> 解析（几乎不耗时）-> 富化（每条1ms）-> 下游，下游只需要前500条就断开
+2. 推模式 + 带缓冲channel + 满了就丢：生产者从不阻塞，但大量数据在半路被丢弃
+3. 推模式 + 带缓冲channel + 阻塞发送：不丢数据，但每一级的缓冲都被填满，
+   数据源远远跑在消费者前面，在途数据多、端到端延迟高，下游断开时已经读出的数据全部白费
+4. 拉模式：下游用Request(n)向上游授予信用，上游只在有信用时读取和发送；
+   信用逐级向上传递，数据源按下游的实际消费速度读取
+5. 对比每种方式的：送达、丢弃、数据源读取次数、最大在途数、平均和最大端到端延迟
+
+核心概念：
+- 背压：让慢的消费者能够限制快的生产者，否则数据只能被丢弃或在某处无限堆积
+- 阻塞的channel本身也是一种背压，但它只作用于相邻的两级，缓冲区越大反应越迟钝
+- 信用（credit/demand）：下游声明"我还能接收n个"，上游最多发送n个，
+  这是Reactive Streams、HTTP/2、gRPC流控的共同思路
+- 补货水位：不是每消费一个就请求一个，而是消费掉一半窗口时一次请求一批，减少信令开销
+- 在途数据量 ≈ 各级窗口之和，与数据源速度无关，端到端延迟因此有上界
+
+运行方式：go run ./cmd/medium/24_backpressure [-total=2000] [-take=500] [-buffer=100] [-window=16] [-enrich=1ms]
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// 流水线参数，除parseTime外都可以用同名的命令行参数修改
+var (
+	totalRecords = 2000
+	takeRecords  = 500 // 下游只需要这么多
+	pushBuffer   = 100 // 推模式每一级channel的缓冲
+	pullWindow   = 16  // 拉模式每一级的信用窗口
+	enrichTime   = time.Millisecond
+)
+
+const parseTime = 0 // 解析只是转换格式，几乎不耗时
+
+type record struct {
+	id     int
+	readAt time.Time
+}
+
+// source 可以按需读取的数据源，例如数据库游标或文件
+type source struct {
+	next  int
+	reads atomic.Int64
+}
+
+func (s *source) Next() (record, bool) {
+	if s.next >= totalRecords {
+		return record{}, false
+	}
+	s.next++
+	s.reads.Add(1)
+	return record{id: s.next, readAt: time.Now()}, true
+}
+
+// work 模拟每条数据的处理耗时
+func work(d time.Duration) {
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// result 一次运行的统计
+type result struct {
+	delivered, dropped int64
+	reads              int64
+	maxInFlight        int64
+	avgLatency         time.Duration
+	maxLatency         time.Duration
+	elapsed            time.Duration
+}
+
+// sink 统计下游收到的数据，收满takeRecords条后返回
+type sink struct {
+	src      *source
+	dropped  *atomic.Int64
+	start    time.Time
+	res      result
+	totalLat time.Duration
+}
+
+func newSink(src *source, dropped *atomic.Int64) *sink {
+	return &sink{src: src, dropped: dropped, start: time.Now()}
+}
+
+// receive 记录一条数据，返回是否已经收够
+func (s *sink) receive(r record) bool {
+	lat := time.Since(r.readAt)
+	s.res.delivered++
+	s.totalLat += lat
+	s.res.maxLatency = max(s.res.maxLatency, lat)
+	// 在途数：已从数据源读出、既没送达也没被丢弃的数据
+	inFlight := s.src.reads.Load() - s.res.delivered - s.dropped.Load()
+	s.res.maxInFlight = max(s.res.maxInFlight, inFlight)
+	return s.res.delivered >= int64(takeRecords)
+}
+
+func (s *sink) finish() result {
+	s.res.elapsed = time.Since(s.start)
+	s.res.reads = s.src.reads.Load()
+	s.res.dropped = s.dropped.Load()
+	if s.res.delivered > 0 {
+		s.res.avgLatency = s.totalLat / time.Duration(s.res.delivered)
+	}
+	return s.res
+}
+
+// ---------- 推模式 ----------
+
+// runPush 每一级用带缓冲的channel连接；drop为true时channel满了就丢弃，否则阻塞发送
+func runPush(drop bool) result {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := &source{}
+	var dropped atomic.Int64
+
+	send := func(ch chan<- record, r record) bool {
+		if drop {
+			select {
+			case ch <- r:
+			default:
+				dropped.Add(1)
+			}
+			return true
+		}
+		select {
+		case ch <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	stage := func(in <-chan record, d time.Duration) <-chan record {
+		out := make(chan record, pushBuffer)
+		go func() {
+			defer close(out)
+			for r := range in {
+				work(d)
+				if !send(out, r) {
+					return
+				}
+			}
+		}()
+		return out
+	}
+
+	raw := make(chan record, pushBuffer)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(raw)
+		for {
+			r, ok := src.Next()
+			if !ok || !send(raw, r) {
+				return
+			}
+		}
+	}()
+	out := stage(stage(raw, parseTime), enrichTime)
+
+	s := newSink(src, &dropped)
+	for r := range out {
+		if s.receive(r) {
+			break
+		}
+	}
+	res := s.finish()
+	cancel()
+	for range out { // 排空，让上游退出
+	}
+	wg.Wait()
+	return res
+}
+
+// ---------- 拉模式 ----------
+
+// demand 下游授予的、还没用掉的信用
+type demand struct {
+	n      atomic.Int64
+	notify chan struct{} // 信用从0变为正数时唤醒上游
+}
+
+func newDemand() *demand { return &demand{notify: make(chan struct{}, 1)} }
+
+func (d *demand) add(n int64) {
+	d.n.Add(n)
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take 等到有信用时用掉一个，ctx结束时返回false
+func (d *demand) take(ctx context.Context) bool {
+	for {
+		if n := d.n.Load(); n > 0 {
+			if d.n.CompareAndSwap(n, n-1) {
+				return true
+			}
+			continue
+		}
+		select {
+		case <-d.notify:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Flow 拉模式的流：下游调用Request授予信用，上游在有信用时才产生数据
+// Items的容量等于窗口，下游保证未满足的请求不超过窗口，所以上游发送永远不会阻塞
+type Flow struct {
+	Items  <-chan record
+	demand *demand
+}
+
+func (f *Flow) Request(n int64) { f.demand.add(n) }
+
+// pullSource 数据源只在有信用时读取下一条
+func pullSource(ctx context.Context, src *source, window int) *Flow {
+	items := make(chan record, window)
+	d := newDemand()
+	go func() {
+		defer close(items)
+		for d.take(ctx) {
+			r, ok := src.Next()
+			if !ok {
+				return
+			}
+			items <- r
+		}
+	}()
+	return &Flow{Items: items, demand: d}
+}
+
+// subscriber 下游一侧的补货逻辑：先请求一个窗口，每消费掉半个窗口再请求半个窗口
+type subscriber struct {
+	up       *Flow
+	window   int64
+	consumed int64
+}
+
+func subscribe(up *Flow, window int) *subscriber {
+	up.Request(int64(window))
+	return &subscriber{up: up, window: int64(window)}
+}
+
+func (s *subscriber) next(ctx context.Context) (record, bool) {
+	select {
+	case r, ok := <-s.up.Items:
+		if !ok {
+			return record{}, false
+		}
+		s.consumed++
+		if s.consumed >= s.window/2 {
+			s.up.Request(s.consumed)
+			s.consumed = 0
+		}
+		return r, true
+	case <-ctx.Done():
+		return record{}, false
+	}
+}
+
+// pullStage 中间级：既是上游的订阅者，又是下游的数据源；只在下游有信用时才从上游取数据
+func pullStage(ctx context.Context, up *Flow, d time.Duration, window int) *Flow {
+	items := make(chan record, window)
+	dm := newDemand()
+	go func() {
+		defer close(items)
+		sub := subscribe(up, window)
+		for dm.take(ctx) {
+			r, ok := sub.next(ctx)
+			if !ok {
+				return
+			}
+			work(d)
+			items <- r
+		}
+	}()
+	return &Flow{Items: items, demand: dm}
+}
+
+func runPull() result {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src := &source{}
+	var dropped atomic.Int64 // 拉模式不丢数据，恒为0
+
+	out := pullStage(ctx, pullStage(ctx, pullSource(ctx, src, pullWindow), parseTime, pullWindow), enrichTime, pullWindow)
+	s := newSink(src, &dropped)
+	sub := subscribe(out, pullWindow)
+	for {
+		r, ok := sub.next(ctx)
+		if !ok || s.receive(r) {
+			break
+		}
+	}
+	return s.finish() // 下游断开后不再授予信用，上游自然停下
+}
+
+func printResult(name string, r result) {
+	fmt.Printf("  %-18s 送达 %4d  丢弃 %4d  源读取 %4d  最大在途 %4d  延迟 平均 %-8v 最大 %-8v 耗时 %v\n",
+		name, r.delivered, r.dropped, r.reads, r.maxInFlight,
+		r.avgLatency.Round(100*time.Microsecond), r.maxLatency.Round(100*time.Microsecond), r.elapsed.Round(time.Millisecond))
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+func main() {
+	flag.IntVar(&totalRecords, "total", totalRecords, "数据源的记录数")
+	flag.IntVar(&takeRecords, "take", takeRecords, "下游取多少条后断开")
+	flag.IntVar(&pushBuffer, "buffer", pushBuffer, "推模式每一级channel的缓冲")
+	flag.IntVar(&pullWindow, "window", pullWindow, "拉模式每一级的信用窗口")
+	flag.DurationVar(&enrichTime, "enrich", enrichTime, "富化每条记录的耗时")
+	demoflag.Parse()
+
+	fmt.Println("=== 端到端背压演示 ===")
+	fmt.Printf("数据源 %d 条，解析几乎不耗时，富化 %v/条，下游取前 %d 条后断开\n", totalRecords, enrichTime, takeRecords)
+	fmt.Printf("推模式每级缓冲 %d，拉模式每级信用窗口 %d\n\n", pushBuffer, pullWindow)
+
+	dropRes := runPush(true)
+	printResult("推模式(满了丢弃)", dropRes)
+	blockRes := runPush(false)
+	printResult("推模式(阻塞发送)", blockRes)
+	pullRes := runPull()
+	printResult("拉模式(信用流控)", pullRes)
+
+	fmt.Println()
+	check(fmt.Sprintf("丢弃模式丢掉了 %d 条数据，下游没能收满%d条", dropRes.dropped, takeRecords), dropRes.dropped > 0 && dropRes.delivered < int64(takeRecords))
+	check(fmt.Sprintf("阻塞模式不丢数据，但源读取比送达多读了 %d 条（下游断开后白费）", blockRes.reads-blockRes.delivered),
+		blockRes.dropped == 0 && blockRes.reads-blockRes.delivered > int64(2*pushBuffer))
+	check(fmt.Sprintf("拉模式不丢数据，多读的不超过三级窗口之和 %d: 实际 %d", 3*pullWindow, pullRes.reads-pullRes.delivered),
+		pullRes.dropped == 0 && pullRes.delivered == int64(takeRecords) && pullRes.reads-pullRes.delivered <= int64(3*pullWindow))
+	check(fmt.Sprintf("拉模式最大在途 %d 远小于阻塞模式 %d", pullRes.maxInFlight, blockRes.maxInFlight), pullRes.maxInFlight*3 < blockRes.maxInFlight)
+	check("拉模式的平均端到端延迟低于阻塞模式", pullRes.avgLatency < blockRes.avgLatency)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 丢弃模式最快，但数据源和解析级的成果大部分被扔掉，而且是在下游不知情的情况下")
+	fmt.Println("2. 阻塞模式的在途数接近慢速级之前的缓冲之和：数据在缓冲里排队，延迟 ≈ 在途数 × 最慢一级的处理时间")
+	fmt.Println("3. 拉模式的数据源按富化级的速度读取，在途数和延迟由窗口决定，与数据源多快无关")
+	fmt.Println("4. 下游断开时，拉模式的上游只多读了几个窗口的数据；阻塞模式已经读出的数据全部白费")
+	fmt.Println("5. 三种方式总耗时接近：吞吐由最慢的一级决定，背压改变的是数据在哪里等待")
+	fmt.Println("6. 窗口太小时信令多、流水线容易断流；窗口越大越接近阻塞模式，是吞吐和延迟之间的权衡")
+}
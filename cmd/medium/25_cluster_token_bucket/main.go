@@ -0,0 +1,429 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/25_cluster_token_bucket/main.go
+主题：集群同步的令牌桶（本地配额缓存 vs 中心限流器）
+
+本示例演示：
+1. 4个限流实例共享全局限额每秒2000个请求，负载不均匀且热点会转移：
+   前500ms实例0是热点，后500ms实例3是热点
+2. 中心限流器：每次判断都向协调者goroutine请求令牌，精确但每次判断都要等一次往返
+3. 静态切分：每个实例固定分到1/4的速率，不通信、零延迟，但热点实例被限死，空闲实例的份额浪费
+4. 周期同步：每个实例用本地令牌桶判断，定期把消耗和需求上报给协调者，
+   协调者按最大最小公平（水位填充）重新分配每个实例的速率
+5. 同步间隔10ms、50ms、250ms的对比：间隔越长消息越少，但热点转移后适应得越慢
+
+核心概念：
+- 本地缓存的配额让判断变成纯内存操作，代价是全局视图有延迟
+- 准确性有两个方向：超发（放行超过全局上限）和欠发（全局还有余量却拒绝了请求）
+- 水位填充：需求低于平均份额的实例拿到它需要的，剩下的由需求高的实例平分
+- 消息数 ≈ 实例数 × 运行时间 / 同步间隔，与请求量无关；中心限流器的消息数与请求量成正比
+
+运行方式：go run ./cmd/medium/25_cluster_token_bucket [-rate=2000] [-burst=100] [-instances=4] [-rtt=1ms] [-hot=3000] [-cold=200]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// 集群和负载参数，可以用同名的命令行参数修改
+var (
+	globalRate  = 2000.0 // 全局每秒令牌数
+	globalBurst = 100.0
+	instances   = 4
+	phase       = 500 * time.Millisecond // 每个热点阶段的时长
+	rtt         = time.Millisecond       // 实例与协调者之间的往返延迟
+	hotLoad     = 3000.0                 // 热点实例每秒请求数
+	coldLoad    = 200.0                  // 其他实例每秒请求数
+)
+
+const (
+	tick        = 2 * time.Millisecond  // 实例产生请求的间隔
+	bucketWidth = 50 * time.Millisecond // 统计准入数的时间桶
+)
+
+// load 实例id在时刻elapsed的每秒请求数
+func load(id int, elapsed time.Duration) float64 {
+	hot := 0
+	if elapsed >= phase {
+		hot = instances - 1
+	}
+	if id == hot {
+		return hotLoad
+	}
+	return coldLoad
+}
+
+// bucket 令牌桶，速率和容量可以在运行中调整
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate, burst float64) *bucket {
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take 最多取n个令牌，返回取到的个数
+func (b *bucket) take(n int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	got := min(n, int(b.tokens))
+	b.tokens -= float64(got)
+	return got
+}
+
+func (b *bucket) set(rate, burst float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.rate, b.burst = rate, burst
+	b.tokens = math.Min(b.tokens, burst)
+}
+
+// Limiter 一个实例上的限流器：请求n个，返回放行的个数
+type Limiter interface {
+	AllowN(n int) int
+}
+
+// Strategy 一种集群限流方式：为每个实例创建Limiter，返回协调者消息计数和停止函数
+type Strategy struct {
+	Name  string
+	Setup func() (limiters []Limiter, messages func() int64, stop func())
+}
+
+// ---------- 中心限流器 ----------
+
+type takeRequest struct {
+	n     int
+	reply chan int
+}
+
+type centralLimiter struct {
+	requests chan<- takeRequest
+}
+
+func (c centralLimiter) AllowN(n int) int {
+	reply := make(chan int, 1)
+	c.requests <- takeRequest{n: n, reply: reply}
+	got := <-reply
+	time.Sleep(rtt) // 网络往返
+	return got
+}
+
+func central() Strategy {
+	return Strategy{Name: "中心限流器", Setup: func() ([]Limiter, func() int64, func()) {
+		requests := make(chan takeRequest)
+		done := make(chan struct{})
+		var messages atomic.Int64
+		go func() {
+			global := newBucket(globalRate, globalBurst)
+			for {
+				select {
+				case req := <-requests:
+					messages.Add(1)
+					req.reply <- global.take(req.n)
+				case <-done:
+					return
+				}
+			}
+		}()
+		ls := make([]Limiter, instances)
+		for i := range ls {
+			ls[i] = centralLimiter{requests: requests}
+		}
+		return ls, messages.Load, func() { close(done) }
+	}}
+}
+
+// ---------- 静态切分 ----------
+
+type localLimiter struct{ b *bucket }
+
+func (l localLimiter) AllowN(n int) int { return l.b.take(n) }
+
+func static() Strategy {
+	return Strategy{Name: "静态切分", Setup: func() ([]Limiter, func() int64, func()) {
+		ls := make([]Limiter, instances)
+		for i := range ls {
+			ls[i] = localLimiter{b: newBucket(globalRate/float64(instances), globalBurst/float64(instances))}
+		}
+		return ls, func() int64 { return 0 }, func() {}
+	}}
+}
+
+// ---------- 周期同步 ----------
+
+// report 实例上报：距上次上报的请求数和时长
+type report struct {
+	id       int
+	attempts int64
+	elapsed  time.Duration
+	reply    chan float64 // 协调者回复新分配的速率
+}
+
+// syncedLimiter 本地令牌桶，后台goroutine定期上报需求并接收新的速率
+type syncedLimiter struct {
+	b        *bucket
+	attempts atomic.Int64
+}
+
+func (s *syncedLimiter) AllowN(n int) int {
+	s.attempts.Add(int64(n))
+	return s.b.take(n)
+}
+
+// waterFill 最大最小公平分配：按需求从小到大，需求低于剩余平均份额的拿到需求，其余平分剩下的
+func waterFill(total float64, demand []float64) []float64 {
+	idx := make([]int, len(demand))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return demand[idx[a]] < demand[idx[b]] })
+	alloc := make([]float64, len(demand))
+	remaining := total
+	for k, i := range idx {
+		share := remaining / float64(len(idx)-k)
+		alloc[i] = math.Min(demand[i], share)
+		remaining -= alloc[i]
+	}
+	// 需求总和小于总量时，多出来的平分，避免需求突增时所有实例都被限在上一轮的需求上
+	for i := range alloc {
+		alloc[i] += remaining / float64(len(alloc))
+	}
+	return alloc
+}
+
+func synced(interval time.Duration) Strategy {
+	return Strategy{Name: fmt.Sprintf("周期同步(%v)", interval), Setup: func() ([]Limiter, func() int64, func()) {
+		reports := make(chan report)
+		done := make(chan struct{})
+		var messages atomic.Int64
+		var wg sync.WaitGroup
+
+		// 协调者：记录每个实例最近的需求速率，收到上报时重新计算该实例的份额
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			demand := make([]float64, instances)
+			for i := range demand {
+				demand[i] = globalRate / float64(instances)
+			}
+			for {
+				select {
+				case r := <-reports:
+					messages.Add(1)
+					demand[r.id] = float64(r.attempts) / r.elapsed.Seconds()
+					r.reply <- waterFill(globalRate, demand)[r.id]
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		ls := make([]Limiter, instances)
+		for i := range ls {
+			sl := &syncedLimiter{b: newBucket(globalRate/float64(instances), globalBurst/float64(instances))}
+			ls[i] = sl
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				last := time.Now()
+				reply := make(chan float64, 1)
+				for {
+					select {
+					case now := <-ticker.C:
+						r := report{id: id, attempts: sl.attempts.Swap(0), elapsed: now.Sub(last), reply: reply}
+						last = now
+						select {
+						case reports <- r:
+						case <-done:
+							return
+						}
+						rate := <-reply
+						time.Sleep(rtt) // 新的速率经过一次往返才生效
+						sl.b.set(rate, globalBurst*rate/globalRate)
+					case <-done:
+						return
+					}
+				}
+			}(i)
+		}
+		return ls, messages.Load, func() { close(done); wg.Wait() }
+	}}
+}
+
+// ---------- 运行与统计 ----------
+
+type runResult struct {
+	attempts, admitted int64
+	perBucket          []int64 // 每个时间桶的准入数
+	hotAfterShift      int64   // 热点转移后第一个100ms内新热点实例的准入数
+	avgLatency         time.Duration
+	messages           int64
+}
+
+func run(s Strategy) runResult {
+	limiters, messages, stop := s.Setup()
+	duration := 2 * phase
+	buckets := make([]atomic.Int64, int(duration/bucketWidth)+1)
+	var attempts, admitted, hotAfter, decisions, latency atomic.Int64
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for id, l := range limiters {
+		wg.Add(1)
+		go func(id int, l Limiter) {
+			defer wg.Done()
+			ticker := time.NewTicker(tick)
+			defer ticker.Stop()
+			last, carry := start, 0.0
+			for now := range ticker.C {
+				elapsed := now.Sub(start)
+				if elapsed >= duration {
+					return
+				}
+				// 按负载计算这段时间内到达的请求数，小数部分留到下一次
+				carry += load(id, elapsed) * now.Sub(last).Seconds()
+				last = now
+				n := int(carry)
+				carry -= float64(n)
+				if n == 0 {
+					continue
+				}
+
+				t := time.Now()
+				got := l.AllowN(n)
+				latency.Add(int64(time.Since(t)))
+				decisions.Add(1)
+				attempts.Add(int64(n))
+				admitted.Add(int64(got))
+				at := time.Since(start)
+				buckets[min(int(at/bucketWidth), len(buckets)-1)].Add(int64(got))
+				if id == instances-1 && at >= phase && at < phase+100*time.Millisecond {
+					hotAfter.Add(int64(got))
+				}
+			}
+		}(id, l)
+	}
+	wg.Wait()
+	stop()
+
+	r := runResult{
+		attempts:      attempts.Load(),
+		admitted:      admitted.Load(),
+		hotAfterShift: hotAfter.Load(),
+		messages:      messages(),
+	}
+	if d := decisions.Load(); d > 0 {
+		r.avgLatency = time.Duration(latency.Load() / d)
+	}
+	for i := range buckets {
+		r.perBucket = append(r.perBucket, buckets[i].Load())
+	}
+	return r
+}
+
+// maxWindow 任意连续100ms内的最大准入数
+func (r runResult) maxWindow() int64 {
+	per := int(100 * time.Millisecond / bucketWidth)
+	var best int64
+	for i := 0; i+per <= len(r.perBucket); i++ {
+		var sum int64
+		for _, v := range r.perBucket[i : i+per] {
+			sum += v
+		}
+		best = max(best, sum)
+	}
+	return best
+}
+
+// pad 按显示宽度补齐空格，中文字符占两列
+func pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return s + strings.Repeat(" ", max(width-w, 0))
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+func main() {
+	flag.Float64Var(&globalRate, "rate", globalRate, "全局每秒令牌数")
+	flag.Float64Var(&globalBurst, "burst", globalBurst, "全局突发容量")
+	flag.IntVar(&instances, "instances", instances, "限流实例数量")
+	flag.DurationVar(&phase, "phase", phase, "每个热点阶段的时长")
+	flag.DurationVar(&rtt, "rtt", rtt, "实例与协调者之间的往返延迟")
+	flag.Float64Var(&hotLoad, "hot", hotLoad, "热点实例每秒请求数")
+	flag.Float64Var(&coldLoad, "cold", coldLoad, "其他实例每秒请求数")
+	demoflag.Parse()
+
+	fmt.Println("=== 集群同步令牌桶演示 ===")
+	fmt.Printf("%d个实例共享全局限额 %.0f/秒（突发 %.0f），往返延迟 %v\n", instances, globalRate, globalBurst, rtt)
+	fmt.Printf("负载：热点实例 %.0f/秒，其他实例各 %.0f/秒；前%v热点是实例0，之后是实例%d\n\n", hotLoad, coldLoad, phase, instances-1)
+
+	ideal := globalRate * (2 * phase).Seconds()
+	window := globalRate * 0.1
+	strategies := []Strategy{central(), static(), synced(10 * time.Millisecond), synced(50 * time.Millisecond), synced(250 * time.Millisecond)}
+	results := make(map[string]runResult)
+
+	fmt.Printf("  %s %7s %7s %7s %s %s %s %s\n", pad("方式", 16), pad("请求", 7), pad("放行", 7), pad("利用率", 7),
+		pad("100ms最大放行", 14), pad("转移后新热点放行", 17), pad("平均判断耗时", 13), "协调消息")
+	for _, s := range strategies {
+		r := run(s)
+		results[s.Name] = r
+		fmt.Printf("  %s %7d %7d %6.0f%% %13d %17d %13v %9d\n", pad(s.Name, 16), r.attempts, r.admitted,
+			100*float64(r.admitted)/ideal, r.maxWindow(), r.hotAfterShift, r.avgLatency.Round(time.Microsecond), r.messages)
+	}
+
+	c, st := results["中心限流器"], results["静态切分"]
+	fast, slow := results["周期同步(10ms)"], results["周期同步(250ms)"]
+	fmt.Println()
+	check(fmt.Sprintf("中心限流器任意100ms放行不超过 %.0f+突发 %.0f", window, globalBurst), float64(c.maxWindow()) <= window+globalBurst)
+	check("中心限流器的每次判断都要等一次往返", c.avgLatency >= rtt)
+	check("静态切分零通信，但利用率明显低于中心限流器", st.messages == 0 && st.admitted < c.admitted*3/4)
+	check("周期同步的判断是本地操作，耗时远小于往返延迟", fast.avgLatency < rtt/4)
+	check("周期同步(10ms)的利用率接近中心限流器", fast.admitted > c.admitted*85/100)
+	check("同步间隔越长，热点转移后新热点实例拿到的放行越少", slow.hotAfterShift < fast.hotAfterShift)
+	check("同步间隔越长，协调消息越少", slow.messages < fast.messages)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 中心限流器最准确，但判断耗时等于往返延迟，协调者要处理每一个请求，是性能瓶颈和单点")
+	fmt.Println("2. 静态切分的问题是欠发：热点实例被限在1/4，其他实例的份额用不完，全局利用率低")
+	fmt.Println("3. 周期同步在两者之间：判断在本地完成，速率按需求重新分配，利用率接近中心限流器")
+	fmt.Println("4. 热点转移后，在下一次同步之前新热点仍然按旧份额限流；同步间隔就是适应速度的下限")
+	fmt.Println("5. 各实例的份额基于不同时刻的需求计算，总和可能短暂超过全局速率，这是本地缓存的超发来源")
+	fmt.Println("6. 单核机器上goroutine调度会放大延迟，多核时各方式的耗时差距更接近往返延迟的差距")
+}
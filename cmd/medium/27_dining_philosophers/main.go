@@ -0,0 +1,568 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/27_dining_philosophers/main.go
+主题：哲学家就餐问题与三种避免死锁的策略
+
+本示例演示：
+1. 朴素解法：每个哲学家先拿左手的叉子再拿右手的。插桩在"拿到第一把叉子之后"加一道屏障，
+   让所有人都先拿起左叉，必然形成循环等待；看门狗发现进度停滞后从等待图中找出环，
+   pkg/deadlock 的栈采样也报告了同样的阻塞
+2. 资源排序：总是先拿编号小的叉子，打破循环等待条件
+3. 服务员（信号量）：最多允许N-1个人同时入座拿叉子，至少有一个人能拿到两把
+4. Chandy–Misra：没有共享的叉子对象，邻居之间用消息传递叉子和"请求令牌"，
+   吃过的叉子是脏的，被请求时必须交出，保证不会死锁也不会饿死
+5. 每次进餐都检查同一把叉子没有被两个人同时使用，统计每人进餐次数和最长饥饿时间
+
+核心概念：
+- 死锁的四个必要条件：互斥、持有并等待、不可抢占、循环等待，破坏任意一个即可
+- 资源排序破坏循环等待；服务员限制同时竞争的人数；Chandy–Misra用"脏叉子必须让出"实现抢占
+- 死锁依赖时序，不插桩时朴素解法可能跑很多次都不出问题
+- 等待图（wait-for graph）：谁在等谁持有的资源，图中有环就是死锁
+
+运行方式：go run ./cmd/medium/27_dining_philosophers [-strategy=all|naive|ordered|waiter|chandy-misra] [-n=5] [-meals=20]
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/deadlock"
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+const (
+	eatTime  = time.Millisecond
+	maxThink = 2 * time.Millisecond
+	stall    = 200 * time.Millisecond // 这么久没有人吃上饭就认为死锁了
+)
+
+// table 餐桌：叉子和插桩数据。哲学家p左手是叉子p，右手是叉子(p+1)%n
+type table struct {
+	n, meals int
+	forks    []chan struct{} // 容量为1，里面有值表示叉子在桌上
+
+	mu      sync.Mutex
+	holder  []int // 叉子 -> 持有者，-1表示在桌上
+	waiting []int // 哲学家 -> 正在等的叉子，-1表示没有在等
+
+	inUse      []atomic.Int32 // 叉子同时被几个人用来吃饭，只能是0或1
+	violations atomic.Int64
+	eating     atomic.Int32
+	peakEating atomic.Int32
+	progress   atomic.Int64
+	eaten      []atomic.Int64
+	maxWait    []atomic.Int64 // 每个哲学家从饿了到吃上饭的最长时间
+}
+
+func newTable(n, meals int) *table {
+	t := &table{
+		n: n, meals: meals,
+		forks:   make([]chan struct{}, n),
+		holder:  make([]int, n),
+		waiting: make([]int, n),
+		inUse:   make([]atomic.Int32, n),
+		eaten:   make([]atomic.Int64, n),
+		maxWait: make([]atomic.Int64, n),
+	}
+	for i := range t.forks {
+		t.forks[i] = make(chan struct{}, 1)
+		t.forks[i] <- struct{}{}
+		t.holder[i] = -1
+		t.waiting[i] = -1
+	}
+	return t
+}
+
+func (t *table) left(p int) int  { return p }
+func (t *table) right(p int) int { return (p + 1) % t.n }
+
+// take 哲学家p拿起叉子f，ctx取消时放弃等待
+func (t *table) take(ctx context.Context, p, f int) error {
+	t.mu.Lock()
+	t.waiting[p] = f
+	t.mu.Unlock()
+	select {
+	case <-t.forks[f]:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	t.mu.Lock()
+	t.waiting[p] = -1
+	t.holder[f] = p
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *table) put(f int) {
+	t.mu.Lock()
+	t.holder[f] = -1
+	t.mu.Unlock()
+	t.forks[f] <- struct{}{}
+}
+
+// eat 进餐并检查两把叉子都只有自己在用
+func (t *table) eat(p int, waited time.Duration) {
+	for _, f := range []int{t.left(p), t.right(p)} {
+		if t.inUse[f].Add(1) != 1 {
+			t.violations.Add(1)
+		}
+	}
+	n := t.eating.Add(1)
+	for old := t.peakEating.Load(); n > old && !t.peakEating.CompareAndSwap(old, n); old = t.peakEating.Load() {
+	}
+	time.Sleep(eatTime)
+	t.eating.Add(-1)
+	for _, f := range []int{t.left(p), t.right(p)} {
+		t.inUse[f].Add(-1)
+	}
+	t.eaten[p].Add(1)
+	t.progress.Add(1)
+	if w := int64(waited); w > t.maxWait[p].Load() {
+		t.maxWait[p].Store(w) // 只有哲学家p自己写
+	}
+}
+
+func thinkTime(rng *rand.Rand) time.Duration {
+	return time.Duration(rng.Int63n(int64(maxThink) + 1))
+}
+
+// waitCycle 在等待图中找环：p等的叉子被q持有，则p -> q
+func (t *table) waitCycle() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for start := 0; start < t.n; start++ {
+		visited := make(map[int]bool)
+		var path []string
+		for p := start; ; {
+			if visited[p] {
+				if p == start {
+					return path
+				}
+				break
+			}
+			visited[p] = true
+			f := t.waiting[p]
+			if f < 0 || t.holder[f] < 0 {
+				break
+			}
+			q := t.holder[f]
+			path = append(path, fmt.Sprintf("P%d等叉子%d(被P%d持有)", p, f, q))
+			p = q
+		}
+	}
+	return nil
+}
+
+// dineFunc 一种策略：让所有哲学家吃完t.meals顿，或者在ctx取消时返回
+type dineFunc func(ctx context.Context, t *table, hook func(p int)) error
+
+// runLocking 用共享叉子的策略：acquire拿到两把叉子后返回释放函数
+func runLocking(ctx context.Context, t *table, acquire func(ctx context.Context, p int) (func(), error)) error {
+	var wg sync.WaitGroup
+	for p := 0; p < t.n; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(p)))
+			for m := 0; m < t.meals; m++ {
+				time.Sleep(thinkTime(rng))
+				hungry := time.Now()
+				release, err := acquire(ctx, p)
+				if err != nil {
+					return
+				}
+				t.eat(p, time.Since(hungry))
+				release()
+			}
+		}(p)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// naive 先左后右；hook在拿到第一把叉子后调用，用来插桩
+func naive(ctx context.Context, t *table, hook func(p int)) error {
+	return runLocking(ctx, t, func(ctx context.Context, p int) (func(), error) {
+		l, r := t.left(p), t.right(p)
+		if err := t.take(ctx, p, l); err != nil {
+			return nil, err
+		}
+		if hook != nil {
+			hook(p)
+		}
+		if err := t.take(ctx, p, r); err != nil {
+			t.put(l)
+			return nil, err
+		}
+		return func() { t.put(r); t.put(l) }, nil
+	})
+}
+
+// ordered 先拿编号小的叉子：最后一个哲学家先拿右手的叉子0，环被打破
+func ordered(ctx context.Context, t *table, hook func(p int)) error {
+	return runLocking(ctx, t, func(ctx context.Context, p int) (func(), error) {
+		first, second := min(t.left(p), t.right(p)), max(t.left(p), t.right(p))
+		if err := t.take(ctx, p, first); err != nil {
+			return nil, err
+		}
+		if hook != nil {
+			hook(p)
+		}
+		if err := t.take(ctx, p, second); err != nil {
+			t.put(first)
+			return nil, err
+		}
+		return func() { t.put(second); t.put(first) }, nil
+	})
+}
+
+// waiter 入座前先向服务员要一个座位，只有n-1个座位
+func waiter(ctx context.Context, t *table, hook func(p int)) error {
+	seats := make(chan struct{}, t.n-1)
+	return runLocking(ctx, t, func(ctx context.Context, p int) (func(), error) {
+		select {
+		case seats <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		l, r := t.left(p), t.right(p)
+		if err := t.take(ctx, p, l); err != nil {
+			<-seats
+			return nil, err
+		}
+		if hook != nil {
+			hook(p)
+		}
+		if err := t.take(ctx, p, r); err != nil {
+			t.put(l)
+			<-seats
+			return nil, err
+		}
+		return func() { t.put(r); t.put(l); <-seats }, nil
+	})
+}
+
+// ---------- Chandy–Misra ----------
+
+type cmKind int
+
+const (
+	cmRequest cmKind = iota // 请求令牌：对方想要这把叉子
+	cmFork                  // 叉子本身
+)
+
+type cmMsg struct {
+	fork int
+	kind cmKind
+}
+
+// cmState 哲学家对一把叉子的视角
+type cmState struct {
+	have  bool // 叉子在我手里
+	dirty bool // 我用它吃过饭了
+	token bool // 请求令牌在我手里：我没有叉子时可以用它请求；我有叉子时说明对方在等
+}
+
+// chandyMisra 叉子f由哲学家f和f-1共享。初始时每把叉子是脏的，交给编号小的一方，
+// 令牌给另一方，这样"谁优先"构成一个无环图；吃完饭的人把叉子弄脏，被请求时必须交出，
+// 优先级随之反转，图始终无环
+func chandyMisra(ctx context.Context, t *table, hook func(p int)) error {
+	n := t.n
+	// 每把叉子和它的令牌要么在某个哲学家手里，要么在路上，每人最多同时收到4条消息，发送永远不会阻塞
+	inbox := make([]chan cmMsg, n)
+	for i := range inbox {
+		inbox[i] = make(chan cmMsg, 4)
+	}
+	other := func(f, p int) int {
+		if p == f {
+			return (f - 1 + n) % n
+		}
+		return f
+	}
+
+	allFed := make(chan struct{})
+	var fed sync.WaitGroup
+	fed.Add(n)
+	go func() {
+		fed.Wait()
+		close(allFed)
+	}()
+
+	var wg sync.WaitGroup
+	for p := 0; p < n; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(p)))
+			mine := []int{t.left(p), t.right(p)}
+			state := map[int]*cmState{}
+			for _, f := range mine {
+				owner := min(p, other(f, p))
+				state[f] = &cmState{have: owner == p, dirty: owner == p, token: owner != p}
+			}
+			send := func(f int, kind cmKind) { inbox[other(f, p)] <- cmMsg{fork: f, kind: kind} }
+			handle := func(m cmMsg) {
+				s := state[m.fork]
+				if m.kind == cmFork {
+					s.have, s.dirty = true, false
+				} else {
+					s.token = true
+				}
+			}
+			// respond 交出被请求的脏叉子；饿了就用手里的令牌去要缺的叉子
+			respond := func(hungry bool) {
+				for _, f := range mine {
+					s := state[f]
+					if s.token && s.have && s.dirty {
+						s.have, s.dirty = false, false // 交出前擦干净
+						send(f, cmFork)
+					}
+					if hungry && s.token && !s.have {
+						s.token = false
+						send(f, cmRequest)
+					}
+				}
+			}
+			// serve 一边处理消息一边等待，until返回true或ctx取消时结束
+			serve := func(hungry bool, until <-chan time.Time, done func() bool) bool {
+				for !done() {
+					select {
+					case m := <-inbox[p]:
+						handle(m)
+						respond(hungry)
+					case <-until:
+						return true
+					case <-ctx.Done():
+						return false
+					}
+				}
+				return true
+			}
+			never := func() bool { return false }
+
+			for meal := 0; meal < t.meals; meal++ {
+				timer := time.NewTimer(thinkTime(rng))
+				ok := serve(false, timer.C, never)
+				timer.Stop()
+				if !ok {
+					return
+				}
+				hungry := time.Now()
+				respond(true)
+				if !serve(true, nil, func() bool { return state[mine[0]].have && state[mine[1]].have }) {
+					return
+				}
+				// 进餐期间到达的请求留在inbox里，吃完再处理
+				t.eat(p, time.Since(hungry))
+				for _, f := range mine {
+					state[f].dirty = true
+				}
+				respond(false)
+			}
+			fed.Done()
+			// 自己吃饱了，继续给邻居递叉子，直到所有人都吃完
+			for {
+				select {
+				case m := <-inbox[p]:
+					handle(m)
+					respond(false)
+				case <-allFed:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// ---------- 运行与观察 ----------
+
+type result struct {
+	elapsed    time.Duration
+	deadlocked bool
+	cycle      []string
+}
+
+// run 运行一种策略；看门狗发现stall时间内没有人吃上饭就判定死锁，取消ctx让所有哲学家放下叉子退出
+func run(t *table, dine dineFunc, hook func(p int)) result {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		dine(ctx, t, hook)
+		close(done)
+	}()
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	last, lastChange := t.progress.Load(), time.Now()
+	for {
+		select {
+		case <-done:
+			return result{elapsed: time.Since(start)}
+		case now := <-ticker.C:
+			if p := t.progress.Load(); p != last {
+				last, lastChange = p, now
+				continue
+			}
+			if now.Sub(lastChange) < stall {
+				continue
+			}
+			cycle := t.waitCycle()
+			cancel()
+			<-done
+			return result{elapsed: time.Since(start), deadlocked: true, cycle: cycle}
+		}
+	}
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// pad 按显示宽度补齐空格，中文字符占两列
+func pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return s + strings.Repeat(" ", max(width-w, 0))
+}
+
+func demoNaive(n, meals int) {
+	// 屏障：每个哲学家拿到第一把叉子后等所有人都拿到，之后的调用直接通过
+	var arrived atomic.Int32
+	gate := make(chan struct{})
+	barrier := func(int) {
+		if arrived.Add(1) == int32(n) {
+			close(gate)
+		}
+		<-gate
+	}
+
+	reports := make(chan deadlock.Report, 1)
+	d := deadlock.Start(deadlock.Config{
+		Interval:  20 * time.Millisecond,
+		Threshold: 100 * time.Millisecond,
+		Ignore:    func(b deadlock.Blocked) bool { return !strings.Contains(b.Site, "main.(*table).take") },
+		OnReport: func(r deadlock.Report) {
+			select {
+			case reports <- r:
+			default:
+			}
+		},
+	})
+	t := newTable(n, meals)
+	res := run(t, naive, barrier)
+	d.Stop()
+
+	fmt.Printf("  插桩后运行 %v，共吃了 %d 顿\n", res.elapsed.Round(time.Millisecond), t.progress.Load())
+	if res.deadlocked {
+		fmt.Println("  等待图中的环:")
+		for _, e := range res.cycle {
+			fmt.Printf("    %s\n", e)
+		}
+	}
+	check(fmt.Sprintf("看门狗在 %v 无进展后判定死锁", stall), res.deadlocked)
+	check(fmt.Sprintf("等待图中%d个哲学家构成一个环", n), len(res.cycle) == n)
+	select {
+	case r := <-reports:
+		fmt.Println("  pkg/deadlock 的报告:")
+		for _, line := range strings.Split(r.String(), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+		check("栈采样也发现所有哲学家阻塞在拿叉子上", len(r.Blocked) == n)
+	default:
+		check("pkg/deadlock 没有给出报告", false)
+	}
+
+	// 不插桩：死锁只在所有人恰好同时拿起左叉时发生
+	deadlocks := 0
+	const runs = 5
+	for i := 0; i < runs; i++ {
+		if run(newTable(n, meals), naive, nil).deadlocked {
+			deadlocks++
+		}
+	}
+	fmt.Printf("  不插桩运行 %d 次，死锁 %d 次：同样的代码，结果取决于调度时序\n", runs, deadlocks)
+}
+
+func demoStrategies(n, meals int, names []string, strategies map[string]dineFunc) {
+	fmt.Printf("  %s%s%s%s%s%s\n", pad("策略", 14), pad("耗时", 10), pad("每人进餐", 10), pad("最长饥饿", 10), pad("同时进餐", 10), "叉子冲突")
+	for _, name := range names {
+		t := newTable(n, meals)
+		res := run(t, strategies[name], nil)
+		lo, hi := int64(meals), int64(0)
+		var wait time.Duration
+		for p := 0; p < n; p++ {
+			lo, hi = min(lo, t.eaten[p].Load()), max(hi, t.eaten[p].Load())
+			wait = max(wait, time.Duration(t.maxWait[p].Load()))
+		}
+		fmt.Printf("  %s%s%s%s%s%d\n", pad(name, 14), pad(res.elapsed.Round(time.Millisecond).String(), 10),
+			pad(fmt.Sprintf("%d-%d", lo, hi), 10), pad(wait.Round(100*time.Microsecond).String(), 10),
+			pad(fmt.Sprint(t.peakEating.Load()), 10), t.violations.Load())
+		check(fmt.Sprintf("%s: 没有死锁，每人都吃完%d顿，叉子从未被两人同时使用", name, meals),
+			!res.deadlocked && lo == int64(meals) && t.violations.Load() == 0 && int(t.peakEating.Load()) <= n/2)
+	}
+}
+
+func main() {
+	strategy := flag.String("strategy", "all", "要运行的策略：all、naive、ordered、waiter、chandy-misra")
+	n := flag.Int("n", 5, "哲学家人数（至少2）")
+	meals := flag.Int("meals", 20, "每人要吃的顿数")
+	demoflag.Parse()
+
+	strategies := map[string]dineFunc{"ordered": ordered, "waiter": waiter, "chandy-misra": chandyMisra}
+	names := []string{"ordered", "waiter", "chandy-misra"}
+	switch {
+	case *n < 2 || *meals < 1:
+		fmt.Fprintln(os.Stderr, "n至少为2，meals至少为1")
+		os.Exit(2)
+	case *strategy == "all" || *strategy == "naive":
+	case strategies[*strategy] != nil:
+		names = []string{*strategy}
+	default:
+		fmt.Fprintf(os.Stderr, "未知的策略 %q\n", *strategy)
+		os.Exit(2)
+	}
+
+	fmt.Println("=== 哲学家就餐问题演示 ===")
+	fmt.Printf("%d位哲学家，每人吃%d顿，每顿 %v，思考 0-%v\n", *n, *meals, eatTime, maxThink)
+
+	if *strategy == "all" || *strategy == "naive" {
+		fmt.Println("\n1. 朴素解法：先左后右，插桩让所有人同时拿起左叉")
+		demoNaive(*n, *meals)
+	}
+	if *strategy != "naive" {
+		fmt.Println("\n2. 三种避免死锁的策略")
+		demoStrategies(*n, *meals, names, strategies)
+	}
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 朴素解法中每个人都持有一把叉子并等待邻居手里的另一把，等待图是一个环，谁也无法前进")
+	fmt.Println("2. 死锁能否出现取决于时序，插桩（屏障、延时）能把偶发的问题变成每次都能复现")
+	fmt.Println("3. 资源排序只需改变拿叉子的顺序；服务员用信号量限制竞争者数量，多了一次排队")
+	fmt.Println("4. Chandy–Misra没有任何锁，叉子和请求令牌都通过channel传递，刚吃过的人必须让出叉子，不会有人饿死")
+	fmt.Println("5. 叉子冲突计数为0说明三种策略都保证了互斥，同时进餐的人数不会超过n/2")
+}
@@ -0,0 +1,358 @@
+/*
+Golang并发编程学习Demo - 中等级别
+文件：cmd/medium/28_cow_registry/main.go
+主题：写时复制（RCU风格）的并发注册表
+
+本示例演示：
+1. pkg/registry：读者一次原子加载拿到不可变快照，写者复制、修改、原子替换
+2. 批量修改：Update中的多处修改一次发布，读者不会看到只做了一半的转账；
+   拆成两次Put时读者能观察到中间状态
+3. 快照隔离：读者持有的旧快照在写入后保持不变，遍历期间不需要任何锁
+4. 计时对比服务器列表的三种实现在不同写入比例下的性能：
+   写时复制、RWMutex原地读取、RWMutex下复制一份再读取（hard/02_load_balancer.go原来的做法）；
+   注册表本身的对比也写成了基准测试：go test -bench . ./pkg/registry
+5. 写入的代价：每次写入都要复制整张表，分配的字节数随表的大小线性增长
+
+核心概念：
+- RCU（read-copy-update）：读者无锁、无等待，写者承担复制的成本，旧版本在没有读者后回收
+- atomic.Pointer[T]：类型安全的原子指针，Load/Store都是一条原子指令
+- 快照中保存值而不是可变对象的指针，"修改一台服务器的状态"就是写入一个新值，快照才是真正不可变的
+- 写者之间仍然需要互斥锁，否则两个写者基于同一个旧快照复制，后发布的会覆盖先发布的修改
+
+运行方式：go run ./cmd/medium/28_cow_registry [-servers=64] [-benchtime=200ms]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/registry"
+)
+
+// server 服务器的状态；按值保存，修改状态就是替换成新值
+type server struct {
+	ID      int
+	Addr    string
+	Healthy bool
+}
+
+// serverList 负载均衡器需要的两个操作
+type serverList interface {
+	// Pick 在健康的服务器中按key选一台（遍历整张表，和负载均衡策略的Select一样）
+	Pick(key uint64) (server, bool)
+	// SetHealthy 修改一台服务器的健康状态
+	SetHealthy(id int, healthy bool)
+}
+
+// pick 两遍遍历：先数健康的服务器，再选第key%healthy台，不分配内存
+func pick(servers []server, key uint64) (server, bool) {
+	healthy := 0
+	for i := range servers {
+		if servers[i].Healthy {
+			healthy++
+		}
+	}
+	if healthy == 0 {
+		return server{}, false
+	}
+	n := int(key % uint64(healthy))
+	for i := range servers {
+		if servers[i].Healthy {
+			if n == 0 {
+				return servers[i], true
+			}
+			n--
+		}
+	}
+	return server{}, false
+}
+
+// cowList 写时复制
+type cowList struct {
+	reg *registry.Registry[int, server]
+}
+
+func (l *cowList) Pick(key uint64) (server, bool) { return pick(l.reg.Load().Values(), key) }
+
+func (l *cowList) SetHealthy(id int, healthy bool) {
+	l.reg.Update(func(e *registry.Editor[int, server]) {
+		if s, ok := e.Get(id); ok {
+			s.Healthy = healthy
+			e.Put(id, s)
+		}
+	})
+}
+
+// rwList RWMutex保护的切片，读者在读锁内原地遍历
+type rwList struct {
+	mu      sync.RWMutex
+	index   map[int]int
+	servers []server
+	copy    bool // true时读者在读锁内复制一份，解锁后再遍历
+}
+
+func (l *rwList) Pick(key uint64) (server, bool) {
+	l.mu.RLock()
+	if !l.copy {
+		defer l.mu.RUnlock()
+		return pick(l.servers, key)
+	}
+	servers := make([]server, len(l.servers))
+	copy(servers, l.servers)
+	l.mu.RUnlock()
+	return pick(servers, key)
+}
+
+func (l *rwList) SetHealthy(id int, healthy bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i, ok := l.index[id]; ok {
+		l.servers[i].Healthy = healthy
+	}
+}
+
+func newServers(n int) []server {
+	servers := make([]server, n)
+	for i := range servers {
+		servers[i] = server{ID: i, Addr: fmt.Sprintf("10.0.%d.%d:8080", i/256, i%256), Healthy: i%8 != 0}
+	}
+	return servers
+}
+
+func newCowList(n int) *cowList {
+	reg := registry.New[int, server]()
+	reg.Update(func(e *registry.Editor[int, server]) {
+		for _, s := range newServers(n) {
+			e.Put(s.ID, s)
+		}
+	})
+	return &cowList{reg: reg}
+}
+
+func newRWList(n int, copy bool) *rwList {
+	l := &rwList{index: make(map[int]int), servers: newServers(n), copy: copy}
+	for i, s := range l.servers {
+		l.index[s.ID] = i
+	}
+	return l
+}
+
+func check(name string, ok bool) {
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+	fmt.Printf("  %s %s\n", mark, name)
+}
+
+// pad 按显示宽度补齐空格，中文字符占两列
+func pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return s + strings.Repeat(" ", max(width-w, 0))
+}
+
+// measure workers个goroutine反复调用op(worker, i)，i从1开始计数，持续d时间；
+// 返回平均每次操作的用时和分配的字节数
+func measure(workers int, d time.Duration, op func(worker, i int)) (perOp time.Duration, bytesPerOp uint64) {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var n atomic.Int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(d)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			i := 1
+			for ; time.Now().Before(deadline); i++ {
+				op(w, i)
+			}
+			n.Add(int64(i - 1))
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	total := max(n.Load(), 1)
+	return elapsed / time.Duration(total), (after.TotalAlloc - before.TotalAlloc) / uint64(total)
+}
+
+// ---------- 1. 批量修改与快照隔离 ----------
+
+// transfers 写者在账户之间转账，读者不停地对快照求和；返回读者看到总额不对的次数
+func transfers(batched bool) (reads, torn int64) {
+	const accounts, each, moves = 8, 100, 2000
+	reg := registry.New[string, int]()
+	reg.Update(func(e *registry.Editor[string, int]) {
+		for i := 0; i < accounts; i++ {
+			e.Put(fmt.Sprintf("acct-%d", i), each)
+		}
+	})
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	var nReads, nTorn atomic.Int64
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				sum := 0
+				reg.Load().Range(func(_ string, v int) bool {
+					sum += v
+					return true
+				})
+				nReads.Add(1)
+				if sum != accounts*each {
+					nTorn.Add(1)
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+
+	for i := 0; i < moves; i++ {
+		from, to := fmt.Sprintf("acct-%d", i%accounts), fmt.Sprintf("acct-%d", (i+3)%accounts)
+		if batched {
+			reg.Update(func(e *registry.Editor[string, int]) {
+				a, _ := e.Get(from)
+				b, _ := e.Get(to)
+				e.Put(from, a-1)
+				e.Put(to, b+1)
+			})
+			runtime.Gosched()
+			continue
+		}
+		a, _ := reg.Get(from)
+		reg.Put(from, a-1)
+		runtime.Gosched() // 两次Put之间让出CPU，模拟多核上读者随时可能插进来
+		b, _ := reg.Get(to)
+		reg.Put(to, b+1)
+	}
+	stop.Store(true)
+	wg.Wait()
+	return nReads.Load(), nTorn.Load()
+}
+
+func demoConsistency() {
+	reads, torn := transfers(false)
+	fmt.Printf("  拆成两次Put: 读者求和 %d 次，总额不对 %d 次\n", reads, torn)
+	check("两次Put之间读者能看到钱已扣除但还没到账的中间状态", torn > 0)
+	reads, torn = transfers(true)
+	fmt.Printf("  一次Update:  读者求和 %d 次，总额不对 %d 次\n", reads, torn)
+	check("Update中的修改一次发布，读者永远看不到中间状态", torn == 0)
+
+	l := newCowList(8)
+	old := l.reg.Load()
+	before := fmt.Sprint(old.Values())
+	for id := 0; id < 8; id++ {
+		l.SetHealthy(id, false)
+	}
+	cur := l.reg.Load()
+	_, okOld := pick(old.Values(), 0)
+	_, okCur := pick(cur.Values(), 0)
+	fmt.Printf("  旧快照版本 %d，当前版本 %d\n", old.Version(), cur.Version())
+	check("写入后旧快照的内容不变，仍然可以从中选出健康的服务器", fmt.Sprint(old.Values()) == before && okOld)
+	check("当前快照中所有服务器都已下线", !okCur)
+}
+
+// ---------- 2. 读多写少的基准测试 ----------
+
+func demoBenchmark(n int, benchtime time.Duration) {
+	impls := []struct {
+		name string
+		make func() serverList
+	}{
+		{"写时复制", func() serverList { return newCowList(n) }},
+		{"RWMutex", func() serverList { return newRWList(n, false) }},
+		{"RWMutex+复制", func() serverList { return newRWList(n, true) }},
+	}
+	// writeEvery: 每多少次操作有一次写入，0表示只读
+	ratios := []struct {
+		name       string
+		writeEvery int
+	}{{"只读", 0}, {"0.1%写", 1000}, {"1%写", 100}, {"10%写", 10}, {"50%写", 2}}
+
+	header := pad("实现(ns/op)", 16)
+	for _, r := range ratios {
+		header += pad(r.name, 12)
+	}
+	fmt.Printf("  %s\n", strings.TrimRight(header, " "))
+	results := make(map[string][]time.Duration)
+	for _, impl := range impls {
+		row := pad(impl.name, 16)
+		for _, ratio := range ratios {
+			l := impl.make()
+			every := ratio.writeEvery
+			// 每个CPU 4个goroutine，读者和写者在单核上也会交替运行
+			d, _ := measure(4*runtime.GOMAXPROCS(0), benchtime, func(w, i int) {
+				key := uint64(w+1)*7919 + uint64(i)
+				if every > 0 && i%every == 0 {
+					l.SetHealthy(int(key%uint64(n)), key%3 != 0)
+					return
+				}
+				l.Pick(key)
+			})
+			results[impl.name] = append(results[impl.name], d)
+			row += pad(d.String(), 12)
+		}
+		fmt.Printf("  %s\n", strings.TrimRight(row, " "))
+	}
+	cow, rw, rwCopy := results["写时复制"], results["RWMutex"], results["RWMutex+复制"]
+	check("只读时写时复制不比RWMutex慢（省掉了读锁的两次原子操作，单核上读锁没有竞争，差距不大）", cow[0] < rw[0]*12/10)
+	check("只读时RWMutex+复制最慢（每次读取都要分配并复制整张表）", rwCopy[0] > rw[0] && rwCopy[0] > cow[0])
+	check("50%写入时写时复制不再占优（每次写入复制整张表）", cow[len(cow)-1] > rw[len(rw)-1])
+}
+
+func demoWriteCost(benchtime time.Duration) {
+	fmt.Printf("  %s%s%s\n", pad("表大小", 10), pad("ns/写", 12), "B/写")
+	for _, n := range []int{16, 256, 4096} {
+		l := newCowList(n)
+		d, bytes := measure(1, benchtime, func(_, i int) { l.SetHealthy(i%n, i%2 == 0) })
+		fmt.Printf("  %s%s%d\n", pad(fmt.Sprint(n), 10), pad(d.String(), 12), bytes)
+	}
+}
+
+func main() {
+	servers := flag.Int("servers", 64, "服务器列表的长度")
+	benchtime := flag.Duration("benchtime", 200*time.Millisecond, "每个基准测试的运行时间")
+	demoflag.Parse()
+
+	fmt.Println("=== 写时复制注册表演示 ===")
+	fmt.Printf("GOMAXPROCS=%d\n", runtime.GOMAXPROCS(0))
+
+	fmt.Println("\n1. 批量修改与快照隔离")
+	demoConsistency()
+
+	fmt.Printf("\n2. %d台服务器，每次读取遍历整张表选一台健康的服务器\n", *servers)
+	demoBenchmark(*servers, *benchtime)
+
+	fmt.Println("\n3. 写时复制的写入代价")
+	demoWriteCost(*benchtime)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 读者只做一次原子加载，之后遍历的是不会再变的快照，不持有锁，也不会阻塞写者")
+	fmt.Println("2. 多处修改放在同一个Update里才是原子的，分开写入时读者能看到中间状态")
+	fmt.Println("3. 旧LoadBalancer在读锁内复制切片是为了缩短持锁时间，写时复制把这次复制挪到了很少发生的写入上")
+	fmt.Println("4. 写入比例升高后，每次写入复制整张表的成本超过读锁的开销，RWMutex反而更好")
+	fmt.Println("5. 多核机器上RWMutex的读锁计数器在CPU之间来回传递缓存行，写时复制的优势比单核更明显")
+}
@@ -1,6 +1,6 @@
 /*
 Golang并发编程学习Demo - 简单级别
-文件：01_basic_goroutine.go
+文件：cmd/simple/01_basic_goroutine/main.go
 主题：基础Goroutine使用
 
 本示例演示：
@@ -15,14 +15,18 @@ Golang并发编程学习Demo - 简单级别
 - 主程序需要等待goroutine完成
 - goroutine的执行顺序是不确定的
 
-运行方式：go run simple/01_basic_goroutine.go
+运行方式：go run ./cmd/simple/01_basic_goroutine [-wait=1s]
 */
 
 package main
 
 import (
+	"flag"
 	"fmt"
 	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
 )
 
 // printNumbers 打印数字序列
@@ -46,9 +50,15 @@ func printLetters() {
 }
 
 func main() {
+	wait := flag.Duration("wait", time.Second, "主程序等待goroutine的时间，调小可以看到goroutine被提前终止")
+	demoflag.Parse()
+
 	fmt.Println("=== 基础Goroutine演示 ===")
 	fmt.Println("观察数字和字母的交替输出，体现并发执行特性")
 
+	// 两个goroutine的输出顺序每次都可能不同，黄金输出检查只比较输出了哪些行
+	end := golden.Unordered()
+
 	// 使用go关键字启动第一个goroutine
 	// 这会立即返回，不会阻塞主程序
 	go printNumbers()
@@ -60,7 +70,8 @@ func main() {
 	// 主goroutine需要等待子goroutine完成
 	// 这里使用sleep是一种粗糙的方式，实际项目中应该使用WaitGroup
 	fmt.Println("主程序等待goroutine完成...")
-	time.Sleep(1 * time.Second)
+	time.Sleep(*wait)
+	end()
 
 	fmt.Println("程序结束")
 	fmt.Println("注意：如果主程序提前结束，所有goroutine都会被强制终止")
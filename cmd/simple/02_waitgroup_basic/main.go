@@ -1,6 +1,6 @@
 /*
 Golang并发编程学习Demo - 简单级别
-文件：02_waitgroup_basic.go
+文件：cmd/simple/02_waitgroup_basic/main.go
 主题：WaitGroup基础使用
 
 本示例演示：
@@ -16,15 +16,19 @@ Golang并发编程学习Demo - 简单级别
 - Wait()阻塞直到计数为0
 - defer确保Done()一定被调用
 
-运行方式：go run simple/02_waitgroup_basic.go
+运行方式：go run ./cmd/simple/02_waitgroup_basic [-workers=5]
 */
 
 package main
 
 import (
+	"flag"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
 )
 
 // worker 工作者函数，模拟一个需要时间的任务
@@ -45,6 +49,9 @@ func worker(id int, wg *sync.WaitGroup) {
 }
 
 func main() {
+	workers := flag.Int("workers", 5, "工作者数量")
+	demoflag.Parse()
+
 	fmt.Println("=== WaitGroup基础演示 ===")
 	fmt.Println("演示如何使用WaitGroup等待多个goroutine完成")
 
@@ -52,9 +59,10 @@ func main() {
 	// WaitGroup内部维护一个计数器
 	var wg sync.WaitGroup
 
-	// 启动5个工作者goroutine
-	fmt.Println("启动5个工作者...")
-	for i := 1; i <= 5; i++ {
+	// 启动工作者goroutine
+	fmt.Printf("启动%d个工作者...\n", *workers)
+	end := golden.Unordered() // 工作者开始工作的顺序不固定
+	for i := 1; i <= *workers; i++ {
 		// 每启动一个goroutine前，先Add(1)增加计数器
 		wg.Add(1) // 计数器+1，告诉WaitGroup要等待一个goroutine
 
@@ -66,6 +74,7 @@ func main() {
 	// 直到WaitGroup内部计数器变为0
 	fmt.Println("主程序等待所有工作者完成...")
 	wg.Wait()
+	end()
 
 	fmt.Println("所有工作者完成！")
 	fmt.Println("程序正常结束，没有goroutine泄露")
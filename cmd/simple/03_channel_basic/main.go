@@ -1,6 +1,6 @@
 /*
 Golang并发编程学习Demo - 简单级别
-文件：03_channel_basic.go
+文件：cmd/simple/03_channel_basic/main.go
 主题：基础Channel通信
 
 本示例演示：
@@ -18,20 +18,24 @@ Golang并发编程学习Demo - 简单级别
 - 关闭channel通知接收者没有更多数据
 - range可以自动检测channel关闭
 
-运行方式：go run simple/03_channel_basic.go
+运行方式：go run ./cmd/simple/03_channel_basic [-interval=500ms]
 */
 
 package main
 
 import (
+	"flag"
 	"fmt"
 	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
 )
 
 // sender 发送者函数，向channel发送数据
 // ch: 只写channel，只能向其发送数据
 // chan<- string 是单向channel类型，提高代码安全性
-func sender(ch chan<- string) {
+// interval: 每条消息发送之后的处理时间
+func sender(ch chan<- string, interval time.Duration) {
 	messages := []string{"Hello", "World", "Go", "Channel"}
 
 	fmt.Println("开始发送消息...")
@@ -43,16 +47,20 @@ func sender(ch chan<- string) {
 		ch <- msg
 
 		// 模拟一些处理时间
-		time.Sleep(500 * time.Millisecond)
+		time.Sleep(interval)
 	}
 
 	// 关闭channel非常重要！
 	// 这会通知接收者没有更多数据会被发送
+	// 先打印再关闭：关闭之后主goroutine的range随即结束，程序可能在这一行打印之前就退出了
+	fmt.Println("发送完成，关闭channel")
 	close(ch)
-	fmt.Println("发送完成，channel已关闭")
 }
 
 func main() {
+	interval := flag.Duration("interval", 500*time.Millisecond, "发送者每条消息之后的处理时间")
+	demoflag.Parse()
+
 	fmt.Println("=== 基础Channel演示 ===")
 	fmt.Println("演示goroutine间通过channel进行通信")
 
@@ -64,7 +72,7 @@ func main() {
 	// 必须在goroutine中运行，否则会死锁
 	// 因为无缓冲channel的发送操作会阻塞直到有接收者
 	fmt.Println("启动发送者goroutine...")
-	go sender(ch)
+	go sender(ch, *interval)
 
 	// 在主goroutine中接收消息
 	// 使用range遍历channel，会自动处理channel关闭
@@ -1,6 +1,6 @@
 /*
 Golang并发编程学习Demo - 简单级别
-文件：04_buffered_channel.go
+文件：cmd/simple/04_buffered_channel/main.go
 主题：缓冲Channel使用
 
 本示例演示：
@@ -17,14 +17,19 @@ Golang并发编程学习Demo - 简单级别
 - len()获取当前缓冲区中的元素数量
 - cap()获取缓冲区的总容量
 
-运行方式：go run simple/04_buffered_channel.go
+运行方式：go run ./cmd/simple/04_buffered_channel [-buffer=5] [-wait=3s] [-consumers=3]
 */
 
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
 )
 
 // demonstrateBasicBufferedChannel 演示基础缓冲channel操作
@@ -58,11 +63,13 @@ func demonstrateBasicBufferedChannel() {
 	time.Sleep(500 * time.Millisecond) // 让goroutine有时间尝试发送
 
 	fmt.Println("\n开始接收消息...")
+	end := golden.Unordered() // "第四条消息发送成功"和接收的输出谁先谁后不固定
 	for i := 0; i < 4; i++ {
 		msg := <-bufferedCh
 		fmt.Printf("接收到: %s (剩余: %d)\n", msg, len(bufferedCh))
 		time.Sleep(200 * time.Millisecond)
 	}
+	end()
 
 	close(bufferedCh)
 }
@@ -77,7 +84,7 @@ func producer(ch chan<- int, start, count int) {
 		value := start + i
 
 		// 检查发送前的缓冲区状态
-		fmt.Printf("准备发送: %d (当前缓冲区长度: %d)\n", value, len(ch))
+		fmt.Printf("准备发送: %d (当前缓冲区长度: %d)\n", value, golden.Varying(len(ch)))
 
 		ch <- value
 
@@ -95,22 +102,24 @@ func consumer(ch <-chan int, consumerID string) {
 	messageCount := 0
 	for value := range ch { // range会自动处理channel关闭
 		messageCount++
-		fmt.Printf("消费者 %s 接收到: %d (第%d条消息)\n", consumerID, value, messageCount)
+		fmt.Printf("消费者 %s 接收到: %d (第%d条消息)\n", consumerID, value, golden.Varying(messageCount))
 
 		// 模拟处理时间
 		time.Sleep(150 * time.Millisecond)
 	}
 
-	fmt.Printf("消费者 %s 完成工作，共处理 %d 条消息\n", consumerID, messageCount)
+	fmt.Printf("消费者 %s 完成工作，共处理 %d 条消息\n", consumerID, golden.Varying(messageCount))
 }
 
 // demonstrateProducerConsumer 演示生产者-消费者模式
-func demonstrateProducerConsumer() {
+// buffer: channel的容量，wait: 等待生产者和消费者完成的时间
+func demonstrateProducerConsumer(buffer int, wait time.Duration) {
 	fmt.Println("\n=== 生产者-消费者模式演示 ===")
 
-	// 创建一个容量为5的缓冲channel
+	// 创建一个容量为buffer的缓冲channel（默认5）
 	// 这样生产者可以在消费者准备好之前发送一些数据
-	productChannel := make(chan int, 5)
+	productChannel := make(chan int, buffer)
+	end := golden.Unordered()
 
 	// 启动生产者goroutine
 	go producer(productChannel, 100, 10)
@@ -119,19 +128,22 @@ func demonstrateProducerConsumer() {
 	go consumer(productChannel, "A")
 
 	// 等待足够的时间让生产者和消费者完成工作
-	time.Sleep(3 * time.Second)
+	// 调小-wait可以看到程序在消费者处理完之前就进入了下一个演示
+	time.Sleep(wait)
+	end()
 }
 
 // demonstrateMultipleConsumers 演示多个消费者竞争同一个缓冲channel
-func demonstrateMultipleConsumers() {
+func demonstrateMultipleConsumers(consumers int) {
 	fmt.Println("\n=== 多消费者竞争演示 ===")
 
 	// 创建一个较大的缓冲channel
 	jobChannel := make(chan int, 8)
+	end := golden.Unordered()
 
-	// 启动多个消费者
-	for i := 1; i <= 3; i++ {
-		consumerID := fmt.Sprintf("Consumer-%d", i)
+	// 启动多个消费者；哪个消费者抢到哪个任务每次都不同
+	for i := 1; i <= consumers; i++ {
+		consumerID := fmt.Sprint(golden.Varying(fmt.Sprintf("Consumer-%d", i)))
 		go consumer(jobChannel, consumerID)
 	}
 
@@ -150,6 +162,7 @@ func demonstrateMultipleConsumers() {
 
 	// 等待所有消费者处理完成
 	time.Sleep(4 * time.Second)
+	end()
 }
 
 // demonstrateChannelCapacityEffects 演示不同缓冲区大小的影响
@@ -163,6 +176,7 @@ func demonstrateChannelCapacityEffects() {
 		fmt.Printf("\n--- 测试容量为 %d 的缓冲channel ---\n", cap)
 
 		ch := make(chan string, cap)
+		end := golden.Unordered()
 
 		// 记录开始时间
 		start := time.Now()
@@ -187,21 +201,32 @@ func demonstrateChannelCapacityEffects() {
 		// 等待处理完成
 		time.Sleep(1 * time.Second)
 		close(ch)
+		end()
 	}
 }
 
 func main() {
+	buffer := flag.Int("buffer", 5, "生产者-消费者演示中channel的容量")
+	wait := flag.Duration("wait", 3*time.Second, "生产者-消费者演示等待完成的时间")
+	consumers := flag.Int("consumers", 3, "多消费者竞争演示中的消费者数量")
+	demoflag.Parse()
+	if *buffer < 0 || *consumers < 1 {
+		fmt.Fprintln(os.Stderr, "buffer不能为负数，consumers至少为1")
+		os.Exit(2)
+	}
+
 	fmt.Println("=== 缓冲Channel详细演示 ===")
-	fmt.Println("观察缓冲channel如何改变goroutine间的通信行为\n")
+	fmt.Println("观察缓冲channel如何改变goroutine间的通信行为")
+	fmt.Println()
 
 	// 1. 基础缓冲channel操作
 	demonstrateBasicBufferedChannel()
 
 	// 2. 生产者-消费者模式
-	demonstrateProducerConsumer()
+	demonstrateProducerConsumer(*buffer, *wait)
 
 	// 3. 多消费者竞争
-	demonstrateMultipleConsumers()
+	demonstrateMultipleConsumers(*consumers)
 
 	// 4. 缓冲区大小对性能的影响
 	demonstrateChannelCapacityEffects()
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
 )
 
 // 基础select演示
 func main() {
+	delay1 := flag.Duration("delay1", 1*time.Second, "channel1发送前的延迟")
+	delay2 := flag.Duration("delay2", 2*time.Second, "channel2发送前的延迟，小于-delay1时先收到channel2的消息")
+	demoflag.Parse()
+
 	fmt.Println("=== 基础Select演示 ===")
 
 	ch1 := make(chan string)
@@ -14,12 +21,12 @@ func main() {
 
 	// 启动两个goroutine发送数据
 	go func() {
-		time.Sleep(1 * time.Second)
+		time.Sleep(*delay1)
 		ch1 <- "来自channel1的消息"
 	}()
 
 	go func() {
-		time.Sleep(2 * time.Second)
+		time.Sleep(*delay2)
 		ch2 <- "来自channel2的消息"
 	}()
 
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// Attempt 一次尝试的记录
+type Attempt struct {
+	Number   int
+	Timeout  time.Duration // 本次尝试获得的超时时间
+	Duration time.Duration // 实际耗时
+	Err      error
+}
+
+// BudgetReport 在总预算内重试的结果
+type BudgetReport struct {
+	Attempts  []Attempt
+	Remaining time.Duration // 结束时剩余的预算
+	Err       error         // 最终错误，成功时为nil
+}
+
+var ErrBudgetExhausted = errors.New("超出总时间预算")
+
+// RetryWithBudget 在总预算budget内最多尝试maxAttempts次，每次尝试的超时为perAttempt
+// 剩余预算不足perAttempt时，最后一次尝试只能使用剩余的时间
+func RetryWithBudget(budget, perAttempt time.Duration, maxAttempts int,
+	op func(ctx context.Context) error) BudgetReport {
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	var report BudgetReport
+	for n := 1; n <= maxAttempts; n++ {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			report.Err = ErrBudgetExhausted
+			break
+		}
+
+		timeout := perAttempt
+		if remaining < timeout {
+			timeout = remaining
+		}
+
+		// 每次尝试的ctx从总预算的ctx派生，两个期限中较早的一个生效
+		attemptCtx, attemptCancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := op(attemptCtx)
+		attemptCancel()
+
+		report.Attempts = append(report.Attempts, Attempt{
+			Number:   n,
+			Timeout:  timeout,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+		report.Err = err
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			report.Err = fmt.Errorf("%w: 最后一次错误: %v", ErrBudgetExhausted, err)
+			break
+		}
+	}
+
+	report.Remaining = time.Until(deadline)
+	if report.Remaining < 0 {
+		report.Remaining = 0
+	}
+	return report
+}
+
+// slowOperation 前failures次调用耗时slow，之后耗时fast
+func slowOperation(failures int, slow, fast time.Duration) func(ctx context.Context) error {
+	calls := 0
+	return func(ctx context.Context) error {
+		calls++
+		delay := fast
+		if calls <= failures {
+			delay = slow
+		}
+
+		select {
+		case <-time.After(delay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func printReport(report BudgetReport) {
+	for _, a := range report.Attempts {
+		result := "成功"
+		if a.Err != nil {
+			result = a.Err.Error()
+		}
+		fmt.Printf("  第 %d 次: 超时 %-6v 耗时 %-6v %s\n",
+			a.Number, a.Timeout.Round(time.Millisecond), a.Duration.Round(time.Millisecond), result)
+	}
+	fmt.Printf("  结果: %v, 剩余预算: %v\n", report.Err, report.Remaining.Round(time.Millisecond))
+}
+
+// 带超时的select演示
+func main() {
+	delay := flag.Duration("delay", 3*time.Second, "消息发送前的延迟，小于-timeout时能收到消息")
+	timeout := flag.Duration("timeout", 2*time.Second, "等待消息的超时时间")
+	demoflag.Parse()
+
+	fmt.Println("=== 带超时的Select演示 ===")
+
+	ch := make(chan string)
+
+	// 启动一个goroutine，延迟一段时间后发送消息
+	go func() {
+		time.Sleep(*delay)
+		ch <- "延迟消息"
+	}()
+
+	fmt.Printf("等待消息（超时时间：%v）...\n", *timeout)
+
+	select {
+	case msg := <-ch:
+		fmt.Printf("接收到消息: %s\n", msg)
+	case <-time.After(*timeout):
+		fmt.Println("超时！没有接收到消息")
+	}
+
+	// 总预算内的多次尝试：3次尝试，总共不超过2秒
+	fmt.Println("\n每次500ms超时、总预算2秒、最多3次，前2次调用很慢:")
+	printReport(RetryWithBudget(2*time.Second, 500*time.Millisecond, 3,
+		slowOperation(2, time.Second, 100*time.Millisecond)))
+
+	fmt.Println("\n每次500ms超时、总预算1.2秒、最多3次，调用一直很慢:")
+	printReport(RetryWithBudget(1200*time.Millisecond, 500*time.Millisecond, 3,
+		slowOperation(3, time.Second, 100*time.Millisecond)))
+
+	fmt.Println("程序结束")
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
+)
+
+// 基础互斥锁演示
+var (
+	counter int
+	mutex   sync.Mutex
+)
+
+func increment(wg *sync.WaitGroup, n int) {
+	defer wg.Done()
+
+	for i := 0; i < n; i++ {
+		mutex.Lock()   // 加锁
+		counter++      // 临界区
+		mutex.Unlock() // 解锁
+	}
+}
+
+// ===== TryLock =====
+
+// tryRefresh 用TryLock实现"正在刷新就跳过"：拿不到锁说明别人在刷新，不必排队等待
+func tryRefresh(id int, mu *sync.Mutex, refreshed *int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if !mu.TryLock() {
+		fmt.Printf("goroutine %d: 其他goroutine正在刷新，跳过\n", golden.Varying(id))
+		return
+	}
+	defer mu.Unlock()
+
+	fmt.Printf("goroutine %d: 获得锁，开始刷新缓存\n", golden.Varying(id))
+	time.Sleep(100 * time.Millisecond)
+	*refreshed++
+}
+
+// ===== 锁竞争统计 =====
+
+// waitBuckets 等待时间直方图的桶上限
+var waitBuckets = []time.Duration{
+	time.Microsecond, 10 * time.Microsecond, 100 * time.Microsecond,
+	time.Millisecond, 10 * time.Millisecond,
+}
+
+// ProfiledMutex 包装sync.Mutex，记录每个调用方获取锁的等待时间
+type ProfiledMutex struct {
+	mu sync.Mutex
+
+	statsMu   sync.Mutex
+	waits     map[string][]time.Duration // 调用方 -> 每次的等待时间
+	histogram []int                      // 与waitBuckets对应，最后一个桶为超过上限的
+	contended int                        // TryLock失败、需要排队的次数
+	total     int
+}
+
+func NewProfiledMutex() *ProfiledMutex {
+	return &ProfiledMutex{
+		waits:     make(map[string][]time.Duration),
+		histogram: make([]int, len(waitBuckets)+1),
+	}
+}
+
+// Lock 获取锁并记录等待时间；先TryLock，失败才说明发生了竞争
+func (m *ProfiledMutex) Lock(caller string) {
+	start := time.Now()
+	contended := !m.mu.TryLock()
+	if contended {
+		m.mu.Lock()
+	}
+	wait := time.Since(start)
+
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	m.total++
+	if contended {
+		m.contended++
+	}
+	m.waits[caller] = append(m.waits[caller], wait)
+	bucket := sort.Search(len(waitBuckets), func(i int) bool { return wait < waitBuckets[i] })
+	m.histogram[bucket]++
+}
+
+func (m *ProfiledMutex) Unlock() {
+	m.mu.Unlock()
+}
+
+// Report 打印每个调用方的等待时间和整体直方图
+func (m *ProfiledMutex) Report() {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	fmt.Printf("共获取锁 %d 次，其中 %d 次发生竞争 (%.0f%%)\n",
+		m.total, golden.Varying(m.contended), golden.Varying(float64(m.contended)/float64(m.total)*100))
+
+	callers := make([]string, 0, len(m.waits))
+	for caller := range m.waits {
+		callers = append(callers, caller)
+	}
+	sort.Strings(callers)
+	for _, caller := range callers {
+		var sum, max time.Duration
+		for _, w := range m.waits[caller] {
+			sum += w
+			if w > max {
+				max = w
+			}
+		}
+		n := len(m.waits[caller])
+		fmt.Printf("  %-10s 获取 %4d 次, 平均等待 %-10v 最长等待 %v\n",
+			caller, n, (sum / time.Duration(n)).Round(time.Microsecond), max.Round(time.Microsecond))
+	}
+
+	fmt.Println("等待时间分布:")
+	for i, count := range m.histogram {
+		label := fmt.Sprintf(">= %v", waitBuckets[len(waitBuckets)-1])
+		if i < len(waitBuckets) {
+			label = fmt.Sprintf("< %v", waitBuckets[i])
+		}
+		bar := strings.Repeat("█", (count*40+m.total-1)/m.total)
+		fmt.Printf("  %-10s %4d %s\n", label, golden.Varying(count), golden.Varying(bar))
+	}
+}
+
+// runContention 一个"慢"调用方在锁内停留较久，其他调用方因此排队
+func runContention() {
+	mu := NewProfiledMutex()
+	shared := 0
+
+	var wg sync.WaitGroup
+	// hold为锁内耗时，pause为两次加锁之间在锁外的耗时
+	worker := func(name string, iterations int, hold, pause time.Duration) {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			mu.Lock(name)
+			shared++
+			if hold > 0 {
+				time.Sleep(hold) // 锁内做耗时操作：典型的热点锁成因
+			}
+			mu.Unlock()
+			time.Sleep(pause)
+		}
+	}
+
+	wg.Add(4)
+	go worker("slow", 50, 2*time.Millisecond, time.Millisecond)
+	go worker("fast-1", 200, 0, 200*time.Microsecond)
+	go worker("fast-2", 200, 0, 200*time.Microsecond)
+	go worker("fast-3", 200, 0, 200*time.Microsecond)
+	wg.Wait()
+
+	fmt.Printf("shared = %d\n", shared)
+	mu.Report()
+}
+
+func main() {
+	goroutines := flag.Int("goroutines", 3, "同时增加计数器的goroutine数量")
+	increments := flag.Int("increments", 1000, "每个goroutine增加计数器的次数")
+	demoflag.Parse()
+
+	fmt.Println("=== 基础互斥锁演示 ===")
+
+	var wg sync.WaitGroup
+	expected := *goroutines * *increments
+
+	// 多个goroutine同时增加计数器
+	for i := 0; i < *goroutines; i++ {
+		wg.Add(1)
+		go increment(&wg, *increments)
+	}
+
+	wg.Wait()
+
+	fmt.Printf("最终计数器值: %d (期望值: %d)\n", counter, expected)
+
+	// 演示不使用锁的情况
+	counter = 0
+	fmt.Println("\n不使用锁的情况:")
+
+	for i := 0; i < *goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < *increments; j++ {
+				counter++ // 没有锁保护，可能出现竞态条件
+			}
+		}()
+	}
+
+	wg.Wait()
+	fmt.Printf("不安全的计数器值: %d (可能不等于%d)\n", golden.Varying(counter), expected)
+
+	// TryLock: 不阻塞地尝试加锁
+	fmt.Println("\nTryLock演示:")
+	var refreshMu sync.Mutex
+	refreshed := 0
+	end := golden.Unordered() // 哪个goroutine抢到锁不固定
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go tryRefresh(i, &refreshMu, &refreshed, &wg)
+	}
+	wg.Wait()
+	end()
+	fmt.Printf("3个goroutine尝试刷新，实际刷新 %d 次\n", refreshed)
+
+	// 锁竞争统计: 找出热点锁和等待最久的调用方
+	fmt.Println("\n锁竞争统计:")
+	runContention()
+	fmt.Println("TryLock很少是正确的选择；这里用它区分\"立即拿到\"和\"需要排队\"两种情况")
+}
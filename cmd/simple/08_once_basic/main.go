@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
+	"github.com/klsakura/day1/pkg/syncx"
+)
+
+// sync.Once演示
+var once sync.Once
+
+func initialize() {
+	fmt.Println("执行初始化操作...")
+	time.Sleep(1 * time.Second)
+	fmt.Println("初始化完成！")
+}
+
+func worker(id int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	fmt.Printf("工作者 %d 尝试初始化\n", id)
+
+	// 确保initialize只执行一次
+	once.Do(initialize)
+
+	fmt.Printf("工作者 %d 继续执行自己的任务\n", id)
+	time.Sleep(500 * time.Millisecond)
+	fmt.Printf("工作者 %d 完成\n", id)
+}
+
+// demoOnceHelpers Go 1.21新增的sync.OnceFunc/OnceValue/OnceValues
+func demoOnceHelpers() {
+	var wg sync.WaitGroup
+
+	// OnceFunc: 返回一个只会执行一次的函数，适合关闭、清理
+	cleanup := sync.OnceFunc(func() { fmt.Println("执行清理（只会打印一次）") })
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cleanup()
+		}()
+	}
+	wg.Wait()
+
+	// OnceValue: 第一次调用时计算，之后直接返回缓存的值
+	calls := 0
+	loadConfig := sync.OnceValue(func() map[string]string {
+		calls++
+		return map[string]string{"env": "dev"}
+	})
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = loadConfig()["env"]
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("OnceValue: 3次调用，加载函数执行了 %d 次\n", calls)
+
+	// OnceValues: 错误也会被缓存，失败之后再调用仍返回同一个错误
+	attempts := 0
+	connect := sync.OnceValues(func() (string, error) {
+		attempts++
+		return "", errors.New("连接失败")
+	})
+	_, err1 := connect()
+	_, err2 := connect()
+	fmt.Printf("OnceValues: 两次调用都返回 %v / %v，连接函数只执行了 %d 次\n", err1, err2, attempts)
+}
+
+// demoResettableOnce 初始化失败后重试，成功后不再执行，Reset后重新初始化
+func demoResettableOnce() {
+	// 实现在pkg/syncx中，并发下的行为由那里的测试覆盖（go test -race ./pkg/syncx）
+	var once syncx.ResettableOnce
+	attempts := 0
+	connect := func() error {
+		attempts++
+		fmt.Printf("第 %d 次尝试连接...\n", attempts)
+		if attempts < 3 {
+			return errors.New("连接失败")
+		}
+		return nil
+	}
+
+	for i := 1; i <= 4; i++ {
+		err := once.Do(connect)
+		fmt.Printf("调用 %d: err=%v\n", i, err)
+	}
+
+	once.Reset()
+	fmt.Println("Reset后再次调用:")
+	fmt.Printf("err=%v, 共尝试 %d 次\n", once.Do(connect), attempts)
+}
+
+func main() {
+	workers := flag.Int("workers", 5, "同时尝试初始化的工作者数量")
+	demoflag.Parse()
+
+	fmt.Println("=== sync.Once演示 ===")
+
+	var wg sync.WaitGroup
+
+	// 启动多个工作者，每个都尝试初始化
+	end := golden.Unordered()
+	for i := 1; i <= *workers; i++ {
+		wg.Add(1)
+		go worker(i, &wg)
+	}
+
+	wg.Wait()
+	end()
+	fmt.Println("所有工作者完成！")
+
+	fmt.Println("\n=== OnceFunc / OnceValue / OnceValues ===")
+	demoOnceHelpers()
+
+	fmt.Println("\n=== 可重置的Once ===")
+	demoResettableOnce()
+}
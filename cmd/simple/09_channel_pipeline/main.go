@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/klsakura/day1/pkg/chanx"
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// 简单的channel管道演示
+func generator(nums ...int) <-chan int {
+	out := make(chan int)
+	go func() {
+		for _, n := range nums {
+			out <- n
+		}
+		close(out)
+	}()
+	return out
+}
+
+func square(in <-chan int) <-chan int {
+	out := make(chan int)
+	go func() {
+		for n := range in {
+			out <- n * n
+		}
+		close(out)
+	}()
+	return out
+}
+
+func main() {
+	n := flag.Int("n", 5, "管道输入1..n")
+	take := flag.Int("take", 3, "组合器管道从1..2n的奇数中取前几个")
+	demoflag.Parse()
+	if *n < 1 || *take < 0 {
+		fmt.Fprintln(os.Stderr, "n至少为1，take不能为负数")
+		os.Exit(2)
+	}
+	nums := make([]int, 2**n)
+	for i := range nums {
+		nums[i] = i + 1
+	}
+
+	fmt.Println("=== 简单Channel管道演示 ===")
+
+	// 设置管道: generator -> square
+	numbers := generator(nums[:*n]...)
+	squares := square(numbers)
+
+	// 消费结果
+	fmt.Println("原数字 -> 平方:")
+	for result := range squares {
+		fmt.Printf("结果: %d\n", result)
+	}
+
+	// 同样的管道也可以用 pkg/chanx 中的泛型组合器搭建
+	// ctx取消时所有阶段都会退出，Take提前结束也不会让上游goroutine泄漏
+	fmt.Printf("\n使用组合器: 1..%d -> 取奇数 -> 平方 -> 前%d个\n", len(nums), *take)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	odds := chanx.Filter(ctx, chanx.Generate(ctx, nums...), func(n int) bool { return n%2 == 1 })
+	first3 := chanx.Take(ctx, chanx.Map(ctx, odds, func(n int) int { return n * n }), *take)
+	sum := chanx.Reduce(ctx, first3, 0, func(acc, n int) int {
+		fmt.Printf("结果: %d\n", n)
+		return acc + n
+	})
+	fmt.Printf("总和: %d\n", sum)
+
+	fmt.Println("管道处理完成！")
+}
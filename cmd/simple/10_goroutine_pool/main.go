@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
+	"github.com/klsakura/day1/pkg/pool"
+)
+
+// 简单的goroutine池演示
+type Job struct {
+	ID   int
+	Data string
+}
+
+type Result struct {
+	Job Job
+	Sum int
+}
+
+func worker(id int, jobs <-chan Job, results chan<- Result, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for job := range jobs {
+		fmt.Printf("工作者 %d 处理任务 %d\n", golden.Varying(id), job.ID)
+
+		// 模拟工作：计算字符串长度
+		time.Sleep(500 * time.Millisecond)
+		sum := len(job.Data)
+
+		results <- Result{job, sum}
+		fmt.Printf("工作者 %d 完成任务 %d\n", golden.Varying(id), job.ID)
+	}
+}
+
+// measure 任务函数：计算字符串长度，耗时与长度成反比，所以后提交的任务可能先完成
+func measure(ctx context.Context, data string) (int, error) {
+	if data == "" {
+		return 0, errors.New("空字符串")
+	}
+
+	select {
+	case <-time.After(time.Duration(300/len(data)) * 10 * time.Millisecond):
+		return len(data), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// runGenericPool 使用 pkg/pool 的泛型池：每个任务返回错误，结果按提交顺序输出
+func runGenericPool(data []string, workers int, stopTimeout time.Duration) {
+	p := pool.New(workers, len(data), measure)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range p.Results() {
+			if r.Err != nil {
+				fmt.Printf("任务 %d (%q) 失败: %v\n", r.Index, data[r.Index], r.Err)
+				continue
+			}
+			fmt.Printf("任务 %d (%q) -> 长度: %d\n", r.Index, data[r.Index], r.Value)
+		}
+	}()
+
+	for _, d := range data {
+		p.Submit(d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	err := p.Stop(ctx)
+	<-done
+	fmt.Printf("Stop返回: %v\n", err)
+
+	if _, err := p.Submit("late"); err != nil {
+		fmt.Printf("停止后提交: %v\n", err)
+	}
+}
+
+func main() {
+	workers := flag.Int("workers", 3, "工作者数量")
+	jobCount := flag.Int("jobs", 5, "任务数量")
+	demoflag.Parse()
+
+	fmt.Println("=== 简单Goroutine池演示 ===")
+
+	numWorkers, numJobs := *workers, *jobCount
+
+	jobs := make(chan Job, numJobs)
+	results := make(chan Result, numJobs)
+
+	var wg sync.WaitGroup
+
+	// 哪个工作者处理哪个任务、结果到达的顺序都不固定
+	end := golden.Unordered()
+
+	// 启动工作者
+	for w := 1; w <= numWorkers; w++ {
+		wg.Add(1)
+		go worker(w, jobs, results, &wg)
+	}
+
+	// 发送任务
+	jobData := []string{"hello", "world", "golang", "concurrency", "programming"}
+	for j := 1; j <= numJobs; j++ {
+		jobs <- Job{ID: j, Data: jobData[(j-1)%len(jobData)]}
+	}
+	close(jobs)
+
+	// 等待所有工作者完成
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// 收集结果
+	fmt.Println("\n结果:")
+	for result := range results {
+		fmt.Printf("任务 %d (%s) -> 长度: %d\n",
+			result.Job.ID, result.Job.Data, result.Sum)
+	}
+	end()
+
+	fmt.Println("所有任务完成！")
+
+	fmt.Println("\n=== 泛型任务池: 错误返回与有序结果 ===")
+	runGenericPool([]string{"a", "hello", "", "golang", "concurrency", "go"}, 3, 5*time.Second)
+
+	fmt.Println("\n=== 泛型任务池: Stop期限不足时取消剩余任务 ===")
+	runGenericPool([]string{"concurrency", "programming", "goroutine", "a", "b", "c"}, 2, 500*time.Millisecond)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
+	"github.com/klsakura/day1/pkg/rwstore"
+)
+
+// 读写锁演示：读多写少时，RWMutex允许多个读者同时持有锁
+
+// 演示1: 统计同时持有读锁的读者数量
+func concurrentReaders() {
+	var mu sync.RWMutex
+	var active, maxActive int64
+	var wg sync.WaitGroup
+
+	// 读者拿到读锁的先后不固定
+	end := golden.Unordered()
+	for i := 1; i <= 5; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			mu.RLock()
+			n := atomic.AddInt64(&active, 1)
+			for {
+				cur := atomic.LoadInt64(&maxActive)
+				if n <= cur || atomic.CompareAndSwapInt64(&maxActive, cur, n) {
+					break
+				}
+			}
+			fmt.Printf("读者 %d 持有读锁 (当前读者数: %d)\n", id, golden.Varying(n))
+			time.Sleep(100 * time.Millisecond)
+			atomic.AddInt64(&active, -1)
+			mu.RUnlock()
+		}(i)
+	}
+
+	// 写者需要等所有读者释放读锁
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	mu.Lock()
+	fmt.Printf("写者获得写锁，等待了 %v\n", time.Since(start).Round(10*time.Millisecond))
+	mu.Unlock()
+
+	wg.Wait()
+	end()
+	fmt.Printf("同时持有读锁的最大读者数: %d\n", maxActive)
+}
+
+// measureStore 4倍GOMAXPROCS个goroutine随机读写d时间，返回每秒操作数；readPercent为读操作所占百分比。
+// 同样的对比也写成了基准测试：go test -bench Store ./pkg/rwstore
+func measureStore(s rwstore.Store, readPercent int, d time.Duration) float64 {
+	var ops int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(d)
+	for g := 0; g < 4*runtime.GOMAXPROCS(0); g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(time.Now().UnixNano()))
+			n := int64(0)
+			for time.Now().Before(deadline) {
+				key := r.Intn(1000)
+				if r.Intn(100) < readPercent {
+					s.Get(key)
+				} else {
+					s.Set(key, key)
+				}
+				n++
+			}
+			atomic.AddInt64(&ops, n)
+		}()
+	}
+	wg.Wait()
+	return float64(ops) / time.Since(start).Seconds()
+}
+
+func parseRatios(s string) ([]int, error) {
+	var ratios []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 0 || n > 100 {
+			return nil, fmt.Errorf("无效的读比例 %q", field)
+		}
+		ratios = append(ratios, n)
+	}
+	return ratios, nil
+}
+
+func main() {
+	ratiosFlag := flag.String("ratios", "100,99,90,50,10", "读操作百分比，逗号分隔")
+	demoflag.Parse()
+
+	ratios, err := parseRatios(*ratiosFlag)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("=== 读写锁演示 ===")
+
+	fmt.Println("\n1. 多个读者同时持有读锁:")
+	concurrentReaders()
+
+	fmt.Printf("\n2. Mutex与RWMutex吞吐对比 (GOMAXPROCS=%d，每项约1秒):\n", golden.Varying(runtime.GOMAXPROCS(0)))
+	fmt.Printf("%-8s %14s %14s %8s\n", "读比例", "Mutex ops/s", "RWMutex ops/s", "倍数")
+	for _, ratio := range ratios {
+		m := measureStore(rwstore.NewMutexStore(1000), ratio, time.Second)
+		rw := measureStore(rwstore.NewRWMutexStore(1000), ratio, time.Second)
+		fmt.Printf("%-8s %14.0f %14.0f %7.2fx\n", strconv.Itoa(ratio)+"%", golden.Varying(m), golden.Varying(rw), golden.Varying(rw/m))
+	}
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 读操作占绝大多数且临界区较长时，RWMutex明显更快")
+	fmt.Println("2. 写操作增多后，RWMutex的额外开销使它不比Mutex快，甚至更慢")
+	fmt.Println("3. 只有一个CPU时读者无法真正并行，两者差别不大")
+	fmt.Println("4. 可以用 -ratios 指定其他读比例，例如 go run ./cmd/simple/11_rwmutex -ratios=100,75,25")
+}
@@ -0,0 +1,281 @@
+/*
+Golang并发编程学习Demo - 简单级别
+文件：cmd/simple/12_cond_bounded_buffer/main.go
+主题：用sync.Cond实现有界缓冲区
+
+本示例演示：
+1. sync.Cond的Wait/Signal/Broadcast
+2. 为什么Wait必须放在for循环里（被唤醒时条件不一定成立）
+3. Signal与Broadcast的区别：Broadcast会产生无效唤醒
+4. 关闭时用Broadcast唤醒所有等待者
+5. 与simple/04中基于缓冲channel的实现对比
+
+学习要点：
+- Wait会先释放锁，被唤醒后重新加锁再返回
+- 被唤醒到重新拿到锁之间，条件可能已被其他goroutine改变，所以要循环检查
+- 状态变化只影响一个等待者时用Signal，影响所有等待者时（如关闭）用Broadcast
+- 大多数情况下缓冲channel更简单；Cond适合等待条件复杂、无法用channel表达的场景
+
+运行方式：go run ./cmd/simple/12_cond_bounded_buffer [-producers=4] [-consumers=8] [-total=20000] [-capacity=4]
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
+)
+
+var ErrBufferClosed = errors.New("buffer closed")
+
+// BoundedBuffer 基于sync.Cond的有界缓冲区
+type BoundedBuffer struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond // 有数据可读
+	notFull  *sync.Cond // 有空间可写
+	items    []int
+	capacity int
+	closed   bool
+
+	broadcast bool // true时每次Put/Get都用Broadcast，用于对比
+	rewaits   int  // 被唤醒后发现条件仍不满足、需要再次等待的次数
+}
+
+func NewBoundedBuffer(capacity int, broadcast bool) *BoundedBuffer {
+	b := &BoundedBuffer{capacity: capacity, broadcast: broadcast}
+	b.notEmpty = sync.NewCond(&b.mu)
+	b.notFull = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *BoundedBuffer) wake(c *sync.Cond) {
+	if b.broadcast {
+		c.Broadcast()
+	} else {
+		c.Signal()
+	}
+}
+
+// Put 写入一个元素，缓冲区满时等待；关闭后返回ErrBufferClosed
+func (b *BoundedBuffer) Put(v int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	woken := false
+	// 必须用for而不是if：醒来时空间可能已被其他生产者抢走，或者缓冲区已关闭
+	for len(b.items) == b.capacity && !b.closed {
+		if woken {
+			b.rewaits++
+		}
+		b.notFull.Wait()
+		woken = true
+	}
+	if b.closed {
+		return ErrBufferClosed
+	}
+
+	b.items = append(b.items, v)
+	b.wake(b.notEmpty)
+	return nil
+}
+
+// Get 读取一个元素，缓冲区空时等待；关闭且读空后返回ok=false
+func (b *BoundedBuffer) Get() (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	woken := false
+	for len(b.items) == 0 && !b.closed {
+		if woken {
+			b.rewaits++
+		}
+		b.notEmpty.Wait()
+		woken = true
+	}
+	if len(b.items) == 0 {
+		return 0, false
+	}
+
+	v := b.items[0]
+	b.items = b.items[1:]
+	b.wake(b.notFull)
+	return v, true
+}
+
+// Close 关闭缓冲区，唤醒所有等待者：这种"所有人都要知道"的状态变化必须用Broadcast
+func (b *BoundedBuffer) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	b.notEmpty.Broadcast()
+	b.notFull.Broadcast()
+}
+
+func (b *BoundedBuffer) Rewaits() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rewaits
+}
+
+// runCondWorkload 多个生产者和消费者通过BoundedBuffer传递total个元素
+func runCondWorkload(buf *BoundedBuffer, producers, consumers, total int) (int, time.Duration) {
+	start := time.Now()
+	var producerWg, consumerWg sync.WaitGroup
+	sums := make([]int, consumers)
+
+	for p := 0; p < producers; p++ {
+		producerWg.Add(1)
+		go func(p int) {
+			defer producerWg.Done()
+			for i := p; i < total; i += producers {
+				buf.Put(i)
+			}
+		}(p)
+	}
+	for c := 0; c < consumers; c++ {
+		consumerWg.Add(1)
+		go func(c int) {
+			defer consumerWg.Done()
+			for {
+				v, ok := buf.Get()
+				if !ok {
+					return
+				}
+				sums[c] += v
+			}
+		}(c)
+	}
+
+	producerWg.Wait()
+	buf.Close()
+	consumerWg.Wait()
+
+	sum := 0
+	for _, s := range sums {
+		sum += s
+	}
+	return sum, time.Since(start)
+}
+
+// runChannelWorkload 同样的负载，用缓冲channel实现（关闭channel相当于Close+Broadcast）
+func runChannelWorkload(capacity, producers, consumers, total int) (int, time.Duration) {
+	start := time.Now()
+	ch := make(chan int, capacity)
+	var producerWg, consumerWg sync.WaitGroup
+	sums := make([]int, consumers)
+
+	for p := 0; p < producers; p++ {
+		producerWg.Add(1)
+		go func(p int) {
+			defer producerWg.Done()
+			for i := p; i < total; i += producers {
+				ch <- i
+			}
+		}(p)
+	}
+	for c := 0; c < consumers; c++ {
+		consumerWg.Add(1)
+		go func(c int) {
+			defer consumerWg.Done()
+			for v := range ch {
+				sums[c] += v
+			}
+		}(c)
+	}
+
+	producerWg.Wait()
+	close(ch)
+	consumerWg.Wait()
+
+	sum := 0
+	for _, s := range sums {
+		sum += s
+	}
+	return sum, time.Since(start)
+}
+
+func main() {
+	producersFlag := flag.Int("producers", 4, "对比测试中的生产者数量")
+	consumersFlag := flag.Int("consumers", 8, "对比测试中的消费者数量")
+	totalFlag := flag.Int("total", 20000, "对比测试中传递的元素总数")
+	capacityFlag := flag.Int("capacity", 4, "对比测试中缓冲区的容量")
+	demoflag.Parse()
+
+	fmt.Println("=== sync.Cond有界缓冲区演示 ===")
+
+	// 演示1: 基本的生产和消费，缓冲区满时生产者等待
+	fmt.Println("\n1. 容量为2的缓冲区:")
+	buf := NewBoundedBuffer(2, false)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 5; i++ {
+			fmt.Printf("生产者写入 %d...\n", i)
+			buf.Put(i)
+		}
+		buf.Close()
+	}()
+	time.Sleep(100 * time.Millisecond) // 让生产者先填满缓冲区
+	end := golden.Unordered()          // 读出之后生产者随即写入，两边的输出谁先谁后不固定
+	for {
+		v, ok := buf.Get()
+		if !ok {
+			break
+		}
+		fmt.Printf("消费者读出 %d\n", v)
+		time.Sleep(50 * time.Millisecond)
+	}
+	<-done
+	end()
+	fmt.Println("缓冲区已关闭并读空")
+
+	// 演示2: 关闭时Broadcast唤醒所有阻塞的消费者
+	fmt.Println("\n2. 关闭时唤醒所有等待者:")
+	buf = NewBoundedBuffer(2, false)
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			_, ok := buf.Get()
+			fmt.Printf("消费者 %d 醒来, ok=%v\n", id, ok)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	fmt.Println("关闭缓冲区")
+	end = golden.Unordered() // 被唤醒的顺序不固定
+	buf.Close()
+	wg.Wait()
+	end()
+	fmt.Printf("关闭后Put返回: %v\n", buf.Put(1))
+
+	// 演示3: Signal与Broadcast对比，以及与channel实现对比
+	producers, consumers, total, capacity := *producersFlag, *consumersFlag, *totalFlag, *capacityFlag
+	expected := total * (total - 1) / 2
+	fmt.Printf("\n3. %d个生产者、%d个消费者传递 %d 个元素 (容量%d):\n", producers, consumers, total, capacity)
+
+	signalBuf := NewBoundedBuffer(capacity, false)
+	sum, elapsed := runCondWorkload(signalBuf, producers, consumers, total)
+	fmt.Printf("Cond+Signal:    总和正确=%v 耗时=%-10v 再次等待=%d\n",
+		sum == expected, elapsed.Round(time.Millisecond), golden.Varying(signalBuf.Rewaits()))
+
+	broadcastBuf := NewBoundedBuffer(capacity, true)
+	sum, elapsed = runCondWorkload(broadcastBuf, producers, consumers, total)
+	fmt.Printf("Cond+Broadcast: 总和正确=%v 耗时=%-10v 再次等待=%d\n",
+		sum == expected, elapsed.Round(time.Millisecond), golden.Varying(broadcastBuf.Rewaits()))
+
+	sum, elapsed = runChannelWorkload(capacity, producers, consumers, total)
+	fmt.Printf("缓冲channel:    总和正确=%v 耗时=%v\n", sum == expected, elapsed.Round(time.Millisecond))
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. Broadcast每次唤醒所有等待者，但只有一个能拿到元素，其余醒来后只能再次等待")
+	fmt.Println("2. 即使只用Signal也可能出现再次等待：唤醒后、拿到锁前，元素已被其他goroutine取走")
+	fmt.Println("3. 缓冲channel内置了等待、唤醒和关闭语义，能用channel时优先用channel")
+}
@@ -0,0 +1,206 @@
+/*
+Golang并发编程学习Demo - 简单级别
+文件：cmd/simple/13_atomic_config/main.go
+主题：用atomic.Value / atomic.Pointer热更新配置
+
+本示例演示：
+1. 多个读者持续读取配置，写者定期整体替换配置
+2. atomic.Pointer[T]（泛型，类型安全）与atomic.Value（存any）两种写法
+3. 与RWMutex保护的版本对比读取吞吐
+4. 原地修改共享配置导致的数据竞争（-racy 参数）
+
+学习要点：
+- 配置对象创建后不再修改（不可变），更新时构造新对象再原子替换指针
+- 读者拿到的总是某个完整版本，不会读到一半新一半旧的配置
+- atomic.Value要求每次Store的类型相同，Load后需要类型断言
+- 原地修改字段是数据竞争，用 go run -race ./cmd/simple/13_atomic_config -racy 可以看到报告
+
+运行方式：go run ./cmd/simple/13_atomic_config [-racy] [-readers=8] [-duration=500ms]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
+)
+
+// Config 不可变配置：Version和Checksum总是一起更新，读者用它们检查配置是否完整
+type Config struct {
+	Version  int
+	Timeout  time.Duration
+	Checksum int
+}
+
+func newConfig(version int) *Config {
+	return &Config{
+		Version:  version,
+		Timeout:  time.Duration(version) * time.Millisecond,
+		Checksum: version * 31,
+	}
+}
+
+func (c *Config) valid() bool {
+	return c.Checksum == c.Version*31
+}
+
+// ConfigSource 不同的配置存取方式
+type ConfigSource interface {
+	Load() *Config
+	Store(*Config)
+}
+
+// PointerSource 使用atomic.Pointer[Config]
+type PointerSource struct {
+	p atomic.Pointer[Config]
+}
+
+func (s *PointerSource) Load() *Config   { return s.p.Load() }
+func (s *PointerSource) Store(c *Config) { s.p.Store(c) }
+
+// ValueSource 使用atomic.Value，Load后需要类型断言
+type ValueSource struct {
+	v atomic.Value
+}
+
+func (s *ValueSource) Load() *Config   { return s.v.Load().(*Config) }
+func (s *ValueSource) Store(c *Config) { s.v.Store(c) }
+
+// MutexSource 使用RWMutex保护指针
+type MutexSource struct {
+	mu  sync.RWMutex
+	cfg *Config
+}
+
+func (s *MutexSource) Load() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+func (s *MutexSource) Store(c *Config) {
+	s.mu.Lock()
+	s.cfg = c
+	s.mu.Unlock()
+}
+
+// RacySource 错误示范：写者原地修改同一个Config对象，没有任何同步
+type RacySource struct {
+	cfg *Config
+}
+
+func (s *RacySource) Load() *Config { return s.cfg }
+func (s *RacySource) Store(c *Config) {
+	s.cfg.Version = c.Version
+	time.Sleep(time.Microsecond) // 放大两次写入之间的窗口
+	s.cfg.Checksum = c.Checksum
+}
+
+// hotSwap 启动readers个读者持续读取，写者每10ms替换一次配置，运行duration后停止
+// 返回总读取次数、读者看到的不同版本数、读到不完整配置的次数
+func hotSwap(src ConfigSource, readers int, duration time.Duration) (reads int64, versions int, torn int64) {
+	src.Store(newConfig(1))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	seen := make([]map[int]bool, readers)
+
+	for i := 0; i < readers; i++ {
+		seen[i] = make(map[int]bool)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var n, bad int64
+			for {
+				select {
+				case <-stop:
+					atomic.AddInt64(&reads, n)
+					atomic.AddInt64(&torn, bad)
+					return
+				default:
+				}
+				cfg := src.Load()
+				// 同一个读者多次读取字段时，要使用同一份快照cfg
+				version, checksum := cfg.Version, cfg.Checksum
+				if checksum != version*31 {
+					bad++
+				}
+				seen[i][version] = true
+				n++
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for version := 2; ; version++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				src.Store(newConfig(version))
+			}
+		}
+	}()
+
+	time.Sleep(duration)
+	close(stop)
+	wg.Wait()
+
+	all := make(map[int]bool)
+	for _, m := range seen {
+		for v := range m {
+			all[v] = true
+		}
+	}
+	return reads, len(all), torn
+}
+
+func main() {
+	racy := flag.Bool("racy", false, "额外运行原地修改配置的错误示范（配合 -race 查看报告）")
+	readersFlag := flag.Int("readers", 8, "持续读取配置的读者数量")
+	durationFlag := flag.Duration("duration", 500*time.Millisecond, "每种实现的运行时长")
+	demoflag.Parse()
+
+	fmt.Println("=== 配置热更新演示 ===")
+
+	sources := []struct {
+		name string
+		src  ConfigSource
+	}{
+		{"atomic.Pointer", &PointerSource{}},
+		{"atomic.Value", &ValueSource{}},
+		{"RWMutex", &MutexSource{}},
+	}
+
+	readers, duration := *readersFlag, *durationFlag
+	fmt.Printf("%d个读者持续读取，写者每10ms替换一次配置，运行 %v\n\n", readers, duration)
+
+	for _, s := range sources {
+		reads, versions, torn := hotSwap(s.src, readers, duration)
+		fmt.Printf("%-15s 读取 %9d 次 (%5.1f M/s), 看到 %2d 个版本, 不完整配置 %d 次\n",
+			s.name, golden.Varying(reads), golden.Varying(float64(reads)/duration.Seconds()/1e6), golden.Varying(versions), torn)
+	}
+
+	if *racy {
+		fmt.Println("\n错误示范: 原地修改共享配置")
+		src := &RacySource{cfg: newConfig(1)}
+		reads, versions, torn := hotSwap(src, readers, duration)
+		fmt.Printf("%-15s 读取 %9d 次, 看到 %2d 个版本, 不完整配置 %d 次\n", "原地修改", reads, versions, torn)
+		fmt.Printf("最终配置是否完整: %v\n", src.Load().valid())
+	}
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 替换指针的三种方式都不会读到不完整的配置")
+	fmt.Println("2. 原子操作的读取不需要加锁，读多写少时吞吐更高")
+	fmt.Println("3. 运行 go run -race ./cmd/simple/13_atomic_config -racy 查看原地修改引发的数据竞争")
+}
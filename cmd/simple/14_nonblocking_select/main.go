@@ -0,0 +1,220 @@
+/*
+Golang并发编程学习Demo - 简单级别
+文件：cmd/simple/14_nonblocking_select/main.go
+主题：非阻塞channel操作
+
+本示例演示：
+1. 尝试发送（try-send）：缓冲区满时立即返回，而不是阻塞
+2. 尝试接收（try-receive）：没有数据时立即返回
+3. 清空（drain）channel中已有的数据
+4. 用reflect.Select在数量不固定的一组channel上做select
+
+学习要点：
+- select带default分支时不会阻塞：没有case就绪就执行default
+- try-send常用于"丢弃而不是阻塞"的场景，例如指标上报、通知合并
+- drain只取出当前已缓冲的数据，不会等待之后才到达的数据
+- 普通select的case数量在编译时固定，数量在运行时才确定时需要reflect.Select
+
+运行方式：go run ./cmd/simple/14_nonblocking_select [-buffer=3] [-events=6] [-channels=4]
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+// trySend 尝试发送，channel已满时返回false
+func trySend(ch chan<- int, v int) bool {
+	select {
+	case ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+// tryReceive 尝试接收，没有数据时ok为false；closed表示channel已关闭
+func tryReceive(ch <-chan int) (v int, ok bool, closed bool) {
+	select {
+	case v, open := <-ch:
+		if !open {
+			return 0, false, true
+		}
+		return v, true, false
+	default:
+		return 0, false, false
+	}
+}
+
+// drain 取出channel中当前所有缓冲的数据并返回
+func drain(ch <-chan int) []int {
+	var out []int
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return out
+			}
+			out = append(out, v)
+		default:
+			return out
+		}
+	}
+}
+
+// selectAny 在任意数量的channel上等待，返回第一个就绪的channel下标和值
+// 已关闭的channel会从候选中移除，全部关闭后返回-1
+func selectAny(chans []<-chan int) (int, int) {
+	cases := make([]reflect.SelectCase, len(chans))
+	for i, ch := range chans {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+	}
+
+	for remaining := len(cases); remaining > 0; {
+		chosen, value, ok := reflect.Select(cases)
+		if !ok {
+			// 置为零值后reflect.Select会忽略这个case，相当于普通select中的nil channel
+			cases[chosen].Chan = reflect.Value{}
+			remaining--
+			continue
+		}
+		return chosen, int(value.Int())
+	}
+	return -1, 0
+}
+
+// demonstrateTrySend 事件通知：缓冲区满时丢弃新事件，发送方永不阻塞
+func demonstrateTrySend(buffer, count int) {
+	fmt.Println("=== 尝试发送 ===")
+
+	events := make(chan int, buffer)
+	dropped := 0
+	for i := 1; i <= count; i++ {
+		if trySend(events, i) {
+			fmt.Printf("事件 %d 已发送\n", i)
+		} else {
+			dropped++
+			fmt.Printf("事件 %d 被丢弃（缓冲区已满）\n", i)
+		}
+	}
+	fmt.Printf("发送方没有阻塞，丢弃了 %d 个事件\n", dropped)
+
+	// 合并通知：容量为1的channel，重复的通知自动合并为一次
+	wakeup := make(chan int, 1)
+	for i := 0; i < 5; i++ {
+		trySend(wakeup, 1)
+	}
+	fmt.Printf("发送5次唤醒通知，channel中只有 %d 个\n", len(wakeup))
+}
+
+// demonstrateTryReceive 轮询：没有数据时去做别的事情
+func demonstrateTryReceive() {
+	fmt.Println("\n=== 尝试接收 ===")
+
+	// 容量为1：发送和关闭一起完成，收到结果后的下一次轮询一定看到channel已关闭
+	results := make(chan int, 1)
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		results <- 42
+		close(results)
+	}()
+
+	for i := 1; ; i++ {
+		v, ok, closed := tryReceive(results)
+		switch {
+		case closed:
+			fmt.Println("channel已关闭，停止轮询")
+			return
+		case ok:
+			fmt.Printf("第 %d 次轮询收到结果: %d\n", i, v)
+		default:
+			fmt.Printf("第 %d 次轮询没有结果，先做其他工作\n", i)
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
+// demonstrateDrain 关闭前清空缓冲区中的残留数据
+func demonstrateDrain() {
+	fmt.Println("\n=== 清空channel ===")
+
+	queue := make(chan int, 10)
+	for i := 1; i <= 4; i++ {
+		queue <- i
+	}
+
+	fmt.Printf("清空前长度: %d\n", len(queue))
+	fmt.Printf("取出: %v\n", drain(queue))
+	fmt.Printf("清空后长度: %d\n", len(queue))
+	fmt.Printf("再次清空（没有数据，立即返回）: %v\n", drain(queue))
+}
+
+// demonstrateReflectSelect 在运行时才确定数量的n个channel上select
+func demonstrateReflectSelect(n int) {
+	fmt.Println("\n=== reflect.Select动态select ===")
+
+	chans := make([]<-chan int, n)
+	for i := 0; i < n; i++ {
+		ch := make(chan int)
+		chans[i] = ch
+		go func(id int, ch chan<- int) {
+			defer close(ch)
+			for j := 1; j <= 2; j++ {
+				time.Sleep(time.Duration(id*40+j*30) * time.Millisecond)
+				ch <- id*10 + j
+			}
+		}(i, ch)
+	}
+
+	// 每轮重新从所有channel中选择，直到全部关闭
+	cases := make([]reflect.SelectCase, n)
+	for i, ch := range chans {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+	}
+	for remaining := n; remaining > 0; {
+		chosen, value, ok := reflect.Select(cases)
+		if !ok {
+			fmt.Printf("channel %d 已关闭\n", chosen)
+			cases[chosen].Chan = reflect.Value{}
+			remaining--
+			continue
+		}
+		fmt.Printf("从 channel %d 收到 %d\n", chosen, value.Int())
+	}
+
+	// selectAny封装了同样的逻辑，只取第一个就绪的值
+	fast := make(chan int, 1)
+	fast <- 7
+	idx, v := selectAny([]<-chan int{make(chan int), fast, make(chan int)})
+	fmt.Printf("selectAny: channel %d 最先就绪, 值 %d\n", idx, v)
+}
+
+func main() {
+	buffer := flag.Int("buffer", 3, "尝试发送演示中事件channel的容量")
+	events := flag.Int("events", 6, "尝试发送的事件数量，超过-buffer的部分被丢弃")
+	channels := flag.Int("channels", 4, "reflect.Select演示中的channel数量")
+	demoflag.Parse()
+	if *buffer < 0 || *events < 0 || *channels < 1 {
+		fmt.Fprintln(os.Stderr, "buffer和events不能为负数，channels至少为1")
+		os.Exit(2)
+	}
+
+	fmt.Println("=== 非阻塞Channel操作演示 ===")
+
+	demonstrateTrySend(*buffer, *events)
+	demonstrateTryReceive()
+	demonstrateDrain()
+	demonstrateReflectSelect(*channels)
+
+	fmt.Println("\n观察要点：")
+	fmt.Println("1. 带default的select不会阻塞，适合丢弃、轮询和清空")
+	fmt.Println("2. 轮询会空转消耗CPU，能阻塞等待时优先阻塞等待")
+	fmt.Println("3. reflect.Select比普通select慢，只在channel数量动态变化时使用")
+}
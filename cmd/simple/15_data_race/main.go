@@ -0,0 +1,349 @@
+/*
+Golang并发编程学习Demo - 简单级别
+文件：cmd/simple/15_data_race/main.go
+主题：数据竞争及其修复
+
+本示例演示：
+1. 竞争的计数器、map和切片append
+2. 每种竞争分别用互斥锁、原子操作或channel修复
+3. 在竞争检测器下运行这些用例，查看检测报告
+
+学习要点：
+- 多个goroutine同时访问同一变量、且至少一个是写操作，就是数据竞争
+- 竞争不一定每次都导致错误结果，所以要依靠 -race 检测
+- 并发写map会直接导致程序崩溃（fatal error: concurrent map writes）
+- 修复方式：互斥锁保护、原子操作、或者让唯一的goroutine拥有数据并通过channel访问
+
+运行方式：
+  go run ./cmd/simple/15_data_race                       只运行修复后的版本
+  go run ./cmd/simple/15_data_race -racy                 同时运行有竞争的版本（map用例可能崩溃）
+  go run -race ./cmd/simple/15_data_race -racy           在竞争检测器下运行
+  go run ./cmd/simple/15_data_race -under-race           自动以 -race 逐个运行每个用例并汇总报告
+  go run ./cmd/simple/15_data_race -case=counter         只运行某一类用例（counter/map/slice）
+*/
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+)
+
+const workers = 8
+const perWorker = 1000
+
+// ===== 计数器 =====
+
+func racyCounter() int {
+	counter := 0
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				counter++ // 读-改-写不是原子的
+			}
+		}()
+	}
+	wg.Wait()
+	return counter
+}
+
+func mutexCounter() int {
+	counter := 0
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				mu.Lock()
+				counter++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return counter
+}
+
+func atomicCounter() int {
+	var counter int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				atomic.AddInt64(&counter, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(counter)
+}
+
+// ===== map =====
+
+func racyMap() int {
+	m := make(map[int]int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				m[w*perWorker+i] = i // 并发写map
+			}
+		}(w)
+	}
+	wg.Wait()
+	return len(m)
+}
+
+func mutexMap() int {
+	m := make(map[int]int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				mu.Lock()
+				m[w*perWorker+i] = i
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+	return len(m)
+}
+
+// channelMap 只有owner goroutine访问map，其他goroutine通过channel提交写入
+func channelMap() int {
+	type entry struct{ key, value int }
+	writes := make(chan entry)
+	size := make(chan int)
+
+	go func() {
+		m := make(map[int]int)
+		for e := range writes {
+			m[e.key] = e.value
+		}
+		size <- len(m)
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				writes <- entry{w*perWorker + i, i}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(writes)
+	return <-size
+}
+
+// ===== 切片append =====
+
+func racySlice() int {
+	var s []int
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				s = append(s, i) // 多个goroutine同时修改切片头和底层数组
+			}
+		}()
+	}
+	wg.Wait()
+	return len(s)
+}
+
+func mutexSlice() int {
+	var s []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				mu.Lock()
+				s = append(s, i)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return len(s)
+}
+
+// channelSlice 各goroutine把结果发送到channel，由收集者单独append
+func channelSlice() int {
+	results := make(chan int, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				results <- i
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var s []int
+	for v := range results {
+		s = append(s, v)
+	}
+	return len(s)
+}
+
+// ===== 运行 =====
+
+type variant struct {
+	name string
+	racy bool
+	run  func() int
+	code string // 关键代码
+}
+
+var cases = []struct {
+	name     string
+	variants []variant
+}{
+	{"counter", []variant{
+		{"竞争版本", true, racyCounter, "counter++"},
+		{"互斥锁", false, mutexCounter, "mu.Lock(); counter++"},
+		{"原子操作", false, atomicCounter, "atomic.AddInt64"},
+	}},
+	{"map", []variant{
+		{"竞争版本", true, racyMap, "m[k] = v"},
+		{"互斥锁", false, mutexMap, "mu.Lock(); m[k] = v"},
+		{"channel", false, channelMap, "owner goroutine独占map"},
+	}},
+	{"slice", []variant{
+		{"竞争版本", true, racySlice, "s = append(s, v)"},
+		{"互斥锁", false, mutexSlice, "mu.Lock(); append"},
+		{"channel", false, channelSlice, "收集者单独append"},
+	}},
+}
+
+// raceEnabled 判断当前程序是否以 -race 编译
+func raceEnabled() bool {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return false
+	}
+	for _, s := range info.Settings {
+		if s.Key == "-race" {
+			return s.Value == "true"
+		}
+	}
+	return false
+}
+
+func runCases(only string, includeRacy bool) {
+	expected := workers * perWorker
+	for _, c := range cases {
+		if only != "" && only != c.name {
+			continue
+		}
+		fmt.Printf("\n[%s] 期望结果 %d\n", c.name, expected)
+		for _, v := range c.variants {
+			if v.racy && !includeRacy {
+				continue
+			}
+			got := v.run()
+			fmt.Printf("  %-8s %-24s 结果=%-5d 正确=%v\n", v.name, v.code, got, got == expected)
+		}
+	}
+}
+
+// runUnderRace 以 go run -race 重新运行本文件，每类用例单独一个进程，汇总检测到的竞争数量
+func runUnderRace() {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		fmt.Println("无法确定源文件路径")
+		return
+	}
+
+	for _, c := range cases {
+		cmd := exec.Command("go", "run", "-race", file, "-racy", "-case="+c.name)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		err := cmd.Run()
+
+		// 发现竞争时检测器会让进程以非0状态退出，这里只关心报告内容
+		races := strings.Count(out.String(), "WARNING: DATA RACE")
+		fmt.Printf("[%s] 检测到 %d 处数据竞争\n", c.name, races)
+		if races == 0 && err != nil {
+			fmt.Printf("  运行失败: %v\n%s", err, out.String())
+		}
+		if first := firstRace(out.String()); first != "" {
+			fmt.Println(first)
+		}
+	}
+}
+
+// firstRace 提取第一份竞争报告的前几行
+func firstRace(output string) string {
+	i := strings.Index(output, "WARNING: DATA RACE")
+	if i < 0 {
+		return ""
+	}
+	lines := strings.Split(output[i:], "\n")
+	if len(lines) > 6 {
+		lines = lines[:6]
+	}
+	return "  " + strings.Join(lines, "\n  ")
+}
+
+func main() {
+	only := flag.String("case", "", "只运行指定用例: counter, map, slice")
+	racy := flag.Bool("racy", false, "同时运行有数据竞争的版本")
+	underRace := flag.Bool("under-race", false, "以 go run -race 逐个运行所有用例并汇总")
+	demoflag.Parse()
+
+	if *underRace {
+		fmt.Println("=== 在竞争检测器下运行 ===")
+		runUnderRace()
+		return
+	}
+
+	fmt.Println("=== 数据竞争演示 ===")
+	fmt.Printf("竞争检测器: %v\n", raceEnabled())
+	if *racy {
+		fmt.Println("注意: 竞争版本的结果不确定，只有一个CPU时也可能恰好正确；map用例可能直接崩溃")
+	}
+
+	runCases(*only, *racy)
+
+	if !*racy {
+		fmt.Println("\n加上 -racy 运行有竞争的版本，或使用 -under-race 查看竞争检测报告")
+	}
+}
@@ -0,0 +1,396 @@
+/*
+Golang并发编程练习 - 限时挑战
+文件：exercises/challenge/main.go
+
+选一个练习开始倒计时，之后每次保存练习文件都会自动评分（竞态检测和goroutine泄漏检查都打开）。
+在时限内全部通过时，从开始到保存出通过版本的用时记录到本地排行榜（exercises/.leaderboard.json）；
+每次评分的结果和grader命令一样记录到学习进度。
+
+挑战要从还没有完成的练习开始：练习文件已经能通过评分时不能开始，先用 git checkout -- 练习文件 恢复模板。
+
+运行方式：
+  go run ./exercises/challenge simple/03              # 开始挑战，时限按级别：简单10分钟、中等20分钟、困难30分钟
+  go run ./exercises/challenge -limit 5m simple/03    # 自定义时限
+  go run ./exercises/challenge -name 小明 medium/01   # 排行榜上显示的名字，默认为系统用户名
+  go run ./exercises/challenge -board                 # 查看排行榜，可以加级别或练习只看一部分
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klsakura/day1/pkg/curriculum"
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/leaderboard"
+	"github.com/klsakura/day1/pkg/progress"
+)
+
+// defaultLimits 各级别默认的时限
+var defaultLimits = map[string]time.Duration{
+	"simple": 10 * time.Minute,
+	"medium": 20 * time.Minute,
+	"hard":   30 * time.Minute,
+}
+
+func mark(ok bool) string {
+	if ok {
+		return "✓"
+	}
+	return "✗"
+}
+
+// clock 倒计时显示的时间，例如09:58、1:02:03
+func clock(d time.Duration) string {
+	d = max(d, 0).Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// defaultPlayer 系统用户名，取不到时为"匿名"
+func defaultPlayer() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "匿名"
+}
+
+// isTerminal 标准输出是终端时倒计时在同一行刷新，否则每分钟打印一行
+func isTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// record 把一次评分结果写入进度文件
+func record(store *progress.Store, rep *grade.Report, at time.Time) {
+	tasks := make([]progress.TaskResult, 0, len(rep.Results))
+	for _, r := range rep.Results {
+		tasks = append(tasks, progress.TaskResult{Name: r.Name, Passed: r.Passed})
+	}
+	// 编译错误可能很长，进度文件里只留第一行
+	errMsg, _, _ := strings.Cut(rep.Err, "\n")
+	store.Record(rep.Exercise.Level+"/"+rep.Exercise.Name, tasks, errMsg, at)
+}
+
+// tasksPassed 通过的任务数和总任务数
+func tasksPassed(rep *grade.Report) (passed, total int) {
+	for _, r := range rep.Results {
+		if r.Passed {
+			passed++
+		}
+	}
+	return passed, len(rep.Results)
+}
+
+// printReport 和grader命令一样列出每个任务的结果
+func printReport(rep *grade.Report) {
+	width := 0
+	for _, r := range rep.Results {
+		width = max(width, grade.DisplayWidth(r.Name))
+	}
+	for _, r := range rep.Results {
+		fmt.Printf("    %s %s%s\n", mark(r.Passed), grade.Pad(r.Name, width+2), r.Duration.Round(10*time.Millisecond))
+		for _, m := range r.Messages {
+			fmt.Printf("        %s\n", m)
+		}
+	}
+	if rep.Err != "" {
+		for _, line := range strings.Split(rep.Err, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
+
+// printBoard 一个练习排名前n的成绩，player的成绩标上★
+func printBoard(board *leaderboard.Board, exercise, player string, n int) {
+	entries := board.Top(exercise, n)
+	if len(entries) == 0 {
+		fmt.Printf("  %s：还没有人完成挑战\n", exercise)
+		return
+	}
+	fmt.Printf("  %s：\n", exercise)
+	rows := [][]string{{"名次", "挑战者", "用时", "评分次数", "时限", "完成时间"}}
+	for i, e := range entries {
+		who := e.Player
+		if e.Player == player {
+			who += " ★"
+		}
+		rows = append(rows, []string{fmt.Sprint(i + 1), who, clock(e.Duration), fmt.Sprint(e.Attempts), clock(e.Limit), e.At.Format("01-02 15:04")})
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], grade.DisplayWidth(cell))
+		}
+	}
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			b.WriteString(grade.Pad(cell, widths[i]+2))
+		}
+		fmt.Println("    " + strings.TrimRight(b.String(), " "))
+	}
+}
+
+// watcher 轮询练习文件的内容：只用标准库，编辑器用各种方式保存（覆盖写、先写临时文件再改名）都能发现
+type watcher struct {
+	path    string
+	seen    []byte    // 最近一次读到的内容
+	savedAt time.Time // 最近一次内容变化的时间
+	graded  []byte    // 最近一次评分的内容
+}
+
+// poll 读一次文件，返回是否有一个已经稳定下来、还没有评分的新版本。
+// 内容刚变化时先等一个轮询间隔，避免编辑器分两次写入时评分到写了一半的文件
+func (w *watcher) poll(now time.Time) (bool, error) {
+	data, err := os.ReadFile(w.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil // 改名保存的中间状态
+	}
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(data, w.seen) {
+		w.seen, w.savedAt = data, now
+		return false, nil
+	}
+	return !bytes.Equal(data, w.graded), nil
+}
+
+func main() {
+	limit := flag.Duration("limit", 0, "时限，默认按级别：simple 10m，medium 20m，hard 30m")
+	player := flag.String("name", defaultPlayer(), "排行榜上显示的名字")
+	showBoard := flag.Bool("board", false, "只显示排行榜，参数可以是级别或练习")
+	top := flag.Int("top", 10, "排行榜显示前几名")
+	boardFile := flag.String("leaderboard", "", "排行榜文件路径，默认为练习目录下的"+leaderboard.FileName)
+	progressFile := flag.String("progress", "", "进度文件路径，默认为练习目录下的"+progress.FileName)
+	interval := flag.Duration("interval", 300*time.Millisecond, "检查练习文件是否保存的间隔")
+	race := flag.Bool("race", true, "在竞态检测器下评分")
+	timeout := flag.Duration("timeout", 2*time.Minute, "每次评分（包括编译）的超时")
+	gate := flag.Bool("gate", true, "只能挑战按学习路线已经解锁的练习")
+	flag.Parse()
+
+	dir, err := grade.ExercisesDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	all, err := grade.Discover(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var list []grade.Exercise
+	for _, ex := range all {
+		if grade.Selected(ex, flag.Args()) {
+			list = append(list, ex)
+		}
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(os.Stderr, "没有匹配 %v 的练习\n", flag.Args())
+		os.Exit(2)
+	}
+
+	path := *boardFile
+	if path == "" {
+		path = filepath.Join(dir, leaderboard.FileName)
+	}
+	board, err := leaderboard.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *showBoard {
+		fmt.Printf("=== 限时挑战排行榜（%s）===\n", board.Path())
+		n := 0
+		for _, ex := range list {
+			id := ex.Level + "/" + ex.Name
+			// 没有指定练习时只列出有成绩的
+			if len(board.Top(id, 1)) > 0 || len(list) == 1 {
+				fmt.Println()
+				printBoard(board, id, *player, *top)
+				n++
+			}
+		}
+		if n == 0 {
+			fmt.Println("\n还没有完成的挑战，开始一个：go run ./exercises/challenge simple/01")
+		}
+		return
+	}
+
+	if len(list) != 1 {
+		fmt.Fprintf(os.Stderr, "一次只能挑战一个练习，%v 匹配了 %d 个，请写成\"级别/编号\"，例如simple/03\n", flag.Args(), len(list))
+		os.Exit(2)
+	}
+	ex := list[0]
+	id := ex.Level + "/" + ex.Name
+	if *limit <= 0 {
+		*limit = defaultLimits[ex.Level]
+	}
+
+	path = *progressFile
+	if path == "" {
+		path = filepath.Join(dir, progress.FileName)
+	}
+	store, err := progress.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	cur := curriculum.Default()
+	if *gate && !cur.Unlocked(store, id) {
+		fmt.Fprintf(os.Stderr, "🔒 %s 还没有解锁，先完成：%s（-gate=false 强制开始）\n", id, strings.Join(cur.Missing(store, id), "、"))
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts := grade.Options{Race: *race, LeakCheck: true, Timeout: *timeout}
+
+	// 先评分一次当前的练习文件：已经能通过的练习挑战没有意义
+	fmt.Printf("=== 限时挑战：%s ===\n", id)
+	fmt.Println("检查练习文件的当前状态...")
+	rep := grade.Run(ctx, ex, opts)
+	if ctx.Err() != nil {
+		fmt.Println("已取消")
+		os.Exit(1)
+	}
+	if rep.Passed() {
+		fmt.Fprintf(os.Stderr, "%s 已经能通过评分，挑战要从模板开始：git checkout -- %s\n", ex.File, ex.File)
+		os.Exit(2)
+	}
+	passed, total := tasksPassed(rep)
+	fmt.Printf("当前通过 %d/%d 个任务\n\n", passed, total)
+	if best, ok := board.Best(id, *player); ok {
+		fmt.Printf("%s 的最好成绩：%s（评分 %d 次）\n", *player, clock(best.Duration), best.Attempts)
+	}
+	if leader := board.Top(id, 1); len(leader) > 0 {
+		fmt.Printf("排行榜第一：%s %s\n", leader[0].Player, clock(leader[0].Duration))
+	}
+	fmt.Printf("时限 %s，现在开始！编辑 %s，每次保存都会自动评分，Ctrl+C 放弃\n\n", clock(*limit), ex.File)
+
+	start := time.Now()
+	deadline := start.Add(*limit)
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	w := &watcher{path: ex.File}
+	w.poll(start)
+	w.graded = w.seen // 开始时的内容已经评分过了
+	term := isTerminal()
+	poll := time.NewTicker(*interval)
+	defer poll.Stop()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	attempts := 0
+	last := fmt.Sprintf("%d/%d", passed, total)
+
+	lastMinute := -1
+	// status 倒计时：终端里在同一行刷新，否则每过一分钟打印一行
+	status := func(now time.Time, force bool) {
+		remaining := deadline.Sub(now)
+		minute := int((remaining + time.Minute - 1) / time.Minute) // 向上取整，10:00到09:01算同一分钟
+		switch {
+		case term:
+			fmt.Printf("\r\033[K⏱  剩余 %s  评分 %d 次  最近 %s", clock(remaining), attempts, last)
+		case force || minute != lastMinute:
+			fmt.Printf("⏱  剩余 %s\n", clock(remaining))
+		}
+		lastMinute = minute
+	}
+	// clearStatus 打印其他内容前清掉倒计时那一行
+	clearStatus := func() {
+		if term {
+			fmt.Print("\r\033[K")
+		}
+	}
+	status(start, true)
+
+	for {
+		select {
+		case <-ctx.Done():
+			clearStatus()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				fmt.Printf("⏰ 时间到！%s 内没有通过全部任务，最近一次通过 %s。再来一次：go run ./exercises/challenge %s\n", clock(*limit), last, flag.Arg(0))
+			} else {
+				fmt.Printf("放弃挑战，用时 %s\n", clock(time.Since(start)))
+			}
+			os.Exit(1)
+		case now := <-tick.C:
+			status(now, false)
+		case now := <-poll.C:
+			ready, err := w.poll(now)
+			if err != nil {
+				clearStatus()
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if !ready {
+				continue
+			}
+			w.graded = w.seen
+			savedAt := w.savedAt
+			attempts++
+			clearStatus()
+			fmt.Printf("[%s] 第 %d 次评分...\n", clock(savedAt.Sub(start)), attempts)
+			rep := grade.Run(ctx, ex, opts)
+			if ctx.Err() != nil {
+				continue // 时间到或者放弃，交给上面的分支
+			}
+			record(store, rep, time.Now())
+			unlocked := cur.Update(store, time.Now())
+			if err := store.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "保存进度失败: %v\n", err)
+			}
+			printReport(rep)
+			passed, total := tasksPassed(rep)
+			last = fmt.Sprintf("%d/%d", passed, total)
+			if !rep.Passed() {
+				fmt.Printf("%s 通过 %s，继续加油\n\n", mark(false), last)
+				status(time.Now(), true)
+				continue
+			}
+
+			// 用时算到保存出通过版本的时刻，不算评分本身花的时间
+			elapsed := savedAt.Sub(start)
+			fmt.Printf("\n🏆 挑战成功！用时 %s，评分 %d 次\n", clock(elapsed), attempts)
+			if len(unlocked) > 0 {
+				fmt.Printf("🔓 解锁了新练习：%s\n", strings.Join(unlocked, "、"))
+			}
+			prev, hadBest := board.Best(id, *player)
+			rank := board.Add(leaderboard.Entry{Exercise: id, Player: *player, Duration: elapsed, Attempts: attempts, Limit: *limit, At: time.Now()})
+			if err := board.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "保存排行榜失败: %v\n", err)
+				os.Exit(1)
+			}
+			switch {
+			case !hadBest:
+				fmt.Printf("第一次完成这个挑战，排名第 %d\n", rank)
+			case elapsed < prev.Duration:
+				fmt.Printf("刷新个人最好成绩（原来 %s），排名第 %d\n", clock(prev.Duration), rank)
+			default:
+				fmt.Printf("个人最好成绩仍是 %s，这次排名第 %d\n", clock(prev.Duration), rank)
+			}
+			fmt.Println()
+			printBoard(board, id, *player, 5)
+			return
+		}
+	}
+}
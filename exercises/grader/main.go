@@ -0,0 +1,203 @@
+/*
+Golang并发编程练习 - 自动评分
+文件：exercises/grader/main.go
+
+对每个练习，把练习文件和同名的_test.go评分文件一起用go test在竞态检测器下运行，
+逐个任务（子测试）报告通过与否。评分文件中的任务直接调用练习里的函数（numberSender之类的
+TODO函数），检查行为是否正确，是否会卡住。结果正确但有数据竞争，或者任务结束后
+还有goroutine没有退出（泄漏），任务同样算失败。
+
+运行方式：
+  go run ./exercises/grader                  # 评分全部练习
+  go run ./exercises/grader simple           # 只评分一个级别
+  go run ./exercises/grader simple/03 hard   # 按"级别/编号"或文件路径选择
+  go run ./exercises/grader -v simple/01     # 失败时输出评分进程和练习的完整输出
+  go run ./exercises/grader -leaks=false     # 排查时暂时不检查goroutine泄漏（-race=false同理）
+  go run ./exercises/grader -solutions       # 评分solutions/下的参考答案，确认答案和评分文件一致
+
+每次评分练习文件的结果都会记录到exercises/.progress.json，用 go run ./exercises/progress 查看进度；
+-record=false 不记录，评分参考答案时也不记录。
+
+记录进度时按学习路线（pkg/curriculum）只评分已经解锁的练习：前置练习全部通过后练习才解锁，
+评分后打印新解锁的练习。-gate=false 不检查解锁，评分选中的全部练习。
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klsakura/day1/pkg/curriculum"
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/progress"
+)
+
+func printBlock(title, text string) {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return
+	}
+	fmt.Printf("    ---- %s ----\n", title)
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Printf("    | %s\n", line)
+	}
+}
+
+func mark(ok bool) string {
+	if ok {
+		return "✓"
+	}
+	return "✗"
+}
+
+// record 把一次评分结果写入进度文件
+func record(store *progress.Store, rep *grade.Report, at time.Time) {
+	tasks := make([]progress.TaskResult, 0, len(rep.Results))
+	for _, r := range rep.Results {
+		tasks = append(tasks, progress.TaskResult{Name: r.Name, Passed: r.Passed})
+	}
+	// 编译错误可能很长，进度文件里只留第一行
+	errMsg, _, _ := strings.Cut(rep.Err, "\n")
+	store.Record(rep.Exercise.Level+"/"+rep.Exercise.Name, tasks, errMsg, at)
+}
+
+func main() {
+	verbose := flag.Bool("v", false, "任务失败时输出评分进程的标准错误和练习打印的内容")
+	race := flag.Bool("race", true, "在竞态检测器下运行")
+	leaks := flag.Bool("leaks", true, "每个任务结束后检查goroutine泄漏")
+	timeout := flag.Duration("timeout", 2*time.Minute, "每个练习（包括编译）的超时")
+	solutions := flag.Bool("solutions", false, "评分参考答案而不是练习文件")
+	recordProgress := flag.Bool("record", true, "把评分结果记录到进度文件")
+	progressFile := flag.String("progress", "", "进度文件路径，默认为练习目录下的"+progress.FileName)
+	gate := flag.Bool("gate", true, "记录进度时只评分按学习路线已经解锁的练习")
+	flag.Parse()
+
+	dir, err := grade.ExercisesDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	all, err := grade.Discover(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var list []grade.Exercise
+	for _, ex := range all {
+		if grade.Selected(ex, flag.Args()) {
+			list = append(list, ex)
+		}
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(os.Stderr, "没有匹配 %v 的练习\n", flag.Args())
+		os.Exit(2)
+	}
+
+	var store *progress.Store
+	if *recordProgress && !*solutions {
+		path := *progressFile
+		if path == "" {
+			path = filepath.Join(dir, progress.FileName)
+		}
+		if store, err = progress.Open(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	if *solutions {
+		fmt.Println("=== 参考答案评分 ===")
+	} else {
+		fmt.Println("=== 练习自动评分 ===")
+	}
+	cur := curriculum.Default()
+	var files, filesPassed, tasks, tasksPassed, races, leaked, locked int
+	for _, ex := range list {
+		id := ex.Level + "/" + ex.Name
+		if store != nil && *gate && !cur.Unlocked(store, id) {
+			locked++
+			// 没有指定练习时只在最后汇总，不逐个列出
+			if flag.NArg() > 0 {
+				fmt.Printf("\n🔒 %s 还没有解锁，先完成：%s\n", id, describe(cur, cur.Missing(store, id)))
+			}
+			continue
+		}
+		rep := grade.Run(context.Background(), ex, grade.Options{Race: *race, LeakCheck: *leaks, Timeout: *timeout, Solution: *solutions})
+		if store != nil {
+			record(store, rep, time.Now())
+		}
+		files++
+		if rep.Passed() {
+			filesPassed++
+		}
+		fmt.Printf("\n%s %s/%s\n", mark(rep.Passed()), ex.Level, ex.Name)
+		width := 0
+		for _, r := range rep.Results {
+			width = max(width, grade.DisplayWidth(r.Name))
+		}
+		for _, r := range rep.Results {
+			tasks++
+			if r.Passed {
+				tasksPassed++
+			}
+			if r.Race {
+				races++
+			}
+			if r.Leak {
+				leaked++
+			}
+			fmt.Printf("    %s %s%s\n", mark(r.Passed), grade.Pad(r.Name, width+2), r.Duration.Round(10*time.Millisecond))
+			for _, m := range r.Messages {
+				fmt.Printf("        %s\n", m)
+			}
+		}
+		if rep.Err != "" {
+			for _, line := range strings.Split(rep.Err, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+		if *verbose && !rep.Passed() {
+			printBlock("评分进程输出", rep.Output)
+			printBlock("练习打印的内容", rep.Stdout)
+		}
+	}
+
+	fmt.Printf("\n练习: %d/%d 通过，任务: %d/%d 通过\n", filesPassed, files, tasksPassed, tasks)
+	if races > 0 || leaked > 0 {
+		fmt.Printf("其中 %d 个任务有数据竞争，%d 个任务泄漏了goroutine\n", races, leaked)
+	}
+	if locked > 0 {
+		fmt.Printf("跳过 %d 个还没有解锁的练习，学习路线见 go run ./exercises/progress -path（-gate=false 强制评分）\n", locked)
+	}
+	if store != nil {
+		if unlocked := cur.Update(store, time.Now()); len(unlocked) > 0 {
+			fmt.Printf("🔓 解锁了新练习：%s\n", describe(cur, unlocked))
+		}
+		if err := store.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "保存进度失败: %v\n", err)
+		} else {
+			fmt.Printf("进度已记录到 %s，查看进度: go run ./exercises/progress\n", store.Path())
+		}
+	}
+	if files == 0 || filesPassed != files {
+		os.Exit(1)
+	}
+}
+
+// describe 练习列表，附上每个练习练的内容
+func describe(cur *curriculum.Curriculum, ids []string) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id
+		if n, ok := cur.Lookup(id); ok && n.Skill != "" {
+			parts[i] += "（" + n.Skill + "）"
+		}
+	}
+	return strings.Join(parts, "、")
+}
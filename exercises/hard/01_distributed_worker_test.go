@@ -0,0 +1,393 @@
+// 评分：go run ./exercises/grader hard/01
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/leakcheck"
+)
+
+// gradeNode 记录收到的任务的WorkerNode，用来检查调度器的路由
+type gradeNode struct {
+	id        string
+	unhealthy atomic.Bool
+
+	mu    sync.Mutex
+	tasks []string
+}
+
+func newGradeNode(id string) *gradeNode { return &gradeNode{id: id} }
+
+func (n *gradeNode) GetID() string { return n.id }
+
+func (n *gradeNode) ProcessTask(task Task) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.tasks = append(n.tasks, task.ID)
+	return nil
+}
+
+func (n *gradeNode) IsHealthy() bool { return !n.unhealthy.Load() }
+
+func (n *gradeNode) GetLoad() float64 { return 0 }
+
+func (n *gradeNode) received() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.tasks...)
+}
+
+// route 返回每个key被路由到的节点ID
+func route(t *grade.T, ring *ConsistentHashRing, keys []string) map[string]string {
+	owners := make(map[string]string, len(keys))
+	for _, k := range keys {
+		node, err := ring.GetNode(k)
+		if err != nil || node == nil {
+			t.Fatalf("GetNode(%q)返回(%v, %v)，环上有节点时应返回一个节点", k, node, err)
+		}
+		owners[k] = node.GetID()
+	}
+	return owners
+}
+
+func taskKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("task-%d", i)
+	}
+	return keys
+}
+
+// submit 提交任务，队列满时稍后重试
+func submit(t *grade.T, ts *TaskScheduler, task Task) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := ts.SubmitTask(task)
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("SubmitTask(%s)持续失败: %v", task.ID, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitReceived 等到节点们一共收到want个任务
+func waitReceived(nodes []*gradeNode, want int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		got := 0
+		for _, n := range nodes {
+			got += len(n.received())
+		}
+		if got >= want || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDistributedWorker(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "SimpleWorker的ID、健康状态和处理时间",
+			Run: func(t *grade.T) {
+				w := NewSimpleWorker("w1", 20*time.Millisecond)
+				if w == nil {
+					t.Fatalf("NewSimpleWorker返回nil")
+				}
+				grade.CaptureStdout(func() {
+					if id := w.GetID(); id != "w1" {
+						t.Errorf("GetID() = %q，期望\"w1\"", id)
+					}
+					if !w.IsHealthy() {
+						t.Errorf("新建的工作者应该是健康的")
+					}
+					start := time.Now()
+					if err := w.ProcessTask(Task{ID: "t1"}); err != nil {
+						t.Errorf("健康的工作者处理任务返回错误: %v", err)
+					}
+					if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+						t.Errorf("processingTime=20ms，ProcessTask只用了 %v", elapsed)
+					}
+					w.SetHealthy(false)
+					if w.IsHealthy() {
+						t.Errorf("SetHealthy(false)后IsHealthy()仍为true")
+					}
+					if err := w.ProcessTask(Task{ID: "t2"}); err == nil {
+						t.Errorf("不健康的工作者处理任务应返回错误")
+					}
+					w.SetHealthy(true)
+					if !w.IsHealthy() {
+						t.Errorf("SetHealthy(true)后IsHealthy()仍为false")
+					}
+				})
+			},
+		},
+		grade.Task{
+			Name: "SimpleWorker并发处理任务时计数准确且没有数据竞争",
+			Run: func(t *grade.T) {
+				w := NewSimpleWorker("w1", time.Millisecond)
+				if w == nil {
+					t.Fatalf("NewSimpleWorker返回nil")
+				}
+				grade.CaptureStdout(func() {
+					var wg sync.WaitGroup
+					for i := 0; i < 20; i++ {
+						wg.Add(2)
+						go func(i int) {
+							defer wg.Done()
+							w.ProcessTask(Task{ID: fmt.Sprintf("t%d", i)})
+						}(i)
+						go func() {
+							defer wg.Done()
+							w.SetHealthy(true)
+							w.IsHealthy()
+							w.GetLoad()
+						}()
+					}
+					wg.Wait()
+				})
+				w.mu.RLock()
+				n := atomic.LoadInt64(&w.processedCount)
+				w.mu.RUnlock()
+				if n != 20 {
+					t.Errorf("处理了20个任务，processedCount = %d", n)
+				}
+			},
+		},
+		grade.Task{
+			Name: "空的哈希环GetNode返回错误",
+			Run: func(t *grade.T) {
+				ring := NewConsistentHashRing(10)
+				if ring == nil {
+					t.Fatalf("NewConsistentHashRing返回nil")
+				}
+				if node, err := ring.GetNode("task-1"); err == nil {
+					t.Errorf("环上没有节点时GetNode返回(%v, nil)，应返回错误", node)
+				}
+			},
+		},
+		grade.Task{
+			Name: "相同任务ID总是路由到同一节点且负载大致均衡",
+			Run: func(t *grade.T) {
+				ring := NewConsistentHashRing(100)
+				if ring == nil {
+					t.Fatalf("NewConsistentHashRing返回nil")
+				}
+				for i := 1; i <= 4; i++ {
+					ring.AddNode(newGradeNode(fmt.Sprintf("node-%d", i)))
+				}
+				keys := taskKeys(2000)
+				first := route(t, ring, keys)
+				again := route(t, ring, keys)
+				counts := make(map[string]int)
+				for _, k := range keys {
+					if first[k] != again[k] {
+						t.Fatalf("%s第一次路由到%s，第二次路由到%s", k, first[k], again[k])
+					}
+					counts[first[k]]++
+				}
+				// 每个节点100个虚拟节点，各自分到的任务应该在平均值（500）附近
+				for i := 1; i <= 4; i++ {
+					id := fmt.Sprintf("node-%d", i)
+					if counts[id] < 200 {
+						t.Errorf("2000个任务中%s只分到 %d 个，各节点分到的数量: %v（虚拟节点没有生效？）", id, counts[id], counts)
+						break
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "移除节点只迁移该节点上的任务，重新加入后路由恢复",
+			Run: func(t *grade.T) {
+				ring := NewConsistentHashRing(50)
+				if ring == nil {
+					t.Fatalf("NewConsistentHashRing返回nil")
+				}
+				nodes := make([]*gradeNode, 5)
+				for i := range nodes {
+					nodes[i] = newGradeNode(fmt.Sprintf("node-%d", i))
+					ring.AddNode(nodes[i])
+				}
+				keys := taskKeys(1000)
+				before := route(t, ring, keys)
+
+				ring.RemoveNode("node-2")
+				after := route(t, ring, keys)
+				moved := 0
+				for _, k := range keys {
+					switch {
+					case after[k] == "node-2":
+						t.Fatalf("移除node-2后%s仍然路由到node-2", k)
+					case before[k] != "node-2" && after[k] != before[k]:
+						t.Fatalf("%s原来在%s上，移除node-2后被迁移到了%s", k, before[k], after[k])
+					case before[k] == "node-2":
+						moved++
+					}
+				}
+				if moved == 0 {
+					t.Errorf("node-2上原来没有任何任务，无法检查迁移")
+				}
+
+				ring.AddNode(nodes[2])
+				restored := route(t, ring, keys)
+				for _, k := range keys {
+					if restored[k] != before[k] {
+						t.Fatalf("node-2重新加入后%s路由到%s，原来是%s", k, restored[k], before[k])
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "并发添加、移除节点和路由没有数据竞争",
+			Run: func(t *grade.T) {
+				ring := NewConsistentHashRing(20)
+				if ring == nil {
+					t.Fatalf("NewConsistentHashRing返回nil")
+				}
+				ring.AddNode(newGradeNode("stable"))
+				var wg sync.WaitGroup
+				for g := 0; g < 4; g++ {
+					wg.Add(2)
+					go func(g int) {
+						defer wg.Done()
+						for i := 0; i < 50; i++ {
+							id := fmt.Sprintf("node-%d-%d", g, i%5)
+							ring.AddNode(newGradeNode(id))
+							ring.RemoveNode(id)
+						}
+					}(g)
+					go func(g int) {
+						defer wg.Done()
+						for i := 0; i < 200; i++ {
+							if node, err := ring.GetNode(fmt.Sprintf("task-%d-%d", g, i)); err != nil || node == nil {
+								t.Errorf("环上始终有节点stable，GetNode却返回(%v, %v)", node, err)
+								return
+							}
+						}
+					}(g)
+				}
+				wg.Wait()
+			},
+		},
+		grade.Task{
+			Name:    "调度器处理每个任务恰好一次并按一致性哈希路由",
+			Timeout: 10 * time.Second,
+			Run: func(t *grade.T) {
+				ts := NewTaskScheduler(50)
+				if ts == nil {
+					t.Fatalf("NewTaskScheduler返回nil")
+				}
+				nodes := []*gradeNode{newGradeNode("a"), newGradeNode("b"), newGradeNode("c")}
+				grade.CaptureStdout(func() {
+					for _, n := range nodes {
+						ts.AddWorker(n)
+					}
+					ts.Start()
+					defer ts.Stop()
+					// 每个ID提交两次，两次必须由同一个节点处理
+					keys := taskKeys(100)
+					for round := 0; round < 2; round++ {
+						for _, k := range keys {
+							submit(t, ts, Task{ID: k, Created: time.Now()})
+						}
+					}
+					if got := waitReceived(nodes, 200, 5*time.Second); got != 200 {
+						t.Fatalf("提交了200个任务，工作者一共收到 %d 个", got)
+					}
+				})
+				owner := make(map[string]string)
+				count := make(map[string]int)
+				for _, n := range nodes {
+					for _, id := range n.received() {
+						count[id]++
+						if prev, ok := owner[id]; ok && prev != n.id {
+							t.Errorf("%s先后被%s和%s处理：相同ID应该路由到同一个工作者", id, prev, n.id)
+						}
+						owner[id] = n.id
+					}
+				}
+				for id, c := range count {
+					if c != 2 {
+						t.Errorf("%s提交了2次，被处理了 %d 次", id, c)
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name:    "不把任务交给不健康的工作者",
+			Timeout: 10 * time.Second,
+			Run: func(t *grade.T) {
+				ts := NewTaskScheduler(50)
+				if ts == nil {
+					t.Fatalf("NewTaskScheduler返回nil")
+				}
+				healthy, sick := newGradeNode("healthy"), newGradeNode("sick")
+				sick.unhealthy.Store(true)
+				grade.CaptureStdout(func() {
+					ts.AddWorker(healthy)
+					ts.AddWorker(sick)
+					ts.Start()
+					defer ts.Stop()
+					for _, k := range taskKeys(100) {
+						submit(t, ts, Task{ID: k})
+					}
+					// 不健康节点的任务可能被丢弃也可能转给其他节点，这里只要求健康节点的任务都到达
+					waitReceived([]*gradeNode{healthy}, 30, 2*time.Second)
+					time.Sleep(100 * time.Millisecond)
+				})
+				if n := len(sick.received()); n > 0 {
+					t.Errorf("不健康的工作者收到了 %d 个任务", n)
+				}
+				if n := len(healthy.received()); n == 0 {
+					t.Errorf("健康的工作者没有收到任何任务")
+				}
+			},
+		},
+		grade.Task{
+			Name:    "运行中增删工作者没有数据竞争，Stop后后台goroutine全部退出",
+			Timeout: 10 * time.Second,
+			Run: func(t *grade.T) {
+				base := leakcheck.Take()
+				ts := NewTaskScheduler(20)
+				if ts == nil {
+					t.Fatalf("NewTaskScheduler返回nil")
+				}
+				grade.CaptureStdout(func() {
+					ts.AddWorker(newGradeNode("stable"))
+					ts.Start()
+					var wg sync.WaitGroup
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for i := 0; i < 50; i++ {
+							id := fmt.Sprintf("temp-%d", i%3)
+							ts.AddWorker(newGradeNode(id))
+							time.Sleep(time.Millisecond)
+							ts.RemoveWorker(id)
+						}
+					}()
+					for _, k := range taskKeys(200) {
+						submit(t, ts, Task{ID: k})
+					}
+					wg.Wait()
+					time.Sleep(50 * time.Millisecond)
+					if !grade.Within(2*time.Second, ts.Stop) {
+						t.Fatalf("Stop在2秒内没有返回")
+					}
+				})
+				if leaked := leakcheck.Check(base, time.Second); len(leaked) > 0 {
+					t.Errorf("Stop之后仍有goroutine在运行\n%s", leakcheck.Report(leaked, false))
+				}
+			},
+		},
+	)
+}
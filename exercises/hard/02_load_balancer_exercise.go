@@ -0,0 +1,194 @@
+/*
+Golang并发编程练习 - 困难级别
+练习文件：02_load_balancer_exercise.go
+练习主题：负载均衡策略
+
+练习目标：
+1. 实现轮询、最少连接、平滑加权轮询三种负载均衡策略
+2. 策略会被大量请求同时调用，状态要并发安全
+3. 用写时复制维护后端列表：请求路径只读快照，增删后端时替换整个列表
+4. 正确统计每个后端正在处理的请求数
+
+练习任务：
+- 任务1：RoundRobin按顺序轮流选择健康的后端
+- 任务2：LeastConnections选择正在处理的请求最少的后端
+- 任务3：WeightedRoundRobin按权重分配，并且不连续把请求压给同一个后端
+- 任务4：LoadBalancer在运行期间支持增删后端，Do统计正在处理的请求数
+
+对应Demo：cmd/hard/02_load_balancer
+
+运行方式：go run exercises/hard/02_load_balancer_exercise.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Backend 后端服务器（已实现）
+type Backend struct {
+	ID     string
+	Weight int
+
+	active  atomic.Int64 // 正在处理的请求数，由LoadBalancer.Do增减
+	healthy atomic.Bool
+}
+
+// NewBackend 新建的后端是健康的
+func NewBackend(id string, weight int) *Backend {
+	b := &Backend{ID: id, Weight: weight}
+	b.healthy.Store(true)
+	return b
+}
+
+// Active 正在处理的请求数
+func (b *Backend) Active() int64 { return b.active.Load() }
+
+func (b *Backend) IsHealthy() bool { return b.healthy.Load() }
+
+func (b *Backend) SetHealthy(healthy bool) { b.healthy.Store(healthy) }
+
+// ErrNoBackend 没有健康的后端
+var ErrNoBackend = errors.New("no healthy backend")
+
+// Strategy 负载均衡策略
+// Pick从backends中选择一个健康的后端，没有健康的后端时返回nil。
+// Pick会被多个goroutine同时调用；backends是LoadBalancer的快照，只能读不能修改。
+type Strategy interface {
+	Pick(backends []*Backend) *Backend
+	Name() string
+}
+
+// RoundRobin 轮询：按列表顺序依次选择健康的后端
+type RoundRobin struct {
+	// TODO: 定义需要的字段
+	// 提示：一个原子计数器就够了
+}
+
+// TODO: 实现RoundRobin的Pick
+func (rr *RoundRobin) Pick(backends []*Backend) *Backend {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 先过滤出健康的后端
+	// 2. 计数器原子加一后对健康后端数取模
+	return nil
+}
+
+func (rr *RoundRobin) Name() string { return "RoundRobin" }
+
+// LeastConnections 最少连接：选择Active()最小的健康后端
+type LeastConnections struct{}
+
+// TODO: 实现LeastConnections的Pick
+func (lc *LeastConnections) Pick(backends []*Backend) *Backend {
+	// 在这里实现您的代码
+	return nil
+}
+
+func (lc *LeastConnections) Name() string { return "LeastConnections" }
+
+// WeightedRoundRobin 平滑加权轮询，零值可以直接使用
+// 每次选择时每个健康后端的当前权重加上自己的Weight，选当前权重最大的，
+// 再把它的当前权重减去所有健康后端的Weight之和。权重5:1:1时选择顺序为a a b a c a a，
+// 而不是a a a a a b c。
+type WeightedRoundRobin struct {
+	mu sync.Mutex
+	// TODO: 定义需要的字段
+	// 提示：每个后端的当前权重，可以用map[*Backend]int
+}
+
+// TODO: 实现WeightedRoundRobin的Pick
+func (wrr *WeightedRoundRobin) Pick(backends []*Backend) *Backend {
+	// 在这里实现您的代码
+	// 提示：Weight<=0的后端按1计算
+	return nil
+}
+
+func (wrr *WeightedRoundRobin) Name() string { return "WeightedRoundRobin" }
+
+// LoadBalancer 负载均衡器
+type LoadBalancer struct {
+	strategy Strategy
+	// TODO: 定义需要的字段
+	// 提示：用atomic.Pointer[[]*Backend]保存后端列表快照，增删时加锁复制后整体替换
+}
+
+// TODO: 实现LoadBalancer的所有方法
+func NewLoadBalancer(strategy Strategy) *LoadBalancer {
+	// 在这里实现您的代码
+	return nil
+}
+
+// AddBackend 添加后端，已有相同ID的后端时替换它
+func (lb *LoadBalancer) AddBackend(b *Backend) {
+	// 在这里实现您的代码
+}
+
+// RemoveBackend 移除后端，返回后不会再有新请求发给它
+func (lb *LoadBalancer) RemoveBackend(id string) {
+	// 在这里实现您的代码
+}
+
+// Backends 当前后端列表的快照
+func (lb *LoadBalancer) Backends() []*Backend {
+	// 在这里实现您的代码
+	return nil
+}
+
+// Do 用策略选择一个后端并调用fn，返回fn的错误；没有健康的后端时返回ErrNoBackend
+func (lb *LoadBalancer) Do(fn func(b *Backend) error) error {
+	// 在这里实现您的代码
+	// 提示：fn执行期间b.active加一，返回后减一（fn返回错误时也要减）
+	return nil
+}
+
+func main() {
+	fmt.Println("=== 负载均衡策略练习 ===")
+
+	// 任务1：轮询
+	fmt.Println("\n任务1：轮询")
+	// TODO:
+	// 1. 创建3个后端，用RoundRobin选择9次，打印选择顺序
+	// 2. 把其中一个标记为不健康，再选择6次
+
+	fmt.Println("任务1完成\n")
+
+	// 任务2：最少连接
+	fmt.Println("任务2：最少连接")
+	// TODO:
+	// 1. 创建使用LeastConnections的LoadBalancer和3个后端
+	// 2. 并发发起30个请求，fn睡眠随机10-100ms
+	// 3. 统计每个后端处理的请求数，和轮询比较
+
+	fmt.Println("任务2完成\n")
+
+	// 任务3：平滑加权轮询
+	fmt.Println("任务3：平滑加权轮询")
+	// TODO:
+	// 1. 创建权重为5、1、1的三个后端
+	// 2. 选择14次，打印选择顺序，观察是否平滑
+
+	fmt.Println("任务3完成\n")
+
+	// 任务4：动态增删后端
+	fmt.Println("任务4：动态增删后端")
+	// TODO:
+	// 1. 10个goroutine持续调用Do
+	// 2. 期间添加一个后端、移除一个后端
+	// 3. 用 go run -race 确认没有数据竞争
+
+	fmt.Println("任务4完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 各个后端处理能力不同时，轮询会出现什么问题？")
+	fmt.Println("2. 最少连接策略在请求处理时间差异很大时有什么优势？")
+	fmt.Println("3. 为什么后端列表适合写时复制，而不是每次请求都加读锁？")
+	fmt.Println("4. 后端被移除时，正在它上面处理的请求应该怎样处理？")
+}
@@ -0,0 +1,313 @@
+// 评分：go run ./exercises/grader hard/02
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+func newBackends(weights ...int) []*Backend {
+	list := make([]*Backend, len(weights))
+	for i, w := range weights {
+		list[i] = NewBackend(string(rune('a'+i)), w)
+	}
+	return list
+}
+
+// pickN 连续选择n次，返回选中的ID序列
+func pickN(t *grade.T, s Strategy, backends []*Backend, n int) []string {
+	seq := make([]string, n)
+	for i := range seq {
+		b := s.Pick(backends)
+		if b == nil {
+			t.Fatalf("%s.Pick返回nil，有健康的后端时应选择一个", s.Name())
+		}
+		seq[i] = b.ID
+	}
+	return seq
+}
+
+func countIDs(seq []string) map[string]int {
+	counts := make(map[string]int)
+	for _, id := range seq {
+		counts[id]++
+	}
+	return counts
+}
+
+// longestRun 同一个ID连续出现的最长次数
+func longestRun(seq []string) int {
+	longest, run := 0, 0
+	for i := range seq {
+		if i > 0 && seq[i] == seq[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func newLB(t *grade.T, s Strategy, backends ...*Backend) *LoadBalancer {
+	lb := NewLoadBalancer(s)
+	if lb == nil {
+		t.Fatalf("NewLoadBalancer返回nil")
+	}
+	for _, b := range backends {
+		lb.AddBackend(b)
+	}
+	return lb
+}
+
+func TestLoadBalancer(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "RoundRobin按顺序轮流选择",
+			Run: func(t *grade.T) {
+				seq := pickN(t, &RoundRobin{}, newBackends(1, 1, 1), 9)
+				for i := 3; i < len(seq); i++ {
+					if seq[i] != seq[i-3] {
+						t.Fatalf("3个后端选择9次得到 %s，期望每3次一个循环", strings.Join(seq, " "))
+					}
+				}
+				if c := countIDs(seq); c["a"] != 3 || c["b"] != 3 || c["c"] != 3 {
+					t.Errorf("3个后端选择9次得到 %s，期望每个后端3次", strings.Join(seq, " "))
+				}
+			},
+		},
+		grade.Task{
+			Name: "所有策略都跳过不健康的后端",
+			Run: func(t *grade.T) {
+				for _, s := range []Strategy{&RoundRobin{}, &LeastConnections{}, &WeightedRoundRobin{}} {
+					backends := newBackends(1, 5, 1)
+					backends[1].SetHealthy(false)
+					seq := pickN(t, s, backends, 10)
+					if c := countIDs(seq); c["b"] > 0 {
+						t.Errorf("%s选中了不健康的后端b %d 次", s.Name(), c["b"])
+					}
+					for _, b := range backends {
+						b.SetHealthy(false)
+					}
+					if b := s.Pick(backends); b != nil {
+						t.Errorf("%s在所有后端都不健康时返回了 %s，期望nil", s.Name(), b.ID)
+					}
+					if b := s.Pick(nil); b != nil {
+						t.Errorf("%s在没有后端时返回了 %s，期望nil", s.Name(), b.ID)
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "并发轮询时每个后端分到的请求数相等",
+			Run: func(t *grade.T) {
+				const goroutines, picks = 20, 100
+				backends := newBackends(1, 1, 1, 1)
+				rr := &RoundRobin{}
+				var mu sync.Mutex
+				counts := make(map[string]int)
+				var wg sync.WaitGroup
+				for g := 0; g < goroutines; g++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						local := make(map[string]int)
+						for i := 0; i < picks; i++ {
+							if b := rr.Pick(backends); b != nil {
+								local[b.ID]++
+							}
+						}
+						mu.Lock()
+						for id, n := range local {
+							counts[id] += n
+						}
+						mu.Unlock()
+					}()
+				}
+				wg.Wait()
+				want := goroutines * picks / len(backends)
+				for _, b := range backends {
+					if counts[b.ID] != want {
+						t.Errorf("并发选择 %d 次后各后端的次数为 %v，期望每个 %d 次：计数器要原子地自增并取值",
+							goroutines*picks, counts, want)
+						break
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "LeastConnections选择正在处理的请求最少的后端",
+			Run: func(t *grade.T) {
+				backends := newBackends(1, 1, 1, 1)
+				for i, n := range []int64{3, 1, 2, 0} {
+					backends[i].active.Store(n)
+				}
+				backends[3].SetHealthy(false) // 最少但不健康
+				lc := &LeastConnections{}
+				if b := lc.Pick(backends); b == nil || b.ID != "b" {
+					t.Errorf("活跃请求数为 a=3 b=1 c=2 d=0(不健康) 时选择了 %v，期望b", b)
+				}
+				backends[1].active.Store(5)
+				if b := lc.Pick(backends); b == nil || b.ID != "c" {
+					t.Errorf("活跃请求数为 a=3 b=5 c=2 d=0(不健康) 时选择了 %v，期望c", b)
+				}
+			},
+		},
+		grade.Task{
+			Name: "WeightedRoundRobin按权重平滑分配",
+			Run: func(t *grade.T) {
+				seq := pickN(t, &WeightedRoundRobin{}, newBackends(5, 1, 1), 14)
+				if c := countIDs(seq); c["a"] != 10 || c["b"] != 2 || c["c"] != 2 {
+					t.Errorf("权重5:1:1选择14次得到 %s，期望a 10次、b 2次、c 2次", strings.Join(seq, " "))
+				}
+				if n := longestRun(seq); n > 4 {
+					t.Errorf("权重5:1:1选择14次得到 %s，a连续出现了 %d 次：平滑加权轮询应该把b和c穿插在a中间",
+						strings.Join(seq, " "), n)
+				}
+				seq = pickN(t, &WeightedRoundRobin{}, newBackends(2, 0), 6)
+				if c := countIDs(seq); c["a"] != 4 || c["b"] != 2 {
+					t.Errorf("权重2:0选择6次得到 %s，期望权重<=0按1计算（a 4次、b 2次）", strings.Join(seq, " "))
+				}
+			},
+		},
+		grade.Task{
+			Name: "Do在fn执行期间计入活跃请求",
+			Run: func(t *grade.T) {
+				b := NewBackend("a", 1)
+				lb := newLB(t, &RoundRobin{}, b)
+				var during int64 = -1
+				var got *Backend
+				errFn := errors.New("处理失败")
+				err := lb.Do(func(picked *Backend) error {
+					got = picked
+					during = picked.Active()
+					return errFn
+				})
+				if got != b {
+					t.Fatalf("Do没有用选中的后端调用fn")
+				}
+				if !errors.Is(err, errFn) {
+					t.Errorf("Do返回 %v，期望原样返回fn的错误", err)
+				}
+				if during != 1 {
+					t.Errorf("fn执行期间Active() = %d，期望1", during)
+				}
+				if n := b.Active(); n != 0 {
+					t.Errorf("fn返回错误后Active() = %d，期望0：fn失败时也要减回去", n)
+				}
+				b.SetHealthy(false)
+				called := false
+				if err := lb.Do(func(*Backend) error { called = true; return nil }); !errors.Is(err, ErrNoBackend) || called {
+					t.Errorf("没有健康后端时Do返回 %v（fn被调用: %v），期望ErrNoBackend且不调用fn", err, called)
+				}
+			},
+		},
+		grade.Task{
+			Name: "LeastConnections配合Do把并发请求分散到不同后端",
+			Run: func(t *grade.T) {
+				backends := newBackends(1, 1, 1)
+				lb := newLB(t, &LeastConnections{}, backends...)
+				release := make(chan struct{})
+				entered := make(chan string, 3)
+				var wg sync.WaitGroup
+				defer func() {
+					close(release)
+					wg.Wait()
+				}()
+				used := make(map[string]bool)
+				for i := 0; i < 3; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						lb.Do(func(b *Backend) error {
+							entered <- b.ID
+							<-release
+							return nil
+						})
+					}()
+					select {
+					case id := <-entered:
+						if used[id] {
+							t.Fatalf("第%d个并发请求又发给了正在处理请求的后端 %s，其他后端空闲", i+1, id)
+						}
+						used[id] = true
+					case <-time.After(time.Second):
+						t.Fatalf("Do没有调用fn")
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "运行期间增删后端",
+			Run: func(t *grade.T) {
+				lb := newLB(t, &RoundRobin{}, newBackends(1, 1)...)
+				var removed atomic.Bool
+				var afterRemove atomic.Int32
+				var failures atomic.Int32
+				stop := make(chan struct{})
+				var wg sync.WaitGroup
+				for i := 0; i < 8; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for {
+							select {
+							case <-stop:
+								return
+							default:
+							}
+							// 在Do之前读取：如果这时a已经被移除，这次请求一定不能发给a
+							wasRemoved := removed.Load()
+							err := lb.Do(func(b *Backend) error {
+								if wasRemoved && b.ID == "a" {
+									afterRemove.Add(1)
+								}
+								return nil
+							})
+							if err != nil {
+								failures.Add(1)
+							}
+						}
+					}()
+				}
+				for i := 0; i < 50; i++ {
+					lb.AddBackend(NewBackend(fmt.Sprintf("x%d", i), 1))
+				}
+				for i := 0; i < 50; i++ {
+					lb.RemoveBackend(fmt.Sprintf("x%d", i))
+				}
+				lb.RemoveBackend("a")
+				removed.Store(true)
+				time.Sleep(50 * time.Millisecond)
+				close(stop)
+				wg.Wait()
+
+				if n := afterRemove.Load(); n > 0 {
+					t.Errorf("RemoveBackend(\"a\")返回后仍有 %d 个请求发给了a", n)
+				}
+				if n := failures.Load(); n > 0 {
+					t.Errorf("后端b一直在线，却有 %d 个请求返回了错误", n)
+				}
+				var got []string
+				for _, b := range lb.Backends() {
+					got = append(got, b.ID)
+				}
+				if fmt.Sprint(got) != "[b]" {
+					t.Errorf("增删之后Backends()为 %v，期望 [b]", got)
+				}
+				lb.AddBackend(NewBackend("b", 3))
+				if list := lb.Backends(); len(list) != 1 || list[0].Weight != 3 {
+					t.Errorf("添加相同ID的后端时应替换原来的后端，现在Backends()有 %d 个", len(list))
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,157 @@
+/*
+Golang并发编程练习 - 困难级别
+练习文件：03_message_queue_exercise.go
+练习主题：带重试和死信队列的消息队列
+
+练习目标：
+1. 用固定数量的工作者goroutine处理消息
+2. 处理失败的消息按退避时间重新投递，等待期间不占用工作者
+3. 超过最大投递次数的消息进入死信队列
+4. 实现优雅关闭：不再接收新消息，等待进行中和待重试的消息处理完，所有goroutine退出
+
+练习任务：
+- 任务1：Publish把消息交给工作者，工作者调用handler
+- 任务2：handler返回错误时按Backoff重试，超过MaxAttempts进入死信队列
+- 任务3：Close等待所有消息到达终态（成功或死信）后返回，之后Publish返回ErrQueueClosed
+- 任务4：Close的ctx到期时取消正在处理的消息，剩下的消息进入死信队列，返回ctx的错误
+
+对应Demo：cmd/hard/03_message_queue
+
+运行方式：go run exercises/hard/03_message_queue_exercise.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQueueClosed 队列已关闭，不再接收消息
+var ErrQueueClosed = errors.New("queue closed")
+
+// Message 消息
+type Message struct {
+	ID       string
+	Body     string
+	Attempts int // 已投递的次数，第一次调用handler时为1
+}
+
+// Handler 处理消息，返回错误表示需要重试；ctx在Close超时后被取消
+type Handler func(ctx context.Context, msg Message) error
+
+// QueueConfig 队列配置
+type QueueConfig struct {
+	Workers     int                             // 工作者数量
+	MaxAttempts int                             // 每条消息最多投递几次，用完后进入死信队列
+	Backoff     func(attempt int) time.Duration // 第attempt次投递失败后，等待多久再投递；nil表示立即重试
+}
+
+// Stats 队列统计
+type Stats struct {
+	Published int64 // 被接收的消息数
+	Succeeded int64 // 处理成功的消息数
+	Retried   int64 // 重新投递的次数
+	Dead      int64 // 进入死信队列的消息数
+}
+
+// RetryQueue 带重试的消息队列
+type RetryQueue struct {
+	config  QueueConfig
+	handler Handler
+
+	mu sync.Mutex
+	// TODO: 定义需要的字段
+	// 提示：
+	// - 交给工作者的channel
+	// - 是否已关闭，以及还没到达终态的消息数（sync.WaitGroup）
+	// - 传给handler的ctx和它的cancel
+	// - 工作者的WaitGroup、死信队列、统计
+}
+
+// TODO: 实现RetryQueue的所有方法
+// NewRetryQueue 创建队列并启动config.Workers个工作者
+func NewRetryQueue(config QueueConfig, handler Handler) *RetryQueue {
+	// 在这里实现您的代码
+	return nil
+}
+
+// Publish 发布消息，没有空闲工作者时等待；队列关闭后返回ErrQueueClosed
+func (q *RetryQueue) Publish(msg Message) error {
+	// 在这里实现您的代码
+	// 提示：检查是否关闭和登记一条未完成的消息要在同一次加锁中完成，
+	// 否则Close可能在两者之间开始等待，漏掉这条消息
+	return nil
+}
+
+// worker 从channel中取消息处理，channel关闭后退出
+func (q *RetryQueue) worker() {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. Attempts加一后调用handler
+	// 2. 成功：统计，这条消息完成
+	// 3. 失败且还有次数：另起goroutine等待Backoff后重新放回channel，工作者继续处理下一条
+	// 4. 失败且次数用完，或ctx已取消：进入死信队列，这条消息完成
+}
+
+// DeadLetters 死信队列中的消息（副本）
+func (q *RetryQueue) DeadLetters() []Message {
+	// 在这里实现您的代码
+	return nil
+}
+
+// Stats 统计信息
+func (q *RetryQueue) Stats() Stats {
+	// 在这里实现您的代码
+	return Stats{}
+}
+
+// Close 关闭队列：拒绝新消息，等待所有已接收的消息成功或进入死信队列，然后停止工作者。
+// ctx到期时取消handler的ctx，待重试的消息不再投递，直接进入死信队列，返回ctx.Err()。
+func (q *RetryQueue) Close(ctx context.Context) error {
+	// 在这里实现您的代码
+	// 提示：所有消息完成后才能关闭工作者的channel，否则待重试的消息放回时会向已关闭的channel发送
+	return nil
+}
+
+func main() {
+	fmt.Println("=== 带重试的消息队列练习 ===")
+
+	// 任务1：基本投递
+	fmt.Println("\n任务1：基本投递")
+	// TODO:
+	// 1. 创建3个工作者的队列，handler打印消息后返回nil
+	// 2. 发布10条消息，Close后打印Stats
+
+	fmt.Println("任务1完成\n")
+
+	// 任务2：重试和死信
+	fmt.Println("任务2：重试和死信")
+	// TODO:
+	// 1. MaxAttempts为3，Backoff为 attempt*50ms
+	// 2. handler对ID为奇数的消息前两次失败，对"poison"消息总是失败
+	// 3. 打印每次投递的Attempts，Close后打印DeadLetters
+
+	fmt.Println("任务2完成\n")
+
+	// 任务3：关闭超时
+	fmt.Println("任务3：关闭超时")
+	// TODO:
+	// 1. handler睡眠1秒，期间检查ctx.Done()
+	// 2. 发布消息后用200ms超时的ctx调用Close
+	// 3. 观察Close返回的错误和死信队列
+
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么退避等待不能在工作者goroutine里time.Sleep？")
+	fmt.Println("2. 什么时候关闭工作者的channel才是安全的？")
+	fmt.Println("3. 同一条消息可能被处理多次，handler需要满足什么性质？")
+	fmt.Println("4. 死信队列中的消息之后应该怎样处理？")
+}
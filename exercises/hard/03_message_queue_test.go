@@ -0,0 +1,353 @@
+// 评分：go run ./exercises/grader hard/03
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+var errHandler = errors.New("处理失败")
+
+// delivery 一次handler调用
+type delivery struct {
+	msg Message
+	at  time.Time
+}
+
+// recorder 记录handler收到的每次投递
+type recorder struct {
+	mu   sync.Mutex
+	list []delivery
+}
+
+func (r *recorder) add(msg Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.list = append(r.list, delivery{msg, time.Now()})
+}
+
+func (r *recorder) deliveries() []delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]delivery(nil), r.list...)
+}
+
+func newQueue(t *grade.T, config QueueConfig, h Handler) *RetryQueue {
+	q := NewRetryQueue(config, h)
+	if q == nil {
+		t.Fatalf("NewRetryQueue返回nil")
+	}
+	return q
+}
+
+func publish(t *grade.T, q *RetryQueue, msg Message) {
+	var err error
+	if !grade.Within(time.Second, func() { err = q.Publish(msg) }) {
+		t.Fatalf("Publish(%s)阻塞超过1秒：工作者没有在接收消息", msg.ID)
+	}
+	if err != nil {
+		t.Fatalf("Publish(%s)返回 %v", msg.ID, err)
+	}
+}
+
+// closeQueue 关闭队列，timeout内没有返回或返回错误时任务失败
+func closeQueue(t *grade.T, q *RetryQueue, timeout time.Duration) {
+	var err error
+	if !grade.Within(timeout+500*time.Millisecond, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err = q.Close(ctx)
+	}) {
+		t.Fatalf("Close在ctx到期后没有返回：ctx到期时要取消处理并停止等待")
+	}
+	if err != nil {
+		t.Fatalf("Close返回 %v，消息都能在 %v 内完成，期望nil", err, timeout)
+	}
+}
+
+func constBackoff(d time.Duration) func(int) time.Duration {
+	return func(int) time.Duration { return d }
+}
+
+func TestMessageQueue(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "消息交给handler处理且Attempts从1开始",
+			Run: func(t *grade.T) {
+				var rec recorder
+				q := newQueue(t, QueueConfig{Workers: 2, MaxAttempts: 3}, func(ctx context.Context, msg Message) error {
+					rec.add(msg)
+					return nil
+				})
+				for i := 1; i <= 3; i++ {
+					publish(t, q, Message{ID: fmt.Sprint(i), Body: "内容"})
+				}
+				closeQueue(t, q, time.Second)
+				got := rec.deliveries()
+				if len(got) != 3 {
+					t.Fatalf("handler被调用 %d 次，期望3次（Close应等待所有消息处理完）", len(got))
+				}
+				for _, d := range got {
+					if d.msg.Attempts != 1 || d.msg.Body != "内容" {
+						t.Errorf("handler收到 %+v，期望Body不变、Attempts为1", d.msg)
+					}
+				}
+				if s := q.Stats(); s.Published != 3 || s.Succeeded != 3 || s.Retried != 0 || s.Dead != 0 {
+					t.Errorf("Stats() = %+v，期望Published=3 Succeeded=3 Retried=0 Dead=0", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "失败的消息按退避时间重试直到成功",
+			Run: func(t *grade.T) {
+				var rec recorder
+				var backoffArgs []int
+				var mu sync.Mutex
+				q := newQueue(t, QueueConfig{
+					Workers:     1,
+					MaxAttempts: 5,
+					Backoff: func(attempt int) time.Duration {
+						mu.Lock()
+						backoffArgs = append(backoffArgs, attempt)
+						mu.Unlock()
+						return 50 * time.Millisecond
+					},
+				}, func(ctx context.Context, msg Message) error {
+					rec.add(msg)
+					if msg.Attempts < 3 {
+						return errHandler
+					}
+					return nil
+				})
+				publish(t, q, Message{ID: "m"})
+				closeQueue(t, q, 2*time.Second)
+				got := rec.deliveries()
+				var attempts []int
+				for _, d := range got {
+					attempts = append(attempts, d.msg.Attempts)
+				}
+				if fmt.Sprint(attempts) != "[1 2 3]" {
+					t.Fatalf("各次投递的Attempts为 %v，期望 [1 2 3]（前两次失败，第三次成功）", attempts)
+				}
+				for i := 1; i < len(got); i++ {
+					if gap := got[i].at.Sub(got[i-1].at); gap < 40*time.Millisecond {
+						t.Errorf("第%d次和第%d次投递只间隔 %v，Backoff要求等待50ms", i, i+1, gap.Round(time.Millisecond))
+					}
+				}
+				mu.Lock()
+				args := fmt.Sprint(backoffArgs)
+				mu.Unlock()
+				if args != "[1 2]" {
+					t.Errorf("Backoff的参数依次为 %s，期望 [1 2]（第几次投递失败）", args)
+				}
+				if s := q.Stats(); s.Succeeded != 1 || s.Retried != 2 || s.Dead != 0 {
+					t.Errorf("Stats() = %+v，期望Succeeded=1 Retried=2 Dead=0", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "超过MaxAttempts的消息进入死信队列",
+			Run: func(t *grade.T) {
+				var rec recorder
+				q := newQueue(t, QueueConfig{Workers: 2, MaxAttempts: 3, Backoff: constBackoff(time.Millisecond)},
+					func(ctx context.Context, msg Message) error {
+						rec.add(msg)
+						if msg.ID == "poison" {
+							return errHandler
+						}
+						return nil
+					})
+				publish(t, q, Message{ID: "poison"})
+				publish(t, q, Message{ID: "ok"})
+				closeQueue(t, q, 2*time.Second)
+				n := 0
+				for _, d := range rec.deliveries() {
+					if d.msg.ID == "poison" {
+						n++
+					}
+				}
+				if n != 3 {
+					t.Errorf("总是失败的消息被投递了 %d 次，MaxAttempts=3", n)
+				}
+				dead := q.DeadLetters()
+				if len(dead) != 1 || dead[0].ID != "poison" || dead[0].Attempts != 3 {
+					t.Errorf("DeadLetters() = %+v，期望只有poison且Attempts=3", dead)
+				}
+				if s := q.Stats(); s.Published != 2 || s.Succeeded != 1 || s.Retried != 2 || s.Dead != 1 {
+					t.Errorf("Stats() = %+v，期望Published=2 Succeeded=1 Retried=2 Dead=1", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "退避等待期间不占用工作者",
+			Run: func(t *grade.T) {
+				var rec recorder
+				q := newQueue(t, QueueConfig{Workers: 1, MaxAttempts: 2, Backoff: constBackoff(500 * time.Millisecond)},
+					func(ctx context.Context, msg Message) error {
+						rec.add(msg)
+						if msg.ID == "a" && msg.Attempts == 1 {
+							return errHandler
+						}
+						return nil
+					})
+				publish(t, q, Message{ID: "a"})
+				deadline := time.Now().Add(time.Second)
+				for len(rec.deliveries()) == 0 && time.Now().Before(deadline) {
+					time.Sleep(time.Millisecond)
+				}
+				start := time.Now()
+				publish(t, q, Message{ID: "b"})
+				for time.Now().Before(deadline) {
+					var handled bool
+					for _, d := range rec.deliveries() {
+						handled = handled || d.msg.ID == "b"
+					}
+					if handled {
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+				elapsed := time.Since(start)
+				closeQueue(t, q, 2*time.Second)
+				var order []string
+				for _, d := range rec.deliveries() {
+					order = append(order, fmt.Sprintf("%s#%d", d.msg.ID, d.msg.Attempts))
+				}
+				if fmt.Sprint(order) != "[a#1 b#1 a#2]" {
+					t.Errorf("投递顺序为 %v，期望 [a#1 b#1 a#2]：a等待重试时唯一的工作者应该去处理b", order)
+				} else if elapsed > 300*time.Millisecond {
+					t.Errorf("b在发布 %v 后才被处理：退避等待不能占用工作者", elapsed.Round(time.Millisecond))
+				}
+			},
+		},
+		grade.Task{
+			Name: "Close等待待重试的消息完成",
+			Run: func(t *grade.T) {
+				q := newQueue(t, QueueConfig{Workers: 2, MaxAttempts: 5, Backoff: constBackoff(50 * time.Millisecond)},
+					func(ctx context.Context, msg Message) error {
+						if msg.Attempts < 3 {
+							return errHandler
+						}
+						return nil
+					})
+				for i := 0; i < 5; i++ {
+					publish(t, q, Message{ID: fmt.Sprint(i)})
+				}
+				// 此时消息都在等待重试，Close不能直接关闭
+				closeQueue(t, q, 2*time.Second)
+				if s := q.Stats(); s.Succeeded != 5 || s.Dead != 0 {
+					t.Errorf("Close返回后Stats() = %+v，期望5条消息全部重试成功：Close要等待待重试的消息", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "Close之后Publish返回ErrQueueClosed",
+			Run: func(t *grade.T) {
+				q := newQueue(t, QueueConfig{Workers: 1, MaxAttempts: 1}, func(context.Context, Message) error { return nil })
+				closeQueue(t, q, time.Second)
+				var err error
+				if !grade.Within(time.Second, func() { err = q.Publish(Message{ID: "late"}) }) {
+					t.Fatalf("Close之后Publish被阻塞，应该立即返回ErrQueueClosed")
+				}
+				if !errors.Is(err, ErrQueueClosed) {
+					t.Errorf("Close之后Publish返回 %v，期望ErrQueueClosed", err)
+				}
+				if s := q.Stats(); s.Published != 0 {
+					t.Errorf("被拒绝的消息计入了Published（%d）", s.Published)
+				}
+			},
+		},
+		grade.Task{
+			Name: "Close超时时取消处理并返回ctx的错误",
+			Run: func(t *grade.T) {
+				started := make(chan struct{}, 1)
+				q := newQueue(t, QueueConfig{Workers: 1, MaxAttempts: 3, Backoff: constBackoff(time.Millisecond)},
+					func(ctx context.Context, msg Message) error {
+						started <- struct{}{}
+						<-ctx.Done()
+						return ctx.Err()
+					})
+				publish(t, q, Message{ID: "slow"})
+				select {
+				case <-started:
+				case <-time.After(time.Second):
+					t.Fatalf("handler没有被调用")
+				}
+				var err error
+				start := time.Now()
+				if !grade.Within(time.Second, func() {
+					ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+					defer cancel()
+					err = q.Close(ctx)
+				}) {
+					t.Fatalf("handler阻塞时Close在ctx到期后没有返回：要取消传给handler的ctx")
+				}
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Close返回 %v，期望context.DeadlineExceeded", err)
+				}
+				if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+					t.Errorf("Close只用了 %v，应该等到ctx到期", elapsed.Round(time.Millisecond))
+				}
+				dead := q.DeadLetters()
+				if len(dead) != 1 || dead[0].ID != "slow" {
+					t.Errorf("DeadLetters() = %+v，被取消的消息应该进入死信队列且不再重试", dead)
+				}
+			},
+		},
+		grade.Task{
+			Name:    "并发发布时每条消息恰好成功一次",
+			Timeout: 10 * time.Second,
+			Run: func(t *grade.T) {
+				var mu sync.Mutex
+				successes := make(map[string]int)
+				q := newQueue(t, QueueConfig{Workers: 4, MaxAttempts: 3, Backoff: constBackoff(time.Millisecond)},
+					func(ctx context.Context, msg Message) error {
+						var n int
+						fmt.Sscan(msg.ID, &n)
+						if n%3 == 0 && msg.Attempts == 1 {
+							return errHandler
+						}
+						mu.Lock()
+						successes[msg.ID]++
+						mu.Unlock()
+						return nil
+					})
+				const publishers, each = 10, 20
+				var wg sync.WaitGroup
+				for p := 0; p < publishers; p++ {
+					wg.Add(1)
+					go func(p int) {
+						defer wg.Done()
+						for i := 0; i < each; i++ {
+							q.Publish(Message{ID: fmt.Sprint(p*each + i)})
+						}
+					}(p)
+				}
+				if !grade.Within(5*time.Second, wg.Wait) {
+					t.Fatalf("%d 个goroutine并发发布 %d 条消息没有在5秒内完成", publishers, publishers*each)
+				}
+				closeQueue(t, q, 2*time.Second)
+				mu.Lock()
+				defer mu.Unlock()
+				if len(successes) != publishers*each {
+					t.Errorf("%d 条消息处理成功，期望 %d 条", len(successes), publishers*each)
+				}
+				for id, n := range successes {
+					if n != 1 {
+						t.Errorf("消息 %s 成功了 %d 次", id, n)
+					}
+				}
+				if s := q.Stats(); s.Published != publishers*each || s.Succeeded != publishers*each || s.Retried != 67 {
+					t.Errorf("Stats() = %+v，期望Published=Succeeded=%d，Retried=67", s, publishers*each)
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,188 @@
+/*
+Golang并发编程练习 - 困难级别
+练习文件：04_connection_pool_exercise.go
+练习主题：带泄漏检测的连接池
+
+练习目标：
+1. 限制同时打开的连接数，复用空闲连接
+2. 连接用完时让Acquire等待，支持ctx超时，超时的等待者不能吞掉归还的连接
+3. 处理拨号失败和已损坏的连接，不让它们永久占用名额
+4. 记录每个连接在哪里被借出，找出长时间没有归还的连接
+
+练习任务：
+- 任务1：Acquire优先复用空闲连接，没有空闲连接且未达到MaxOpen时拨号
+- 任务2：达到MaxOpen时等待其他goroutine归还，ctx到期时返回ctx的错误
+- 任务3：Release检查重复归还，已关闭的连接不放回池中
+- 任务4：Leaks报告借出超过LeakTimeout的连接以及调用Acquire的位置
+- 任务5：Close关闭空闲连接并唤醒所有等待者
+
+对应Demo：cmd/hard/04_connection_pool
+
+运行方式：go run exercises/hard/04_connection_pool_exercise.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Conn 模拟的数据库连接（已实现）
+type Conn struct {
+	ID     int
+	closed atomic.Bool
+}
+
+// Close 关闭连接；连接出错时使用者也可以直接关闭它，再交给Release
+func (c *Conn) Close() { c.closed.Store(true) }
+
+func (c *Conn) IsClosed() bool { return c.closed.Load() }
+
+// dialed 已拨号的次数，也用作连接ID
+var dialed atomic.Int64
+
+// dial 模拟建立连接（已实现）
+func dial() (*Conn, error) {
+	time.Sleep(10 * time.Millisecond) // 模拟建立连接的耗时
+	return &Conn{ID: int(dialed.Add(1))}, nil
+}
+
+var (
+	// ErrPoolClosed 连接池已关闭
+	ErrPoolClosed = errors.New("pool closed")
+	// ErrNotBorrowed 归还的连接不是从这个池借出的，或者已经归还过
+	ErrNotBorrowed = errors.New("connection not borrowed from pool")
+)
+
+// PoolConfig 连接池配置
+type PoolConfig struct {
+	MaxOpen     int           // 最多同时打开的连接数（包括正在拨号的），<=0时按1处理
+	MaxIdle     int           // 最多保留的空闲连接数，多出的连接归还时关闭；<=0时等于MaxOpen
+	LeakTimeout time.Duration // 借出超过这个时间没有归还视为泄漏，<=0时不检测
+}
+
+// Leak 疑似泄漏的连接
+type Leak struct {
+	ConnID int
+	Held   time.Duration // 已经借出多久
+	Caller string        // 调用Acquire的位置，格式为"文件名:行号"
+}
+
+// PoolStats 连接池统计
+type PoolStats struct {
+	Open    int   // 已打开的连接数（包括正在拨号的）
+	Idle    int   // 空闲连接数
+	InUse   int   // 借出的连接数
+	Waiting int   // 正在等待连接的Acquire数
+	Waits   int64 // 累计需要等待的Acquire次数
+}
+
+// Pool 连接池
+type Pool struct {
+	config PoolConfig
+	dial   func() (*Conn, error)
+	now    func() time.Time // 当前时间，评分时替换成可以手动拨动的时钟
+
+	mu sync.Mutex
+	// TODO: 定义需要的字段
+	// 提示：
+	// - 空闲连接、已打开的连接数、是否已关闭
+	// - 借出的连接 -> 借出时间和调用位置
+	// - 等待者队列：每个等待者一个带1个缓冲的channel，归还时把连接直接交给最早的等待者
+}
+
+// NewPool 创建连接池，dial用来建立新连接
+func NewPool(config PoolConfig, dial func() (*Conn, error)) *Pool {
+	// TODO: 初始化您定义的字段
+	return &Pool{config: config, dial: dial, now: time.Now}
+}
+
+// TODO: 实现Pool的所有方法
+// Acquire 借出一个连接：优先复用空闲连接，其次拨号，都不行时等待归还；ctx到期返回ctx.Err()
+func (p *Pool) Acquire(ctx context.Context) (*Conn, error) {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 用runtime.Caller(1)取得调用者的位置，只保留文件名（filepath.Base）
+	// 2. 拨号前先占用名额，解锁后再拨号，拨号失败要把名额还回去（可能有等待者在等这个名额）
+	// 3. 等待时ctx到期：加锁把自己从等待队列中移除；如果已经不在队列里，
+	//    说明连接已经交给了自己，要把它还回池中，否则这个连接就丢了
+	return nil, nil
+}
+
+// Release 归还连接；已关闭的连接不放回池中并释放名额；Close之后归还的连接被关闭
+func (p *Pool) Release(c *Conn) error {
+	// 在这里实现您的代码
+	return nil
+}
+
+// Leaks 借出超过LeakTimeout还没有归还的连接，按ConnID排序
+func (p *Pool) Leaks() []Leak {
+	// 在这里实现您的代码
+	return nil
+}
+
+// Stats 统计信息
+func (p *Pool) Stats() PoolStats {
+	// 在这里实现您的代码
+	return PoolStats{}
+}
+
+// Close 关闭连接池：关闭空闲连接，等待中的Acquire返回ErrPoolClosed。
+// 借出的连接归还时再关闭。重复调用返回ErrPoolClosed。
+func (p *Pool) Close() error {
+	// 在这里实现您的代码
+	return nil
+}
+
+func main() {
+	fmt.Println("=== 带泄漏检测的连接池练习 ===")
+
+	// 任务1：复用和限制连接数
+	fmt.Println("\n任务1：复用和限制连接数")
+	// TODO:
+	// 1. 用dial创建MaxOpen为3的连接池
+	// 2. 启动10个goroutine，每个借出连接、使用20ms后归还，重复5次
+	// 3. 打印拨号次数（dialed）和最终的Stats
+
+	fmt.Println("任务1完成\n")
+
+	// 任务2：等待超时
+	fmt.Println("任务2：等待超时")
+	// TODO:
+	// 1. MaxOpen为1，先借出唯一的连接不归还
+	// 2. 用100ms超时的ctx再借一次，打印返回的错误和等待时间
+	// 3. 归还连接后再借一次，确认连接没有丢失
+
+	fmt.Println("任务2完成\n")
+
+	// 任务3：泄漏检测
+	fmt.Println("任务3：泄漏检测")
+	// TODO:
+	// 1. LeakTimeout为50ms，借出两个连接，只归还一个
+	// 2. 100ms后打印Leaks()，确认报告了忘记归还的连接和借出的位置
+
+	fmt.Println("任务3完成\n")
+
+	// 任务4：关闭
+	fmt.Println("任务4：关闭")
+	// TODO:
+	// 1. 借出唯一的连接，启动一个goroutine等待连接
+	// 2. 调用Close，打印等待者收到的错误
+	// 3. 归还连接，确认它被关闭
+
+	fmt.Println("任务4完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么拨号不能在持有锁的时候进行？")
+	fmt.Println("2. 等待者超时和连接交给它几乎同时发生时，会出现什么问题？")
+	fmt.Println("3. 连接泄漏在生产环境中通常有什么表现？")
+	fmt.Println("4. 为什么空闲连接数要有上限，而不是保留所有打开过的连接？")
+}
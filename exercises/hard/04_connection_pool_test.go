@@ -0,0 +1,466 @@
+// 评分：go run ./exercises/grader hard/04
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+var errDial = errors.New("拨号失败")
+
+// gradeDialer 记录拨号次数，可以让前几次拨号失败
+type gradeDialer struct {
+	dials    atomic.Int64
+	failures atomic.Int64 // 还要失败的次数
+}
+
+func (d *gradeDialer) Dial() (*Conn, error) {
+	if d.failures.Add(-1) >= 0 {
+		return nil, errDial
+	}
+	return &Conn{ID: int(d.dials.Add(1))}, nil
+}
+
+func newPool(t *grade.T, config PoolConfig) (*Pool, *gradeDialer, *grade.FakeClock) {
+	d := &gradeDialer{}
+	p := NewPool(config, d.Dial)
+	if p == nil {
+		t.Fatalf("NewPool返回nil")
+	}
+	clock := grade.NewFakeClock()
+	p.now = clock.Now
+	return p, d, clock
+}
+
+// acquire 借出连接，1秒内没有借到时任务失败
+func acquire(t *grade.T, p *Pool) *Conn {
+	var c *Conn
+	var err error
+	if !grade.Within(2*time.Second, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		c, err = p.Acquire(ctx)
+	}) {
+		t.Fatalf("Acquire在ctx到期后仍然阻塞")
+	}
+	if err != nil {
+		t.Fatalf("池中有可用的连接或名额，Acquire却返回 %v", err)
+	}
+	if c == nil {
+		t.Fatalf("Acquire返回了nil连接")
+	}
+	return c
+}
+
+func release(t *grade.T, p *Pool, c *Conn) {
+	if err := p.Release(c); err != nil {
+		t.Fatalf("归还借出的连接%d返回 %v", c.ID, err)
+	}
+}
+
+// waitAcquire 在后台借出连接，结果从返回的channel中读取；任务结束时cancel让它退出
+type acquired struct {
+	conn *Conn
+	err  error
+}
+
+func waitAcquire(p *Pool) (<-chan acquired, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan acquired, 1)
+	go func() {
+		c, err := p.Acquire(ctx)
+		result <- acquired{c, err}
+	}()
+	return result, cancel
+}
+
+func TestConnectionPool(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "归还的连接被复用而不是重新拨号",
+			Run: func(t *grade.T) {
+				p, d, _ := newPool(t, PoolConfig{MaxOpen: 2})
+				a := acquire(t, p)
+				if s := p.Stats(); s.Open != 1 || s.InUse != 1 {
+					t.Errorf("借出一个连接后Stats() = %+v，期望Open=1 InUse=1", s)
+				}
+				release(t, p, a)
+				if s := p.Stats(); s.Open != 1 || s.Idle != 1 || s.InUse != 0 {
+					t.Errorf("归还后Stats() = %+v，期望Open=1 Idle=1 InUse=0", s)
+				}
+				b := acquire(t, p)
+				if b != a || d.dials.Load() != 1 {
+					t.Errorf("有空闲连接时又拨号了（共 %d 次），应该复用刚归还的连接", d.dials.Load())
+				}
+				release(t, p, b)
+			},
+		},
+		grade.Task{
+			Name: "并发借出时打开的连接数不超过MaxOpen",
+			Run: func(t *grade.T) {
+				const maxOpen = 3
+				p, d, _ := newPool(t, PoolConfig{MaxOpen: maxOpen})
+				var inUse, peak, errs atomic.Int64
+				var mu sync.Mutex
+				holders := make(map[*Conn]bool)
+				var shared atomic.Bool
+				var wg sync.WaitGroup
+				for g := 0; g < 10; g++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for i := 0; i < 10; i++ {
+							ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+							c, err := p.Acquire(ctx)
+							cancel()
+							if err != nil || c == nil {
+								errs.Add(1)
+								return
+							}
+							mu.Lock()
+							if holders[c] {
+								shared.Store(true)
+							}
+							holders[c] = true
+							mu.Unlock()
+							n := inUse.Add(1)
+							for cur := peak.Load(); n > cur && !peak.CompareAndSwap(cur, n); cur = peak.Load() {
+							}
+							time.Sleep(time.Millisecond)
+							inUse.Add(-1)
+							mu.Lock()
+							delete(holders, c)
+							mu.Unlock()
+							p.Release(c)
+						}
+					}()
+				}
+				if !grade.Within(3*time.Second, wg.Wait) {
+					t.Fatalf("10个goroutine各借出10次没有在3秒内完成：连接归还后没有唤醒等待者")
+				}
+				if n := errs.Load(); n > 0 {
+					t.Fatalf("%d 个goroutine借出连接失败", n)
+				}
+				if shared.Load() {
+					t.Errorf("同一个连接同时借给了两个goroutine")
+				}
+				if n := peak.Load(); n > maxOpen {
+					t.Errorf("同时借出了 %d 个连接，MaxOpen=%d", n, maxOpen)
+				}
+				if n := d.dials.Load(); n > maxOpen {
+					t.Errorf("拨号了 %d 次，MaxOpen=%d 且连接都被复用，最多拨号 %d 次", n, maxOpen, maxOpen)
+				}
+				if s := p.Stats(); s.InUse != 0 || s.Open > maxOpen || s.Waits == 0 {
+					t.Errorf("结束后Stats() = %+v，期望InUse=0、Open<=%d、Waits>0", s, maxOpen)
+				}
+			},
+		},
+		grade.Task{
+			Name: "连接用完时Acquire等待归还",
+			Run: func(t *grade.T) {
+				p, d, _ := newPool(t, PoolConfig{MaxOpen: 1})
+				a := acquire(t, p)
+				result, cancel := waitAcquire(p)
+				defer cancel()
+				select {
+				case r := <-result:
+					t.Fatalf("唯一的连接已借出，Acquire却立即返回了（%v, %v）", r.conn, r.err)
+				case <-time.After(100 * time.Millisecond):
+				}
+				if s := p.Stats(); s.Waiting != 1 {
+					t.Errorf("有一个Acquire在等待时Stats().Waiting = %d", s.Waiting)
+				}
+				release(t, p, a)
+				select {
+				case r := <-result:
+					if r.err != nil || r.conn != a {
+						t.Fatalf("归还后等待者得到（%v, %v），期望刚归还的连接", r.conn, r.err)
+					}
+					release(t, p, r.conn)
+				case <-time.After(time.Second):
+					t.Fatalf("连接归还后等待的Acquire没有被唤醒")
+				}
+				if n := d.dials.Load(); n != 1 {
+					t.Errorf("拨号了 %d 次，期望1次", n)
+				}
+			},
+		},
+		grade.Task{
+			Name: "等待超时返回ctx的错误",
+			Run: func(t *grade.T) {
+				p, _, _ := newPool(t, PoolConfig{MaxOpen: 1})
+				a := acquire(t, p)
+				var err error
+				start := time.Now()
+				if !grade.Within(time.Second, func() {
+					ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+					defer cancel()
+					_, err = p.Acquire(ctx)
+				}) {
+					t.Fatalf("ctx到期后Acquire仍然阻塞")
+				}
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("等待超时后Acquire返回 %v，期望context.DeadlineExceeded", err)
+				}
+				if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+					t.Errorf("Acquire只等了 %v 就返回了", elapsed.Round(time.Millisecond))
+				}
+				if s := p.Stats(); s.Waiting != 0 {
+					t.Errorf("超时返回后Stats().Waiting = %d，超时的等待者要从队列中移除", s.Waiting)
+				}
+				release(t, p, a)
+				release(t, p, acquire(t, p))
+			},
+		},
+		grade.Task{
+			Name: "等待者超时和归还同时发生时不丢失连接",
+			Run: func(t *grade.T) {
+				const maxOpen = 2
+				p, _, _ := newPool(t, PoolConfig{MaxOpen: maxOpen})
+				stop := make(chan struct{})
+				var wg sync.WaitGroup
+				loop := func(timeout time.Duration, hold bool) {
+					defer wg.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+						ctx, cancel := context.WithTimeout(context.Background(), timeout)
+						c, err := p.Acquire(ctx)
+						cancel()
+						if err == nil && c != nil {
+							if hold {
+								time.Sleep(200 * time.Microsecond)
+							}
+							p.Release(c)
+						}
+					}
+				}
+				for i := 0; i < maxOpen; i++ {
+					wg.Add(1)
+					go loop(500*time.Millisecond, true)
+				}
+				for i := 0; i < 8; i++ {
+					wg.Add(1)
+					go loop(100*time.Microsecond, false)
+				}
+				time.Sleep(300 * time.Millisecond)
+				close(stop)
+				if !grade.Within(2*time.Second, wg.Wait) {
+					t.Fatalf("停止后借出连接的goroutine没有退出：有Acquire一直阻塞")
+				}
+				if s := p.Stats(); s.InUse != 0 || s.Waiting != 0 || s.Open > maxOpen {
+					t.Errorf("结束后Stats() = %+v，期望InUse=0 Waiting=0 Open<=%d", s, maxOpen)
+				}
+				// 连接交给了已经超时的等待者又没有还回来时，这里会借不满MaxOpen个
+				conns := make([]*Conn, maxOpen)
+				for i := range conns {
+					var err error
+					if !grade.Within(time.Second, func() {
+						ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+						defer cancel()
+						conns[i], err = p.Acquire(ctx)
+					}) || err != nil {
+						t.Fatalf("没有人持有连接，却借不到第%d个连接（%v）：超时的等待者吞掉了交给它的连接", i+1, err)
+					}
+					if conns[i] == nil {
+						t.Fatalf("Acquire返回了nil连接")
+					}
+				}
+				for _, c := range conns {
+					release(t, p, c)
+				}
+			},
+		},
+		grade.Task{
+			Name: "拨号失败时返回错误并归还名额",
+			Run: func(t *grade.T) {
+				p, d, _ := newPool(t, PoolConfig{MaxOpen: 1})
+				d.failures.Store(2)
+				for i := 1; i <= 2; i++ {
+					var err error
+					if !grade.Within(time.Second, func() {
+						ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+						defer cancel()
+						_, err = p.Acquire(ctx)
+					}) {
+						t.Fatalf("第%d次拨号失败后Acquire阻塞了：失败的拨号仍然占着名额", i)
+					}
+					if !errors.Is(err, errDial) {
+						t.Fatalf("第%d次拨号失败时Acquire返回 %v，期望原样返回拨号的错误", i, err)
+					}
+				}
+				if s := p.Stats(); s.Open != 0 {
+					t.Errorf("两次拨号都失败后Stats().Open = %d，期望0", s.Open)
+				}
+				release(t, p, acquire(t, p))
+				if s := p.Stats(); s.Open != 1 || s.Idle != 1 {
+					t.Errorf("拨号成功并归还后Stats() = %+v，期望Open=1 Idle=1", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "Release拒绝重复归还，已关闭的连接不放回池中",
+			Run: func(t *grade.T) {
+				p, d, _ := newPool(t, PoolConfig{MaxOpen: 1})
+				a := acquire(t, p)
+				release(t, p, a)
+				if err := p.Release(a); !errors.Is(err, ErrNotBorrowed) {
+					t.Errorf("重复归还返回 %v，期望ErrNotBorrowed", err)
+				}
+				if err := p.Release(&Conn{ID: 99}); !errors.Is(err, ErrNotBorrowed) {
+					t.Errorf("归还不是从池中借出的连接返回 %v，期望ErrNotBorrowed", err)
+				}
+				if s := p.Stats(); s.Idle != 1 {
+					t.Fatalf("重复归还后Stats().Idle = %d，同一个连接被放回了两次", s.Idle)
+				}
+
+				a = acquire(t, p)
+				result, cancel := waitAcquire(p)
+				defer cancel()
+				time.Sleep(50 * time.Millisecond)
+				a.Close() // 使用中发现连接坏了
+				release(t, p, a)
+				select {
+				case r := <-result:
+					if r.err != nil || r.conn == nil {
+						t.Fatalf("坏连接归还后等待者得到（%v, %v），期望一个新拨号的连接", r.conn, r.err)
+					}
+					if r.conn == a || r.conn.IsClosed() {
+						t.Errorf("等待者拿到了已关闭的连接")
+					}
+					release(t, p, r.conn)
+				case <-time.After(time.Second):
+					t.Fatalf("坏连接归还后等待者没有被唤醒：名额空出来了，应该让等待者拨号")
+				}
+				if n := d.dials.Load(); n != 2 {
+					t.Errorf("拨号了 %d 次，期望2次", n)
+				}
+				if s := p.Stats(); s.Open != 1 {
+					t.Errorf("Stats().Open = %d，坏连接不再计入打开的连接", s.Open)
+				}
+			},
+		},
+		grade.Task{
+			Name: "超过MaxIdle的连接归还时被关闭",
+			Run: func(t *grade.T) {
+				p, d, _ := newPool(t, PoolConfig{MaxOpen: 3, MaxIdle: 1})
+				conns := []*Conn{acquire(t, p), acquire(t, p), acquire(t, p)}
+				for _, c := range conns {
+					release(t, p, c)
+				}
+				closed := 0
+				for _, c := range conns {
+					if c.IsClosed() {
+						closed++
+					}
+				}
+				if closed != 2 {
+					t.Errorf("归还3个连接后有 %d 个被关闭，MaxIdle=1，期望关闭2个", closed)
+				}
+				if s := p.Stats(); s.Open != 1 || s.Idle != 1 {
+					t.Errorf("Stats() = %+v，期望Open=1 Idle=1", s)
+				}
+				c := acquire(t, p)
+				if c.IsClosed() || d.dials.Load() != 3 {
+					t.Errorf("再次借出时拿到了已关闭的连接或重新拨号了，应该复用留下的空闲连接")
+				}
+				release(t, p, c)
+			},
+		},
+		grade.Task{
+			Name: "Leaks报告借出超时的连接和借出位置",
+			Run: func(t *grade.T) {
+				p, _, clock := newPool(t, PoolConfig{MaxOpen: 2, LeakTimeout: time.Minute})
+				_, _, line, _ := runtime.Caller(0)
+				a, err := p.Acquire(context.Background())
+				if err != nil || a == nil {
+					t.Fatalf("Acquire返回（%v, %v）", a, err)
+				}
+				clock.Advance(30 * time.Second)
+				b := acquire(t, p)
+				defer p.Release(b)
+				clock.Advance(40 * time.Second)
+
+				leaks := p.Leaks()
+				if len(leaks) != 1 || leaks[0].ConnID != a.ID {
+					t.Fatalf("a借出70秒、b借出40秒，LeakTimeout=1分钟，Leaks() = %+v，期望只有a", leaks)
+				}
+				if leaks[0].Held != 70*time.Second {
+					t.Errorf("Leak.Held = %v，期望70s（用p.now计算）", leaks[0].Held)
+				}
+				want := fmt.Sprintf("04_connection_pool_test.go:%d", line+1)
+				if !strings.HasSuffix(leaks[0].Caller, want) {
+					t.Errorf("Leak.Caller = %q，期望调用Acquire的位置 %q", leaks[0].Caller, want)
+				}
+				release(t, p, a)
+				if leaks := p.Leaks(); len(leaks) != 0 {
+					t.Errorf("a归还后Leaks() = %+v，期望为空", leaks)
+				}
+				clock.Advance(20 * time.Second)
+				if leaks := p.Leaks(); len(leaks) != 1 || leaks[0].ConnID != b.ID {
+					t.Errorf("b借出60秒后Leaks() = %+v，期望只有b", leaks)
+				}
+			},
+		},
+		grade.Task{
+			Name: "Close关闭空闲连接并唤醒等待者",
+			Run: func(t *grade.T) {
+				p, _, _ := newPool(t, PoolConfig{MaxOpen: 1})
+				idle := acquire(t, p)
+				release(t, p, idle)
+				if err := p.Close(); err != nil {
+					t.Fatalf("Close返回 %v", err)
+				}
+				if !idle.IsClosed() {
+					t.Errorf("Close没有关闭空闲连接")
+				}
+				if s := p.Stats(); s.Open != 0 || s.Idle != 0 {
+					t.Errorf("Close后Stats() = %+v，期望Open=0 Idle=0", s)
+				}
+				if err := p.Close(); !errors.Is(err, ErrPoolClosed) {
+					t.Errorf("重复Close返回 %v，期望ErrPoolClosed", err)
+				}
+
+				p, _, _ = newPool(t, PoolConfig{MaxOpen: 1})
+				busy := acquire(t, p)
+				result, cancel := waitAcquire(p)
+				defer cancel()
+				time.Sleep(50 * time.Millisecond)
+				if err := p.Close(); err != nil {
+					t.Fatalf("Close返回 %v", err)
+				}
+				select {
+				case r := <-result:
+					if !errors.Is(r.err, ErrPoolClosed) {
+						t.Errorf("Close后等待者得到（%v, %v），期望ErrPoolClosed", r.conn, r.err)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("Close没有唤醒等待中的Acquire")
+				}
+				if _, err := p.Acquire(context.Background()); !errors.Is(err, ErrPoolClosed) {
+					t.Errorf("Close后Acquire返回 %v，期望ErrPoolClosed", err)
+				}
+				release(t, p, busy)
+				if !busy.IsClosed() {
+					t.Errorf("Close之后归还的连接没有被关闭")
+				}
+				if s := p.Stats(); s.Open != 0 || s.InUse != 0 {
+					t.Errorf("借出的连接归还后Stats() = %+v，期望Open=0 InUse=0", s)
+				}
+			},
+		},
+	)
+}
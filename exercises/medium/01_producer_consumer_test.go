@@ -0,0 +1,141 @@
+// 评分：go run ./exercises/grader medium/01
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+// collect 接收channel中的全部产品，直到channel关闭
+func collect(products <-chan Product) []Product {
+	var list []Product
+	for p := range products {
+		list = append(list, p)
+	}
+	return list
+}
+
+func TestProducerConsumer(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "Produce生产count个产品后调用wg.Done",
+			Run: func(t *grade.T) {
+				products := make(chan Product, 100)
+				var wg sync.WaitGroup
+				wg.Add(1)
+				p := &Producer{ID: "P1", Type: "Electronics"}
+				grade.CaptureStdout(func() {
+					go p.Produce(products, 10, &wg)
+					if !grade.Within(2*time.Second, wg.Wait) {
+						t.Fatalf("Produce没有调用wg.Done()，或者生产10个产品超过了2秒")
+					}
+				})
+				close(products) // Produce不应关闭channel：多个生产者共用时由协调者在全部完成后关闭
+				list := collect(products)
+				if len(list) != 10 {
+					t.Fatalf("生产了 %d 个产品，期望10个", len(list))
+				}
+				seen := make(map[int]bool)
+				for _, prod := range list {
+					if seen[prod.ID] {
+						t.Errorf("产品ID %d 重复", prod.ID)
+					}
+					seen[prod.ID] = true
+					if prod.Name == "" {
+						t.Errorf("产品 %d 没有名字", prod.ID)
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "Consume消费到channel关闭后调用wg.Done",
+			Run: func(t *grade.T) {
+				products := make(chan Product, 10)
+				for i := 1; i <= 5; i++ {
+					products <- Product{ID: i, Name: "测试产品", Price: 1, Category: "Test"}
+				}
+				close(products)
+				var wg sync.WaitGroup
+				wg.Add(1)
+				c := &Consumer{ID: "C1", ProcessingTime: time.Millisecond}
+				grade.CaptureStdout(func() {
+					go c.Consume(products, &wg)
+					if !grade.Within(2*time.Second, wg.Wait) {
+						t.Fatalf("channel关闭后Consume没有结束或没有调用wg.Done()")
+					}
+				})
+				if n := len(products); n != 0 {
+					t.Errorf("Consume返回时channel中还剩 %d 个产品", n)
+				}
+			},
+		},
+		grade.Task{
+			Name: "Consume在channel关闭前持续等待",
+			Run: func(t *grade.T) {
+				products := make(chan Product)
+				var wg sync.WaitGroup
+				wg.Add(1)
+				c := &Consumer{ID: "C1", ProcessingTime: time.Millisecond}
+				grade.CaptureStdout(func() {
+					go c.Consume(products, &wg)
+					if grade.Within(100*time.Millisecond, wg.Wait) {
+						t.Fatalf("channel还没有关闭Consume就结束了，之后生产的产品没有人消费")
+					}
+					select {
+					case products <- Product{ID: 1, Name: "迟到的产品"}:
+					case <-time.After(time.Second):
+						t.Fatalf("Consume没有从channel接收产品")
+					}
+					close(products)
+					if !grade.Within(time.Second, wg.Wait) {
+						t.Errorf("channel关闭后Consume没有结束")
+					}
+				})
+			},
+		},
+		grade.Task{
+			Name:    "多生产者多消费者：全部产品被消费后所有goroutine退出",
+			Timeout: 15 * time.Second,
+			Run: func(t *grade.T) {
+				products := make(chan Product, 20)
+				// 在生产者和消费者之间插入一个无缓冲的计数阶段：消费者全部退出时，
+				// 计数阶段转发出去的产品一定都已被消费者取走
+				counted := make(chan Product)
+				var forwarded atomic.Int32
+				var prodWG, consWG sync.WaitGroup
+				grade.CaptureStdout(func() {
+					go func() {
+						for p := range products {
+							counted <- p
+							forwarded.Add(1)
+						}
+						close(counted)
+					}()
+					for i, typ := range []string{"Electronics", "Books", "Food"} {
+						prodWG.Add(1)
+						go (&Producer{ID: string(rune('A' + i)), Type: typ}).Produce(products, 8, &prodWG)
+					}
+					for i := 0; i < 3; i++ {
+						consWG.Add(1)
+						go (&Consumer{ID: string(rune('X' + i)), ProcessingTime: time.Millisecond}).Consume(counted, &consWG)
+					}
+					if !grade.Within(10*time.Second, prodWG.Wait) {
+						t.Fatalf("3个生产者在10秒内没有全部完成")
+					}
+					close(products)
+					if !grade.Within(5*time.Second, consWG.Wait) {
+						t.Fatalf("所有生产者完成并关闭channel后，消费者没有全部退出")
+					}
+				})
+				if n := forwarded.Load(); n != 24 {
+					t.Errorf("共消费了 %d 个产品，期望24个", n)
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,152 @@
+/*
+Golang并发编程练习 - 中等级别
+练习文件：03_rate_limiter_exercise.go
+练习主题：令牌桶限流
+
+练习目标：
+1. 理解令牌桶的两个参数：速率决定长期平均吞吐，容量决定允许的突发
+2. 按经过的时间惰性补充令牌，不需要后台goroutine定时放令牌
+3. 先预约令牌再等待，等待被取消时归还预约
+4. 按key分别限流，并发创建每个key的限流器时不重复创建
+
+练习任务：
+- 任务1：Allow按经过的时间补充令牌，最多补到容量，有令牌时取走一个
+- 任务2：Wait没有令牌时等到补充出来，ctx取消时返回ctx.Err()并归还预约
+- 任务3：KeyedLimiter为每个key维护独立的令牌桶
+
+对应Demo：cmd/medium/03_rate_limiter
+
+运行方式：go run exercises/medium/03_rate_limiter_exercise.go
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock 时钟，评分时替换成手动拨动的假时钟
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock 系统时钟（已实现）
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// TokenBucket 令牌桶：每秒补充rate个令牌，最多存burst个，新建时是满的
+type TokenBucket struct {
+	rate  float64
+	burst int
+	clock Clock
+
+	mu sync.Mutex
+	// TODO: 定义需要的字段
+	// 提示：当前令牌数（用float64，补充时会有小数）、上次补充的时间
+}
+
+// NewTokenBucket 创建令牌桶，clock为nil时使用RealClock
+func NewTokenBucket(rate float64, burst int, clock Clock) *TokenBucket {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	// TODO: 初始化您定义的字段，桶一开始是满的
+	return &TokenBucket{rate: rate, burst: burst, clock: clock}
+}
+
+// TODO: 实现Allow
+// Allow 有令牌时取走一个并返回true，否则立即返回false
+func (tb *TokenBucket) Allow() bool {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 加锁后先补充：令牌数 += 距上次补充经过的秒数 * rate，不超过burst，记下这次的时间
+	// 2. 令牌数 >= 1 时减一并返回true
+	// 3. 时间一律用tb.clock.Now()，评分时的时钟不会自己走
+	return true
+}
+
+// TODO: 实现Wait
+// Wait 等到取得一个令牌后返回nil；ctx先结束时返回ctx.Err()，这次没有取得令牌
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 加锁补充后直接减一（可以减成负数，表示预约了将来的令牌），有令牌时立即返回nil
+	// 2. 令牌数为负时需要等待 -令牌数/rate 秒，解锁后再等待，不要持有锁等待
+	// 3. 用select同时等待tb.clock.After(需要等的时间)和ctx.Done()
+	// 4. ctx先结束时加锁把预约的令牌加回去（不超过burst），否则后面的请求要替它多等
+	return nil
+}
+
+// KeyedLimiter 按key限流，例如每个用户、每个IP各自一个令牌桶
+type KeyedLimiter struct {
+	rate  float64
+	burst int
+	clock Clock
+
+	mu sync.Mutex
+	// TODO: 定义需要的字段
+	// 提示：key到*TokenBucket的map
+}
+
+// NewKeyedLimiter 创建按key限流的限流器，每个key的令牌桶参数相同
+func NewKeyedLimiter(rate float64, burst int, clock Clock) *KeyedLimiter {
+	// TODO: 初始化您定义的字段
+	return &KeyedLimiter{rate: rate, burst: burst, clock: clock}
+}
+
+// TODO: 实现Allow
+// Allow key对应的令牌桶有令牌时返回true；第一次见到的key创建一个满的令牌桶
+func (kl *KeyedLimiter) Allow(key string) bool {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 查找和创建令牌桶都要在锁内完成，否则两个goroutine可能各自创建一个，突发量翻倍
+	// 2. 取到令牌桶后解锁再调用它的Allow，令牌桶自己有锁，不同key之间不必互相等待
+	return true
+}
+
+func main() {
+	fmt.Println("=== 令牌桶限流练习 ===")
+
+	// 任务1：突发与补充
+	fmt.Println("\n任务1：突发与补充")
+	// TODO:
+	// 1. 创建每秒10个、容量5的令牌桶
+	// 2. 连续调用Allow 8次，打印每次的结果（前5次通过）
+	// 3. 等待200ms后再调用3次，观察补充了2个令牌
+
+	fmt.Println("任务1完成\n")
+
+	// 任务2：等待令牌
+	fmt.Println("任务2：等待令牌")
+	// TODO:
+	// 1. 创建每秒5个、容量1的令牌桶
+	// 2. 连续Wait 5次，打印每次返回的时间（第一次立即返回，之后约每200ms一次）
+	// 3. 用100ms超时的ctx调用Wait，观察返回context.DeadlineExceeded
+
+	fmt.Println("任务2完成\n")
+
+	// 任务3：按用户限流
+	fmt.Println("任务3：按用户限流")
+	// TODO:
+	// 1. 创建每秒1个、容量2的KeyedLimiter
+	// 2. 用户alice连续请求3次，bob请求1次，打印每次的结果
+	// 3. 观察alice的第3次被拒绝，bob不受alice影响
+
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么不用后台goroutine每隔1/rate秒放一个令牌？")
+	fmt.Println("2. Wait为什么要先减令牌再等待，而不是等到有令牌时再取？")
+	fmt.Println("3. KeyedLimiter的map会一直增长，应该怎样清理长期不用的key？")
+	fmt.Println("4. 多台服务器共同限流时，单机令牌桶还够用吗？")
+}
@@ -0,0 +1,249 @@
+// 评分：go run ./exercises/grader medium/03
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+// allowN 连续调用n次allow，返回通过的次数
+func allowN(n int, allow func() bool) int {
+	passed := 0
+	for i := 0; i < n; i++ {
+		if allow() {
+			passed++
+		}
+	}
+	return passed
+}
+
+func TestRateLimiter(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "Allow允许burst个突发，之后按速率补充且不超过容量",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				tb := NewTokenBucket(10, 5, clock)
+				if n := allowN(8, tb.Allow); n != 5 {
+					t.Fatalf("新建的容量5的令牌桶连续请求8次通过了 %d 次，期望5次：新建时桶是满的", n)
+				}
+				clock.Advance(150 * time.Millisecond)
+				if n := allowN(3, tb.Allow); n != 1 {
+					t.Errorf("每秒10个，时钟走150ms后通过了 %d 次，期望1次", n)
+				}
+				clock.Advance(50 * time.Millisecond)
+				if n := allowN(3, tb.Allow); n != 1 {
+					t.Errorf("又走50ms后通过了 %d 次，期望1次：不满一个的令牌要累积下来", n)
+				}
+				clock.Advance(time.Hour)
+				if n := allowN(10, tb.Allow); n != 5 {
+					t.Errorf("空闲一小时后连续请求10次通过了 %d 次，期望5次：补充不能超过容量", n)
+				}
+			},
+		},
+		grade.Task{
+			Name: "并发调用Allow时通过的请求数恰好等于容量",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				tb := NewTokenBucket(1, 20, clock)
+				var passed atomic.Int32
+				var wg sync.WaitGroup
+				start := make(chan struct{})
+				for i := 0; i < 100; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						<-start
+						if tb.Allow() {
+							passed.Add(1)
+						}
+					}()
+				}
+				close(start)
+				wg.Wait()
+				if n := passed.Load(); n != 20 {
+					t.Errorf("100个goroutine同时请求，通过了 %d 个，期望20个", n)
+				}
+			},
+		},
+		grade.Task{
+			Name: "Wait有令牌时立即返回，没有时等到补充出来",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				tb := NewTokenBucket(5, 1, clock)
+				var err error
+				if !grade.Within(time.Second, func() { err = tb.Wait(context.Background()) }) || err != nil {
+					t.Fatalf("桶里有令牌时Wait没有立即返回nil（%v）", err)
+				}
+				done := make(chan error, 1)
+				go func() { done <- tb.Wait(context.Background()) }()
+				if !clock.WaitPending(1) {
+					select {
+					case err := <-done:
+						t.Fatalf("桶里没有令牌时Wait立即返回了 %v，期望等待200ms（用clock.After）", err)
+					case <-time.After(time.Second):
+						t.Fatalf("Wait没有调用clock.After等待补充：时间要一律从tb.clock获取")
+					}
+				}
+				clock.Advance(100 * time.Millisecond)
+				select {
+				case <-done:
+					t.Fatalf("每秒5个，时钟只走了100ms Wait就返回了")
+				case <-time.After(20 * time.Millisecond):
+				}
+				clock.Advance(100 * time.Millisecond)
+				select {
+				case err := <-done:
+					if err != nil {
+						t.Errorf("等到令牌后Wait返回 %v，期望nil", err)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("时钟走够200ms后Wait没有返回")
+				}
+				if tb.Allow() {
+					t.Errorf("Wait取走了补充出来的令牌，紧接着的Allow不应该通过")
+				}
+			},
+		},
+		grade.Task{
+			Name: "多个Wait按顺序排队，各自等待不同的时间",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				tb := NewTokenBucket(10, 1, clock)
+				tb.Allow()
+				done := make([]chan struct{}, 3)
+				for i := range done {
+					done[i] = make(chan struct{})
+					ch := done[i]
+					go func() { tb.Wait(context.Background()); close(ch) }()
+					if !clock.WaitPending(i + 1) {
+						t.Fatalf("第%d个Wait没有开始等待", i+1)
+					}
+				}
+				for i := range done {
+					clock.Advance(100 * time.Millisecond)
+					select {
+					case <-done[i]:
+					case <-time.After(time.Second):
+						t.Fatalf("每秒10个，时钟走到 %dms 后第%d个Wait没有返回：每个Wait要预约一个令牌，按排在前面的数量计算等待时间", (i+1)*100, i+1)
+					}
+					for j := i + 1; j < len(done); j++ {
+						select {
+						case <-done[j]:
+							t.Fatalf("时钟走到 %dms 时第%d个Wait就返回了，多个Wait拿到了同一个令牌", (i+1)*100, j+1)
+						default:
+						}
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "ctx取消时Wait返回ctx.Err()并归还预约的令牌",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				tb := NewTokenBucket(1, 1, clock)
+				tb.Allow()
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan error, 1)
+				go func() { done <- tb.Wait(ctx) }()
+				if !clock.WaitPending(1) {
+					t.Fatalf("桶里没有令牌时Wait没有调用clock.After等待")
+				}
+				cancel()
+				select {
+				case err := <-done:
+					if !errors.Is(err, context.Canceled) {
+						t.Errorf("ctx取消后Wait返回 %v，期望context.Canceled", err)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("ctx取消后Wait没有返回：要同时等待ctx.Done()")
+				}
+				clock.Advance(time.Second)
+				if !tb.Allow() {
+					t.Errorf("取消的Wait没有归还预约的令牌：每秒1个，1秒后Allow应该通过")
+				}
+
+				// 桶又空了：多个等待者同时取消，归还预约也要加锁
+				ctx, cancel = context.WithCancel(context.Background())
+				errs := make(chan error, 10)
+				for i := 0; i < 10; i++ {
+					go func() { errs <- tb.Wait(ctx) }()
+				}
+				if !clock.WaitPending(10) {
+					t.Fatalf("10个Wait没有都开始等待")
+				}
+				cancel()
+				for i := 0; i < 10; i++ {
+					select {
+					case <-errs:
+					case <-time.After(time.Second):
+						t.Fatalf("ctx取消后有Wait没有返回")
+					}
+				}
+				clock.Advance(time.Second)
+				if n := allowN(3, tb.Allow); n != 1 {
+					t.Errorf("10个Wait同时取消后又过了1秒，Allow通过了 %d 次，期望1次：每个取消的Wait都要归还自己的预约", n)
+				}
+
+				ctx, cancel = context.WithCancel(context.Background())
+				cancel()
+				if err := tb.Wait(ctx); !errors.Is(err, context.Canceled) {
+					t.Errorf("ctx已经取消时Wait返回 %v，期望context.Canceled", err)
+				}
+			},
+		},
+		grade.Task{
+			Name: "KeyedLimiter每个key各自限流",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				kl := NewKeyedLimiter(1, 2, clock)
+				if n := allowN(3, func() bool { return kl.Allow("alice") }); n != 2 {
+					t.Errorf("容量2，alice连续请求3次通过了 %d 次，期望2次", n)
+				}
+				if !kl.Allow("bob") {
+					t.Errorf("alice用完令牌后bob的第一次请求被拒绝了：每个key要有自己的令牌桶")
+				}
+				clock.Advance(time.Second)
+				if n := allowN(3, func() bool { return kl.Allow("alice") }); n != 1 {
+					t.Errorf("1秒后alice请求3次通过了 %d 次，期望1次", n)
+				}
+			},
+		},
+		grade.Task{
+			Name: "KeyedLimiter并发访问同一个新key时只创建一个令牌桶",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				kl := NewKeyedLimiter(1, 3, clock)
+				for round := 0; round < 20; round++ {
+					key := fmt.Sprintf("user-%d", round)
+					var passed atomic.Int32
+					var wg sync.WaitGroup
+					start := make(chan struct{})
+					for i := 0; i < 10; i++ {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							<-start
+							if kl.Allow(key) {
+								passed.Add(1)
+							}
+						}()
+					}
+					close(start)
+					wg.Wait()
+					if n := passed.Load(); n != 3 {
+						t.Fatalf("10个goroutine同时请求新的key，通过了 %d 个，期望3个：查找和创建令牌桶要在同一次加锁内完成", n)
+					}
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,155 @@
+/*
+Golang并发编程练习 - 中等级别
+练习文件：05_context_cancellation_exercise.go
+练习主题：Context取消传播、超时预算与errgroup
+
+练习目标：
+1. 理解取消如何沿着ctx树从父节点传播到子孙节点
+2. 自己实现按时钟计算的时间预算，子ctx的截止时间不超过父ctx
+3. 把一个请求的总预算分配给顺序执行的各个步骤
+4. 实现errgroup，用它写一个快速失败、限制并发数的抓取器
+
+练习任务：
+- 任务1：WithBudget派生带时间预算的ctx，到期时以ErrBudgetExceeded为原因取消
+- 任务2：RunSteps依次执行各步骤，每一步的时间不超过ctx剩余的预算
+- 任务3：实现Group（WithContext、Go、Wait），第一个错误取消其余任务
+- 任务4：FetchAll并发抓取，限制并发数，任一失败立即停止
+
+对应Demo：cmd/medium/05_context_cancellation
+
+运行方式：go run exercises/medium/05_context_cancellation_exercise.go
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock 时钟，评分时替换成手动拨动的假时钟
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock 系统时钟（已实现）
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// ErrBudgetExceeded 时间预算用完；errors.Is(ErrBudgetExceeded, context.DeadlineExceeded)为true
+var ErrBudgetExceeded = fmt.Errorf("time budget exceeded: %w", context.DeadlineExceeded)
+
+// TODO: 实现WithBudget
+// WithBudget 派生一个最多持续budget的ctx，时间按clock计算。
+// 到期时ctx被取消，context.Cause返回ErrBudgetExceeded；父ctx被取消时随之取消。
+// 父ctx的截止时间更早时沿用父ctx的截止时间。
+func WithBudget(parent context.Context, clock Clock, budget time.Duration) (context.Context, context.CancelFunc) {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. context.WithCancelCause负责取消和传播，到期时cancel(ErrBudgetExceeded)
+	// 2. 定义一个内嵌context.Context的结构体，重写Deadline()返回clock.Now()+budget
+	// 3. 返回之前就调用clock.After登记计时，再启动goroutine等待它；
+	//    goroutine还要监听ctx.Done()，提前取消时立即退出
+	return parent, func() {}
+}
+
+// Step 顺序执行的一步
+type Step struct {
+	Name    string
+	Timeout time.Duration // 这一步最多用多久，不超过ctx剩余的时间
+	Run     func(ctx context.Context) error
+}
+
+// TODO: 实现RunSteps
+// RunSteps 依次执行steps，每一步的ctx由WithBudget(ctx, clock, step.Timeout)派生。
+// 某一步返回错误，或者开始某一步之前ctx已经结束时，停止并返回"步骤名: 原因"。
+func RunSteps(ctx context.Context, clock Clock, steps []Step) error {
+	// 在这里实现您的代码
+	// 提示：每一步结束后都要调用cancel，用fmt.Errorf的%w包装原因
+	return nil
+}
+
+// Group 一组协同工作的goroutine，零值可以直接使用（不取消任何ctx）
+type Group struct {
+	wg sync.WaitGroup
+	// TODO: 定义需要的字段
+	// 提示：取消ctx的函数、第一个错误，以及保证只记录一次的sync.Once
+}
+
+// TODO: 实现Group
+// WithContext 返回新的Group和派生的ctx：第一个返回错误的任务以这个错误为原因取消ctx，Wait返回时ctx也被取消
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	// 在这里实现您的代码
+	return &Group{}, ctx
+}
+
+// Go 在新的goroutine中执行f
+func (g *Group) Go(f func() error) {
+	// 在这里实现您的代码
+}
+
+// Wait 等待所有任务结束，返回第一个错误
+func (g *Group) Wait() error {
+	// 在这里实现您的代码
+	return nil
+}
+
+// Fetcher 抓取一个url
+type Fetcher func(ctx context.Context, url string) (string, error)
+
+// TODO: 实现FetchAll
+// FetchAll 并发抓取urls，同时进行的抓取不超过limit个，总用时不超过budget，结果按urls的顺序返回。
+// 任一抓取失败时取消其余抓取、不再开始新的抓取，等已开始的抓取都返回后
+// 返回第一个错误，格式为"fetch <url>: <原因>"。
+func FetchAll(ctx context.Context, clock Clock, urls []string, limit int, budget time.Duration, fetch Fetcher) ([]string, error) {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 先用WithBudget限制总时间，再用WithContext创建Group
+	// 2. 用容量为limit的channel作为信号量，拿到名额之后再检查一次ctx是否已经结束
+	// 3. 循环变量要复制一份再在goroutine中使用
+	return nil, nil
+}
+
+func main() {
+	fmt.Println("=== Context取消与errgroup练习 ===")
+
+	// 任务1：取消传播和时间预算
+	fmt.Println("\n任务1：取消传播和时间预算")
+	// TODO:
+	// 1. 用RealClock{}创建300ms预算的ctx，再从它派生一个申请1秒的子ctx
+	// 2. 打印子ctx的Deadline()还剩多久，等待子ctx结束后打印context.Cause
+
+	fmt.Println("任务1完成\n")
+
+	// 任务2：分步骤的超时预算
+	fmt.Println("任务2：分步骤的超时预算")
+	// TODO:
+	// 1. 总预算250ms，三个步骤分别需要50ms、100ms、150ms
+	// 2. 观察哪一步因为总预算不够而失败
+
+	fmt.Println("任务2完成\n")
+
+	// 任务3：快速失败的并发抓取
+	fmt.Println("任务3：快速失败的并发抓取")
+	// TODO:
+	// 1. 模拟抓取：耗时和url长度成正比，以"bad"开头的url返回错误，等待时监听ctx
+	// 2. 6个url中有一个bad，limit为3，观察其余抓取被取消、后面的url没有开始
+	// 3. 再抓取全部成功的一组url，打印结果
+
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 子ctx申请的时间比父ctx剩余的时间长时，应该以哪个为准？为什么？")
+	fmt.Println("2. 为什么每一步结束后都要调用cancel，即使这一步已经成功了？")
+	fmt.Println("3. 一个抓取失败后，为什么还要等已经开始的抓取返回才返回错误？")
+	fmt.Println("4. 为什么用假时钟测试截止时间，而不是真的sleep？")
+}
@@ -0,0 +1,408 @@
+// 评分：go run ./exercises/grader medium/05
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+// doneWithin ctx在d内结束返回true
+func doneWithin(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func checkDeadline(t *grade.T, what string, ctx context.Context, want time.Duration) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		t.Fatalf("%s的Deadline()没有截止时间", what)
+	}
+	if got := d.Sub(grade.Epoch); got != want {
+		t.Errorf("%s的截止时间为起点后 %v，期望 %v", what, got, want)
+	}
+}
+
+// blockFetch 等到ctx结束，返回取消的原因
+func blockFetch(ctx context.Context, url string) (string, error) {
+	<-ctx.Done()
+	return "", context.Cause(ctx)
+}
+
+var errFetch = errors.New("503 service unavailable")
+
+func TestContextCancellation(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "WithBudget按时钟计算截止时间并在到期时取消",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				ctx, cancel := WithBudget(context.Background(), clock, 5*time.Second)
+				defer cancel()
+				checkDeadline(t, "5秒预算的ctx", ctx, 5*time.Second)
+				if n := clock.Pending(); n != 1 {
+					t.Fatalf("WithBudget返回后clock上有 %d 个计时器，期望1个：返回之前就要调用clock.After", n)
+				}
+				clock.Advance(4 * time.Second)
+				if doneWithin(ctx, 20*time.Millisecond) {
+					t.Fatalf("时钟只走了4秒，5秒预算的ctx就结束了（%v）", context.Cause(ctx))
+				}
+				clock.Advance(time.Second)
+				if !doneWithin(ctx, time.Second) {
+					t.Fatalf("时钟走到5秒后ctx没有被取消")
+				}
+				if cause := context.Cause(ctx); !errors.Is(cause, ErrBudgetExceeded) {
+					t.Errorf("预算用完时context.Cause = %v，期望ErrBudgetExceeded", cause)
+				}
+
+				ctx, cancel = WithBudget(context.Background(), clock, 0)
+				defer cancel()
+				if !doneWithin(ctx, time.Second) || !errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+					t.Errorf("预算为0时ctx应立即以ErrBudgetExceeded结束，现在Cause = %v", context.Cause(ctx))
+				}
+			},
+		},
+		grade.Task{
+			Name: "取消沿着ctx树向下传播",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				root, cancelRoot := context.WithCancel(context.Background())
+				defer cancelRoot()
+				child, cancelChild := WithBudget(root, clock, 5*time.Second)
+				defer cancelChild()
+				grandchild, cancelGrandchild := WithBudget(child, clock, 10*time.Second)
+				defer cancelGrandchild()
+				sibling, cancelSibling := WithBudget(root, clock, 10*time.Second)
+				defer cancelSibling()
+
+				checkDeadline(t, "父ctx只剩5秒时申请10秒的子ctx", grandchild, 5*time.Second)
+				cancelChild()
+				if !doneWithin(grandchild, time.Second) {
+					t.Fatalf("父ctx取消后子ctx没有被取消")
+				}
+				if cause := context.Cause(grandchild); !errors.Is(cause, context.Canceled) {
+					t.Errorf("父ctx被cancel取消后子ctx的Cause = %v，期望context.Canceled", cause)
+				}
+				if doneWithin(sibling, 20*time.Millisecond) || root.Err() != nil {
+					t.Fatalf("取消一个ctx影响了它的兄弟或父节点")
+				}
+
+				next, cancelNext := WithBudget(sibling, clock, time.Minute)
+				defer cancelNext()
+				clock.Advance(10 * time.Second)
+				if !doneWithin(next, time.Second) {
+					t.Fatalf("父ctx预算用完后子ctx没有被取消")
+				}
+				if cause := context.Cause(next); !errors.Is(cause, ErrBudgetExceeded) {
+					t.Errorf("父ctx预算用完时子ctx的Cause = %v，期望沿用父ctx的ErrBudgetExceeded", cause)
+				}
+			},
+		},
+		grade.Task{
+			Name: "cancel之后不留下等待计时的goroutine",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				for i := 0; i < 100; i++ {
+					ctx, cancel := WithBudget(context.Background(), clock, time.Hour)
+					cancel()
+					if !errors.Is(ctx.Err(), context.Canceled) {
+						t.Fatalf("调用cancel后ctx.Err() = %v，期望context.Canceled", ctx.Err())
+					}
+				}
+				// 时钟不再前进：计时goroutine只等计时器的话永远不会退出，任务结束后的泄漏检查会发现它们
+			},
+		},
+		grade.Task{
+			Name: "RunSteps给每一步分配不超过剩余预算的时间",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				total, cancel := WithBudget(context.Background(), clock, 5*time.Second)
+				defer cancel()
+				var ran []string
+				// step 检查这一步的截止时间，然后让时钟走spend
+				step := func(name string, timeout, wantDeadline, spend time.Duration) Step {
+					return Step{Name: name, Timeout: timeout, Run: func(ctx context.Context) error {
+						ran = append(ran, name)
+						checkDeadline(t, "步骤"+name, ctx, wantDeadline)
+						clock.Advance(spend)
+						return nil
+					}}
+				}
+				err := RunSteps(total, clock, []Step{
+					step("a", time.Second, time.Second, 500*time.Millisecond),
+					step("b", 2*time.Second, 2500*time.Millisecond, time.Second), // 0.5秒时开始
+					step("c", 10*time.Second, 5*time.Second, 0),                  // 申请10秒，总预算只剩3.5秒
+				})
+				if err != nil {
+					t.Errorf("所有步骤都成功时RunSteps返回 %v", err)
+				}
+				if fmt.Sprint(ran) != "[a b c]" {
+					t.Errorf("执行的步骤为 %v，期望依次执行 [a b c]", ran)
+				}
+			},
+		},
+		grade.Task{
+			Name: "某一步超时后停止并返回带步骤名的错误",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				var ran []string
+				err := RunSteps(context.Background(), clock, []Step{
+					{Name: "鉴权", Timeout: time.Second, Run: func(ctx context.Context) error {
+						ran = append(ran, "鉴权")
+						return nil
+					}},
+					{Name: "查询", Timeout: 2 * time.Second, Run: func(ctx context.Context) error {
+						ran = append(ran, "查询")
+						clock.Advance(2 * time.Second)
+						if !doneWithin(ctx, time.Second) {
+							return errors.New("预算用完后ctx没有被取消")
+						}
+						return context.Cause(ctx)
+					}},
+					{Name: "渲染", Timeout: time.Second, Run: func(ctx context.Context) error {
+						ran = append(ran, "渲染")
+						return nil
+					}},
+				})
+				if fmt.Sprint(ran) != "[鉴权 查询]" {
+					t.Errorf("执行的步骤为 %v，查询失败后不应该继续执行渲染", ran)
+				}
+				if !errors.Is(err, ErrBudgetExceeded) || !strings.HasPrefix(fmt.Sprint(err), "查询: ") {
+					t.Errorf("RunSteps返回 %v，期望\"查询: \"开头并用%%w包装ErrBudgetExceeded", err)
+				}
+			},
+		},
+		grade.Task{
+			Name: "RunSteps在ctx取消后不再开始下一步，并取消每一步的ctx",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				parent, cancelParent := context.WithCancel(context.Background())
+				defer cancelParent()
+				var ranSecond bool
+				err := RunSteps(parent, clock, []Step{
+					{Name: "第一步", Timeout: time.Hour, Run: func(ctx context.Context) error {
+						cancelParent() // 请求方放弃了
+						return nil
+					}},
+					{Name: "第二步", Timeout: time.Hour, Run: func(ctx context.Context) error {
+						ranSecond = true
+						return nil
+					}},
+				})
+				if ranSecond {
+					t.Errorf("ctx取消后仍然开始了第二步")
+				}
+				if !errors.Is(err, context.Canceled) || !strings.HasPrefix(fmt.Sprint(err), "第二步: ") {
+					t.Errorf("RunSteps返回 %v，期望\"第二步: \"开头并包装context.Canceled", err)
+				}
+
+				var ctxs []context.Context
+				step := func(ctx context.Context) error { ctxs = append(ctxs, ctx); return nil }
+				RunSteps(context.Background(), clock, []Step{
+					{Name: "x", Timeout: time.Hour, Run: step},
+					{Name: "y", Timeout: time.Hour, Run: step},
+				})
+				for i, ctx := range ctxs {
+					if ctx.Err() == nil {
+						t.Errorf("第%d步成功返回后它的ctx没有被取消：每一步结束都要调用cancel", i+1)
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "Group等待所有任务并返回第一个错误",
+			Run: func(t *grade.T) {
+				g, ctx := WithContext(context.Background())
+				if g == nil {
+					t.Fatalf("WithContext返回nil")
+				}
+				errFirst := errors.New("第一个错误")
+				var slowDone atomic.Bool
+				g.Go(func() error {
+					<-ctx.Done()
+					time.Sleep(20 * time.Millisecond)
+					slowDone.Store(true)
+					return ctx.Err()
+				})
+				g.Go(func() error { return errFirst })
+				var err error
+				if !grade.Within(time.Second, func() { err = g.Wait() }) {
+					t.Fatalf("一个任务返回错误后Wait没有返回：ctx要以这个错误为原因取消")
+				}
+				if !errors.Is(err, errFirst) {
+					t.Errorf("Wait返回 %v，期望第一个错误", err)
+				}
+				if !slowDone.Load() {
+					t.Errorf("Wait在还有任务运行时就返回了")
+				}
+				if cause := context.Cause(ctx); !errors.Is(cause, errFirst) {
+					t.Errorf("context.Cause(ctx) = %v，期望第一个错误", cause)
+				}
+
+				g, ctx = WithContext(context.Background())
+				var n atomic.Int32
+				for i := 0; i < 10; i++ {
+					g.Go(func() error { n.Add(1); return nil })
+				}
+				if err := g.Wait(); err != nil || n.Load() != 10 {
+					t.Errorf("10个任务都成功时Wait返回 %v，执行了 %d 个", err, n.Load())
+				}
+				if ctx.Err() == nil {
+					t.Errorf("Wait返回后ctx没有被取消")
+				}
+
+				var zero Group
+				zero.Go(func() error { return errFirst })
+				zero.Go(func() error { return nil })
+				if err := zero.Wait(); !errors.Is(err, errFirst) {
+					t.Errorf("零值Group的Wait返回 %v，期望第一个错误", err)
+				}
+			},
+		},
+		grade.Task{
+			Name: "FetchAll按顺序返回结果且并发数不超过limit",
+			Run: func(t *grade.T) {
+				const limit = 4
+				var inFlight, peak atomic.Int32
+				fetch := func(ctx context.Context, url string) (string, error) {
+					n := inFlight.Add(1)
+					for cur := peak.Load(); n > cur && !peak.CompareAndSwap(cur, n); cur = peak.Load() {
+					}
+					time.Sleep(2 * time.Millisecond)
+					inFlight.Add(-1)
+					return "body-" + url, nil
+				}
+				urls := make([]string, 20)
+				for i := range urls {
+					urls[i] = fmt.Sprintf("u%d", i)
+				}
+				var got []string
+				var err error
+				if !grade.Within(2*time.Second, func() {
+					got, err = FetchAll(context.Background(), grade.NewFakeClock(), urls, limit, time.Hour, fetch)
+				}) {
+					t.Fatalf("FetchAll没有在2秒内返回")
+				}
+				if err != nil {
+					t.Fatalf("全部抓取成功时FetchAll返回 %v", err)
+				}
+				if len(got) != len(urls) {
+					t.Fatalf("返回了 %d 个结果，期望 %d 个", len(got), len(urls))
+				}
+				for i, body := range got {
+					if body != "body-"+urls[i] {
+						t.Fatalf("第%d个结果为 %q，期望 %q：结果要按urls的顺序", i, body, "body-"+urls[i])
+					}
+				}
+				if p := peak.Load(); p > limit {
+					t.Errorf("同时进行了 %d 个抓取，limit=%d", p, limit)
+				} else if p < 2 {
+					t.Errorf("同时进行的抓取最多只有 %d 个，抓取没有并发执行", p)
+				}
+			},
+		},
+		grade.Task{
+			Name: "一个抓取失败时取消其余抓取且不再开始新的抓取",
+			Run: func(t *grade.T) {
+				var mu sync.Mutex
+				var started []string
+				var inFlight atomic.Int32
+				fetch := func(ctx context.Context, url string) (string, error) {
+					inFlight.Add(1)
+					defer inFlight.Add(-1)
+					mu.Lock()
+					started = append(started, url)
+					mu.Unlock()
+					if url == "u1" {
+						// 等三个抓取都开始后再失败
+						for i := 0; i < 100 && inFlight.Load() < 3; i++ {
+							time.Sleep(time.Millisecond)
+						}
+						return "", errFetch
+					}
+					return blockFetch(ctx, url)
+				}
+				urls := []string{"u0", "u1", "u2", "u3", "u4", "u5", "u6"}
+				var got []string
+				var err error
+				if !grade.Within(2*time.Second, func() {
+					got, err = FetchAll(context.Background(), grade.NewFakeClock(), urls, 3, time.Hour, fetch)
+				}) {
+					t.Fatalf("u1失败后FetchAll没有返回：要取消其余抓取")
+				}
+				if !errors.Is(err, errFetch) || !strings.HasPrefix(fmt.Sprint(err), "fetch u1: ") {
+					t.Errorf("FetchAll返回 %v，期望\"fetch u1: \"开头并包装抓取的错误", err)
+				}
+				if got != nil {
+					t.Errorf("失败时返回了结果 %v，期望nil", got)
+				}
+				if n := inFlight.Load(); n != 0 {
+					t.Errorf("FetchAll返回时还有 %d 个抓取没有结束", n)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, url := range started {
+					if url >= "u3" {
+						t.Errorf("u1失败后又开始了抓取 %s（已开始: %v）：拿到名额后要再检查ctx", url, started)
+						break
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "总预算用完时FetchAll返回ErrBudgetExceeded",
+			Run: func(t *grade.T) {
+				clock := grade.NewFakeClock()
+				var starts atomic.Int32
+				begin := make(chan struct{}, 4)
+				fetch := func(ctx context.Context, url string) (string, error) {
+					starts.Add(1)
+					begin <- struct{}{}
+					return blockFetch(ctx, url)
+				}
+				done := make(chan error, 1)
+				go func() {
+					_, err := FetchAll(context.Background(), clock, []string{"a", "b", "c", "d"}, 2, 3*time.Second, fetch)
+					done <- err
+				}()
+				for i := 0; i < 2; i++ {
+					select {
+					case <-begin:
+					case <-time.After(time.Second):
+						t.Fatalf("limit=2时没有开始两个抓取")
+					}
+				}
+				clock.Advance(2 * time.Second)
+				select {
+				case err := <-done:
+					t.Fatalf("预算3秒，时钟只走了2秒FetchAll就返回了 %v", err)
+				case <-time.After(20 * time.Millisecond):
+				}
+				clock.Advance(time.Second)
+				select {
+				case err := <-done:
+					if !errors.Is(err, ErrBudgetExceeded) {
+						t.Errorf("预算用完时FetchAll返回 %v，期望包装ErrBudgetExceeded", err)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("预算用完后FetchAll没有返回")
+				}
+				if n := starts.Load(); n != 2 {
+					t.Errorf("开始了 %d 个抓取，预算用完后不应该再开始新的抓取", n)
+				}
+			},
+		},
+	)
+}
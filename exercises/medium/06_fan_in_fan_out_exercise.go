@@ -0,0 +1,128 @@
+/*
+Golang并发编程练习 - 中等级别
+练习文件：06_fan_in_fan_out_exercise.go
+练习主题：扇入扇出与快速失败
+
+练习目标：
+1. 扇出：多个工作者从同一个channel取任务，并行处理
+2. 扇入：把多个结果channel合并成一个，全部输入关闭后才关闭输出
+3. 谁创建channel谁负责关闭，下游用range读到关闭为止
+4. 一个任务失败时取消其余任务、不再分发新任务，并且不留下阻塞的goroutine
+
+练习任务：
+- 任务1：实现FanOut，启动n个工作者，每个工作者有自己的结果channel
+- 任务2：实现Merge，合并任意多个结果channel
+- 任务3：实现ProcessAll，按任务顺序返回结果，任何一个任务失败时快速失败
+
+对应Demo：cmd/medium/06_fan_in_fan_out
+
+运行方式：go run exercises/medium/06_fan_in_fan_out_exercise.go
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Task 任务
+type Task struct {
+	ID   int
+	Data int
+}
+
+// Result 处理结果
+type Result struct {
+	TaskID int
+	Value  int
+	Worker int // 处理它的工作者编号，从0开始
+}
+
+// generate 把任务依次发送到返回的channel，发送完后关闭（已实现）
+func generate(tasks []Task) <-chan Task {
+	out := make(chan Task)
+	go func() {
+		defer close(out)
+		for _, t := range tasks {
+			out <- t
+		}
+	}()
+	return out
+}
+
+// TODO: 实现FanOut
+// FanOut 启动n个工作者，都从in接收任务，用process处理后发送到自己的结果channel；
+// in关闭并处理完后每个工作者关闭自己的结果channel
+func FanOut(in <-chan Task, n int, process func(worker int, t Task) Result) []<-chan Result {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 为每个工作者创建一个channel，放进返回的切片
+	// 2. 每个工作者一个goroutine：for t := range in，处理后发送到自己的channel
+	// 3. 工作者退出前close自己的channel（用defer），下游才能用range读到结束
+
+	return nil
+}
+
+// TODO: 实现Merge
+// Merge 把inputs中的结果都转发到返回的channel，所有inputs都关闭后关闭返回的channel
+func Merge(inputs ...<-chan Result) <-chan Result {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 每个输入一个goroutine，range读取并转发到输出channel
+	// 2. 用WaitGroup等所有转发goroutine结束后再close输出，不能由某个转发goroutine关闭
+	// 3. 等待和关闭也要放在单独的goroutine里，Merge本身要立即返回
+
+	return nil
+}
+
+// TODO: 实现ProcessAll
+// ProcessAll 用workers个工作者并发处理tasks，按tasks的顺序返回结果。
+// 任何一个任务返回错误时：取消传给其余任务的ctx，不再开始新的任务，返回第一个错误。
+// ctx被取消时返回ctx.Err()。返回时所有启动的goroutine都已经退出
+func ProcessAll(ctx context.Context, tasks []Task, workers int, process func(ctx context.Context, t Task) (Result, error)) ([]Result, error) {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 用context.WithCancel派生一个ctx，第一个错误时cancel，让其余任务和分发都停下来
+	// 2. 分发任务的下标而不是任务本身，结果写到results[i]，就能按顺序返回且不需要加锁
+	// 3. 分发的goroutine发送时用select同时等待ctx.Done()，否则工作者退出后它会一直阻塞
+	// 4. 用sync.Once或加锁记录第一个错误；等所有工作者退出后再返回
+
+	return nil, nil
+}
+
+func main() {
+	fmt.Println("=== 扇入扇出练习 ===")
+
+	tasks := make([]Task, 10)
+	for i := range tasks {
+		tasks[i] = Task{ID: i + 1, Data: i + 1}
+	}
+
+	// 任务1、2：扇出再扇入
+	fmt.Println("\n任务1、2：扇出再扇入")
+	// TODO:
+	// 1. 用FanOut启动3个工作者处理generate(tasks)，计算Data的平方，每个任务模拟耗时20ms
+	// 2. 用Merge合并结果，range打印每个结果和处理它的工作者
+	// 3. 观察结果的顺序与任务顺序不同，每个工作者都处理了一部分任务
+
+	fmt.Println("任务1、2完成\n")
+
+	// 任务3：快速失败
+	fmt.Println("任务3：快速失败")
+	// TODO:
+	// 1. 用ProcessAll和3个工作者处理tasks，全部成功时打印按顺序返回的结果
+	// 2. 再处理一次，让Data为3的任务返回错误，其余任务等待100ms或ctx取消
+	// 3. 打印返回的错误、耗时和实际开始的任务数，观察没有等全部任务完成就返回了
+
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么FanOut给每个工作者一个结果channel，而不是共用一个？共用时由谁关闭？")
+	fmt.Println("2. Merge中为什么不能让转发goroutine在自己的输入结束时关闭输出channel？")
+	fmt.Println("3. ProcessAll失败后，为什么还要等所有工作者退出才返回？")
+	fmt.Println("4. 扇出的工作者数量应该怎样选择？CPU密集和IO密集的任务有什么不同？")
+}
@@ -0,0 +1,294 @@
+// 评分：go run ./exercises/grader medium/06
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+// tasksN ID和Data都是1..n的任务
+func tasksN(n int) []Task {
+	tasks := make([]Task, n)
+	for i := range tasks {
+		tasks[i] = Task{ID: i + 1, Data: i + 1}
+	}
+	return tasks
+}
+
+// peakCounter 记录同时运行的最大数量
+type peakCounter struct {
+	cur, peak atomic.Int32
+}
+
+func (p *peakCounter) enter() {
+	n := p.cur.Add(1)
+	for old := p.peak.Load(); n > old && !p.peak.CompareAndSwap(old, n); old = p.peak.Load() {
+	}
+}
+
+func (p *peakCounter) leave() { p.cur.Add(-1) }
+
+// drain 读到channel关闭为止，d内没有读完返回已经读到的结果和false
+func drain(ch <-chan Result, d time.Duration) ([]Result, bool) {
+	var got []Result
+	deadline := time.After(d)
+	for {
+		select {
+		case r, ok := <-ch:
+			if !ok {
+				return got, true
+			}
+			got = append(got, r)
+		case <-deadline:
+			return got, false
+		}
+	}
+}
+
+var errBadData = errors.New("数据无效")
+
+func TestFanInFanOut(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "FanOut的n个工作者并行处理，任务处理完后关闭各自的channel",
+			Run: func(t *grade.T) {
+				const n = 4
+				// 前n个任务互相等待：只有n个工作者同时在处理时才能全部通过
+				var arrived sync.WaitGroup
+				arrived.Add(n)
+				var first atomic.Int32
+				process := func(worker int, task Task) Result {
+					if first.Add(1) <= n {
+						arrived.Done()
+						if !grade.Within(time.Second, arrived.Wait) {
+							t.Errorf("1秒内没有%d个任务同时在处理：工作者没有并行运行", n)
+						}
+					}
+					return Result{TaskID: task.ID, Value: task.Data * task.Data, Worker: worker}
+				}
+				outs := FanOut(generate(tasksN(20)), n, process)
+				if len(outs) != n {
+					t.Fatalf("FanOut返回了 %d 个channel，期望 %d 个", len(outs), n)
+				}
+				seen := make(map[int]bool)
+				for w, out := range outs {
+					got, ok := drain(out, 3*time.Second)
+					if !ok {
+						t.Fatalf("任务都发完后第%d个工作者的channel没有关闭", w)
+					}
+					for _, r := range got {
+						if r.Value != r.TaskID*r.TaskID {
+							t.Errorf("任务%d的结果为 %d，期望 %d", r.TaskID, r.Value, r.TaskID*r.TaskID)
+						}
+						if r.Worker != w {
+							t.Errorf("第%d个channel收到了工作者%d的结果：每个工作者只发送到自己的channel", w, r.Worker)
+						}
+						seen[r.TaskID] = true
+					}
+				}
+				if len(seen) != 20 {
+					t.Errorf("收到了 %d 个不同任务的结果，期望20个", len(seen))
+				}
+			},
+		},
+		grade.Task{
+			Name: "Merge收齐所有输入，全部关闭后才关闭输出",
+			Run: func(t *grade.T) {
+				a, b, c := make(chan Result), make(chan Result), make(chan Result)
+				out := Merge(a, b, c)
+				if out == nil {
+					t.Fatalf("Merge返回了nil")
+				}
+				go func() {
+					for i := 1; i <= 5; i++ {
+						a <- Result{TaskID: i}
+					}
+					close(a)
+				}()
+				go func() {
+					b <- Result{TaskID: 6}
+					close(b)
+				}()
+				var got []int
+				for len(got) < 6 {
+					select {
+					case r, ok := <-out:
+						if !ok {
+							t.Fatalf("输入c还没有关闭Merge的输出就关闭了，只收到 %v", got)
+						}
+						got = append(got, r.TaskID)
+					case <-time.After(time.Second):
+						t.Fatalf("Merge没有转发全部结果，只收到 %v", got)
+					}
+				}
+				select {
+				case r, ok := <-out:
+					t.Fatalf("输入c还没有关闭，Merge的输出却读到了 %v（ok=%v）", r, ok)
+				case <-time.After(20 * time.Millisecond):
+				}
+				go func() {
+					c <- Result{TaskID: 7}
+					close(c)
+				}()
+				rest, ok := drain(out, time.Second)
+				if !ok {
+					t.Fatalf("所有输入都关闭后Merge的输出没有关闭")
+				}
+				for _, r := range rest {
+					got = append(got, r.TaskID)
+				}
+				sort.Ints(got)
+				if fmt.Sprint(got) != "[1 2 3 4 5 6 7]" {
+					t.Errorf("Merge转发的结果为 %v，期望 [1 2 3 4 5 6 7]", got)
+				}
+
+				if _, ok := drain(Merge(), time.Second); !ok {
+					t.Errorf("没有输入时Merge的输出应该立即关闭")
+				}
+			},
+		},
+		grade.Task{
+			Name: "FanOut与Merge组合处理全部任务",
+			Run: func(t *grade.T) {
+				process := func(worker int, task Task) Result {
+					return Result{TaskID: task.ID, Value: task.Data * 2, Worker: worker}
+				}
+				got, ok := drain(Merge(FanOut(generate(tasksN(100)), 5, process)...), 2*time.Second)
+				if !ok {
+					t.Fatalf("100个任务处理完后合并的channel没有关闭")
+				}
+				if len(got) != 100 {
+					t.Fatalf("收到 %d 个结果，期望100个", len(got))
+				}
+				sum := 0
+				for _, r := range got {
+					sum += r.Value
+				}
+				if sum != 100*101 {
+					t.Errorf("结果之和为 %d，期望 %d", sum, 100*101)
+				}
+			},
+		},
+		grade.Task{
+			Name: "ProcessAll按任务顺序返回结果，并发数不超过workers",
+			Run: func(t *grade.T) {
+				const workers = 3
+				var pc peakCounter
+				process := func(ctx context.Context, task Task) (Result, error) {
+					pc.enter()
+					defer pc.leave()
+					time.Sleep(time.Duration(task.ID%4) * time.Millisecond) // 后开始的任务可能先完成
+					return Result{TaskID: task.ID, Value: task.Data * task.Data}, nil
+				}
+				var got []Result
+				var err error
+				if !grade.Within(2*time.Second, func() {
+					got, err = ProcessAll(context.Background(), tasksN(30), workers, process)
+				}) {
+					t.Fatalf("ProcessAll没有在2秒内返回")
+				}
+				if err != nil {
+					t.Fatalf("全部任务成功时ProcessAll返回 %v", err)
+				}
+				if len(got) != 30 {
+					t.Fatalf("返回了 %d 个结果，期望30个", len(got))
+				}
+				for i, r := range got {
+					if r.TaskID != i+1 || r.Value != (i+1)*(i+1) {
+						t.Fatalf("第%d个结果为 %+v，期望任务%d的结果：结果要按任务顺序排列", i, r, i+1)
+					}
+				}
+				if p := pc.peak.Load(); p > workers {
+					t.Errorf("同时处理了 %d 个任务，workers=%d", p, workers)
+				} else if p < 2 {
+					t.Errorf("同时处理的任务最多只有 %d 个，任务没有并发处理", p)
+				}
+			},
+		},
+		grade.Task{
+			Name: "一个任务失败时ProcessAll取消其余任务并快速返回",
+			Run: func(t *grade.T) {
+				var started atomic.Int32
+				process := func(ctx context.Context, task Task) (Result, error) {
+					started.Add(1)
+					if task.Data == 2 {
+						time.Sleep(10 * time.Millisecond) // 等其他工作者也开始处理
+						return Result{}, errBadData
+					}
+					select {
+					case <-ctx.Done():
+						return Result{}, ctx.Err()
+					case <-time.After(time.Hour):
+						return Result{TaskID: task.ID}, nil
+					}
+				}
+				var got []Result
+				var err error
+				if !grade.Within(2*time.Second, func() {
+					got, err = ProcessAll(context.Background(), tasksN(100), 4, process)
+				}) {
+					t.Fatalf("任务2失败后ProcessAll没有返回：要取消传给其余任务的ctx")
+				}
+				if !errors.Is(err, errBadData) {
+					t.Errorf("ProcessAll返回 %v，期望任务2的错误，而不是其他任务被取消的错误", err)
+				}
+				if got != nil {
+					t.Errorf("失败时ProcessAll返回了 %d 个结果，期望nil", len(got))
+				}
+				if n := started.Load(); n > 10 {
+					t.Errorf("失败后仍然开始了新任务，一共开始了 %d/100 个", n)
+				}
+				// 返回时所有工作者和分发goroutine都要退出，任务结束后的泄漏检查会发现遗留的goroutine
+			},
+		},
+		grade.Task{
+			Name: "调用方取消ctx时ProcessAll返回ctx.Err()",
+			Run: func(t *grade.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				var started atomic.Int32
+				process := func(ctx context.Context, task Task) (Result, error) {
+					if started.Add(1) == 3 {
+						cancel()
+					}
+					<-ctx.Done()
+					return Result{}, ctx.Err()
+				}
+				var err error
+				if !grade.Within(2*time.Second, func() {
+					_, err = ProcessAll(ctx, tasksN(50), 3, process)
+				}) {
+					t.Fatalf("ctx取消后ProcessAll没有返回")
+				}
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("ctx取消后ProcessAll返回 %v，期望context.Canceled", err)
+				}
+				if n := started.Load(); n > 6 {
+					t.Errorf("ctx取消后仍然开始了新任务，一共开始了 %d/50 个", n)
+				}
+
+				ctx, cancel = context.WithCancel(context.Background())
+				cancel()
+				var ran atomic.Bool
+				got, err := ProcessAll(ctx, tasksN(5), 2, func(ctx context.Context, task Task) (Result, error) {
+					ran.Store(true)
+					return Result{}, nil
+				})
+				if !errors.Is(err, context.Canceled) || got != nil {
+					t.Errorf("ctx已经取消时ProcessAll返回 (%v, %v)，期望 (nil, context.Canceled)", got, err)
+				}
+				if ran.Load() {
+					t.Errorf("ctx已经取消时ProcessAll仍然处理了任务")
+				}
+			},
+		},
+	)
+}
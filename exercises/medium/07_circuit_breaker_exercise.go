@@ -0,0 +1,137 @@
+/*
+Golang并发编程练习 - 中等级别
+练习文件：07_circuit_breaker_exercise.go
+练习主题：熔断器状态机
+
+练习目标：
+1. 实现熔断器的三种状态：CLOSED、OPEN、HALF_OPEN
+2. 理解状态转换的触发条件
+3. 在持有锁时只做状态判断，调用被保护的函数时不持有锁
+4. 限制半开状态下同时试探的请求数
+
+练习任务：
+- 任务1：CLOSED状态连续失败达到阈值后转为OPEN
+- 任务2：OPEN状态快速失败，不调用被保护的函数
+- 任务3：OPEN持续OpenTimeout后转为HALF_OPEN，试探成功回到CLOSED，失败重新OPEN
+- 任务4：HALF_OPEN时最多HalfOpenMaxCalls个请求同时试探，其余快速失败
+
+对应Demo：cmd/medium/07_circuit_breaker（按失败率熔断，这里按连续失败次数熔断）
+
+运行方式：go run exercises/medium/07_circuit_breaker_exercise.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 正常通过请求
+	StateOpen                  // 拒绝请求，快速失败
+	StateHalfOpen              // 允许少量请求试探下游是否恢复
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "CLOSED"
+	case StateOpen:
+		return "OPEN"
+	case StateHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ErrOpen 熔断器拒绝了请求，被保护的函数没有被调用
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Config 熔断器配置
+type Config struct {
+	FailureThreshold int           // CLOSED状态下连续失败多少次后熔断
+	OpenTimeout      time.Duration // OPEN持续多久后允许试探
+	HalfOpenMaxCalls int           // HALF_OPEN时允许同时进行的试探请求数
+}
+
+// CircuitBreaker 熔断器
+type CircuitBreaker struct {
+	config Config
+	now    func() time.Time // 当前时间，评分时替换成可以手动拨动的时钟
+
+	mu sync.Mutex
+	// TODO: 定义需要的字段
+	// 提示：当前状态、连续失败次数、进入OPEN的时间、正在进行的试探数
+}
+
+// NewCircuitBreaker 创建熔断器，初始状态为CLOSED
+func NewCircuitBreaker(config Config) *CircuitBreaker {
+	// TODO: 初始化您定义的字段
+	return &CircuitBreaker{config: config, now: time.Now}
+}
+
+// TODO: 实现Call
+// 熔断器允许时调用fn并返回它的错误，拒绝时不调用fn，直接返回ErrOpen
+func (cb *CircuitBreaker) Call(fn func() error) error {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 加锁判断是否允许请求：OPEN且已超过OpenTimeout时转为HALF_OPEN
+	// 2. HALF_OPEN时正在试探的请求数达到HalfOpenMaxCalls则拒绝
+	// 3. 解锁后调用fn：fn可能很慢，持有锁调用会让所有请求排队
+	// 4. 再加锁根据结果更新状态（注意：fn执行期间状态可能已经被其他请求改变）
+	return nil
+}
+
+// TODO: 实现State
+// 返回当前状态
+func (cb *CircuitBreaker) State() State {
+	// 在这里实现您的代码
+	return StateClosed
+}
+
+func main() {
+	fmt.Println("=== 熔断器状态机练习 ===")
+
+	// 任务1：连续失败触发熔断
+	fmt.Println("\n任务1：连续失败触发熔断")
+	// TODO:
+	// 1. 创建熔断器（FailureThreshold: 3, OpenTimeout: 500ms, HalfOpenMaxCalls: 1）
+	// 2. 调用一个总是失败的函数，每次打印返回的错误和State()
+	// 3. 观察第3次失败后状态变为OPEN，之后的调用返回ErrOpen
+
+	fmt.Println("任务1完成\n")
+
+	// 任务2：自动恢复
+	fmt.Println("任务2：自动恢复")
+	// TODO:
+	// 1. 等待超过OpenTimeout
+	// 2. 调用一个成功的函数，观察状态经过HALF_OPEN回到CLOSED
+	// 3. 再次触发熔断，等待后让试探失败，观察状态回到OPEN
+
+	fmt.Println("任务2完成\n")
+
+	// 任务3：半开状态的并发试探
+	fmt.Println("任务3：半开状态的并发试探")
+	// TODO:
+	// 1. 触发熔断并等待超过OpenTimeout
+	// 2. 同时发起10个调用，被保护的函数睡眠100ms后成功
+	// 3. 统计实际调用fn的次数和返回ErrOpen的次数
+
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 按连续失败次数和按失败率熔断各适合什么场景？")
+	fmt.Println("2. 为什么调用fn时不能持有锁？")
+	fmt.Println("3. HALF_OPEN时如果不限制试探数，下游刚恢复时会发生什么？")
+	fmt.Println("4. fn执行期间熔断器已经被其他请求打开，这次调用的结果应该怎样处理？")
+}
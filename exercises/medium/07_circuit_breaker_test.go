@@ -0,0 +1,277 @@
+// 评分：go run ./exercises/grader medium/07
+
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+var errDown = errors.New("下游不可用")
+
+func newBreaker(t *grade.T, config Config) (*CircuitBreaker, *grade.FakeClock) {
+	cb := NewCircuitBreaker(config)
+	if cb == nil {
+		t.Fatalf("NewCircuitBreaker返回nil")
+	}
+	clock := grade.NewFakeClock()
+	cb.now = clock.Now
+	return cb, clock
+}
+
+func fail() error    { return errDown }
+func succeed() error { return nil }
+
+// trip 连续失败直到熔断
+func trip(t *grade.T, cb *CircuitBreaker, threshold int) {
+	for i := 0; i < threshold; i++ {
+		cb.Call(fail)
+	}
+	if s := cb.State(); s != StateOpen {
+		t.Fatalf("连续失败 %d 次（等于FailureThreshold）后状态为 %s，期望OPEN", threshold, s)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "CLOSED状态连续失败达到阈值后熔断",
+			Run: func(t *grade.T) {
+				cb, _ := newBreaker(t, Config{FailureThreshold: 3, OpenTimeout: time.Second, HalfOpenMaxCalls: 1})
+				if s := cb.State(); s != StateClosed {
+					t.Fatalf("新建的熔断器状态为 %s，期望CLOSED", s)
+				}
+				for i, step := range []struct {
+					fn   func() error
+					want State
+				}{
+					{fail, StateClosed}, {fail, StateClosed},
+					{succeed, StateClosed}, // 成功清零连续失败次数
+					{fail, StateClosed}, {fail, StateClosed},
+					{fail, StateOpen},
+				} {
+					err := cb.Call(step.fn)
+					if want := step.fn(); !errors.Is(err, want) {
+						t.Fatalf("第%d次调用返回 %v，CLOSED状态下应原样返回fn的结果 %v", i+1, err, want)
+					}
+					if s := cb.State(); s != step.want {
+						t.Fatalf("调用序列 失败,失败,成功,失败,失败,失败 的第%d步后状态为 %s，期望 %s（成功应清零连续失败次数）", i+1, s, step.want)
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "OPEN时快速失败且不调用fn",
+			Run: func(t *grade.T) {
+				cb, clock := newBreaker(t, Config{FailureThreshold: 2, OpenTimeout: time.Second, HalfOpenMaxCalls: 1})
+				trip(t, cb, 2)
+				clock.Advance(time.Second - time.Millisecond)
+				called := 0
+				for i := 0; i < 5; i++ {
+					err := cb.Call(func() error { called++; return nil })
+					if !errors.Is(err, ErrOpen) {
+						t.Errorf("OPEN状态下Call返回 %v，期望ErrOpen", err)
+					}
+				}
+				if called > 0 {
+					t.Errorf("OPEN状态下fn被调用了 %d 次，应该直接拒绝", called)
+				}
+				if s := cb.State(); s != StateOpen {
+					t.Errorf("还没到OpenTimeout，状态为 %s，期望OPEN", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "超过OpenTimeout后试探成功回到CLOSED",
+			Run: func(t *grade.T) {
+				cb, clock := newBreaker(t, Config{FailureThreshold: 2, OpenTimeout: time.Second, HalfOpenMaxCalls: 1})
+				trip(t, cb, 2)
+				clock.Advance(time.Second)
+				var during State
+				called := false
+				err := cb.Call(func() error {
+					called = true
+					during = cb.State()
+					return nil
+				})
+				if !called {
+					t.Fatalf("超过OpenTimeout后Call返回 %v 而没有调用fn，应该放行一个试探请求", err)
+				}
+				if err != nil {
+					t.Errorf("试探成功时Call返回 %v，期望nil", err)
+				}
+				if during != StateHalfOpen {
+					t.Errorf("试探请求执行期间状态为 %s，期望HALF_OPEN", during)
+				}
+				if s := cb.State(); s != StateClosed {
+					t.Fatalf("试探成功后状态为 %s，期望CLOSED", s)
+				}
+				cb.Call(fail)
+				if s := cb.State(); s != StateClosed {
+					t.Errorf("恢复后第一次失败就变成了 %s：恢复时应清零连续失败次数", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "试探失败重新熔断并重新计时",
+			Run: func(t *grade.T) {
+				cb, clock := newBreaker(t, Config{FailureThreshold: 2, OpenTimeout: time.Second, HalfOpenMaxCalls: 1})
+				trip(t, cb, 2)
+				clock.Advance(2 * time.Second)
+				if err := cb.Call(fail); !errors.Is(err, errDown) {
+					t.Fatalf("试探请求返回 %v，期望fn的错误（超过OpenTimeout后应放行试探）", err)
+				}
+				if s := cb.State(); s != StateOpen {
+					t.Fatalf("试探失败后状态为 %s，期望OPEN", s)
+				}
+				clock.Advance(time.Second - time.Millisecond)
+				if err := cb.Call(succeed); !errors.Is(err, ErrOpen) {
+					t.Errorf("试探失败后不到OpenTimeout又放行了请求（返回 %v）：重新熔断时要重新计时", err)
+				}
+				clock.Advance(time.Millisecond)
+				if err := cb.Call(succeed); err != nil {
+					t.Errorf("重新熔断OpenTimeout之后的试探返回 %v，期望nil", err)
+				}
+			},
+		},
+		grade.Task{
+			Name: "HALF_OPEN时限制同时试探的请求数",
+			Run: func(t *grade.T) {
+				cb, clock := newBreaker(t, Config{FailureThreshold: 1, OpenTimeout: time.Second, HalfOpenMaxCalls: 2})
+				trip(t, cb, 1)
+				clock.Advance(time.Second)
+
+				entered := make(chan struct{}, 2)
+				release := make(chan struct{})
+				var wg sync.WaitGroup
+				errs := make([]error, 2)
+				for i := 0; i < 2; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						errs[i] = cb.Call(func() error {
+							entered <- struct{}{}
+							<-release
+							return nil
+						})
+					}(i)
+				}
+				var once sync.Once
+				finish := func() {
+					once.Do(func() { close(release) })
+					wg.Wait()
+				}
+				defer finish() // 提前失败时也放行试探，不留下阻塞的goroutine
+				for i := 0; i < 2; i++ {
+					select {
+					case <-entered:
+					case <-time.After(time.Second):
+						t.Fatalf("HalfOpenMaxCalls=2，只有 %d 个试探请求进入了fn", i)
+					}
+				}
+
+				var err error
+				called := false
+				if !grade.Within(500*time.Millisecond, func() {
+					err = cb.Call(func() error { called = true; return nil })
+				}) {
+					t.Fatalf("试探名额用完时第3个请求被阻塞，应该立即返回ErrOpen")
+				}
+				if called || !errors.Is(err, ErrOpen) {
+					t.Errorf("2个试探正在进行时第3个请求返回 %v（fn被调用: %v），期望ErrOpen且不调用fn", err, called)
+				}
+				finish()
+				for i, e := range errs {
+					if e != nil {
+						t.Errorf("第%d个试探返回 %v，期望nil", i+1, e)
+					}
+				}
+				if s := cb.State(); s != StateClosed {
+					t.Errorf("试探全部成功后状态为 %s，期望CLOSED", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "调用fn期间不持有锁",
+			Run: func(t *grade.T) {
+				cb, _ := newBreaker(t, Config{FailureThreshold: 3, OpenTimeout: time.Second, HalfOpenMaxCalls: 1})
+				entered := make(chan struct{})
+				release := make(chan struct{})
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					cb.Call(func() error {
+						close(entered)
+						<-release
+						return nil
+					})
+				}()
+				defer func() {
+					close(release)
+					<-done
+				}()
+				select {
+				case <-entered:
+				case <-time.After(time.Second):
+					t.Fatalf("Call没有调用fn")
+				}
+				if !grade.Within(500*time.Millisecond, func() { cb.State() }) {
+					t.Fatalf("一个fn正在执行时State()被阻塞：调用fn时不能持有锁")
+				}
+				if !grade.Within(500*time.Millisecond, func() { cb.Call(succeed) }) {
+					t.Fatalf("一个fn正在执行时另一个Call被阻塞：调用fn时不能持有锁")
+				}
+			},
+		},
+		grade.Task{
+			Name: "并发调用时计数准确且没有数据竞争",
+			Run: func(t *grade.T) {
+				cb := NewCircuitBreaker(Config{FailureThreshold: 5, OpenTimeout: time.Millisecond, HalfOpenMaxCalls: 3})
+				if cb == nil {
+					t.Fatalf("NewCircuitBreaker返回nil")
+				}
+				const goroutines, calls = 20, 50
+				var called, rejected, other atomic.Int32
+				var wg sync.WaitGroup
+				for g := 0; g < goroutines; g++ {
+					wg.Add(1)
+					go func(seed int64) {
+						defer wg.Done()
+						rnd := rand.New(rand.NewSource(seed))
+						for i := 0; i < calls; i++ {
+							failing := rnd.Intn(3) == 0
+							err := cb.Call(func() error {
+								called.Add(1)
+								if failing {
+									return errDown
+								}
+								return nil
+							})
+							switch {
+							case errors.Is(err, ErrOpen):
+								rejected.Add(1)
+							case err != nil && !errors.Is(err, errDown):
+								other.Add(1)
+							}
+							cb.State()
+						}
+					}(int64(g))
+				}
+				wg.Wait()
+				if n := other.Load(); n > 0 {
+					t.Errorf("%d 次调用返回了既不是fn的错误也不是ErrOpen的错误", n)
+				}
+				if got := called.Load() + rejected.Load(); got != goroutines*calls {
+					t.Errorf("调用fn %d 次 + 拒绝 %d 次 = %d，期望等于总调用数 %d：每次Call要么调用fn，要么返回ErrOpen",
+						called.Load(), rejected.Load(), got, goroutines*calls)
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,182 @@
+/*
+Golang并发编程练习 - 中等级别
+练习文件：09_actor_model_exercise.go
+练习主题：Actor消息传递与状态隔离
+
+练习目标：
+1. 理解Actor模型：状态只属于一个goroutine，其他goroutine通过消息读写它
+2. 用邮箱channel串行处理消息，用回复channel返回结果
+3. 停止actor时处理完已经收到的消息，之后的请求立即返回错误而不是永远阻塞
+4. 在多个actor之间组合操作，失败时补偿
+
+练习任务：
+- 任务1：实现账户actor的run循环和Deposit、Withdraw、Balance
+- 任务2：实现Stop，处理完邮箱中已有的消息后退出，可以重复调用
+- 任务3：实现Transfer，转入失败时把钱退回
+
+对应Demo：cmd/medium/09_actor_model
+
+运行方式：go run exercises/medium/09_actor_model_exercise.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("余额不足")
+	ErrInvalidAmount     = errors.New("金额必须大于0")
+	ErrStopped           = errors.New("账户已停止")
+)
+
+// op 消息的种类
+type op int
+
+const (
+	opDeposit op = iota
+	opWithdraw
+	opBalance
+)
+
+// message 发给账户actor的消息
+type message struct {
+	op     op
+	amount int
+	reply  chan reply // 容量为1，actor回复时不会阻塞
+}
+
+// reply actor的回复
+type reply struct {
+	balance int
+	err     error
+}
+
+// Account 银行账户actor：余额只由run goroutine读写，其他goroutine通过邮箱发消息
+type Account struct {
+	mailbox chan message
+	stop    chan struct{} // Stop时关闭
+	done    chan struct{} // run退出后关闭
+	once    sync.Once
+	final   int // run退出时的余额，done关闭后才能读
+}
+
+// TODO: 实现NewAccount
+// NewAccount 创建账户并启动它的goroutine，mailboxSize是邮箱的缓冲大小
+func NewAccount(initial, mailboxSize int) *Account {
+	// 在这里实现您的代码
+	// 提示：创建三个channel，用go a.run(initial)启动actor；余额作为run的参数传进去，不放在结构体里
+
+	return &Account{}
+}
+
+// TODO: 实现run
+// run 逐条处理消息；Stop后处理完邮箱里已有的消息再退出
+func (a *Account) run(balance int) {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. defer close(a.done)
+	// 2. for循环中select：从邮箱收到消息时处理并回复；a.stop关闭时进入第3步
+	// 3. 用带default的select把邮箱里剩下的消息处理完，邮箱空了就把余额写到a.final并返回
+	// 4. 取款金额大于余额时回复ErrInsufficientFunds，余额不变
+}
+
+// TODO: 实现send
+// send 把消息放进邮箱并等待回复；账户已经停止时返回ErrStopped
+func (a *Account) send(o op, amount int) reply {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 创建容量为1的回复channel
+	// 2. 发送到邮箱时用select同时等待a.stop，账户停止后不能一直阻塞在满的邮箱上
+	// 3. 等待回复时用select同时等待a.done：消息放进邮箱后账户可能已经退出
+	// 4. a.done关闭时回复可能已经在缓冲里了（Stop处理了这条消息），先非阻塞地再取一次
+
+	return reply{}
+}
+
+// TODO: 实现Deposit
+// Deposit 存入amount
+func (a *Account) Deposit(amount int) error {
+	// 在这里实现您的代码
+	// 提示：amount不大于0时返回ErrInvalidAmount，否则用send发送opDeposit
+
+	return nil
+}
+
+// TODO: 实现Withdraw
+// Withdraw 取出amount，余额不足时返回ErrInsufficientFunds，余额不变
+func (a *Account) Withdraw(amount int) error {
+	// 在这里实现您的代码
+
+	return nil
+}
+
+// TODO: 实现Balance
+// Balance 当前余额
+func (a *Account) Balance() (int, error) {
+	// 在这里实现您的代码
+
+	return 0, nil
+}
+
+// TODO: 实现Stop
+// Stop 停止账户：处理完邮箱中已有的消息后退出，返回最终余额。可以重复调用，之后的请求返回ErrStopped
+func (a *Account) Stop() int {
+	// 在这里实现您的代码
+	// 提示：用a.once保证a.stop只关闭一次，然后等待a.done，再返回a.final
+
+	return 0
+}
+
+// TODO: 实现Transfer
+// Transfer 从from转amount到to；转入失败时把钱退回from
+func Transfer(from, to *Account, amount int) error {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 先从from取出，失败直接返回
+	// 2. 再存入to，失败时存回from，返回的错误要用%w包装存入失败的原因
+
+	return nil
+}
+
+func main() {
+	fmt.Println("=== Actor模型练习 ===")
+
+	// 任务1：并发存取
+	fmt.Println("\n任务1：并发存取")
+	// TODO:
+	// 1. 创建初始余额100的账户，100个goroutine各存入10，打印余额（1100）
+	// 2. 取出2000（余额不足）和600，打印返回的错误和余额
+
+	fmt.Println("任务1完成\n")
+
+	// 任务2：转账
+	fmt.Println("任务2：转账")
+	// TODO:
+	// 1. 创建alice和bob两个账户，各500
+	// 2. 并发进行50次alice转bob 7元、50次bob转alice 5元
+	// 3. 打印两人的余额和总额，总额应该还是1000
+
+	fmt.Println("任务2完成\n")
+
+	// 任务3：停止
+	fmt.Println("任务3：停止")
+	// TODO:
+	// 1. 停止bob，打印最终余额；再停止一次，观察返回相同的余额
+	// 2. 停止后向bob存款、从alice转账给bob，观察返回ErrStopped且alice的钱被退回
+	// 3. 停止其余账户
+
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 账户的余额为什么不需要加锁？")
+	fmt.Println("2. 回复channel为什么要有1个缓冲？")
+	fmt.Println("3. Transfer分成取出和存入两条消息，中间别人看到的总额是不对的，怎样避免？")
+	fmt.Println("4. 邮箱满了会怎样？和用Mutex保护余额相比各有什么优缺点？")
+}
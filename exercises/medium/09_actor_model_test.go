@@ -0,0 +1,231 @@
+// 评分：go run ./exercises/grader medium/09
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+// balanceOf 读取余额，1秒内没有回复时任务失败
+func balanceOf(t *grade.T, a *Account, who string) int {
+	var b int
+	var err error
+	if !grade.Within(time.Second, func() { b, err = a.Balance() }) {
+		t.Fatalf("%s的Balance()没有在1秒内返回：actor没有处理消息或没有回复", who)
+	}
+	if err != nil {
+		t.Fatalf("%s的Balance()返回错误 %v", who, err)
+	}
+	return b
+}
+
+// stopWithin 停止账户，1秒内没有返回时任务失败
+func stopWithin(t *grade.T, a *Account, who string) int {
+	var final int
+	if !grade.Within(time.Second, func() { final = a.Stop() }) {
+		t.Fatalf("%s的Stop()没有在1秒内返回", who)
+	}
+	return final
+}
+
+func TestActorModel(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "并发存款不丢失，余额只由actor读写",
+			Run: func(t *grade.T) {
+				a := NewAccount(100, 8)
+				defer stopWithin(t, a, "账户")
+				var wg sync.WaitGroup
+				var failed atomic.Int32
+				for i := 0; i < 50; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for j := 0; j < 20; j++ {
+							if a.Deposit(1) != nil {
+								failed.Add(1)
+							}
+						}
+					}()
+				}
+				if !grade.Within(2*time.Second, wg.Wait) {
+					t.Fatalf("50个goroutine的存款没有在2秒内完成")
+				}
+				if n := failed.Load(); n > 0 {
+					t.Errorf("有 %d 次存款返回了错误", n)
+				}
+				if b := balanceOf(t, a, "账户"); b != 1100 {
+					t.Errorf("初始100，并发存入1000次1元后余额为 %d，期望1100", b)
+				}
+				if err := a.Deposit(0); !errors.Is(err, ErrInvalidAmount) {
+					t.Errorf("Deposit(0)返回 %v，期望ErrInvalidAmount", err)
+				}
+				if err := a.Withdraw(-5); !errors.Is(err, ErrInvalidAmount) {
+					t.Errorf("Withdraw(-5)返回 %v，期望ErrInvalidAmount", err)
+				}
+			},
+		},
+		grade.Task{
+			Name: "并发取款不会透支",
+			Run: func(t *grade.T) {
+				a := NewAccount(100, 4)
+				defer stopWithin(t, a, "账户")
+				var wg sync.WaitGroup
+				var ok, insufficient atomic.Int32
+				for i := 0; i < 50; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						switch err := a.Withdraw(3); {
+						case err == nil:
+							ok.Add(1)
+						case errors.Is(err, ErrInsufficientFunds):
+							insufficient.Add(1)
+						default:
+							t.Errorf("Withdraw返回了意外的错误 %v", err)
+						}
+					}()
+				}
+				if !grade.Within(2*time.Second, wg.Wait) {
+					t.Fatalf("50个goroutine的取款没有在2秒内完成")
+				}
+				if ok.Load() != 33 || insufficient.Load() != 17 {
+					t.Errorf("余额100，50次并发取出3元：成功 %d 次、余额不足 %d 次，期望33次和17次", ok.Load(), insufficient.Load())
+				}
+				if b := balanceOf(t, a, "账户"); b != 1 {
+					t.Errorf("取款后余额为 %d，期望1", b)
+				}
+			},
+		},
+		grade.Task{
+			Name: "Stop处理完已收到的消息后退出，之后的请求返回ErrStopped",
+			Run: func(t *grade.T) {
+				a := NewAccount(0, 16)
+				var wg sync.WaitGroup
+				var deposited atomic.Int32
+				for i := 0; i < 20; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for {
+							err := a.Deposit(1)
+							if err != nil {
+								if !errors.Is(err, ErrStopped) {
+									t.Errorf("停止时Deposit返回 %v，期望ErrStopped", err)
+								}
+								return
+							}
+							deposited.Add(1)
+						}
+					}()
+				}
+				time.Sleep(10 * time.Millisecond)
+				final := stopWithin(t, a, "账户")
+				if !grade.Within(time.Second, wg.Wait) {
+					t.Fatalf("Stop之后有Deposit一直没有返回：发送和等待回复都要同时等待停止")
+				}
+				if n := int(deposited.Load()); final != n {
+					t.Errorf("Stop返回的余额为 %d，但成功的存款一共 %d 次：返回nil的存款都要计入，没有计入的要返回ErrStopped", final, n)
+				}
+				if again := stopWithin(t, a, "账户"); again != final {
+					t.Errorf("第二次Stop返回 %d，第一次返回 %d", again, final)
+				}
+				if _, err := a.Balance(); !errors.Is(err, ErrStopped) {
+					t.Errorf("停止后Balance返回 %v，期望ErrStopped", err)
+				}
+				if err := a.Withdraw(1); !errors.Is(err, ErrStopped) {
+					t.Errorf("停止后Withdraw返回 %v，期望ErrStopped", err)
+				}
+				// actor的goroutine要在Stop后退出，任务结束后的泄漏检查会发现它
+			},
+		},
+		grade.Task{
+			Name: "多个goroutine同时调用Stop都能返回相同的余额",
+			Run: func(t *grade.T) {
+				a := NewAccount(42, 1)
+				results := make(chan int, 10)
+				for i := 0; i < 10; i++ {
+					go func() { results <- a.Stop() }()
+				}
+				for i := 0; i < 10; i++ {
+					select {
+					case got := <-results:
+						if got != 42 {
+							t.Errorf("Stop返回 %d，期望42", got)
+						}
+					case <-time.After(time.Second):
+						t.Fatalf("同时调用Stop时有的调用没有返回（或者重复关闭channel导致panic）")
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "双向并发转账后总额不变",
+			Run: func(t *grade.T) {
+				alice, bob := NewAccount(1000, 4), NewAccount(1000, 4)
+				defer stopWithin(t, alice, "alice")
+				defer stopWithin(t, bob, "bob")
+				var wg sync.WaitGroup
+				for i := 0; i < 20; i++ {
+					wg.Add(2)
+					go func() {
+						defer wg.Done()
+						for j := 0; j < 20; j++ {
+							Transfer(alice, bob, 7)
+						}
+					}()
+					go func() {
+						defer wg.Done()
+						for j := 0; j < 20; j++ {
+							Transfer(bob, alice, 11)
+						}
+					}()
+				}
+				if !grade.Within(3*time.Second, wg.Wait) {
+					t.Fatalf("双向转账没有在3秒内完成：两个账户互相等待了吗？")
+				}
+				a, b := balanceOf(t, alice, "alice"), balanceOf(t, bob, "bob")
+				if a+b != 2000 {
+					t.Errorf("转账后alice=%d bob=%d，总额 %d，期望2000", a, b, a+b)
+				}
+				if a < 0 || b < 0 {
+					t.Errorf("转账后alice=%d bob=%d，出现了透支", a, b)
+				}
+				if a == 1000 {
+					t.Errorf("转账后余额没有变化，Transfer没有转账")
+				}
+
+				if err := Transfer(alice, bob, 1_000_000); !errors.Is(err, ErrInsufficientFunds) {
+					t.Errorf("余额不足时Transfer返回 %v，期望ErrInsufficientFunds", err)
+				}
+			},
+		},
+		grade.Task{
+			Name: "转入已停止的账户时把钱退回",
+			Run: func(t *grade.T) {
+				alice, bob := NewAccount(500, 4), NewAccount(0, 4)
+				defer stopWithin(t, alice, "alice")
+				stopWithin(t, bob, "bob")
+				var err error
+				if !grade.Within(time.Second, func() { err = Transfer(alice, bob, 200) }) {
+					t.Fatalf("转账给已停止的账户时Transfer没有返回")
+				}
+				if !errors.Is(err, ErrStopped) {
+					t.Errorf("转账给已停止的账户返回 %v，期望用%%w包装ErrStopped", err)
+				}
+				if b := balanceOf(t, alice, "alice"); b != 500 {
+					t.Errorf("转入失败后alice的余额为 %d，期望退回到500", b)
+				}
+				if err := Transfer(bob, alice, 1); !errors.Is(err, ErrStopped) {
+					t.Errorf("从已停止的账户转出返回 %v，期望ErrStopped", err)
+				}
+			},
+		},
+	)
+}
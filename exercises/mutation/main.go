@@ -0,0 +1,269 @@
+/*
+Golang并发编程练习 - 变异测试
+文件：exercises/mutation/main.go
+
+练习通过评分之后，对练习代码做一处小的并发相关修改（删掉加锁、提前解锁、删掉wg.Done()、
+删掉close、把channel缓冲改成0……），再用同一套评分任务评分，看评分能不能发现这处错误。
+被发现（杀死）的变异越多，说明评分越严格；存活的变异说明"这样写错了评分也会通过"，
+输出会说明要怎样的检查才能发现它。
+
+并发代码的错误往往只在特定的调度下出现，返回值看起来也对。这个命令演示为什么并发测试
+必须在竞态检测器下制造真正的并发、设置超时、检查goroutine泄漏和全部结果。
+
+运行方式：
+  go run ./exercises/mutation simple/07                 # 对自己通过评分的练习做变异测试
+  go run ./exercises/mutation -solutions simple         # 对参考答案做变异测试，检验评分文件本身
+  go run ./exercises/mutation -kind remove-done medium  # 只做一种变异
+  go run ./exercises/mutation -v simple/07              # 列出每个变异改动的那一行
+
+只变异评分会调用到的函数，只在main里用到的代码不做变异。
+每个变异都要在竞态检测器下编译运行一次，练习较多时比较慢，-p 设置同时运行的变异数。
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/mutate"
+)
+
+// outcome 一个变异的评分结果
+type outcome int
+
+const (
+	killed   outcome = iota // 评分失败，变异被发现
+	survived                // 评分仍然全部通过
+	invalid                 // 变异后编译失败，不计入结果
+)
+
+// result 一个变异和它的评分结果
+type result struct {
+	mutant  mutate.Mutant
+	outcome outcome
+	reason  string // 被杀死的原因：哪个任务、为什么失败
+}
+
+// compileFailed 编译失败时go命令的输出以"# 包名"开头
+func compileFailed(rep *grade.Report) bool {
+	return len(rep.Results) == 0 && strings.Contains(rep.Output, "# command-line-arguments")
+}
+
+// killReason 第一个失败的任务和原因
+func killReason(rep *grade.Report) string {
+	for _, r := range rep.Results {
+		if r.Passed {
+			continue
+		}
+		why := ""
+		switch {
+		case r.Race:
+			why = "数据竞争"
+		case r.Leak:
+			why = "goroutine泄漏"
+		case len(r.Messages) > 0:
+			why = r.Messages[0]
+		}
+		if why == "" {
+			return "任务「" + r.Name + "」失败"
+		}
+		return "任务「" + r.Name + "」失败：" + why
+	}
+	first, _, _ := strings.Cut(rep.Err, "\n")
+	return first
+}
+
+// changedLine 变异改动的那一行，原来的和变异后的
+func changedLine(orig, mutated []byte, line int) (before, after string) {
+	get := func(src []byte) string {
+		lines := bytes.Split(src, []byte("\n"))
+		if line-1 < len(lines) {
+			return strings.TrimSpace(string(lines[line-1]))
+		}
+		return ""
+	}
+	return get(orig), get(mutated)
+}
+
+// run 评分全部变异，最多p个同时运行，结果顺序与mutants相同
+func run(ctx context.Context, ex grade.Exercise, mutants []mutate.Mutant, opts grade.Options, p int) []result {
+	results := make([]result, len(mutants))
+	sem := make(chan struct{}, p)
+	var wg sync.WaitGroup
+	for i, m := range mutants {
+		i, m := i, m
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			o := opts
+			o.Source = m.Source
+			rep := grade.Run(ctx, ex, o)
+			res := result{mutant: m}
+			switch {
+			case compileFailed(rep):
+				res.outcome = invalid
+			case rep.Passed():
+				res.outcome = survived
+			default:
+				res.outcome = killed
+				res.reason = killReason(rep)
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func main() {
+	solutions := flag.Bool("solutions", false, "对参考答案而不是练习文件做变异测试")
+	kind := flag.String("kind", "", "只做一种变异："+fmt.Sprint(mutate.Kinds))
+	verbose := flag.Bool("v", false, "列出每个变异改动的那一行")
+	timeout := flag.Duration("timeout", time.Minute, "每个变异（包括编译）的超时")
+	parallel := flag.Int("p", max(runtime.NumCPU()/2, 1), "同时评分的变异数")
+	flag.Parse()
+
+	if *kind != "" && !validKind(mutate.Kind(*kind)) {
+		fmt.Fprintf(os.Stderr, "没有 %s 这种变异，可选：%v\n", *kind, mutate.Kinds)
+		os.Exit(2)
+	}
+	dir, err := grade.ExercisesDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	all, err := grade.Discover(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var list []grade.Exercise
+	for _, ex := range all {
+		if grade.Selected(ex, flag.Args()) && ex.Test != "" && (!*solutions || ex.Solution != "") {
+			list = append(list, ex)
+		}
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(os.Stderr, "没有匹配 %v 的练习\n", flag.Args())
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	opts := grade.Options{Race: true, LeakCheck: true, Timeout: *timeout, Solution: *solutions}
+	var total, totalKilled, totalSurvived int
+	lessons := make(map[mutate.Kind]bool)
+	for _, ex := range list {
+		id := ex.Level + "/" + ex.Name
+		file := ex.File
+		if *solutions {
+			file = ex.Solution
+		}
+		fmt.Printf("\n=== %s（%s）===\n", id, file)
+
+		// 变异测试要从一份能通过评分的代码开始，否则分不清失败是变异造成的还是本来就有
+		if rep := grade.Run(ctx, ex, opts); !rep.Passed() {
+			fmt.Println("  评分没有全部通过，先完成练习：go run ./exercises/grader " + id)
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		gradeSrc, err := os.ReadFile(ex.Test)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		mutants, err := mutate.Generate(file, src, gradeSrc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *kind != "" {
+			var keep []mutate.Mutant
+			for _, m := range mutants {
+				if m.Kind == mutate.Kind(*kind) {
+					keep = append(keep, m)
+				}
+			}
+			mutants = keep
+		}
+		if len(mutants) == 0 {
+			fmt.Println("  评分调用到的代码中没有可以变异的地方（锁、Done、Wait、close、带缓冲的channel）")
+			continue
+		}
+
+		results := run(ctx, ex, mutants, opts, *parallel)
+		width := 0
+		for _, r := range results {
+			width = max(width, grade.DisplayWidth(string(r.mutant.Kind)))
+		}
+		var k, s, inv int
+		for _, r := range results {
+			m := r.mutant
+			where := fmt.Sprintf("第%d行 %s：%s", m.Line, m.Func, m.Desc)
+			switch r.outcome {
+			case killed:
+				k++
+				fmt.Printf("  ✓ 杀死  %s%s\n", grade.Pad(string(m.Kind), width+2), where)
+				fmt.Printf("          %s\n", r.reason)
+			case survived:
+				s++
+				lessons[m.Kind] = true
+				fmt.Printf("  ✗ 存活  %s%s\n", grade.Pad(string(m.Kind), width+2), where)
+			case invalid:
+				inv++
+				fmt.Printf("  - 无效  %s%s（变异后无法编译）\n", grade.Pad(string(m.Kind), width+2), where)
+			}
+			if *verbose && r.outcome != invalid {
+				before, after := changedLine(src, m.Source, m.Line)
+				fmt.Printf("          - %s\n          + %s\n", before, after)
+			}
+		}
+		fmt.Printf("  变异得分：%d/%d 被评分发现", k, k+s)
+		if inv > 0 {
+			fmt.Printf("（另有 %d 个无法编译，不计入）", inv)
+		}
+		fmt.Println()
+		total += k + s
+		totalKilled += k
+		totalSurvived += s
+	}
+
+	if total == 0 {
+		return
+	}
+	fmt.Printf("\n总计：%d/%d 个变异被评分发现\n", totalKilled, total)
+	if totalSurvived == 0 {
+		fmt.Println("所有变异都被发现了：这些评分任务足够严格")
+		return
+	}
+	fmt.Println("\n存活的变异说明这样写错了评分也会通过。要发现它们，评分任务需要：")
+	for _, k := range mutate.Kinds {
+		if lessons[k] {
+			fmt.Printf("  %s：%s\n", k, k.Lesson())
+		}
+	}
+	os.Exit(1)
+}
+
+func validKind(k mutate.Kind) bool {
+	for _, kind := range mutate.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+/*
+Golang并发编程练习 - 学习进度
+文件：exercises/progress/main.go
+
+读取评分器记录的进度文件（exercises/.progress.json），按simple/medium/hard
+汇总显示每个练习的完成状态、最近一次通过的任务数、评分次数和完成用时。
+还没有解锁的练习（前置练习没有全部通过，见pkg/curriculum）显示为🔒。
+
+运行方式：
+  go run ./exercises/progress                    # 显示全部练习的进度
+  go run ./exercises/progress medium             # 只显示一个级别
+  go run ./exercises/progress -path              # 按推荐顺序显示学习路线和每个练习的前置练习
+  go run ./exercises/progress -unlock medium/05  # 已经掌握前置内容时，跳过前置练习直接解锁
+  go run ./exercises/progress -reset simple/03   # 清除一个练习的记录，-reset all 清除全部
+  go run ./exercises/progress -file my.json      # 使用其他进度文件
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klsakura/day1/pkg/curriculum"
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/progress"
+)
+
+// status 完成状态：曾经全部通过为完成，评分过但没通过显示最近一次通过的任务数
+func status(r progress.Record, ok, unlocked bool) string {
+	switch {
+	case !unlocked && !r.Completed():
+		// 用-gate=false评分过的练习同样算没有解锁
+		return "🔒 未解锁"
+	case !ok:
+		return "◯ 未开始"
+	case r.Completed() && r.LastPassed:
+		return "✓ 完成"
+	case r.Completed():
+		// 通过后又改坏了
+		return "✓ 完成(最近未通过)"
+	case r.LastError != "":
+		return "✗ 编译失败"
+	default:
+		passed, total := r.TasksPassed()
+		return fmt.Sprintf("◐ %d/%d", passed, total)
+	}
+}
+
+// ago 最近评分时间，今天的只显示时刻
+func ago(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if y, m, d := t.Date(); now.Year() == y && now.Month() == m && now.Day() == d {
+		return t.Format("今天 15:04")
+	}
+	return t.Format("01-02 15:04")
+}
+
+// elapsed 完成用时：从第一次评分到第一次全部通过
+func elapsed(r progress.Record) string {
+	d, ok := r.TimeToComplete()
+	switch {
+	case !ok:
+		return "-"
+	case d < time.Minute:
+		return "一次通过"
+	case d < time.Hour:
+		return fmt.Sprintf("%d分钟", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d小时%d分钟", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%d天", int(d.Hours()/24))
+	}
+}
+
+func main() {
+	file := flag.String("file", "", "进度文件路径，默认为练习目录下的"+progress.FileName)
+	reset := flag.String("reset", "", "清除一个练习（级别/编号）的记录，all 清除全部")
+	unlock := flag.String("unlock", "", "不等前置练习通过，直接解锁一个练习（级别/编号）")
+	showPath := flag.Bool("path", false, "按推荐顺序显示学习路线")
+	flag.Parse()
+
+	dir, err := grade.ExercisesDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	path := *file
+	if path == "" {
+		path = filepath.Join(dir, progress.FileName)
+	}
+	store, err := progress.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	all, err := grade.Discover(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	cur := curriculum.Default()
+	if err := cur.Check(all); err != nil {
+		fmt.Fprintln(os.Stderr, "学习路线:", err)
+	}
+
+	if *unlock != "" {
+		id, ok := find(all, *unlock)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "没有匹配 %s 的练习\n", *unlock)
+			os.Exit(2)
+		}
+		if cur.Unlocked(store, id) {
+			fmt.Printf("%s 已经解锁\n", id)
+			return
+		}
+		store.Unlock(id, time.Now())
+		if err := store.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("🔓 已解锁 %s，跳过的前置练习：%s\n", id, strings.Join(cur.Missing(store, id), "、"))
+		return
+	}
+
+	if *showPath {
+		printPath(cur, store)
+		return
+	}
+
+	if *reset != "" {
+		n := 0
+		for _, r := range store.All() {
+			if *reset == "all" || strings.HasPrefix(r.Exercise, strings.TrimSuffix(*reset, "/")) {
+				store.Reset(r.Exercise)
+				n++
+			}
+		}
+		if *reset == "all" {
+			// 没有评分记录、用-unlock解锁的练习也一起清除
+			store.Reset("")
+		}
+		if err := store.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("清除了 %d 个练习的记录\n", n)
+		return
+	}
+
+	now := time.Now()
+	headers := []string{"练习", "状态", "评分次数", "最近评分", "完成用时"}
+	var totalDone, total int
+	fmt.Printf("=== 练习进度（%s）===\n", path)
+	for _, level := range []string{"simple", "medium", "hard"} {
+		if flag.NArg() > 0 && flag.Arg(0) != level {
+			continue
+		}
+		var rows [][]string
+		done, attempts := 0, 0
+		for _, ex := range all {
+			if ex.Level != level {
+				continue
+			}
+			id := ex.Level + "/" + ex.Name
+			r, ok := store.Get(id)
+			if r.Completed() {
+				done++
+			}
+			attempts += r.Attempts
+			rows = append(rows, []string{ex.Name, status(r, ok, cur.Unlocked(store, id)), fmt.Sprint(r.Attempts), ago(r.LastAttempt, now), elapsed(r)})
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		totalDone += done
+		total += len(rows)
+
+		widths := make([]int, len(headers))
+		for _, row := range append([][]string{headers}, rows...) {
+			for i, cell := range row {
+				widths[i] = max(widths[i], grade.DisplayWidth(cell))
+			}
+		}
+		fmt.Printf("\n%s：%d/%d 完成，共评分 %d 次\n", level, done, len(rows), attempts)
+		for _, row := range append([][]string{headers}, rows...) {
+			var b strings.Builder
+			for i, cell := range row {
+				b.WriteString(grade.Pad(cell, widths[i]+2))
+			}
+			fmt.Println("  " + strings.TrimRight(b.String(), " "))
+		}
+	}
+	if total == 0 {
+		fmt.Fprintf(os.Stderr, "没有匹配 %v 的练习\n", flag.Args())
+		os.Exit(2)
+	}
+
+	fmt.Printf("\n总计：%d/%d 完成 %s\n", totalDone, total, bar(totalDone, total, 20))
+	if n, ok := cur.Next(store); ok {
+		fmt.Printf("下一个练习：%s（%s），完成后评分：go run ./exercises/grader %s\n", n.Exercise, n.Skill, short(n.Exercise))
+	}
+}
+
+// find 按"级别/编号"或"级别/文件名"找到练习
+func find(all []grade.Exercise, arg string) (string, bool) {
+	arg = strings.TrimSuffix(arg, "/")
+	for _, ex := range all {
+		id := ex.Level + "/" + ex.Name
+		if id == arg || short(id) == arg {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// short 级别/编号，例如simple/03
+func short(id string) string {
+	level, name, _ := strings.Cut(id, "/")
+	num, _, _ := strings.Cut(name, "_")
+	return level + "/" + num
+}
+
+// printPath 按推荐顺序列出路线上的练习、状态和前置练习
+func printPath(cur *curriculum.Curriculum, store *progress.Store) {
+	fmt.Println("=== 学习路线（按推荐顺序）===")
+	for i, n := range cur.Path() {
+		r, ok := store.Get(n.Exercise)
+		fmt.Printf("\n%2d. %s  %s\n", i+1, grade.Pad(n.Exercise, 32), status(r, ok, cur.Unlocked(store, n.Exercise)))
+		fmt.Printf("    练习：%s\n", n.Skill)
+		if len(n.Requires) == 0 {
+			continue
+		}
+		reqs := make([]string, len(n.Requires))
+		for j, req := range n.Requires {
+			reqs[j] = "✗ " + short(req)
+			if r, _ := store.Get(req); r.Completed() {
+				reqs[j] = "✓ " + short(req)
+			}
+		}
+		line := "    前置：" + strings.Join(reqs, "  ")
+		if at, ok := store.UnlockedAt(n.Exercise); ok {
+			line += "  （" + at.Format("01-02 15:04") + " 解锁）"
+		}
+		fmt.Println(line)
+	}
+	if n, ok := cur.Next(store); ok {
+		fmt.Printf("\n下一个练习：%s（%s）\n", n.Exercise, n.Skill)
+	} else {
+		fmt.Println("\n路线上的练习已全部完成！")
+	}
+}
+
+// bar 文本进度条
+func bar(done, total, width int) string {
+	n := done * width / total
+	return "[" + strings.Repeat("#", n) + strings.Repeat("-", width-n) + "]"
+}
@@ -41,7 +41,7 @@ run_exercise() {
     echo -e "${GREEN}💡 提示：${NC}"
     echo -e "- 这是一个练习文件，包含TODO标记的代码需要您来实现"
     echo -e "- 请先打开文件查看练习要求和提示"
-    echo -e "- 实现代码后再运行测试"
+    echo -e "- 实现代码后再运行自动评分（菜单6）"
     echo ""
     echo "按 Enter 键查看练习文件内容，或按 Ctrl+C 跳过..."
     read
@@ -95,6 +95,8 @@ run_medium_exercises() {
     
     local exercises=(
         "medium/01_producer_consumer_exercise.go:生产者消费者模式练习"
+        "medium/05_context_cancellation_exercise.go:Context取消与errgroup练习"
+        "medium/07_circuit_breaker_exercise.go:熔断器状态机练习"
     )
     
     for exercise in "${exercises[@]}"; do
@@ -115,6 +117,9 @@ run_hard_exercises() {
     
     local exercises=(
         "hard/01_distributed_worker_exercise.go:分布式工作者系统练习"
+        "hard/02_load_balancer_exercise.go:负载均衡策略练习"
+        "hard/03_message_queue_exercise.go:带重试的消息队列练习"
+        "hard/04_connection_pool_exercise.go:带泄漏检测的连接池练习"
     )
     
     for exercise in "${exercises[@]}"; do
@@ -279,6 +284,54 @@ show_stats() {
             done
         fi
     done
+
+    echo ""
+    echo -e "${CYAN}=== 评分记录 ===${NC}"
+    echo "以自动评分的结果为准，评分过的练习会记录在 .progress.json"
+    go run ./progress
+}
+
+# 自动评分
+grade_exercises() {
+    echo -e "${CYAN}=== 自动评分 ===${NC}"
+    echo "输入要评分的练习，例如 simple、simple/03、hard/01；直接回车评分全部练习"
+    echo ""
+    read -p "评分范围: " target
+
+    echo -e "${GREEN}======== 开始评分（竞态检测器下运行，首次编译较慢） ========${NC}"
+    go run ./grader $target
+    echo -e "${GREEN}======== 评分结束 ========${NC}"
+}
+
+# 与参考答案对比
+compare_solution() {
+    echo -e "${CYAN}请选择要对比的练习：${NC}"
+    echo ""
+
+    local count=1
+    local files=()
+    for level in simple medium hard; do
+        for file in $level/*_exercise.go; do
+            local solution="solutions/${file%_exercise.go}_solution.go"
+            if [[ -f "$file" && -f "$solution" ]]; then
+                echo "$count) $file"
+                files[$count]="$file"
+                ((count++))
+            fi
+        done
+    done
+    echo ""
+
+    read -p "请输入文件编号 (1-$((count-1))): " choice
+    if [[ -z "${files[$choice]}" ]]; then
+        echo -e "${RED}无效选择${NC}"
+        return 1
+    fi
+    local file="${files[$choice]}"
+    local solution="solutions/${file%_exercise.go}_solution.go"
+    echo -e "${YELLOW}提示：建议先通过自动评分再看答案${NC}"
+    echo ""
+    diff -u "$file" "$solution" | ${PAGER:-less}
 }
 
 # 主菜单
@@ -291,12 +344,14 @@ show_menu() {
     echo "3) 运行 Hard 级别练习"
     echo "4) 打开特定练习文件编辑"
     echo "5) 查看练习统计信息"
-    echo "6) 创建新练习文件"
-    echo "7) 查看使用说明"
-    echo "8) 退出"
+    echo "6) 自动评分练习"
+    echo "7) 与参考答案对比"
+    echo "8) 创建新练习文件"
+    echo "9) 查看使用说明"
+    echo "10) 退出"
     echo ""
     
-    read -p "请输入选择 (1-8): " choice
+    read -p "请输入选择 (1-10): " choice
     
     case $choice in
         1) clear; run_simple_exercises ;;
@@ -304,9 +359,11 @@ show_menu() {
         3) clear; run_hard_exercises ;;
         4) clear; open_exercise ;;
         5) clear; show_stats ;;
-        6) clear; create_exercise ;;
-        7) clear; show_help ;;
-        8) echo -e "${GREEN}感谢使用练习系统！祝学习愉快！${NC}"; exit 0 ;;
+        6) clear; grade_exercises ;;
+        7) clear; compare_solution ;;
+        8) clear; create_exercise ;;
+        9) clear; show_help ;;
+        10) echo -e "${GREEN}感谢使用练习系统！祝学习愉快！${NC}"; exit 0 ;;
         *) echo -e "${RED}无效选择，请重新选择${NC}"; echo ""; show_menu ;;
     esac
 }
@@ -319,7 +376,7 @@ show_help() {
     echo "   • 选择对应级别的练习"
     echo "   • 查看练习文件中的TODO标记"
     echo "   • 根据提示实现代码"
-    echo "   • 运行测试验证结果"
+    echo "   • 运行自动评分验证结果（菜单6，或 go run ./grader）"
     echo ""
     echo -e "${YELLOW}2. 文件结构：${NC}"
     echo "   • 每个练习文件都有详细的任务说明"
@@ -335,6 +392,7 @@ show_help() {
     echo "   • 参考对应的demo文件"
     echo "   • 查看练习文件中的提示"
     echo "   • 阅读 exercises/README.md"
+    echo "   • 完成后与 solutions/ 下的参考答案对比（菜单7）"
     echo ""
 }
 
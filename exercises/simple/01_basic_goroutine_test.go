@@ -0,0 +1,91 @@
+// 评分：go run ./exercises/grader simple/01
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+var numberRe = regexp.MustCompile(`\d+`)
+
+// printedNumbers 输出中依次出现的所有数字
+func printedNumbers(out string) string {
+	return fmt.Sprint(numberRe.FindAllString(out, -1))
+}
+
+// shown 失败信息中展示的输出
+func shown(out string) string {
+	if strings.TrimSpace(out) == "" {
+		return "（没有输出）"
+	}
+	return "\n" + strings.TrimRight(out, "\n")
+}
+
+func TestBasicGoroutine(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "printOddNumbers依次打印1、3、5、7、9",
+			Run: func(t *grade.T) {
+				out := grade.CaptureStdout(printOddNumbers)
+				if got := printedNumbers(out); got != "[1 3 5 7 9]" {
+					t.Errorf("打印的数字是 %s，期望 [1 3 5 7 9]\n输出: %s", got, shown(out))
+				}
+			},
+		},
+		grade.Task{
+			Name: "printEvenNumbers依次打印2、4、6、8、10",
+			Run: func(t *grade.T) {
+				out := grade.CaptureStdout(printEvenNumbers)
+				if got := printedNumbers(out); got != "[2 4 6 8 10]" {
+					t.Errorf("打印的数字是 %s，期望 [2 4 6 8 10]\n输出: %s", got, shown(out))
+				}
+			},
+		},
+		grade.Task{
+			Name: "两个打印函数在goroutine中并发执行互不干扰",
+			Run: func(t *grade.T) {
+				out := grade.CaptureStdout(func() {
+					done := make(chan struct{})
+					go func() {
+						printOddNumbers()
+						close(done)
+					}()
+					printEvenNumbers()
+					<-done
+				})
+				var odd, even string
+				for _, n := range numberRe.FindAllString(out, -1) {
+					var v int
+					fmt.Sscan(n, &v)
+					if v%2 == 1 {
+						odd += n + " "
+					} else {
+						even += n + " "
+					}
+				}
+				if odd != "1 3 5 7 9 " || even != "2 4 6 8 10 " {
+					t.Errorf("并发执行时奇数打印了 %q，偶数打印了 %q", odd, even)
+				}
+			},
+		},
+		grade.Task{
+			Name:    "printCountdown从n倒数到1并带上名字",
+			Timeout: 8 * time.Second,
+			Run: func(t *grade.T) {
+				out := grade.CaptureStdout(func() { printCountdown(3, "Timer-X") })
+				if got := printedNumbers(out); got != "[3 2 1]" {
+					t.Errorf("printCountdown(3, \"Timer-X\")打印的数字是 %s，期望 [3 2 1]\n输出: %s", got, shown(out))
+				}
+				if !strings.Contains(out, "Timer-X") {
+					t.Errorf("输出中没有倒计时的名字Timer-X，多个倒计时并发时无法区分")
+				}
+			},
+		},
+	)
+}
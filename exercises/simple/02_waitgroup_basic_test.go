@@ -0,0 +1,59 @@
+// 评分：go run ./exercises/grader simple/02
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+func TestWaitGroupBasic(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "worker结束时调用wg.Done",
+			Run: func(t *grade.T) {
+				var wg sync.WaitGroup
+				wg.Add(1)
+				go worker(1, &wg)
+				if !grade.Within(time.Second, wg.Wait) {
+					t.Errorf("wg.Wait()在1秒内没有返回：worker没有调用wg.Done()")
+				}
+			},
+		},
+		grade.Task{
+			Name: "worker打印自己的id且格式正确",
+			Run: func(t *grade.T) {
+				var wg sync.WaitGroup
+				wg.Add(1)
+				out := grade.CaptureStdout(func() {
+					worker(7, &wg)
+				})
+				if !strings.Contains(out, "7") {
+					t.Errorf("worker(7, ...)的输出中没有id 7，输出: %q", out)
+				}
+				if strings.Contains(out, "%!") {
+					t.Errorf("输出 %q 中有格式错误：格式字符串里缺少与参数对应的%%d", out)
+				}
+			},
+		},
+		grade.Task{
+			Name: "10个worker并发运行后Wait返回",
+			Run: func(t *grade.T) {
+				var wg sync.WaitGroup
+				grade.CaptureStdout(func() {
+					for i := 0; i < 10; i++ {
+						wg.Add(1)
+						go worker(i, &wg)
+					}
+					if !grade.Within(time.Second, wg.Wait) {
+						t.Errorf("10个worker启动后wg.Wait()在1秒内没有返回")
+					}
+				})
+			},
+		},
+	)
+}
@@ -0,0 +1,69 @@
+// 评分：go run ./exercises/grader simple/03
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+// receiveAll 接收ch中的全部消息，timeout内ch没有关闭时closed为false
+func receiveAll(ch <-chan string, timeout time.Duration) (msgs []string, closed bool) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return msgs, true
+			}
+			msgs = append(msgs, msg)
+		case <-deadline:
+			return msgs, false
+		}
+	}
+}
+
+func TestChannelBasic(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "sender按顺序发送全部消息",
+			Run: func(t *grade.T) {
+				ch := make(chan string)
+				go sender(ch)
+				msgs, _ := receiveAll(ch, time.Second)
+				if fmt.Sprint(msgs) != "[hello world hehehe]" {
+					t.Errorf("收到 %q，期望 [hello world hehehe]", msgs)
+				}
+			},
+		},
+		grade.Task{
+			Name: "sender发送完后关闭channel",
+			Run: func(t *grade.T) {
+				ch := make(chan string)
+				go sender(ch)
+				if _, closed := receiveAll(ch, time.Second); !closed {
+					t.Errorf("1秒内channel没有关闭：接收方的for range会永远等下去")
+				}
+			},
+		},
+		grade.Task{
+			Name: "使用缓冲channel时sender不阻塞",
+			Run: func(t *grade.T) {
+				ch := make(chan string, 16)
+				if !grade.Within(time.Second, func() { sender(ch) }) {
+					t.Fatalf("缓冲足够时sender在1秒内没有返回")
+				}
+				n := 0
+				for range ch {
+					n++
+				}
+				if n != 3 {
+					t.Errorf("缓冲channel中有 %d 条消息，期望3条", n)
+				}
+			},
+		},
+	)
+}
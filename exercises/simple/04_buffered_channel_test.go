@@ -0,0 +1,72 @@
+// 评分：go run ./exercises/grader simple/04
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+func TestBufferedChannel(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "consumer消费完已关闭的channel后返回",
+			Run: func(t *grade.T) {
+				ch := make(chan string, 5)
+				for i := 0; i < 5; i++ {
+					ch <- fmt.Sprintf("数据%d", i)
+				}
+				close(ch)
+				var out string
+				ok := grade.Within(time.Second, func() {
+					out = grade.CaptureStdout(func() { consumer(ch, 1) })
+				})
+				if !ok {
+					t.Fatalf("channel关闭后consumer在1秒内没有返回")
+				}
+				if len(ch) != 0 {
+					t.Errorf("consumer返回时channel中还剩 %d 条数据", len(ch))
+				}
+				for i := 0; i < 5; i++ {
+					if !strings.Contains(out, fmt.Sprintf("数据%d", i)) {
+						t.Errorf("输出中没有\"数据%d\"：每条消费的数据都应该打印出来", i)
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "多个consumer分担数据且每条只被消费一次",
+			Run: func(t *grade.T) {
+				const n = 30
+				ch := make(chan string, 8)
+				var wg sync.WaitGroup
+				out := grade.CaptureStdout(func() {
+					for i := 0; i < 3; i++ {
+						wg.Add(1)
+						go func(i int) {
+							defer wg.Done()
+							consumer(ch, i)
+						}(i)
+					}
+					for i := 0; i < n; i++ {
+						ch <- fmt.Sprintf("<%d>", i)
+					}
+					close(ch)
+					if !grade.Within(2*time.Second, wg.Wait) {
+						t.Errorf("channel关闭后3个consumer在2秒内没有全部返回")
+					}
+				})
+				for i := 0; i < n; i++ {
+					if c := strings.Count(out, fmt.Sprintf("<%d>", i)); c != 1 {
+						t.Errorf("数据<%d>被消费了 %d 次", i, c)
+					}
+				}
+			},
+		},
+	)
+}
@@ -1,49 +1,98 @@
+/*
+Golang并发编程练习 - 简单级别
+练习文件：05_select_basic_exercise.go
+练习主题：Select语句
+
+练习目标：
+1. 用select同时等待多个channel
+2. 用default分支实现非阻塞操作
+3. 理解关闭的channel总是立即可读、nil channel永远不会被选中
+
+练习任务：
+- 任务1：实现receiveFirst，返回先到达的消息和它来自哪个channel
+- 任务2：实现tryReceive，没有数据时立即返回
+- 任务3：实现mergeUntilClosed，合并两个channel直到都被关闭
+
+对应Demo：cmd/simple/05_select_basic
+
+运行方式：go run exercises/simple/05_select_basic_exercise.go
+*/
+
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"time"
 )
 
-// TODO: 在这里实现你的代码
+// receiveFirst 同时等待两个channel，返回先到达的消息和它来自哪个channel（1或2）
+// TODO: 用select实现
+func receiveFirst(ch1, ch2 <-chan string) (string, int) {
+	// 在这里实现您的代码
+
+	return "", 0
+}
+
+// tryReceive 非阻塞接收：有数据时返回(数据, true)；没有数据或channel已关闭时立即返回("", false)
+// TODO: 用带default分支的select实现
+func tryReceive(ch <-chan string) (string, bool) {
+	// 在这里实现您的代码
+	// 提示：channel关闭后接收会立即返回零值，用 v, ok := <-ch 区分
+
+	return "", false
+}
+
+// mergeUntilClosed 同时从ch1和ch2接收，直到两个channel都被关闭，按收到的顺序返回全部数据
+// TODO: 用for循环加select实现
+func mergeUntilClosed(ch1, ch2 <-chan string) []string {
+	// 在这里实现您的代码
+	// 提示：
+	// 1. 一个channel关闭后，它的case每次都会立即选中并收到零值，循环会空转
+	// 2. 把关闭的channel变量设为nil：从nil channel接收永远阻塞，select不会再选中它
+	// 3. 两个变量都为nil时结束循环
+
+	return nil
+}
 
 func main() {
-	ch1 := make(chan string, 10)
-	ch2 := make(chan string, 10)
+	fmt.Println("=== Select语句练习 ===")
+
+	fmt.Println("\n任务1：等待先到达的消息")
+	fast, slow := make(chan string), make(chan string, 1) // slow有缓冲：没人接收时发送方也能退出
+	go func() { time.Sleep(50 * time.Millisecond); fast <- "50ms后的消息" }()
+	go func() { time.Sleep(200 * time.Millisecond); slow <- "200ms后的消息" }()
+	msg, from := receiveFirst(slow, fast)
+	fmt.Printf("先收到来自通道%d的: %s\n", from, msg)
+
+	fmt.Println("\n任务2：非阻塞接收")
+	ch := make(chan string, 1)
+	v, ok := tryReceive(ch)
+	fmt.Printf("空channel: %q, %v\n", v, ok)
+	ch <- "数据"
+	v, ok = tryReceive(ch)
+	fmt.Printf("有数据时: %q, %v\n", v, ok)
 
+	fmt.Println("\n任务3：合并两个channel")
+	letters, numbers := make(chan string), make(chan string)
 	go func() {
-		scanner := bufio.NewScanner(os.Stdin)
-		for {
-			fmt.Print("请输入内容：")
-			if scanner.Scan() {
-				text := scanner.Text()
-				ch1 <- text
-			} else {
-				// 读取失败（例如 Ctrl+D），退出
-				close(ch1)
-				break
-			}
+		defer close(letters)
+		for _, s := range []string{"a", "b", "c"} {
+			letters <- s
+			time.Sleep(20 * time.Millisecond)
 		}
 	}()
-
 	go func() {
-		time.Sleep(1 * time.Second)
-		ch2 <- "1秒后的消息"
-		// close(ch2)
-	}()
-
-	for {
-		select {
-		case msg := <-ch1:
-			fmt.Printf(msg)
-		case msg := <-ch2:
-			fmt.Printf("收到通道2的数据了%s\n", msg)
-		case <-time.After(5 * time.Second):
-			fmt.Printf("暂时没有消息进入\n")
+		defer close(numbers)
+		for _, s := range []string{"1", "2"} {
+			time.Sleep(30 * time.Millisecond)
+			numbers <- s
 		}
+	}()
+	fmt.Printf("合并结果: %v\n", mergeUntilClosed(letters, numbers))
 
-	}
-
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 多个case同时就绪时，select选择哪一个？")
+	fmt.Println("2. 没有default分支、所有case都没有就绪时会怎样？全部是nil channel呢？")
+	fmt.Println("3. 为什么关闭的channel要设为nil，而不是用一个标志变量跳过它？")
 }
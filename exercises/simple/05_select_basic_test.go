@@ -0,0 +1,101 @@
+// 评分：go run ./exercises/grader simple/05
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+func TestSelectBasic(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "receiveFirst返回已经有数据的channel",
+			Run: func(t *grade.T) {
+				ch1, ch2 := make(chan string), make(chan string, 1)
+				ch2 <- "来自ch2"
+				if msg, from := receiveFirst(ch1, ch2); msg != "来自ch2" || from != 2 {
+					t.Errorf("只有ch2有数据时返回(%q, %d)，期望(\"来自ch2\", 2)", msg, from)
+				}
+			},
+		},
+		grade.Task{
+			Name: "receiveFirst阻塞等待先到达的消息",
+			Run: func(t *grade.T) {
+				ch1, ch2 := make(chan string), make(chan string)
+				go func() {
+					time.Sleep(30 * time.Millisecond)
+					ch1 <- "来自ch1"
+				}()
+				start := time.Now()
+				msg, from := receiveFirst(ch1, ch2)
+				if msg != "来自ch1" || from != 1 {
+					t.Errorf("ch1在30ms后收到数据，receiveFirst返回(%q, %d)，期望(\"来自ch1\", 1)", msg, from)
+				}
+				if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+					t.Errorf("receiveFirst在 %v 后就返回了，没有等待数据到达", elapsed)
+				}
+			},
+		},
+		grade.Task{
+			Name: "tryReceive有数据时取出，否则立即返回",
+			Run: func(t *grade.T) {
+				ch := make(chan string, 1)
+				ch <- "数据"
+				if v, ok := tryReceive(ch); v != "数据" || !ok {
+					t.Errorf("有数据时返回(%q, %v)，期望(\"数据\", true)", v, ok)
+				}
+				var v string
+				var ok bool
+				if !grade.Within(100*time.Millisecond, func() { v, ok = tryReceive(ch) }) {
+					t.Fatalf("channel为空时tryReceive阻塞了：需要default分支")
+				}
+				if v != "" || ok {
+					t.Errorf("channel为空时返回(%q, %v)，期望(\"\", false)", v, ok)
+				}
+				close(ch)
+				if v, ok := tryReceive(ch); v != "" || ok {
+					t.Errorf("channel已关闭时返回(%q, %v)，期望(\"\", false)", v, ok)
+				}
+			},
+		},
+		grade.Task{
+			Name: "mergeUntilClosed收齐两个channel的数据，都关闭后才返回",
+			Run: func(t *grade.T) {
+				ch1, ch2 := make(chan string), make(chan string)
+				go func() {
+					defer close(ch1)
+					ch1 <- "a"
+					ch1 <- "b"
+				}()
+				go func() {
+					defer close(ch2)
+					// ch1关闭之后ch2才有数据：关闭的ch1如果还在被选中，会收到一串空字符串
+					time.Sleep(50 * time.Millisecond)
+					ch2 <- "x"
+					time.Sleep(20 * time.Millisecond)
+					ch2 <- "y"
+				}()
+				var got []string
+				if !grade.Within(time.Second, func() { got = mergeUntilClosed(ch1, ch2) }) {
+					t.Fatalf("两个channel都关闭后mergeUntilClosed仍没有返回")
+				}
+				count := make(map[string]int)
+				for _, v := range got {
+					count[v]++
+				}
+				if count[""] > 0 {
+					t.Fatalf("结果中有 %d 个空字符串：channel关闭后还在从它接收，需要把它设为nil", count[""])
+				}
+				if len(got) != 4 || count["a"] != 1 || count["b"] != 1 || count["x"] != 1 || count["y"] != 1 {
+					t.Errorf("返回 %q，期望a、b、x、y各一次", got)
+				}
+				if len(got) == 4 && (got[0] != "a" || got[1] != "b") {
+					t.Errorf("返回 %q，同一个channel的数据应保持发送顺序", got)
+				}
+			},
+		},
+	)
+}
@@ -1,12 +1,72 @@
+/*
+Golang并发编程练习 - 简单级别
+练习文件：06_timeout_select_exercise.go
+练习主题：带超时的select
+
+练习目标：
+1. 用select和time.After给操作加上超时
+2. 理解超时后仍在运行的goroutine会怎样
+3. 区分"每次循环重新计时"和"整体截止时间"
+
+练习任务：
+- 任务1：实现fetchWithTimeout，超时返回errTimeout，且超时后不泄漏goroutine
+- 任务2：实现collectUntil，在给定时间内尽量多地接收数据
+
+运行方式：go run exercises/simple/06_timeout_select_exercise.go
+*/
+
 package main
 
 import (
+	"errors"
 	"fmt"
+	"time"
 )
 
-// TODO: 在这里实现你的代码
+var errTimeout = errors.New("操作超时")
+
+// fetchWithTimeout 在新的goroutine中执行work，timeout内完成时返回结果，否则返回errTimeout
+// TODO: 实现这个函数
+// 提示：保存结果的channel要有缓冲，否则超时后没有人接收，work所在的goroutine会永远阻塞在发送上
+func fetchWithTimeout(work func() string, timeout time.Duration) (string, error) {
+	// 在这里实现您的代码
+
+	return "", nil
+}
+
+// collectUntil 从ch接收数据，直到经过d或ch被关闭，返回收到的全部数据
+// TODO: 实现这个函数
+// 提示：计时器要在循环外创建一次，time.After写在循环里的select中每次都会重新计时
+func collectUntil(ch <-chan int, d time.Duration) []int {
+	// 在这里实现您的代码
+
+	return nil
+}
 
 func main() {
-	fmt.Println("06_timeout_select_exercise")
-	// TODO: 实现带超时的select练习
+	fmt.Println("=== 超时控制练习 ===")
+
+	fmt.Println("\n任务1：带超时的调用")
+	fast := func() string { time.Sleep(50 * time.Millisecond); return "快速结果" }
+	slow := func() string { time.Sleep(500 * time.Millisecond); return "慢速结果" }
+	for _, work := range []func() string{fast, slow} {
+		v, err := fetchWithTimeout(work, 200*time.Millisecond)
+		fmt.Printf("结果: %q, 错误: %v\n", v, err)
+	}
+
+	fmt.Println("\n任务2：限时接收")
+	ch := make(chan int)
+	go func() {
+		for i := 1; ; i++ {
+			ch <- i
+			time.Sleep(30 * time.Millisecond)
+		}
+	}()
+	fmt.Printf("300ms内收到: %v\n", collectUntil(ch, 300*time.Millisecond))
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 超时返回后，work所在的goroutine还在运行吗？它最终会怎样？")
+	fmt.Println("2. 如果work能够响应取消，应该怎样修改fetchWithTimeout的签名？")
+	fmt.Println("3. time.After和time.NewTimer有什么区别？")
 }
@@ -0,0 +1,117 @@
+// 评分：go run ./exercises/grader simple/06
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/leakcheck"
+)
+
+func TestTimeoutSelect(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "fetchWithTimeout及时完成时返回结果",
+			Run: func(t *grade.T) {
+				v, err := fetchWithTimeout(func() string {
+					time.Sleep(10 * time.Millisecond)
+					return "结果"
+				}, time.Second)
+				if v != "结果" || err != nil {
+					t.Errorf("返回(%q, %v)，期望(\"结果\", nil)", v, err)
+				}
+			},
+		},
+		grade.Task{
+			Name: "fetchWithTimeout超时返回errTimeout",
+			Run: func(t *grade.T) {
+				start := time.Now()
+				_, err := fetchWithTimeout(func() string {
+					time.Sleep(500 * time.Millisecond)
+					return "太慢了"
+				}, 50*time.Millisecond)
+				elapsed := time.Since(start)
+				if !errors.Is(err, errTimeout) {
+					t.Errorf("work需要500ms、超时50ms时返回的错误是 %v，期望errTimeout", err)
+				}
+				if elapsed > 300*time.Millisecond {
+					t.Errorf("超时50ms，却在 %v 后才返回：没有在超时后立即返回", elapsed)
+				}
+			},
+		},
+		grade.Task{
+			Name: "fetchWithTimeout超时后不泄漏goroutine",
+			Run: func(t *grade.T) {
+				base := leakcheck.Take()
+				release := make(chan struct{})
+				_, err := fetchWithTimeout(func() string {
+					<-release
+					return "迟到的结果"
+				}, 20*time.Millisecond)
+				if !errors.Is(err, errTimeout) {
+					t.Fatalf("没有超时（返回的错误是 %v），无法检查泄漏", err)
+				}
+				close(release) // work在超时之后才完成，它的goroutine应该能把结果发出去并退出
+				if leaked := leakcheck.Check(base, time.Second); len(leaked) > 0 {
+					t.Errorf("work完成后它的goroutine没有退出（结果channel没有缓冲？）\n%s", leakcheck.Report(leaked, false))
+				}
+			},
+		},
+		grade.Task{
+			Name: "collectUntil在截止时间前一直接收",
+			Run: func(t *grade.T) {
+				ch := make(chan int)
+				stop := make(chan struct{})
+				defer close(stop)
+				go func() {
+					for i := 1; ; i++ {
+						select {
+						case ch <- i:
+						case <-stop:
+							return
+						}
+						time.Sleep(10 * time.Millisecond)
+					}
+				}()
+				start := time.Now()
+				got := collectUntil(ch, 200*time.Millisecond)
+				elapsed := time.Since(start)
+				if elapsed < 150*time.Millisecond || elapsed > 400*time.Millisecond {
+					t.Errorf("d=200ms，collectUntil在 %v 后返回", elapsed)
+				}
+				// 每10ms一个数据，200ms内至少应收到5个；time.After写在循环里时每次收到数据都会重新计时，永远不会超时
+				if len(got) < 5 {
+					t.Errorf("200ms内只收到 %d 个数据", len(got))
+				}
+				for i, v := range got {
+					if v != i+1 {
+						t.Errorf("收到的数据 %v 不是从1开始的连续整数", got)
+						break
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "collectUntil在channel关闭时提前返回",
+			Run: func(t *grade.T) {
+				ch := make(chan int, 3)
+				ch <- 1
+				ch <- 2
+				ch <- 3
+				close(ch)
+				start := time.Now()
+				got := collectUntil(ch, time.Second)
+				if fmt.Sprint(got) != "[1 2 3]" {
+					t.Errorf("返回 %v，期望 [1 2 3]（channel关闭后不应再收到零值）", got)
+				}
+				if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+					t.Errorf("channel已关闭，却等了 %v 才返回", elapsed)
+				}
+			},
+		},
+	)
+}
@@ -1,12 +1,77 @@
+/*
+Golang并发编程练习 - 简单级别
+练习文件：07_mutex_basic_exercise.go
+练习主题：互斥锁保护共享数据
+
+练习目标：
+1. 用sync.Mutex保护map
+2. 理解为什么读也需要加锁
+3. 用go run -race验证没有数据竞争
+
+练习任务：
+- 任务1：实现按键计数的SafeCounter
+- 任务2：100个goroutine并发计数，结果必须准确
+
+运行方式：go run -race exercises/simple/07_mutex_basic_exercise.go
+*/
+
 package main
 
 import (
 	"fmt"
+	"sync"
 )
 
-// TODO: 在这里实现你的代码
+// SafeCounter 并发安全的按键计数器
+// TODO: 添加需要的字段
+// 提示：一个sync.Mutex和一个map[string]int
+type SafeCounter struct {
+}
+
+// NewSafeCounter 创建计数器
+// TODO: 初始化map
+func NewSafeCounter() *SafeCounter {
+	// 在这里实现您的代码
+	return nil
+}
+
+// Inc 把key的计数加一
+func (c *SafeCounter) Inc(key string) {
+	// 在这里实现您的代码
+
+}
+
+// Value 返回key的当前计数
+func (c *SafeCounter) Value(key string) int {
+	// 在这里实现您的代码
+	// 提示：读取map也要加锁，并发读写map会导致程序崩溃
+	return 0
+}
 
 func main() {
-	fmt.Println("07_mutex_basic_exercise")
-	// TODO: 实现互斥锁基础用法的练习
+	fmt.Println("=== 互斥锁练习 ===")
+
+	c := NewSafeCounter()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Inc(fmt.Sprintf("key-%d", i%3))
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		fmt.Printf("%s: %d\n", key, c.Value(key))
+	}
+	fmt.Println("三个计数之和应为10000")
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 如果Value不加锁会发生什么？")
+	fmt.Println("2. 读多写少时可以换成什么锁？")
+	fmt.Println("3. 为什么Mutex字段不能被复制？")
 }
@@ -0,0 +1,84 @@
+// 评分：go run ./exercises/grader simple/07
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+func TestMutexBasic(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "SafeCounter单个goroutine计数正确",
+			Run: func(t *grade.T) {
+				c := NewSafeCounter()
+				if c == nil {
+					t.Fatalf("NewSafeCounter返回nil")
+				}
+				for i := 0; i < 3; i++ {
+					c.Inc("a")
+				}
+				c.Inc("b")
+				if a, b, z := c.Value("a"), c.Value("b"), c.Value("z"); a != 3 || b != 1 || z != 0 {
+					t.Errorf("Value(a, b, z) = (%d, %d, %d)，期望 (3, 1, 0)", a, b, z)
+				}
+			},
+		},
+		grade.Task{
+			Name: "100个goroutine并发计数准确且没有数据竞争",
+			Run: func(t *grade.T) {
+				c := NewSafeCounter()
+				if c == nil {
+					t.Fatalf("NewSafeCounter返回nil")
+				}
+				var wg sync.WaitGroup
+				for i := 0; i < 100; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						key := fmt.Sprintf("key-%d", i%4)
+						for j := 0; j < 100; j++ {
+							c.Inc(key)
+							c.Value(key) // 读写交替，没加锁的读会被竞态检测器发现
+						}
+					}(i)
+				}
+				wg.Wait()
+				for i := 0; i < 4; i++ {
+					key := fmt.Sprintf("key-%d", i)
+					if v := c.Value(key); v != 2500 {
+						t.Errorf("Value(%q) = %d，期望2500", key, v)
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "只有Inc并发写入时也没有数据竞争",
+			Run: func(t *grade.T) {
+				c := NewSafeCounter()
+				if c == nil {
+					t.Fatalf("NewSafeCounter返回nil")
+				}
+				// 上一个任务每次Inc后紧跟一次加锁的Value，会掩盖只锁住一半的Inc；这里只并发调用Inc
+				var wg sync.WaitGroup
+				for i := 0; i < 50; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for j := 0; j < 200; j++ {
+							c.Inc("hot")
+						}
+					}()
+				}
+				wg.Wait()
+				if v := c.Value("hot"); v != 10000 {
+					t.Errorf("Value(\"hot\") = %d，期望10000", v)
+				}
+			},
+		},
+	)
+}
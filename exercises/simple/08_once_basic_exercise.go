@@ -1,12 +1,79 @@
+/*
+Golang并发编程练习 - 简单级别
+练习文件：08_once_basic_exercise.go
+练习主题：sync.Once单次初始化
+
+练习目标：
+1. 用sync.Once实现并发安全的延迟初始化
+2. 理解"先检查再初始化"为什么不是并发安全的
+
+练习任务：
+- 任务1：实现GetConfig，第一次调用时加载配置
+- 任务2：50个goroutine同时调用，loadConfig只能执行一次，所有人拿到同一个配置
+
+运行方式：go run -race exercises/simple/08_once_basic_exercise.go
+*/
+
 package main
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// TODO: 在这里实现你的代码
+// Config 应用配置
+type Config struct {
+	Name    string
+	Workers int
+}
+
+// loadCount loadConfig被调用的次数
+var loadCount atomic.Int32
+
+// loadConfig 模拟代价很高的配置加载（已实现，不需要修改）
+func loadConfig() *Config {
+	loadCount.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return &Config{Name: "app", Workers: 4}
+}
+
+// GetConfig 返回全局唯一的配置，第一次调用时加载
+// TODO: 实现这个函数
+// 提示：用包级变量保存配置，用sync.Once保证无论多少goroutine同时调用，loadConfig都只执行一次
+func GetConfig() *Config {
+	// 在这里实现您的代码
+
+	return nil
+}
 
 func main() {
-	fmt.Println("08_once_basic_exercise")
-	// TODO: 实现sync.Once基础用法的练习
+	fmt.Println("=== sync.Once练习 ===")
+
+	var wg sync.WaitGroup
+	configs := make([]*Config, 50)
+	for i := range configs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			configs[i] = GetConfig()
+		}(i)
+	}
+	wg.Wait()
+
+	same := true
+	for _, c := range configs {
+		if c != configs[0] {
+			same = false
+		}
+	}
+	fmt.Printf("loadConfig执行次数: %d（应为1）\n", loadCount.Load())
+	fmt.Printf("所有goroutine拿到同一个配置: %v\n", same && configs[0] != nil)
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. if config == nil { config = loadConfig() } 在并发下会出什么问题？")
+	fmt.Println("2. 如果loadConfig可能失败，sync.Once还合适吗？")
+	fmt.Println("3. Go 1.21的sync.OnceValue能怎样简化这段代码？")
 }
@@ -0,0 +1,63 @@
+// 评分：go run ./exercises/grader simple/08
+
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+func TestOnceBasic(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "50个goroutine同时调用GetConfig只加载一次",
+			Run: func(t *grade.T) {
+				var wg sync.WaitGroup
+				start := make(chan struct{})
+				configs := make([]*Config, 50)
+				for i := range configs {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						<-start // 同时开始，先检查再初始化的写法一定会多次加载
+						configs[i] = GetConfig()
+					}(i)
+				}
+				close(start)
+				wg.Wait()
+				if n := loadCount.Load(); n != 1 {
+					t.Errorf("loadConfig执行了 %d 次，期望1次", n)
+				}
+				if configs[0] == nil {
+					t.Fatalf("GetConfig返回nil")
+				}
+				for i, c := range configs {
+					if c != configs[0] {
+						t.Errorf("第%d个goroutine拿到的配置和第0个不是同一个对象", i)
+						break
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "之后的调用返回同一个配置且不再加载",
+			Run: func(t *grade.T) {
+				first := GetConfig()
+				before := loadCount.Load()
+				for i := 0; i < 10; i++ {
+					if c := GetConfig(); c != first || c == nil {
+						t.Fatalf("第%d次调用返回了不同的配置", i+2)
+					}
+				}
+				if n := loadCount.Load(); n != before {
+					t.Errorf("已经初始化后又执行了 %d 次loadConfig", n-before)
+				}
+				if first != nil && (first.Name != "app" || first.Workers != 4) {
+					t.Errorf("配置内容是 %+v，应该直接使用loadConfig的返回值", *first)
+				}
+			},
+		},
+	)
+}
@@ -1,12 +1,60 @@
+/*
+Golang并发编程练习 - 简单级别
+练习文件：09_channel_pipeline_exercise.go
+练习主题：Channel管道
+
+练习目标：
+1. 把处理过程拆成多个由channel连接的阶段
+2. 每个阶段由自己的goroutine运行，处理完后关闭输出channel
+3. 理解关闭信号如何沿着管道向下游传递
+
+练习任务：
+- 任务1：generate把参数依次发送到channel
+- 任务2：square把每个数平方后发给下游
+- 任务3：sum累加所有输入，直到上游关闭
+
+运行方式：go run exercises/simple/09_channel_pipeline_exercise.go
+*/
+
 package main
 
 import (
 	"fmt"
 )
 
-// TODO: 在这里实现你的代码
+// generate 启动一个goroutine把nums依次发送到返回的channel，发送完后关闭它
+// TODO: 实现这个函数
+func generate(nums ...int) <-chan int {
+	// 在这里实现您的代码
+
+	return nil
+}
+
+// square 启动一个goroutine，把in中的每个数平方后发送到返回的channel，in关闭后关闭输出
+// TODO: 实现这个函数
+func square(in <-chan int) <-chan int {
+	// 在这里实现您的代码
+
+	return nil
+}
+
+// sum 累加in中的所有数，直到in被关闭
+// TODO: 实现这个函数
+func sum(in <-chan int) int {
+	// 在这里实现您的代码
+
+	return 0
+}
 
 func main() {
-	fmt.Println("09_channel_pipeline_exercise")
-	// TODO: 实现channel管道的练习
+	fmt.Println("=== Channel管道练习 ===")
+
+	fmt.Printf("1²+2²+3²+4²+5² = %d（应为55）\n", sum(square(generate(1, 2, 3, 4, 5))))
+	fmt.Printf("两次平方: 2⁴+3⁴ = %d（应为97）\n", sum(square(square(generate(2, 3)))))
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 如果square忘记关闭输出channel会发生什么？")
+	fmt.Println("2. 如果sum中途返回，上游的goroutine会怎样？怎样避免泄漏？")
+	fmt.Println("3. 怎样让square的处理并行化而保持输出顺序？")
 }
@@ -0,0 +1,114 @@
+// 评分：go run ./exercises/grader simple/09
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+// drain 接收ch中的全部数据，timeout内ch没有关闭时closed为false
+func drain(ch <-chan int, timeout time.Duration) (vals []int, closed bool) {
+	if ch == nil {
+		return nil, false
+	}
+	deadline := time.After(timeout)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return vals, true
+			}
+			vals = append(vals, v)
+		case <-deadline:
+			return vals, false
+		}
+	}
+}
+
+func TestChannelPipeline(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "generate按顺序发送参数后关闭",
+			Run: func(t *grade.T) {
+				vals, closed := drain(generate(3, 1, 4, 1, 5), time.Second)
+				if fmt.Sprint(vals) != "[3 1 4 1 5]" {
+					t.Errorf("收到 %v，期望 [3 1 4 1 5]", vals)
+				}
+				if !closed {
+					t.Errorf("发送完后channel没有关闭")
+				}
+			},
+		},
+		grade.Task{
+			Name: "square逐个平方并在上游关闭后关闭",
+			Run: func(t *grade.T) {
+				in := make(chan int)
+				go func() {
+					for _, v := range []int{1, -2, 3} {
+						in <- v
+					}
+					close(in)
+				}()
+				vals, closed := drain(square(in), time.Second)
+				if fmt.Sprint(vals) != "[1 4 9]" {
+					t.Errorf("收到 %v，期望 [1 4 9]", vals)
+				}
+				if !closed {
+					t.Errorf("上游关闭后square的输出channel没有关闭")
+				}
+			},
+		},
+		grade.Task{
+			Name: "square不等上游结束就开始输出",
+			Run: func(t *grade.T) {
+				in := make(chan int)
+				out := square(in)
+				if out == nil {
+					t.Fatalf("square返回nil")
+				}
+				in <- 6
+				select {
+				case v := <-out:
+					if v != 36 {
+						t.Errorf("收到 %d，期望36", v)
+					}
+				case <-time.After(time.Second):
+					t.Errorf("上游还没关闭时，square没有把已收到的数据发给下游")
+				}
+				close(in)
+			},
+		},
+		grade.Task{
+			Name: "sum累加到上游关闭",
+			Run: func(t *grade.T) {
+				in := make(chan int, 4)
+				in <- 1
+				in <- 2
+				in <- 3
+				in <- 4
+				close(in)
+				if s := sum(in); s != 10 {
+					t.Errorf("sum = %d，期望10", s)
+				}
+			},
+		},
+		grade.Task{
+			Name: "三个阶段连成管道",
+			Run: func(t *grade.T) {
+				nums := make([]int, 100)
+				want := 0
+				for i := range nums {
+					nums[i] = i
+					want += i * i * i * i
+				}
+				if s := sum(square(square(generate(nums...)))); s != want {
+					t.Errorf("sum(square(square(generate(0..99)))) = %d，期望%d", s, want)
+				}
+			},
+		},
+	)
+}
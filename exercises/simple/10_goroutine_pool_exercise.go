@@ -1,12 +1,54 @@
+/*
+Golang并发编程练习 - 简单级别
+练习文件：10_goroutine_pool_exercise.go
+练习主题：Goroutine池
+
+练习目标：
+1. 用固定数量的工作者goroutine处理一批任务
+2. 用jobs/results两个channel分发任务和收集结果
+3. 限制同时运行的任务数
+
+练习任务：
+- 任务1：实现runPool，用workers个goroutine对jobs中的每个元素调用fn
+- 任务2：结果按jobs的原始顺序返回
+- 任务3：任何时刻同时执行的fn不超过workers个
+
+运行方式：go run exercises/simple/10_goroutine_pool_exercise.go
+*/
+
 package main
 
 import (
 	"fmt"
+	"time"
 )
 
-// TODO: 在这里实现你的代码
+// runPool 用workers个goroutine并发地对jobs中的每个元素调用fn，返回与jobs顺序一致的结果
+// TODO: 实现这个函数
+// 提示：
+// 1. 任务channel中发送下标，工作者把结果写到results[下标]，不同下标的写入不冲突
+// 2. 用WaitGroup等待所有工作者退出
+func runPool(workers int, jobs []int, fn func(int) int) []int {
+	// 在这里实现您的代码
+
+	return nil
+}
 
 func main() {
-	fmt.Println("10_goroutine_pool_exercise")
-	// TODO: 实现goroutine池的练习
+	fmt.Println("=== Goroutine池练习 ===")
+
+	jobs := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	start := time.Now()
+	results := runPool(3, jobs, func(n int) int {
+		time.Sleep(100 * time.Millisecond)
+		return n * n
+	})
+	fmt.Printf("结果: %v\n", results)
+	fmt.Printf("耗时: %v（3个工作者处理10个100ms的任务，约400ms）\n", time.Since(start).Round(10*time.Millisecond))
+
+	// 反思问题：
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 工作者数量应该怎样选择？CPU密集和IO密集的任务有什么不同？")
+	fmt.Println("2. 如果fn可能返回错误，怎样在第一个错误时停止其余任务？")
+	fmt.Println("3. 为每个任务启动一个goroutine有什么问题？")
 }
@@ -0,0 +1,94 @@
+// 评分：go run ./exercises/grader simple/10
+
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+)
+
+func TestGoroutinePool(t *testing.T) {
+	grade.Test(t,
+		grade.Task{
+			Name: "runPool按原始顺序返回结果",
+			Run: func(t *grade.T) {
+				jobs := make([]int, 50)
+				for i := range jobs {
+					jobs[i] = i
+				}
+				got := runPool(4, jobs, func(n int) int {
+					time.Sleep(time.Duration(n%5) * time.Millisecond) // 打乱完成顺序
+					return n * 10
+				})
+				if len(got) != len(jobs) {
+					t.Fatalf("返回 %d 个结果，期望 %d 个", len(got), len(jobs))
+				}
+				for i, v := range got {
+					if v != i*10 {
+						t.Errorf("results[%d] = %d，期望 %d（结果没有按jobs的顺序排列）", i, v, i*10)
+						break
+					}
+				}
+			},
+		},
+		grade.Task{
+			Name: "同时运行的任务不超过workers个",
+			Run: func(t *grade.T) {
+				var running, peak atomic.Int32
+				jobs := make([]int, 20)
+				runPool(3, jobs, func(n int) int {
+					cur := running.Add(1)
+					for {
+						p := peak.Load()
+						if cur <= p || peak.CompareAndSwap(p, cur) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					running.Add(-1)
+					return n
+				})
+				if p := peak.Load(); p > 3 {
+					t.Errorf("workers=3，但同时运行了 %d 个任务", p)
+				}
+				if p := peak.Load(); p < 2 {
+					t.Errorf("同时运行的任务最多只有 %d 个：任务没有并发执行", p)
+				}
+			},
+		},
+		grade.Task{
+			Name: "多个工作者比串行执行快",
+			Run: func(t *grade.T) {
+				jobs := make([]int, 12)
+				start := time.Now()
+				got := runPool(4, jobs, func(n int) int {
+					time.Sleep(50 * time.Millisecond)
+					return n
+				})
+				elapsed := time.Since(start)
+				if len(got) != len(jobs) {
+					t.Fatalf("返回 %d 个结果，期望 %d 个", len(got), len(jobs))
+				}
+				// 串行600ms，4个工作者约150ms
+				if elapsed > 400*time.Millisecond {
+					t.Errorf("12个50ms的任务用4个工作者耗时 %v，接近串行执行", elapsed)
+				}
+			},
+		},
+		grade.Task{
+			Name: "没有任务时立即返回",
+			Run: func(t *grade.T) {
+				var got []int
+				if !grade.Within(time.Second, func() { got = runPool(4, nil, func(n int) int { return n }) }) {
+					t.Fatalf("jobs为空时runPool没有返回")
+				}
+				if len(got) != 0 {
+					t.Errorf("jobs为空时返回 %v", got)
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,393 @@
+/*
+Golang并发编程练习 - 困难级别（参考答案）
+练习文件：01_distributed_worker_exercise.go
+练习主题：分布式工作者系统
+
+运行方式：go run exercises/solutions/hard/01_distributed_worker_solution.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Task 任务结构
+type Task struct {
+	ID       string
+	Data     interface{}
+	Priority int
+	Created  time.Time
+}
+
+// WorkerNode 工作节点接口
+type WorkerNode interface {
+	GetID() string
+	ProcessTask(task Task) error
+	IsHealthy() bool
+	GetLoad() float64
+}
+
+// SimpleWorker 简单工作者实现
+type SimpleWorker struct {
+	id             string
+	processedCount int64
+	isHealthy      bool
+	processingTime time.Duration
+	mu             sync.RWMutex
+	active         atomic.Int32 // 正在处理的任务数
+}
+
+// NewSimpleWorker 新建的工作者是健康的
+func NewSimpleWorker(id string, processingTime time.Duration) *SimpleWorker {
+	return &SimpleWorker{id: id, isHealthy: true, processingTime: processingTime}
+}
+
+func (w *SimpleWorker) GetID() string { return w.id }
+
+// ProcessTask 处理任务；模拟处理的睡眠不持有锁，同一个工作者可以并发处理多个任务
+func (w *SimpleWorker) ProcessTask(task Task) error {
+	if !w.IsHealthy() {
+		return fmt.Errorf("工作者 %s 不健康，拒绝任务 %s", w.id, task.ID)
+	}
+	w.active.Add(1)
+	defer w.active.Add(-1)
+	time.Sleep(w.processingTime)
+
+	w.mu.Lock()
+	w.processedCount++
+	n := w.processedCount
+	w.mu.Unlock()
+	fmt.Printf("工作者 %s 完成任务 %s (累计 %d)\n", w.id, task.ID, n)
+	return nil
+}
+
+func (w *SimpleWorker) IsHealthy() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.isHealthy
+}
+
+// GetLoad 正在处理的任务数乘以单个任务的处理时间（秒），即清空手头工作还需要多久
+func (w *SimpleWorker) GetLoad() float64 {
+	return float64(w.active.Load()) * w.processingTime.Seconds()
+}
+
+func (w *SimpleWorker) SetHealthy(healthy bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.isHealthy = healthy
+}
+
+// ProcessedCount 已处理的任务数
+func (w *SimpleWorker) ProcessedCount() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.processedCount
+}
+
+var errNoNodes = errors.New("哈希环上没有节点")
+
+// ConsistentHashRing 一致性哈希环
+type ConsistentHashRing struct {
+	mu           sync.RWMutex
+	virtualNodes int                   // 每个节点的虚拟节点数
+	hashes       []uint32              // 所有虚拟节点的哈希值，升序
+	owners       map[uint32]string     // 虚拟节点哈希 -> 节点ID
+	nodes        map[string]WorkerNode // 节点ID -> 节点
+}
+
+func NewConsistentHashRing(virtualNodes int) *ConsistentHashRing {
+	return &ConsistentHashRing{
+		virtualNodes: max(virtualNodes, 1),
+		owners:       make(map[uint32]string),
+		nodes:        make(map[string]WorkerNode),
+	}
+}
+
+func (ring *ConsistentHashRing) hashFunction(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// virtualKey 节点的第i个虚拟节点在环上的名字
+func virtualKey(nodeID string, i int) string {
+	return nodeID + "#" + strconv.Itoa(i)
+}
+
+// AddNode 为节点创建virtualNodes个虚拟节点；重复添加同一个ID时替换节点对象
+func (ring *ConsistentHashRing) AddNode(node WorkerNode) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	id := node.GetID()
+	if _, ok := ring.nodes[id]; !ok {
+		for i := 0; i < ring.virtualNodes; i++ {
+			h := ring.hashFunction(virtualKey(id, i))
+			if _, taken := ring.owners[h]; taken {
+				continue // 极少见的哈希冲突：保留先加入的虚拟节点
+			}
+			ring.owners[h] = id
+			ring.hashes = append(ring.hashes, h)
+		}
+		sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+	}
+	ring.nodes[id] = node
+}
+
+// RemoveNode 移除节点的所有虚拟节点，其他节点的位置不变
+func (ring *ConsistentHashRing) RemoveNode(nodeID string) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	if _, ok := ring.nodes[nodeID]; !ok {
+		return
+	}
+	delete(ring.nodes, nodeID)
+	kept := ring.hashes[:0]
+	for _, h := range ring.hashes {
+		if ring.owners[h] == nodeID {
+			delete(ring.owners, h)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	ring.hashes = kept
+}
+
+// GetNode 顺时针找到第一个哈希值不小于任务哈希的虚拟节点，越过末尾时回到开头
+func (ring *ConsistentHashRing) GetNode(taskID string) (WorkerNode, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	if len(ring.hashes) == 0 {
+		return nil, errNoNodes
+	}
+	h := ring.hashFunction(taskID)
+	i := sort.Search(len(ring.hashes), func(i int) bool { return ring.hashes[i] >= h })
+	if i == len(ring.hashes) {
+		i = 0
+	}
+	return ring.nodes[ring.owners[ring.hashes[i]]], nil
+}
+
+// TaskScheduler 任务调度器
+type TaskScheduler struct {
+	ring     *ConsistentHashRing
+	tasks    chan Task
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup // 调度协程和正在处理任务的协程
+	dropped  atomic.Int64   // 因为没有可用工作者而丢弃的任务
+}
+
+func NewTaskScheduler(virtualNodes int) *TaskScheduler {
+	return &TaskScheduler{
+		ring:  NewConsistentHashRing(virtualNodes),
+		tasks: make(chan Task, 100),
+		stop:  make(chan struct{}),
+	}
+}
+
+func (ts *TaskScheduler) AddWorker(worker WorkerNode) { ts.ring.AddNode(worker) }
+
+func (ts *TaskScheduler) RemoveWorker(workerID string) { ts.ring.RemoveNode(workerID) }
+
+// SubmitTask 把任务放入队列；队列满时立即返回错误，由调用者决定重试还是丢弃
+func (ts *TaskScheduler) SubmitTask(task Task) error {
+	select {
+	case <-ts.stop:
+		return errors.New("调度器已停止")
+	default:
+	}
+	select {
+	case ts.tasks <- task:
+		return nil
+	default:
+		return errors.New("任务队列已满")
+	}
+}
+
+func (ts *TaskScheduler) Start() {
+	ts.wg.Add(1)
+	go ts.processTask()
+}
+
+// Stop 停止调度并等待正在处理的任务完成；队列中尚未调度的任务被丢弃。可以重复调用
+func (ts *TaskScheduler) Stop() {
+	ts.stopOnce.Do(func() { close(ts.stop) })
+	ts.wg.Wait()
+}
+
+func (ts *TaskScheduler) processTask() {
+	defer ts.wg.Done()
+	for {
+		select {
+		case <-ts.stop:
+			return
+		case task := <-ts.tasks:
+			worker, err := ts.ring.GetNode(task.ID)
+			if err != nil || !worker.IsHealthy() {
+				// 不转给其他节点：一致性哈希的意义就在于同一个ID总由同一个节点处理
+				ts.dropped.Add(1)
+				continue
+			}
+			ts.wg.Add(1)
+			go func() {
+				defer ts.wg.Done()
+				if err := worker.ProcessTask(task); err != nil {
+					ts.dropped.Add(1)
+				}
+			}()
+		}
+	}
+}
+
+// submitAll 提交任务，队列满时稍等再试
+func submitAll(ts *TaskScheduler, tasks []Task) {
+	for _, t := range tasks {
+		for ts.SubmitTask(t) != nil {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func makeTasks(prefix string, n int) []Task {
+	tasks := make([]Task, n)
+	for i := range tasks {
+		tasks[i] = Task{ID: fmt.Sprintf("%s-%d", prefix, i), Created: time.Now()}
+	}
+	return tasks
+}
+
+func main() {
+	fmt.Println("=== 分布式工作者系统练习 ===")
+
+	// 任务1：测试一致性哈希环
+	fmt.Println("\n任务1：一致性哈希环基础测试")
+	ring := NewConsistentHashRing(3)
+	for i := 1; i <= 4; i++ {
+		ring.AddNode(NewSimpleWorker(fmt.Sprintf("worker-%d", i), 10*time.Millisecond))
+	}
+	for _, t := range makeTasks("task", 10) {
+		node, _ := ring.GetNode(t.ID)
+		fmt.Printf("%s -> %s\n", t.ID, node.GetID())
+	}
+	fmt.Println("任务1完成")
+
+	// 任务2：负载均衡测试
+	fmt.Println("\n任务2：负载均衡测试")
+	for _, vnodes := range []int{3, 100} {
+		ring := NewConsistentHashRing(vnodes)
+		for i := 1; i <= 5; i++ {
+			ring.AddNode(NewSimpleWorker(fmt.Sprintf("worker-%d", i), 0))
+		}
+		counts := make(map[string]int)
+		for _, t := range makeTasks("job", 50) {
+			node, _ := ring.GetNode(t.ID)
+			counts[node.GetID()]++
+		}
+		fmt.Printf("%3d个虚拟节点，50个任务的分布: %v\n", vnodes, counts)
+	}
+	fmt.Println("任务2完成")
+
+	// 任务3：动态节点管理
+	fmt.Println("\n任务3：动态节点管理")
+	ts := NewTaskScheduler(50)
+	workers := make([]*SimpleWorker, 4)
+	for i := range workers {
+		workers[i] = NewSimpleWorker(fmt.Sprintf("worker-%d", i+1), 5*time.Millisecond)
+		ts.AddWorker(workers[i])
+	}
+	ts.Start()
+	submitAll(ts, makeTasks("batch1", 20))
+	extra := NewSimpleWorker("worker-5", 5*time.Millisecond)
+	ts.AddWorker(extra)
+	fmt.Println("运行中添加了 worker-5")
+	workers[1].SetHealthy(false)
+	fmt.Println("worker-2 故障")
+	submitAll(ts, makeTasks("batch2", 20))
+	ts.RemoveWorker("worker-2")
+	fmt.Println("移除了 worker-2")
+	workers[1].SetHealthy(true)
+	ts.AddWorker(workers[1])
+	fmt.Println("worker-2 恢复并重新加入")
+	submitAll(ts, makeTasks("batch3", 20))
+	time.Sleep(100 * time.Millisecond)
+	ts.Stop()
+	fmt.Printf("丢弃的任务: %d\n", ts.dropped.Load())
+	fmt.Println("任务3完成")
+
+	// 任务4：高并发压力测试
+	fmt.Println("\n任务4：高并发压力测试")
+	ts = NewTaskScheduler(100)
+	var fleet []*SimpleWorker
+	for i := 0; i < 10; i++ {
+		w := NewSimpleWorker(fmt.Sprintf("node-%d", i), time.Millisecond)
+		fleet = append(fleet, w)
+		ts.AddWorker(w)
+	}
+	fleet[3].SetHealthy(false)
+	fleet[7].SetHealthy(false)
+	ts.Start()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			submitAll(ts, makeTasks(fmt.Sprintf("load%d", g), 100))
+		}(g)
+	}
+	wg.Wait()
+	time.Sleep(50 * time.Millisecond)
+	ts.Stop()
+	var processed int64
+	for _, w := range fleet {
+		processed += w.ProcessedCount()
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("提交1000个任务，处理 %d 个，丢弃 %d 个（路由到2个故障节点），耗时 %v\n",
+		processed, ts.dropped.Load(), elapsed.Round(time.Millisecond))
+	fmt.Println("任务4完成")
+
+	// 任务5：一致性验证
+	fmt.Println("\n任务5：一致性验证（挑战任务）")
+	ring = NewConsistentHashRing(100)
+	for i := 1; i <= 5; i++ {
+		ring.AddNode(NewSimpleWorker(fmt.Sprintf("worker-%d", i), 0))
+	}
+	keys := makeTasks("key", 1000)
+	before := make(map[string]string)
+	for _, k := range keys {
+		n, _ := ring.GetNode(k.ID)
+		before[k.ID] = n.GetID()
+	}
+	ring.AddNode(NewSimpleWorker("worker-6", 0))
+	moved, wrong := 0, 0
+	for _, k := range keys {
+		n, _ := ring.GetNode(k.ID)
+		if n.GetID() != before[k.ID] {
+			moved++
+			if n.GetID() != "worker-6" {
+				wrong++
+			}
+		}
+	}
+	fmt.Printf("添加第6个节点后，1000个key中 %d 个迁移（理想约1/6），迁移到其他旧节点的 %d 个（应为0）\n", moved, wrong)
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 虚拟节点数量如何影响负载均衡？")
+	fmt.Println("   虚拟节点越多，每个节点在环上占据的区间越分散，分到的key越接近平均值，代价是环更大、查找稍慢")
+	fmt.Println("2. 节点故障时，如何保证任务不丢失？")
+	fmt.Println("   提交时持久化或保留任务，处理失败后重新入队；从环上移除故障节点后，它的key由顺时针的下一个节点接管")
+	fmt.Println("3. 一致性哈希相比简单哈希有什么优势？")
+	fmt.Println("   hash%n在节点数变化时几乎所有key都要迁移，一致性哈希只迁移新增或移除节点负责的那部分")
+	fmt.Println("4. 如何设计一个支持权重的负载均衡算法？")
+	fmt.Println("   按权重分配虚拟节点数，权重为2的节点在环上的虚拟节点是权重为1的两倍")
+}
@@ -0,0 +1,327 @@
+/*
+Golang并发编程练习 - 困难级别（参考答案）
+练习文件：02_load_balancer_exercise.go
+练习主题：负载均衡策略
+
+运行方式：go run exercises/solutions/hard/02_load_balancer_solution.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backend 后端服务器（已实现）
+type Backend struct {
+	ID     string
+	Weight int
+
+	active  atomic.Int64 // 正在处理的请求数，由LoadBalancer.Do增减
+	healthy atomic.Bool
+}
+
+// NewBackend 新建的后端是健康的
+func NewBackend(id string, weight int) *Backend {
+	b := &Backend{ID: id, Weight: weight}
+	b.healthy.Store(true)
+	return b
+}
+
+// Active 正在处理的请求数
+func (b *Backend) Active() int64 { return b.active.Load() }
+
+func (b *Backend) IsHealthy() bool { return b.healthy.Load() }
+
+func (b *Backend) SetHealthy(healthy bool) { b.healthy.Store(healthy) }
+
+// ErrNoBackend 没有健康的后端
+var ErrNoBackend = errors.New("no healthy backend")
+
+// Strategy 负载均衡策略
+// Pick从backends中选择一个健康的后端，没有健康的后端时返回nil。
+// Pick会被多个goroutine同时调用；backends是LoadBalancer的快照，只能读不能修改。
+type Strategy interface {
+	Pick(backends []*Backend) *Backend
+	Name() string
+}
+
+// healthy 过滤出健康的后端，返回新切片，不修改快照
+func healthy(backends []*Backend) []*Backend {
+	list := make([]*Backend, 0, len(backends))
+	for _, b := range backends {
+		if b.IsHealthy() {
+			list = append(list, b)
+		}
+	}
+	return list
+}
+
+// RoundRobin 轮询：按列表顺序依次选择健康的后端
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+func (rr *RoundRobin) Pick(backends []*Backend) *Backend {
+	list := healthy(backends)
+	if len(list) == 0 {
+		return nil
+	}
+	// 原子自增保证并发调用时每个序号只被用一次，各后端分到的请求数严格轮流
+	n := rr.next.Add(1) - 1
+	return list[n%uint64(len(list))]
+}
+
+func (rr *RoundRobin) Name() string { return "RoundRobin" }
+
+// LeastConnections 最少连接：选择Active()最小的健康后端
+type LeastConnections struct{}
+
+func (lc *LeastConnections) Pick(backends []*Backend) *Backend {
+	var best *Backend
+	var bestActive int64
+	for _, b := range backends {
+		if !b.IsHealthy() {
+			continue
+		}
+		// 每个后端只读一次Active，比较和记录用同一个值
+		if active := b.Active(); best == nil || active < bestActive {
+			best, bestActive = b, active
+		}
+	}
+	return best
+}
+
+func (lc *LeastConnections) Name() string { return "LeastConnections" }
+
+// WeightedRoundRobin 平滑加权轮询，零值可以直接使用
+// 每次选择时每个健康后端的当前权重加上自己的Weight，选当前权重最大的，
+// 再把它的当前权重减去所有健康后端的Weight之和。权重5:1:1时选择顺序为a a b a c a a，
+// 而不是a a a a a b c。
+type WeightedRoundRobin struct {
+	mu      sync.Mutex
+	current map[*Backend]int
+}
+
+func (wrr *WeightedRoundRobin) Pick(backends []*Backend) *Backend {
+	wrr.mu.Lock()
+	defer wrr.mu.Unlock()
+	if wrr.current == nil {
+		wrr.current = make(map[*Backend]int)
+	}
+	var best *Backend
+	total := 0
+	seen := make(map[*Backend]bool, len(backends))
+	for _, b := range backends {
+		seen[b] = true
+		if !b.IsHealthy() {
+			continue
+		}
+		w := max(b.Weight, 1)
+		total += w
+		wrr.current[b] += w
+		if best == nil || wrr.current[b] > wrr.current[best] {
+			best = b
+		}
+	}
+	// 已经不在列表中的后端不再保留当前权重，避免map随增删无限增长
+	for b := range wrr.current {
+		if !seen[b] {
+			delete(wrr.current, b)
+		}
+	}
+	if best != nil {
+		wrr.current[best] -= total
+	}
+	return best
+}
+
+func (wrr *WeightedRoundRobin) Name() string { return "WeightedRoundRobin" }
+
+// LoadBalancer 负载均衡器
+type LoadBalancer struct {
+	strategy Strategy
+
+	mu       sync.Mutex                 // 只在增删后端时使用，串行化写者
+	backends atomic.Pointer[[]*Backend] // 只读快照，请求路径只做一次原子加载
+}
+
+func NewLoadBalancer(strategy Strategy) *LoadBalancer {
+	lb := &LoadBalancer{strategy: strategy}
+	lb.backends.Store(&[]*Backend{})
+	return lb
+}
+
+// AddBackend 添加后端，已有相同ID的后端时替换它
+func (lb *LoadBalancer) AddBackend(b *Backend) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	old := *lb.backends.Load()
+	list := make([]*Backend, 0, len(old)+1)
+	for _, cur := range old {
+		if cur.ID != b.ID {
+			list = append(list, cur)
+		}
+	}
+	list = append(list, b)
+	lb.backends.Store(&list)
+}
+
+// RemoveBackend 移除后端，返回后不会再有新请求发给它
+func (lb *LoadBalancer) RemoveBackend(id string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	old := *lb.backends.Load()
+	list := make([]*Backend, 0, len(old))
+	for _, cur := range old {
+		if cur.ID != id {
+			list = append(list, cur)
+		}
+	}
+	lb.backends.Store(&list)
+}
+
+// Backends 当前后端列表的快照
+func (lb *LoadBalancer) Backends() []*Backend {
+	return append([]*Backend(nil), *lb.backends.Load()...)
+}
+
+// Do 用策略选择一个后端并调用fn，返回fn的错误；没有健康的后端时返回ErrNoBackend
+func (lb *LoadBalancer) Do(fn func(b *Backend) error) error {
+	// 快照不会被修改，传给策略时不用复制
+	b := lb.strategy.Pick(*lb.backends.Load())
+	if b == nil {
+		return ErrNoBackend
+	}
+	b.active.Add(1)
+	defer b.active.Add(-1)
+	return fn(b)
+}
+
+func ids(list []*Backend) string {
+	s := make([]string, len(list))
+	for i, b := range list {
+		s[i] = b.ID
+	}
+	return strings.Join(s, " ")
+}
+
+func main() {
+	fmt.Println("=== 负载均衡策略练习 ===")
+
+	// 任务1：轮询
+	fmt.Println("\n任务1：轮询")
+	backends := []*Backend{NewBackend("a", 1), NewBackend("b", 1), NewBackend("c", 1)}
+	rr := &RoundRobin{}
+	var picked []*Backend
+	for i := 0; i < 9; i++ {
+		picked = append(picked, rr.Pick(backends))
+	}
+	fmt.Printf("全部健康: %s\n", ids(picked))
+	backends[1].SetHealthy(false)
+	picked = picked[:0]
+	for i := 0; i < 6; i++ {
+		picked = append(picked, rr.Pick(backends))
+	}
+	fmt.Printf("b不健康:  %s\n", ids(picked))
+	fmt.Println("任务1完成\n")
+
+	// 任务2：最少连接
+	fmt.Println("任务2：最少连接")
+	for _, strategy := range []Strategy{&RoundRobin{}, &LeastConnections{}} {
+		lb := NewLoadBalancer(strategy)
+		// c处理得慢，最少连接会少给它分请求
+		delays := map[string]time.Duration{"a": 10 * time.Millisecond, "b": 20 * time.Millisecond, "c": 100 * time.Millisecond}
+		for _, id := range []string{"a", "b", "c"} {
+			lb.AddBackend(NewBackend(id, 1))
+		}
+		var mu sync.Mutex
+		counts := make(map[string]int)
+		var wg sync.WaitGroup
+		for i := 0; i < 30; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lb.Do(func(b *Backend) error {
+					time.Sleep(delays[b.ID] + time.Duration(rand.Intn(10))*time.Millisecond)
+					mu.Lock()
+					counts[b.ID]++
+					mu.Unlock()
+					return nil
+				})
+			}()
+			time.Sleep(5 * time.Millisecond)
+		}
+		wg.Wait()
+		fmt.Printf("%-16s a=%d b=%d c=%d（c最慢）\n", strategy.Name(), counts["a"], counts["b"], counts["c"])
+	}
+	fmt.Println("任务2完成\n")
+
+	// 任务3：平滑加权轮询
+	fmt.Println("任务3：平滑加权轮询")
+	weighted := []*Backend{NewBackend("a", 5), NewBackend("b", 1), NewBackend("c", 1)}
+	wrr := &WeightedRoundRobin{}
+	picked = picked[:0]
+	for i := 0; i < 14; i++ {
+		picked = append(picked, wrr.Pick(weighted))
+	}
+	fmt.Printf("权重5:1:1: %s\n", ids(picked))
+	fmt.Println("任务3完成\n")
+
+	// 任务4：动态增删后端
+	fmt.Println("任务4：动态增删后端")
+	lb := NewLoadBalancer(&RoundRobin{})
+	lb.AddBackend(NewBackend("a", 1))
+	lb.AddBackend(NewBackend("b", 1))
+	stop := make(chan struct{})
+	var served sync.Map
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				lb.Do(func(b *Backend) error {
+					served.Store(b.ID, true)
+					time.Sleep(time.Millisecond)
+					return nil
+				})
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	lb.AddBackend(NewBackend("c", 1))
+	time.Sleep(50 * time.Millisecond)
+	lb.RemoveBackend("a")
+	fmt.Printf("移除a之后的后端: %s\n", ids(lb.Backends()))
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	var all []string
+	served.Range(func(k, _ any) bool { all = append(all, k.(string)); return true })
+	fmt.Printf("处理过请求的后端数: %d\n", len(all))
+	fmt.Println("任务4完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 各个后端处理能力不同时，轮询会出现什么问题？")
+	fmt.Println("   慢的后端分到和快的一样多的请求，请求在慢的后端上堆积，整体延迟被最慢的后端拖累")
+	fmt.Println("2. 最少连接策略在请求处理时间差异很大时有什么优势？")
+	fmt.Println("   正在处理的请求数反映了后端实际的忙碌程度，处理得慢的后端连接数高，自然少分到新请求")
+	fmt.Println("3. 为什么后端列表适合写时复制，而不是每次请求都加读锁？")
+	fmt.Println("   每个请求都要读列表，增删后端很少；读锁在多核高并发下仍会争用同一个计数器，原子加载快照没有争用")
+	fmt.Println("4. 后端被移除时，正在它上面处理的请求应该怎样处理？")
+	fmt.Println("   让它们处理完（连接排空），只是不再分配新请求；等Active()降到0再真正下线")
+}
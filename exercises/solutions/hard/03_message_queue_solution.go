@@ -0,0 +1,274 @@
+/*
+Golang并发编程练习 - 困难级别（参考答案）
+练习文件：03_message_queue_exercise.go
+练习主题：带重试和死信队列的消息队列
+
+运行方式：go run exercises/solutions/hard/03_message_queue_solution.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueClosed 队列已关闭，不再接收消息
+var ErrQueueClosed = errors.New("queue closed")
+
+// Message 消息
+type Message struct {
+	ID       string
+	Body     string
+	Attempts int // 已投递的次数，第一次调用handler时为1
+}
+
+// Handler 处理消息，返回错误表示需要重试；ctx在Close超时后被取消
+type Handler func(ctx context.Context, msg Message) error
+
+// QueueConfig 队列配置
+type QueueConfig struct {
+	Workers     int                             // 工作者数量
+	MaxAttempts int                             // 每条消息最多投递几次，用完后进入死信队列
+	Backoff     func(attempt int) time.Duration // 第attempt次投递失败后，等待多久再投递；nil表示立即重试
+}
+
+// Stats 队列统计
+type Stats struct {
+	Published int64 // 被接收的消息数
+	Succeeded int64 // 处理成功的消息数
+	Retried   int64 // 重新投递的次数
+	Dead      int64 // 进入死信队列的消息数
+}
+
+// RetryQueue 带重试的消息队列
+type RetryQueue struct {
+	config  QueueConfig
+	handler Handler
+
+	mu         sync.Mutex
+	closed     bool
+	deadLetter []Message
+
+	ready   chan Message       // 交给工作者的消息；所有消息完成后由Close关闭
+	pending sync.WaitGroup     // 已接收但还没成功或进入死信队列的消息
+	workers sync.WaitGroup     // 工作者goroutine
+	ctx     context.Context    // 传给handler，Close超时后取消
+	cancel  context.CancelFunc // 取消ctx
+
+	published, succeeded, retried, dead atomic.Int64
+}
+
+// NewRetryQueue 创建队列并启动config.Workers个工作者
+func NewRetryQueue(config QueueConfig, handler Handler) *RetryQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &RetryQueue{
+		config:  config,
+		handler: handler,
+		ready:   make(chan Message),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for i := 0; i < max(config.Workers, 1); i++ {
+		q.workers.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// Publish 发布消息，没有空闲工作者时等待；队列关闭后返回ErrQueueClosed
+func (q *RetryQueue) Publish(msg Message) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	// 在同一次加锁中登记：Close设置closed之后开始等待pending，不会漏掉这条消息
+	q.pending.Add(1)
+	q.mu.Unlock()
+
+	q.published.Add(1)
+	msg.Attempts = 0
+	// pending不为0时Close不会关闭ready，这里的发送是安全的
+	q.ready <- msg
+	return nil
+}
+
+// worker 从channel中取消息处理，channel关闭后退出
+func (q *RetryQueue) worker() {
+	defer q.workers.Done()
+	for msg := range q.ready {
+		if err := q.ctx.Err(); err != nil {
+			// Close已经超时，不再调用handler
+			q.toDeadLetter(msg)
+			continue
+		}
+		msg.Attempts++
+		if err := q.handler(q.ctx, msg); err == nil {
+			q.succeeded.Add(1)
+			q.pending.Done()
+			continue
+		}
+		if msg.Attempts >= max(q.config.MaxAttempts, 1) || q.ctx.Err() != nil {
+			q.toDeadLetter(msg)
+			continue
+		}
+		q.retried.Add(1)
+		// 退避等待放在单独的goroutine里，工作者立即去处理下一条消息
+		go q.retryLater(msg)
+	}
+}
+
+// retryLater 等待退避时间后把消息放回channel；等待期间ctx取消则进入死信队列
+func (q *RetryQueue) retryLater(msg Message) {
+	var d time.Duration
+	if q.config.Backoff != nil {
+		d = q.config.Backoff(msg.Attempts)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-q.ctx.Done():
+		q.toDeadLetter(msg)
+		return
+	}
+	// 这条消息还没完成，pending不为0，ready不会被关闭；工作者在ctx取消后仍然会取走消息
+	q.ready <- msg
+}
+
+// toDeadLetter 消息进入死信队列，这条消息完成
+func (q *RetryQueue) toDeadLetter(msg Message) {
+	q.mu.Lock()
+	q.deadLetter = append(q.deadLetter, msg)
+	q.mu.Unlock()
+	q.dead.Add(1)
+	q.pending.Done()
+}
+
+// DeadLetters 死信队列中的消息（副本）
+func (q *RetryQueue) DeadLetters() []Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Message(nil), q.deadLetter...)
+}
+
+// Stats 统计信息
+func (q *RetryQueue) Stats() Stats {
+	return Stats{
+		Published: q.published.Load(),
+		Succeeded: q.succeeded.Load(),
+		Retried:   q.retried.Load(),
+		Dead:      q.dead.Load(),
+	}
+}
+
+// Close 关闭队列：拒绝新消息，等待所有已接收的消息成功或进入死信队列，然后停止工作者。
+// ctx到期时取消handler的ctx，待重试的消息不再投递，直接进入死信队列，返回ctx.Err()。
+func (q *RetryQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	q.closed = true
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.pending.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		// 取消后handler应尽快返回，待重试的消息进入死信队列，pending很快归零
+		q.cancel()
+		<-done
+	}
+	// 没有未完成的消息，也不会再有Publish和重试，可以安全地关闭channel让工作者退出
+	close(q.ready)
+	q.workers.Wait()
+	q.cancel()
+	return err
+}
+
+func main() {
+	fmt.Println("=== 带重试的消息队列练习 ===")
+
+	// 任务1：基本投递
+	fmt.Println("\n任务1：基本投递")
+	q := NewRetryQueue(QueueConfig{Workers: 3, MaxAttempts: 1}, func(ctx context.Context, msg Message) error {
+		fmt.Printf("处理消息 %s: %s\n", msg.ID, msg.Body)
+		return nil
+	})
+	for i := 1; i <= 10; i++ {
+		q.Publish(Message{ID: strconv.Itoa(i), Body: fmt.Sprintf("内容%d", i)})
+	}
+	q.Close(context.Background())
+	fmt.Printf("统计: %+v\n", q.Stats())
+	fmt.Println("任务1完成\n")
+
+	// 任务2：重试和死信
+	fmt.Println("任务2：重试和死信")
+	q = NewRetryQueue(QueueConfig{
+		Workers:     2,
+		MaxAttempts: 3,
+		Backoff:     func(attempt int) time.Duration { return time.Duration(attempt) * 50 * time.Millisecond },
+	}, func(ctx context.Context, msg Message) error {
+		n, _ := strconv.Atoi(msg.ID)
+		fail := msg.ID == "poison" || (n%2 == 1 && msg.Attempts < 3)
+		fmt.Printf("消息 %s 第%d次投递，失败=%v\n", msg.ID, msg.Attempts, fail)
+		if fail {
+			return errors.New("处理失败")
+		}
+		return nil
+	})
+	for _, id := range []string{"1", "2", "3", "poison"} {
+		q.Publish(Message{ID: id})
+	}
+	q.Close(context.Background())
+	fmt.Printf("统计: %+v\n", q.Stats())
+	for _, m := range q.DeadLetters() {
+		fmt.Printf("死信: %s（投递 %d 次）\n", m.ID, m.Attempts)
+	}
+	fmt.Println("任务2完成\n")
+
+	// 任务3：关闭超时
+	fmt.Println("任务3：关闭超时")
+	q = NewRetryQueue(QueueConfig{Workers: 1, MaxAttempts: 3}, func(ctx context.Context, msg Message) error {
+		select {
+		case <-time.After(time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	q.Publish(Message{ID: "slow"})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	start := time.Now()
+	err := q.Close(ctx)
+	cancel()
+	fmt.Printf("Close用时 %v，返回: %v\n", time.Since(start).Round(10*time.Millisecond), err)
+	fmt.Printf("死信: %d 条\n", len(q.DeadLetters()))
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么退避等待不能在工作者goroutine里time.Sleep？")
+	fmt.Println("   等待期间这个工作者不能处理其他消息，一批消息同时失败时所有工作者都在睡眠，健康的消息也被拖住")
+	fmt.Println("2. 什么时候关闭工作者的channel才是安全的？")
+	fmt.Println("   不再有发送者的时候：已拒绝新的Publish，并且所有已接收的消息都成功或进入了死信队列，没有待重试的消息")
+	fmt.Println("3. 同一条消息可能被处理多次，handler需要满足什么性质？")
+	fmt.Println("   幂等：handler可能在完成副作用之后才失败，重试时要能识别已经处理过的部分，例如按消息ID去重")
+	fmt.Println("4. 死信队列中的消息之后应该怎样处理？")
+	fmt.Println("   告警并人工或自动排查原因，修复后重新投递；不能静默丢弃，它们通常是bug或脏数据的信号")
+}
@@ -0,0 +1,381 @@
+/*
+Golang并发编程练习 - 困难级别（参考答案）
+练习文件：04_connection_pool_exercise.go
+练习主题：带泄漏检测的连接池
+
+运行方式：go run exercises/solutions/hard/04_connection_pool_solution.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Conn 模拟的数据库连接（已实现）
+type Conn struct {
+	ID     int
+	closed atomic.Bool
+}
+
+// Close 关闭连接；连接出错时使用者也可以直接关闭它，再交给Release
+func (c *Conn) Close() { c.closed.Store(true) }
+
+func (c *Conn) IsClosed() bool { return c.closed.Load() }
+
+// dialed 已拨号的次数，也用作连接ID
+var dialed atomic.Int64
+
+// dial 模拟建立连接（已实现）
+func dial() (*Conn, error) {
+	time.Sleep(10 * time.Millisecond) // 模拟建立连接的耗时
+	return &Conn{ID: int(dialed.Add(1))}, nil
+}
+
+var (
+	// ErrPoolClosed 连接池已关闭
+	ErrPoolClosed = errors.New("pool closed")
+	// ErrNotBorrowed 归还的连接不是从这个池借出的，或者已经归还过
+	ErrNotBorrowed = errors.New("connection not borrowed from pool")
+)
+
+// PoolConfig 连接池配置
+type PoolConfig struct {
+	MaxOpen     int           // 最多同时打开的连接数（包括正在拨号的），<=0时按1处理
+	MaxIdle     int           // 最多保留的空闲连接数，多出的连接归还时关闭；<=0时等于MaxOpen
+	LeakTimeout time.Duration // 借出超过这个时间没有归还视为泄漏，<=0时不检测
+}
+
+// Leak 疑似泄漏的连接
+type Leak struct {
+	ConnID int
+	Held   time.Duration // 已经借出多久
+	Caller string        // 调用Acquire的位置，格式为"文件名:行号"
+}
+
+// PoolStats 连接池统计
+type PoolStats struct {
+	Open    int   // 已打开的连接数（包括正在拨号的）
+	Idle    int   // 空闲连接数
+	InUse   int   // 借出的连接数
+	Waiting int   // 正在等待连接的Acquire数
+	Waits   int64 // 累计需要等待的Acquire次数
+}
+
+// borrow 一次借出的记录
+type borrow struct {
+	at     time.Time
+	caller string
+}
+
+// Pool 连接池
+type Pool struct {
+	config PoolConfig
+	dial   func() (*Conn, error)
+	now    func() time.Time // 当前时间，评分时替换成可以手动拨动的时钟
+
+	mu       sync.Mutex
+	closed   bool
+	open     int              // 已打开和正在拨号的连接数
+	idle     []*Conn          // 空闲连接，后进先出
+	borrowed map[*Conn]borrow // 借出的连接
+	waiters  []chan *Conn     // 等待者，先到先得；收到nil表示分到了名额，需要自己拨号
+	waits    int64            // 累计等待次数
+}
+
+// NewPool 创建连接池，dial用来建立新连接
+func NewPool(config PoolConfig, dial func() (*Conn, error)) *Pool {
+	config.MaxOpen = max(config.MaxOpen, 1)
+	if config.MaxIdle <= 0 {
+		config.MaxIdle = config.MaxOpen
+	}
+	return &Pool{
+		config:   config,
+		dial:     dial,
+		now:      time.Now,
+		borrowed: make(map[*Conn]borrow),
+	}
+}
+
+// Acquire 借出一个连接：优先复用空闲连接，其次拨号，都不行时等待归还；ctx到期返回ctx.Err()
+func (p *Pool) Acquire(ctx context.Context) (*Conn, error) {
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(1); ok {
+		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.lend(c, caller)
+		p.mu.Unlock()
+		return c, nil
+	}
+	if p.open < p.config.MaxOpen {
+		p.open++
+		p.mu.Unlock()
+		return p.dialConn(caller)
+	}
+	// 缓冲为1：交付连接的一方持有锁，不能阻塞在发送上
+	w := make(chan *Conn, 1)
+	p.waiters = append(p.waiters, w)
+	p.waits++
+	p.mu.Unlock()
+
+	select {
+	case c, ok := <-w:
+		if !ok {
+			return nil, ErrPoolClosed
+		}
+		if c == nil {
+			return p.dialConn(caller)
+		}
+		p.mu.Lock()
+		p.lend(c, caller)
+		p.mu.Unlock()
+		return c, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i, cur := range p.waiters {
+			if cur == w {
+				p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// 已经不在队列里：连接（或名额）在ctx到期的同时交给了自己，要还回池中
+		if c, ok := <-w; ok {
+			if c == nil {
+				p.freeSlot()
+			} else {
+				p.put(c)
+			}
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// dialConn 在已经占用名额的情况下拨号，失败时归还名额
+func (p *Pool) dialConn(caller string) (*Conn, error) {
+	c, err := p.dial()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.freeSlot()
+		return nil, err
+	}
+	if p.closed {
+		// 拨号期间连接池被关闭
+		p.open--
+		c.Close()
+		return nil, ErrPoolClosed
+	}
+	p.lend(c, caller)
+	return c, nil
+}
+
+// lend 记录借出，调用者持有锁
+func (p *Pool) lend(c *Conn, caller string) {
+	p.borrowed[c] = borrow{at: p.now(), caller: caller}
+}
+
+// put 把可用的连接交给最早的等待者，或者放回空闲列表，调用者持有锁
+func (p *Pool) put(c *Conn) {
+	if p.closed {
+		p.open--
+		c.Close()
+		return
+	}
+	if len(p.waiters) > 0 {
+		w := p.waiters[0]
+		p.waiters = p.waiters[1:]
+		w <- c
+		return
+	}
+	if len(p.idle) < p.config.MaxIdle {
+		p.idle = append(p.idle, c)
+		return
+	}
+	p.open--
+	c.Close()
+}
+
+// freeSlot 一个连接名额空出来了：有等待者时让它去拨号，否则减少打开数，调用者持有锁
+func (p *Pool) freeSlot() {
+	if len(p.waiters) > 0 {
+		w := p.waiters[0]
+		p.waiters = p.waiters[1:]
+		w <- nil
+		return
+	}
+	p.open--
+}
+
+// Release 归还连接；已关闭的连接不放回池中并释放名额；Close之后归还的连接被关闭
+func (p *Pool) Release(c *Conn) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.borrowed[c]; !ok {
+		return ErrNotBorrowed
+	}
+	delete(p.borrowed, c)
+	if c.IsClosed() {
+		p.freeSlot()
+	} else {
+		p.put(c)
+	}
+	return nil
+}
+
+// Leaks 借出超过LeakTimeout还没有归还的连接，按ConnID排序
+func (p *Pool) Leaks() []Leak {
+	if p.config.LeakTimeout <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	var leaks []Leak
+	for c, b := range p.borrowed {
+		if held := now.Sub(b.at); held >= p.config.LeakTimeout {
+			leaks = append(leaks, Leak{ConnID: c.ID, Held: held, Caller: b.caller})
+		}
+	}
+	sort.Slice(leaks, func(i, j int) bool { return leaks[i].ConnID < leaks[j].ConnID })
+	return leaks
+}
+
+// Stats 统计信息
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		Open:    p.open,
+		Idle:    len(p.idle),
+		InUse:   len(p.borrowed),
+		Waiting: len(p.waiters),
+		Waits:   p.waits,
+	}
+}
+
+// Close 关闭连接池：关闭空闲连接，等待中的Acquire返回ErrPoolClosed。
+// 借出的连接归还时再关闭。重复调用返回ErrPoolClosed。
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	p.closed = true
+	for _, c := range p.idle {
+		c.Close()
+	}
+	p.open -= len(p.idle)
+	p.idle = nil
+	for _, w := range p.waiters {
+		close(w)
+	}
+	p.waiters = nil
+	return nil
+}
+
+func main() {
+	fmt.Println("=== 带泄漏检测的连接池练习 ===")
+
+	// 任务1：复用和限制连接数
+	fmt.Println("\n任务1：复用和限制连接数")
+	pool := NewPool(PoolConfig{MaxOpen: 3}, dial)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				c, err := pool.Acquire(context.Background())
+				if err != nil {
+					fmt.Println("借出失败:", err)
+					return
+				}
+				time.Sleep(20 * time.Millisecond)
+				pool.Release(c)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("50次借出共拨号 %d 次\n", dialed.Load())
+	fmt.Printf("统计: %+v\n", pool.Stats())
+	pool.Close()
+	fmt.Println("任务1完成\n")
+
+	// 任务2：等待超时
+	fmt.Println("任务2：等待超时")
+	pool = NewPool(PoolConfig{MaxOpen: 1}, dial)
+	held, _ := pool.Acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	start := time.Now()
+	_, err := pool.Acquire(ctx)
+	cancel()
+	fmt.Printf("等待 %v 后返回: %v\n", time.Since(start).Round(10*time.Millisecond), err)
+	pool.Release(held)
+	c, err := pool.Acquire(context.Background())
+	fmt.Printf("归还后再借出: 连接%d，错误: %v\n", c.ID, err)
+	pool.Release(c)
+	pool.Close()
+	fmt.Println("任务2完成\n")
+
+	// 任务3：泄漏检测
+	fmt.Println("任务3：泄漏检测")
+	pool = NewPool(PoolConfig{MaxOpen: 2, LeakTimeout: 50 * time.Millisecond}, dial)
+	returned, _ := pool.Acquire(context.Background())
+	forgotten, _ := pool.Acquire(context.Background())
+	pool.Release(returned)
+	time.Sleep(100 * time.Millisecond)
+	for _, leak := range pool.Leaks() {
+		fmt.Printf("疑似泄漏: 连接%d 已借出 %v，借出位置 %s\n", leak.ConnID, leak.Held.Round(10*time.Millisecond), leak.Caller)
+	}
+	pool.Release(forgotten)
+	fmt.Printf("归还后泄漏数: %d\n", len(pool.Leaks()))
+	pool.Close()
+	fmt.Println("任务3完成\n")
+
+	// 任务4：关闭
+	fmt.Println("任务4：关闭")
+	pool = NewPool(PoolConfig{MaxOpen: 1}, dial)
+	held, _ = pool.Acquire(context.Background())
+	waitErr := make(chan error)
+	go func() {
+		_, err := pool.Acquire(context.Background())
+		waitErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	fmt.Println("Close:", pool.Close())
+	fmt.Println("等待者收到:", <-waitErr)
+	pool.Release(held)
+	fmt.Printf("归还后连接已关闭: %v，统计: %+v\n", held.IsClosed(), pool.Stats())
+	fmt.Println("任务4完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么拨号不能在持有锁的时候进行？")
+	fmt.Println("   拨号可能要几十毫秒甚至超时几秒，期间所有归还、复用空闲连接的操作都被挡住，连接池退化成串行")
+	fmt.Println("2. 等待者超时和连接交给它几乎同时发生时，会出现什么问题？")
+	fmt.Println("   等待者已经返回错误，交给它的连接没人使用也没人归还，名额永久少一个；超时后要检查并把连接还回池中")
+	fmt.Println("3. 连接泄漏在生产环境中通常有什么表现？")
+	fmt.Println("   池中可用连接越来越少，请求在Acquire上排队超时，而数据库端的活跃连接并不多；记录借出位置能直接定位忘记归还的代码")
+	fmt.Println("4. 为什么空闲连接数要有上限，而不是保留所有打开过的连接？")
+	fmt.Println("   突发流量过后大量连接长期空闲，占用数据库的连接数和内存；保留少量空闲连接就能满足常态流量")
+}
@@ -0,0 +1,204 @@
+/*
+Golang并发编程练习 - 中等级别（参考答案）
+练习文件：01_producer_consumer_exercise.go
+练习主题：生产者-消费者模式
+
+运行方式：go run exercises/solutions/medium/01_producer_consumer_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Product 产品结构
+type Product struct {
+	ID       int
+	Name     string
+	Price    float64
+	Category string
+}
+
+// Producer 生产者结构
+type Producer struct {
+	ID   string
+	Type string
+}
+
+// Consumer 消费者结构
+type Consumer struct {
+	ID             string
+	ProcessingTime time.Duration
+}
+
+// nextProductID 所有生产者共用的ID计数器，保证多个生产者之间的ID也不重复
+var nextProductID atomic.Int64
+
+// Produce 生产count个产品到channel。
+// 不关闭channel：多个生产者共用一个channel时，只有协调者知道什么时候全部生产完
+func (p *Producer) Produce(products chan<- Product, count int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for i := 0; i < count; i++ {
+		id := int(nextProductID.Add(1))
+		product := Product{
+			ID:       id,
+			Name:     fmt.Sprintf("%s-%s-%d", p.Type, p.ID, i+1),
+			Price:    float64(10+rand.Intn(90)) + 0.99,
+			Category: p.Type,
+		}
+		products <- product
+		fmt.Printf("生产者 %s 生产: #%d %s\n", p.ID, product.ID, product.Name)
+		time.Sleep(time.Duration(rand.Intn(20)) * time.Millisecond)
+	}
+}
+
+// Consume 消费产品直到channel被关闭且取完
+func (c *Consumer) Consume(products <-chan Product, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for product := range products {
+		time.Sleep(c.ProcessingTime)
+		fmt.Printf("消费者 %s 消费: #%d %s (%.2f)\n", c.ID, product.ID, product.Name, product.Price)
+	}
+}
+
+// run 启动生产者和消费者：所有生产者完成后关闭channel，再等所有消费者退出
+func run(buffer int, producers []*Producer, counts []int, consumers []*Consumer) {
+	products := make(chan Product, buffer)
+	var prodWG, consWG sync.WaitGroup
+	for _, c := range consumers {
+		consWG.Add(1)
+		go c.Consume(products, &consWG)
+	}
+	for i, p := range producers {
+		prodWG.Add(1)
+		go p.Produce(products, counts[i], &prodWG)
+	}
+	prodWG.Wait()
+	close(products)
+	consWG.Wait()
+}
+
+// countingConsumer 统计每个消费者处理的数量
+func countingConsumer(c *Consumer, products <-chan Product, counts *sync.Map, wg *sync.WaitGroup) {
+	defer wg.Done()
+	n := 0
+	for range products {
+		time.Sleep(c.ProcessingTime)
+		n++
+	}
+	counts.Store(c.ID, n)
+}
+
+func main() {
+	fmt.Println("=== 生产者-消费者模式练习 ===")
+
+	// 任务1：单生产者单消费者
+	fmt.Println("\n任务1：单生产者单消费者")
+	run(5,
+		[]*Producer{{ID: "P1", Type: "Electronics"}}, []int{10},
+		[]*Consumer{{ID: "C1", ProcessingTime: 20 * time.Millisecond}})
+	fmt.Println("任务1完成")
+
+	// 任务2：多生产者单消费者
+	fmt.Println("\n任务2：多生产者单消费者")
+	run(10,
+		[]*Producer{{ID: "P1", Type: "Electronics"}, {ID: "P2", Type: "Books"}, {ID: "P3", Type: "Food"}}, []int{8, 8, 8},
+		[]*Consumer{{ID: "C1", ProcessingTime: 5 * time.Millisecond}})
+	fmt.Println("任务2完成")
+
+	// 任务3：单生产者多消费者
+	fmt.Println("\n任务3：单生产者多消费者")
+	run(15,
+		[]*Producer{{ID: "P1", Type: "Clothing"}}, []int{20},
+		[]*Consumer{
+			{ID: "C1", ProcessingTime: 10 * time.Millisecond},
+			{ID: "C2", ProcessingTime: 20 * time.Millisecond},
+			{ID: "C3", ProcessingTime: 30 * time.Millisecond},
+			{ID: "C4", ProcessingTime: 40 * time.Millisecond},
+		})
+	fmt.Println("任务3完成")
+
+	// 任务4：多生产者多消费者
+	fmt.Println("\n任务4：多生产者多消费者系统")
+	products := make(chan Product, 20)
+	var prodWG, consWG sync.WaitGroup
+	var consumed sync.Map
+	produced := 0
+	for i, typ := range []string{"Electronics", "Books", "Food", "Toys", "Sports"} {
+		n := 5 + rand.Intn(11)
+		produced += n
+		prodWG.Add(1)
+		go (&Producer{ID: fmt.Sprintf("P%d", i+1), Type: typ}).Produce(products, n, &prodWG)
+	}
+	speeds := []time.Duration{5 * time.Millisecond, 15 * time.Millisecond, 30 * time.Millisecond}
+	for i, d := range speeds {
+		consWG.Add(1)
+		go countingConsumer(&Consumer{ID: fmt.Sprintf("C%d", i+1), ProcessingTime: d}, products, &consumed, &consWG)
+	}
+	prodWG.Wait()
+	close(products)
+	consWG.Wait()
+	total := 0
+	fmt.Printf("共生产 %d 个产品\n", produced)
+	for i := range speeds {
+		id := fmt.Sprintf("C%d", i+1)
+		n, _ := consumed.Load(id)
+		total += n.(int)
+		fmt.Printf("消费者 %s（每个 %v）处理了 %d 个\n", id, speeds[i], n)
+	}
+	fmt.Printf("共消费 %d 个产品，处理得快的消费者自然取走更多\n", total)
+	fmt.Println("任务4完成")
+
+	// 任务5：带优先级的生产消费
+	fmt.Println("\n任务5：优先级处理（挑战任务）")
+	high, low := make(chan Product, 10), make(chan Product, 10)
+	for i := 1; i <= 5; i++ {
+		low <- Product{ID: i, Name: fmt.Sprintf("普通订单-%d", i)}
+		high <- Product{ID: 100 + i, Name: fmt.Sprintf("加急订单-%d", i)}
+	}
+	close(high)
+	close(low)
+	for high != nil || low != nil {
+		// 先非阻塞地检查高优先级channel，没有数据时再同时等待两个
+		select {
+		case p, ok := <-high:
+			if !ok {
+				high = nil
+				continue
+			}
+			fmt.Printf("处理: %s\n", p.Name)
+			continue
+		default:
+		}
+		select {
+		case p, ok := <-high:
+			if !ok {
+				high = nil
+				continue
+			}
+			fmt.Printf("处理: %s\n", p.Name)
+		case p, ok := <-low:
+			if !ok {
+				low = nil
+				continue
+			}
+			fmt.Printf("处理: %s\n", p.Name)
+		}
+	}
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 缓冲区大小如何影响系统性能？")
+	fmt.Println("   缓冲吸收生产和消费速度的短时波动；太小时双方频繁互相等待，太大时只是把积压藏起来并占用内存")
+	fmt.Println("2. 如何确保所有生产者完成后正确关闭channel？")
+	fmt.Println("   生产者不关闭channel，由协调者在生产者的WaitGroup.Wait()返回后关闭一次")
+	fmt.Println("3. 多消费者竞争时，如何保证负载均衡？")
+	fmt.Println("   所有消费者从同一个channel取数据，空闲的消费者先取到，天然按处理能力分配")
+	fmt.Println("4. 在什么情况下需要多个channel？")
+	fmt.Println("   需要区分优先级、按类别路由，或者不同消费者只处理特定类型的产品时")
+}
@@ -0,0 +1,176 @@
+/*
+Golang并发编程练习 - 中等级别（参考答案）
+练习文件：03_rate_limiter_exercise.go
+练习主题：令牌桶限流
+
+运行方式：go run exercises/solutions/medium/03_rate_limiter_solution.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock 时钟，评分时替换成手动拨动的假时钟
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock 系统时钟（已实现）
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// TokenBucket 令牌桶：每秒补充rate个令牌，最多存burst个，新建时是满的
+type TokenBucket struct {
+	rate  float64
+	burst int
+	clock Clock
+
+	mu     sync.Mutex
+	tokens float64   // 可以为负：已经被Wait预约的将来的令牌
+	last   time.Time // 上次补充的时间
+}
+
+// NewTokenBucket 创建令牌桶，clock为nil时使用RealClock
+func NewTokenBucket(rate float64, burst int, clock Clock) *TokenBucket {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return &TokenBucket{rate: rate, burst: burst, clock: clock, tokens: float64(burst), last: clock.Now()}
+}
+
+// refill 按经过的时间补充令牌，调用方持有锁
+func (tb *TokenBucket) refill() {
+	now := tb.clock.Now()
+	if elapsed := now.Sub(tb.last).Seconds(); elapsed > 0 {
+		tb.tokens = min(tb.tokens+elapsed*tb.rate, float64(tb.burst))
+	}
+	tb.last = now
+}
+
+// Allow 有令牌时取走一个并返回true，否则立即返回false
+func (tb *TokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill()
+	if tb.tokens >= 1 {
+		tb.tokens--
+		return true
+	}
+	return false
+}
+
+// Wait 等到取得一个令牌后返回nil；ctx先结束时返回ctx.Err()，这次没有取得令牌
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tb.mu.Lock()
+	tb.refill()
+	tb.tokens-- // 先预约，后来的请求排在后面
+	if tb.tokens >= 0 {
+		tb.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+	tb.mu.Unlock()
+
+	select {
+	case <-tb.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		tb.mu.Lock()
+		tb.refill()
+		tb.tokens = min(tb.tokens+1, float64(tb.burst)) // 归还预约
+		tb.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// KeyedLimiter 按key限流，例如每个用户、每个IP各自一个令牌桶
+type KeyedLimiter struct {
+	rate  float64
+	burst int
+	clock Clock
+
+	mu      sync.Mutex
+	buckets map[string]*TokenBucket
+}
+
+// NewKeyedLimiter 创建按key限流的限流器，每个key的令牌桶参数相同
+func NewKeyedLimiter(rate float64, burst int, clock Clock) *KeyedLimiter {
+	return &KeyedLimiter{rate: rate, burst: burst, clock: clock, buckets: make(map[string]*TokenBucket)}
+}
+
+// Allow key对应的令牌桶有令牌时返回true；第一次见到的key创建一个满的令牌桶
+func (kl *KeyedLimiter) Allow(key string) bool {
+	kl.mu.Lock()
+	tb, ok := kl.buckets[key]
+	if !ok {
+		tb = NewTokenBucket(kl.rate, kl.burst, kl.clock)
+		kl.buckets[key] = tb
+	}
+	kl.mu.Unlock()
+	return tb.Allow()
+}
+
+func main() {
+	fmt.Println("=== 令牌桶限流练习 ===")
+
+	// 任务1：突发与补充
+	fmt.Println("\n任务1：突发与补充")
+	tb := NewTokenBucket(10, 5, nil)
+	for i := 1; i <= 8; i++ {
+		fmt.Printf("请求%d: %v\n", i, tb.Allow())
+	}
+	time.Sleep(200 * time.Millisecond)
+	fmt.Println("200ms后:")
+	for i := 1; i <= 3; i++ {
+		fmt.Printf("请求%d: %v\n", i, tb.Allow())
+	}
+	fmt.Println("任务1完成\n")
+
+	// 任务2：等待令牌
+	fmt.Println("任务2：等待令牌")
+	tb = NewTokenBucket(5, 1, nil)
+	start := time.Now()
+	for i := 1; i <= 5; i++ {
+		tb.Wait(context.Background())
+		fmt.Printf("第%d次Wait返回: %v\n", i, time.Since(start).Round(10*time.Millisecond))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err := tb.Wait(ctx)
+	cancel()
+	fmt.Printf("100ms超时的Wait: %v（DeadlineExceeded: %v）\n", err, errors.Is(err, context.DeadlineExceeded))
+	fmt.Println("任务2完成\n")
+
+	// 任务3：按用户限流
+	fmt.Println("任务3：按用户限流")
+	kl := NewKeyedLimiter(1, 2, nil)
+	for i := 1; i <= 3; i++ {
+		fmt.Printf("alice请求%d: %v\n", i, kl.Allow("alice"))
+	}
+	fmt.Printf("bob请求1: %v\n", kl.Allow("bob"))
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么不用后台goroutine每隔1/rate秒放一个令牌？")
+	fmt.Println("   每个令牌桶一个goroutine和计时器，按key限流时开销随key数增长；按经过的时间计算结果相同，只在调用时花一点算术")
+	fmt.Println("2. Wait为什么要先减令牌再等待，而不是等到有令牌时再取？")
+	fmt.Println("   先减令牌就是排队：每个等待者知道自己要等多久，醒来不用再抢；等有令牌再取会让多个等待者同时醒来竞争，先来的不一定先得到")
+	fmt.Println("3. KeyedLimiter的map会一直增长，应该怎样清理长期不用的key？")
+	fmt.Println("   记录每个key最后使用的时间，定期删除空闲超过一段时间的key；空闲足够久的令牌桶一定是满的，删掉再新建没有区别")
+	fmt.Println("4. 多台服务器共同限流时，单机令牌桶还够用吗？")
+	fmt.Println("   不够，每台各自限流时总量是单机限额乘以机器数；需要把令牌数放在Redis等共享存储里原子地扣减，或者按机器数分配限额")
+}
@@ -0,0 +1,255 @@
+/*
+Golang并发编程练习 - 中等级别（参考答案）
+练习文件：05_context_cancellation_exercise.go
+练习主题：Context取消传播、超时预算与errgroup
+
+运行方式：go run exercises/solutions/medium/05_context_cancellation_solution.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clock 时钟，评分时替换成手动拨动的假时钟
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock 系统时钟（已实现）
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// ErrBudgetExceeded 时间预算用完；errors.Is(ErrBudgetExceeded, context.DeadlineExceeded)为true
+var ErrBudgetExceeded = fmt.Errorf("time budget exceeded: %w", context.DeadlineExceeded)
+
+// budgetCtx 带截止时间的ctx；取消由内嵌的ctx负责，这里只报告按clock计算的截止时间
+type budgetCtx struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *budgetCtx) Deadline() (time.Time, bool) { return c.deadline, true }
+
+// WithBudget 派生一个最多持续budget的ctx，时间按clock计算。
+// 到期时ctx被取消，context.Cause返回ErrBudgetExceeded；父ctx被取消时随之取消。
+// 父ctx的截止时间更早时沿用父ctx的截止时间。
+func WithBudget(parent context.Context, clock Clock, budget time.Duration) (context.Context, context.CancelFunc) {
+	deadline := clock.Now().Add(budget)
+	if d, ok := parent.Deadline(); ok && !d.After(deadline) {
+		// 父ctx的预算更紧：它到期时会取消，子ctx随之取消，不需要再计时
+		return context.WithCancel(parent)
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	if budget <= 0 {
+		cancel(ErrBudgetExceeded)
+		return &budgetCtx{ctx, deadline}, func() { cancel(context.Canceled) }
+	}
+	// 返回前登记计时：调用者拿到ctx之后拨动时钟，计时一定已经开始
+	timer := clock.After(budget)
+	go func() {
+		select {
+		case <-timer:
+			cancel(ErrBudgetExceeded)
+		case <-ctx.Done():
+			// 提前取消，计时goroutine随之退出
+		}
+	}()
+	return &budgetCtx{ctx, deadline}, func() { cancel(context.Canceled) }
+}
+
+// Step 顺序执行的一步
+type Step struct {
+	Name    string
+	Timeout time.Duration // 这一步最多用多久，不超过ctx剩余的时间
+	Run     func(ctx context.Context) error
+}
+
+// RunSteps 依次执行steps，每一步的ctx由WithBudget(ctx, clock, step.Timeout)派生。
+// 某一步返回错误，或者开始某一步之前ctx已经结束时，停止并返回"步骤名: 原因"。
+func RunSteps(ctx context.Context, clock Clock, steps []Step) error {
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", step.Name, context.Cause(ctx))
+		}
+		stepCtx, cancel := WithBudget(ctx, clock, step.Timeout)
+		err := step.Run(stepCtx)
+		// 每一步结束都要取消，否则计时goroutine要等到预算用完才退出
+		cancel()
+		if err != nil {
+			return fmt.Errorf("%s: %w", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// Group 一组协同工作的goroutine，零值可以直接使用（不取消任何ctx）
+type Group struct {
+	cancel func(error)
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext 返回新的Group和派生的ctx：第一个返回错误的任务以这个错误为原因取消ctx，Wait返回时ctx也被取消
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go 在新的goroutine中执行f
+func (g *Group) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				if g.cancel != nil {
+					g.cancel(err)
+				}
+			})
+		}
+	}()
+}
+
+// Wait 等待所有任务结束，返回第一个错误
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	return g.err
+}
+
+// Fetcher 抓取一个url
+type Fetcher func(ctx context.Context, url string) (string, error)
+
+// FetchAll 并发抓取urls，同时进行的抓取不超过limit个，总用时不超过budget，结果按urls的顺序返回。
+// 任一抓取失败时取消其余抓取、不再开始新的抓取，等已开始的抓取都返回后
+// 返回第一个错误，格式为"fetch <url>: <原因>"。
+func FetchAll(ctx context.Context, clock Clock, urls []string, limit int, budget time.Duration, fetch Fetcher) ([]string, error) {
+	ctx, cancel := WithBudget(ctx, clock, budget)
+	defer cancel()
+	g, ctx := WithContext(ctx)
+
+	results := make([]string, len(urls))
+	sem := make(chan struct{}, max(limit, 1))
+	var stopped error
+	for i, url := range urls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		// 两个case同时就绪时select随机选择，拿到名额之后还要再检查一次
+		if ctx.Err() != nil {
+			stopped = context.Cause(ctx)
+			break
+		}
+		i, url := i, url
+		g.Go(func() error {
+			defer func() { <-sem }()
+			body, err := fetch(ctx, url)
+			if err != nil {
+				return fmt.Errorf("fetch %s: %w", url, err)
+			}
+			results[i] = body
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if stopped != nil {
+		// 预算用完或父ctx取消时，正在进行的抓取恰好都成功了，剩下的没有开始
+		return nil, stopped
+	}
+	return results, nil
+}
+
+// sleepFetch 模拟抓取：按url长度耗时，以"bad"开头的url失败
+func sleepFetch(ctx context.Context, url string) (string, error) {
+	select {
+	case <-time.After(time.Duration(len(url)) * 20 * time.Millisecond):
+	case <-ctx.Done():
+		fmt.Printf("  %s 被取消: %v\n", url, context.Cause(ctx))
+		return "", context.Cause(ctx)
+	}
+	if strings.HasPrefix(url, "bad") {
+		return "", errors.New("500 internal server error")
+	}
+	fmt.Printf("  %s 完成\n", url)
+	return "<" + url + ">", nil
+}
+
+func main() {
+	fmt.Println("=== Context取消与errgroup练习 ===")
+	clock := RealClock{}
+
+	// 任务1：取消传播和时间预算
+	fmt.Println("\n任务1：取消传播和时间预算")
+	request, cancelRequest := WithBudget(context.Background(), clock, 300*time.Millisecond)
+	db, cancelDB := WithBudget(request, clock, time.Second)
+	deadline, _ := db.Deadline()
+	fmt.Printf("子ctx申请1秒，实际截止时间还剩 %v\n", time.Until(deadline).Round(100*time.Millisecond))
+	<-db.Done()
+	fmt.Printf("子ctx结束: %v\n", context.Cause(db))
+	cancelDB()
+	cancelRequest()
+	fmt.Println("任务1完成\n")
+
+	// 任务2：分步骤的超时预算
+	fmt.Println("任务2：分步骤的超时预算")
+	total, cancelTotal := WithBudget(context.Background(), clock, 250*time.Millisecond)
+	work := func(d time.Duration) func(context.Context) error {
+		return func(ctx context.Context) error {
+			select {
+			case <-time.After(d):
+				return nil
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		}
+	}
+	err := RunSteps(total, clock, []Step{
+		{Name: "鉴权", Timeout: 100 * time.Millisecond, Run: work(50 * time.Millisecond)},
+		{Name: "查询", Timeout: 150 * time.Millisecond, Run: work(100 * time.Millisecond)},
+		{Name: "渲染", Timeout: 200 * time.Millisecond, Run: work(150 * time.Millisecond)},
+	})
+	cancelTotal()
+	fmt.Printf("RunSteps返回: %v\n", err)
+	fmt.Println("任务2完成\n")
+
+	// 任务3：快速失败的并发抓取
+	fmt.Println("任务3：快速失败的并发抓取")
+	urls := []string{"a.com", "bb.com", "bad.com", "dddd.com", "eeeee.com", "ffffffffff.com"}
+	start := time.Now()
+	_, err = FetchAll(context.Background(), clock, urls, 3, time.Second, sleepFetch)
+	fmt.Printf("FetchAll返回: %v（用时 %v）\n", err, time.Since(start).Round(10*time.Millisecond))
+	body, err := FetchAll(context.Background(), clock, []string{"a.com", "bb.com", "ccc.com"}, 2, time.Second, sleepFetch)
+	fmt.Printf("全部成功: %v %v\n", body, err)
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 子ctx申请的时间比父ctx剩余的时间长时，应该以哪个为准？为什么？")
+	fmt.Println("   以更早的截止时间为准：父ctx到期时会取消所有子孙，子ctx报告更晚的Deadline只会误导下游做出错误的规划")
+	fmt.Println("2. 为什么每一步结束后都要调用cancel，即使这一步已经成功了？")
+	fmt.Println("   cancel释放计时资源并让等待预算的goroutine退出；不调用的话要等到预算用完才释放，请求量大时就是泄漏")
+	fmt.Println("3. 一个抓取失败后，为什么还要等已经开始的抓取返回才返回错误？")
+	fmt.Println("   它们还在写results、占用连接；提前返回会让调用者和这些goroutine同时访问结果，也无法保证没有goroutine泄漏")
+	fmt.Println("4. 为什么用假时钟测试截止时间，而不是真的sleep？")
+	fmt.Println("   真实时间在负载高的机器上不可控，sleep太短测试会偶发失败、太长测试变慢；假时钟让到期的时刻完全由测试决定")
+}
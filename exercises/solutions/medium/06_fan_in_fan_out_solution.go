@@ -0,0 +1,201 @@
+/*
+Golang并发编程练习 - 中等级别（参考答案）
+练习文件：06_fan_in_fan_out_exercise.go
+练习主题：扇入扇出与快速失败
+
+运行方式：go run exercises/solutions/medium/06_fan_in_fan_out_solution.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Task 任务
+type Task struct {
+	ID   int
+	Data int
+}
+
+// Result 处理结果
+type Result struct {
+	TaskID int
+	Value  int
+	Worker int // 处理它的工作者编号，从0开始
+}
+
+// generate 把任务依次发送到返回的channel，发送完后关闭（已实现）
+func generate(tasks []Task) <-chan Task {
+	out := make(chan Task)
+	go func() {
+		defer close(out)
+		for _, t := range tasks {
+			out <- t
+		}
+	}()
+	return out
+}
+
+// FanOut 启动n个工作者，都从in接收任务，用process处理后发送到自己的结果channel；
+// in关闭并处理完后每个工作者关闭自己的结果channel
+func FanOut(in <-chan Task, n int, process func(worker int, t Task) Result) []<-chan Result {
+	outs := make([]<-chan Result, n)
+	for i := 0; i < n; i++ {
+		out := make(chan Result)
+		outs[i] = out
+		go func(worker int) {
+			defer close(out)
+			for t := range in {
+				out <- process(worker, t)
+			}
+		}(i)
+	}
+	return outs
+}
+
+// Merge 把inputs中的结果都转发到返回的channel，所有inputs都关闭后关闭返回的channel
+func Merge(inputs ...<-chan Result) <-chan Result {
+	out := make(chan Result)
+	var wg sync.WaitGroup
+	wg.Add(len(inputs))
+	for _, in := range inputs {
+		go func(in <-chan Result) {
+			defer wg.Done()
+			for r := range in {
+				out <- r
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// ProcessAll 用workers个工作者并发处理tasks，按tasks的顺序返回结果。
+// 任何一个任务返回错误时：取消传给其余任务的ctx，不再开始新的任务，返回第一个错误。
+// ctx被取消时返回ctx.Err()。返回时所有启动的goroutine都已经退出
+func ProcessAll(ctx context.Context, tasks []Task, workers int, process func(ctx context.Context, t Task) (Result, error)) ([]Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range tasks {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make([]Result, len(tasks))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue // 已经失败，把剩下的下标取完让分发goroutine退出
+				}
+				r, err := process(ctx, tasks[i])
+				if err != nil {
+					fail(err)
+					continue
+				}
+				results[i] = r
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func main() {
+	fmt.Println("=== 扇入扇出练习 ===")
+
+	tasks := make([]Task, 10)
+	for i := range tasks {
+		tasks[i] = Task{ID: i + 1, Data: i + 1}
+	}
+
+	// 任务1、2：扇出再扇入
+	fmt.Println("\n任务1、2：扇出再扇入")
+	square := func(worker int, t Task) Result {
+		time.Sleep(20 * time.Millisecond)
+		return Result{TaskID: t.ID, Value: t.Data * t.Data, Worker: worker}
+	}
+	perWorker := make([]int, 3)
+	for r := range Merge(FanOut(generate(tasks), 3, square)...) {
+		fmt.Printf("任务%d = %d（工作者%d）\n", r.TaskID, r.Value, r.Worker)
+		perWorker[r.Worker]++
+	}
+	fmt.Printf("每个工作者处理的任务数: %v\n", perWorker)
+	fmt.Println("任务1、2完成\n")
+
+	// 任务3：快速失败
+	fmt.Println("任务3：快速失败")
+	results, err := ProcessAll(context.Background(), tasks, 3, func(ctx context.Context, t Task) (Result, error) {
+		return Result{TaskID: t.ID, Value: t.Data * t.Data}, nil
+	})
+	fmt.Printf("全部成功: err=%v\n", err)
+	for _, r := range results {
+		fmt.Printf("  任务%d = %d\n", r.TaskID, r.Value)
+	}
+
+	var started atomic.Int32
+	start := time.Now()
+	_, err = ProcessAll(context.Background(), tasks, 3, func(ctx context.Context, t Task) (Result, error) {
+		started.Add(1)
+		if t.Data == 3 {
+			return Result{}, errors.New("数据3无效")
+		}
+		select {
+		case <-time.After(100 * time.Millisecond):
+			return Result{TaskID: t.ID, Value: t.Data * t.Data}, nil
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+	})
+	fmt.Printf("有任务失败: err=%v，耗时 %v，开始了 %d/%d 个任务\n",
+		err, time.Since(start).Round(10*time.Millisecond), started.Load(), len(tasks))
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么FanOut给每个工作者一个结果channel，而不是共用一个？共用时由谁关闭？")
+	fmt.Println("   每个channel只有一个发送方，发送方结束时关闭它就是安全的；共用一个channel时哪个工作者都不知道自己是不是最后一个，需要另外用WaitGroup等所有工作者结束后再关闭")
+	fmt.Println("2. Merge中为什么不能让转发goroutine在自己的输入结束时关闭输出channel？")
+	fmt.Println("   其他输入可能还没读完，它们的转发goroutine再发送就会panic；下游也会在数据没收齐时就结束range")
+	fmt.Println("3. ProcessAll失败后，为什么还要等所有工作者退出才返回？")
+	fmt.Println("   工作者还在运行时返回会泄漏goroutine，它们还可能在返回之后写results；调用方看到返回就应该能确定所有工作都已经停下")
+	fmt.Println("4. 扇出的工作者数量应该怎样选择？CPU密集和IO密集的任务有什么不同？")
+	fmt.Println("   CPU密集的任务取runtime.NumCPU()左右，再多只会增加切换；IO密集的任务大部分时间在等待，可以多开，上限由下游能承受的并发决定")
+}
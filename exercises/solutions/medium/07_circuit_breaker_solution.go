@@ -0,0 +1,216 @@
+/*
+Golang并发编程练习 - 中等级别（参考答案）
+练习文件：07_circuit_breaker_exercise.go
+练习主题：熔断器状态机
+
+运行方式：go run exercises/solutions/medium/07_circuit_breaker_solution.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 正常通过请求
+	StateOpen                  // 拒绝请求，快速失败
+	StateHalfOpen              // 允许少量请求试探下游是否恢复
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "CLOSED"
+	case StateOpen:
+		return "OPEN"
+	case StateHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ErrOpen 熔断器拒绝了请求，被保护的函数没有被调用
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Config 熔断器配置
+type Config struct {
+	FailureThreshold int           // CLOSED状态下连续失败多少次后熔断
+	OpenTimeout      time.Duration // OPEN持续多久后允许试探
+	HalfOpenMaxCalls int           // HALF_OPEN时允许同时进行的试探请求数
+}
+
+// CircuitBreaker 熔断器
+type CircuitBreaker struct {
+	config Config
+	now    func() time.Time // 当前时间，评分时替换成可以手动拨动的时钟
+
+	mu         sync.Mutex
+	state      State
+	failures   int       // CLOSED状态下的连续失败次数
+	openedAt   time.Time // 进入OPEN的时间
+	probes     int       // HALF_OPEN状态下正在进行的试探数
+	generation uint64    // 每次状态变化加一，用来识别状态变化之前开始的调用
+}
+
+// NewCircuitBreaker 创建熔断器，初始状态为CLOSED
+func NewCircuitBreaker(config Config) *CircuitBreaker {
+	return &CircuitBreaker{config: config, now: time.Now, state: StateClosed}
+}
+
+// Call 熔断器允许时调用fn并返回它的错误，拒绝时不调用fn，直接返回ErrOpen
+func (cb *CircuitBreaker) Call(fn func() error) error {
+	gen, err := cb.before()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// fn panic时也要记为失败，否则HALF_OPEN的试探名额永远不会归还
+		if r := recover(); r != nil {
+			cb.after(gen, false)
+			panic(r)
+		}
+	}()
+	// 不持有锁调用fn：fn可能很慢，持有锁会让所有请求（包括本应快速失败的）排队
+	err = fn()
+	cb.after(gen, err == nil)
+	return err
+}
+
+// before 判断是否允许请求，允许时返回当前的状态代数
+func (cb *CircuitBreaker) before() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == StateOpen && cb.now().Sub(cb.openedAt) >= cb.config.OpenTimeout {
+		cb.setState(StateHalfOpen)
+	}
+	switch cb.state {
+	case StateOpen:
+		return 0, ErrOpen
+	case StateHalfOpen:
+		if cb.probes >= max(cb.config.HalfOpenMaxCalls, 1) {
+			return 0, ErrOpen
+		}
+		cb.probes++
+	}
+	return cb.generation, nil
+}
+
+// after 根据调用结果更新状态
+func (cb *CircuitBreaker) after(gen uint64, ok bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if gen != cb.generation {
+		// 调用开始之后状态已经变了（例如其他请求已经触发熔断），这次的结果不代表当前状态
+		return
+	}
+	switch cb.state {
+	case StateClosed:
+		if ok {
+			cb.failures = 0
+			return
+		}
+		cb.failures++
+		if cb.failures >= max(cb.config.FailureThreshold, 1) {
+			cb.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		// 一次试探就决定结果：成功说明下游已恢复，失败重新熔断并重新计时
+		if ok {
+			cb.setState(StateClosed)
+		} else {
+			cb.setState(StateOpen)
+		}
+	}
+}
+
+// setState 切换状态并清空与上一个状态有关的计数，调用者持有锁
+func (cb *CircuitBreaker) setState(s State) {
+	cb.state = s
+	cb.generation++
+	cb.failures = 0
+	cb.probes = 0
+	if s == StateOpen {
+		cb.openedAt = cb.now()
+	}
+}
+
+// State 返回当前状态；OPEN到HALF_OPEN的转换在超时后的下一次Call时发生
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+func main() {
+	fmt.Println("=== 熔断器状态机练习 ===")
+	errDown := errors.New("下游不可用")
+
+	// 任务1：连续失败触发熔断
+	fmt.Println("\n任务1：连续失败触发熔断")
+	cb := NewCircuitBreaker(Config{FailureThreshold: 3, OpenTimeout: 500 * time.Millisecond, HalfOpenMaxCalls: 1})
+	for i := 1; i <= 5; i++ {
+		err := cb.Call(func() error { return errDown })
+		fmt.Printf("第%d次调用: 错误=%v, 状态=%s\n", i, err, cb.State())
+	}
+	fmt.Println("任务1完成\n")
+
+	// 任务2：自动恢复
+	fmt.Println("任务2：自动恢复")
+	time.Sleep(600 * time.Millisecond)
+	err := cb.Call(func() error {
+		fmt.Printf("试探中，状态=%s\n", cb.State())
+		return nil
+	})
+	fmt.Printf("试探成功: 错误=%v, 状态=%s\n", err, cb.State())
+	for i := 0; i < 3; i++ {
+		cb.Call(func() error { return errDown })
+	}
+	time.Sleep(600 * time.Millisecond)
+	err = cb.Call(func() error { return errDown })
+	fmt.Printf("试探失败: 错误=%v, 状态=%s\n", err, cb.State())
+	fmt.Println("任务2完成\n")
+
+	// 任务3：半开状态的并发试探
+	fmt.Println("任务3：半开状态的并发试探")
+	time.Sleep(600 * time.Millisecond)
+	var called, rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cb.Call(func() error {
+				called.Add(1)
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			})
+			if errors.Is(err, ErrOpen) {
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("调用fn %d 次，快速失败 %d 次，最终状态=%s\n", called.Load(), rejected.Load(), cb.State())
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 按连续失败次数和按失败率熔断各适合什么场景？")
+	fmt.Println("   请求量小时失败率波动大，连续失败次数更稳定；请求量大时偶发失败总会打断连续计数，按窗口内的失败率更准确")
+	fmt.Println("2. 为什么调用fn时不能持有锁？")
+	fmt.Println("   fn通常是网络调用，持有锁会让所有请求串行执行，OPEN状态本应立即返回的请求也要排队等锁")
+	fmt.Println("3. HALF_OPEN时如果不限制试探数，下游刚恢复时会发生什么？")
+	fmt.Println("   积压的请求会同时涌向刚恢复的下游，很可能再次把它压垮，熔断器在OPEN和HALF_OPEN之间来回切换")
+	fmt.Println("4. fn执行期间熔断器已经被其他请求打开，这次调用的结果应该怎样处理？")
+	fmt.Println("   忽略：结果属于上一个状态，用状态代数识别；否则一个迟到的成功可能把刚打开的熔断器错误地关闭")
+}
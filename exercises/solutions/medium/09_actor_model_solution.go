@@ -0,0 +1,233 @@
+/*
+Golang并发编程练习 - 中等级别（参考答案）
+练习文件：09_actor_model_exercise.go
+练习主题：Actor消息传递与状态隔离
+
+运行方式：go run exercises/solutions/medium/09_actor_model_solution.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("余额不足")
+	ErrInvalidAmount     = errors.New("金额必须大于0")
+	ErrStopped           = errors.New("账户已停止")
+)
+
+// op 消息的种类
+type op int
+
+const (
+	opDeposit op = iota
+	opWithdraw
+	opBalance
+)
+
+// message 发给账户actor的消息
+type message struct {
+	op     op
+	amount int
+	reply  chan reply // 容量为1，actor回复时不会阻塞
+}
+
+// reply actor的回复
+type reply struct {
+	balance int
+	err     error
+}
+
+// Account 银行账户actor：余额只由run goroutine读写，其他goroutine通过邮箱发消息
+type Account struct {
+	mailbox chan message
+	stop    chan struct{} // Stop时关闭
+	done    chan struct{} // run退出后关闭
+	once    sync.Once
+	final   int // run退出时的余额，done关闭后才能读
+}
+
+// NewAccount 创建账户并启动它的goroutine，mailboxSize是邮箱的缓冲大小
+func NewAccount(initial, mailboxSize int) *Account {
+	a := &Account{
+		mailbox: make(chan message, mailboxSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go a.run(initial)
+	return a
+}
+
+// run 逐条处理消息；Stop后处理完邮箱里已有的消息再退出
+func (a *Account) run(balance int) {
+	defer close(a.done)
+	for {
+		select {
+		case m := <-a.mailbox:
+			balance = handle(balance, m)
+		case <-a.stop:
+			for {
+				select {
+				case m := <-a.mailbox:
+					balance = handle(balance, m)
+				default:
+					a.final = balance
+					return
+				}
+			}
+		}
+	}
+}
+
+// handle 处理一条消息并回复，返回新的余额
+func handle(balance int, m message) int {
+	var err error
+	switch m.op {
+	case opDeposit:
+		balance += m.amount
+	case opWithdraw:
+		if m.amount > balance {
+			err = ErrInsufficientFunds
+		} else {
+			balance -= m.amount
+		}
+	}
+	m.reply <- reply{balance, err}
+	return balance
+}
+
+// send 把消息放进邮箱并等待回复；账户已经停止时返回ErrStopped
+func (a *Account) send(o op, amount int) reply {
+	select {
+	case <-a.stop:
+		return reply{err: ErrStopped}
+	default:
+	}
+	m := message{op: o, amount: amount, reply: make(chan reply, 1)}
+	select {
+	case a.mailbox <- m:
+	case <-a.stop:
+		return reply{err: ErrStopped}
+	}
+	select {
+	case r := <-m.reply:
+		return r
+	case <-a.done:
+		// run在关闭done之前回复，回复已经在缓冲里就说明消息被处理了
+		select {
+		case r := <-m.reply:
+			return r
+		default:
+			return reply{err: ErrStopped}
+		}
+	}
+}
+
+// Deposit 存入amount
+func (a *Account) Deposit(amount int) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return a.send(opDeposit, amount).err
+}
+
+// Withdraw 取出amount，余额不足时返回ErrInsufficientFunds，余额不变
+func (a *Account) Withdraw(amount int) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return a.send(opWithdraw, amount).err
+}
+
+// Balance 当前余额
+func (a *Account) Balance() (int, error) {
+	r := a.send(opBalance, 0)
+	return r.balance, r.err
+}
+
+// Stop 停止账户：处理完邮箱中已有的消息后退出，返回最终余额。可以重复调用，之后的请求返回ErrStopped
+func (a *Account) Stop() int {
+	a.once.Do(func() { close(a.stop) })
+	<-a.done
+	return a.final
+}
+
+// Transfer 从from转amount到to；转入失败时把钱退回from
+func Transfer(from, to *Account, amount int) error {
+	if err := from.Withdraw(amount); err != nil {
+		return err
+	}
+	if err := to.Deposit(amount); err != nil {
+		if refundErr := from.Deposit(amount); refundErr != nil {
+			return fmt.Errorf("转入失败且无法退回: %w", errors.Join(err, refundErr))
+		}
+		return fmt.Errorf("转入失败，已退回: %w", err)
+	}
+	return nil
+}
+
+func main() {
+	fmt.Println("=== Actor模型练习 ===")
+
+	// 任务1：并发存取
+	fmt.Println("\n任务1：并发存取")
+	acc := NewAccount(100, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			acc.Deposit(10)
+		}()
+	}
+	wg.Wait()
+	balance, _ := acc.Balance()
+	fmt.Printf("100个goroutine各存入10后余额: %d\n", balance)
+	fmt.Printf("取出2000: %v\n", acc.Withdraw(2000))
+	fmt.Printf("取出600: %v\n", acc.Withdraw(600))
+	balance, _ = acc.Balance()
+	fmt.Printf("余额: %d\n", balance)
+	fmt.Println("任务1完成\n")
+
+	// 任务2：转账
+	fmt.Println("任务2：转账")
+	alice, bob := NewAccount(500, 10), NewAccount(500, 10)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); Transfer(alice, bob, 7) }()
+		go func() { defer wg.Done(); Transfer(bob, alice, 5) }()
+	}
+	wg.Wait()
+	a, _ := alice.Balance()
+	b, _ := bob.Balance()
+	fmt.Printf("alice=%d bob=%d 总额=%d\n", a, b, a+b)
+	fmt.Println("任务2完成\n")
+
+	// 任务3：停止
+	fmt.Println("任务3：停止")
+	fmt.Printf("bob停止，最终余额: %d\n", bob.Stop())
+	fmt.Printf("再次停止: %d\n", bob.Stop())
+	fmt.Printf("停止后存入: %v\n", bob.Deposit(1))
+	fmt.Printf("转账给已停止的bob: %v\n", Transfer(alice, bob, 100))
+	a, _ = alice.Balance()
+	fmt.Printf("alice余额: %d\n", a)
+	alice.Stop()
+	acc.Stop()
+	fmt.Println("任务3完成\n")
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 账户的余额为什么不需要加锁？")
+	fmt.Println("   余额只是run goroutine的局部变量，其他goroutine拿不到它，只能发消息；消息一条一条处理，本身就是串行的")
+	fmt.Println("2. 回复channel为什么要有1个缓冲？")
+	fmt.Println("   这里的请求方把消息放进邮箱后一定会等到回复或done，无缓冲也能工作；但如果请求方加上超时、放弃等待，无缓冲时actor会阻塞在回复上，再也处理不了别的消息。有1个缓冲时actor回复永远不阻塞，不依赖请求方怎样写")
+	fmt.Println("3. Transfer分成取出和存入两条消息，中间别人看到的总额是不对的，怎样避免？")
+	fmt.Println("   让一个actor同时持有两个账户（例如银行actor），或者用两阶段提交；actor之间没有跨actor的原子操作")
+	fmt.Println("4. 邮箱满了会怎样？和用Mutex保护余额相比各有什么优缺点？")
+	fmt.Println("   发送方阻塞等待，这就是背压；actor隔离了状态、不会死锁在锁顺序上，但每次操作多一次channel往返，读多写少时不如锁")
+}
@@ -0,0 +1,82 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：01_basic_goroutine_exercise.go
+练习主题：基础Goroutine使用
+
+运行方式：go run exercises/solutions/simple/01_basic_goroutine_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// printOddNumbers 打印奇数1, 3, 5, 7, 9，每次打印后睡眠100毫秒
+func printOddNumbers() {
+	for i := 1; i <= 9; i += 2 {
+		fmt.Printf("奇数: %d\n", i)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// printEvenNumbers 打印偶数2, 4, 6, 8, 10，每次打印后睡眠150毫秒
+func printEvenNumbers() {
+	for i := 2; i <= 10; i += 2 {
+		fmt.Printf("偶数: %d\n", i)
+		time.Sleep(150 * time.Millisecond)
+	}
+}
+
+// printCountdown 从n倒数到1，每秒打印一个数字
+func printCountdown(n int, name string) {
+	for i := n; i >= 1; i-- {
+		fmt.Printf("%s: %d\n", name, i)
+		time.Sleep(time.Second)
+	}
+	fmt.Printf("%s: 结束\n", name)
+}
+
+func main() {
+	fmt.Println("=== Goroutine基础练习 ===")
+
+	// 任务1：基础并发执行
+	fmt.Println("\n任务1：奇数偶数并发打印")
+	go printOddNumbers()
+	go printEvenNumbers()
+	// 奇数用时500ms，偶数用时750ms，多等一会儿确保都已完成
+	time.Sleep(time.Second)
+	fmt.Println("任务1完成")
+
+	// 任务2：多个goroutine
+	fmt.Println("\n任务2：多个倒计时")
+	go printCountdown(5, "Timer-A")
+	go printCountdown(3, "Timer-B")
+	go printCountdown(7, "Timer-C")
+	// 最长的倒计时需要7秒
+	time.Sleep(7*time.Second + 500*time.Millisecond)
+	fmt.Println("任务2完成")
+
+	// 任务3：观察执行顺序
+	fmt.Println("\n任务3：观察执行顺序")
+	for id := 1; id <= 5; id++ {
+		go func(id int) {
+			for i := 1; i <= 3; i++ {
+				fmt.Printf("goroutine %d 第%d次打印\n", id, i)
+				time.Sleep(10 * time.Millisecond)
+			}
+		}(id)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	fmt.Println("所有练习完成！")
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 为什么每次运行程序，输出的顺序可能不同？")
+	fmt.Println("   goroutine由调度器安排运行，哪个先被调度、何时被抢占都不确定")
+	fmt.Println("2. 如果主程序提前结束会发生什么？")
+	fmt.Println("   main返回时进程退出，还在运行的goroutine被直接终止，不会执行完")
+	fmt.Println("3. time.Sleep是等待goroutine的好方法吗？为什么？")
+	fmt.Println("   不是：睡眠时间只能靠猜，短了goroutine没做完，长了白白浪费时间，应该用WaitGroup或channel")
+}
@@ -0,0 +1,36 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：02_waitgroup_basic_exercise.go
+练习主题：WaitGroup同步
+
+运行方式：go run exercises/solutions/simple/02_waitgroup_basic_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// worker 模拟一项工作，结束时通过defer调用wg.Done，即使中途panic也不会漏掉
+func worker(id int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	fmt.Printf("工作者 %d 开始工作\n", id)
+	time.Sleep(time.Duration(id+1) * 20 * time.Millisecond)
+	fmt.Printf("工作者 %d 完成工作\n", id)
+}
+
+func main() {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		// Add要在启动goroutine之前调用，否则Wait可能在Add之前返回
+		wg.Add(1)
+		go worker(i, &wg)
+	}
+
+	wg.Wait()
+	fmt.Println("所有工作者都已完成")
+}
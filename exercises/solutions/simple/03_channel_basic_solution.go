@@ -0,0 +1,33 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：03_channel_basic_exercise.go
+练习主题：基础Channel
+
+运行方式：go run exercises/solutions/simple/03_channel_basic_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+)
+
+// sender 依次发送消息，发送完后关闭channel；只有发送方才应该关闭channel
+func sender(ch chan<- string) {
+	defer close(ch)
+	for _, v := range []string{"hello", "world", "hehehe"} {
+		ch <- v
+	}
+}
+
+func main() {
+	ch := make(chan string)
+
+	go sender(ch)
+
+	// range在channel关闭且数据取完后结束
+	for msg := range ch {
+		fmt.Printf("接收到数据 %s\n", msg)
+	}
+	fmt.Println("channel已关闭，接收结束")
+}
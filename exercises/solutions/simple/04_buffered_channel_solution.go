@@ -0,0 +1,47 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：04_buffered_channel_exercise.go
+练习主题：缓冲Channel与多个消费者
+
+运行方式：go run exercises/solutions/simple/04_buffered_channel_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// consumer 消费ch中的数据直到ch被关闭
+func consumer(ch <-chan string, i int) {
+	for data := range ch {
+		fmt.Printf("消费者%d 消费了 %s\n", i, data)
+	}
+}
+
+func main() {
+	ch := make(chan string, 8)
+
+	// 用WaitGroup等待消费者把数据取完，代替猜测睡眠时间
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			consumer(ch, i)
+		}(i)
+	}
+
+	go func() {
+		defer close(ch)
+		for i := 0; i < 10; i++ {
+			ch <- fmt.Sprintf("数据%d", i)
+			time.Sleep(80 * time.Millisecond) // 控制发送速度
+		}
+	}()
+
+	wg.Wait()
+	fmt.Println("所有数据都已消费")
+}
@@ -0,0 +1,104 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：05_select_basic_exercise.go
+练习主题：Select语句
+
+运行方式：go run exercises/solutions/simple/05_select_basic_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// receiveFirst 同时等待两个channel，返回先到达的消息和它来自哪个channel（1或2）
+func receiveFirst(ch1, ch2 <-chan string) (string, int) {
+	select {
+	case msg := <-ch1:
+		return msg, 1
+	case msg := <-ch2:
+		return msg, 2
+	}
+}
+
+// tryReceive 非阻塞接收：有数据时返回(数据, true)；没有数据或channel已关闭时立即返回("", false)
+func tryReceive(ch <-chan string) (string, bool) {
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			return "", false
+		}
+		return v, true
+	default:
+		return "", false
+	}
+}
+
+// mergeUntilClosed 同时从ch1和ch2接收，直到两个channel都被关闭，按收到的顺序返回全部数据
+func mergeUntilClosed(ch1, ch2 <-chan string) []string {
+	var out []string
+	for ch1 != nil || ch2 != nil {
+		select {
+		case v, ok := <-ch1:
+			if !ok {
+				ch1 = nil // 关闭后不再选中这个case
+				continue
+			}
+			out = append(out, v)
+		case v, ok := <-ch2:
+			if !ok {
+				ch2 = nil
+				continue
+			}
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func main() {
+	fmt.Println("=== Select语句练习 ===")
+
+	fmt.Println("\n任务1：等待先到达的消息")
+	fast, slow := make(chan string), make(chan string, 1) // slow有缓冲：没人接收时发送方也能退出
+	go func() { time.Sleep(50 * time.Millisecond); fast <- "50ms后的消息" }()
+	go func() { time.Sleep(200 * time.Millisecond); slow <- "200ms后的消息" }()
+	msg, from := receiveFirst(slow, fast)
+	fmt.Printf("先收到来自通道%d的: %s\n", from, msg)
+
+	fmt.Println("\n任务2：非阻塞接收")
+	ch := make(chan string, 1)
+	v, ok := tryReceive(ch)
+	fmt.Printf("空channel: %q, %v\n", v, ok)
+	ch <- "数据"
+	v, ok = tryReceive(ch)
+	fmt.Printf("有数据时: %q, %v\n", v, ok)
+
+	fmt.Println("\n任务3：合并两个channel")
+	letters, numbers := make(chan string), make(chan string)
+	go func() {
+		defer close(letters)
+		for _, s := range []string{"a", "b", "c"} {
+			letters <- s
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	go func() {
+		defer close(numbers)
+		for _, s := range []string{"1", "2"} {
+			time.Sleep(30 * time.Millisecond)
+			numbers <- s
+		}
+	}()
+	fmt.Printf("合并结果: %v\n", mergeUntilClosed(letters, numbers))
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 多个case同时就绪时，select选择哪一个？")
+	fmt.Println("   随机选择一个，保证每个case都有机会；不能依赖case的书写顺序表达优先级")
+	fmt.Println("2. 没有default分支、所有case都没有就绪时会怎样？全部是nil channel呢？")
+	fmt.Println("   select阻塞直到某个case就绪；全部是nil channel时永远阻塞，如果所有goroutine都这样就会报deadlock")
+	fmt.Println("3. 为什么关闭的channel要设为nil，而不是用一个标志变量跳过它？")
+	fmt.Println("   select的case不能按条件跳过，关闭的channel每次都会就绪，用标志变量只能在选中后continue，循环会空转占满CPU")
+}
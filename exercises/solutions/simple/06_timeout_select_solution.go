@@ -0,0 +1,83 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：06_timeout_select_exercise.go
+练习主题：带超时的select
+
+运行方式：go run exercises/solutions/simple/06_timeout_select_solution.go
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errTimeout = errors.New("操作超时")
+
+// fetchWithTimeout 在新的goroutine中执行work，timeout内完成时返回结果，否则返回errTimeout
+func fetchWithTimeout(work func() string, timeout time.Duration) (string, error) {
+	// 缓冲为1：超时返回后没有人接收，work完成时仍能发送成功并退出
+	result := make(chan string, 1)
+	go func() {
+		result <- work()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case v := <-result:
+		return v, nil
+	case <-timer.C:
+		return "", errTimeout
+	}
+}
+
+// collectUntil 从ch接收数据，直到经过d或ch被关闭，返回收到的全部数据
+func collectUntil(ch <-chan int, d time.Duration) []int {
+	var got []int
+	deadline := time.NewTimer(d) // 整体截止时间，只创建一次
+	defer deadline.Stop()
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		case <-deadline.C:
+			return got
+		}
+	}
+}
+
+func main() {
+	fmt.Println("=== 超时控制练习 ===")
+
+	fmt.Println("\n任务1：带超时的调用")
+	fast := func() string { time.Sleep(50 * time.Millisecond); return "快速结果" }
+	slow := func() string { time.Sleep(500 * time.Millisecond); return "慢速结果" }
+	for _, work := range []func() string{fast, slow} {
+		v, err := fetchWithTimeout(work, 200*time.Millisecond)
+		fmt.Printf("结果: %q, 错误: %v\n", v, err)
+	}
+
+	fmt.Println("\n任务2：限时接收")
+	ch := make(chan int)
+	go func() {
+		for i := 1; ; i++ {
+			ch <- i
+			time.Sleep(30 * time.Millisecond)
+		}
+	}()
+	fmt.Printf("300ms内收到: %v\n", collectUntil(ch, 300*time.Millisecond))
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 超时返回后，work所在的goroutine还在运行吗？它最终会怎样？")
+	fmt.Println("   还在运行，select不能中止它；work结束后把结果写进有缓冲的channel然后退出，结果被丢弃")
+	fmt.Println("2. 如果work能够响应取消，应该怎样修改fetchWithTimeout的签名？")
+	fmt.Println("   改成接收context.Context：func(ctx context.Context) string，超时时取消ctx让work提前返回")
+	fmt.Println("3. time.After和time.NewTimer有什么区别？")
+	fmt.Println("   time.After每次调用都创建新的计时器且无法停止，NewTimer可以Stop，适合在循环或提前返回的场景中使用")
+}
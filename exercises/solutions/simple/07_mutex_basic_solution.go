@@ -0,0 +1,71 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：07_mutex_basic_exercise.go
+练习主题：互斥锁保护共享数据
+
+运行方式：go run -race exercises/solutions/simple/07_mutex_basic_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SafeCounter 并发安全的按键计数器
+type SafeCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewSafeCounter 创建计数器
+func NewSafeCounter() *SafeCounter {
+	return &SafeCounter{counts: make(map[string]int)}
+}
+
+// Inc 把key的计数加一
+func (c *SafeCounter) Inc(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key]++
+}
+
+// Value 返回key的当前计数
+func (c *SafeCounter) Value(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[key]
+}
+
+func main() {
+	fmt.Println("=== 互斥锁练习 ===")
+
+	c := NewSafeCounter()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Inc(fmt.Sprintf("key-%d", i%3))
+			}
+		}(i)
+	}
+	wg.Wait()
+	total := 0
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		total += c.Value(key)
+		fmt.Printf("%s: %d\n", key, c.Value(key))
+	}
+	fmt.Printf("三个计数之和: %d（应为10000）\n", total)
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 如果Value不加锁会发生什么？")
+	fmt.Println("   读和写同时访问map是数据竞争，运行时检测到并发读写map会直接终止程序")
+	fmt.Println("2. 读多写少时可以换成什么锁？")
+	fmt.Println("   sync.RWMutex，多个读者可以同时持有读锁")
+	fmt.Println("3. 为什么Mutex字段不能被复制？")
+	fmt.Println("   复制出的锁是独立的状态，两份数据各自加锁起不到互斥作用；所以方法使用指针接收者")
+}
@@ -0,0 +1,78 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：08_once_basic_exercise.go
+练习主题：sync.Once单次初始化
+
+运行方式：go run -race exercises/solutions/simple/08_once_basic_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config 应用配置
+type Config struct {
+	Name    string
+	Workers int
+}
+
+// loadCount loadConfig被调用的次数
+var loadCount atomic.Int32
+
+// loadConfig 模拟代价很高的配置加载
+func loadConfig() *Config {
+	loadCount.Add(1)
+	time.Sleep(50 * time.Millisecond)
+	return &Config{Name: "app", Workers: 4}
+}
+
+var (
+	configOnce sync.Once
+	config     *Config
+)
+
+// GetConfig 返回全局唯一的配置，第一次调用时加载。
+// 其他goroutine在Do中等待加载完成，Do返回后对config的读取不会与写入竞争
+func GetConfig() *Config {
+	configOnce.Do(func() {
+		config = loadConfig()
+	})
+	return config
+}
+
+func main() {
+	fmt.Println("=== sync.Once练习 ===")
+
+	var wg sync.WaitGroup
+	configs := make([]*Config, 50)
+	for i := range configs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			configs[i] = GetConfig()
+		}(i)
+	}
+	wg.Wait()
+
+	same := true
+	for _, c := range configs {
+		if c != configs[0] {
+			same = false
+		}
+	}
+	fmt.Printf("loadConfig执行次数: %d（应为1）\n", loadCount.Load())
+	fmt.Printf("所有goroutine拿到同一个配置: %v\n", same && configs[0] != nil)
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. if config == nil { config = loadConfig() } 在并发下会出什么问题？")
+	fmt.Println("   多个goroutine同时看到nil，各自加载一次，还有对config的数据竞争")
+	fmt.Println("2. 如果loadConfig可能失败，sync.Once还合适吗？")
+	fmt.Println("   Once只执行一次，失败后也不会重试；需要重试时改用互斥锁加状态判断")
+	fmt.Println("3. Go 1.21的sync.OnceValue能怎样简化这段代码？")
+	fmt.Println("   var GetConfig = sync.OnceValue(loadConfig)，不再需要单独的Once和全局变量")
+}
@@ -0,0 +1,61 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：09_channel_pipeline_exercise.go
+练习主题：Channel管道
+
+运行方式：go run exercises/solutions/simple/09_channel_pipeline_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+)
+
+// generate 启动一个goroutine把nums依次发送到返回的channel，发送完后关闭它
+func generate(nums ...int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for _, n := range nums {
+			out <- n
+		}
+	}()
+	return out
+}
+
+// square 启动一个goroutine，把in中的每个数平方后发送到返回的channel，in关闭后关闭输出
+func square(in <-chan int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for n := range in {
+			out <- n * n
+		}
+	}()
+	return out
+}
+
+// sum 累加in中的所有数，直到in被关闭
+func sum(in <-chan int) int {
+	total := 0
+	for n := range in {
+		total += n
+	}
+	return total
+}
+
+func main() {
+	fmt.Println("=== Channel管道练习 ===")
+
+	fmt.Printf("1²+2²+3²+4²+5² = %d（应为55）\n", sum(square(generate(1, 2, 3, 4, 5))))
+	fmt.Printf("两次平方: 2⁴+3⁴ = %d（应为97）\n", sum(square(square(generate(2, 3)))))
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 如果square忘记关闭输出channel会发生什么？")
+	fmt.Println("   sum的range永远等不到结束，所有goroutine阻塞后程序报deadlock")
+	fmt.Println("2. 如果sum中途返回，上游的goroutine会怎样？怎样避免泄漏？")
+	fmt.Println("   上游阻塞在发送上永远不会退出；给每个阶段传入done channel或context，下游退出时通知上游")
+	fmt.Println("3. 怎样让square的处理并行化而保持输出顺序？")
+	fmt.Println("   给每个数据带上序号，多个square并行处理后按序号重新排序，或者按顺序把结果channel排成队列")
+}
@@ -0,0 +1,61 @@
+/*
+Golang并发编程练习 - 简单级别（参考答案）
+练习文件：10_goroutine_pool_exercise.go
+练习主题：Goroutine池
+
+运行方式：go run exercises/solutions/simple/10_goroutine_pool_solution.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// runPool 用workers个goroutine并发地对jobs中的每个元素调用fn，返回与jobs顺序一致的结果
+func runPool(workers int, jobs []int, fn func(int) int) []int {
+	results := make([]int, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				// 每个下标只由一个工作者写入，不同元素之间没有竞争
+				results[i] = fn(jobs[i])
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait() // Wait返回后results中的写入对调用者可见
+	return results
+}
+
+func main() {
+	fmt.Println("=== Goroutine池练习 ===")
+
+	jobs := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	start := time.Now()
+	results := runPool(3, jobs, func(n int) int {
+		time.Sleep(100 * time.Millisecond)
+		return n * n
+	})
+	fmt.Printf("结果: %v\n", results)
+	fmt.Printf("耗时: %v（3个工作者处理10个100ms的任务，约400ms）\n", time.Since(start).Round(10*time.Millisecond))
+
+	fmt.Println("\n思考题：")
+	fmt.Println("1. 工作者数量应该怎样选择？CPU密集和IO密集的任务有什么不同？")
+	fmt.Println("   CPU密集取GOMAXPROCS左右，再多只会增加切换；IO密集大部分时间在等待，可以多开，受下游容量限制")
+	fmt.Println("2. 如果fn可能返回错误，怎样在第一个错误时停止其余任务？")
+	fmt.Println("   用context.WithCancel，出错时cancel，分发循环和工作者检查ctx.Done()后停止；或者用errgroup")
+	fmt.Println("3. 为每个任务启动一个goroutine有什么问题？")
+	fmt.Println("   任务很多时goroutine数量不受控制，会压垮下游资源（连接、文件句柄）并占用大量内存")
+}
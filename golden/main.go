@@ -0,0 +1,240 @@
+/*
+Golang并发编程学习 - demo的黄金输出检查
+文件：golden/main.go
+
+以确定性模式（DEMO_DETERMINISTIC=1）运行demo，把输出中的时刻、时长替换成占位符后，
+和保存在 cmd/级别/testdata/目录名.golden 中的期望输出逐行比较。修改或重构demo之后运行一遍，
+输出有变化的demo会显示差异；变化是有意的，就用-update更新期望输出并一起提交。
+
+运行方式：
+  go run ./golden                     # 检查所有有黄金输出的demo
+  go run ./golden simple/03 simple/05 # 按"级别/编号"或demo目录选择
+  go run ./golden -count 5 simple     # 每个demo运行5次，检查输出是否稳定
+  go run ./golden -update simple/09   # 用这次的输出生成或更新黄金输出
+
+demo怎样在确定性模式下输出稳定的内容，见pkg/golden。
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klsakura/day1/pkg/demoflag"
+	"github.com/klsakura/day1/pkg/golden"
+)
+
+var levels = []string{"simple", "medium", "hard"}
+
+// demo 一个demo包和它的黄金输出文件
+type demo struct {
+	id     string // 级别/目录名，例如simple/03_channel_basic
+	dir    string // 例如cmd/simple/03_channel_basic
+	golden string
+}
+
+// goldenPath 黄金输出放在级别目录下的testdata中，go命令会忽略这个目录
+func goldenPath(dir string) string {
+	return filepath.Join(filepath.Dir(dir), "testdata", filepath.Base(dir)+".golden")
+}
+
+// discover 列出所有demo包
+func discover() ([]demo, error) {
+	var demos []demo
+	for _, level := range levels {
+		mains, err := filepath.Glob(filepath.Join("cmd", level, "*", "main.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(mains)
+		for _, f := range mains {
+			dir := filepath.Dir(f)
+			demos = append(demos, demo{
+				id:     level + "/" + filepath.Base(dir),
+				dir:    dir,
+				golden: goldenPath(dir),
+			})
+		}
+	}
+	return demos, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// selected 参数为空时选择所有有黄金输出的demo（-update时选择简单级别）；参数可以是级别、"级别/编号"或demo目录
+func selected(d demo, args []string, update bool) bool {
+	if len(args) == 0 {
+		if update {
+			return strings.HasPrefix(d.id, "simple/")
+		}
+		return exists(d.golden)
+	}
+	for _, a := range args {
+		a = strings.TrimSuffix(filepath.ToSlash(a), "/")
+		switch {
+		case a == strings.Split(d.id, "/")[0],
+			strings.HasPrefix(d.id, a),
+			strings.HasSuffix(filepath.ToSlash(d.dir), a):
+			return true
+		}
+	}
+	return false
+}
+
+// build 编译demo，只编译一次，-count大于1时重复运行同一个程序
+func build(ctx context.Context, pkg, dir string) (string, error) {
+	bin := filepath.Join(dir, filepath.Base(pkg))
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	out, err := exec.CommandContext(ctx, "go", "build", "-o", bin, "./"+filepath.ToSlash(pkg)).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("编译失败: %v\n%s", err, out)
+	}
+	return bin, nil
+}
+
+// defaultEnv 去掉设置demo参数的环境变量（见pkg/demoflag），黄金输出是按参数默认值生成的
+func defaultEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, demoflag.Prefix) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// run 以确定性模式运行一次，返回规范化之后的输出（标准输出和标准错误合在一起）
+func run(ctx context.Context, bin string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin)
+	cmd.Env = append(defaultEnv(), golden.EnvVar+"=1")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("超过 %v 没有结束", timeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, out.String())
+	}
+	return golden.Normalize(out.String()), nil
+}
+
+func main() {
+	update := flag.Bool("update", false, "用这次的输出生成或覆盖黄金输出")
+	count := flag.Int("count", 1, "每个demo运行的次数，每次的输出都要和黄金输出一致")
+	timeout := flag.Duration("timeout", 2*time.Minute, "单个demo运行一次的时间上限")
+	contextLines := flag.Int("context", 3, "差异前后显示的相同行数")
+	flag.Parse()
+
+	for _, dir := range []string{".", ".."} {
+		if exists(filepath.Join(dir, "go.mod")) && exists(filepath.Join(dir, "cmd")) {
+			os.Chdir(dir)
+			break
+		}
+	}
+	demos, err := discover()
+	if err != nil || len(demos) == 0 {
+		fmt.Fprintln(os.Stderr, "找不到demo，请在仓库根目录下运行")
+		os.Exit(2)
+	}
+	tmp, err := os.MkdirTemp("", "golden-*")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer os.RemoveAll(tmp)
+
+	ctx := context.Background()
+	checked, failed := 0, 0
+	for _, d := range demos {
+		if !selected(d, flag.Args(), *update) {
+			continue
+		}
+		checked++
+		start := time.Now()
+		msg, ok := check(ctx, d, tmp, *update, *count, *timeout, *contextLines)
+		status := "ok  "
+		if !ok {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("%s %-36s %v\n", status, d.id, time.Since(start).Round(100*time.Millisecond))
+		if msg != "" {
+			fmt.Println(indent(msg))
+		}
+	}
+
+	if checked == 0 {
+		fmt.Fprintln(os.Stderr, "没有选中任何demo；还没有黄金输出时先用 -update 生成")
+		os.Exit(2)
+	}
+	fmt.Printf("\n检查 %d 个demo，%d 个失败\n", checked, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// check 编译运行一个demo并与黄金输出比较；update时写入黄金输出。返回要显示的说明和是否通过
+func check(ctx context.Context, d demo, tmp string, update bool, count int, timeout time.Duration, contextLines int) (string, bool) {
+	bin, err := build(ctx, d.dir, tmp)
+	if err != nil {
+		return err.Error(), false
+	}
+	var want string
+	if !update {
+		data, err := os.ReadFile(d.golden)
+		if err != nil {
+			return "没有黄金输出，先用 go run ./golden -update " + d.id + " 生成", false
+		}
+		want = golden.Normalize(string(data))
+	}
+	for i := 1; i <= count; i++ {
+		got, err := run(ctx, bin, timeout)
+		if err != nil {
+			return fmt.Sprintf("第%d次运行失败: %v", i, err), false
+		}
+		if update && i == 1 {
+			// 第一次的输出作为黄金输出，其余几次用来确认输出稳定
+			if err := os.MkdirAll(filepath.Dir(d.golden), 0o755); err != nil {
+				return err.Error(), false
+			}
+			if err := os.WriteFile(d.golden, []byte(got), 0o644); err != nil {
+				return err.Error(), false
+			}
+			want = got
+			continue
+		}
+		if diff := golden.Diff(want, got, contextLines); diff != "" {
+			return fmt.Sprintf("第%d次运行的输出与黄金输出不同（-期望 +实际）：\n%s", i, diff), false
+		}
+	}
+	if update {
+		return "已写入 " + filepath.ToSlash(d.golden), true
+	}
+	return "", true
+}
+
+func indent(s string) string {
+	s = strings.TrimRight(s, "\n")
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
@@ -0,0 +1,290 @@
+/*
+Golang并发编程学习 - 统一的demo启动器
+文件：gorun/main.go
+
+按级别和名字列出、运行demo，不需要记住路径。每个demo是cmd/级别/编号_名字/下的一个main包，
+demo名是目录名去掉编号、下划线换成连字符，例如cmd/hard/03_message_queue的名字是message-queue；也可以用编号（03或3）或名字的唯一前缀。
+名字之后的参数原样传给demo，demo自己用flag解析。
+
+运行方式：
+  go run ./gorun                                # 列出所有demo
+  go run ./gorun hard                           # 列出困难级别的demo
+  go run ./gorun hard message-queue             # 运行cmd/hard/03_message_queue
+  go run ./gorun hard 03 -consumers 5           # 用编号选择，-consumers 5传给demo
+  go run ./gorun medium rate -h                 # 名字前缀唯一即可；-h查看demo支持的参数
+  go run ./gorun -race simple data-race         # 在竞态检测器下运行
+
+也可以用 go install ./gorun 安装到$GOPATH/bin，之后在仓库根目录下直接运行 gorun hard message-queue。
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var levels = []string{"simple", "medium", "hard"}
+
+// demo 一个demo包
+type demo struct {
+	level string
+	num   int    // 目录名中的编号
+	name  string // 命令行中使用的名字，例如message-queue
+	dir   string // 例如cmd/hard/03_message_queue
+	title string
+}
+
+// demoDir demo的目录名："03_message_queue"
+var demoDir = regexp.MustCompile(`^(\d+)_(.+)$`)
+
+// discover 列出所有demo，按级别和编号排序
+func discover() ([]demo, error) {
+	titles := readmeTitles("README.md")
+	var demos []demo
+	for _, level := range levels {
+		mains, err := filepath.Glob(filepath.Join("cmd", level, "*", "main.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(mains)
+		for _, f := range mains {
+			dir := filepath.Dir(f)
+			base := filepath.Base(dir)
+			m := demoDir.FindStringSubmatch(base)
+			if m == nil {
+				continue
+			}
+			num, _ := strconv.Atoi(m[1])
+			title := titles[level+"/"+base]
+			if title == "" {
+				title = headerTitle(f, "主题：")
+			}
+			demos = append(demos, demo{
+				level: level,
+				num:   num,
+				name:  strings.ReplaceAll(m[2], "_", "-"),
+				dir:   dir,
+				title: title,
+			})
+		}
+	}
+	return demos, nil
+}
+
+// readmeEntry README中的demo条目："1. **01_basic_goroutine** - 说明"
+var readmeEntry = regexp.MustCompile(`^\d+\. \*\*(.+?)\*\* - (.+)$`)
+
+// readmeTitles 从README的各级别列表中读取demo的说明，键为"级别/目录名"
+func readmeTitles(path string) map[string]string {
+	titles := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return titles
+	}
+	defer f.Close()
+	level := ""
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "## ") {
+			level = ""
+			for _, l := range levels {
+				if strings.Contains(strings.ToLower(line), l) {
+					level = l
+				}
+			}
+			continue
+		}
+		if m := readmeEntry.FindStringSubmatch(line); m != nil && level != "" {
+			titles[level+"/"+m[1]] = m[2]
+		}
+	}
+	return titles
+}
+
+// headerTitle 文件开头注释中以prefix开头的一行，例如"主题：基础Goroutine使用"
+func headerTitle(path, prefix string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for i := 0; i < 20 && sc.Scan(); i++ {
+		if line := strings.TrimSpace(sc.Text()); strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+// knownLevel level是否是demo的级别之一
+func knownLevel(level string) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// find 在level中按名字、编号、目录名或名字前缀查找demo；前缀匹配到多个时返回错误并列出候选
+func find(demos []demo, level, query string) (demo, error) {
+	if !knownLevel(level) {
+		return demo{}, fmt.Errorf("没有级别 %s，可选: %s", level, strings.Join(levels, ", "))
+	}
+	var prefixed []demo
+	for _, d := range demos {
+		if d.level != level {
+			continue
+		}
+		base := filepath.Base(d.dir)
+		if n, err := strconv.Atoi(query); err == nil && n == d.num {
+			return d, nil
+		}
+		if query == d.name || query == base || strings.ReplaceAll(query, "_", "-") == d.name {
+			return d, nil
+		}
+		if strings.HasPrefix(d.name, strings.ReplaceAll(query, "_", "-")) {
+			prefixed = append(prefixed, d)
+		}
+	}
+	switch {
+	case len(prefixed) == 1:
+		return prefixed[0], nil
+	case len(prefixed) > 1:
+		names := make([]string, len(prefixed))
+		for i, d := range prefixed {
+			names[i] = d.name
+		}
+		return demo{}, fmt.Errorf("%s 匹配到多个demo: %s", query, strings.Join(names, ", "))
+	}
+	return demo{}, fmt.Errorf("%s 级别没有名为 %s 的demo，用 gorun %s 查看全部", level, query, level)
+}
+
+// list 按级别列出demo，level为空时列出全部
+func list(demos []demo, level string) {
+	width := 0
+	for _, d := range demos {
+		width = max(width, len(d.name))
+	}
+	current := ""
+	for _, d := range demos {
+		if level != "" && d.level != level {
+			continue
+		}
+		if d.level != current {
+			if current != "" {
+				fmt.Println()
+			}
+			current = d.level
+			fmt.Printf("%s:\n", d.level)
+		}
+		fmt.Printf("  %02d  %-*s  %s\n", d.num, width, d.name, d.title)
+	}
+}
+
+// run 编译demo并运行，args原样传给demo；返回demo的退出码
+func run(d demo, args []string, race bool) (int, error) {
+	tmp, err := os.MkdirTemp("", "gorun-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmp)
+
+	bin := filepath.Join(tmp, filepath.Base(d.dir))
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	buildArgs := []string{"build", "-o", bin}
+	if race {
+		buildArgs = append(buildArgs, "-race")
+	}
+	build := exec.Command("go", append(buildArgs, "./"+filepath.ToSlash(d.dir))...)
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		return 0, fmt.Errorf("编译 %s 失败: %v", d.dir, err)
+	}
+
+	// Ctrl+C同时发给demo和启动器：启动器忽略它，等demo自己处理完退出后再清理临时文件
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+
+	cmd := exec.Command(bin, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "用法: gorun [-race] [级别 [demo名|编号 [demo参数...]]]")
+	fmt.Fprintln(out, "")
+	fmt.Fprintln(out, "  gorun                       列出所有demo")
+	fmt.Fprintln(out, "  gorun hard                  列出一个级别的demo")
+	fmt.Fprintln(out, "  gorun hard message-queue    运行demo，之后的参数传给demo")
+	fmt.Fprintln(out, "")
+	flag.PrintDefaults()
+}
+
+func main() {
+	race := flag.Bool("race", false, "在竞态检测器下编译运行demo")
+	flag.Usage = usage
+	flag.Parse()
+
+	for _, dir := range []string{".", ".."} {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			if _, err := os.Stat(filepath.Join(dir, "cmd")); err == nil {
+				os.Chdir(dir)
+				break
+			}
+		}
+	}
+	demos, err := discover()
+	if err != nil || len(demos) == 0 {
+		fmt.Fprintln(os.Stderr, "找不到demo，请在仓库根目录下运行")
+		os.Exit(2)
+	}
+
+	args := flag.Args()
+	switch len(args) {
+	case 0:
+		list(demos, "")
+		return
+	case 1:
+		if !knownLevel(args[0]) {
+			fmt.Fprintf(os.Stderr, "没有级别 %s，可选: %s\n", args[0], strings.Join(levels, ", "))
+			os.Exit(2)
+		}
+		list(demos, args[0])
+		return
+	}
+
+	d, err := find(demos, args[0], args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	code, err := run(d, args[2:], *race)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,225 @@
+// Package actor 提供基于邮箱的Actor和管理它们的System。
+//
+// 每个Actor有自己的邮箱和消息循环，按消息类型分派给注册的处理器；处理器只在Actor自己的
+// 协程中运行，Actor的状态不需要加锁。Ask发送QueryMessage并返回回复的Future。
+package actor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/future"
+)
+
+// Message Actor之间传递的消息，GetType决定由哪个处理器处理
+type Message interface {
+	GetType() string
+}
+
+// StartMessage 启动消息
+type StartMessage struct{}
+
+func (m StartMessage) GetType() string { return "START" }
+
+// StopMessage 停止消息，Actor处理到它时退出消息循环
+type StopMessage struct{}
+
+func (m StopMessage) GetType() string { return "STOP" }
+
+// DataMessage 携带任意数据的消息
+type DataMessage struct {
+	Data   interface{}
+	Sender string
+}
+
+func (m DataMessage) GetType() string { return "DATA" }
+
+// QueryMessage 请求-响应消息，Actor通过Reply回复
+// 完成Promise不会阻塞，即使提问方已经超时离开，Actor也不会被卡住
+type QueryMessage struct {
+	Query string
+	Reply *future.Promise[interface{}]
+}
+
+func (m QueryMessage) GetType() string { return "QUERY" }
+
+// Actor接口
+type Actor interface {
+	Start()
+	Stop()
+	Send(msg Message)
+	GetAddress() string
+}
+
+// BaseActor 基础Actor实现，具体的Actor内嵌它并注册自己的处理器
+type BaseActor struct {
+	address  string
+	mailbox  chan Message
+	done     chan bool
+	running  bool
+	handlers map[string]func(Message)
+	logf     func(format string, args ...any)
+	mu       sync.RWMutex
+}
+
+// NewBaseActor 创建Actor，邮箱容量为mailboxSize，已注册START和STOP的默认处理器
+func NewBaseActor(address string, mailboxSize int) *BaseActor {
+	actor := &BaseActor{
+		address:  address,
+		mailbox:  make(chan Message, mailboxSize),
+		done:     make(chan bool),
+		handlers: make(map[string]func(Message)),
+		logf:     func(string, ...any) {},
+	}
+
+	// 注册默认处理器
+	actor.RegisterHandler("START", actor.handleStart)
+	actor.RegisterHandler("STOP", actor.handleStop)
+
+	return actor
+}
+
+// SetLogf 设置记录启动、停止、丢弃等事件的函数，为nil时不记录；需要在Start之前调用
+func (a *BaseActor) SetLogf(logf func(format string, args ...any)) {
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	a.logf = logf
+}
+
+// RegisterHandler 注册msgType类型消息的处理器，已有的处理器会被替换
+func (a *BaseActor) RegisterHandler(msgType string, handler func(Message)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handlers[msgType] = handler
+}
+
+// Start 启动消息循环，重复调用没有效果
+func (a *BaseActor) Start() {
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return
+	}
+	a.running = true
+	a.mu.Unlock()
+
+	a.logf("Actor %s 启动\n", a.address)
+
+	go a.messageLoop()
+}
+
+// Stop 发送停止消息，邮箱中排在它前面的消息仍会被处理
+func (a *BaseActor) Stop() {
+	a.mu.RLock()
+	if !a.running {
+		a.mu.RUnlock()
+		return
+	}
+	a.mu.RUnlock()
+
+	a.Send(StopMessage{})
+}
+
+// Send 把消息放入邮箱，不阻塞；邮箱已满时丢弃消息
+func (a *BaseActor) Send(msg Message) {
+	select {
+	case a.mailbox <- msg:
+	default:
+		a.logf("Actor %s 邮箱已满，消息被丢弃: %s\n", a.address, msg.GetType())
+	}
+}
+
+// GetAddress 返回Actor的地址
+func (a *BaseActor) GetAddress() string {
+	return a.address
+}
+
+func (a *BaseActor) messageLoop() {
+	for {
+		select {
+		case msg := <-a.mailbox:
+			a.processMessage(msg)
+		case <-a.done:
+			return
+		}
+	}
+}
+
+func (a *BaseActor) processMessage(msg Message) {
+	a.mu.RLock()
+	handler, exists := a.handlers[msg.GetType()]
+	a.mu.RUnlock()
+
+	if exists {
+		handler(msg)
+	} else {
+		a.logf("Actor %s 收到未知消息类型: %s\n", a.address, msg.GetType())
+	}
+}
+
+func (a *BaseActor) handleStart(msg Message) {
+	a.logf("Actor %s 收到启动消息\n", a.address)
+}
+
+func (a *BaseActor) handleStop(msg Message) {
+	a.logf("Actor %s 收到停止消息，准备关闭\n", a.address)
+	a.mu.Lock()
+	a.running = false
+	a.mu.Unlock()
+	close(a.done)
+}
+
+// System 按地址管理一组Actor
+type System struct {
+	actors map[string]Actor
+	mu     sync.RWMutex
+}
+
+// NewSystem 创建空的Actor系统
+func NewSystem() *System {
+	return &System{
+		actors: make(map[string]Actor),
+	}
+}
+
+// RegisterActor 按地址注册Actor，地址相同时替换
+func (s *System) RegisterActor(actor Actor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actors[actor.GetAddress()] = actor
+}
+
+// GetActor 按地址查找Actor，不存在时返回nil
+func (s *System) GetActor(address string) Actor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.actors[address]
+}
+
+// StartAll 启动所有Actor
+func (s *System) StartAll() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, actor := range s.actors {
+		actor.Start()
+	}
+}
+
+// StopAll 向所有Actor发送停止消息
+func (s *System) StopAll() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, actor := range s.actors {
+		actor.Stop()
+	}
+}
+
+// Ask 向Actor发送查询并返回结果的Future，timeout内没有回复时以future.ErrTimeout失败
+func Ask(actor Actor, query string, timeout time.Duration) *future.Future[interface{}] {
+	reply := future.NewPromise[interface{}]()
+	actor.Send(QueryMessage{Query: query, Reply: reply})
+	return future.WithTimeout(reply.Future(), timeout)
+}
@@ -0,0 +1,158 @@
+// Package barrier 提供可重复使用的循环屏障 CyclicBarrier。
+//
+// parties个goroutine各自调用Await，最后一个到达的goroutine先执行屏障动作，然后放行所有人，
+// 屏障自动进入下一代(generation)，可以用于多轮同步。
+//
+// 屏障损坏：等待中的某个调用方ctx结束、屏障动作返回错误或panic、或者调用了Reset，
+// 当前这一代的所有等待者都返回错误，之后的Await立即返回ErrBrokenBarrier，直到Reset。
+// 这样一个参与者退出时，其他参与者不会永远等下去。
+package barrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrBrokenBarrier 屏障已损坏，错误链中还包含导致损坏的原因
+	ErrBrokenBarrier = errors.New("barrier: broken")
+	// ErrReset 屏障被Reset时仍在等待的调用方收到的损坏原因
+	ErrReset = errors.New("barrier: reset")
+)
+
+// generation 屏障的一代，所有参与者到达或屏障损坏时关闭done
+type generation struct {
+	done   chan struct{}
+	broken bool
+	cause  error
+}
+
+func (g *generation) err() error {
+	return fmt.Errorf("%w: %w", ErrBrokenBarrier, g.cause)
+}
+
+// CyclicBarrier 循环屏障
+type CyclicBarrier struct {
+	parties int
+	action  func() error
+
+	mu    sync.Mutex
+	gen   *generation
+	count int // 当前这一代已到达的数量
+}
+
+// New 创建parties个参与者的屏障，action在每一代最后一个参与者到达时由它执行一次，可以为nil
+// action执行时持有屏障的锁，不能在action中调用屏障的方法
+func New(parties int, action func() error) *CyclicBarrier {
+	if parties <= 0 {
+		panic("barrier: parties must be positive")
+	}
+	return &CyclicBarrier{parties: parties, action: action, gen: &generation{done: make(chan struct{})}}
+}
+
+// Parties 参与者数量
+func (b *CyclicBarrier) Parties() int { return b.parties }
+
+// Await 等待所有参与者到达，返回到达序号：parties-1表示第一个到达，0表示最后一个到达
+// ctx结束时屏障损坏，本调用方返回ctx.Err()，其他等待者返回ErrBrokenBarrier
+func (b *CyclicBarrier) Await(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	g := b.gen
+	if g.broken {
+		b.mu.Unlock()
+		return 0, g.err()
+	}
+	if err := ctx.Err(); err != nil {
+		b.breakLocked(err)
+		b.mu.Unlock()
+		return 0, err
+	}
+
+	index := b.parties - 1 - b.count
+	b.count++
+	if index == 0 {
+		if err := b.runAction(); err != nil {
+			b.breakLocked(err)
+			b.mu.Unlock()
+			return 0, g.err()
+		}
+		b.nextLocked()
+		b.mu.Unlock()
+		return 0, nil
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-g.done:
+	case <-ctx.Done():
+		b.mu.Lock()
+		// 可能在ctx结束的同时这一代已经放行或被别人弄坏了，这时按那个结果返回
+		if b.gen == g && !g.broken {
+			b.breakLocked(ctx.Err())
+			b.mu.Unlock()
+			return index, ctx.Err()
+		}
+		b.mu.Unlock()
+	}
+	// broken在close(done)之前设置，这里读取不需要加锁
+	if g.broken {
+		return index, g.err()
+	}
+	return index, nil
+}
+
+func (b *CyclicBarrier) runAction() (err error) {
+	if b.action == nil {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("barrier action panic: %v", r)
+		}
+	}()
+	return b.action()
+}
+
+// breakLocked 损坏当前这一代并唤醒所有等待者
+func (b *CyclicBarrier) breakLocked(cause error) {
+	b.gen.broken = true
+	b.gen.cause = cause
+	close(b.gen.done)
+}
+
+// nextLocked 放行当前这一代并开始下一代
+func (b *CyclicBarrier) nextLocked() {
+	close(b.gen.done)
+	b.gen = &generation{done: make(chan struct{})}
+	b.count = 0
+}
+
+// Reset 把屏障恢复到初始状态；仍在等待的调用方收到包含ErrReset的ErrBrokenBarrier
+func (b *CyclicBarrier) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.gen.broken {
+		b.breakLocked(ErrReset)
+	}
+	b.gen = &generation{done: make(chan struct{})}
+	b.count = 0
+}
+
+// Broken 当前这一代是否已损坏
+func (b *CyclicBarrier) Broken() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gen.broken
+}
+
+// Waiting 当前这一代正在等待的参与者数量
+func (b *CyclicBarrier) Waiting() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.gen.broken {
+		return 0
+	}
+	return b.count
+}
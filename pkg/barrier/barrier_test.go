@@ -0,0 +1,202 @@
+package barrier
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// awaitWaiting 等到当前这一代有n个参与者在等待
+func awaitWaiting(t *testing.T, b *CyclicBarrier, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); b.Waiting() < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Waiting() = %d, want %d", b.Waiting(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// collectErrs 收集n个Await的结果，超时说明有等待者没有被唤醒
+func collectErrs(t *testing.T, errs <-chan error, n int) []error {
+	t.Helper()
+	got := make([]error, 0, n)
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-errs:
+			got = append(got, err)
+		case <-time.After(time.Second):
+			t.Fatalf("只有 %d 个等待者返回, want %d", len(got), n)
+		}
+	}
+	return got
+}
+
+// TestGenerations 每一代所有参与者都被放行，到达序号各不相同，屏障动作每一代恰好执行一次，
+// 并且在放行之前执行
+func TestGenerations(t *testing.T) {
+	const parties, rounds = 5, 50
+
+	var arrived, actions atomic.Int64
+	b := New(parties, func() error {
+		n := actions.Add(1)
+		if a := arrived.Load(); a != n*parties {
+			t.Errorf("第 %d 代的动作执行时已到达 %d 个, want %d", n, a, n*parties)
+		}
+		return nil
+	})
+
+	var mu sync.Mutex
+	indexes := make([][]int, rounds)
+	var wg sync.WaitGroup
+	for p := 0; p < parties; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				arrived.Add(1)
+				index, err := b.Await(context.Background())
+				if err != nil {
+					t.Errorf("第 %d 代 Await() err = %v", r, err)
+					return
+				}
+				if n := actions.Load(); n < int64(r+1) {
+					t.Errorf("第 %d 代放行时动作只执行了 %d 次", r, n)
+				}
+				mu.Lock()
+				indexes[r] = append(indexes[r], index)
+				mu.Unlock()
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("参与者没有全部通过屏障")
+	}
+
+	if n := actions.Load(); n != rounds {
+		t.Fatalf("动作执行了 %d 次, want %d", n, rounds)
+	}
+	for r, idx := range indexes {
+		sort.Ints(idx)
+		for i, v := range idx {
+			if v != i {
+				t.Fatalf("第 %d 代的到达序号 %v, want 0..%d各一个", r, idx, parties-1)
+			}
+		}
+		if len(idx) != parties {
+			t.Fatalf("第 %d 代放行了 %d 个参与者, want %d", r, len(idx), parties)
+		}
+	}
+}
+
+// TestBreak ctx取消、Reset或屏障动作失败都会损坏当前这一代，所有等待者都返回错误
+func TestBreak(t *testing.T) {
+	errAction := errors.New("动作失败")
+	tests := []struct {
+		name   string
+		cause  error
+		direct int // 直接返回ctx.Err()而不是ErrBrokenBarrier的等待者数量
+		// brk 在两个参与者等待时损坏屏障；cancel是第一个等待者ctx的取消函数
+		brk func(b *CyclicBarrier, cancel context.CancelFunc) error
+	}{
+		{"ctx取消", context.Canceled, 1, func(b *CyclicBarrier, cancel context.CancelFunc) error {
+			cancel()
+			return nil
+		}},
+		{"Reset", ErrReset, 0, func(b *CyclicBarrier, cancel context.CancelFunc) error {
+			b.Reset()
+			return nil
+		}},
+		{"动作失败", errAction, 0, func(b *CyclicBarrier, cancel context.CancelFunc) error {
+			_, err := b.Await(context.Background())
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(3, func() error { return errAction })
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			errs := make(chan error, 2)
+			go func() {
+				_, err := b.Await(ctx)
+				errs <- err
+			}()
+			go func() {
+				_, err := b.Await(context.Background())
+				errs <- err
+			}()
+			awaitWaiting(t, b, 2)
+
+			if err := tt.brk(b, cancel); err != nil {
+				if !errors.Is(err, ErrBrokenBarrier) || !errors.Is(err, tt.cause) {
+					t.Fatalf("最后到达者 err = %v, want ErrBrokenBarrier和%v", err, tt.cause)
+				}
+			}
+			direct := 0
+			for _, err := range collectErrs(t, errs, 2) {
+				if !errors.Is(err, tt.cause) {
+					t.Fatalf("等待者 err = %v, want %v", err, tt.cause)
+				}
+				if !errors.Is(err, ErrBrokenBarrier) {
+					direct++
+				}
+			}
+			if direct != tt.direct {
+				t.Fatalf("%d 个等待者没有返回ErrBrokenBarrier, want %d", direct, tt.direct)
+			}
+
+			if tt.cause != ErrReset {
+				if !b.Broken() {
+					t.Fatal("Broken() = false, want true")
+				}
+				if _, err := b.Await(context.Background()); !errors.Is(err, ErrBrokenBarrier) {
+					t.Fatalf("损坏后 Await() err = %v, want ErrBrokenBarrier", err)
+				}
+				b.Reset()
+			}
+			if b.Broken() || b.Waiting() != 0 {
+				t.Fatalf("Reset后 Broken() = %v, Waiting() = %d, want false, 0", b.Broken(), b.Waiting())
+			}
+		})
+	}
+}
+
+// TestResetReuse Reset之后屏障可以继续用于新的一代
+func TestResetReuse(t *testing.T) {
+	b := New(2, nil)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		errs <- err
+	}()
+	awaitWaiting(t, b, 1)
+	b.Reset()
+	if err := collectErrs(t, errs, 1)[0]; !errors.Is(err, ErrReset) {
+		t.Fatalf("Reset时等待者 err = %v, want ErrReset", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := b.Await(context.Background())
+			errs <- err
+		}()
+	}
+	for _, err := range collectErrs(t, errs, 2) {
+		if err != nil {
+			t.Fatalf("Reset后 Await() err = %v, want nil", err)
+		}
+	}
+}
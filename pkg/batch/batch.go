@@ -0,0 +1,248 @@
+// Package batch 提供把逐个提交的元素合并成批次处理的 Processor[T]。
+//
+// 调用方一次提交一个元素并拿到一个Future，后台收集goroutine攒够MaxSize个元素，
+// 或者批次中第一个元素已等待MaxDelay时，调用一次Flush处理整批（例如一条批量INSERT）。
+// Flush的结果会完成批次中每个元素的Future。
+//
+// Flush在收集goroutine中串行执行：Flush变慢时队列会被填满，
+// 这时Submit按溢出策略阻塞等待或立即返回ErrQueueFull，把压力反馈给调用方。
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/future"
+)
+
+var (
+	// ErrClosed Processor已关闭，不再接收元素
+	ErrClosed = errors.New("batch: processor closed")
+	// ErrQueueFull 队列已满且溢出策略为Reject
+	ErrQueueFull = errors.New("batch: queue full")
+)
+
+// Overflow 队列满时Submit的行为
+type Overflow int
+
+const (
+	Block  Overflow = iota // 阻塞直到有空位或ctx结束
+	Reject                 // 立即返回ErrQueueFull
+)
+
+// Reason 批次被刷新的原因
+type Reason int
+
+const (
+	BySize  Reason = iota // 达到MaxSize
+	ByDelay               // 第一个元素等待超过MaxDelay
+	ByClose               // Close时刷新剩余元素
+)
+
+func (r Reason) String() string {
+	switch r {
+	case BySize:
+		return "size"
+	case ByDelay:
+		return "delay"
+	default:
+		return "close"
+	}
+}
+
+// Flushed 元素所在批次的信息，作为Future的结果
+type Flushed struct {
+	Batch  int64 // 批次序号，从1开始
+	Size   int
+	Reason Reason
+}
+
+// Config 批处理参数
+type Config struct {
+	MaxSize   int           // 每批最多元素数
+	MaxDelay  time.Duration // 批次中第一个元素最多等待多久
+	QueueSize int           // 等待收集的元素队列容量
+	Overflow  Overflow
+}
+
+// Stats 累计统计
+type Stats struct {
+	Items    int64
+	Batches  int64
+	BySize   int64
+	ByDelay  int64
+	Failed   int64 // Flush返回错误的批次数
+	Rejected int64 // 因队列满被拒绝的元素数
+}
+
+type entry[T any] struct {
+	item    T
+	promise *future.Promise[Flushed]
+}
+
+// Processor 批处理器
+type Processor[T any] struct {
+	cfg   Config
+	flush func(ctx context.Context, items []T) error
+
+	mu     sync.RWMutex // Submit持读锁发送，Close持写锁关闭queue
+	closed bool
+	queue  chan entry[T]
+	done   chan struct{}
+
+	ctx    context.Context // 传给Flush，Close超时后被取消
+	cancel context.CancelFunc
+
+	items, batches, bySize, byDelay, failed, rejected atomic.Int64
+}
+
+// New 创建并启动批处理器
+func New[T any](cfg Config, flush func(ctx context.Context, items []T) error) *Processor[T] {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 100
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 10 * time.Millisecond
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = cfg.MaxSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Processor[T]{
+		cfg:    cfg,
+		flush:  flush,
+		queue:  make(chan entry[T], cfg.QueueSize),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go p.collect()
+	return p
+}
+
+// Submit 提交一个元素，返回的Future在元素所在批次刷新后完成，Flush失败时以该错误失败
+func (p *Processor[T]) Submit(ctx context.Context, item T) (*future.Future[Flushed], error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, ErrClosed
+	}
+
+	e := entry[T]{item: item, promise: future.NewPromise[Flushed]()}
+	select {
+	case p.queue <- e:
+		return e.promise.Future(), nil
+	default:
+	}
+
+	if p.cfg.Overflow == Reject {
+		p.rejected.Add(1)
+		return nil, ErrQueueFull
+	}
+	select {
+	case p.queue <- e:
+		return e.promise.Future(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// collect 攒批并刷新，批次中第一个元素到达时启动MaxDelay计时器
+func (p *Processor[T]) collect() {
+	defer close(p.done)
+
+	var pending []entry[T]
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+		select {
+		case e, ok := <-p.queue:
+			if !ok {
+				if len(pending) > 0 {
+					p.run(pending, ByClose)
+				}
+				return
+			}
+			pending = append(pending, e)
+			if len(pending) == 1 {
+				timer.Reset(p.cfg.MaxDelay)
+			}
+			if len(pending) == p.cfg.MaxSize {
+				// 停止计时器并清空可能已到期的信号，避免下一批被提前刷新
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				p.run(pending, BySize)
+				pending = nil
+			}
+		case <-timer.C:
+			if len(pending) > 0 {
+				p.run(pending, ByDelay)
+				pending = nil
+			}
+		}
+	}
+}
+
+func (p *Processor[T]) run(pending []entry[T], reason Reason) {
+	items := make([]T, len(pending))
+	for i, e := range pending {
+		items[i] = e.item
+	}
+
+	err := p.flush(p.ctx, items)
+
+	info := Flushed{Batch: p.batches.Add(1), Size: len(items), Reason: reason}
+	p.items.Add(int64(len(items)))
+	switch reason {
+	case BySize:
+		p.bySize.Add(1)
+	case ByDelay:
+		p.byDelay.Add(1)
+	}
+	if err != nil {
+		p.failed.Add(1)
+	}
+	for _, e := range pending {
+		e.promise.Complete(info, err)
+	}
+}
+
+// Close 不再接收元素，刷新队列中剩余的元素后返回
+// ctx到期时取消传给Flush的ctx并返回ctx.Err()
+func (p *Processor[T]) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stats 当前累计统计
+func (p *Processor[T]) Stats() Stats {
+	return Stats{
+		Items:    p.items.Load(),
+		Batches:  p.batches.Load(),
+		BySize:   p.bySize.Load(),
+		ByDelay:  p.byDelay.Load(),
+		Failed:   p.failed.Load(),
+		Rejected: p.rejected.Load(),
+	}
+}
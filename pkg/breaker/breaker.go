@@ -0,0 +1,182 @@
+// Package breaker 提供按失败率触发的熔断器。
+//
+// 三种状态：CLOSED正常通过请求；失败率达到阈值后转为OPEN，快速失败、不调用下游；
+// 等待ResetTimeout后转为HALF_OPEN，放行请求试探下游是否恢复，成功则回到CLOSED，失败则回到OPEN。
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOpen 熔断器处于OPEN状态，请求没有执行
+var ErrOpen = errors.New("circuit breaker is OPEN")
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 关闭状态：正常通过请求
+	StateOpen                  // 开启状态：拒绝请求，快速失败
+	StateHalfOpen              // 半开状态：允许少量请求试探
+)
+
+// String 实现Stringer接口，便于打印状态
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "CLOSED"
+	case StateOpen:
+		return "OPEN"
+	case StateHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Transition 一次状态变化，Requests和Failures是变化时的累计计数
+type Transition struct {
+	From, To State
+	Requests int64
+	Failures int64
+}
+
+// FailureRatio 变化时的失败率，没有请求时为0
+func (t Transition) FailureRatio() float64 {
+	if t.Requests == 0 {
+		return 0
+	}
+	return float64(t.Failures) / float64(t.Requests)
+}
+
+// Config 熔断器配置参数
+type Config struct {
+	ResetTimeout    time.Duration // 从OPEN到HALF_OPEN的等待时间
+	FailureRatio    float64       // 失败率阈值（0.0-1.0）
+	MinRequestCount int           // 最小请求数，低于此数不触发熔断
+
+	// OnStateChange 状态变化时调用，可以为nil。调用时持有熔断器的锁，不能再调用熔断器的方法
+	OnStateChange func(Transition)
+
+	// Now 读取当前时间，为nil时使用time.Now；测试时可以换成手动前进的时钟
+	Now func() time.Time
+}
+
+// Breaker 熔断器
+type Breaker struct {
+	config       Config
+	state        State
+	failures     int64 // 失败计数（原子操作）
+	requests     int64 // 请求计数（原子操作）
+	lastFailTime time.Time
+	mu           sync.RWMutex // 保护状态变更
+}
+
+// New 创建熔断器，初始状态为CLOSED
+func New(config Config) *Breaker {
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Breaker{config: config, state: StateClosed}
+}
+
+// Call 执行被保护的函数调用；OPEN状态下不调用fn，返回ErrOpen
+func (b *Breaker) Call(fn func() error) error {
+	if !b.allowRequest() {
+		return ErrOpen
+	}
+
+	// 增加请求计数（用defer确保一定执行）
+	defer atomic.AddInt64(&b.requests, 1)
+
+	if err := fn(); err != nil {
+		b.onFailure()
+		return err
+	}
+	b.onSuccess()
+	return nil
+}
+
+// setState 切换状态并通知，调用方持有写锁
+func (b *Breaker) setState(to State) {
+	t := Transition{From: b.state, To: to,
+		Requests: atomic.LoadInt64(&b.requests), Failures: atomic.LoadInt64(&b.failures)}
+	b.state = to
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(t)
+	}
+}
+
+// allowRequest 检查当前状态是否允许请求通过
+func (b *Breaker) allowRequest() bool {
+	b.mu.RLock()
+	state, lastFail := b.state, b.lastFailTime
+	b.mu.RUnlock()
+
+	switch state {
+	case StateClosed, StateHalfOpen:
+		return true
+	case StateOpen:
+		if b.config.Now().Sub(lastFail) <= b.config.ResetTimeout {
+			return false
+		}
+		// 达到重置时间，转为半开状态；加写锁后重新检查，其他goroutine可能已经切换过了
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.state == StateOpen {
+			b.setState(StateHalfOpen)
+		}
+		return b.state != StateOpen
+	default:
+		return false
+	}
+}
+
+// onSuccess 半开状态下的成功说明下游恢复，转为关闭状态
+func (b *Breaker) onSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateHalfOpen {
+		b.setState(StateClosed)
+		atomic.StoreInt64(&b.failures, 0)
+	}
+}
+
+// onFailure 记录失败；半开状态下立即转为开启，关闭状态下失败率达到阈值时转为开启
+func (b *Breaker) onFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	atomic.AddInt64(&b.failures, 1)
+	b.lastFailTime = b.config.Now()
+
+	switch b.state {
+	case StateHalfOpen:
+		b.setState(StateOpen)
+	case StateClosed:
+		requests := atomic.LoadInt64(&b.requests)
+		failures := atomic.LoadInt64(&b.failures)
+		// 只有在请求数达到最小值时才考虑熔断
+		if requests >= int64(b.config.MinRequestCount) &&
+			float64(failures)/float64(requests) >= b.config.FailureRatio {
+			b.setState(StateOpen)
+		}
+	}
+}
+
+// State 当前状态
+func (b *Breaker) State() State {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.state
+}
+
+// Stats 累计请求数、失败数和当前状态
+func (b *Breaker) Stats() (requests, failures int64, state State) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return atomic.LoadInt64(&b.requests), atomic.LoadInt64(&b.failures), b.state
+}
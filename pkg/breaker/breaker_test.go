@@ -0,0 +1,187 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("下游不可用")
+
+// clock 手动前进的时钟
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// step 一次调用：先让时钟前进wait，再调用下游；fail为true时下游返回错误
+type step struct {
+	wait    time.Duration
+	fail    bool
+	wantErr error
+	want    State // 调用之后的状态
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	ok := func(want State) step { return step{want: want} }
+	fail := func(want State) step { return step{fail: true, wantErr: errDown, want: want} }
+	rejected := step{wantErr: ErrOpen, want: StateOpen}
+	after := func(d time.Duration, s step) step { s.wait = d; return s }
+
+	tests := []struct {
+		name  string
+		steps []step
+		want  []Transition // 只比较From和To
+	}{
+		{
+			name:  "请求数不足时不熔断",
+			steps: []step{fail(StateClosed), fail(StateClosed), fail(StateClosed)},
+		},
+		{
+			name:  "失败率达到阈值后熔断",
+			steps: []step{ok(StateClosed), ok(StateClosed), fail(StateClosed), fail(StateOpen), rejected},
+			want:  []Transition{{From: StateClosed, To: StateOpen}},
+		},
+		{
+			name:  "失败率低于阈值保持关闭",
+			steps: []step{ok(StateClosed), ok(StateClosed), ok(StateClosed), ok(StateClosed), ok(StateClosed), fail(StateClosed)},
+		},
+		{
+			name: "ResetTimeout之内保持开启",
+			steps: []step{ok(StateClosed), fail(StateClosed), fail(StateClosed), fail(StateOpen),
+				after(time.Second, rejected), after(time.Second, rejected)},
+			want: []Transition{{From: StateClosed, To: StateOpen}},
+		},
+		{
+			name: "半开试探成功后关闭",
+			steps: []step{fail(StateClosed), fail(StateClosed), fail(StateClosed), fail(StateOpen),
+				after(3*time.Second, ok(StateClosed)), ok(StateClosed)},
+			want: []Transition{
+				{From: StateClosed, To: StateOpen},
+				{From: StateOpen, To: StateHalfOpen},
+				{From: StateHalfOpen, To: StateClosed},
+			},
+		},
+		{
+			name: "半开试探失败后重新开启并重新计时",
+			steps: []step{fail(StateClosed), fail(StateClosed), fail(StateClosed), fail(StateOpen),
+				after(3*time.Second, fail(StateOpen)), after(time.Second, rejected), after(2*time.Second, ok(StateClosed))},
+			want: []Transition{
+				{From: StateClosed, To: StateOpen},
+				{From: StateOpen, To: StateHalfOpen},
+				{From: StateHalfOpen, To: StateOpen},
+				{From: StateOpen, To: StateHalfOpen},
+				{From: StateHalfOpen, To: StateClosed},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &clock{now: time.Unix(0, 0)}
+			var got []Transition
+			b := New(Config{
+				ResetTimeout:    2 * time.Second,
+				FailureRatio:    0.5,
+				MinRequestCount: 3,
+				Now:             c.Now,
+				OnStateChange:   func(tr Transition) { got = append(got, Transition{From: tr.From, To: tr.To}) },
+			})
+			for i, s := range tt.steps {
+				c.Advance(s.wait)
+				called := false
+				err := b.Call(func() error {
+					called = true
+					if s.fail {
+						return errDown
+					}
+					return nil
+				})
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("第%d步: Call() = %v, want %v", i+1, err, s.wantErr)
+				}
+				if called == (s.wantErr == ErrOpen) {
+					t.Fatalf("第%d步: 下游是否被调用 = %v", i+1, called)
+				}
+				if st := b.State(); st != s.want {
+					t.Fatalf("第%d步: State() = %v, want %v", i+1, st, s.want)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("状态变化 %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("状态变化 %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestBreakerConcurrent 并发调用时每次状态变化都从上一次变化到的状态开始，不会重复或跳过
+func TestBreakerConcurrent(t *testing.T) {
+	c := &clock{now: time.Unix(0, 0)}
+	var (
+		mu          sync.Mutex
+		transitions []Transition
+	)
+	b := New(Config{
+		ResetTimeout:    time.Second,
+		FailureRatio:    0.5,
+		MinRequestCount: 10,
+		Now:             c.Now,
+		OnStateChange: func(tr Transition) {
+			mu.Lock()
+			transitions = append(transitions, tr)
+			mu.Unlock()
+		},
+	})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if g == 0 && i%50 == 0 {
+					c.Advance(2 * time.Second)
+				}
+				b.Call(func() error {
+					if (g+i)%3 == 0 {
+						return nil
+					}
+					return errDown
+				})
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if len(transitions) == 0 {
+		t.Fatal("失败率约2/3，熔断器应该至少熔断一次")
+	}
+	prev := StateClosed
+	for i, tr := range transitions {
+		if tr.From != prev || tr.From == tr.To {
+			t.Fatalf("第%d次状态变化 %v -> %v，上一个状态是 %v", i+1, tr.From, tr.To, prev)
+		}
+		prev = tr.To
+	}
+	requests, failures, state := b.Stats()
+	if state != prev || failures > requests {
+		t.Fatalf("Stats() = %d, %d, %v；最后一次状态变化到 %v", requests, failures, state, prev)
+	}
+}
@@ -0,0 +1,95 @@
+// Package bufpool 提供三种获取固定大小缓冲区的方式，用于对比对象池的效果：
+//
+//   - NoPool：每次都分配新的缓冲区
+//   - SyncPool：基于sync.Pool，按P缓存、几乎无锁，但池中对象可能在GC时被清空
+//   - BoundedPool：用带缓冲的channel保存有限个空闲缓冲区，不受GC影响
+//
+// 池中存放的是*[]byte而不是[]byte：把切片头转换成interface{}本身就要分配。
+package bufpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Pool 三种方式的共同接口
+type Pool interface {
+	Get() *[]byte
+	Put(b *[]byte)
+}
+
+// NoPool 每次都分配Size字节的新缓冲区
+type NoPool struct{ Size int }
+
+func (p NoPool) Get() *[]byte {
+	b := make([]byte, p.Size)
+	return &b
+}
+
+func (p NoPool) Put(*[]byte) {}
+
+// SyncPool 基于sync.Pool
+type SyncPool struct {
+	pool sync.Pool
+	news atomic.Int64
+}
+
+func NewSyncPool(size int) *SyncPool {
+	p := &SyncPool{}
+	p.pool.New = func() any {
+		p.news.Add(1)
+		b := make([]byte, size)
+		return &b
+	}
+	return p
+}
+
+func (p *SyncPool) Get() *[]byte  { return p.pool.Get().(*[]byte) }
+func (p *SyncPool) Put(b *[]byte) { p.pool.Put(b) }
+
+// News New被调用的次数，即池中没有可用对象的次数
+func (p *SyncPool) News() int64 { return p.news.Load() }
+
+// BoundedPool 用带缓冲的channel保存最多maxIdle个空闲缓冲区
+// Get时没有空闲的就新分配，Put时已满或容量不对就丢弃，交给GC回收
+type BoundedPool struct {
+	size int
+	free chan *[]byte
+
+	hits, misses, drops atomic.Int64
+}
+
+func NewBoundedPool(size, maxIdle int) *BoundedPool {
+	return &BoundedPool{size: size, free: make(chan *[]byte, maxIdle)}
+}
+
+func (p *BoundedPool) Get() *[]byte {
+	select {
+	case b := <-p.free:
+		p.hits.Add(1)
+		return b
+	default:
+		p.misses.Add(1)
+		b := make([]byte, p.size)
+		return &b
+	}
+}
+
+// Put 归还缓冲区；被扩容过的缓冲区放回池中会一直占着内存，所以容量不对的直接丢弃
+func (p *BoundedPool) Put(b *[]byte) {
+	if cap(*b) != p.size {
+		p.drops.Add(1)
+		return
+	}
+	*b = (*b)[:p.size]
+	select {
+	case p.free <- b:
+	default:
+		p.drops.Add(1)
+	}
+}
+
+// Stats Get命中和未命中空闲缓冲区的次数，以及Put时丢弃的次数
+func (p *BoundedPool) Stats() (hits, misses, drops int64) {
+	return p.hits.Load(), p.misses.Load(), p.drops.Load()
+}
@@ -0,0 +1,85 @@
+package bufpool
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestBoundedPool(t *testing.T) {
+	p := NewBoundedPool(1024, 2)
+
+	a, b, c := p.Get(), p.Get(), p.Get()
+	p.Put(a)
+	p.Put(b)
+	p.Put(c) // 空闲数已达上限，丢弃
+	if hits, misses, drops := p.Stats(); hits != 0 || misses != 3 || drops != 1 {
+		t.Fatalf("Stats() = %d, %d, %d, want 0, 3, 1", hits, misses, drops)
+	}
+
+	// 取回的是归还的缓冲区，长度恢复成size
+	*a = (*a)[:10]
+	p.Get()
+	p.Get()
+	p.Put(a)
+	if got := p.Get(); got != a || len(*got) != 1024 {
+		t.Fatalf("Get()没有复用归还的缓冲区，或长度 %d 没有恢复", len(*got))
+	}
+
+	big := make([]byte, 4096)
+	p.Put(&big)
+	if hits, _, drops := p.Stats(); hits != 3 || drops != 2 {
+		t.Fatalf("Stats() hits=%d drops=%d, want 3, 2：容量不对的缓冲区应该被丢弃", hits, drops)
+	}
+}
+
+// TestBoundedPoolSurvivesGC 有界池中的空闲缓冲区不会被GC清空
+func TestBoundedPoolSurvivesGC(t *testing.T) {
+	p := NewBoundedPool(1024, 1)
+	b := p.Get()
+	p.Put(b)
+	runtime.GC()
+	runtime.GC()
+	if p.Get() != b {
+		t.Fatal("2次GC后有界池中的缓冲区丢失")
+	}
+}
+
+// handle 模拟一次请求：取缓冲区，填充数据并计算校验和，归还缓冲区
+func handle(p Pool) uint32 {
+	bp := p.Get()
+	b := *bp
+	for i := 0; i < len(b); i += 64 {
+		b[i] = byte(i)
+	}
+	var sum uint32
+	for i := 0; i < len(b); i += 256 {
+		sum += uint32(b[i])
+	}
+	p.Put(bp)
+	return sum
+}
+
+// BenchmarkPools 每次操作 = 处理一个64KB缓冲区的请求，RunParallel并发执行
+//
+//	go test -bench Pools ./pkg/bufpool
+func BenchmarkPools(b *testing.B) {
+	const size = 64 << 10
+	pools := []struct {
+		name string
+		pool Pool
+	}{
+		{"NoPool", NoPool{Size: size}},
+		{"SyncPool", NewSyncPool(size)},
+		{"BoundedPool", NewBoundedPool(size, runtime.GOMAXPROCS(0))},
+	}
+	for _, p := range pools {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					handle(p.pool)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,279 @@
+// Package cache 提供分片的并发LRU缓存 LRUCache[K, V]。
+//
+//   - 键按哈希分到多个分片，每个分片有自己的锁和LRU链表，不同分片的访问互不阻塞
+//   - 每个分片容量为 MaxSize/Shards，超出时淘汰该分片中最久未使用的条目
+//   - 每个条目有过期时间，过期的条目在被访问时删除并计为未命中
+//   - GetOrLoad 在未命中时调用Loader加载；同一个键的并发未命中只触发一次加载，其余调用方等待并共享结果；
+//     Loader的panic转换成*PanicError返回给所有等待者
+//
+// 另外提供写回缓存 WriteBehind[K, V]：写入只更新内存，后台批量写入慢速后端，失败时重试，
+// 未写入的条目数有上限，达到上限时写入者等待（背压）。
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config 缓存配置
+type Config[K comparable, V any] struct {
+	MaxSize int           // 总容量
+	Shards  int           // 分片数，默认16
+	TTL     time.Duration // 默认过期时间，0表示不过期
+	Loader  func(ctx context.Context, key K) (V, error)
+	Hash    func(key K) uint64 // 为nil时对fmt.Sprint(key)做FNV哈希
+	Now     func() time.Time   // 为nil时使用time.Now，演示中可以替换成手动推进的时钟
+}
+
+// Stats 累计统计
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Loads       int64 // Loader实际被调用的次数
+	Shared      int64 // 等待其他调用方的加载结果、没有自己调用Loader的次数
+	LoadErrors  int64
+	Evictions   int64 // 因容量不足被淘汰
+	Expirations int64 // 因过期被删除
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time // 零值表示不过期
+}
+
+// PanicError Loader的panic被转换成的错误，所有等待这次加载的调用方都会收到
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("cache: loader panic: %v", e.Value)
+}
+
+// call 一次正在进行的加载
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type shard[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List // 前端是最近使用的
+	items    map[K]*list.Element
+	loading  map[K]*call[V]
+}
+
+// LRUCache 分片的并发LRU缓存
+type LRUCache[K comparable, V any] struct {
+	cfg    Config[K, V]
+	shards []*shard[K, V]
+
+	hits, misses, loads, shared, loadErrors, evictions, expirations atomic.Int64
+}
+
+// New 创建缓存
+func New[K comparable, V any](cfg Config[K, V]) *LRUCache[K, V] {
+	if cfg.Shards <= 0 {
+		cfg.Shards = 16
+	}
+	if cfg.MaxSize < cfg.Shards {
+		cfg.MaxSize = cfg.Shards
+	}
+	if cfg.Hash == nil {
+		cfg.Hash = func(key K) uint64 {
+			h := fnv.New64a()
+			fmt.Fprint(h, key)
+			return h.Sum64()
+		}
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	c := &LRUCache[K, V]{cfg: cfg}
+	for i := 0; i < cfg.Shards; i++ {
+		c.shards = append(c.shards, &shard[K, V]{
+			capacity: cfg.MaxSize / cfg.Shards,
+			ll:       list.New(),
+			items:    make(map[K]*list.Element),
+			loading:  make(map[K]*call[V]),
+		})
+	}
+	return c
+}
+
+func (c *LRUCache[K, V]) shardFor(key K) *shard[K, V] {
+	return c.shards[c.cfg.Hash(key)%uint64(len(c.shards))]
+}
+
+// lookup 在持有分片锁时查找未过期的条目，并移到链表前端
+func (c *LRUCache[K, V]) lookup(s *shard[K, V], key K) (V, bool) {
+	el, ok := s.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if !e.expires.IsZero() && !c.cfg.Now().Before(e.expires) {
+		s.ll.Remove(el)
+		delete(s.items, key)
+		c.expirations.Add(1)
+		var zero V
+		return zero, false
+	}
+	s.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Get 读取未过期的条目
+func (c *LRUCache[K, V]) Get(key K) (V, bool) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	v, ok := c.lookup(s, key)
+	s.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return v, ok
+}
+
+// Set 使用默认TTL写入
+func (c *LRUCache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.cfg.TTL)
+}
+
+// SetWithTTL 写入并指定过期时间，ttl为0表示不过期
+func (c *LRUCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.store(s, key, value, ttl)
+}
+
+func (c *LRUCache[K, V]) store(s *shard[K, V], key K, value V, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.cfg.Now().Add(ttl)
+	}
+	if el, ok := s.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.ll.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	for s.ll.Len() > s.capacity {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*entry[K, V]).key)
+		c.evictions.Add(1)
+	}
+}
+
+// Delete 删除条目
+func (c *LRUCache[K, V]) Delete(key K) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		s.ll.Remove(el)
+		delete(s.items, key)
+	}
+}
+
+// GetOrLoad 命中时直接返回，未命中时用Loader加载并写入缓存
+// 同一个键同时只有一次加载；加载在独立于调用方取消的ctx中进行，
+// 某个调用方放弃等待不会让其他等待者失败，加载失败的结果不写入缓存
+func (c *LRUCache[K, V]) GetOrLoad(ctx context.Context, key K) (V, error) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	if v, ok := c.lookup(s, key); ok {
+		s.mu.Unlock()
+		c.hits.Add(1)
+		return v, nil
+	}
+	c.misses.Add(1)
+
+	cl, inflight := s.loading[key]
+	if !inflight {
+		cl = &call[V]{done: make(chan struct{})}
+		s.loading[key] = cl
+	}
+	s.mu.Unlock()
+
+	if inflight {
+		c.shared.Add(1)
+	} else {
+		go c.load(s, key, cl, context.WithoutCancel(ctx))
+	}
+
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (c *LRUCache[K, V]) load(s *shard[K, V], key K, cl *call[V], ctx context.Context) {
+	c.loads.Add(1)
+	cl.value, cl.err = c.callLoader(ctx, key)
+
+	s.mu.Lock()
+	delete(s.loading, key)
+	if cl.err == nil {
+		c.store(s, key, cl.value, c.cfg.TTL)
+	} else {
+		c.loadErrors.Add(1)
+	}
+	s.mu.Unlock()
+	close(cl.done)
+}
+
+// callLoader 调用Loader并把panic转换成错误，否则加载goroutine退出后loading中的记录不会删除，
+// 这个键之后的所有GetOrLoad都会一直等待
+func (c *LRUCache[K, V]) callLoader(ctx context.Context, key K) (v V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return c.cfg.Loader(ctx, key)
+}
+
+// Len 当前条目数（包括已过期但还没被访问到的条目）
+func (c *LRUCache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.ll.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Stats 当前累计统计
+func (c *LRUCache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Loads:       c.loads.Load(),
+		Shared:      c.shared.Load(),
+		LoadErrors:  c.loadErrors.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+}
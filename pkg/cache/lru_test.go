@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestLoaderPanic Loader panic时所有等待者都收到*PanicError，之后同一个键可以重新加载
+func TestLoaderPanic(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c := New(Config[string, int]{MaxSize: 16, Loader: func(ctx context.Context, key string) (int, error) {
+		if calls.Add(1) == 1 {
+			<-release
+			panic("boom")
+		}
+		return 42, nil
+	}})
+
+	const waiters = 5
+	errs := make(chan error, waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			_, err := c.GetOrLoad(context.Background(), "k")
+			errs <- err
+		}()
+	}
+	// 等所有调用方都登记到同一次加载上再让Loader panic
+	for deadline := time.Now().Add(time.Second); c.Stats().Misses < waiters; {
+		if time.Now().After(deadline) {
+			t.Fatal("调用方没有全部开始等待")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	for i := 0; i < waiters; i++ {
+		select {
+		case err := <-errs:
+			var pe *PanicError
+			if !errors.As(err, &pe) || pe.Value != "boom" {
+				t.Fatalf("GetOrLoad() err = %v, want *PanicError", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Loader panic后GetOrLoad一直阻塞")
+		}
+	}
+	if s := c.Stats(); s.Loads != 1 || s.LoadErrors != 1 {
+		t.Fatalf("Loads = %d, LoadErrors = %d, want 1, 1", s.Loads, s.LoadErrors)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if v, err := c.GetOrLoad(ctx, "k"); v != 42 || err != nil {
+		t.Fatalf("panic之后GetOrLoad() = %d, %v, want 42, nil", v, err)
+	}
+}
+
+// TestGetOrLoadDedup 同一个键的并发未命中只调用一次Loader，加载成功后命中缓存
+func TestGetOrLoadDedup(t *testing.T) {
+	var calls atomic.Int32
+	c := New(Config[int, int]{MaxSize: 16, Loader: func(ctx context.Context, key int) (int, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return key * 2, nil
+	}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetOrLoad(context.Background(), 7); v != 14 || err != nil {
+				t.Errorf("GetOrLoad(7) = %d, %v, want 14, nil", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("Loader被调用 %d 次, want 1", n)
+	}
+	if v, ok := c.Get(7); !ok || v != 14 {
+		t.Fatalf("Get(7) = %d, %v, want 14, true", v, ok)
+	}
+}
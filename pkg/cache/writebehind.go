@@ -0,0 +1,283 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriteBehindClosed 缓存已关闭，不再接收写入
+var ErrWriteBehindClosed = errors.New("cache: write-behind closed")
+
+// WriteBehindConfig 写回缓存配置
+type WriteBehindConfig struct {
+	MaxDirty      int           // 尚未写入后端的条目上限（包括正在写入的），达到上限时Set阻塞
+	BatchSize     int           // 每批最多写入的条目数，脏条目达到这个数量时立即刷新
+	FlushInterval time.Duration // 定时刷新的间隔
+	MaxRetries    int           // 一批写入失败后的重试次数，用完后放回脏数据，等下一轮刷新
+	RetryBackoff  time.Duration // 第一次重试前的等待时间，之后每次翻倍
+}
+
+// WriteBehindStats 累计统计
+type WriteBehindStats struct {
+	Writes         int64 // Set调用次数
+	Coalesced      int64 // 覆盖了尚未写入的旧值，合并成一次后端写入
+	Flushes        int64 // 成功写入的批次数
+	FlushedEntries int64
+	Retries        int64
+	FailedFlushes  int64 // 重试用尽、放回脏数据的批次数
+	BlockedWrites  int64 // 因脏条目达到上限而等待的Set次数
+}
+
+// WriteBehind 写回缓存：Set只更新内存并记为脏数据，后台goroutine批量写入慢速后端
+// 同一个键在写入前被多次修改时只写入最后的值
+type WriteBehind[K comparable, V any] struct {
+	cfg   WriteBehindConfig
+	write func(ctx context.Context, batch map[K]V) error
+
+	mu       sync.Mutex
+	data     map[K]V
+	dirty    map[K]V
+	inflight int           // 正在写入后端的条目数
+	space    chan struct{} // 脏条目减少时关闭并替换，唤醒等待的写入者
+	idle     chan struct{} // 没有脏条目和正在写入的条目时关闭并替换，用于Flush
+	closed   bool
+
+	kick chan struct{} // 脏条目达到BatchSize时通知刷新goroutine
+	stop chan struct{}
+	done chan struct{}
+
+	writes, coalesced, flushes, flushedEntries, retries, failedFlushes, blockedWrites atomic.Int64
+}
+
+// NewWriteBehind 创建写回缓存，write把一批条目写入后端
+func NewWriteBehind[K comparable, V any](cfg WriteBehindConfig, write func(ctx context.Context, batch map[K]V) error) *WriteBehind[K, V] {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxDirty < cfg.BatchSize {
+		cfg.MaxDirty = cfg.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 100 * time.Millisecond
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 10 * time.Millisecond
+	}
+	w := &WriteBehind[K, V]{
+		cfg:   cfg,
+		write: write,
+		data:  make(map[K]V),
+		dirty: make(map[K]V),
+		space: make(chan struct{}),
+		idle:  make(chan struct{}),
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Get 读取缓存中的最新值（可能还没写入后端）
+func (w *WriteBehind[K, V]) Get(key K) (V, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	v, ok := w.data[key]
+	return v, ok
+}
+
+// Set 写入缓存并记为脏数据；脏条目达到MaxDirty时等待刷新腾出空间或ctx结束
+// 已经是脏数据的键直接覆盖，不占用新的额度
+func (w *WriteBehind[K, V]) Set(ctx context.Context, key K, value V) error {
+	w.writes.Add(1)
+	blocked := false
+	w.mu.Lock()
+	for {
+		if w.closed {
+			w.mu.Unlock()
+			return ErrWriteBehindClosed
+		}
+		if _, ok := w.dirty[key]; ok {
+			w.coalesced.Add(1)
+			break
+		}
+		if len(w.dirty)+w.inflight < w.cfg.MaxDirty {
+			break
+		}
+		if !blocked {
+			blocked = true
+			w.blockedWrites.Add(1)
+		}
+		space := w.space
+		w.mu.Unlock()
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		w.mu.Lock()
+	}
+	w.data[key] = value
+	w.dirty[key] = value
+	full := len(w.dirty) >= w.cfg.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// DirtyLen 尚未写入后端的条目数（包括正在写入的）
+func (w *WriteBehind[K, V]) DirtyLen() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.dirty) + w.inflight
+}
+
+func (w *WriteBehind[K, V]) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.kick:
+		case <-w.stop:
+			// 关闭前把剩余的脏数据写完（每批仍然带重试）
+			for w.flushOnce() {
+			}
+			return
+		}
+		// 一次把积压的脏数据分批写完，而不是每个tick只写一批
+		for w.flushOnce() {
+		}
+	}
+}
+
+// flushOnce 取出一批脏数据写入后端，返回是否成功写入了数据
+func (w *WriteBehind[K, V]) flushOnce() bool {
+	w.mu.Lock()
+	if len(w.dirty) == 0 {
+		w.mu.Unlock()
+		return false
+	}
+	batch := make(map[K]V, min(len(w.dirty), w.cfg.BatchSize))
+	for k, v := range w.dirty {
+		batch[k] = v
+		delete(w.dirty, k)
+		if len(batch) == w.cfg.BatchSize {
+			break
+		}
+	}
+	w.inflight += len(batch)
+	w.mu.Unlock()
+
+	err := w.writeWithRetry(batch)
+
+	w.mu.Lock()
+	w.inflight -= len(batch)
+	if err != nil {
+		// 放回脏数据；写入期间又被修改过的键保留新值
+		for k, v := range batch {
+			if _, ok := w.dirty[k]; !ok {
+				w.dirty[k] = v
+			}
+		}
+		w.failedFlushes.Add(1)
+	} else {
+		w.flushes.Add(1)
+		w.flushedEntries.Add(int64(len(batch)))
+	}
+	close(w.space)
+	w.space = make(chan struct{})
+	if len(w.dirty) == 0 && w.inflight == 0 {
+		close(w.idle)
+		w.idle = make(chan struct{})
+	}
+	w.mu.Unlock()
+	return err == nil
+}
+
+func (w *WriteBehind[K, V]) writeWithRetry(batch map[K]V) error {
+	backoff := w.cfg.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= w.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			w.retries.Add(1)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = w.write(context.Background(), batch); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Flush 等待当前所有脏数据写入后端，或ctx结束
+func (w *WriteBehind[K, V]) Flush(ctx context.Context) error {
+	for {
+		w.mu.Lock()
+		if len(w.dirty) == 0 && w.inflight == 0 {
+			w.mu.Unlock()
+			return nil
+		}
+		idle := w.idle
+		w.mu.Unlock()
+
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close 不再接收写入，把剩余脏数据写入后端后返回；ctx结束时返回ctx.Err()，后台仍会继续写入
+// 重试用尽仍写入失败的条目留在缓存中，返回的错误包含条目数
+func (w *WriteBehind[K, V]) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.stop)
+		// 唤醒等待空间的写入者，让它们返回ErrWriteBehindClosed
+		close(w.space)
+		w.space = make(chan struct{})
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if n := w.DirtyLen(); n > 0 {
+		return fmt.Errorf("cache: %d dirty entries not written", n)
+	}
+	return nil
+}
+
+// Stats 当前累计统计
+func (w *WriteBehind[K, V]) Stats() WriteBehindStats {
+	return WriteBehindStats{
+		Writes:         w.writes.Load(),
+		Coalesced:      w.coalesced.Load(),
+		Flushes:        w.flushes.Load(),
+		FlushedEntries: w.flushedEntries.Load(),
+		Retries:        w.retries.Load(),
+		FailedFlushes:  w.failedFlushes.Load(),
+		BlockedWrites:  w.blockedWrites.Load(),
+	}
+}
@@ -0,0 +1,173 @@
+// Package chanx 提供基于泛型的channel组合器，用于搭建可取消的管道。
+//
+// PriorityMux按严格或加权优先级合并多个输入，弥补select在多个case就绪时随机选择的问题。
+//
+// 每个组合器都在独立的goroutine中运行，输入关闭或ctx取消时关闭输出channel；
+// 因此下游停止读取后只需取消ctx，上游的goroutine就不会泄漏。
+package chanx
+
+import "context"
+
+// Generate 依次发送values，发送完毕后关闭输出
+func Generate[T any](ctx context.Context, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// OrDone 包装in，使读取方在ctx取消时也能退出range循环
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Map 对每个元素应用f
+func Map[T, U any](ctx context.Context, in <-chan T, f func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range OrDone(ctx, in) {
+			select {
+			case out <- f(v):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Filter 只保留满足keep的元素
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range OrDone(ctx, in) {
+			if !keep(v) {
+				continue
+			}
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Take 最多转发n个元素后关闭输出
+// 注意：Take不会继续读取in，上游需要通过ctx取消才能退出
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Reduce 依次用f合并所有元素，阻塞直到in关闭或ctx取消
+func Reduce[T, A any](ctx context.Context, in <-chan T, initial A, f func(A, T) A) A {
+	acc := initial
+	for v := range OrDone(ctx, in) {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Tee 把每个元素同时发送到两个输出
+// 两个输出都取走当前元素后才会读取下一个，所以两个读取方必须并发消费
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1 := make(chan T)
+	out2 := make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			// 用局部变量控制发送：某一路发送成功后置为nil，select不会再选中它
+			o1, o2 := out1, out2
+			for i := 0; i < 2; i++ {
+				select {
+				case o1 <- v:
+					o1 = nil
+				case o2 <- v:
+					o2 = nil
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Bridge 把"channel的channel"展开为单个channel，按内层channel到达的顺序依次读取
+func Bridge[T any](ctx context.Context, chans <-chan <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			var stream <-chan T
+			select {
+			case s, ok := <-chans:
+				if !ok {
+					return
+				}
+				stream = s
+			case <-ctx.Done():
+				return
+			}
+
+			for v := range OrDone(ctx, stream) {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
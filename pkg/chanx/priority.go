@@ -0,0 +1,163 @@
+package chanx
+
+import (
+	"context"
+	"reflect"
+)
+
+// Priority 多个输入同时有数据时的选择方式，用Strict或Weighted创建
+type Priority struct {
+	weighted bool
+	weights  []int
+}
+
+// Strict 严格优先级：总是先取下标小的输入，低优先级输入只有在更高优先级全部没有数据时才会被读取
+func Strict() Priority { return Priority{} }
+
+// Weighted 加权优先级：都有数据时按weights的比例轮流读取（平滑加权轮询），低优先级不会被饿死
+// weights[i]对应第i个输入，缺少或<=0的按1计算
+func Weighted(weights ...int) Priority { return Priority{weighted: true, weights: weights} }
+
+func (p Priority) weight(i int) int {
+	if i < len(p.weights) && p.weights[i] > 0 {
+		return p.weights[i]
+	}
+	return 1
+}
+
+// PriorityMux 把inputs按优先级合并到一个输出，inputs[0]的优先级最高
+//
+// 直接对多个channel做select时，多个case同时就绪会随机选一个，无法表达优先级。
+// PriorityMux为每个输入预读最多一个元素，在下游准备好接收时才在已预读的元素中按优先级选择，
+// 等待下游期间到达的更高优先级元素也会参与选择。
+// 全部输入关闭且预读的元素都已发出，或ctx取消时关闭输出。
+func PriorityMux[T any](ctx context.Context, prio Priority, inputs ...<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		m := &mux[T]{
+			prio:    prio,
+			inputs:  inputs,
+			pending: make([]*T, len(inputs)),
+			open:    make([]bool, len(inputs)),
+			current: make([]int, len(inputs)),
+		}
+		for i := range m.open {
+			m.open[i] = true
+		}
+		m.run(ctx, out)
+	}()
+	return out
+}
+
+type mux[T any] struct {
+	prio    Priority
+	inputs  []<-chan T
+	pending []*T   // 每个输入预读的元素
+	open    []bool // 输入是否还没关闭
+	current []int  // 平滑加权轮询的当前权重
+}
+
+func (m *mux[T]) run(ctx context.Context, out chan<- T) {
+	for {
+		m.fill()
+		c := m.choose()
+		if c < 0 && !m.anyOpen() {
+			return
+		}
+
+		// 同时等待：把选中的元素发给下游、ctx取消、还没有预读元素的输入有新数据
+		cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}}
+		sendCase := -1
+		if c >= 0 {
+			sendCase = len(cases)
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(out), Send: reflect.ValueOf(m.pending[c]).Elem()})
+		}
+		var idx []int // cases下标 -> 输入下标
+		for i, ch := range m.inputs {
+			if m.open[i] && m.pending[i] == nil {
+				idx = append(idx, i)
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+			}
+		}
+
+		chosen, v, ok := reflect.Select(cases)
+		switch {
+		case chosen == 0:
+			return
+		case chosen == sendCase:
+			m.commit(c)
+		default:
+			i := idx[chosen-len(cases)+len(idx)]
+			m.receive(i, v, ok)
+		}
+	}
+}
+
+// fill 非阻塞地为每个没有预读元素的输入读取一个元素
+func (m *mux[T]) fill() {
+	for i, ch := range m.inputs {
+		if !m.open[i] || m.pending[i] != nil {
+			continue
+		}
+		select {
+		case v, ok := <-ch:
+			if ok {
+				m.pending[i] = &v
+			} else {
+				m.open[i] = false
+			}
+		default:
+		}
+	}
+}
+
+func (m *mux[T]) receive(i int, v reflect.Value, ok bool) {
+	if !ok {
+		m.open[i] = false
+		return
+	}
+	t, _ := v.Interface().(T) // T是接口类型且收到nil时断言失败，t为零值nil，正是收到的值
+	m.pending[i] = &t
+}
+
+func (m *mux[T]) anyOpen() bool {
+	for _, o := range m.open {
+		if o {
+			return true
+		}
+	}
+	return false
+}
+
+// choose 在有预读元素的输入中选择下一个发出的，没有时返回-1；不修改状态
+func (m *mux[T]) choose() int {
+	best := -1
+	for i, p := range m.pending {
+		if p == nil {
+			continue
+		}
+		if !m.prio.weighted {
+			return i
+		}
+		if best < 0 || m.current[i]+m.prio.weight(i) > m.current[best]+m.prio.weight(best) {
+			best = i
+		}
+	}
+	return best
+}
+
+// commit 第c个输入的元素已经发出：清空预读，并更新平滑加权轮询的权重
+func (m *mux[T]) commit(c int) {
+	if m.prio.weighted {
+		total := 0
+		for i, p := range m.pending {
+			if p != nil {
+				m.current[i] += m.prio.weight(i)
+				total += m.prio.weight(i)
+			}
+		}
+		m.current[c] -= total
+	}
+	m.pending[c] = nil
+}
@@ -0,0 +1,147 @@
+package chanx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// filled 返回放入了n个值并已关闭的channel，值为 base+0 .. base+n-1
+func filled(base, n int) <-chan int {
+	ch := make(chan int, n)
+	for i := 0; i < n; i++ {
+		ch <- base + i
+	}
+	close(ch)
+	return ch
+}
+
+// collect 读取out直到关闭，超时说明输出没有关闭
+func collect(t *testing.T, out <-chan int) []int {
+	t.Helper()
+	var got []int
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case v, ok := <-out:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		case <-timeout:
+			t.Fatalf("输出没有关闭，已读到 %v", got)
+		}
+	}
+}
+
+// TestStrictDrainsHigherFirst 所有输入都有数据时，严格优先级先读完下标小的输入
+func TestStrictDrainsHigherFirst(t *testing.T) {
+	out := PriorityMux(context.Background(), Strict(), filled(0, 3), filled(100, 3), filled(200, 3))
+	got := collect(t, out)
+	if want := "[0 1 2 100 101 102 200 201 202]"; fmt.Sprint(got) != want {
+		t.Fatalf("输出 %v, want %s", got, want)
+	}
+}
+
+// TestStrictPrefersLateHigher 等待下游期间到达的高优先级元素排在已预读的低优先级元素前面
+func TestStrictPrefersLateHigher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	high, low := make(chan int), make(chan int)
+	out := PriorityMux(ctx, Strict(), high, low)
+
+	// 无缓冲channel的发送返回时PriorityMux已经收到了这个值
+	low <- 1
+	high <- 2
+	if v := <-out; v != 2 {
+		t.Fatalf("第一个输出 %d, want 2", v)
+	}
+	if v := <-out; v != 1 {
+		t.Fatalf("第二个输出 %d, want 1", v)
+	}
+}
+
+// TestWeightedRatio 所有输入都有数据时按权重比例读取，数据不足的输入读完后其余输入照常输出
+func TestWeightedRatio(t *testing.T) {
+	tests := []struct {
+		weights []int
+		want    []int // 前20个输出中每个输入的个数
+	}{
+		{[]int{3, 1}, []int{15, 5}},
+		{[]int{1, 1}, []int{10, 10}},
+		{[]int{2, 1, 1}, []int{10, 5, 5}},
+		{[]int{4, 0}, []int{16, 4}}, // <=0的权重按1计算
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.weights), func(t *testing.T) {
+			inputs := make([]<-chan int, len(tt.weights))
+			for i := range inputs {
+				inputs[i] = filled(i*100, 50)
+			}
+			got := collect(t, PriorityMux(context.Background(), Weighted(tt.weights...), inputs...))
+			if len(got) != 50*len(inputs) {
+				t.Fatalf("输出 %d 个, want %d", len(got), 50*len(inputs))
+			}
+
+			counts := make([]int, len(inputs))
+			for _, v := range got[:20] {
+				counts[v/100]++
+			}
+			if fmt.Sprint(counts) != fmt.Sprint(tt.want) {
+				t.Fatalf("前20个输出中各输入的个数 %v, want %v", counts, tt.want)
+			}
+		})
+	}
+}
+
+// TestClosesAfterAllInputs 只有所有输入都关闭后输出才关闭
+func TestClosesAfterAllInputs(t *testing.T) {
+	for _, prio := range []Priority{Strict(), Weighted(2, 1)} {
+		a, b := make(chan int), make(chan int)
+		out := PriorityMux(context.Background(), prio, a, b)
+
+		a <- 1
+		close(a)
+		if v := <-out; v != 1 {
+			t.Fatalf("输出 %d, want 1", v)
+		}
+		select {
+		case v, ok := <-out:
+			t.Fatalf("还有输入没有关闭时读到 %d, %v", v, ok)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		b <- 2
+		close(b)
+		if got := collect(t, out); fmt.Sprint(got) != "[2]" {
+			t.Fatalf("输出 %v, want [2]", got)
+		}
+	}
+}
+
+// TestCancelStops ctx取消后PriorityMux的goroutine退出并关闭输出，
+// 包括输入一直不关闭、以及预读的元素因为没人读取而阻塞在发送上的情况
+func TestCancelStops(t *testing.T) {
+	for _, blocked := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		in := make(chan int)
+		out := PriorityMux(ctx, Strict(), in)
+		if blocked {
+			in <- 1
+		}
+		cancel()
+
+		select {
+		case _, ok := <-out:
+			// 取消和发送同时就绪时可能先发出预读的元素，再读一次一定是关闭
+			if ok {
+				if _, ok = <-out; ok {
+					t.Fatal("取消后仍有输出")
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("取消后输出没有关闭（预读元素阻塞: %v）", blocked)
+		}
+	}
+}
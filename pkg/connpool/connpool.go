@@ -0,0 +1,358 @@
+// Package connpool 提供带健康检查和空闲清理的连接池。
+//
+// 池中保持MinConnections到MaxConnections个连接；借出时检查连接是否存活，失效的连接被关闭并
+// 重新创建。建立连接失败时按ConnectRetry重试，后台协程定期检查空闲连接的健康状况，并关闭
+// 空闲超过MaxIdleTime的连接，同时保证不低于最小连接数。
+package connpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/retry"
+)
+
+// Connection 池中管理的连接
+type Connection interface {
+	Connect() error
+	Close() error
+	Execute(query string) (interface{}, error)
+	IsAlive() bool
+	GetID() string
+	GetCreatedTime() time.Time
+	GetLastUsed() time.Time
+	SetLastUsed(time.Time)
+}
+
+// Config 连接池配置
+type Config struct {
+	MinConnections    int           // 最小连接数
+	MaxConnections    int           // 最大连接数
+	MaxIdleTime       time.Duration // 最大空闲时间
+	ConnectionTimeout time.Duration // 连接超时时间
+	HealthCheckPeriod time.Duration // 健康检查周期
+	ConnectRetry      retry.Policy  // 建立连接失败时的重试策略
+
+	// Logf 记录建连重试、建连失败和关闭等事件，为nil时不记录
+	Logf func(format string, args ...any)
+}
+
+var (
+	// ErrPoolClosed 连接池已关闭，重试也不会成功
+	ErrPoolClosed = errors.New("connection pool is closed")
+	// ErrPoolTimeout 等待空闲连接超时，已经等了ConnectionTimeout，立即重试只会让调用方等得更久
+	ErrPoolTimeout = errors.New("connection timeout")
+)
+
+// Pool 连接池：空闲连接放在有缓冲的channel中，后台定期做健康检查和空闲清理
+type Pool struct {
+	config      Config
+	connections chan Connection
+	active      map[string]Connection
+	factory     func(id string) Connection
+	mu          sync.RWMutex
+	closed      bool
+	wg          sync.WaitGroup
+	stopCh      chan bool
+	stats       struct {
+		created     int64
+		borrowed    int64
+		returned    int64
+		failed      int64
+		evicted     int64
+		healthCheck int64
+		retried     int64 // 建立连接的重试次数
+	}
+}
+
+// New 创建连接池并建立MinConnections个连接，factory按ID创建还未连接的Connection
+func New(config Config, factory func(id string) Connection) (*Pool, error) {
+	if config.MinConnections < 0 || config.MaxConnections < config.MinConnections {
+		return nil, fmt.Errorf("invalid pool configuration")
+	}
+	if config.Logf == nil {
+		config.Logf = func(string, ...any) {}
+	}
+
+	pool := &Pool{
+		config:      config,
+		connections: make(chan Connection, config.MaxConnections),
+		active:      make(map[string]Connection),
+		factory:     factory,
+		stopCh:      make(chan bool),
+	}
+
+	// 创建最小连接数
+	for i := 0; i < config.MinConnections; i++ {
+		conn := pool.createConnection()
+		if conn != nil {
+			pool.connections <- conn
+		}
+	}
+
+	// 启动健康检查
+	pool.wg.Add(1)
+	go pool.healthChecker()
+
+	// 启动空闲连接清理
+	pool.wg.Add(1)
+	go pool.idleConnectionEvector()
+
+	return pool, nil
+}
+
+func (p *Pool) createConnection() Connection {
+	id := fmt.Sprintf("conn-%d", atomic.AddInt64(&p.stats.created, 1))
+	conn := p.factory(id)
+
+	policy := p.config.ConnectRetry
+	policy.OnAttempt = func(a retry.Attempt) {
+		if a.Retry {
+			atomic.AddInt64(&p.stats.retried, 1)
+			p.config.Logf("连接 %s 第 %d 次连接失败，%v 后重试: %v\n", id, a.Number, a.Delay.Round(time.Millisecond), a.Err)
+		}
+	}
+	err := retry.Do(context.Background(), policy, func(ctx context.Context) error {
+		return conn.Connect()
+	})
+	if err != nil {
+		atomic.AddInt64(&p.stats.failed, 1)
+		p.config.Logf("创建连接失败: %v\n", err)
+		return nil
+	}
+
+	return conn
+}
+
+// BorrowConnection 借出一个连接，ConnectionTimeout内没有空闲连接时返回ErrPoolTimeout
+func (p *Pool) BorrowConnection() (Connection, error) {
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+
+	atomic.AddInt64(&p.stats.borrowed, 1)
+
+	select {
+	case conn := <-p.connections:
+		// 检查连接是否仍然有效
+		if conn.IsAlive() {
+			p.mu.Lock()
+			p.active[conn.GetID()] = conn
+			p.mu.Unlock()
+
+			conn.SetLastUsed(time.Now())
+			return conn, nil
+		} else {
+			// 连接已失效，创建新连接
+			atomic.AddInt64(&p.stats.evicted, 1)
+			conn.Close()
+
+			newConn := p.createConnection()
+			if newConn != nil {
+				p.mu.Lock()
+				p.active[newConn.GetID()] = newConn
+				p.mu.Unlock()
+				return newConn, nil
+			}
+		}
+
+	case <-time.After(p.config.ConnectionTimeout):
+		return nil, ErrPoolTimeout
+	}
+
+	// 如果池中没有可用连接，尝试创建新连接
+	p.mu.RLock()
+	activeCount := len(p.active)
+	p.mu.RUnlock()
+
+	if activeCount < p.config.MaxConnections {
+		conn := p.createConnection()
+		if conn != nil {
+			p.mu.Lock()
+			p.active[conn.GetID()] = conn
+			p.mu.Unlock()
+			return conn, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no available connections")
+}
+
+// ReturnConnection 归还连接，失效的连接和池满时多出的连接会被关闭
+func (p *Pool) ReturnConnection(conn Connection) error {
+	if p.closed {
+		conn.Close()
+		return ErrPoolClosed
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.active[conn.GetID()]; !exists {
+		return fmt.Errorf("connection not from this pool")
+	}
+
+	delete(p.active, conn.GetID())
+	atomic.AddInt64(&p.stats.returned, 1)
+
+	// 检查连接是否仍然有效
+	if conn.IsAlive() {
+		select {
+		case p.connections <- conn:
+			return nil
+		default:
+			// 池已满，关闭连接
+			conn.Close()
+			return nil
+		}
+	} else {
+		// 连接已失效，关闭它
+		atomic.AddInt64(&p.stats.evicted, 1)
+		conn.Close()
+		return nil
+	}
+}
+
+func (p *Pool) healthChecker() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.HealthCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.performHealthCheck()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+func (p *Pool) performHealthCheck() {
+	atomic.AddInt64(&p.stats.healthCheck, 1)
+
+	// 检查池中的空闲连接
+	poolSize := len(p.connections)
+	for i := 0; i < poolSize; i++ {
+		select {
+		case conn := <-p.connections:
+			if conn.IsAlive() {
+				// 连接健康，放回池中
+				p.connections <- conn
+			} else {
+				// 连接不健康，关闭并可能创建新连接
+				atomic.AddInt64(&p.stats.evicted, 1)
+				conn.Close()
+
+				// 如果连接数低于最小值，创建新连接
+				if len(p.connections) < p.config.MinConnections {
+					newConn := p.createConnection()
+					if newConn != nil {
+						p.connections <- newConn
+					}
+				}
+			}
+		default:
+			break
+		}
+	}
+}
+
+func (p *Pool) idleConnectionEvector() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.MaxIdleTime / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.evictIdleConnections()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+func (p *Pool) evictIdleConnections() {
+	now := time.Now()
+	poolSize := len(p.connections)
+
+	for i := 0; i < poolSize; i++ {
+		select {
+		case conn := <-p.connections:
+			if now.Sub(conn.GetLastUsed()) > p.config.MaxIdleTime {
+				// 连接空闲时间过长，关闭它
+				atomic.AddInt64(&p.stats.evicted, 1)
+				conn.Close()
+
+				// 确保不低于最小连接数
+				if len(p.connections) < p.config.MinConnections {
+					newConn := p.createConnection()
+					if newConn != nil {
+						p.connections <- newConn
+					}
+				}
+			} else {
+				// 连接仍在有效期内，放回池中
+				p.connections <- conn
+			}
+		default:
+			break
+		}
+	}
+}
+
+// Close 停止后台任务并关闭所有空闲和借出的连接
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return fmt.Errorf("pool already closed")
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	// 停止后台任务
+	close(p.stopCh)
+	p.wg.Wait()
+
+	// 关闭所有连接
+	close(p.connections)
+	for conn := range p.connections {
+		conn.Close()
+	}
+
+	// 关闭活跃连接
+	p.mu.RLock()
+	for _, conn := range p.active {
+		conn.Close()
+	}
+	p.mu.RUnlock()
+
+	p.config.Logf("连接池已关闭\n")
+	return nil
+}
+
+// GetStats 返回连接数和各项累计计数
+func (p *Pool) GetStats() map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return map[string]interface{}{
+		"idle_connections":   len(p.connections),
+		"active_connections": len(p.active),
+		"created":            atomic.LoadInt64(&p.stats.created),
+		"borrowed":           atomic.LoadInt64(&p.stats.borrowed),
+		"returned":           atomic.LoadInt64(&p.stats.returned),
+		"failed":             atomic.LoadInt64(&p.stats.failed),
+		"evicted":            atomic.LoadInt64(&p.stats.evicted),
+		"health_checks":      atomic.LoadInt64(&p.stats.healthCheck),
+		"connect_retries":    atomic.LoadInt64(&p.stats.retried),
+	}
+}
@@ -0,0 +1,40 @@
+// Package contextkeys 定义示例之间共用的context键，目前用于传递请求ID，
+// 让工作池、流水线、消息队列等示例的日志可以按请求串联起来。
+package contextkeys
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// ctxKey 未导出的键类型，避免与其他包放入context的值冲突
+type ctxKey int
+
+const requestIDKey ctxKey = iota
+
+var requestSeq int64
+
+// NewRequestID 生成进程内唯一的请求ID，例如 "req-0001"
+func NewRequestID() string {
+	return fmt.Sprintf("req-%04d", atomic.AddInt64(&requestSeq, 1))
+}
+
+// WithRequestID 返回携带请求ID的context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFrom 取出context中的请求ID
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok && id != ""
+}
+
+// LogPrefix 返回用于日志行首的 "[req-0001] "，context中没有请求ID时返回空字符串
+func LogPrefix(ctx context.Context) string {
+	if id, ok := RequestIDFrom(ctx); ok {
+		return "[" + id + "] "
+	}
+	return ""
+}
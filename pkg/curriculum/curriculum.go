@@ -0,0 +1,140 @@
+// Package curriculum 练习的学习路线：每个练习依赖哪些前置练习，以及推荐的完成顺序。
+//
+// 前置练习全部评分通过（progress.Record.Completed）之后练习才解锁。解锁的时间记录在进度文件里，
+// 之后即使前置练习又改坏了，已经解锁的练习也不会重新锁上。没有前置练习的入门练习始终可用；
+// 不在路线里的练习（例如刚加入、还没有安排位置的）同样不受限制，Check会把它们报告出来。
+//
+// 评分器和浏览器练习场在评分前用Unlocked检查，评分记录之后用Update解锁新的练习；
+// progress命令用Next推荐下一个练习。
+package curriculum
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/progress"
+)
+
+// Node 路线上的一个练习
+type Node struct {
+	Exercise string   // 级别/文件名，例如simple/03_channel_basic
+	Skill    string   // 这个练习练的是什么，提示前置关系时显示
+	Requires []string // 前置练习，都必须排在这个练习之前
+}
+
+// Curriculum 一条学习路线，nodes的顺序就是推荐的完成顺序
+type Curriculum struct {
+	nodes []Node
+	index map[string]int
+}
+
+// New 检查路线并创建：练习不能重复，前置练习必须在路线中且排在前面（因此不会有环）
+func New(nodes []Node) (*Curriculum, error) {
+	c := &Curriculum{nodes: nodes, index: make(map[string]int, len(nodes))}
+	for i, n := range nodes {
+		if _, dup := c.index[n.Exercise]; dup {
+			return nil, fmt.Errorf("练习 %s 在路线中出现了两次", n.Exercise)
+		}
+		for _, req := range n.Requires {
+			if _, ok := c.index[req]; !ok {
+				return nil, fmt.Errorf("%s 的前置练习 %s 不在路线中，或者排在它后面", n.Exercise, req)
+			}
+		}
+		c.index[n.Exercise] = i
+	}
+	return c, nil
+}
+
+// Default 仓库练习的学习路线
+func Default() *Curriculum {
+	c, err := New(defaultPath)
+	if err != nil {
+		panic("curriculum: " + err.Error())
+	}
+	return c
+}
+
+// Path 推荐的完成顺序
+func (c *Curriculum) Path() []Node {
+	return append([]Node(nil), c.nodes...)
+}
+
+// Lookup 路线上的练习，不在路线上时返回false
+func (c *Curriculum) Lookup(exercise string) (Node, bool) {
+	i, ok := c.index[exercise]
+	if !ok {
+		return Node{}, false
+	}
+	return c.nodes[i], true
+}
+
+// Check 对照实际存在的练习检查路线：路线里的练习必须存在，存在的练习应当都在路线里
+func (c *Curriculum) Check(exercises []grade.Exercise) error {
+	exists := make(map[string]bool, len(exercises))
+	var missing []string
+	for _, ex := range exercises {
+		id := ex.Level + "/" + ex.Name
+		exists[id] = true
+		if _, ok := c.index[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	for _, n := range c.nodes {
+		if !exists[n.Exercise] {
+			return fmt.Errorf("路线中的练习 %s 不存在", n.Exercise)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("练习 %v 不在学习路线中，请在pkg/curriculum中安排它们的前置练习", missing)
+	}
+	return nil
+}
+
+// Missing 还没有完成的前置练习
+func (c *Curriculum) Missing(store *progress.Store, exercise string) []string {
+	n, ok := c.Lookup(exercise)
+	if !ok {
+		return nil
+	}
+	var missing []string
+	for _, req := range n.Requires {
+		if r, _ := store.Get(req); !r.Completed() {
+			missing = append(missing, req)
+		}
+	}
+	return missing
+}
+
+// Unlocked 练习是否可以做：有解锁记录，或者前置练习都已完成
+func (c *Curriculum) Unlocked(store *progress.Store, exercise string) bool {
+	if _, ok := store.UnlockedAt(exercise); ok {
+		return true
+	}
+	return len(c.Missing(store, exercise)) == 0
+}
+
+// Update 把前置练习已经全部完成、但还没有解锁记录的练习记为解锁，按路线顺序返回新解锁的练习。
+// 入门练习没有前置练习，不需要记录
+func (c *Curriculum) Update(store *progress.Store, at time.Time) []string {
+	var unlocked []string
+	for _, n := range c.nodes {
+		if len(n.Requires) == 0 || len(c.Missing(store, n.Exercise)) > 0 {
+			continue
+		}
+		if store.Unlock(n.Exercise, at) {
+			unlocked = append(unlocked, n.Exercise)
+		}
+	}
+	return unlocked
+}
+
+// Next 按推荐顺序第一个已解锁、还没有完成的练习；全部完成时返回false
+func (c *Curriculum) Next(store *progress.Store) (Node, bool) {
+	for _, n := range c.nodes {
+		if r, _ := store.Get(n.Exercise); !r.Completed() && c.Unlocked(store, n.Exercise) {
+			return n, true
+		}
+	}
+	return Node{}, false
+}
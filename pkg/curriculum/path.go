@@ -0,0 +1,47 @@
+package curriculum
+
+// defaultPath 仓库练习的推荐顺序和前置关系。
+// 加入新练习时在这里安排它的位置：前置练习写它直接用到的技能，不必把前置的前置也列出来
+var defaultPath = []Node{
+	{Exercise: "simple/01_basic_goroutine", Skill: "启动goroutine"},
+	{Exercise: "simple/02_waitgroup_basic", Skill: "WaitGroup等待goroutine结束",
+		Requires: []string{"simple/01_basic_goroutine"}},
+	{Exercise: "simple/03_channel_basic", Skill: "channel收发与关闭",
+		Requires: []string{"simple/01_basic_goroutine"}},
+	{Exercise: "simple/04_buffered_channel", Skill: "缓冲channel",
+		Requires: []string{"simple/03_channel_basic"}},
+	{Exercise: "simple/05_select_basic", Skill: "select多路复用",
+		Requires: []string{"simple/03_channel_basic"}},
+	{Exercise: "simple/06_timeout_select", Skill: "超时与取消",
+		Requires: []string{"simple/05_select_basic"}},
+	{Exercise: "simple/07_mutex_basic", Skill: "互斥锁保护共享数据",
+		Requires: []string{"simple/02_waitgroup_basic"}},
+	{Exercise: "simple/08_once_basic", Skill: "sync.Once单次初始化",
+		Requires: []string{"simple/07_mutex_basic"}},
+	{Exercise: "simple/09_channel_pipeline", Skill: "channel管道",
+		Requires: []string{"simple/02_waitgroup_basic", "simple/04_buffered_channel"}},
+	{Exercise: "simple/10_goroutine_pool", Skill: "goroutine池",
+		Requires: []string{"simple/02_waitgroup_basic", "simple/04_buffered_channel"}},
+
+	{Exercise: "medium/01_producer_consumer", Skill: "生产者-消费者",
+		Requires: []string{"simple/09_channel_pipeline", "simple/10_goroutine_pool"}},
+	{Exercise: "medium/03_rate_limiter", Skill: "令牌桶限流",
+		Requires: []string{"simple/06_timeout_select", "simple/07_mutex_basic"}},
+	{Exercise: "medium/05_context_cancellation", Skill: "Context取消与errgroup",
+		Requires: []string{"simple/06_timeout_select", "simple/10_goroutine_pool"}},
+	{Exercise: "medium/06_fan_in_fan_out", Skill: "扇入扇出与快速失败",
+		Requires: []string{"simple/09_channel_pipeline", "medium/05_context_cancellation"}},
+	{Exercise: "medium/07_circuit_breaker", Skill: "熔断器状态机",
+		Requires: []string{"simple/06_timeout_select", "simple/07_mutex_basic"}},
+	{Exercise: "medium/09_actor_model", Skill: "Actor消息传递与状态隔离",
+		Requires: []string{"simple/05_select_basic", "medium/01_producer_consumer"}},
+
+	{Exercise: "hard/01_distributed_worker", Skill: "一致性哈希与任务分发",
+		Requires: []string{"simple/07_mutex_basic", "medium/01_producer_consumer"}},
+	{Exercise: "hard/02_load_balancer", Skill: "负载均衡与写时复制",
+		Requires: []string{"medium/07_circuit_breaker"}},
+	{Exercise: "hard/03_message_queue", Skill: "重试与死信队列",
+		Requires: []string{"medium/01_producer_consumer", "medium/05_context_cancellation"}},
+	{Exercise: "hard/04_connection_pool", Skill: "连接池与泄漏检测",
+		Requires: []string{"simple/07_mutex_basic", "medium/05_context_cancellation"}},
+}
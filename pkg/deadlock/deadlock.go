@@ -0,0 +1,230 @@
+// Package deadlock 定期采样所有goroutine的栈，找出长时间阻塞在channel操作或锁上的goroutine，
+// 输出疑似死锁报告。
+//
+// Go运行时只能发现"所有goroutine都在睡眠"的全局死锁；只要还有一个goroutine在运行
+// （比如HTTP服务器、定时器、这个检测器本身），两个goroutine互相等待的局部死锁就永远不会被报告。
+// 这里的做法是启发式的：同一个goroutine在连续的采样中栈完全没变、并且处于阻塞状态，
+// 就认为它从第一次看到起一直阻塞着，超过阈值后报告。报告的是"疑似"，
+// 长时间等待任务的空闲工作者也会被报告，可以用Ignore排除；反复阻塞在同一行、
+// 而每次采样时恰好都在等待的goroutine也无法和一直阻塞区分开。
+package deadlock
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/leakcheck"
+)
+
+// blockingStates 视为阻塞的goroutine状态（runtime.Stack输出中方括号里的部分）
+var blockingStates = map[string]bool{
+	"chan send":                true,
+	"chan receive":             true,
+	"chan send (nil chan)":     true,
+	"chan receive (nil chan)":  true,
+	"select":                   true,
+	"select (no cases)":        true,
+	"semacquire":               true, // 旧版本Go中锁和WaitGroup的等待状态
+	"sync.Mutex.Lock":          true,
+	"sync.RWMutex.Lock":        true,
+	"sync.RWMutex.RLock":       true,
+	"sync.WaitGroup.Wait":      true,
+	"sync.Cond.Wait":           true,
+	"sync.Mutex.Lock (nil)":    true,
+	"sync.RWMutex.RLock (nil)": true,
+}
+
+// Blocked 一个阻塞超过阈值的goroutine
+type Blocked struct {
+	Goroutine leakcheck.Goroutine
+	Since     time.Duration // 已阻塞的时间（从第一次采样到这个栈算起，是下限）
+	Site      string        // 阻塞发生的位置：栈中第一个不属于runtime、sync的函数和行号
+}
+
+func (b Blocked) String() string {
+	return fmt.Sprintf("goroutine %d [%s] 已阻塞 %v 于 %s", b.Goroutine.ID, b.Goroutine.State, b.Since.Round(time.Millisecond), b.Site)
+}
+
+// Report 一次疑似死锁报告
+type Report struct {
+	Time    time.Time
+	Blocked []Blocked // 按goroutine ID排序
+	Total   int       // 采样时goroutine总数
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "疑似死锁: %d/%d 个goroutine阻塞超过阈值\n", len(r.Blocked), r.Total)
+	// 阻塞在同一位置的goroutine放在一起，方便看出互相等待的关系
+	sites := make(map[string][]Blocked)
+	var order []string
+	for _, bl := range r.Blocked {
+		if _, ok := sites[bl.Site]; !ok {
+			order = append(order, bl.Site)
+		}
+		sites[bl.Site] = append(sites[bl.Site], bl)
+	}
+	for _, site := range order {
+		fmt.Fprintf(&b, "  %s:\n", site)
+		for _, bl := range sites[site] {
+			fmt.Fprintf(&b, "    goroutine %d [%s] 已阻塞 %v\n", bl.Goroutine.ID, bl.Goroutine.State, bl.Since.Round(time.Millisecond))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Config 检测器配置
+type Config struct {
+	Interval  time.Duration             // 采样间隔，默认100ms
+	Threshold time.Duration             // 阻塞超过这么久才报告，默认1s
+	Ignore    func(Blocked) bool        // 返回true的goroutine不报告，例如空闲的工作者
+	OnReport  func(Report)              // 有新的goroutine超过阈值时调用，默认打印到标准错误
+	Snapshot  func() leakcheck.Snapshot // 采样函数，默认leakcheck.Take
+}
+
+type seen struct {
+	stack    string
+	first    time.Time
+	reported bool
+}
+
+// Detector 后台检测器
+type Detector struct {
+	cfg  Config
+	stop chan struct{}
+	done chan struct{}
+
+	mu      sync.Mutex
+	tracked map[int]*seen
+	reports []Report
+}
+
+// Start 启动后台检测
+func Start(cfg Config) *Detector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 100 * time.Millisecond
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = time.Second
+	}
+	if cfg.OnReport == nil {
+		cfg.OnReport = func(r Report) { fmt.Fprintln(os.Stderr, r) }
+	}
+	if cfg.Snapshot == nil {
+		cfg.Snapshot = leakcheck.Take
+	}
+	d := &Detector{
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		tracked: make(map[int]*seen),
+	}
+	go d.run()
+	return d
+}
+
+func (d *Detector) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if r, ok := d.sample(now); ok {
+				d.cfg.OnReport(r)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// sample 采样一次，有goroutine第一次超过阈值时返回报告
+func (d *Detector) sample(now time.Time) (Report, bool) {
+	snap := d.cfg.Snapshot()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var blocked []Blocked
+	fresh := false
+	for id, g := range snap {
+		if !blockingStates[g.State] {
+			delete(d.tracked, id)
+			continue
+		}
+		s, ok := d.tracked[id]
+		// 栈变了说明它在两次采样之间运行过，重新计时
+		if !ok || s.stack != g.Stack {
+			d.tracked[id] = &seen{stack: g.Stack, first: now}
+			continue
+		}
+		since := now.Sub(s.first)
+		if since < d.cfg.Threshold {
+			continue
+		}
+		b := Blocked{Goroutine: g, Since: since, Site: site(g.Stack)}
+		if d.cfg.Ignore != nil && d.cfg.Ignore(b) {
+			continue
+		}
+		blocked = append(blocked, b)
+		if !s.reported {
+			s.reported, fresh = true, true
+		}
+	}
+	// 已经退出的goroutine
+	for id := range d.tracked {
+		if _, ok := snap[id]; !ok {
+			delete(d.tracked, id)
+		}
+	}
+	if !fresh {
+		return Report{}, false
+	}
+
+	sort.Slice(blocked, func(i, j int) bool { return blocked[i].Goroutine.ID < blocked[j].Goroutine.ID })
+	r := Report{Time: now, Blocked: blocked, Total: len(snap)}
+	d.reports = append(d.reports, r)
+	return r, true
+}
+
+// Reports 到目前为止的全部报告
+func (d *Detector) Reports() []Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Report(nil), d.reports...)
+}
+
+// Stop 停止检测
+func (d *Detector) Stop() {
+	close(d.stop)
+	<-d.done
+}
+
+// site 栈中第一个用户代码的函数和位置
+// 栈的格式为每帧两行：函数调用，然后是以制表符开头的"文件:行号 +偏移"
+func site(stack string) string {
+	lines := strings.Split(stack, "\n")
+	for i := 1; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		if strings.HasPrefix(fn, "created by ") {
+			break
+		}
+		if strings.HasPrefix(fn, "runtime.") || strings.HasPrefix(fn, "sync.") || strings.HasPrefix(fn, "internal/") {
+			continue
+		}
+		if j := strings.LastIndex(fn, "("); j > 0 {
+			fn = fn[:j]
+		}
+		loc := strings.TrimSpace(lines[i+1])
+		if j := strings.LastIndex(loc, " +"); j > 0 {
+			loc = loc[:j]
+		}
+		return fmt.Sprintf("%s (%s)", fn, loc)
+	}
+	return "未知位置"
+}
@@ -0,0 +1,61 @@
+// Package demoflag 让demo的参数既可以用命令行flag设置，也可以用环境变量设置。
+//
+// demo照常用标准库flag定义参数，把flag.Parse换成demoflag.Parse：参数-consumers
+// 对应环境变量DEMO_CONSUMERS，连字符换成下划线并大写。优先级为命令行 > 环境变量 > 默认值，
+// 默认值就是不带参数运行时的行为，黄金输出按默认值生成。
+//
+//	consumers := flag.Int("consumers", 3, "消费者数量")
+//	demoflag.Parse()
+//
+//	go run ./cmd/hard/03_message_queue -consumers 5
+//	DEMO_CONSUMERS=5 go run ./cmd/hard/03_message_queue
+package demoflag
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix 环境变量名的前缀
+const Prefix = "DEMO_"
+
+// EnvName 参数name对应的环境变量名，例如max-retries对应DEMO_MAX_RETRIES
+func EnvName(name string) string {
+	return Prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Parse 先用环境变量设置已定义的flag，再解析命令行参数；环境变量的值不合法时退出
+func Parse() {
+	flag.Usage = usage
+	var bad []string
+	flag.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(EnvName(f.Name))
+		if !ok {
+			return
+		}
+		if err := flag.Set(f.Name, v); err != nil {
+			bad = append(bad, fmt.Sprintf("环境变量 %s=%q 不合法: %v", EnvName(f.Name), v, err))
+		}
+	})
+	if len(bad) > 0 {
+		for _, msg := range bad {
+			fmt.Fprintln(flag.CommandLine.Output(), msg)
+		}
+		os.Exit(2)
+	}
+	flag.Parse()
+}
+
+// usage 在标准的参数说明后列出对应的环境变量
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "用法: %s [参数]\n", filepath.Base(os.Args[0]))
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\n每个参数也可以用环境变量设置，命令行参数优先:")
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(out, "  %-28s -%s\n", EnvName(f.Name), f.Name)
+	})
+}
@@ -0,0 +1,198 @@
+// Package disruptor 提供LMAX Disruptor风格的单生产者多消费者环形缓冲区。
+//
+// 与channel不同：
+//   - 槽位预先分配，生产者原地填写事件，不需要为每个事件分配内存
+//   - 每个消费者都会看到全部事件（广播），各自维护自己读到的序号
+//   - 消费者一次可以处理生产者已发布的一整批事件，只在批次结束时更新序号
+//   - 等待策略可选：忙等延迟最低但占用CPU，阻塞等待节省CPU但唤醒有延迟
+package disruptor
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+type cacheLinePad [64]byte
+
+// Sequence 带缓存行填充的序号，避免不同goroutine修改的序号落在同一缓存行
+type Sequence struct {
+	_ cacheLinePad
+	v atomic.Int64
+	_ cacheLinePad
+}
+
+func newSequence() *Sequence {
+	s := &Sequence{}
+	s.v.Store(-1)
+	return s
+}
+
+// Load 当前序号，-1表示还没有任何事件
+func (s *Sequence) Load() int64 { return s.v.Load() }
+
+// WaitStrategy 消费者等待生产者发布新事件的方式
+type WaitStrategy interface {
+	// WaitFor 等待cursor达到seq，返回当前可读的最大序号；缓冲区关闭且没有更多事件时ok为false
+	WaitFor(seq int64, cursor *Sequence, closed *atomic.Bool) (available int64, ok bool)
+	// Signal 生产者发布事件或关闭缓冲区后调用
+	Signal()
+}
+
+// BusySpin 忙等策略：不停检查cursor，每自旋64次让出一次处理器
+// 唤醒延迟最低，但每个等待中的消费者都会占满一个CPU核
+type BusySpin struct{}
+
+func (BusySpin) WaitFor(seq int64, cursor *Sequence, closed *atomic.Bool) (int64, bool) {
+	for spins := 1; ; spins++ {
+		if c := cursor.Load(); c >= seq {
+			return c, true
+		}
+		if closed.Load() {
+			// 关闭前发布的事件要先读完
+			c := cursor.Load()
+			return c, c >= seq
+		}
+		// 单核或GOMAXPROCS=1时纯自旋会一直占着处理器，生产者无法运行，所以定期让出
+		if spins%64 == 0 {
+			runtime.Gosched()
+		}
+	}
+}
+
+func (BusySpin) Signal() {}
+
+// Blocking 阻塞策略：没有事件时在条件变量上休眠，生产者发布后唤醒
+type Blocking struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	waiters atomic.Int32
+}
+
+func NewBlocking() *Blocking {
+	b := &Blocking{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *Blocking) WaitFor(seq int64, cursor *Sequence, closed *atomic.Bool) (int64, bool) {
+	if c := cursor.Load(); c >= seq {
+		return c, true
+	}
+
+	b.mu.Lock()
+	// 先登记为等待者再检查cursor：生产者先更新cursor再检查等待者，两者至少有一方能看到对方，不会丢失唤醒
+	b.waiters.Add(1)
+	for cursor.Load() < seq && !closed.Load() {
+		b.cond.Wait()
+	}
+	b.waiters.Add(-1)
+	b.mu.Unlock()
+
+	c := cursor.Load()
+	return c, c >= seq
+}
+
+// Signal 只有存在等待者时才加锁广播，消费者跟得上时生产者不需要碰锁
+func (b *Blocking) Signal() {
+	if b.waiters.Load() > 0 {
+		b.mu.Lock()
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	}
+}
+
+// RingBuffer 单生产者多消费者环形缓冲区，T为预先分配的事件类型
+type RingBuffer[T any] struct {
+	mask    int64
+	entries []T
+	wait    WaitStrategy
+
+	cursor *Sequence // 最后发布的序号
+	closed atomic.Bool
+
+	// 以下字段只由生产者访问
+	next      int64
+	consumers []*Sequence
+	gate      int64 // 上次看到的最慢消费者序号，避免每次发布都扫描所有消费者
+}
+
+// New 创建容量为size（向上取整为2的幂）的环形缓冲区
+func New[T any](size int, wait WaitStrategy) *RingBuffer[T] {
+	n := int64(2)
+	for n < int64(size) {
+		n <<= 1
+	}
+	return &RingBuffer[T]{
+		mask:    n - 1,
+		entries: make([]T, n),
+		wait:    wait,
+		cursor:  newSequence(),
+		next:    -1,
+		gate:    -1,
+	}
+}
+
+// Consumer 一个消费者，会按顺序看到全部事件
+type Consumer[T any] struct {
+	ring    *RingBuffer[T]
+	seq     *Sequence
+	handler func(seq int64, event *T, endOfBatch bool)
+}
+
+// NewConsumer 注册消费者，必须在生产者开始发布之前调用
+func (r *RingBuffer[T]) NewConsumer(handler func(seq int64, event *T, endOfBatch bool)) *Consumer[T] {
+	c := &Consumer[T]{ring: r, seq: newSequence(), handler: handler}
+	r.consumers = append(r.consumers, c.seq)
+	return c
+}
+
+// Run 处理事件直到缓冲区关闭且所有事件都已处理
+func (c *Consumer[T]) Run() {
+	r := c.ring
+	next := c.seq.Load() + 1
+	for {
+		available, ok := r.wait.WaitFor(next, r.cursor, &r.closed)
+		if !ok {
+			return
+		}
+		// 一次处理已发布的整批事件，批次结束时才更新序号
+		for ; next <= available; next++ {
+			c.handler(next, &r.entries[next&r.mask], next == available)
+		}
+		c.seq.v.Store(available)
+	}
+}
+
+// Publish 申请下一个槽位，用fill原地填写事件后发布；缓冲区满时等待最慢的消费者
+func (r *RingBuffer[T]) Publish(fill func(event *T)) {
+	next := r.next + 1
+	wrap := next - int64(len(r.entries))
+	for wrap > r.gate {
+		r.gate = r.minConsumer()
+		if wrap > r.gate {
+			runtime.Gosched()
+		}
+	}
+
+	fill(&r.entries[next&r.mask])
+	r.next = next
+	r.cursor.v.Store(next)
+	r.wait.Signal()
+}
+
+func (r *RingBuffer[T]) minConsumer() int64 {
+	min := r.next
+	for _, s := range r.consumers {
+		if v := s.Load(); v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// Close 不再发布事件，消费者处理完剩余事件后Run返回
+func (r *RingBuffer[T]) Close() {
+	r.closed.Store(true)
+	r.wait.Signal()
+}
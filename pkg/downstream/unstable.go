@@ -0,0 +1,61 @@
+// Package downstream 提供示例共用的模拟下游服务，用于演示熔断、限流等保护机制。
+package downstream
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// UnstableService 模拟不稳定的外部服务
+type UnstableService struct {
+	failureRate float64      // 失败率（0.0-1.0）
+	quiet       bool         // 为true时不打印每次调用的结果
+	mu          sync.RWMutex // 保护失败率的并发修改
+}
+
+// NewUnstableService 创建不稳定服务实例
+func NewUnstableService(failureRate float64) *UnstableService {
+	return &UnstableService{
+		failureRate: failureRate,
+	}
+}
+
+// Call 模拟服务调用
+func (s *UnstableService) Call(requestID string) error {
+	s.mu.RLock()
+	failureRate, quiet := s.failureRate, s.quiet
+	s.mu.RUnlock()
+
+	// 模拟网络延迟和处理时间
+	time.Sleep(time.Duration(rand.Intn(200)+50) * time.Millisecond)
+
+	// 根据失败率随机决定成功或失败
+	if rand.Float64() < failureRate {
+		if !quiet {
+			fmt.Printf("服务调用失败: %s\n", requestID)
+		}
+		return fmt.Errorf("service call failed for request %s", requestID)
+	}
+
+	if !quiet {
+		fmt.Printf("服务调用成功: %s\n", requestID)
+	}
+	return nil
+}
+
+// SetFailureRate 动态设置失败率（用于演示）
+func (s *UnstableService) SetFailureRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failureRate = rate
+	fmt.Printf("服务失败率调整为: %.2f%%\n", rate*100)
+}
+
+// SetQuiet 关闭每次调用的输出，调用量大的演示中使用
+func (s *UnstableService) SetQuiet(quiet bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quiet = quiet
+}
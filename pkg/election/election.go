@@ -0,0 +1,234 @@
+// Package election 提供基于租约的领导者选举。
+//
+// 所有节点通过共享的 Store（模拟etcd、ZooKeeper等协调服务）竞争同一个租约，
+// 拿到租约的节点成为领导者并定期续约；领导者崩溃或与Store失联时租约过期，
+// 其余节点自动选出新的领导者。每次换届任期(term)递增，可以当作fencing token使用。
+//
+// 用法与 k8s client-go 的 leaderelection 类似：
+//
+//	e := election.New(election.Config{
+//		ID:    "node-1",
+//		Store: store,
+//		TTL:   300 * time.Millisecond,
+//		OnElected: func(ctx context.Context, term uint64) {
+//			// 只有领导者才做的工作，ctx在失去领导权时被取消
+//		},
+//	})
+//	go e.Run(ctx)
+package election
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUnreachable 节点与Store之间网络不通
+var ErrUnreachable = errors.New("election: store unreachable")
+
+// Store 保存租约的协调服务，所有节点共享同一个Store
+type Store struct {
+	mu          sync.Mutex
+	leader      string
+	term        uint64
+	expires     time.Time
+	partitioned map[string]bool
+}
+
+func NewStore() *Store {
+	return &Store{partitioned: make(map[string]bool)}
+}
+
+// TryAcquire 租约空闲、已过期或本来就属于id时授予（续约）租约，返回当前任期
+// 新领导者上任时任期加一，同一领导者续约时任期不变
+func (s *Store) TryAcquire(id string, ttl time.Duration) (term uint64, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.partitioned[id] {
+		return 0, false, ErrUnreachable
+	}
+
+	now := time.Now()
+	if s.leader != "" && s.leader != id && now.Before(s.expires) {
+		return s.term, false, nil
+	}
+	if s.leader != id || !now.Before(s.expires) {
+		s.term++
+	}
+	s.leader = id
+	s.expires = now.Add(ttl)
+	return s.term, true, nil
+}
+
+// Resign 领导者主动放弃租约，其他节点无需等待过期
+func (s *Store) Resign(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.partitioned[id] {
+		return ErrUnreachable
+	}
+	if s.leader == id {
+		s.leader = ""
+		s.expires = time.Time{}
+	}
+	return nil
+}
+
+// Leader 返回当前未过期的领导者，没有时返回空字符串
+func (s *Store) Leader() (id string, term uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leader == "" || !time.Now().Before(s.expires) {
+		return "", s.term
+	}
+	return s.leader, s.term
+}
+
+// Partition 模拟网络分区：isolated为true时id的所有请求都返回ErrUnreachable
+func (s *Store) Partition(id string, isolated bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partitioned[id] = isolated
+}
+
+// Config 选举参数与回调
+// OnElected在新goroutine中执行；OnDemoted和OnNewLeader在Run的goroutine中同步调用，应尽快返回
+type Config struct {
+	ID    string
+	Store *Store
+	TTL   time.Duration // 租约时长，默认1秒
+
+	// RetryInterval 跟随者尝试获取租约、领导者续约的间隔，默认TTL/3
+	RetryInterval time.Duration
+
+	// OnElected 成为领导者时在新goroutine中调用，ctx在失去领导权时被取消
+	OnElected func(ctx context.Context, term uint64)
+	// OnDemoted 失去领导权且OnElected已经返回后调用
+	OnDemoted func(term uint64)
+	// OnNewLeader 观察到领导者变化时调用（包括自己当选）
+	OnNewLeader func(id string, term uint64)
+}
+
+// Elector 一个参与选举的节点
+type Elector struct {
+	cfg Config
+
+	leader atomic.Bool
+	term   atomic.Uint64
+}
+
+func New(cfg Config) *Elector {
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Second
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = cfg.TTL / 3
+	}
+	return &Elector{cfg: cfg}
+}
+
+// IsLeader 当前是否认为自己是领导者
+func (e *Elector) IsLeader() bool { return e.leader.Load() }
+
+// Term 最近一次当选的任期
+func (e *Elector) Term() uint64 { return e.term.Load() }
+
+// Run 参与选举直到ctx结束；退出时如果是领导者会主动让出租约
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.RetryInterval)
+	defer ticker.Stop()
+
+	var (
+		leadCancel  context.CancelFunc
+		leadDone    chan struct{}
+		localExpiry time.Time
+		lastLeader  string
+		lastTerm    uint64
+	)
+
+	demote := func() {
+		if leadCancel == nil {
+			return
+		}
+		e.leader.Store(false)
+		leadCancel()
+		<-leadDone
+		leadCancel = nil
+		if e.cfg.OnDemoted != nil {
+			e.cfg.OnDemoted(e.term.Load())
+		}
+	}
+	defer func() {
+		if leadCancel != nil {
+			demote()
+			e.cfg.Store.Resign(e.cfg.ID)
+		}
+	}()
+
+	for {
+		start := time.Now()
+		term, ok, err := e.cfg.Store.TryAcquire(e.cfg.ID, e.cfg.TTL)
+
+		switch {
+		case err != nil:
+			// 联系不上Store：领导者在本地估计的租约到期前仍可继续，到期后必须主动退位，
+			// 因为Store那边的租约可能已经过期并被别人拿走
+			if leadCancel != nil && !time.Now().Before(localExpiry) {
+				demote()
+			}
+
+		case ok:
+			// 本地到期时间从发出请求的时刻算起，并扣掉10%余量，保证比Store更早认为租约失效
+			localExpiry = start.Add(e.cfg.TTL * 9 / 10)
+			if leadCancel != nil && term != e.term.Load() {
+				// 租约曾经过期又重新拿到：旧任期的工作必须先停止
+				demote()
+			}
+			if leadCancel == nil {
+				e.term.Store(term)
+				e.leader.Store(true)
+				leadCtx, cancel := context.WithCancel(ctx)
+				leadCancel, leadDone = cancel, make(chan struct{})
+				go func(done chan struct{}) {
+					defer close(done)
+					if e.cfg.OnElected != nil {
+						e.cfg.OnElected(leadCtx, term)
+					}
+				}(leadDone)
+			}
+
+		default:
+			demote()
+		}
+
+		if err == nil {
+			if id, t := e.cfg.Store.Leader(); id != "" && (id != lastLeader || t != lastTerm) {
+				lastLeader, lastTerm = id, t
+				if e.cfg.OnNewLeader != nil {
+					e.cfg.OnNewLeader(id, t)
+				}
+			}
+		}
+
+		// 领导者的本地租约可能在两次续约之间到期，需要单独的定时器及时退位
+		var expired <-chan time.Time
+		if leadCancel != nil {
+			expired = time.After(time.Until(localExpiry))
+		}
+	wait:
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			demote()
+			expired = nil
+			goto wait
+		case <-ticker.C:
+		}
+	}
+}
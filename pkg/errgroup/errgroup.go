@@ -0,0 +1,55 @@
+// Package errgroup 提供一组协同工作的goroutine：等待全部结束，并返回第一个错误。
+//
+// 与直接使用sync.WaitGroup相比，通过WithContext创建的Group在任一goroutine
+// 返回错误时会取消派生的context，其余goroutine可以据此尽早退出。
+// 接口与golang.org/x/sync/errgroup保持一致，便于日后替换。
+package errgroup
+
+import (
+	"context"
+	"sync"
+)
+
+// Group 一组执行子任务的goroutine，零值可直接使用（不会取消任何context）
+type Group struct {
+	cancel func(error)
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext 返回新的Group和派生的context
+// 第一个返回非nil错误的任务会取消该context，context.Cause可以取到这个错误；
+// Wait返回时context也会被取消
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go 在新的goroutine中执行f
+func (g *Group) Go(f func() error) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		if err := f(); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				if g.cancel != nil {
+					g.cancel(err)
+				}
+			})
+		}
+	}()
+}
+
+// Wait 阻塞直到所有任务结束，返回第一个非nil错误
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	return g.err
+}
@@ -0,0 +1,258 @@
+// Package eventbus 提供进程内的强类型事件总线。
+//
+// 与pkg/pubsub按主题字符串路由不同，这里按事件的Go类型路由：
+//   - 事件类型先用Register登记，发布未登记的类型返回ErrNotRegistered
+//   - Subscribe注册同步处理器，在Publish的调用方goroutine中依次执行，错误汇总返回给发布方
+//   - SubscribeAsync注册异步处理器，每个处理器有自己的队列和goroutine，Publish只负责入队
+//   - 中间件（日志、panic恢复、指标等）包裹每一次处理器调用，同步和异步处理器共用
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrNotRegistered 事件类型没有用Register登记
+	ErrNotRegistered = errors.New("eventbus: event type not registered")
+	// ErrClosed 总线已关闭
+	ErrClosed = errors.New("eventbus: bus closed")
+)
+
+// Envelope 一次处理器调用的上下文，中间件通过它了解正在处理什么
+type Envelope struct {
+	EventType string // 事件类型名，例如 main.OrderPlaced
+	Event     any
+	Handler   string // 处理器名称
+	Async     bool
+}
+
+// HandlerFunc 经过类型擦除的处理器，中间件包裹的就是它
+type HandlerFunc func(ctx context.Context, env Envelope) error
+
+// Middleware 包裹处理器调用，先注册的中间件在最外层
+type Middleware func(next HandlerFunc) HandlerFunc
+
+type subscription struct {
+	id      int64
+	name    string
+	handler HandlerFunc
+	async   bool
+
+	// 以下字段只用于异步处理器
+	mu     sync.RWMutex // Publish持读锁入队，取消订阅持写锁关闭队列
+	closed bool
+	queue  chan asyncItem
+}
+
+type asyncItem struct {
+	ctx context.Context
+	env Envelope
+}
+
+// Bus 事件总线
+type Bus struct {
+	mu          sync.RWMutex
+	types       map[reflect.Type]string
+	subs        map[reflect.Type][]*subscription
+	middlewares []Middleware
+	nextID      int64
+	closed      bool
+
+	onAsyncError func(env Envelope, err error)
+	workers      sync.WaitGroup
+}
+
+// New 创建总线，onAsyncError接收异步处理器返回的错误（可以为nil）
+func New(onAsyncError func(env Envelope, err error)) *Bus {
+	return &Bus{
+		types:        make(map[reflect.Type]string),
+		subs:         make(map[reflect.Type][]*subscription),
+		onAsyncError: onAsyncError,
+	}
+}
+
+// Use 添加中间件，对之后的每一次处理器调用生效
+func (b *Bus) Use(mws ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middlewares = append(b.middlewares, mws...)
+}
+
+// Register 登记事件类型E
+func Register[E any](b *Bus) {
+	t := reflect.TypeOf((*E)(nil)).Elem()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.types[t] = t.String()
+}
+
+func typed[E any](fn func(ctx context.Context, event E) error) HandlerFunc {
+	return func(ctx context.Context, env Envelope) error {
+		return fn(ctx, env.Event.(E))
+	}
+}
+
+// Subscribe 注册E类型的同步处理器，返回取消订阅的函数
+func Subscribe[E any](b *Bus, name string, fn func(ctx context.Context, event E) error) (func(), error) {
+	return b.subscribe(reflect.TypeOf((*E)(nil)).Elem(), &subscription{name: name, handler: typed(fn)})
+}
+
+// SubscribeAsync 注册E类型的异步处理器，队列容量为queueSize；队列满时Publish等待
+func SubscribeAsync[E any](b *Bus, name string, queueSize int, fn func(ctx context.Context, event E) error) (func(), error) {
+	sub := &subscription{name: name, handler: typed(fn), async: true, queue: make(chan asyncItem, queueSize)}
+	return b.subscribe(reflect.TypeOf((*E)(nil)).Elem(), sub)
+}
+
+func (b *Bus) subscribe(t reflect.Type, sub *subscription) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	if _, ok := b.types[t]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotRegistered, t)
+	}
+	b.nextID++
+	sub.id = b.nextID
+	b.subs[t] = append(b.subs[t], sub)
+	if sub.async {
+		b.workers.Add(1)
+		go b.runAsync(sub)
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { b.unsubscribe(t, sub.id) }) }, nil
+}
+
+func (b *Bus) unsubscribe(t reflect.Type, id int64) {
+	b.mu.Lock()
+	subs := b.subs[t]
+	var removed *subscription
+	for i, s := range subs {
+		if s.id == id {
+			removed = s
+			// 复制出新切片，正在遍历旧切片的Publish不受影响
+			b.subs[t] = append(append([]*subscription(nil), subs[:i]...), subs[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if removed != nil && removed.async {
+		removed.closeQueue()
+	}
+}
+
+func (s *subscription) closeQueue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+}
+
+// chain 用当前的中间件包裹处理器
+func (b *Bus) chain(h HandlerFunc) HandlerFunc {
+	b.mu.RLock()
+	mws := b.middlewares
+	b.mu.RUnlock()
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Publish 发布事件：依次执行同步处理器并汇总它们的错误，再把事件放入每个异步处理器的队列
+// 处理器内部可以再次Publish
+func Publish[E any](ctx context.Context, b *Bus, event E) error {
+	t := reflect.TypeOf((*E)(nil)).Elem()
+
+	b.mu.RLock()
+	closed := b.closed
+	name, registered := b.types[t]
+	subs := b.subs[t]
+	b.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+	if !registered {
+		return fmt.Errorf("%w: %s", ErrNotRegistered, t)
+	}
+
+	var errs []error
+	for _, s := range subs {
+		env := Envelope{EventType: name, Event: event, Handler: s.name, Async: s.async}
+		if !s.async {
+			if err := b.chain(s.handler)(ctx, env); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			}
+			continue
+		}
+		if err := s.enqueue(ctx, env); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *subscription) enqueue(ctx context.Context, env Envelope) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		// 并发取消了订阅，忽略即可
+		return nil
+	}
+	select {
+	case s.queue <- asyncItem{ctx: ctx, env: env}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) runAsync(s *subscription) {
+	defer b.workers.Done()
+	for item := range s.queue {
+		// 发布方的ctx可能在入队后就结束了，这里只保留其中的值，不继承取消
+		ctx := context.WithoutCancel(item.ctx)
+		if err := b.chain(s.handler)(ctx, item.env); err != nil && b.onAsyncError != nil {
+			b.onAsyncError(item.env, err)
+		}
+	}
+}
+
+// Close 不再接收发布和订阅，等待异步处理器处理完队列中的事件
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	var async []*subscription
+	for _, subs := range b.subs {
+		for _, s := range subs {
+			if s.async {
+				async = append(async, s)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	for _, s := range async {
+		s.closeQueue()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,111 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PanicError 处理器panic被Recovery中间件转换成的错误
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic: %v", e.Value)
+}
+
+// Recovery 把处理器的panic转换为*PanicError，避免一个处理器拖垮发布方或异步goroutine
+func Recovery() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, env Envelope) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+			return next(ctx, env)
+		}
+	}
+}
+
+// Logging 每次调用结束后用logf输出处理器、耗时和错误
+func Logging(logf func(format string, args ...any)) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, env Envelope) error {
+			start := time.Now()
+			err := next(ctx, env)
+			mode := "sync"
+			if env.Async {
+				mode = "async"
+			}
+			if err != nil {
+				logf("%s -> %s (%s) %v 失败: %v", env.EventType, env.Handler, mode, time.Since(start).Round(time.Microsecond), err)
+			} else {
+				logf("%s -> %s (%s) %v", env.EventType, env.Handler, mode, time.Since(start).Round(time.Microsecond))
+			}
+			return err
+		}
+	}
+}
+
+// HandlerMetrics 单个处理器的统计
+type HandlerMetrics struct {
+	Handler string
+	Calls   int64
+	Errors  int64
+	Total   time.Duration
+	Max     time.Duration
+}
+
+// Metrics 按处理器统计调用次数、错误数和耗时
+type Metrics struct {
+	mu       sync.Mutex
+	handlers map[string]*HandlerMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{handlers: make(map[string]*HandlerMetrics)}
+}
+
+// Middleware 返回记录指标的中间件；放在Recovery外层时panic也会被计为错误
+func (m *Metrics) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, env Envelope) error {
+			start := time.Now()
+			err := next(ctx, env)
+			elapsed := time.Since(start)
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			h, ok := m.handlers[env.Handler]
+			if !ok {
+				h = &HandlerMetrics{Handler: env.Handler}
+				m.handlers[env.Handler] = h
+			}
+			h.Calls++
+			if err != nil {
+				h.Errors++
+			}
+			h.Total += elapsed
+			h.Max = max(h.Max, elapsed)
+			return err
+		}
+	}
+}
+
+// Snapshot 按处理器名称排序的统计快照
+func (m *Metrics) Snapshot() []HandlerMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]HandlerMetrics, 0, len(m.handlers))
+	for _, h := range m.handlers {
+		out = append(out, *h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Handler < out[j].Handler })
+	return out
+}
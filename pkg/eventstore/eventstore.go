@@ -0,0 +1,158 @@
+// Package eventstore 提供内存中的追加式事件存储和追赶订阅(catch-up subscription)。
+//
+// 所有事件写入一个全局日志，每个事件有全局位置Position（从1开始）和所属流内的版本Version。
+// 写入时带上期望的流版本，版本不一致说明期间有其他写入者，返回ErrConcurrency（乐观并发控制）。
+//
+// 订阅者从任意位置开始按顺序读取日志：先读历史事件（追赶阶段），读到末尾后等待新事件（实时阶段）。
+// 两个阶段读的是同一个日志，所以"先读历史再订阅实时消息"那种做法中间的遗漏和重复在这里不会出现。
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrConcurrency 流的当前版本与期望版本不一致
+var ErrConcurrency = errors.New("eventstore: concurrency conflict")
+
+// AnyVersion 不检查流版本
+const AnyVersion = -1
+
+// Event 已存储的事件
+type Event struct {
+	Position int64 // 全局位置
+	StreamID string
+	Version  int // 流内版本，从1开始
+	Type     string
+	Data     any
+	Time     time.Time
+}
+
+// NewEvent 待写入的事件
+type NewEvent struct {
+	Type string
+	Data any
+}
+
+// Store 内存事件存储
+type Store struct {
+	mu       sync.RWMutex
+	log      []Event
+	versions map[string]int
+	appended chan struct{} // 每次写入后关闭并替换，用来唤醒等待新事件的订阅者
+}
+
+func New() *Store {
+	return &Store{versions: make(map[string]int), appended: make(chan struct{})}
+}
+
+// Append 向流追加事件，expectedVersion为写入前流的版本（新流为0），AnyVersion表示不检查
+// 返回最后一个事件的全局位置
+func (s *Store) Append(streamID string, expectedVersion int, events ...NewEvent) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.versions[streamID]
+	if expectedVersion != AnyVersion && expectedVersion != current {
+		return 0, fmt.Errorf("%w: %s 期望版本 %d, 当前版本 %d", ErrConcurrency, streamID, expectedVersion, current)
+	}
+
+	now := time.Now()
+	for _, e := range events {
+		current++
+		s.log = append(s.log, Event{
+			Position: int64(len(s.log)) + 1,
+			StreamID: streamID,
+			Version:  current,
+			Type:     e.Type,
+			Data:     e.Data,
+			Time:     now,
+		})
+	}
+	s.versions[streamID] = current
+
+	close(s.appended)
+	s.appended = make(chan struct{})
+	return int64(len(s.log)), nil
+}
+
+// Load 读取一个流的全部事件和当前版本
+func (s *Store) Load(streamID string) ([]Event, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Event
+	for _, e := range s.log {
+		if e.StreamID == streamID {
+			out = append(out, e)
+		}
+	}
+	return out, s.versions[streamID]
+}
+
+// Head 最后一个事件的全局位置，没有事件时为0
+func (s *Store) Head() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.log))
+}
+
+// read 读取位置大于after的最多limit个事件；没有新事件时返回下一次写入会关闭的channel
+func (s *Store) read(after int64, limit int) ([]Event, <-chan struct{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if after >= int64(len(s.log)) {
+		return nil, s.appended
+	}
+	end := min(after+int64(limit), int64(len(s.log)))
+	// 日志只追加不修改，复制出来后可以在锁外使用
+	return append([]Event(nil), s.log[after:end]...), nil
+}
+
+// Subscription 一个追赶订阅
+type Subscription struct {
+	events   chan Event
+	caughtUp chan struct{}
+}
+
+// Events 按全局位置顺序输出事件，ctx结束后关闭
+func (sub *Subscription) Events() <-chan Event { return sub.events }
+
+// CaughtUp 第一次读到日志末尾（追赶阶段结束）时关闭
+func (sub *Subscription) CaughtUp() <-chan struct{} { return sub.caughtUp }
+
+// Subscribe 从位置after之后开始订阅，after为0表示从头开始
+// 每次从日志中读取一批事件，消费者跟不上时订阅goroutine阻塞在发送上，不会无限缓冲
+func (s *Store) Subscribe(ctx context.Context, after int64) *Subscription {
+	sub := &Subscription{events: make(chan Event, 64), caughtUp: make(chan struct{})}
+	go func() {
+		defer close(sub.events)
+		caughtUp := false
+		for {
+			batch, wait := s.read(after, 256)
+			if wait != nil {
+				if !caughtUp {
+					caughtUp = true
+					close(sub.caughtUp)
+				}
+				select {
+				case <-wait:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+			for _, e := range batch {
+				select {
+				case sub.events <- e:
+					after = e.Position
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return sub
+}
@@ -0,0 +1,79 @@
+// Package fanin 提供扇入工具：把多个channel合并为一个。
+//
+// Merge 按到达顺序输出；MergeOrdered 根据元素自带的序号，用重排缓冲区
+// 恢复全局顺序，适合"分发给多个worker并行处理、结果按原顺序输出"的场景。
+package fanin
+
+import (
+	"sort"
+	"sync"
+)
+
+// Merge 把多个输入channel合并为一个，全部输入关闭后输出channel关闭
+// 输出顺序取决于各输入的到达时间
+func Merge[T any](chans ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+
+	wg.Add(len(chans))
+	for _, ch := range chans {
+		go func(ch <-chan T) {
+			defer wg.Done()
+			for v := range ch {
+				out <- v
+			}
+		}(ch)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// MergeOrdered 合并多个输入，并按序号从first开始依次输出
+// seq返回元素的序号；序号不连续的元素先放入重排缓冲区，等前面的元素到齐后再输出。
+// 如果某个序号始终没有出现，缓冲区中的元素会在全部输入关闭后按序号顺序输出。
+// 序号小于下一个待输出序号的元素（过期或重复）、以及与缓冲区中元素序号相同的元素直接丢弃
+func MergeOrdered[T any](first int, seq func(T) int, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		next := first
+		pending := make(map[int]T)
+		for v := range Merge(chans...) {
+			s := seq(v)
+			if _, dup := pending[s]; dup || s < next {
+				continue
+			}
+			pending[s] = v
+
+			// 输出所有已经连续的元素
+			for {
+				v, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				out <- v
+				next++
+			}
+		}
+
+		// 输入已全部关闭，剩余元素按序号输出（中间有缺失的序号）
+		keys := make([]int, 0, len(pending))
+		for s := range pending {
+			keys = append(keys, s)
+		}
+		sort.Ints(keys)
+		for _, s := range keys {
+			out <- pending[s]
+		}
+	}()
+
+	return out
+}
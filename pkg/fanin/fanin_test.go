@@ -0,0 +1,108 @@
+package fanin
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// source 依次发送values后关闭
+func source(values ...int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for _, v := range values {
+			ch <- v
+		}
+	}()
+	return ch
+}
+
+// collect 读取ch直到关闭；超过1秒没有关闭视为挂起
+func collect(t *testing.T, ch <-chan int) []int {
+	t.Helper()
+	var out []int
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return out
+			}
+			out = append(out, v)
+		case <-timeout:
+			t.Fatalf("输出channel没有关闭，已收到 %v", out)
+		}
+	}
+}
+
+func identity(v int) int { return v }
+
+func TestMerge(t *testing.T) {
+	got := collect(t, Merge(source(0, 3, 6), source(1, 4, 7), source(2, 5, 8)))
+	sort.Ints(got)
+	if want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Merge = %v, want %v", got, want)
+	}
+}
+
+func TestMergeNoInputs(t *testing.T) {
+	if got := collect(t, Merge[int]()); len(got) != 0 {
+		t.Fatalf("Merge() = %v, want 空", got)
+	}
+}
+
+func TestMergeOrdered(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  int
+		inputs [][]int
+		want   []int
+	}{
+		{"乱序", 0, [][]int{{0, 3, 6}, {1, 4, 7}, {2, 5, 8}}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}},
+		{"倒序到达", 0, [][]int{{4, 3, 2, 1, 0}}, []int{0, 1, 2, 3, 4}},
+		{"从非零序号开始", 10, [][]int{{12, 10}, {11}}, []int{10, 11, 12}},
+		{"序号缺失", 1, [][]int{{5, 3}, {4, 1}}, []int{1, 3, 4, 5}},
+		{"全部在缺口之后", 0, [][]int{{9, 7}, {8}}, []int{7, 8, 9}},
+		{"过期序号", 5, [][]int{{3, 5, 1, 6}}, []int{5, 6}},
+		{"已输出的重复序号", 0, [][]int{{0, 1, 0, 2, 1}}, []int{0, 1, 2}},
+		{"缓冲区中的重复序号", 0, [][]int{{2, 2, 3}, {3}}, []int{2, 3}},
+		{"缺口之后的过期与重复", 0, [][]int{{-1, 4, 4, -2}}, []int{4}},
+		{"无输入", 0, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chans := make([]<-chan int, len(tt.inputs))
+			for i, in := range tt.inputs {
+				chans[i] = source(in...)
+			}
+			got := collect(t, MergeOrdered(tt.first, identity, chans...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("MergeOrdered = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMergeOrderedSequenceIsIncreasing 多个输入并发到达时输出的序号严格递增，且不丢元素
+func TestMergeOrderedSequenceIsIncreasing(t *testing.T) {
+	const n, workers = 1000, 8
+	chans := make([]<-chan int, workers)
+	for w := range chans {
+		var values []int
+		for v := w; v < n; v += workers {
+			values = append(values, v)
+		}
+		chans[w] = source(values...)
+	}
+	got := collect(t, MergeOrdered(0, identity, chans...))
+	if len(got) != n {
+		t.Fatalf("收到 %d 个元素, want %d", len(got), n)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("第 %d 个元素为 %d", i, v)
+		}
+	}
+}
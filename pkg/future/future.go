@@ -0,0 +1,210 @@
+// Package future 提供泛型的 Future[T] / Promise[T]。
+//
+// Promise是写端，只能被完成一次（Resolve、Reject或Complete，之后的调用被忽略）；
+// Future是读端，可以被任意多个goroutine等待。完成一个Promise从不阻塞，
+// 所以即使等待方已经超时离开，生产方也不会像向无缓冲channel发送那样被卡住。
+//
+// 组合函数：
+//   - Then：在结果上继续计算，错误直接向后传播
+//   - All：全部成功时返回所有结果，任一失败立即失败
+//   - Any：返回第一个成功的结果，全部失败时返回所有错误
+//   - Race：返回第一个完成的结果，无论成功还是失败
+//   - WithTimeout：超时未完成时以ErrTimeout失败
+//
+// Any和Race没有输入时不存在"第一个"结果，立即以ErrNoFutures失败；All没有输入时以空切片成功。
+//
+// 组合函数为每个输入Future启动一个等待goroutine，输入永远不完成时这些goroutine也不会退出，
+// 所以输入本身应当保证最终完成（例如由带超时的计算产生）。
+package future
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTimeout WithTimeout在限定时间内未完成
+var ErrTimeout = errors.New("future: timeout")
+
+// ErrNoFutures 调用Any或Race时没有传入任何Future
+var ErrNoFutures = errors.New("future: no futures")
+
+// Future 一个异步计算的结果
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Promise 用来完成对应的Future
+type Promise[T any] struct {
+	future *Future[T]
+	once   sync.Once
+}
+
+// NewPromise 创建一个未完成的Promise
+func NewPromise[T any]() *Promise[T] {
+	return &Promise[T]{future: &Future[T]{done: make(chan struct{})}}
+}
+
+// Future 返回与Promise对应的Future
+func (p *Promise[T]) Future() *Future[T] { return p.future }
+
+// Complete 以(value, err)完成Future，返回是否是这次调用完成的
+func (p *Promise[T]) Complete(value T, err error) bool {
+	completed := false
+	p.once.Do(func() {
+		p.future.value, p.future.err = value, err
+		close(p.future.done)
+		completed = true
+	})
+	return completed
+}
+
+// Resolve 以成功结果完成Future
+func (p *Promise[T]) Resolve(value T) bool { return p.Complete(value, nil) }
+
+// Reject 以错误完成Future
+func (p *Promise[T]) Reject(err error) bool {
+	var zero T
+	return p.Complete(zero, err)
+}
+
+// Go 在新的goroutine中执行fn，返回它的Future；ctx原样传给fn，由fn自己响应取消
+func Go[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	p := NewPromise[T]()
+	go func() {
+		p.Complete(fn(ctx))
+	}()
+	return p.Future()
+}
+
+// Resolved 返回一个已经成功完成的Future
+func Resolved[T any](value T) *Future[T] {
+	p := NewPromise[T]()
+	p.Resolve(value)
+	return p.Future()
+}
+
+// Rejected 返回一个已经失败的Future
+func Rejected[T any](err error) *Future[T] {
+	p := NewPromise[T]()
+	p.Reject(err)
+	return p.Future()
+}
+
+// Done 完成时关闭，可用于select
+func (f *Future[T]) Done() <-chan struct{} { return f.done }
+
+// Get 阻塞直到完成
+func (f *Future[T]) Get() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Await 等待完成或ctx结束；ctx结束只是停止等待，不会取消计算本身
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Then f成功后用它的结果执行fn；f失败时直接以同样的错误失败，不执行fn
+func Then[T, U any](f *Future[T], fn func(T) (U, error)) *Future[U] {
+	p := NewPromise[U]()
+	go func() {
+		v, err := f.Get()
+		if err != nil {
+			p.Reject(err)
+			return
+		}
+		p.Complete(fn(v))
+	}()
+	return p.Future()
+}
+
+// All 所有Future都成功时按顺序返回结果；任一失败时立即以该错误失败
+func All[T any](fs ...*Future[T]) *Future[[]T] {
+	p := NewPromise[[]T]()
+	results := make([]T, len(fs))
+
+	var wg sync.WaitGroup
+	for i, f := range fs {
+		wg.Add(1)
+		go func(i int, f *Future[T]) {
+			defer wg.Done()
+			v, err := f.Get()
+			if err != nil {
+				p.Reject(err)
+				return
+			}
+			results[i] = v
+		}(i, f)
+	}
+	go func() {
+		wg.Wait()
+		p.Resolve(results)
+	}()
+	return p.Future()
+}
+
+// Any 返回第一个成功的结果；全部失败时以errors.Join合并的错误失败
+func Any[T any](fs ...*Future[T]) *Future[T] {
+	if len(fs) == 0 {
+		// errors.Join()返回nil，不提前处理的话会以零值"成功"
+		return Rejected[T](ErrNoFutures)
+	}
+	p := NewPromise[T]()
+	errs := make([]error, len(fs))
+
+	var wg sync.WaitGroup
+	for i, f := range fs {
+		wg.Add(1)
+		go func(i int, f *Future[T]) {
+			defer wg.Done()
+			v, err := f.Get()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			p.Resolve(v)
+		}(i, f)
+	}
+	go func() {
+		wg.Wait()
+		p.Reject(errors.Join(errs...))
+	}()
+	return p.Future()
+}
+
+// Race 返回第一个完成的Future的结果，无论成功还是失败
+func Race[T any](fs ...*Future[T]) *Future[T] {
+	if len(fs) == 0 {
+		// 没有输入时永远等不到第一个完成的结果
+		return Rejected[T](ErrNoFutures)
+	}
+	p := NewPromise[T]()
+	for _, f := range fs {
+		go func(f *Future[T]) {
+			p.Complete(f.Get())
+		}(f)
+	}
+	return p.Future()
+}
+
+// WithTimeout d之内没有完成时以ErrTimeout失败
+func WithTimeout[T any](f *Future[T], d time.Duration) *Future[T] {
+	p := NewPromise[T]()
+	timer := time.AfterFunc(d, func() { p.Reject(ErrTimeout) })
+	go func() {
+		v, err := f.Get()
+		timer.Stop()
+		p.Complete(v, err)
+	}()
+	return p.Future()
+}
@@ -0,0 +1,105 @@
+package future
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errA = errors.New("a失败")
+	errB = errors.New("b失败")
+)
+
+// get 等待f完成，超过1秒视为挂起
+func get[T any](t *testing.T, f *Future[T]) (T, error) {
+	t.Helper()
+	select {
+	case <-f.Done():
+		return f.Get()
+	case <-time.After(time.Second):
+		t.Fatal("Future没有完成")
+		panic("unreachable")
+	}
+}
+
+// later d之后以(v, err)完成
+func later[T any](d time.Duration, v T, err error) *Future[T] {
+	p := NewPromise[T]()
+	time.AfterFunc(d, func() { p.Complete(v, err) })
+	return p.Future()
+}
+
+func TestNoFutures(t *testing.T) {
+	if _, err := get(t, Any[int]()); !errors.Is(err, ErrNoFutures) {
+		t.Fatalf("Any() err = %v, want ErrNoFutures", err)
+	}
+	if _, err := get(t, Race[int]()); !errors.Is(err, ErrNoFutures) {
+		t.Fatalf("Race() err = %v, want ErrNoFutures", err)
+	}
+	if v, err := get(t, All[int]()); err != nil || len(v) != 0 {
+		t.Fatalf("All() = %v, %v, want [], nil", v, err)
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func() *Future[int]
+		want    int
+		wantErr []error // 返回的错误需要包含的全部错误
+	}{
+		{"Any返回第一个成功的结果", func() *Future[int] {
+			return Any(Rejected[int](errA), later(20*time.Millisecond, 2, nil), later(10*time.Millisecond, 1, nil))
+		}, 1, nil},
+		{"Any全部失败时合并所有错误", func() *Future[int] {
+			return Any(Rejected[int](errA), later(10*time.Millisecond, 0, errB))
+		}, 0, []error{errA, errB}},
+		{"Race返回第一个完成的结果", func() *Future[int] {
+			return Race(later(20*time.Millisecond, 1, nil), later(10*time.Millisecond, 0, errB))
+		}, 0, []error{errB}},
+		{"Then传播错误", func() *Future[int] {
+			return Then(Rejected[int](errA), func(v int) (int, error) { return v + 1, nil })
+		}, 0, []error{errA}},
+		{"WithTimeout超时", func() *Future[int] {
+			return WithTimeout(later(time.Second, 1, nil), 10*time.Millisecond)
+		}, 0, []error{ErrTimeout}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := get(t, tt.run())
+			if v != tt.want {
+				t.Fatalf("值 = %d, want %d", v, tt.want)
+			}
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			for _, w := range tt.wantErr {
+				if !errors.Is(err, w) {
+					t.Fatalf("err = %v，不包含 %v", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	v, err := get(t, All(later(20*time.Millisecond, 1, nil), Resolved(2), later(10*time.Millisecond, 3, nil)))
+	if err != nil || len(v) != 3 || v[0] != 1 || v[1] != 2 || v[2] != 3 {
+		t.Fatalf("All() = %v, %v, want [1 2 3], nil", v, err)
+	}
+	if _, err := get(t, All(later(time.Second, 1, nil), Rejected[int](errA))); !errors.Is(err, errA) {
+		t.Fatalf("All() err = %v, want 立即以errA失败", err)
+	}
+}
+
+// TestCompleteOnce 只有第一次完成生效
+func TestCompleteOnce(t *testing.T) {
+	p := NewPromise[int]()
+	if !p.Resolve(1) || p.Reject(errA) || p.Resolve(2) {
+		t.Fatal("只有第一次完成应该返回true")
+	}
+	if v, err := get(t, p.Future()); v != 1 || err != nil {
+		t.Fatalf("Get() = %d, %v, want 1, nil", v, err)
+	}
+}
@@ -0,0 +1,94 @@
+// Package golden 支持用"黄金输出"对demo做回归检查。
+//
+// 检查命令（go run ./golden）设置DEMO_DETERMINISTIC=1运行每个demo，把输出规范化后
+// 和cmd/级别/testdata/中保存的期望输出比较。确定性模式下demo的输出应当每次都相同：
+//
+//   - 随机数用NewRand创建，确定性模式下使用固定的种子
+//   - 吞吐量、竞争次数、哪个goroutine抢到了任务这类每次运行都不同的值用Varying包装，
+//     确定性模式下输出占位符
+//   - 多个goroutine并发输出、顺序每次不同的部分用Unordered标记，比较时只看输出了哪些行
+//   - 时长和时刻不需要处理，比较之前由Normalize替换成占位符
+//
+// 单独运行demo时没有设置环境变量，这些函数不改变demo的行为。
+package golden
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// EnvVar 设置为1时demo以确定性模式运行
+const EnvVar = "DEMO_DETERMINISTIC"
+
+// Seed 确定性模式下随机数的种子
+const Seed = 1
+
+// Deterministic 是否以确定性模式运行
+func Deterministic() bool { return os.Getenv(EnvVar) == "1" }
+
+// NewRand 返回随机数生成器：确定性模式下种子固定，否则按当前时间播种。
+// 多个goroutine各自调用NewRand时，确定性模式下它们得到相同的序列，需要不同序列时用NewRandOffset
+func NewRand() *rand.Rand {
+	return NewRandOffset(0)
+}
+
+// NewRandOffset 和NewRand相同，确定性模式下种子为Seed+offset，用于给每个goroutine不同的序列
+func NewRandOffset(offset int64) *rand.Rand {
+	if Deterministic() {
+		return rand.New(rand.NewSource(Seed + offset))
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano() + offset))
+}
+
+// Placeholder 确定性模式下Varying输出的内容
+const Placeholder = "<不固定>"
+
+// Varying 包装一个每次运行都可能不同的值（吞吐量、竞争次数、抢到任务的消费者等），用法与原值相同：
+//
+//	fmt.Printf("%8d 次/秒\n", golden.Varying(ops))
+//
+// 正常运行时按原来的格式输出v；确定性模式下输出Placeholder，保留宽度和左右对齐，表格不会错位
+func Varying(v any) fmt.Formatter { return varying{v} }
+
+type varying struct{ v any }
+
+func (x varying) Format(f fmt.State, verb rune) {
+	if !Deterministic() {
+		fmt.Fprintf(f, fmt.FormatString(f, verb), x.v)
+		return
+	}
+	format := "%"
+	if f.Flag('-') {
+		format += "-"
+	}
+	if w, ok := f.Width(); ok {
+		format += fmt.Sprint(w)
+	}
+	fmt.Fprintf(f, format+"s", Placeholder)
+}
+
+// 无序区域的开始和结束标记，只在确定性模式下输出
+const (
+	BeginUnordered = "<<<以下输出顺序不固定>>>"
+	EndUnordered   = "<<<顺序不固定的输出结束>>>"
+)
+
+// Unordered 标记一段由多个goroutine并发输出、顺序每次可能不同的区域，返回结束这个区域的函数：
+//
+//	end := golden.Unordered()
+//	go printNumbers()
+//	go printLetters()
+//	time.Sleep(time.Second)
+//	end()
+//
+// 确定性模式下在开始和结束处各输出一行标记，Normalize把两行标记之间的行排序后再比较，
+// 只检查输出了哪些行而不检查顺序；正常运行时什么也不输出
+func Unordered() (end func()) {
+	if !Deterministic() {
+		return func() {}
+	}
+	fmt.Println(BeginUnordered)
+	return func() { fmt.Println(EndUnordered) }
+}
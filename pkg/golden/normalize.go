@@ -0,0 +1,139 @@
+package golden
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 规范化规则，按顺序替换；完整的时间戳要在单独的日期、时刻之前替换
+var rules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// 2024-01-02T15:04:05.999Z07:00、2024/01/02 15:04:05 这样的时间戳
+	{regexp.MustCompile(`\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?( [A-Z]{3,4})?`), "<时间>"},
+	{regexp.MustCompile(`\d{4}[-/]\d{2}[-/]\d{2}`), "<日期>"},
+	{regexp.MustCompile(`\b\d{1,2}:\d{2}:\d{2}(\.\d+)?`), "<时间>"},
+	// time.Duration的输出：1h2m3.5s、250ms、1.5µs
+	{regexp.MustCompile(`\b(\d+(\.\d+)?(h|ms|m|s|µs|us|ns))+\b`), "<时长>"},
+	// 指针、channel等的地址
+	{regexp.MustCompile(`\b0x[0-9a-f]{6,}\b`), "<地址>"},
+}
+
+// 时长等按宽度对齐时（%-10v），原来的值长短不同，占位符前后的空格数也随之变化，统一成一个空格
+var (
+	spaceBefore = regexp.MustCompile(`[ \t]+(<时间>|<日期>|<时长>|<地址>)`)
+	spaceAfter  = regexp.MustCompile(`(<时间>|<日期>|<时长>|<地址>)[ \t]+`)
+)
+
+// trailingSpace 行尾的空白，同样会随对齐的宽度变化
+var trailingSpace = regexp.MustCompile(`[ \t]+\n`)
+
+// Normalize 把每次运行都会不同的时刻和时长替换成占位符，统一换行符和占位符两边、行尾的空白，
+// 把Unordered标记的区域内的行排序
+func Normalize(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	for _, r := range rules {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	s = spaceBefore.ReplaceAllString(s, " $1")
+	s = spaceAfter.ReplaceAllString(s, "$1 ")
+	s = trailingSpace.ReplaceAllString(s, "\n")
+	return sortUnordered(strings.TrimRight(s, " \t"))
+}
+
+// sortUnordered 把Unordered标记之间的行排序；缺少结束标记时排序到输出末尾
+func sortUnordered(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := 0; i < len(lines); i++ {
+		if lines[i] != BeginUnordered {
+			continue
+		}
+		j := i + 1
+		for j < len(lines) && lines[j] != EndUnordered {
+			j++
+		}
+		sort.Strings(lines[i+1 : j])
+		i = j
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diff 逐行比较want和got，相同时返回空串；不同时返回类似diff -u的输出，
+// 只保留差异附近context行，以"-"开头的行只在期望输出中，以"+"开头的行只在实际输出中
+func Diff(want, got string, context int) string {
+	if want == got {
+		return ""
+	}
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	// 标记每个操作是否在某个差异的context行以内
+	keep := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind != ' ' {
+			for j := max(0, i-context); j <= min(len(ops)-1, i+context); j++ {
+				keep[j] = true
+			}
+		}
+	}
+	for i, op := range ops {
+		if !keep[i] {
+			if i > 0 && keep[i-1] {
+				out.WriteString("...\n")
+			}
+			continue
+		}
+		if i == 0 || !keep[i-1] {
+			fmt.Fprintf(&out, "@@ 期望第%d行，实际第%d行 @@\n", op.a+1, op.b+1)
+		}
+		fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind byte // ' '相同，'-'删除，'+'新增
+	line string
+	a, b int // 这一行在want和got中的下标（用于显示行号）
+}
+
+// diffLines 基于最长公共子序列的逐行比较；demo的输出只有几百行，O(n*m)足够
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	// lcs[i][j] 是a[i:]和b[j:]的最长公共子序列长度
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		default:
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		}
+	}
+	return ops
+}
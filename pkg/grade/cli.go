@@ -0,0 +1,56 @@
+package grade
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 评分器、进度、挑战和变异测试几个命令共用的函数
+
+// ExercisesDir 在仓库根目录或exercises目录下运行都能找到练习
+func ExercisesDir() (string, error) {
+	for _, dir := range []string{"exercises", "."} {
+		if _, err := os.Stat(filepath.Join(dir, "simple")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("找不到练习目录，请在仓库根目录或exercises目录下运行")
+}
+
+// Selected 参数为空时全选；参数可以是级别、"级别/编号"或练习文件路径
+func Selected(ex Exercise, args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	id := ex.Level + "/" + ex.Name
+	for _, a := range args {
+		a = strings.TrimSuffix(filepath.ToSlash(a), "/")
+		switch {
+		case a == ex.Level,
+			strings.HasPrefix(id, a),
+			strings.HasSuffix(filepath.ToSlash(ex.File), a):
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayWidth 显示宽度，中文字符和符号占两列
+func DisplayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return w
+}
+
+// Pad 按显示宽度补齐空格
+func Pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-DisplayWidth(s), 0))
+}
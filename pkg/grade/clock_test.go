@@ -0,0 +1,48 @@
+package grade
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	c := NewFakeClock()
+	now := c.After(0)
+	a := c.After(time.Second)
+	b := c.After(3 * time.Second)
+	select {
+	case <-now:
+	default:
+		t.Fatal("After(0)没有立即到期")
+	}
+	if c.Pending() != 2 {
+		t.Fatalf("Pending() = %d, want 2", c.Pending())
+	}
+
+	c.Advance(time.Second)
+	select {
+	case at := <-a:
+		if at.Sub(Epoch) != time.Second {
+			t.Fatalf("计时器在 %v 到期, want 1s", at.Sub(Epoch))
+		}
+	default:
+		t.Fatal("Advance到1s后After(1s)没有到期")
+	}
+	select {
+	case <-b:
+		t.Fatal("After(3s)提前到期")
+	default:
+	}
+
+	go func() {
+		c.After(time.Second)
+	}()
+	if !c.WaitPending(2) {
+		t.Fatal("WaitPending(2)没有等到新的计时器")
+	}
+	c.Advance(2 * time.Second)
+	if c.Pending() != 0 || c.Now().Sub(Epoch) != 3*time.Second {
+		t.Fatalf("Pending() = %d，Now() = Epoch+%v, want 0, 3s", c.Pending(), c.Now().Sub(Epoch))
+	}
+	<-b
+}
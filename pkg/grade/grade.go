@@ -0,0 +1,213 @@
+// Package grade 练习的自动评分。
+//
+// 练习都是单文件的package main，评分任务写在练习旁边的xx_test.go中（同一目录、同属package main），
+// 每个练习一个测试函数，用Test把任务作为子测试执行：
+//
+//	func TestChannelBasic(t *testing.T) {
+//		grade.Test(t, grade.Task{Name: "sender发送后关闭channel", Run: func(t *grade.T) { ... }})
+//	}
+//
+// 单独运行练习（go run xx_exercise.go）时不包含测试文件，练习照常执行。测试可以直接用
+//
+//	go test -race -v xx_exercise.go xx_test.go
+//
+// 运行；评分器（Run）用go test -json运行同样的命令，按子测试统计结果，
+// 竞态检测器报告的数据竞争会让发生竞争时正在运行的子测试失败。
+//
+// 任务在单独的goroutine中运行并有超时：卡住的任务记为失败后留在后台，后面的任务照常执行，
+// 不会像go test -timeout那样让整个测试进程退出。
+//
+// 每个任务结束后还会检查goroutine泄漏：任务开始后创建、结束后LeakGrace内仍没有退出的goroutine
+// 会让任务失败。练习中很多TODO是关闭和退出逻辑（关闭channel、调用Done、响应停止信号），
+// 这类错误单看返回值往往发现不了。设置GRADE_LEAKCHECK=0可以关闭这项检查。
+package grade
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/leakcheck"
+)
+
+// LeakEnvVar 设为0时不检查goroutine泄漏
+const LeakEnvVar = "GRADE_LEAKCHECK"
+
+// DefaultTimeout 任务的默认超时
+const DefaultTimeout = 3 * time.Second
+
+// LeakGrace 任务结束后等待它创建的goroutine退出的时间
+const LeakGrace = time.Second
+
+// leakPrefix 泄漏检查失败时失败原因的开头，评分器据此把任务标记为泄漏
+const leakPrefix = "任务结束后仍有goroutine没有退出"
+
+// Task 一个评分任务
+type Task struct {
+	Name       string
+	Timeout    time.Duration // 超时视为失败（通常是死锁或忘记关闭channel），默认DefaultTimeout
+	AllowLeaks bool          // 不做泄漏检查，用于故意留下goroutine的任务
+	Run        func(t *T)
+}
+
+// T 传给任务的句柄，方法与testing.T的同名方法含义相同
+// 任务可能因为超时被放弃而继续在后台运行，所以不直接使用testing.T：
+// 子测试结束后再调用testing.T的方法会panic
+type T struct {
+	name string
+
+	mu     sync.Mutex
+	failed bool
+	logs   []string
+}
+
+// Name 任务名
+func (t *T) Name() string { return t.name }
+
+// Helper 为了满足leakcheck.TB等接口，没有实际作用
+func (t *T) Helper() {}
+
+// Logf 记录信息，任务失败时一并输出
+func (t *T) Logf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+// Errorf 记录失败原因，任务继续执行
+func (t *T) Errorf(format string, args ...any) {
+	t.Logf(format, args...)
+	t.mu.Lock()
+	t.failed = true
+	t.mu.Unlock()
+}
+
+// Fatalf 记录失败原因并立即结束任务，只能在运行任务的goroutine中调用
+func (t *T) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// Failed 任务是否已失败
+func (t *T) Failed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.failed
+}
+
+// Test 把每个任务作为t的子测试依次执行，子测试名就是任务名
+func Test(t *testing.T, tasks ...Task) {
+	t.Helper()
+	for _, task := range tasks {
+		task := task
+		t.Run(task.Name, func(st *testing.T) {
+			st.Helper()
+			r := runTask(task)
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.failed {
+				for _, l := range r.logs {
+					st.Log(l)
+				}
+				st.Fail()
+			}
+		})
+	}
+}
+
+// leakCheck 是否检查泄漏
+func leakCheck() bool { return os.Getenv(LeakEnvVar) != "0" }
+
+// ignoredLeak 运行时和标准库按需启动、之后一直存在的goroutine，不算练习的泄漏
+func ignoredLeak(g leakcheck.Goroutine) bool {
+	return strings.Contains(g.Stack, "os/signal.loop") ||
+		strings.Contains(g.Stack, "runtime.ensureSigM")
+}
+
+// runTask 执行一个任务，任务完成或超时后返回它的句柄
+func runTask(task Task) *T {
+	timeout := task.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	t := &T{name: task.Name}
+	base := leakcheck.Take()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			// 练习中TODO函数返回nil、重复Done等都会panic，记为失败而不是让评分中断
+			if r := recover(); r != nil {
+				t.Errorf("panic: %v", r)
+			}
+		}()
+		task.Run(t)
+	}()
+	select {
+	case <-done:
+		// 已经失败的任务不再检查：泄漏多半是失败的后果，再报一遍只会干扰
+		if !task.AllowLeaks && !t.Failed() && leakCheck() {
+			checkLeaks(t, base)
+		}
+	case <-time.After(timeout):
+		// 卡住的goroutine无法被强制结束，只能留在后台，后面的任务照常执行
+		t.Errorf("超过 %v 没有完成：检查是否有goroutine在等待永远不会到来的数据（死锁、没有关闭channel、没有调用Done）", timeout)
+	}
+	return t
+}
+
+// checkLeaks 任务开始后创建、LeakGrace内没有退出的goroutine让任务失败
+func checkLeaks(t *T, base leakcheck.Snapshot) {
+	var leaked []leakcheck.Goroutine
+	for _, g := range leakcheck.Check(base, LeakGrace) {
+		if !ignoredLeak(g) {
+			leaked = append(leaked, g)
+		}
+	}
+	if len(leaked) > 0 {
+		t.Errorf("%s（停在哪里见下面的状态和函数，常见原因：没有关闭channel、没有调用Done、没有响应停止信号）\n%s",
+			leakPrefix, leakcheck.Report(leaked, false))
+	}
+}
+
+// CaptureStdout 执行fn并返回它写到标准输出的内容，用来检查打印类的练习
+func CaptureStdout(fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		output <- string(b)
+	}()
+	defer func() {
+		os.Stdout = old
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
+
+// Within fn在d内返回时为true；超时时fn所在的goroutine留在后台
+func Within(d time.Duration, fn func()) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
@@ -0,0 +1,345 @@
+package grade
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Exercise 一个练习文件和它的测试文件
+type Exercise struct {
+	Level    string // simple / medium / hard
+	Name     string // 不带_exercise.go后缀的文件名，例如01_basic_goroutine
+	File     string // 练习文件路径
+	Test     string // 测试文件路径（<name>_test.go），没有时为空
+	Solution string // 参考答案路径（dir/solutions/<level>/<name>_solution.go），没有时为空
+}
+
+// Discover 在dir/<level>/下查找所有*_exercise.go及对应的*_test.go和参考答案，按级别和文件名排序
+func Discover(dir string) ([]Exercise, error) {
+	var list []Exercise
+	for _, level := range []string{"simple", "medium", "hard"} {
+		files, err := filepath.Glob(filepath.Join(dir, level, "*_exercise.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, f := range files {
+			name := strings.TrimSuffix(filepath.Base(f), "_exercise.go")
+			ex := Exercise{Level: level, Name: name, File: f}
+			if t := filepath.Join(dir, level, name+"_test.go"); fileExists(t) {
+				ex.Test = t
+			}
+			if sol := filepath.Join(dir, "solutions", level, name+"_solution.go"); fileExists(sol) {
+				ex.Solution = sol
+			}
+			list = append(list, ex)
+		}
+	}
+	return list, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Result 一个评分任务的结果
+type Result struct {
+	Name     string
+	Passed   bool
+	Duration time.Duration
+	Messages []string // 失败原因
+	Race     bool     // 任务运行期间竞态检测器报告了数据竞争
+	Leak     bool     // 任务结束后有goroutine没有退出
+}
+
+// Report 一个练习的评分结果
+type Report struct {
+	Exercise Exercise
+	Results  []Result
+	Err      string // 编译失败、进程崩溃等不属于某个任务的错误
+	Output   string // go test -v格式的完整输出：评分结果、竞态报告、编译错误
+	Stdout   string // 练习代码自己打印的内容，从测试输出中分离出来
+}
+
+// Passed 所有任务都通过且没有其他错误
+func (r *Report) Passed() bool {
+	if r.Err != "" || len(r.Results) == 0 {
+		return false
+	}
+	for _, res := range r.Results {
+		if !res.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Options 评分选项
+type Options struct {
+	Race      bool          // 用-race运行，数据竞争会让正在运行的任务失败
+	LeakCheck bool          // 每个任务结束后检查goroutine泄漏，泄漏会让任务失败
+	Timeout   time.Duration // 整个练习（包括编译）的超时，默认2分钟
+	Solution  bool          // 评分参考答案而不是练习文件
+	Source    []byte        // 非nil时用这段源码代替练习文件（或参考答案）评分，文件本身不动；变异测试用
+}
+
+// Run 用go test -json运行练习和测试文件，按子测试得到每个任务的结果
+func Run(ctx context.Context, ex Exercise, opts Options) *Report {
+	rep := &Report{Exercise: ex}
+	if ex.Test == "" {
+		rep.Err = "没有测试文件"
+		return rep
+	}
+	file := ex.File
+	if opts.Solution {
+		if ex.Solution == "" {
+			rep.Err = "没有参考答案"
+			return rep
+		}
+		file = ex.Solution
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// 超时由ctx控制；-count=1避免用到缓存的结果；
+	// -vet=off：go test默认的vet检查（例如Println末尾多余的换行）与练习是否正确无关，不能让评分编译失败
+	args := []string{"test", "-json", "-count=1", "-timeout=0", "-vet=off"}
+	if opts.Race {
+		args = append(args, "-race")
+	}
+	testFile := ex.Test
+	replace := make(map[string]string)
+	if filepath.Dir(file) != filepath.Dir(testFile) {
+		// 命令行上的文件必须在同一目录：用overlay让测试文件"出现"在参考答案旁边，不复制文件
+		key, virtual, err := beside(file, testFile)
+		if err != nil {
+			rep.Err = err.Error()
+			return rep
+		}
+		replace[key] = testFile
+		testFile = virtual
+	}
+	if opts.Source != nil {
+		src, err := writeTemp("grade-source-*.go", opts.Source)
+		if err != nil {
+			rep.Err = err.Error()
+			return rep
+		}
+		defer os.Remove(src)
+		replace[file] = src
+	}
+	if len(replace) > 0 {
+		overlay, err := writeOverlay(replace)
+		if err != nil {
+			rep.Err = err.Error()
+			return rep
+		}
+		defer os.Remove(overlay)
+		args = append(args, "-overlay", overlay)
+	}
+	args = append(args, file, testFile)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Env = os.Environ()
+	if !opts.LeakCheck {
+		cmd.Env = append(cmd.Env, LeakEnvVar+"=0")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	p := parse(stdout.Bytes())
+	rep.Results = p.results
+	rep.Output = p.output.String() + stderr.String()
+	rep.Stdout = p.stdout.String()
+	switch {
+	case ctx.Err() != nil:
+		rep.Err = fmt.Sprintf("超过 %v 没有结束", timeout)
+	case len(rep.Results) == 0 && err != nil:
+		rep.Err = "编译或运行失败：\n" + strings.TrimSpace(rep.Output)
+	case !p.finished() && err != nil:
+		// 进程在任务中途退出（练习代码调用了os.Exit、log.Fatal，或者panic发生在任务之外的goroutine中）
+		rep.Err = "测试进程异常退出：" + err.Error()
+	case !raceInTask(rep.Results) && p.race:
+		// 竞争发生在任务之外（例如练习的init或包级变量初始化），归不到任何任务上
+		rep.Err = "竞态检测器发现数据竞争，但不在任何评分任务中：用 go run -race 运行练习查看完整报告"
+	}
+	return rep
+}
+
+func raceInTask(results []Result) bool {
+	for _, r := range results {
+		if r.Race {
+			return true
+		}
+	}
+	return false
+}
+
+// beside 把grade映射到file所在目录下的同名文件，返回overlay中的键（绝对路径）和命令行上使用的路径
+func beside(file, grade string) (key, virtual string, err error) {
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return "", "", err
+	}
+	// 命令行上的路径要与file的写法一致（都是相对路径或都是绝对路径），overlay中用绝对路径
+	virtual = filepath.Join(filepath.Dir(file), filepath.Base(grade))
+	return filepath.Join(dir, filepath.Base(grade)), virtual, nil
+}
+
+// writeOverlay 生成go build -overlay使用的文件，replace的键是被替换的文件，值是实际读取的文件
+func writeOverlay(replace map[string]string) (string, error) {
+	abs := make(map[string]string, len(replace))
+	for k, v := range replace {
+		ka, err := filepath.Abs(k)
+		if err != nil {
+			return "", err
+		}
+		va, err := filepath.Abs(v)
+		if err != nil {
+			return "", err
+		}
+		abs[ka] = va
+	}
+	data, err := json.Marshal(map[string]map[string]string{"Replace": abs})
+	if err != nil {
+		return "", err
+	}
+	return writeTemp("grade-overlay-*.json", data)
+}
+
+// writeTemp 把data写到一个临时文件，返回路径
+func writeTemp(pattern string, data []byte) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// event go test -json输出的一行，见go doc test2json
+type event struct {
+	Action  string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+const (
+	raceWarning = "WARNING: DATA RACE"
+	raceDelim   = "=================="
+)
+
+// message testing.T.Log输出的一条记录的第一行，前面可能粘着练习打印的、没有换行的内容
+var message = regexp.MustCompile(`^(.*?) {4}[^\s:]+\.go:\d+: (.*)$`)
+
+// parsed go test -json输出的解析结果
+type parsed struct {
+	results []Result
+	output  strings.Builder // 还原成go test -v格式的完整输出
+	stdout  strings.Builder // 练习代码打印的内容
+	race    bool            // 竞态检测器报告过数据竞争（不论是否在任务中）
+
+	done map[string]bool // 已经有PASS/FAIL结果的子测试
+}
+
+// finished 每个开始的任务都有结果，进程没有在任务中途退出
+func (p *parsed) finished() bool {
+	return len(p.done) == len(p.results)
+}
+
+// parse 解析go test -json的输出：Test.Test调用的每个任务是一个子测试（"TestXxx/任务名"），
+// 没有结果的子测试（进程中途退出）记为失败
+func parse(data []byte) *parsed {
+	p := &parsed{done: make(map[string]bool)}
+	index := make(map[string]int) // 子测试全名 -> results中的下标
+	var inRace, inMessage bool
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 1<<20), 1<<20)
+	for sc.Scan() {
+		var e event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			// 不是JSON的行（例如go命令自己的错误）原样保留
+			p.output.WriteString(sc.Text() + "\n")
+			continue
+		}
+		_, task, isTask := strings.Cut(e.Test, "/")
+		switch e.Action {
+		case "build-output":
+			p.output.WriteString(e.Output)
+		case "run":
+			if isTask {
+				index[e.Test] = len(p.results)
+				p.results = append(p.results, Result{Name: task})
+			}
+		case "pass", "fail":
+			if i, ok := index[e.Test]; ok {
+				r := &p.results[i]
+				r.Passed = e.Action == "pass" && !r.Race
+				r.Duration = time.Duration(e.Elapsed * float64(time.Second))
+				p.done[e.Test] = true
+			}
+		case "output":
+			p.output.WriteString(e.Output)
+			var cur *Result
+			if i, ok := index[e.Test]; ok {
+				cur = &p.results[i]
+			}
+			line := strings.TrimSuffix(e.Output, "\n")
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case inRace:
+				// 竞态报告以一行"=================="结束
+				inRace = trimmed != raceDelim
+			case trimmed == raceWarning:
+				inRace, inMessage = true, false
+				p.race = true
+				if cur != nil && !cur.Race {
+					cur.Race = true
+					cur.Messages = append(cur.Messages, "竞态检测器发现数据竞争（用 go test -race -v 运行练习和测试文件查看完整报告）")
+				}
+			case strings.HasPrefix(trimmed, "=== "), strings.HasPrefix(trimmed, "--- "),
+				trimmed == raceDelim, // 竞态报告开头的分隔行
+				strings.Contains(trimmed, "race detected during execution of test"),
+				e.Test == "" && (trimmed == "PASS" || trimmed == "FAIL" || strings.HasPrefix(trimmed, "ok  ") || strings.HasPrefix(trimmed, "FAIL\t")):
+				// testing自己的输出
+				inMessage = false
+			case cur != nil && inMessage && strings.HasPrefix(line, "        "):
+				cur.Messages = append(cur.Messages, strings.TrimPrefix(line, "        "))
+			default:
+				if m := message.FindStringSubmatch(line); cur != nil && m != nil {
+					p.stdout.WriteString(m[1])
+					if strings.HasPrefix(m[2], leakPrefix) {
+						cur.Leak = true
+					}
+					cur.Messages = append(cur.Messages, m[2])
+					inMessage = true
+					continue
+				}
+				inMessage = false
+				p.stdout.WriteString(e.Output)
+			}
+		}
+	}
+	return p
+}
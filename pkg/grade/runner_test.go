@@ -0,0 +1,86 @@
+package grade
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stream 把(Action, Test, Output)三元组编码成go test -json的输出
+func stream(t *testing.T, events ...[3]string) []byte {
+	t.Helper()
+	var b strings.Builder
+	for _, e := range events {
+		data, err := json.Marshal(event{Action: e[0], Test: e[1], Output: e[2]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+func TestParse(t *testing.T) {
+	const top = "TestChannelBasic"
+	data := stream(t,
+		[3]string{"run", top, ""},
+		[3]string{"run", top + "/发送", ""},
+		[3]string{"output", top + "/发送", "=== RUN   " + top + "/发送\n"},
+		[3]string{"output", top + "/发送", "练习打印的内容\n"},
+		[3]string{"output", top + "/发送", "--- PASS: " + top + "/发送 (0.00s)\n"},
+		[3]string{"pass", top + "/发送", ""},
+
+		[3]string{"run", top + "/关闭", ""},
+		[3]string{"output", top + "/关闭", "没有换行    03_channel_basic_test.go:30: channel没有关闭\n"},
+		[3]string{"output", top + "/关闭", "    03_channel_basic_test.go:30: " + leakPrefix + "\n"},
+		[3]string{"output", top + "/关闭", "        发现 1 个泄漏的goroutine:\n"},
+		[3]string{"output", top + "/关闭", "--- FAIL: " + top + "/关闭 (1.00s)\n"},
+		[3]string{"fail", top + "/关闭", ""},
+
+		[3]string{"run", top + "/竞争", ""},
+		[3]string{"output", top + "/竞争", "==================\n"},
+		[3]string{"output", top + "/竞争", raceWarning + "\n"},
+		[3]string{"output", top + "/竞争", "Read at 0x00c000016438 by goroutine 9:\n"},
+		[3]string{"output", top + "/竞争", "==================\n"},
+		[3]string{"output", top + "/竞争", "    testing.go:1465: race detected during execution of test\n"},
+		[3]string{"output", top + "/竞争", "--- FAIL: " + top + "/竞争 (0.00s)\n"},
+		[3]string{"fail", top + "/竞争", ""},
+		[3]string{"fail", top, ""},
+	)
+
+	p := parse(data)
+	want := []Result{
+		{Name: "发送", Passed: true},
+		{Name: "关闭", Messages: []string{"channel没有关闭", leakPrefix, "发现 1 个泄漏的goroutine:"}, Leak: true},
+		{Name: "竞争", Messages: []string{"竞态检测器发现数据竞争（用 go test -race -v 运行练习和测试文件查看完整报告）"}, Race: true},
+	}
+	for i := range p.results {
+		p.results[i].Duration = 0
+		if len(p.results[i].Messages) == 0 {
+			p.results[i].Messages = nil
+		}
+	}
+	if !reflect.DeepEqual(p.results, want) {
+		t.Fatalf("results =\n%+v\nwant\n%+v", p.results, want)
+	}
+	if got := p.stdout.String(); got != "练习打印的内容\n没有换行" {
+		t.Errorf("stdout = %q", got)
+	}
+	if !p.finished() || !p.race {
+		t.Errorf("finished = %v, race = %v, want 都为true", p.finished(), p.race)
+	}
+}
+
+// TestParseUnfinished 进程在任务中途退出时，没有结果的任务记为失败
+func TestParseUnfinished(t *testing.T) {
+	p := parse(stream(t,
+		[3]string{"run", "TestPool/借出", ""},
+		[3]string{"output", "TestPool/借出", "exit status 1\n"},
+		[3]string{"fail", "TestPool", ""},
+	))
+	if len(p.results) != 1 || p.results[0].Passed || p.finished() {
+		t.Fatalf("results = %+v, finished = %v", p.results, p.finished())
+	}
+}
@@ -0,0 +1,113 @@
+// Package hashring 提供带虚拟节点的一致性哈希环 Ring[N]。
+//
+// 每个节点按"ID#序号"在环上放置replicas个虚拟节点，key顺时针找到的第一个虚拟节点
+// 就是它所属的节点。增删一个节点只影响环上相邻的一段key，其余key的归属不变；
+// 虚拟节点越多，key在节点之间分布得越均匀。
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"sync"
+)
+
+// ErrEmpty 环上没有节点
+var ErrEmpty = errors.New("no workers available")
+
+// Ring 一致性哈希环，节点类型为N，并发安全
+type Ring[N any] struct {
+	replicas   int               // 每个节点的虚拟节点数量
+	ring       map[uint32]string // 哈希环：哈希值 -> 节点ID
+	sortedKeys []uint32          // 排序的哈希值列表
+	nodes      map[string]N      // 节点ID -> 节点
+	mu         sync.RWMutex
+}
+
+// New 创建一致性哈希环，每个节点放置replicas个虚拟节点
+func New[N any](replicas int) *Ring[N] {
+	return &Ring[N]{
+		replicas: replicas,
+		ring:     make(map[uint32]string),
+		nodes:    make(map[string]N),
+	}
+}
+
+// hash 哈希函数：将字符串映射为uint32
+func hash(data string) uint32 {
+	return crc32.ChecksumIEEE([]byte(data))
+}
+
+func virtualNode(id string, i int) string {
+	return fmt.Sprintf("%s#%d", id, i)
+}
+
+// Replicas 每个节点的虚拟节点数量
+func (r *Ring[N]) Replicas() int { return r.replicas }
+
+// Add 以id把节点加入环，id已存在时替换节点
+func (r *Ring[N]) Add(id string, node N) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.nodes[id]; !ok {
+		for i := 0; i < r.replicas; i++ {
+			h := hash(virtualNode(id, i))
+			r.ring[h] = id
+			r.sortedKeys = append(r.sortedKeys, h)
+		}
+		// 对哈希值进行排序，维护环的有序性
+		sort.Slice(r.sortedKeys, func(i, j int) bool { return r.sortedKeys[i] < r.sortedKeys[j] })
+	}
+	r.nodes[id] = node
+}
+
+// Remove 从环上移除节点和它的所有虚拟节点，id不存在时什么也不做
+func (r *Ring[N]) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.nodes[id]; !ok {
+		return
+	}
+	for i := 0; i < r.replicas; i++ {
+		h := hash(virtualNode(id, i))
+		delete(r.ring, h)
+		if j := sort.Search(len(r.sortedKeys), func(j int) bool { return r.sortedKeys[j] >= h }); j < len(r.sortedKeys) && r.sortedKeys[j] == h {
+			r.sortedKeys = append(r.sortedKeys[:j], r.sortedKeys[j+1:]...)
+		}
+	}
+	delete(r.nodes, id)
+}
+
+// Get 返回key所属的节点；环上没有节点时返回ErrEmpty
+func (r *Ring[N]) Get(key string) (N, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.sortedKeys) == 0 {
+		var zero N
+		return zero, ErrEmpty
+	}
+
+	// 在环上找到第一个大于等于key哈希值的虚拟节点，超出范围时回到环的开始
+	h := hash(key)
+	idx := sort.Search(len(r.sortedKeys), func(i int) bool { return r.sortedKeys[i] >= h })
+	if idx == len(r.sortedKeys) {
+		idx = 0
+	}
+	return r.nodes[r.ring[r.sortedKeys[idx]]], nil
+}
+
+// Nodes 环上的所有节点，顺序不固定
+func (r *Ring[N]) Nodes() []N {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make([]N, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
@@ -0,0 +1,180 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func keys(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("key-%d", i)
+	}
+	return out
+}
+
+// assign 每个key当前所属的节点
+func assign(t *testing.T, r *Ring[string], keys []string) map[string]string {
+	t.Helper()
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		n, err := r.Get(k)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", k, err)
+		}
+		out[k] = n
+	}
+	return out
+}
+
+func ringOf(ids ...string) *Ring[string] {
+	r := New[string](100)
+	for _, id := range ids {
+		r.Add(id, id)
+	}
+	return r
+}
+
+func TestGetEmpty(t *testing.T) {
+	r := New[string](10)
+	if _, err := r.Get("key"); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("空环上Get() = %v, want ErrEmpty", err)
+	}
+	r.Add("a", "a")
+	r.Remove("a")
+	if _, err := r.Get("key"); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("移除最后一个节点后Get() = %v, want ErrEmpty", err)
+	}
+}
+
+// TestRemapping 增删节点时只有一部分key改变归属，且只在变化的节点和其他节点之间移动
+func TestRemapping(t *testing.T) {
+	const n = 10000
+	ks := keys(n)
+
+	tests := []struct {
+		name   string
+		before []string
+		change func(r *Ring[string])
+		moved  func(from, to string) bool // 允许的移动
+		// 期望移动的key占比约为1/节点数，允许在[lo, hi]之间
+		lo, hi float64
+	}{
+		{
+			name:   "加入节点",
+			before: []string{"a", "b", "c", "d"},
+			change: func(r *Ring[string]) { r.Add("e", "e") },
+			moved:  func(from, to string) bool { return to == "e" },
+			lo:     0.1, hi: 0.3,
+		},
+		{
+			name:   "移除节点",
+			before: []string{"a", "b", "c", "d", "e"},
+			change: func(r *Ring[string]) { r.Remove("c") },
+			moved:  func(from, to string) bool { return from == "c" },
+			lo:     0.1, hi: 0.3,
+		},
+		{
+			name:   "替换已有节点不移动key",
+			before: []string{"a", "b", "c"},
+			change: func(r *Ring[string]) { r.Add("b", "b") },
+			moved:  func(from, to string) bool { return false },
+		},
+		{
+			name:   "移除不存在的节点不移动key",
+			before: []string{"a", "b", "c"},
+			change: func(r *Ring[string]) { r.Remove("z") },
+			moved:  func(from, to string) bool { return false },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ringOf(tt.before...)
+			before := assign(t, r, ks)
+			tt.change(r)
+			after := assign(t, r, ks)
+
+			moved := 0
+			for _, k := range ks {
+				from, to := before[k], after[k]
+				if from == to {
+					continue
+				}
+				moved++
+				if !tt.moved(from, to) {
+					t.Fatalf("%s 从 %s 移到了 %s", k, from, to)
+				}
+			}
+			if ratio := float64(moved) / n; ratio < tt.lo || ratio > tt.hi {
+				t.Fatalf("移动了 %.1f%% 的key, want %.0f%%~%.0f%%", ratio*100, tt.lo*100, tt.hi*100)
+			}
+		})
+	}
+}
+
+// TestRemoveThenAddRestores 移除再加回同一个节点，所有key回到原来的节点
+func TestRemoveThenAddRestores(t *testing.T) {
+	ks := keys(1000)
+	r := ringOf("a", "b", "c")
+	before := assign(t, r, ks)
+	r.Remove("b")
+	r.Add("b", "b")
+	after := assign(t, r, ks)
+	for _, k := range ks {
+		if before[k] != after[k] {
+			t.Fatalf("%s: %s -> %s", k, before[k], after[k])
+		}
+	}
+}
+
+// TestDistribution 有虚拟节点时每个节点都分到相当一部分key，不会有节点几乎闲置
+func TestDistribution(t *testing.T) {
+	const n = 20000
+	r := ringOf("a", "b", "c", "d")
+	count := make(map[string]int)
+	for _, node := range assign(t, r, keys(n)) {
+		count[node]++
+	}
+	for node, c := range count {
+		if share := float64(c) / n; share < 0.1 || share > 0.4 {
+			t.Errorf("节点 %s 分到 %.1f%% 的key，期望接近25%%", node, share*100)
+		}
+	}
+}
+
+// TestConcurrent 并发增删节点和查询
+func TestConcurrent(t *testing.T) {
+	r := ringOf("a", "b")
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func(g int) {
+			defer wg.Done()
+			id := fmt.Sprintf("n%d", g)
+			for i := 0; i < 100; i++ {
+				r.Add(id, id)
+				r.Remove(id)
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			for _, k := range keys(500) {
+				if _, err := r.Get(k); err != nil {
+					t.Errorf("Get(%q): %v", k, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(r.Nodes()); got != 2 {
+		t.Fatalf("len(Nodes()) = %d, want 2", got)
+	}
+	// 并发增删之后环上不应残留被移除节点的虚拟节点
+	if got := len(r.sortedKeys); got != 2*r.Replicas() {
+		t.Fatalf("环上有 %d 个虚拟节点, want %d", got, 2*r.Replicas())
+	}
+}
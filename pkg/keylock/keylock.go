@@ -0,0 +1,139 @@
+// Package keylock 按键加锁：同一个键上的操作串行执行，不同键之间互不阻塞，不需要一把全局锁。
+//
+//   - Striped：把键哈希到固定数量的互斥锁上，内存固定，但不同的键可能落到同一把锁（假竞争）
+//   - RefCounted：每个正在使用的键一把锁，引用计数归零时删除，没有假竞争，
+//     代价是每次加解锁都要访问一次全局map
+//
+// 两者都提供LockMany：同时锁住多个键时按固定顺序加锁，不会因为加锁顺序不同而死锁。
+package keylock
+
+import (
+	"hash/maphash"
+	"sort"
+	"sync"
+)
+
+// KeyLocker 按键加锁
+type KeyLocker interface {
+	Lock(key string)
+	Unlock(key string)
+	// LockMany 锁住全部keys（重复的键只锁一次），返回解锁函数
+	LockMany(keys ...string) (unlock func())
+}
+
+// Striped 分段锁：固定数量的互斥锁
+type Striped struct {
+	seed  maphash.Seed
+	locks []paddedMutex
+}
+
+// paddedMutex 填充到一个缓存行，相邻的锁被不同CPU频繁访问时不会互相影响
+type paddedMutex struct {
+	sync.Mutex
+	_ [56]byte
+}
+
+// NewStriped 创建n把锁的分段锁，n<=0时为1
+func NewStriped(n int) *Striped {
+	return &Striped{seed: maphash.MakeSeed(), locks: make([]paddedMutex, max(n, 1))}
+}
+
+// Stripe 键对应的锁下标，可以用来判断两个键是否共用一把锁
+func (s *Striped) Stripe(key string) int {
+	return int(maphash.String(s.seed, key) % uint64(len(s.locks)))
+}
+
+func (s *Striped) Lock(key string)   { s.locks[s.Stripe(key)].Lock() }
+func (s *Striped) Unlock(key string) { s.locks[s.Stripe(key)].Unlock() }
+
+// LockMany 按锁下标从小到大加锁；多个键落在同一把锁上时只锁一次
+func (s *Striped) LockMany(keys ...string) func() {
+	idx := make([]int, 0, len(keys))
+	seen := make(map[int]bool, len(keys))
+	for _, k := range keys {
+		if i := s.Stripe(k); !seen[i] {
+			seen[i] = true
+			idx = append(idx, i)
+		}
+	}
+	sort.Ints(idx)
+	for _, i := range idx {
+		s.locks[i].Lock()
+	}
+	return func() {
+		for j := len(idx) - 1; j >= 0; j-- {
+			s.locks[idx[j]].Unlock()
+		}
+	}
+}
+
+// RefCounted 每个键一把锁，没有goroutine持有或等待时删除
+type RefCounted struct {
+	mu    sync.Mutex
+	locks map[string]*refLock
+}
+
+type refLock struct {
+	sync.Mutex
+	refs int // 持有和等待这把锁的goroutine数，由RefCounted.mu保护
+}
+
+// NewRefCounted 创建按键引用计数的锁
+func NewRefCounted() *RefCounted {
+	return &RefCounted{locks: make(map[string]*refLock)}
+}
+
+func (r *RefCounted) Lock(key string) {
+	r.mu.Lock()
+	l, ok := r.locks[key]
+	if !ok {
+		l = &refLock{}
+		r.locks[key] = l
+	}
+	l.refs++
+	r.mu.Unlock()
+	// 在全局锁之外等待键锁，等待期间其他键的加解锁不受影响
+	l.Lock()
+}
+
+func (r *RefCounted) Unlock(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.locks[key]
+	if !ok {
+		panic("keylock: unlock of unlocked key " + key)
+	}
+	l.refs--
+	if l.refs == 0 {
+		delete(r.locks, key)
+	}
+	l.Unlock()
+}
+
+// LockMany 按键的字典序加锁
+func (r *RefCounted) LockMany(keys ...string) func() {
+	sorted := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		r.Lock(k)
+	}
+	return func() {
+		for j := len(sorted) - 1; j >= 0; j-- {
+			r.Unlock(sorted[j])
+		}
+	}
+}
+
+// Len 当前被持有或等待的键数
+func (r *RefCounted) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.locks)
+}
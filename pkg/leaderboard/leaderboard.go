@@ -0,0 +1,152 @@
+// Package leaderboard 限时挑战的本地排行榜：每次在限时内通过评分，记录练习、挑战者、用时和评分次数。
+//
+// 排名先比用时，用时相同再比评分次数，都相同时先完成的排前面。
+// 文件和进度文件一样按"写临时文件再改名"的方式保存。
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileName 排行榜文件的默认文件名，放在exercises目录下
+const FileName = ".leaderboard.json"
+
+// version 文件格式版本，格式不兼容地变化时加一
+const version = 1
+
+// Entry 一次成功的挑战
+type Entry struct {
+	Exercise string        `json:"exercise"` // 级别/文件名，例如simple/03_channel_basic
+	Player   string        `json:"player"`
+	Duration time.Duration `json:"duration"` // 从开始挑战到保存出通过评分的版本
+	Attempts int           `json:"attempts"` // 挑战期间的评分次数，包括最后通过的一次
+	Limit    time.Duration `json:"limit"`    // 挑战的时限
+	At       time.Time     `json:"at"`       // 完成时间
+}
+
+// less 排名顺序
+func less(a, b Entry) bool {
+	if a.Duration != b.Duration {
+		return a.Duration < b.Duration
+	}
+	if a.Attempts != b.Attempts {
+		return a.Attempts < b.Attempts
+	}
+	return a.At.Before(b.At)
+}
+
+type fileFormat struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Board 排行榜文件，方法可以并发调用
+type Board struct {
+	path string
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Open 读取排行榜文件，文件不存在时返回空的Board，第一次Save时创建
+func Open(path string) (*Board, error) {
+	b := &Board{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("排行榜文件 %s 格式错误: %w", path, err)
+	}
+	if f.Version > version {
+		return nil, fmt.Errorf("排行榜文件 %s 的版本 %d 比当前程序支持的版本 %d 新", path, f.Version, version)
+	}
+	b.entries = f.Entries
+	return b, nil
+}
+
+// Path 排行榜文件路径
+func (b *Board) Path() string { return b.path }
+
+// Add 记录一次成功的挑战，返回它在这个练习中的名次（从1开始）
+func (b *Board) Add(e Entry) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = append(b.entries, e)
+	rank := 1
+	for _, other := range b.entries {
+		if other.Exercise == e.Exercise && less(other, e) {
+			rank++
+		}
+	}
+	return rank
+}
+
+// Top 一个练习排名前n的记录，n<=0时返回全部
+func (b *Board) Top(exercise string, n int) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var list []Entry
+	for _, e := range b.entries {
+		if e.Exercise == exercise {
+			list = append(list, e)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return less(list[i], list[j]) })
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+// Best 挑战者在一个练习上的最好成绩
+func (b *Board) Best(exercise, player string) (Entry, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var best Entry
+	found := false
+	for _, e := range b.entries {
+		if e.Exercise == exercise && e.Player == player && (!found || less(e, best)) {
+			best, found = e, true
+		}
+	}
+	return best, found
+}
+
+// Save 写回排行榜文件
+func (b *Board) Save() error {
+	b.mu.Lock()
+	data, err := json.MarshalIndent(fileFormat{Version: version, Entries: b.entries}, "", "  ")
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // 改名成功后这里删除的是不存在的文件
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), b.path)
+}
@@ -0,0 +1,141 @@
+// Package leakcheck 通过对比前后两次goroutine快照来发现泄漏的goroutine。
+//
+// 用法：在demo或测试开始时调用Take，结束时调用Check。Check会在超时前
+// 反复采样，给正在退出的goroutine留出时间，最后返回仍然多出来的goroutine。
+package leakcheck
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Goroutine 一个goroutine的栈信息
+type Goroutine struct {
+	ID    int
+	State string // 例如 "chan send"、"select"、"sleep"
+	Stack string // 完整的栈文本
+}
+
+// Func 返回栈顶函数名
+func (g Goroutine) Func() string {
+	lines := strings.Split(g.Stack, "\n")
+	if len(lines) < 2 {
+		return ""
+	}
+	fn := lines[1]
+	if i := strings.LastIndex(fn, "("); i > 0 {
+		fn = fn[:i]
+	}
+	return fn
+}
+
+// CreatedBy 返回创建该goroutine的位置
+func (g Goroutine) CreatedBy() string {
+	lines := strings.Split(g.Stack, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "created by ") && i+1 < len(lines) {
+			return strings.TrimSpace(lines[i+1])
+		}
+	}
+	return ""
+}
+
+func (g Goroutine) String() string {
+	return fmt.Sprintf("goroutine %d [%s] %s (创建于 %s)", g.ID, g.State, g.Func(), g.CreatedBy())
+}
+
+// Snapshot 某一时刻所有goroutine的集合
+type Snapshot map[int]Goroutine
+
+// Take 采集当前所有goroutine
+func Take() Snapshot {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	return parse(string(buf))
+}
+
+// parse 解析runtime.Stack的输出，每个goroutine以"goroutine N [state]:"开头，之间用空行分隔
+func parse(dump string) Snapshot {
+	snap := make(Snapshot)
+	for _, block := range strings.Split(strings.TrimSpace(dump), "\n\n") {
+		header, _, _ := strings.Cut(block, "\n")
+		// header形如: goroutine 7 [chan send, 1 minutes]:
+		fields := strings.SplitN(strings.TrimPrefix(header, "goroutine "), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		state := strings.TrimSuffix(strings.TrimPrefix(fields[1], "["), "]:")
+		state, _, _ = strings.Cut(state, ",")
+		snap[id] = Goroutine{ID: id, State: state, Stack: block}
+	}
+	return snap
+}
+
+// Check 返回base之后新出现且在timeout内没有退出的goroutine
+func Check(base Snapshot, timeout time.Duration) []Goroutine {
+	deadline := time.Now().Add(timeout)
+	for {
+		leaked := diff(base, Take())
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func diff(base, current Snapshot) []Goroutine {
+	var leaked []Goroutine
+	for id, g := range current {
+		if _, ok := base[id]; !ok {
+			leaked = append(leaked, g)
+		}
+	}
+	sort.Slice(leaked, func(i, j int) bool { return leaked[i].ID < leaked[j].ID })
+	return leaked
+}
+
+// Report 把泄漏列表格式化为可读文本，verbose为true时附带完整的栈
+func Report(leaked []Goroutine, verbose bool) string {
+	if len(leaked) == 0 {
+		return "没有泄漏的goroutine"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "发现 %d 个泄漏的goroutine:\n", len(leaked))
+	for _, g := range leaked {
+		fmt.Fprintf(&b, "  %s\n", g)
+		if verbose {
+			fmt.Fprintf(&b, "%s\n", g.Stack)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// TB 是testing.TB中Verify需要的部分，避免本包依赖testing
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Verify 在测试结束时调用：有泄漏则报告测试失败
+func Verify(t TB, base Snapshot) {
+	t.Helper()
+	if leaked := Check(base, time.Second); len(leaked) > 0 {
+		t.Errorf("%s", Report(leaked, true))
+	}
+}
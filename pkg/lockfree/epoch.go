@@ -0,0 +1,144 @@
+package lockfree
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Reclaimer 基于纪元（epoch）的内存回收。
+//
+// 无锁结构中，一个节点被摘下（unlink）之后，其他goroutine可能刚刚读到它的指针还没来得及用。
+// 有GC时节点不会被提前释放，但如果为了减少分配把节点放回空闲链表复用，复用的节点就可能被
+// 这些迟到的读者看到：读到别人的值，或者CAS因为地址相同而错误地成功（ABA问题）。
+//
+// 做法：全局纪元单调递增；每个参与者在访问结构前Pin，公布"我在纪元e中活动"，结束后Unpin。
+// 被摘下的节点Retire到参与者自己的待回收列表，记下当时的纪元。只有当所有活动的参与者都已
+// 处于当前纪元时，全局纪元才能加一，因此纪元推进两次之后，Retire时还在活动的读者一定都已离开，
+// 节点才交给free复用。
+//
+// Pin、Unpin、Retire都是无锁的；登记参与者和推进纪元时遍历参与者列表需要加锁，
+// 只在每积累batch个待回收节点时发生一次。一个长时间Pin着不动的参与者会阻止纪元推进，
+// 所有待回收节点都会一直积压，这是纪元回收相比hazard pointer的主要缺点。
+type Reclaimer[T any] struct {
+	epoch     atomic.Uint64
+	free      func(T)
+	batch     int
+	retired   atomic.Int64
+	reclaimed atomic.Int64
+
+	mu      sync.Mutex
+	guards  []*Guard[T]
+	orphans []retiredItem[T] // 已注销的参与者留下的待回收节点
+}
+
+type retiredItem[T any] struct {
+	epoch uint64
+	val   T
+}
+
+// NewReclaimer free在节点可以安全复用时被调用；每个参与者积累batch个待回收节点时尝试回收一次
+func NewReclaimer[T any](free func(T), batch int) *Reclaimer[T] {
+	return &Reclaimer[T]{free: free, batch: max(batch, 1)}
+}
+
+// Epoch 当前全局纪元
+func (r *Reclaimer[T]) Epoch() uint64 { return r.epoch.Load() }
+
+// Retired 累计Retire的节点数
+func (r *Reclaimer[T]) Retired() int64 { return r.retired.Load() }
+
+// Reclaimed 累计交给free的节点数
+func (r *Reclaimer[T]) Reclaimed() int64 { return r.reclaimed.Load() }
+
+// Pending 已Retire但还不能复用的节点数
+func (r *Reclaimer[T]) Pending() int64 { return r.retired.Load() - r.reclaimed.Load() }
+
+// Register 登记一个参与者，每个goroutine使用自己的Guard，不能共享
+func (r *Reclaimer[T]) Register() *Guard[T] {
+	g := &Guard[T]{r: r}
+	r.mu.Lock()
+	r.guards = append(r.guards, g)
+	r.mu.Unlock()
+	return g
+}
+
+// tryAdvance 所有活动的参与者都处于当前纪元时把纪元加一，顺便回收注销者留下的节点
+func (r *Reclaimer[T]) tryAdvance() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.epoch.Load()
+	for _, g := range r.guards {
+		if s := g.state.Load(); s&1 == 1 && s>>1 != e {
+			return
+		}
+	}
+	r.epoch.CompareAndSwap(e, e+1)
+	r.orphans = r.reclaim(r.orphans)
+}
+
+// reclaim 释放list中纪元已经推进两次的节点，返回剩下的
+func (r *Reclaimer[T]) reclaim(list []retiredItem[T]) []retiredItem[T] {
+	e := r.epoch.Load()
+	kept := list[:0]
+	for _, it := range list {
+		if it.epoch+2 <= e {
+			r.free(it.val)
+			r.reclaimed.Add(1)
+		} else {
+			kept = append(kept, it)
+		}
+	}
+	var zero retiredItem[T]
+	for i := len(kept); i < len(list); i++ {
+		list[i] = zero // 不再引用已交出的节点
+	}
+	return kept
+}
+
+// Guard 一个参与者
+type Guard[T any] struct {
+	r     *Reclaimer[T]
+	state atomic.Uint64 // 纪元<<1 | 是否活动
+	limbo []retiredItem[T]
+}
+
+// Pin 开始访问共享结构；Pin期间读到的节点不会被复用。不支持嵌套
+func (g *Guard[T]) Pin() {
+	g.state.Store(g.r.epoch.Load()<<1 | 1)
+}
+
+// Unpin 结束访问，之后不能再使用Pin期间读到的节点
+func (g *Guard[T]) Unpin() {
+	g.state.Store(g.state.Load() &^ 1)
+}
+
+// Retire 节点已从结构中摘下，等到没有读者能访问它时再交给free
+func (g *Guard[T]) Retire(v T) {
+	g.limbo = append(g.limbo, retiredItem[T]{epoch: g.r.epoch.Load(), val: v})
+	g.r.retired.Add(1)
+	if len(g.limbo) >= g.r.batch {
+		g.Collect()
+	}
+}
+
+// Collect 尝试推进纪元并回收自己的待回收节点
+func (g *Guard[T]) Collect() {
+	g.r.tryAdvance()
+	g.limbo = g.r.reclaim(g.limbo)
+}
+
+// Unregister 注销参与者，未回收的节点转交给Reclaimer，由之后的纪元推进回收
+func (g *Guard[T]) Unregister() {
+	g.Unpin()
+	r := g.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, x := range r.guards {
+		if x == g {
+			r.guards = append(r.guards[:i], r.guards[i+1:]...)
+			break
+		}
+	}
+	r.orphans = append(r.orphans, g.limbo...)
+	g.limbo = nil
+}
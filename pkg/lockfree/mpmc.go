@@ -0,0 +1,114 @@
+// Package lockfree 提供基于CAS的无锁数据结构。
+//
+// MPMC 是有界的多生产者多消费者队列（Dmitry Vyukov的算法）：
+// 每个槽位带一个序号，生产者和消费者各自用CAS推进自己的位置，
+// 再通过槽位序号确认槽位可写/可读，整个过程不需要互斥锁。
+//
+// Reclaimer 是基于纪元的内存回收：链表类的无锁结构复用被摘下的节点前，
+// 用它确认已经没有goroutine还持有这个节点的指针。
+package lockfree
+
+import (
+	"sync/atomic"
+)
+
+// cacheLinePad 把频繁修改的字段隔开，避免生产者和消费者修改同一缓存行造成伪共享
+type cacheLinePad [64]byte
+
+type cell[T any] struct {
+	// seq == pos      ：槽位空闲，位置为pos的生产者可以写入
+	// seq == pos+1    ：槽位已写入，位置为pos的消费者可以读取
+	// seq == pos+size ：已被读取，等待下一圈位置为pos+size的生产者
+	seq   atomic.Uint64
+	value T
+}
+
+// MPMC 有界无锁队列，容量向上取整为2的幂
+type MPMC[T any] struct {
+	_     cacheLinePad
+	mask  uint64
+	cells []cell[T]
+	_     cacheLinePad
+	head  atomic.Uint64 // 下一个入队位置
+	_     cacheLinePad
+	tail  atomic.Uint64 // 下一个出队位置
+	_     cacheLinePad
+}
+
+// NewMPMC 创建容量至少为capacity的队列
+func NewMPMC[T any](capacity int) *MPMC[T] {
+	size := uint64(2)
+	for size < uint64(capacity) {
+		size <<= 1
+	}
+	q := &MPMC[T]{mask: size - 1, cells: make([]cell[T], size)}
+	for i := range q.cells {
+		q.cells[i].seq.Store(uint64(i))
+	}
+	return q
+}
+
+// Cap 队列容量
+func (q *MPMC[T]) Cap() int { return len(q.cells) }
+
+// TryEnqueue 入队，队列已满时立即返回false
+func (q *MPMC[T]) TryEnqueue(v T) bool {
+	pos := q.head.Load()
+	for {
+		c := &q.cells[pos&q.mask]
+		seq := c.seq.Load()
+		switch diff := int64(seq) - int64(pos); {
+		case diff == 0:
+			// 槽位空闲，抢占这个位置；CAS失败说明被其他生产者抢先，用新的head重试
+			if q.head.CompareAndSwap(pos, pos+1) {
+				c.value = v
+				// 发布：Store之前对value的写入对看到新序号的消费者可见
+				c.seq.Store(pos + 1)
+				return true
+			}
+			pos = q.head.Load()
+		case diff < 0:
+			// 槽位还保存着上一圈未被读取的数据：队列已满
+			return false
+		default:
+			// 其他生产者已经推进了head，重新读取
+			pos = q.head.Load()
+		}
+	}
+}
+
+// TryDequeue 出队，队列为空时立即返回false
+func (q *MPMC[T]) TryDequeue() (T, bool) {
+	pos := q.tail.Load()
+	for {
+		c := &q.cells[pos&q.mask]
+		seq := c.seq.Load()
+		switch diff := int64(seq) - int64(pos+1); {
+		case diff == 0:
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				v := c.value
+				var zero T
+				c.value = zero // 不再引用已出队的值，便于GC回收
+				// 槽位交给下一圈的生产者
+				c.seq.Store(pos + q.mask + 1)
+				return v, true
+			}
+			pos = q.tail.Load()
+		case diff < 0:
+			// 生产者还没有写入这个位置：队列为空
+			var zero T
+			return zero, false
+		default:
+			pos = q.tail.Load()
+		}
+	}
+}
+
+// Len 队列中元素数量的近似值，并发修改时只能作为参考
+func (q *MPMC[T]) Len() int {
+	n := int64(q.head.Load()) - int64(q.tail.Load())
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
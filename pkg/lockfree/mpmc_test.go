@@ -0,0 +1,186 @@
+package lockfree
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// queue 基准测试中三种实现共同的非阻塞接口
+type queue interface {
+	TryEnqueue(v int) bool
+	TryDequeue() (int, bool)
+}
+
+// mutexQueue 互斥锁保护的环形缓冲区，作为对照
+type mutexQueue struct {
+	mu         sync.Mutex
+	buf        []int
+	head, size int
+}
+
+func (q *mutexQueue) TryEnqueue(v int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size == len(q.buf) {
+		return false
+	}
+	q.buf[(q.head+q.size)%len(q.buf)] = v
+	q.size++
+	return true
+}
+
+func (q *mutexQueue) TryDequeue() (int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size == 0 {
+		return 0, false
+	}
+	v := q.buf[q.head]
+	q.head = (q.head + 1) % len(q.buf)
+	q.size--
+	return v, true
+}
+
+// chanQueue 带缓冲的channel，作为对照
+type chanQueue chan int
+
+func (q chanQueue) TryEnqueue(v int) bool {
+	select {
+	case q <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q chanQueue) TryDequeue() (int, bool) {
+	select {
+	case v := <-q:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+var impls = []struct {
+	name string
+	new  func(capacity int) queue
+}{
+	{"MPMC", func(n int) queue { return NewMPMC[int](n) }},
+	{"Mutex", func(n int) queue { return &mutexQueue{buf: make([]int, n)} }},
+	{"Chan", func(n int) queue { return make(chanQueue, n) }},
+}
+
+// transfer producers个生产者共写入total个值，consumers个消费者读出；
+// 返回每个值被读出的次数，队列满或空时让出CPU
+func transfer(q queue, producers, consumers, total int) []int32 {
+	var produced, consumed atomic.Int64
+	seen := make([]int32, total)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v := int(produced.Add(1)) - 1
+				if v >= total {
+					return
+				}
+				for !q.TryEnqueue(v) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for consumed.Load() < int64(total) {
+				v, ok := q.TryDequeue()
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+				consumed.Add(1)
+				atomic.AddInt32(&seen[v], 1)
+			}
+		}()
+	}
+	wg.Wait()
+	return seen
+}
+
+func TestMPMCFullAndEmpty(t *testing.T) {
+	q := NewMPMC[int](3)
+	if q.Cap() != 4 {
+		t.Fatalf("Cap() = %d, want 4", q.Cap())
+	}
+	if _, ok := q.TryDequeue(); ok {
+		t.Fatal("空队列TryDequeue() = true")
+	}
+	// 转两圈，确认槽位序号在每一圈都正确推进
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 4; i++ {
+			if !q.TryEnqueue(round*10 + i) {
+				t.Fatalf("第%d圈第%d次TryEnqueue() = false", round+1, i+1)
+			}
+		}
+		if q.TryEnqueue(99) {
+			t.Fatal("已满的队列TryEnqueue() = true")
+		}
+		if q.Len() != 4 {
+			t.Fatalf("Len() = %d, want 4", q.Len())
+		}
+		for i := 0; i < 4; i++ {
+			if v, ok := q.TryDequeue(); !ok || v != round*10+i {
+				t.Fatalf("TryDequeue() = %d, %v, want %d, true", v, ok, round*10+i)
+			}
+		}
+		if _, ok := q.TryDequeue(); ok {
+			t.Fatal("读空后TryDequeue() = true")
+		}
+	}
+}
+
+// TestMPMCExactlyOnce 容量很小、频繁满和空时，每个值仍然恰好被读出一次
+func TestMPMCExactlyOnce(t *testing.T) {
+	tests := []struct {
+		producers, consumers, capacity int
+	}{
+		{1, 1, 2},
+		{4, 4, 8},
+		{8, 1, 8},
+		{1, 8, 8},
+	}
+	const total = 20000
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d生产者%d消费者", tt.producers, tt.consumers), func(t *testing.T) {
+			seen := transfer(NewMPMC[int](tt.capacity), tt.producers, tt.consumers, total)
+			for v, n := range seen {
+				if n != 1 {
+					t.Fatalf("值 %d 被读出 %d 次", v, n)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTransfer 每次操作 = 一个值从生产者到消费者
+//
+//	go test -bench Transfer ./pkg/lockfree
+func BenchmarkTransfer(b *testing.B) {
+	for _, shape := range [][2]int{{1, 1}, {4, 4}, {8, 1}, {1, 8}} {
+		for _, impl := range impls {
+			b.Run(fmt.Sprintf("%dP%dC/%s", shape[0], shape[1], impl.name), func(b *testing.B) {
+				q := impl.new(1024)
+				b.ResetTimer()
+				transfer(q, shape[0], shape[1], b.N)
+			})
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// Package mapreduce 提供进程内的泛型MapReduce框架。
+//
+// 执行分为三个阶段：
+//  1. Map：输入按SplitSize切分成map任务，由Mappers个并发槽执行，输出按Partition分到各reduce分区
+//  2. Shuffle：把所有map任务中同一分区的输出合并，并按key分组
+//  3. Reduce：每个分区是一个reduce任务，由Reducers个并发槽执行
+//
+// 每个阶段都支持失败重试和落后任务(straggler)的备份执行：任务运行超过StragglerTimeout且有空闲
+// 槽位时，再启动一个备份尝试，先完成的结果生效，另一个尝试被取消并丢弃输出。
+package mapreduce
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// Job 描述一次MapReduce计算
+type Job[I any, K comparable, V any, R any] struct {
+	// Map 处理一条输入，通过emit输出任意数量的键值对
+	Map func(ctx context.Context, input I, emit func(K, V)) error
+	// Reduce 汇总同一个key的所有值
+	Reduce func(ctx context.Context, key K, values []V) (R, error)
+	// Partition 把key分到[0, Reducers)中的某个分区，为nil时对fmt.Sprint(key)做FNV哈希
+	Partition func(key K, reducers int) int
+
+	Mappers   int // map阶段的并发数，默认4
+	Reducers  int // reduce分区数及并发数，默认4
+	SplitSize int // 每个map任务处理的输入条数，默认100
+
+	MaxAttempts      int           // 每个任务失败后的最大尝试次数，默认3
+	StragglerTimeout time.Duration // 超过这个时间的任务会被备份执行，0表示不启用
+}
+
+// PhaseStats 一个阶段的执行统计
+type PhaseStats struct {
+	Tasks      int
+	Attempts   int
+	Retries    int // 因失败重新执行的次数
+	Backups    int // 因落后启动的备份尝试次数
+	BackupWins int // 备份尝试先于原尝试完成的次数
+	Discarded  int // 任务已完成后才返回、被丢弃的尝试
+	Duration   time.Duration
+}
+
+func (s PhaseStats) String() string {
+	return fmt.Sprintf("任务 %d, 尝试 %d, 重试 %d, 备份 %d (备份先完成 %d), 丢弃 %d, 耗时 %v",
+		s.Tasks, s.Attempts, s.Retries, s.Backups, s.BackupWins, s.Discarded, s.Duration.Round(time.Millisecond))
+}
+
+// Stats 整个计算的统计
+type Stats struct {
+	Map, Reduce PhaseStats
+	Keys        int
+}
+
+// Attempt 传给任务函数的尝试信息，可以用来模拟故障
+type Attempt struct {
+	Task   int
+	Number int // 从1开始
+	Backup bool
+}
+
+type attemptKey struct{}
+
+// AttemptFrom 在Map/Reduce函数中取出当前的尝试信息
+func AttemptFrom(ctx context.Context) (Attempt, bool) {
+	a, ok := ctx.Value(attemptKey{}).(Attempt)
+	return a, ok
+}
+
+type kv[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Run 执行Job，返回每个key的reduce结果
+func Run[I any, K comparable, V any, R any](ctx context.Context, job Job[I, K, V, R], inputs []I) (map[K]R, Stats, error) {
+	if job.Mappers <= 0 {
+		job.Mappers = 4
+	}
+	if job.Reducers <= 0 {
+		job.Reducers = 4
+	}
+	if job.SplitSize <= 0 {
+		job.SplitSize = 100
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = 3
+	}
+	if job.Partition == nil {
+		job.Partition = hashPartition[K]
+	}
+
+	var stats Stats
+	var splits [][]I
+	for start := 0; start < len(inputs); start += job.SplitSize {
+		end := min(start+job.SplitSize, len(inputs))
+		splits = append(splits, inputs[start:end])
+	}
+
+	// Map阶段：每个任务输出Reducers个桶
+	mapOut, mapStats, err := runPhase(ctx, len(splits), job.Mappers, job.MaxAttempts, job.StragglerTimeout,
+		func(ctx context.Context, task int) ([][]kv[K, V], error) {
+			buckets := make([][]kv[K, V], job.Reducers)
+			emit := func(k K, v V) {
+				p := job.Partition(k, job.Reducers)
+				buckets[p] = append(buckets[p], kv[K, V]{k, v})
+			}
+			for _, in := range splits[task] {
+				if err := job.Map(ctx, in, emit); err != nil {
+					return nil, err
+				}
+			}
+			return buckets, nil
+		})
+	stats.Map = mapStats
+	if err != nil {
+		return nil, stats, fmt.Errorf("map阶段: %w", err)
+	}
+
+	// Shuffle：按分区收集所有map任务的输出并按key分组
+	groups := make([]map[K][]V, job.Reducers)
+	for p := range groups {
+		groups[p] = make(map[K][]V)
+		for _, buckets := range mapOut {
+			for _, pair := range buckets[p] {
+				groups[p][pair.key] = append(groups[p][pair.key], pair.value)
+			}
+		}
+	}
+
+	reduceOut, reduceStats, err := runPhase(ctx, job.Reducers, job.Reducers, job.MaxAttempts, job.StragglerTimeout,
+		func(ctx context.Context, task int) (map[K]R, error) {
+			out := make(map[K]R, len(groups[task]))
+			for k, vs := range groups[task] {
+				r, err := job.Reduce(ctx, k, vs)
+				if err != nil {
+					return nil, err
+				}
+				out[k] = r
+			}
+			return out, nil
+		})
+	stats.Reduce = reduceStats
+	if err != nil {
+		return nil, stats, fmt.Errorf("reduce阶段: %w", err)
+	}
+
+	result := make(map[K]R)
+	for _, part := range reduceOut {
+		for k, r := range part {
+			result[k] = r
+		}
+	}
+	stats.Keys = len(result)
+	return result, stats, nil
+}
+
+func hashPartition[K comparable](key K, reducers int) int {
+	h := fnv.New32a()
+	fmt.Fprint(h, key)
+	return int(h.Sum32() % uint32(reducers))
+}
+
+type attemptResult[T any] struct {
+	attempt Attempt
+	out     T
+	err     error
+}
+
+type running struct {
+	started  time.Time
+	attempts int // 正在运行的尝试数
+	cancels  []context.CancelFunc
+}
+
+// runPhase 用slots个并发槽执行n个任务，负责失败重试和落后任务的备份执行
+func runPhase[T any](ctx context.Context, n, slots, maxAttempts int, stragglerTimeout time.Duration,
+	exec func(ctx context.Context, task int) (T, error)) ([]T, PhaseStats, error) {
+	start := time.Now()
+	stats := PhaseStats{Tasks: n}
+
+	outputs := make([]T, n)
+	done := make([]bool, n)
+	failures := make([]int, n)
+	backedUp := make([]bool, n)
+	active := make(map[int]*running)
+	queue := make([]int, n)
+	for i := range queue {
+		queue[i] = i
+	}
+
+	results := make(chan attemptResult[T])
+	inflight := 0
+	launch := func(task int, backup bool) {
+		stats.Attempts++
+		r := active[task]
+		if r == nil {
+			r = &running{started: time.Now()}
+			active[task] = r
+		}
+		r.attempts++
+		a := Attempt{Task: task, Number: failures[task] + r.attempts, Backup: backup}
+		attemptCtx, cancel := context.WithCancel(context.WithValue(ctx, attemptKey{}, a))
+		r.cancels = append(r.cancels, cancel)
+		inflight++
+		go func() {
+			out, err := exec(attemptCtx, task)
+			results <- attemptResult[T]{attempt: a, out: out, err: err}
+		}()
+	}
+
+	// 退出时取消所有仍在运行的尝试，并在后台接收它们的结果，避免goroutine阻塞
+	defer func() {
+		for _, r := range active {
+			for _, cancel := range r.cancels {
+				cancel()
+			}
+		}
+		go func(remaining int) {
+			for ; remaining > 0; remaining-- {
+				<-results
+			}
+		}(inflight)
+	}()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	completed := 0
+	for completed < n {
+		for inflight < slots && len(queue) > 0 {
+			task := queue[0]
+			queue = queue[1:]
+			launch(task, false)
+		}
+		// 没有排队的任务时，用空闲槽位备份执行运行过久的任务，每个任务只备份一次
+		if stragglerTimeout > 0 && len(queue) == 0 {
+			for task, r := range active {
+				if inflight >= slots {
+					break
+				}
+				if !backedUp[task] && r.attempts == 1 && time.Since(r.started) > stragglerTimeout {
+					backedUp[task] = true
+					stats.Backups++
+					launch(task, true)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			stats.Duration = time.Since(start)
+			return nil, stats, ctx.Err()
+
+		case <-ticker.C:
+
+		case res := <-results:
+			inflight--
+			task := res.attempt.Task
+			if done[task] {
+				stats.Discarded++
+				continue
+			}
+			r := active[task]
+			r.attempts--
+
+			if res.err != nil {
+				if r.attempts > 0 {
+					continue // 还有另一个尝试在运行，等它的结果
+				}
+				delete(active, task)
+				failures[task]++
+				if failures[task] >= maxAttempts {
+					stats.Duration = time.Since(start)
+					return nil, stats, fmt.Errorf("任务 %d 尝试 %d 次后失败: %w", task, failures[task], res.err)
+				}
+				stats.Retries++
+				backedUp[task] = false
+				queue = append(queue, task)
+				continue
+			}
+
+			done[task] = true
+			outputs[task] = res.out
+			completed++
+			if res.attempt.Backup {
+				stats.BackupWins++
+			}
+			for _, cancel := range r.cancels {
+				cancel()
+			}
+			delete(active, task)
+		}
+	}
+
+	stats.Duration = time.Since(start)
+	return outputs, stats, nil
+}
@@ -0,0 +1,280 @@
+// Package msgqueue 提供带重试和死信队列的内存消息队列。
+//
+// 消费者按主题订阅，主题支持 "orders.*"、"notifications.#" 等通配符（pkg/topicmatch）。
+// Publish把消息并发投递给所有匹配的消费者，只等待每个消费者的第一次尝试结束；某个消费者处理失败时
+// 在后台按指数退避加抖动重试（pkg/retry），只重投给失败的那个消费者，不阻塞发布方。
+// 超过重试次数或不可重试的消息进入死信队列。Wait等待后台重试全部结束，Close放弃还在等待的重试。
+package msgqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/contextkeys"
+	"github.com/klsakura/day1/pkg/retry"
+	"github.com/klsakura/day1/pkg/topicmatch"
+)
+
+// Message 队列中的消息结构
+type Message struct {
+	ID        string      // 消息唯一标识
+	Topic     string      // 消息主题
+	Payload   interface{} // 消息内容
+	Timestamp time.Time   // 创建时间戳
+	Retries   int         // 重试次数
+	Priority  int         // 消息优先级（暂未使用）
+	RequestID string      // 发送方的请求ID，随消息跨越异步边界，用于串联日志
+}
+
+// Context 用消息携带的请求ID重建context，供消费者继续向下传递
+func (m Message) Context() context.Context {
+	ctx := context.Background()
+	if m.RequestID != "" {
+		ctx = contextkeys.WithRequestID(ctx, m.RequestID)
+	}
+	return ctx
+}
+
+// Queue 消息队列接口定义
+type Queue interface {
+	Publish(topic string, message Message) error       // 发布消息
+	Subscribe(topic string, consumer Consumer) error   // 订阅主题
+	Unsubscribe(topic string, consumerID string) error // 取消订阅
+	Close() error                                      // 关闭队列
+}
+
+// Consumer 消费者接口定义
+type Consumer interface {
+	GetID() string                 // 获取消费者ID
+	Consume(message Message) error // 消费消息
+}
+
+// InMemory 基于内存的消息队列实现
+type InMemory struct {
+	subscriptions map[string][]Consumer // 订阅关系：主题（或通配符模式） -> 消费者列表
+	deadLetter    []Message             // 死信队列
+	mu            sync.RWMutex          // 保护订阅关系的读写锁
+	retryPolicy   retry.Policy          // 投递失败的重试策略
+	logf          func(format string, args ...any)
+	ctx           context.Context    // Close时取消，正在等待重试的投递立即放弃
+	cancel        context.CancelFunc // 取消ctx
+	deliveries    sync.WaitGroup     // 还没有结束的投递（包括后台重试）
+	stats         struct {           // 消息处理统计
+		published int64 // 发布消息数
+		consumed  int64 // 成功消费数
+		failed    int64 // 失败的投递次数（每次尝试都计数）
+		retried   int64 // 重试次数
+	}
+}
+
+// NewInMemory 创建内存消息队列，每次投递最多重试maxRetries次
+func NewInMemory(maxRetries int) *InMemory {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &InMemory{
+		subscriptions: make(map[string][]Consumer),
+		deadLetter:    make([]Message, 0),
+		retryPolicy: retry.Policy{
+			MaxAttempts:  maxRetries + 1,
+			InitialDelay: 100 * time.Millisecond,
+			MaxDelay:     time.Second,
+			Jitter:       retry.EqualJitter, // 同时失败的消息错开重试时间
+		},
+		logf:   func(string, ...any) {},
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// SetLogf 设置记录订阅、重试、死信等事件的函数，为nil时不记录；需要在使用队列之前调用
+func (mq *InMemory) SetLogf(logf func(format string, args ...any)) {
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	mq.logf = logf
+}
+
+// Publish 实现Queue接口 - 发布消息到指定主题
+func (mq *InMemory) Publish(topic string, message Message) error {
+	// 获取该主题的所有消费者（包括通配符订阅）
+	consumers := mq.matchConsumers(topic)
+
+	if len(consumers) == 0 {
+		mq.logf("警告: 主题 %s 没有消费者\n", topic)
+		return fmt.Errorf("no consumers for topic: %s", topic)
+	}
+
+	// 增加发布统计
+	atomic.AddInt64(&mq.stats.published, 1)
+
+	// 并发发送消息给所有订阅该主题的消费者，每个投递一个goroutine，由deliveries跟踪
+	var attempted sync.WaitGroup
+	for _, consumer := range consumers {
+		attempted.Add(1)
+		mq.deliveries.Add(1)
+		go func(c Consumer) {
+			defer mq.deliveries.Done()
+			mq.deliverMessage(message, c, attempted.Done)
+		}(consumer)
+	}
+
+	// 只等待第一次尝试结束，失败后的退避重试在后台继续
+	attempted.Wait()
+	return nil
+}
+
+// matchConsumers 找出订阅模式与主题匹配的所有消费者，同一消费者只投递一次
+func (mq *InMemory) matchConsumers(topic string) []Consumer {
+	mq.mu.RLock()
+	defer mq.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	result := make([]Consumer, 0)
+	for pattern, consumers := range mq.subscriptions {
+		if !topicmatch.Match(pattern, topic) {
+			continue
+		}
+		for _, c := range consumers {
+			if !seen[c.GetID()] {
+				seen[c.GetID()] = true
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+// deliverMessage 将消息投递给指定消费者，失败时按重试策略只向这个消费者重投。
+// 第一次尝试的结果计入统计（或进入死信队列）之后调用attempted，之后的重试不再阻塞Publish
+func (mq *InMemory) deliverMessage(message Message, consumer Consumer, attempted func()) {
+	var once sync.Once
+	defer once.Do(attempted) // 队列已关闭没有尝试，或第一次就进入死信队列
+
+	prefix := contextkeys.LogPrefix(message.Context())
+	policy := mq.retryPolicy
+	policy.OnAttempt = func(a retry.Attempt) {
+		if a.Err == nil {
+			// 处理成功，增加成功统计
+			atomic.AddInt64(&mq.stats.consumed, 1)
+			once.Do(attempted)
+			return
+		}
+		// 处理失败，增加失败统计
+		atomic.AddInt64(&mq.stats.failed, 1)
+		if a.Retry {
+			atomic.AddInt64(&mq.stats.retried, 1)
+			mq.logf("%s消息 %s 投递给 %s 第 %d 次失败，%v 后重试\n",
+				prefix, message.ID, consumer.GetID(), a.Number, a.Delay.Round(time.Millisecond))
+			once.Do(attempted)
+		}
+	}
+
+	attempts := 0
+	err := retry.Do(mq.ctx, policy, func(ctx context.Context) error {
+		attempts++
+		return consumer.Consume(message)
+	})
+	message.Retries = attempts - 1
+	if err != nil {
+		// 超过最大重试次数、不可重试或队列已关闭，进入死信队列
+		mq.addToDeadLetter(message, err)
+	}
+}
+
+// addToDeadLetter 将消息添加到死信队列
+func (mq *InMemory) addToDeadLetter(message Message, reason error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	mq.deadLetter = append(mq.deadLetter, message)
+	mq.logf("%s消息 %s 进入死信队列: %v\n", contextkeys.LogPrefix(message.Context()), message.ID, reason)
+}
+
+// Subscribe 实现Queue接口 - 订阅主题，topic可以是通配符模式
+func (mq *InMemory) Subscribe(topic string, consumer Consumer) error {
+	if !topicmatch.Valid(topic) {
+		return fmt.Errorf("invalid topic pattern: %s", topic)
+	}
+
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	// 初始化主题的消费者列表
+	if mq.subscriptions[topic] == nil {
+		mq.subscriptions[topic] = make([]Consumer, 0)
+	}
+
+	// 检查消费者是否已经订阅过该主题
+	for _, c := range mq.subscriptions[topic] {
+		if c.GetID() == consumer.GetID() {
+			return fmt.Errorf("consumer %s already subscribed to topic %s", consumer.GetID(), topic)
+		}
+	}
+
+	// 添加消费者到订阅列表
+	mq.subscriptions[topic] = append(mq.subscriptions[topic], consumer)
+	mq.logf("消费者 %s 订阅主题: %s\n", consumer.GetID(), topic)
+
+	return nil
+}
+
+// Unsubscribe 实现Queue接口 - 取消订阅
+func (mq *InMemory) Unsubscribe(topic string, consumerID string) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	consumers, exists := mq.subscriptions[topic]
+	if !exists {
+		return fmt.Errorf("topic %s not found", topic)
+	}
+
+	// 查找并移除指定消费者
+	for i, consumer := range consumers {
+		if consumer.GetID() == consumerID {
+			// 从切片中移除消费者
+			mq.subscriptions[topic] = append(consumers[:i], consumers[i+1:]...)
+			mq.logf("消费者 %s 取消订阅主题: %s\n", consumerID, topic)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("consumer %s not found in topic %s", consumerID, topic)
+}
+
+// Wait 等待所有投递结束，包括后台还在进行的重试；不能与Publish并发调用
+func (mq *InMemory) Wait() {
+	mq.deliveries.Wait()
+}
+
+// Close 实现Queue接口 - 关闭消息队列，正在等待重试的投递立即放弃并进入死信队列，
+// 返回时所有投递都已结束
+func (mq *InMemory) Close() error {
+	mq.cancel()
+	mq.deliveries.Wait()
+	return nil
+}
+
+// GetStats 获取消息队列统计信息
+func (mq *InMemory) GetStats() (int64, int64, int64, int64, int) {
+	mq.mu.RLock()
+	defer mq.mu.RUnlock()
+
+	return atomic.LoadInt64(&mq.stats.published),
+		atomic.LoadInt64(&mq.stats.consumed),
+		atomic.LoadInt64(&mq.stats.failed),
+		atomic.LoadInt64(&mq.stats.retried),
+		len(mq.deadLetter)
+}
+
+// GetDeadLetters 获取死信队列中的消息
+func (mq *InMemory) GetDeadLetters() []Message {
+	mq.mu.RLock()
+	defer mq.mu.RUnlock()
+
+	// 返回副本，避免外部修改
+	result := make([]Message, len(mq.deadLetter))
+	copy(result, mq.deadLetter)
+	return result
+}
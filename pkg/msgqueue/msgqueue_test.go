@@ -0,0 +1,95 @@
+package msgqueue
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testConsumer 前failures次处理失败，之后成功
+type testConsumer struct {
+	id       string
+	failures int32
+	calls    atomic.Int32
+}
+
+func (c *testConsumer) GetID() string { return c.id }
+
+func (c *testConsumer) Consume(Message) error {
+	if c.calls.Add(1) <= c.failures {
+		return errors.New("处理失败")
+	}
+	return nil
+}
+
+// TestPublishDoesNotWaitForRetries Publish在第一次尝试后返回，退避重试在后台进行，Wait等待重试结束
+func TestPublishDoesNotWaitForRetries(t *testing.T) {
+	mq := NewInMemory(3)
+	defer mq.Close()
+	ok := &testConsumer{id: "ok"}
+	failing := &testConsumer{id: "failing", failures: 100}
+	mq.Subscribe("orders.#", ok)
+	mq.Subscribe("orders.*", failing)
+
+	start := time.Now()
+	if err := mq.Publish("orders.new", Message{ID: "m1"}); err != nil {
+		t.Fatalf("Publish() err = %v", err)
+	}
+	// 三次重试的退避至少有 50ms+100ms+200ms
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Fatalf("Publish阻塞了 %v，应当在第一次尝试后返回", d)
+	}
+	if _, consumed, failed, retried, dead := mq.GetStats(); consumed != 1 || failed != 1 || retried != 1 || dead != 0 {
+		t.Fatalf("Publish返回时 consumed=%d failed=%d retried=%d dead=%d, want 1 1 1 0", consumed, failed, retried, dead)
+	}
+
+	mq.Wait()
+	if _, consumed, failed, retried, dead := mq.GetStats(); consumed != 1 || failed != 4 || retried != 3 || dead != 1 {
+		t.Fatalf("Wait返回时 consumed=%d failed=%d retried=%d dead=%d, want 1 4 3 1", consumed, failed, retried, dead)
+	}
+	if dl := mq.GetDeadLetters(); len(dl) != 1 || dl[0].ID != "m1" || dl[0].Retries != 3 {
+		t.Fatalf("死信队列 %+v, want m1 重试3次", dl)
+	}
+}
+
+// TestRetrySucceedsInBackground 后台重试成功的消息计入成功消费，不进入死信队列
+func TestRetrySucceedsInBackground(t *testing.T) {
+	mq := NewInMemory(3)
+	defer mq.Close()
+	c := &testConsumer{id: "flaky", failures: 1}
+	mq.Subscribe("orders", c)
+
+	mq.Publish("orders", Message{ID: "m1"})
+	if _, consumed, _, _, _ := mq.GetStats(); consumed != 0 {
+		t.Fatalf("第一次失败后 consumed=%d, want 0", consumed)
+	}
+	mq.Wait()
+	if _, consumed, failed, _, dead := mq.GetStats(); consumed != 1 || failed != 1 || dead != 0 {
+		t.Fatalf("consumed=%d failed=%d dead=%d, want 1 1 0", consumed, failed, dead)
+	}
+	if n := c.calls.Load(); n != 2 {
+		t.Fatalf("Consume被调用 %d 次, want 2", n)
+	}
+}
+
+// TestCloseAbandonsRetries Close放弃等待中的重试，返回时消息已经进入死信队列
+func TestCloseAbandonsRetries(t *testing.T) {
+	mq := NewInMemory(10)
+	mq.Subscribe("orders", &testConsumer{id: "failing", failures: 100})
+	mq.Publish("orders", Message{ID: "m1"})
+
+	done := make(chan struct{})
+	go func() {
+		mq.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close没有放弃等待中的重试")
+	}
+	if _, _, _, _, dead := mq.GetStats(); dead != 1 {
+		t.Fatalf("Close返回时 dead=%d, want 1", dead)
+	}
+}
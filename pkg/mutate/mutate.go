@@ -0,0 +1,306 @@
+// Package mutate 对练习代码做小的并发相关变异，检验评分任务是否足够严格。
+//
+// 每个变异只改一处：删掉加锁、把解锁提前到临界区之前、删掉wg.Done()或wg.Wait()、
+// 删掉close、把channel的缓冲改成0。变异后的代码仍然能编译，大多数看起来"差不多能用"——
+// 正是这类错误单看返回值发现不了，评分任务需要在竞态检测器下制造并发、设置超时、
+// 检查goroutine泄漏和全部结果才能发现它们。
+//
+// 只变异评分会调用到的函数：从评分文件引用的名字出发，沿函数体中出现的名字找到所有可达的函数和方法
+// （按名字匹配，不区分接收者类型）。只在main里调用的代码，改成什么样评分都不会发现，变异它没有意义。
+//
+// 变异按源码中的字节位置替换文本，不重新格式化，行号与原文件一致。
+// 删掉语句后可能出现"声明了但没有使用"之类的编译错误，这样的变异不计入结果。
+// 也有变异不改变程序行为（例如缓冲本来就不影响正确性），存活不一定说明评分有漏洞。
+package mutate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+)
+
+// Kind 变异的种类
+type Kind string
+
+const (
+	RemoveLock   Kind = "remove-lock"   // 删掉Lock()/RLock()和对应的解锁
+	UnlockEarly  Kind = "unlock-early"  // 把解锁移到加锁之后，临界区失去保护
+	RemoveDone   Kind = "remove-done"   // 删掉wg.Done()
+	RemoveWait   Kind = "remove-wait"   // 删掉wg.Wait()等Wait()调用
+	RemoveClose  Kind = "remove-close"  // 删掉close(ch)
+	ShrinkBuffer Kind = "shrink-buffer" // make(chan T, n)改成make(chan T, 0)
+)
+
+// Kinds 所有变异种类
+var Kinds = []Kind{RemoveLock, UnlockEarly, RemoveDone, RemoveWait, RemoveClose, ShrinkBuffer}
+
+// Lesson 变异存活时，评分任务应该怎样检查才能发现它
+func (k Kind) Lesson() string {
+	switch k {
+	case RemoveLock, UnlockEarly:
+		return "让多个goroutine同时读写共享数据，在竞态检测器下运行，并核对最终结果（例如计数是否等于操作次数）"
+	case RemoveDone:
+		return "等待全部工作结束时设置超时，少一次Done会让Wait永远不返回"
+	case RemoveWait:
+		return "在函数返回后立即检查全部结果都已经产生，并检查任务结束后没有遗留的goroutine"
+	case RemoveClose:
+		return "用range读到channel关闭为止并设置超时，检查没有goroutine一直阻塞在接收上"
+	case ShrinkBuffer:
+		return "在没有接收方或接收方很慢时检查发送不会阻塞；如果缓冲只是优化、不影响正确性，存活是正常的"
+	}
+	return ""
+}
+
+// Mutant 一个变异
+type Mutant struct {
+	Kind   Kind
+	Func   string // 变异所在的函数，方法写成"类型.方法"
+	Line   int
+	Desc   string // 例如"删除 wg.Done()"
+	Source []byte // 变异后的完整源码
+}
+
+// edit 把src[start:end]替换为text
+type edit struct {
+	start, end int
+	text       string
+}
+
+func apply(src []byte, edits ...edit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		b.Write(src[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.Write(src[last:])
+	return b.Bytes()
+}
+
+// generator 在一个文件上收集变异
+type generator struct {
+	fset    *token.FileSet
+	src     []byte
+	fn      string
+	mutants []Mutant
+}
+
+func (g *generator) offset(p token.Pos) int { return g.fset.Position(p).Offset }
+
+func (g *generator) text(n ast.Node) string {
+	return string(g.src[g.offset(n.Pos()):g.offset(n.End())])
+}
+
+func (g *generator) add(kind Kind, at ast.Node, desc string, edits ...edit) {
+	g.mutants = append(g.mutants, Mutant{
+		Kind: kind, Func: g.fn, Line: g.fset.Position(at.Pos()).Line, Desc: desc,
+		Source: apply(g.src, edits...),
+	})
+}
+
+// remove 删掉一条语句
+func (g *generator) remove(s ast.Stmt) edit {
+	return edit{g.offset(s.Pos()), g.offset(s.End()), ""}
+}
+
+// Generate 解析练习源文件src，返回评分文件gradeSrc会调用到的函数中的所有变异，按行号排序
+func Generate(filename string, src, gradeSrc []byte) ([]Mutant, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", filename, err)
+	}
+	gf, err := parser.ParseFile(fset, "grade.go", gradeSrc, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("解析评分文件失败: %w", err)
+	}
+	graded := reachable(f, names(gf))
+	g := &generator{fset: fset, src: src}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil || !graded[fd] {
+			continue
+		}
+		g.fn = funcName(fd)
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BlockStmt:
+				g.stmts(n.List)
+			case *ast.CaseClause:
+				g.stmts(n.Body)
+			case *ast.CommClause:
+				g.stmts(n.Body)
+			case *ast.CallExpr:
+				g.makeChan(n)
+			}
+			return true
+		})
+	}
+	sort.SliceStable(g.mutants, func(i, j int) bool { return g.mutants[i].Line < g.mutants[j].Line })
+	return g.mutants, nil
+}
+
+// names 节点中出现的所有标识符（包括x.f中的f）
+func names(n ast.Node) map[string]bool {
+	set := make(map[string]bool)
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			set[id.Name] = true
+		}
+		return true
+	})
+	return set
+}
+
+// reachable 从roots中的名字出发能调用到的函数和方法。main和init不会在评分时运行，始终排除
+func reachable(f *ast.File, roots map[string]bool) map[*ast.FuncDecl]bool {
+	byName := make(map[string][]*ast.FuncDecl)
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+			if fd.Recv == nil && (fd.Name.Name == "main" || fd.Name.Name == "init") {
+				continue
+			}
+			byName[fd.Name.Name] = append(byName[fd.Name.Name], fd)
+		}
+	}
+	seen := make(map[*ast.FuncDecl]bool)
+	var queue []string
+	for name := range roots {
+		queue = append(queue, name)
+	}
+	visited := make(map[string]bool)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		for _, fd := range byName[name] {
+			seen[fd] = true
+			for ref := range names(fd.Body) {
+				queue = append(queue, ref)
+			}
+		}
+	}
+	return seen
+}
+
+func funcName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	t := fd.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if idx, ok := t.(*ast.IndexExpr); ok { // 泛型类型
+		t = idx.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name + "." + fd.Name.Name
+	}
+	return fd.Name.Name
+}
+
+// call 语句是"x.method()"、"defer x.method()"或"close(x)"这样的调用时返回调用本身
+func call(s ast.Stmt) (*ast.CallExpr, bool) {
+	var c *ast.CallExpr
+	deferred := false
+	switch s := s.(type) {
+	case *ast.ExprStmt:
+		c, _ = s.X.(*ast.CallExpr)
+	case *ast.DeferStmt:
+		c, deferred = s.Call, true
+	}
+	return c, deferred
+}
+
+// method 无参数的方法调用x.name()，返回接收者x的源码
+func (g *generator) method(c *ast.CallExpr, names ...string) (recv, name string, ok bool) {
+	if c == nil || len(c.Args) != 0 {
+		return "", "", false
+	}
+	sel, isSel := c.Fun.(*ast.SelectorExpr)
+	if !isSel {
+		return "", "", false
+	}
+	for _, n := range names {
+		if sel.Sel.Name == n {
+			return g.text(sel.X), n, true
+		}
+	}
+	return "", "", false
+}
+
+// stmts 在一个语句列表中找加锁、Done、Wait和close
+func (g *generator) stmts(list []ast.Stmt) {
+	for i, s := range list {
+		c, deferred := call(s)
+		if c == nil {
+			continue
+		}
+		if recv, name, ok := g.method(c, "Lock", "RLock"); ok && !deferred {
+			g.lock(list, i, recv, name)
+			continue
+		}
+		if recv, _, ok := g.method(c, "Done"); ok {
+			g.add(RemoveDone, s, "删除 "+recv+".Done()", g.remove(s))
+			continue
+		}
+		if recv, _, ok := g.method(c, "Wait"); ok && !deferred {
+			g.add(RemoveWait, s, "删除 "+recv+".Wait()", g.remove(s))
+			continue
+		}
+		if id, ok := c.Fun.(*ast.Ident); ok && id.Name == "close" && len(c.Args) == 1 {
+			g.add(RemoveClose, s, "删除 close("+g.text(c.Args[0])+")", g.remove(s))
+		}
+	}
+}
+
+// lock list[i]是recv.Lock()或recv.RLock()：在同一个语句列表中找到对应的解锁，
+// 生成"删掉加锁和解锁"与"解锁提前到加锁之后"两个变异；找不到对应的解锁时不变异
+func (g *generator) lock(list []ast.Stmt, i int, recv, name string) {
+	unlockName := "Unlock"
+	if name == "RLock" {
+		unlockName = "RUnlock"
+	}
+	for j := i + 1; j < len(list); j++ {
+		c, deferred := call(list[j])
+		if r, _, ok := g.method(c, unlockName); !ok || r != recv {
+			continue
+		}
+		lockStmt, unlockStmt := list[i], list[j]
+		g.add(RemoveLock, lockStmt, fmt.Sprintf("删除 %s.%s() 和对应的 %s.%s()", recv, name, recv, unlockName),
+			g.remove(lockStmt), g.remove(unlockStmt))
+		// 加锁后紧接着解锁、中间没有语句时，提前解锁不改变任何东西
+		if deferred || j > i+1 {
+			g.add(UnlockEarly, lockStmt, fmt.Sprintf("%s.%s() 移到 %s.%s() 之后，临界区不再受保护", recv, unlockName, recv, name),
+				edit{g.offset(lockStmt.End()), g.offset(lockStmt.End()), "; " + recv + "." + unlockName + "()"},
+				g.remove(unlockStmt))
+		}
+		return
+	}
+}
+
+// makeChan make(chan T, n)的缓冲改为0；缓冲本来就是0时不变异
+func (g *generator) makeChan(c *ast.CallExpr) {
+	id, ok := c.Fun.(*ast.Ident)
+	if !ok || id.Name != "make" || len(c.Args) != 2 {
+		return
+	}
+	if _, ok := c.Args[0].(*ast.ChanType); !ok {
+		return
+	}
+	size := c.Args[1]
+	if lit, ok := size.(*ast.BasicLit); ok && lit.Value == "0" {
+		return
+	}
+	g.add(ShrinkBuffer, c, fmt.Sprintf("%s 的缓冲 %s 改为0", g.text(c.Args[0]), g.text(size)),
+		edit{g.offset(size.Pos()), g.offset(size.End()), "0"})
+}
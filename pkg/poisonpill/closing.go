@@ -0,0 +1,50 @@
+package poisonpill
+
+import "sync"
+
+// Closing 关闭channel的写法，作为毒丸的对照：所有生产者结束后由一方关闭channel，
+// 消费者range读取，排空后一起退出。只有Run负责关闭，生产者不能关闭也不能在返回后继续发送
+type Closing[T any] struct {
+	Buffer    int
+	Producers int
+	Consumers int
+
+	Produce  func(id int, out chan<- T) // 在生产者goroutine中调用，返回表示这个生产者结束
+	Handle   func(id int, v T)          // 在消费者goroutine中处理元素
+	OnClosed func()                     // 所有生产者结束、channel关闭后调用，可以为nil
+	OnExit   func(id int)               // 消费者排空channel退出时调用，可以为nil
+}
+
+// Run 启动生产者和消费者（编号都从1开始），阻塞到所有消费者退出
+func (c Closing[T]) Run() {
+	ch := make(chan T, c.Buffer)
+
+	var producers, consumers sync.WaitGroup
+	for id := 1; id <= c.Producers; id++ {
+		producers.Add(1)
+		go func(id int) {
+			defer producers.Done()
+			c.Produce(id, ch)
+		}(id)
+	}
+	for id := 1; id <= c.Consumers; id++ {
+		consumers.Add(1)
+		go func(id int) {
+			defer consumers.Done()
+			for v := range ch {
+				c.Handle(id, v)
+			}
+			if c.OnExit != nil {
+				c.OnExit(id)
+			}
+		}(id)
+	}
+
+	// 等所有生产者都返回之后才能关闭，否则还在发送的生产者会panic
+	producers.Wait()
+	close(ch)
+	if c.OnClosed != nil {
+		c.OnClosed()
+	}
+	consumers.Wait()
+}
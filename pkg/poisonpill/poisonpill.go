@@ -0,0 +1,106 @@
+// Package poisonpill 用毒丸（poison pill）协议停止从共享channel消费的一组消费者。
+//
+// 关闭channel会让所有消费者一起退出，而且关闭之后不能再发送；毒丸是排在队列里的一个特殊元素，
+// 只会被一个消费者取走，取到的消费者退出，channel和其余消费者不受影响。所以：
+//   - 要停止n个消费者就发送n个毒丸，可以只停掉一部分（缩容）
+//   - 毒丸排在已入队的元素之后，之前的元素仍会被处理
+//   - 全部停止时，毒丸必须在最后一个元素之后发送，否则排在毒丸后面的元素可能没人消费
+//
+// 常见写法是约定一个哨兵值（例如ID为-1的产品）做毒丸；泛型版本无法占用T的某个值，所以把元素和毒丸包装成item。
+//
+// Closing是关闭channel的对照写法，用于所有消费者一起退出的场景。
+package poisonpill
+
+import (
+	"sync"
+)
+
+type item[T any] struct {
+	v    T
+	pill bool
+}
+
+// Group 共用一个带缓冲channel的消费者组，channel始终不关闭
+type Group[T any] struct {
+	ch     chan item[T]
+	handle func(id int, v T)
+	onExit func(id int)
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	started int // 启动过的消费者数，也用来分配编号
+	pills   int // 已发送的毒丸数
+	exited  int
+}
+
+// New 创建消费者组；handle在消费者goroutine中处理元素，onExit在消费者收到毒丸退出时调用，可以为nil
+func New[T any](buffer int, handle func(id int, v T), onExit func(id int)) *Group[T] {
+	return &Group[T]{ch: make(chan item[T], buffer), handle: handle, onExit: onExit}
+}
+
+// Add 再启动n个消费者，编号从1开始依次分配
+func (g *Group[T]) Add(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := 0; i < n; i++ {
+		g.started++
+		g.wg.Add(1)
+		go g.consume(g.started)
+	}
+}
+
+func (g *Group[T]) consume(id int) {
+	defer g.wg.Done()
+	for it := range g.ch {
+		if it.pill {
+			g.mu.Lock()
+			g.exited++
+			g.mu.Unlock()
+			if g.onExit != nil {
+				g.onExit(id)
+			}
+			return
+		}
+		g.handle(id, it.v)
+	}
+}
+
+// Send 发送一个元素，缓冲区满时阻塞
+func (g *Group[T]) Send(v T) {
+	g.ch <- item[T]{v: v}
+}
+
+// Stop 发送n个毒丸停止n个消费者，不超过还没有分到毒丸的消费者数；返回实际发送的个数。
+// 毒丸排在已发送的元素之后，Stop返回时消费者不一定已经退出
+func (g *Group[T]) Stop(n int) int {
+	g.mu.Lock()
+	n = min(n, g.started-g.pills)
+	g.pills += n
+	g.mu.Unlock()
+	for i := 0; i < n; i++ {
+		g.ch <- item[T]{pill: true}
+	}
+	return n
+}
+
+// StopAll 为剩余的每个消费者发送一个毒丸并等待全部退出。
+// 调用前所有生产者必须已经结束，之后Send的元素不会再被处理
+func (g *Group[T]) StopAll() {
+	g.mu.Lock()
+	n := g.started - g.pills
+	g.mu.Unlock()
+	g.Stop(n)
+	g.wg.Wait()
+}
+
+// Running 还没有退出的消费者数
+func (g *Group[T]) Running() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.started - g.exited
+}
+
+// Len channel中排队的元素和毒丸数
+func (g *Group[T]) Len() int {
+	return len(g.ch)
+}
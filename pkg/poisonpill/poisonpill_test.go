@@ -0,0 +1,199 @@
+package poisonpill
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counter 统计每个元素被处理的次数和退出的消费者
+type counter struct {
+	mu     sync.Mutex
+	seen   map[int]int
+	exited []int
+	exits  chan int
+}
+
+func newCounter() *counter {
+	return &counter{seen: make(map[int]int), exits: make(chan int, 100)}
+}
+
+func (c *counter) handle(_ int, v int) {
+	c.mu.Lock()
+	c.seen[v]++
+	c.mu.Unlock()
+}
+
+func (c *counter) onExit(id int) {
+	c.mu.Lock()
+	c.exited = append(c.exited, id)
+	c.mu.Unlock()
+	c.exits <- id
+}
+
+func (c *counter) await(t *testing.T) int {
+	t.Helper()
+	select {
+	case id := <-c.exits:
+		return id
+	case <-time.After(time.Second):
+		t.Fatal("没有消费者退出")
+		return 0
+	}
+}
+
+// TestStopOneKeepsOthers 一个毒丸只停掉一个消费者，其余消费者继续处理之后的元素
+func TestStopOneKeepsOthers(t *testing.T) {
+	c := newCounter()
+	g := New(10, c.handle, c.onExit)
+	g.Add(3)
+
+	for i := 0; i < 5; i++ {
+		g.Send(i)
+	}
+	if n := g.Stop(1); n != 1 {
+		t.Fatalf("Stop(1) = %d, want 1", n)
+	}
+	c.await(t)
+	if r := g.Running(); r != 2 {
+		t.Fatalf("Running() = %d, want 2", r)
+	}
+	select {
+	case id := <-c.exits:
+		t.Fatalf("一个毒丸停掉了第二个消费者 %d", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	for i := 5; i < 10; i++ {
+		g.Send(i)
+	}
+	g.StopAll()
+	if r := g.Running(); r != 0 {
+		t.Fatalf("StopAll之后Running() = %d", r)
+	}
+	for i := 0; i < 10; i++ {
+		if c.seen[i] != 1 {
+			t.Fatalf("元素 %d 被处理 %d 次, want 1", i, c.seen[i])
+		}
+	}
+	if len(c.exited) != 3 || g.Len() != 0 {
+		t.Fatalf("退出 %v，channel剩余 %d, want 3个消费者退出、剩余0", c.exited, g.Len())
+	}
+}
+
+// TestStopCapsAtRunning 毒丸数不超过还没有分到毒丸的消费者数，多余的不会留在channel里
+func TestStopCapsAtRunning(t *testing.T) {
+	c := newCounter()
+	g := New(10, c.handle, c.onExit)
+	g.Add(2)
+
+	if n := g.Stop(5); n != 2 {
+		t.Fatalf("Stop(5) = %d, want 2", n)
+	}
+	if n := g.Stop(1); n != 0 {
+		t.Fatalf("所有消费者都已分到毒丸，Stop(1) = %d, want 0", n)
+	}
+	c.await(t)
+	c.await(t)
+	g.StopAll()
+	if g.Len() != 0 {
+		t.Fatalf("channel剩余 %d", g.Len())
+	}
+
+	// 缩容后再扩容，新的消费者照常工作
+	g.Add(1)
+	g.Send(42)
+	g.StopAll()
+	if c.seen[42] != 1 || len(c.exited) != 3 {
+		t.Fatalf("扩容后的消费者处理了 %d 次，共退出 %d 个", c.seen[42], len(c.exited))
+	}
+}
+
+// TestConcurrentProducers 多个生产者并发发送，中途缩容，生产者结束后StopAll：每个元素恰好处理一次
+func TestConcurrentProducers(t *testing.T) {
+	const producers, each = 4, 500
+	var handled atomic.Int64
+	seen := make([]atomic.Int32, producers*each)
+	g := New(8, func(_ int, v int) {
+		seen[v].Add(1)
+		handled.Add(1)
+	}, nil)
+	g.Add(4)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				g.Send(p*each + i)
+			}
+		}(p)
+	}
+	g.Stop(2)
+	wg.Wait()
+	g.StopAll()
+
+	if n := handled.Load(); n != producers*each {
+		t.Fatalf("处理了 %d 个元素, want %d", n, producers*each)
+	}
+	for v := range seen {
+		if n := seen[v].Load(); n != 1 {
+			t.Fatalf("元素 %d 被处理 %d 次", v, n)
+		}
+	}
+}
+
+// TestClosingDrainsAll 关闭channel的写法：所有生产者结束后每个元素恰好被消费一次，
+// channel关闭发生在所有发送之后，每个消费者都退出
+func TestClosingDrainsAll(t *testing.T) {
+	const producers, consumers, items = 4, 3, 50
+
+	c := newCounter()
+	var closed atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Closing[int]{
+			Buffer:    2,
+			Producers: producers,
+			Consumers: consumers,
+			Produce: func(id int, out chan<- int) {
+				for i := 0; i < items; i++ {
+					out <- id*1000 + i
+				}
+			},
+			Handle:   c.handle,
+			OnClosed: func() { closed.Store(true) },
+			OnExit:   c.onExit,
+		}.Run()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run没有返回")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.seen) != producers*items {
+		t.Fatalf("消费了 %d 个不同元素, want %d", len(c.seen), producers*items)
+	}
+	for v, n := range c.seen {
+		if n != 1 {
+			t.Fatalf("元素 %d 被消费 %d 次, want 1", v, n)
+		}
+	}
+	exited := make(map[int]bool)
+	for _, id := range c.exited {
+		exited[id] = true
+	}
+	if len(c.exited) != consumers || len(exited) != consumers {
+		t.Fatalf("退出的消费者 %v, want %d 个不同编号", c.exited, consumers)
+	}
+	if !closed.Load() {
+		t.Fatal("OnClosed没有被调用")
+	}
+}
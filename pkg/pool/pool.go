@@ -0,0 +1,150 @@
+// Package pool 提供泛型的goroutine池 Pool[I, O]。
+//
+// 每个任务返回 (O, error)，结果按提交顺序（任务下标）输出；Stop(ctx) 不再接收
+// 新任务，并等待已提交的任务处理完，ctx到期时取消仍在执行和排队的任务。
+//
+// TenantPool 是多租户版本：任务带租户ID，每个租户有工作者配额、排队上限和调度权重。
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrPoolStopped 池已停止，不再接收任务
+var ErrPoolStopped = errors.New("pool stopped")
+
+// Result 一个任务的结果，Index为提交时返回的下标
+type Result[O any] struct {
+	Index int
+	Value O
+	Err   error
+}
+
+type job[I any] struct {
+	index int
+	input I
+}
+
+// Pool 固定数量工作者的任务池
+type Pool[I, O any] struct {
+	fn func(context.Context, I) (O, error)
+
+	ctx    context.Context // 传给每个任务，Stop超时后被取消
+	cancel context.CancelFunc
+
+	mu      sync.RWMutex // 保护stopped和jobs的关闭：Submit持读锁发送，Stop持写锁关闭
+	stopped bool
+	next    int64 // 下一个任务下标
+	jobs    chan job[I]
+
+	unordered chan Result[O]
+	results   chan Result[O]
+	workers   sync.WaitGroup
+	done      chan struct{} // 所有结果都已输出
+}
+
+// New 创建并启动任务池：workers个工作者，队列容量queueSize
+func New[I, O any](workers, queueSize int, fn func(context.Context, I) (O, error)) *Pool[I, O] {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool[I, O]{
+		fn:        fn,
+		ctx:       ctx,
+		cancel:    cancel,
+		jobs:      make(chan job[I], queueSize),
+		unordered: make(chan Result[O], workers),
+		results:   make(chan Result[O]),
+		done:      make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		p.workers.Add(1)
+		go p.work()
+	}
+	go func() {
+		p.workers.Wait()
+		close(p.unordered)
+	}()
+	go p.reorder()
+
+	return p
+}
+
+// Submit 提交任务，返回任务下标；队列满时阻塞，池停止后返回ErrPoolStopped
+func (p *Pool[I, O]) Submit(input I) (int, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return 0, ErrPoolStopped
+	}
+
+	// 读锁下多个Submit可能并发，下标用原子操作分配；
+	// Stop要等所有持有读锁的Submit发送完才能关闭jobs，所以分配出去的下标一定会被处理
+	index := int(atomic.AddInt64(&p.next, 1) - 1)
+	p.jobs <- job[I]{index: index, input: input}
+	return index, nil
+}
+
+// Results 按任务下标顺序输出结果，Stop后所有结果输出完毕时关闭
+// 调用方需要并发读取，否则工作者会阻塞在结果输出上
+func (p *Pool[I, O]) Results() <-chan Result[O] {
+	return p.results
+}
+
+func (p *Pool[I, O]) work() {
+	defer p.workers.Done()
+	for j := range p.jobs {
+		var r Result[O]
+		r.Index = j.index
+		// 已被取消时不再执行，直接返回取消原因，保证每个下标都有结果
+		if err := p.ctx.Err(); err != nil {
+			r.Err = err
+		} else {
+			r.Value, r.Err = p.fn(p.ctx, j.input)
+		}
+		p.unordered <- r
+	}
+}
+
+// reorder 用重排缓冲区把乱序完成的结果按下标依次输出
+func (p *Pool[I, O]) reorder() {
+	defer close(p.done)
+	defer close(p.results)
+
+	next := 0
+	pending := make(map[int]Result[O])
+	for r := range p.unordered {
+		pending[r.Index] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			p.results <- r
+			next++
+		}
+	}
+}
+
+// Stop 停止接收新任务并等待已提交的任务完成
+// ctx到期时取消剩余任务（任务的ctx被取消、排队的任务不再执行）并返回ctx.Err()
+func (p *Pool[I, O]) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
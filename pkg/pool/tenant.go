@@ -0,0 +1,254 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrTenantQueueFull 该租户排队的任务已达上限，任务被拒绝；其他租户不受影响
+var ErrTenantQueueFull = errors.New("tenant queue full")
+
+// TenantConfig 单个租户的配额
+type TenantConfig struct {
+	MaxWorkers int // 同时占用的工作者上限，<=0表示不限（最多为池的全部工作者）
+	MaxQueue   int // 排队任务上限，<=0表示不限
+	Weight     int // 公平调度的权重，<=0时为1
+}
+
+// TenantStats 单个租户的统计
+type TenantStats struct {
+	Submitted int64
+	Rejected  int64 // 因排队已满被拒绝
+	Completed int64 // 执行完成（包括返回错误的）
+	Failed    int64 // 返回错误的
+	Canceled  int64 // Stop超时后没有执行就被取消的
+	Running   int   // 当前正在执行
+	Queued    int   // 当前排队
+	PeakRun   int   // 同时执行数的峰值
+	TotalWait time.Duration
+	MaxWait   time.Duration // 从提交到开始执行的最长等待
+}
+
+// AvgWait 已开始执行的任务的平均排队时间
+func (s TenantStats) AvgWait() time.Duration {
+	started := s.Completed + s.Canceled
+	if started == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(started)
+}
+
+type tenantTask struct {
+	fn       func(context.Context) error
+	enqueued time.Time
+}
+
+type tenant struct {
+	cfg    TenantConfig
+	queue  []tenantTask
+	served uint64 // 上次被调度的序号，比例相同时先调度最久没被调度的
+	stats  TenantStats
+}
+
+// TenantPool 多租户任务池：任务带租户ID，每个租户有工作者配额和排队上限，
+// 空闲工作者在有任务且没超配额的租户中选择"正在执行数/权重"最小的一个，
+// 使一个租户的突发流量（吵闹的邻居）不会让其他租户的任务长时间排队
+type TenantPool struct {
+	defaults TenantConfig
+
+	ctx    context.Context // 传给每个任务，Stop超时后被取消
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond // 有新任务、配额释放或停止时广播
+	tenants map[string]*tenant
+	seq     uint64
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewTenantPool 创建并启动多租户池，没有用SetTenant配置过的租户使用defaults
+func NewTenantPool(workers int, defaults TenantConfig) *TenantPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &TenantPool{
+		defaults: defaults,
+		ctx:      ctx,
+		cancel:   cancel,
+		tenants:  make(map[string]*tenant),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// SetTenant 设置租户的配额，可以在运行中调整
+func (p *TenantPool) SetTenant(id string, cfg TenantConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tenant(id).cfg = cfg
+	p.cond.Broadcast()
+}
+
+// tenant 调用方需持有p.mu
+func (p *TenantPool) tenant(id string) *tenant {
+	t, ok := p.tenants[id]
+	if !ok {
+		t = &tenant{cfg: p.defaults}
+		p.tenants[id] = t
+	}
+	return t
+}
+
+// Submit 提交租户id的任务，不阻塞
+// 排队已满返回ErrTenantQueueFull，池停止后返回ErrPoolStopped
+func (p *TenantPool) Submit(id string, fn func(ctx context.Context) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return ErrPoolStopped
+	}
+	t := p.tenant(id)
+	t.stats.Submitted++
+	if t.cfg.MaxQueue > 0 && len(t.queue) >= t.cfg.MaxQueue {
+		t.stats.Rejected++
+		return ErrTenantQueueFull
+	}
+	t.queue = append(t.queue, tenantTask{fn: fn, enqueued: time.Now()})
+	p.cond.Broadcast()
+	return nil
+}
+
+// next 选出下一个要执行的租户，没有可执行的返回nil；调用方需持有p.mu
+func (p *TenantPool) next() *tenant {
+	var best *tenant
+	for _, t := range p.tenants {
+		if len(t.queue) == 0 || (t.cfg.MaxWorkers > 0 && t.stats.Running >= t.cfg.MaxWorkers) {
+			continue
+		}
+		if best == nil || less(t, best) {
+			best = t
+		}
+	}
+	return best
+}
+
+// less a是否比b更应该被调度：比较Running/Weight，交叉相乘避免除法
+func less(a, b *tenant) bool {
+	ra, rb := a.stats.Running*weight(b.cfg), b.stats.Running*weight(a.cfg)
+	if ra != rb {
+		return ra < rb
+	}
+	return a.served < b.served
+}
+
+func weight(cfg TenantConfig) int { return max(cfg.Weight, 1) }
+
+func (p *TenantPool) work() {
+	defer p.wg.Done()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		t := p.next()
+		if t == nil {
+			if p.stopped && p.idle() {
+				return
+			}
+			p.cond.Wait()
+			continue
+		}
+
+		task := t.queue[0]
+		t.queue[0] = tenantTask{}
+		t.queue = t.queue[1:]
+		p.seq++
+		t.served = p.seq
+		wait := time.Since(task.enqueued)
+		t.stats.TotalWait += wait
+		t.stats.MaxWait = max(t.stats.MaxWait, wait)
+
+		// 已被取消时不再执行
+		if p.ctx.Err() != nil {
+			t.stats.Canceled++
+			continue
+		}
+		t.stats.Running++
+		t.stats.PeakRun = max(t.stats.PeakRun, t.stats.Running)
+
+		p.mu.Unlock()
+		err := task.fn(p.ctx)
+		p.mu.Lock()
+
+		t.stats.Running--
+		t.stats.Completed++
+		if err != nil {
+			t.stats.Failed++
+		}
+		// 配额释放后，等待中的工作者可能可以执行这个租户的任务了
+		p.cond.Broadcast()
+	}
+}
+
+// idle 所有租户都没有排队的任务；调用方需持有p.mu
+func (p *TenantPool) idle() bool {
+	for _, t := range p.tenants {
+		if len(t.queue) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Stats 每个租户的统计快照
+func (p *TenantPool) Stats() map[string]TenantStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]TenantStats, len(p.tenants))
+	for id, t := range p.tenants {
+		s := t.stats
+		s.Queued = len(t.queue)
+		out[id] = s
+	}
+	return out
+}
+
+// Tenants 按ID排序的租户列表
+func (p *TenantPool) Tenants() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.tenants))
+	for id := range p.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stop 停止接收新任务并等待所有租户排队的任务执行完
+// ctx到期时取消剩余任务（任务的ctx被取消、排队的任务不再执行）并返回ctx.Err()
+func (p *TenantPool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	p.stopped = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,218 @@
+// Package progress 在本地JSON文件中记录练习进度：每个练习的评分次数、最近一次各任务的结果、
+// 首次和最近评分的时间、第一次全部通过的时间，以及按学习路线（pkg/curriculum）解锁练习的时间。
+//
+// 评分器每评分一个练习调用一次Record，然后Save；progress命令读取同一个文件汇总显示。
+// 文件按"写临时文件再改名"的方式保存，评分中途被打断也不会留下写了一半的文件。
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileName 进度文件的默认文件名，放在exercises目录下
+const FileName = ".progress.json"
+
+// version 文件格式版本，格式不兼容地变化时加一
+const version = 1
+
+// TaskResult 一个评分任务的结果
+type TaskResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+}
+
+// Record 一个练习的进度
+type Record struct {
+	Exercise     string       `json:"exercise"` // 级别/文件名，例如simple/03_channel_basic
+	Attempts     int          `json:"attempts"` // 评分次数（包括编译失败）
+	Passes       int          `json:"passes"`   // 全部任务通过的次数
+	FirstAttempt time.Time    `json:"first_attempt"`
+	LastAttempt  time.Time    `json:"last_attempt"`
+	CompletedAt  time.Time    `json:"completed_at,omitempty"` // 第一次全部通过的时间，未完成时为零值
+	LastPassed   bool         `json:"last_passed"`            // 最近一次是否全部通过
+	LastError    string       `json:"last_error,omitempty"`   // 最近一次编译失败等不属于任务的错误
+	Tasks        []TaskResult `json:"tasks"`                  // 最近一次各任务的结果
+	BestPassed   int          `json:"best_passed"`            // 历史上一次评分中通过最多的任务数
+}
+
+// Completed 是否曾经全部通过
+func (r Record) Completed() bool { return !r.CompletedAt.IsZero() }
+
+// TasksPassed 最近一次评分通过的任务数和总任务数
+func (r Record) TasksPassed() (passed, total int) {
+	for _, t := range r.Tasks {
+		if t.Passed {
+			passed++
+		}
+	}
+	return passed, len(r.Tasks)
+}
+
+// TimeToComplete 从第一次评分到第一次全部通过用了多久
+func (r Record) TimeToComplete() (time.Duration, bool) {
+	if !r.Completed() {
+		return 0, false
+	}
+	return r.CompletedAt.Sub(r.FirstAttempt), true
+}
+
+type fileFormat struct {
+	Version   int                  `json:"version"`
+	Exercises map[string]*Record   `json:"exercises"`
+	Unlocked  map[string]time.Time `json:"unlocked,omitempty"` // 练习 -> 解锁时间；旧版本的文件没有这一项
+}
+
+// Store 进度文件，方法可以并发调用
+type Store struct {
+	path string
+
+	mu       sync.Mutex
+	records  map[string]*Record
+	unlocked map[string]time.Time
+}
+
+// Open 读取进度文件，文件不存在时返回空的Store，第一次Save时创建
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, records: make(map[string]*Record), unlocked: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f fileFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("进度文件 %s 格式错误: %w", path, err)
+	}
+	if f.Version > version {
+		return nil, fmt.Errorf("进度文件 %s 的版本 %d 比当前程序支持的版本 %d 新", path, f.Version, version)
+	}
+	for id, r := range f.Exercises {
+		if r != nil {
+			r.Exercise = id
+			s.records[id] = r
+		}
+	}
+	for id, at := range f.Unlocked {
+		s.unlocked[id] = at
+	}
+	return s, nil
+}
+
+// Path 进度文件路径
+func (s *Store) Path() string { return s.path }
+
+// Record 记录一次评分。errMsg非空表示没能运行任务（编译失败、超时等），此时tasks通常为空
+func (s *Store) Record(exercise string, tasks []TaskResult, errMsg string, at time.Time) Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[exercise]
+	if !ok {
+		r = &Record{Exercise: exercise, FirstAttempt: at}
+		s.records[exercise] = r
+	}
+	r.Attempts++
+	r.LastAttempt = at
+	r.LastError = errMsg
+	r.Tasks = append([]TaskResult(nil), tasks...)
+	passed, total := r.TasksPassed()
+	r.BestPassed = max(r.BestPassed, passed)
+	r.LastPassed = errMsg == "" && total > 0 && passed == total
+	if r.LastPassed {
+		r.Passes++
+		if r.CompletedAt.IsZero() {
+			r.CompletedAt = at
+		}
+	}
+	return *r
+}
+
+// Get 一个练习的进度
+func (s *Store) Get(exercise string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[exercise]
+	if !ok {
+		return Record{}, false
+	}
+	return *r, true
+}
+
+// All 所有有记录的练习，按练习名排序
+func (s *Store) All() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Record, 0, len(s.records))
+	for _, r := range s.records {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Exercise < list[j].Exercise })
+	return list
+}
+
+// Unlock 记录练习被解锁的时间，返回是否是新解锁的；已经解锁过时保留原来的时间
+func (s *Store) Unlock(exercise string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.unlocked[exercise]; ok {
+		return false
+	}
+	s.unlocked[exercise] = at
+	return true
+}
+
+// UnlockedAt 练习被解锁的时间，没有解锁记录时返回false
+func (s *Store) UnlockedAt(exercise string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok := s.unlocked[exercise]
+	return at, ok
+}
+
+// Reset 删除一个练习的进度和解锁记录，exercise为空时删除全部
+func (s *Store) Reset(exercise string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if exercise == "" {
+		s.records = make(map[string]*Record)
+		s.unlocked = make(map[string]time.Time)
+		return
+	}
+	delete(s.records, exercise)
+	delete(s.unlocked, exercise)
+}
+
+// Save 写回进度文件
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(fileFormat{Version: version, Exercises: s.records, Unlocked: s.unlocked}, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // 改名成功后这里删除的是不存在的文件
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,128 @@
+// Package pubsub 提供发布订阅示例共用的组件：带溢出策略的订阅者邮箱 Mailbox、
+// 订阅模式集合 Topics、发布者的订阅者列表 Registry，
+// 以及基于它们的强类型泛型 Publisher[T] / Subscriber[T]。
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy 订阅者消息队列已满时的处理策略
+type OverflowPolicy int
+
+const (
+	DropNewest OverflowPolicy = iota // 丢弃新消息（默认）
+	DropOldest                       // 丢弃队列中最旧的消息，为新消息腾出位置
+	Block                            // 阻塞发布者，直到订阅者腾出空间
+	Disconnect                       // 断开慢订阅者
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropNewest:
+		return "DROP_NEWEST"
+	case DropOldest:
+		return "DROP_OLDEST"
+	case Block:
+		return "BLOCK"
+	case Disconnect:
+		return "DISCONNECT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// DeliverResult 一次投递的结果
+type DeliverResult int
+
+const (
+	Delivered    DeliverResult = iota // 消息进入队列
+	Dropped                           // 队列已满，新消息被丢弃
+	Disconnected                      // 队列已满，订阅者被断开
+	Closed                            // 邮箱已关闭
+)
+
+// Mailbox 包装订阅者的消息channel，按溢出策略投递，并保证关闭后不会再发送
+type Mailbox[T any] struct {
+	ch      chan T
+	policy  OverflowPolicy
+	mu      sync.Mutex // 串行化发送与关闭，避免向已关闭的channel发送
+	closed  bool
+	dropped int64 // 因队列已满被丢弃的消息数
+}
+
+// NewMailbox 使用已有的channel创建邮箱
+func NewMailbox[T any](ch chan T, policy OverflowPolicy) *Mailbox[T] {
+	return &Mailbox[T]{ch: ch, policy: policy}
+}
+
+// C 返回消息channel，邮箱关闭后channel也会被关闭
+func (m *Mailbox[T]) C() <-chan T {
+	return m.ch
+}
+
+// Policy 返回溢出策略
+func (m *Mailbox[T]) Policy() OverflowPolicy {
+	return m.policy
+}
+
+// Deliver 按溢出策略投递消息
+func (m *Mailbox[T]) Deliver(v T) DeliverResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return Closed
+	}
+
+	select {
+	case m.ch <- v:
+		return Delivered
+	default:
+	}
+
+	// 队列已满，按策略处理
+	switch m.policy {
+	case Block:
+		m.ch <- v
+		return Delivered
+	case DropOldest:
+		for {
+			select {
+			case <-m.ch:
+				atomic.AddInt64(&m.dropped, 1)
+			default:
+			}
+			select {
+			case m.ch <- v:
+				return Delivered
+			default:
+			}
+		}
+	case Disconnect:
+		atomic.AddInt64(&m.dropped, 1)
+		m.closed = true
+		close(m.ch)
+		return Disconnected
+	default:
+		atomic.AddInt64(&m.dropped, 1)
+		return Dropped
+	}
+}
+
+// Close 关闭邮箱，可重复调用
+func (m *Mailbox[T]) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.ch)
+	}
+}
+
+// Dropped 返回被丢弃的消息数
+func (m *Mailbox[T]) Dropped() int64 {
+	return atomic.LoadInt64(&m.dropped)
+}
@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"time"
+)
+
+// Message 强类型消息
+type Message[T any] struct {
+	Topic   string
+	Payload T
+	Time    time.Time
+}
+
+// Subscriber 强类型订阅者，主题支持通配符模式
+type Subscriber[T any] struct {
+	ID      int
+	mailbox *Mailbox[Message[T]]
+	topics  Topics[T]
+}
+
+// NewSubscriber 创建订阅者
+func NewSubscriber[T any](id int, bufferSize int, policy OverflowPolicy) *Subscriber[T] {
+	return &Subscriber[T]{
+		ID:      id,
+		mailbox: NewMailbox(make(chan Message[T], bufferSize), policy),
+	}
+}
+
+// Messages 返回消息channel，订阅者被移除或发布者关闭后channel会被关闭
+func (s *Subscriber[T]) Messages() <-chan Message[T] {
+	return s.mailbox.C()
+}
+
+// Subscribe 订阅主题，模式不合法时返回false
+func (s *Subscriber[T]) Subscribe(pattern string) bool {
+	return s.topics.Subscribe(pattern)
+}
+
+// SubscribeFunc 带过滤条件订阅主题，只接收predicate对负载返回true的消息
+func (s *Subscriber[T]) SubscribeFunc(pattern string, predicate func(T) bool) bool {
+	return s.topics.SubscribeFunc(pattern, predicate)
+}
+
+// Unsubscribe 取消订阅主题
+func (s *Subscriber[T]) Unsubscribe(pattern string) bool {
+	return s.topics.Unsubscribe(pattern)
+}
+
+// IsSubscribed 检查主题是否匹配任一订阅模式
+func (s *Subscriber[T]) IsSubscribed(topic string) bool {
+	return s.topics.Matches(topic)
+}
+
+// Policy 返回溢出策略
+func (s *Subscriber[T]) Policy() OverflowPolicy {
+	return s.mailbox.Policy()
+}
+
+// Dropped 返回被丢弃的消息数
+func (s *Subscriber[T]) Dropped() int64 {
+	return s.mailbox.Dropped()
+}
+
+// SubscriberID 实现Member
+func (s *Subscriber[T]) SubscriberID() int {
+	return s.ID
+}
+
+// Close 关闭消息channel，之后的投递返回Closed；通常由发布者调用
+func (s *Subscriber[T]) Close() {
+	s.mailbox.Close()
+}
+
+// Publisher 强类型发布者
+type Publisher[T any] struct {
+	subscribers Registry[*Subscriber[T]]
+}
+
+// NewPublisher 创建发布者
+func NewPublisher[T any]() *Publisher[T] {
+	return &Publisher[T]{}
+}
+
+// AddSubscriber 添加订阅者
+func (p *Publisher[T]) AddSubscriber(sub *Subscriber[T]) {
+	p.subscribers.Add(sub)
+}
+
+// RemoveSubscriber 移除订阅者并关闭其消息channel
+func (p *Publisher[T]) RemoveSubscriber(id int) bool {
+	return p.subscribers.Remove(id)
+}
+
+// Publish 发布消息，返回每个匹配订阅者的投递结果
+func (p *Publisher[T]) Publish(topic string, payload T) map[int]DeliverResult {
+	msg := Message[T]{
+		Topic:   topic,
+		Payload: payload,
+		Time:    time.Now(),
+	}
+
+	results := make(map[int]DeliverResult)
+	p.subscribers.Read(func(subs []*Subscriber[T]) {
+		for _, sub := range subs {
+			if sub.topics.Accepts(topic, payload) {
+				results[sub.ID] = sub.mailbox.Deliver(msg)
+			}
+		}
+	})
+	return results
+}
+
+// Close 关闭所有订阅者的消息channel
+func (p *Publisher[T]) Close() {
+	p.subscribers.CloseAll()
+}
@@ -0,0 +1,238 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPublishWhileRemoving 多个发布者并发发布，同时移除订阅者、订阅者反复取消和重新订阅：
+// 不能向已关闭的channel发送（会panic），移除之后不再投递，
+// 每个订阅者读到的消息数等于Publish报告投递成功的次数
+func TestPublishWhileRemoving(t *testing.T) {
+	const subscribers, publishers, perPublisher = 10, 4, 200
+	p := NewPublisher[string]()
+
+	received := make([]atomic.Int64, subscribers)
+	var readers sync.WaitGroup
+	for id := 0; id < subscribers; id++ {
+		policy := DropNewest
+		if id%2 == 0 {
+			policy = Block
+		}
+		sub := NewSubscriber[string](id, 4, policy)
+		sub.Subscribe("churn.*")
+		p.AddSubscriber(sub)
+
+		readers.Add(1)
+		go func(id int) {
+			defer readers.Done()
+			for range sub.Messages() {
+				received[id].Add(1)
+			}
+		}(id)
+
+		// 订阅者自己反复取消和重新订阅，与Publish中的IsSubscribed并发
+		go func() {
+			for i := 0; i < 50; i++ {
+				sub.Unsubscribe("churn.*")
+				sub.Subscribe("churn.*")
+			}
+		}()
+	}
+
+	var (
+		mu        sync.Mutex
+		delivered = make([]int64, subscribers)
+		removed   = make([]atomic.Bool, subscribers)
+	)
+	var pubs sync.WaitGroup
+	for g := 0; g < publishers; g++ {
+		pubs.Add(1)
+		go func(g int) {
+			defer pubs.Done()
+			for i := 0; i < perPublisher; i++ {
+				// 在Publish之前记下已经移除完成的订阅者，它们不能出现在这次的结果中
+				var gone []int
+				for id := range removed {
+					if removed[id].Load() {
+						gone = append(gone, id)
+					}
+				}
+				results := p.Publish(fmt.Sprintf("churn.%d", g), "msg")
+				for _, id := range gone {
+					if _, ok := results[id]; ok {
+						t.Errorf("订阅者 %d 移除之后仍然被投递", id)
+					}
+				}
+				mu.Lock()
+				for id, r := range results {
+					if r == Delivered {
+						delivered[id]++
+					}
+				}
+				mu.Unlock()
+			}
+		}(g)
+	}
+
+	for id := 1; id < subscribers; id += 2 {
+		time.Sleep(time.Millisecond)
+		if !p.RemoveSubscriber(id) {
+			t.Fatalf("RemoveSubscriber(%d) = false", id)
+		}
+		removed[id].Store(true)
+		if p.RemoveSubscriber(id) {
+			t.Fatalf("重复RemoveSubscriber(%d) = true", id)
+		}
+	}
+
+	pubs.Wait()
+	p.Close()
+	done := make(chan struct{})
+	go func() {
+		readers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close之后订阅者的channel没有全部关闭")
+	}
+
+	for id := range received {
+		if got := received[id].Load(); got != delivered[id] {
+			t.Errorf("订阅者 %d 读到 %d 条消息，Publish报告投递 %d 条", id, got, delivered[id])
+		}
+	}
+}
+
+func TestMailboxOverflow(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		want    DeliverResult // 队列已满时第三条消息的投递结果
+		queue   []int         // 之后队列中剩下的消息
+		dropped int64
+	}{
+		{DropNewest, Dropped, []int{1, 2}, 1},
+		{DropOldest, Delivered, []int{2, 3}, 1},
+		{Disconnect, Disconnected, []int{1, 2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			m := NewMailbox(make(chan int, 2), tt.policy)
+			m.Deliver(1)
+			m.Deliver(2)
+			if got := m.Deliver(3); got != tt.want {
+				t.Fatalf("Deliver(3) = %v, want %v", got, tt.want)
+			}
+			if tt.policy != Disconnect {
+				m.Close()
+			}
+			var queue []int
+			for v := range m.C() {
+				queue = append(queue, v)
+			}
+			if fmt.Sprint(queue) != fmt.Sprint(tt.queue) || m.Dropped() != tt.dropped {
+				t.Fatalf("队列 %v，丢弃 %d, want %v, %d", queue, m.Dropped(), tt.queue, tt.dropped)
+			}
+			if got := m.Deliver(4); got != Closed {
+				t.Fatalf("关闭后Deliver() = %v, want Closed", got)
+			}
+		})
+	}
+}
+
+// TestMailboxBlockUnblocksOnRead Block策略阻塞发布者，直到订阅者读走一条消息
+func TestMailboxBlockUnblocksOnRead(t *testing.T) {
+	m := NewMailbox(make(chan int, 1), Block)
+	m.Deliver(1)
+
+	result := make(chan DeliverResult)
+	go func() { result <- m.Deliver(2) }()
+	select {
+	case <-result:
+		t.Fatal("队列已满时Block策略没有阻塞")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	<-m.C()
+	select {
+	case r := <-result:
+		if r != Delivered {
+			t.Fatalf("Deliver(2) = %v, want Delivered", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("读走消息后发布者仍然阻塞")
+	}
+}
+
+func TestTopics(t *testing.T) {
+	var topics Topics[int]
+	if topics.Subscribe("bad..pattern") || topics.Unsubscribe("news") {
+		t.Fatal("不合法的模式或没有订阅的模式返回true")
+	}
+	topics.Subscribe("news.*")
+	topics.SubscribeFunc("tech.#", func(v int) bool { return v > 10 })
+
+	tests := []struct {
+		topic   string
+		v       int
+		matches bool
+		accepts bool
+	}{
+		{"news.local", 1, true, true},
+		{"tech.go.release", 20, true, true},
+		{"tech.go.release", 5, true, false}, // 主题匹配但不满足过滤条件
+		{"sports", 20, false, false},
+	}
+	for _, tt := range tests {
+		if got := topics.Matches(tt.topic); got != tt.matches {
+			t.Errorf("Matches(%q) = %v, want %v", tt.topic, got, tt.matches)
+		}
+		if got := topics.Accepts(tt.topic, tt.v); got != tt.accepts {
+			t.Errorf("Accepts(%q, %d) = %v, want %v", tt.topic, tt.v, got, tt.accepts)
+		}
+	}
+
+	// 重新普通订阅清除过滤条件，取消订阅后不再匹配
+	topics.Subscribe("tech.#")
+	if !topics.Accepts("tech.go", 5) {
+		t.Error("重新订阅后过滤条件仍然生效")
+	}
+	if !topics.Unsubscribe("tech.#") || topics.Matches("tech.go") {
+		t.Error("取消订阅后仍然匹配")
+	}
+}
+
+// TestRegistryAddThen AddThen中的fn执行期间Read被阻塞，fn里投递的消息排在之后发布的消息前面
+func TestRegistryAddThen(t *testing.T) {
+	p := NewPublisher[int]()
+	sub := NewSubscriber[int](1, 10, Block)
+	sub.Subscribe("t")
+
+	published := make(chan struct{})
+	added := p.subscribers.AddThen(sub, func(added bool) {
+		go func() {
+			p.Publish("t", 2)
+			close(published)
+		}()
+		time.Sleep(10 * time.Millisecond) // 给Publish足够的时间，它应该等待写锁释放
+		sub.mailbox.Deliver(Message[int]{Topic: "t", Payload: 1})
+	})
+	if !added || p.subscribers.Add(sub) {
+		t.Fatal("AddThen应该新添加订阅者，之后的Add不重复添加")
+	}
+	<-published
+	p.Close()
+
+	var got []int
+	for msg := range sub.Messages() {
+		got = append(got, msg.Payload)
+	}
+	if fmt.Sprint(got) != "[1 2]" {
+		t.Fatalf("收到 %v, want [1 2]", got)
+	}
+}
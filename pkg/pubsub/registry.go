@@ -0,0 +1,146 @@
+package pubsub
+
+import (
+	"sync"
+
+	"github.com/klsakura/day1/pkg/topicmatch"
+)
+
+// Topics 订阅者的订阅模式集合，订阅、取消订阅和匹配可以并发进行；零值可用
+// 每个模式可以带一个过滤条件，只接受满足条件的消息
+type Topics[M any] struct {
+	mu      sync.RWMutex
+	filters map[string]func(M) bool // 订阅模式 -> 过滤条件，nil表示接受该模式下的全部消息
+}
+
+// Subscribe 订阅模式，已有的过滤条件被清除；模式不合法时返回false
+func (t *Topics[M]) Subscribe(pattern string) bool {
+	return t.SubscribeFunc(pattern, nil)
+}
+
+// SubscribeFunc 带过滤条件订阅模式，predicate为nil时等同于Subscribe；模式不合法时返回false
+func (t *Topics[M]) SubscribeFunc(pattern string, predicate func(M) bool) bool {
+	if !topicmatch.Valid(pattern) {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.filters == nil {
+		t.filters = make(map[string]func(M) bool)
+	}
+	t.filters[pattern] = predicate
+	return true
+}
+
+// Unsubscribe 取消订阅模式（需与订阅时的模式完全一致），没有订阅时返回false
+func (t *Topics[M]) Unsubscribe(pattern string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.filters[pattern]; !ok {
+		return false
+	}
+	delete(t.filters, pattern)
+	return true
+}
+
+// Matches 主题是否匹配任一订阅模式，不考虑过滤条件
+func (t *Topics[M]) Matches(topic string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for pattern := range t.filters {
+		if topicmatch.Match(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Accepts 主题匹配某个订阅模式，且满足该模式的过滤条件
+func (t *Topics[M]) Accepts(topic string, m M) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for pattern, filter := range t.filters {
+		if topicmatch.Match(pattern, topic) && (filter == nil || filter(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Member 可以放入Registry的订阅者
+type Member interface {
+	comparable
+	SubscriberID() int
+	Close() // 关闭订阅者的消息channel，可重复调用
+}
+
+// Registry 发布者持有的订阅者列表；零值可用
+//
+// Read在读锁下访问列表，发布消息时用它遍历订阅者；Add、Remove持有写锁，会等待正在进行的Read结束。
+// 因此Remove返回后不会再有发布看到被移除的订阅者，Remove关闭订阅者的channel也不会与投递竞争。
+type Registry[S Member] struct {
+	mu   sync.RWMutex
+	subs []S
+}
+
+// Add 添加订阅者，已经在列表中时不重复添加；返回是否新添加
+func (r *Registry[S]) Add(s S) bool {
+	return r.AddThen(s, nil)
+}
+
+// AddThen 在同一次写锁内添加订阅者并调用fn（fn可以为nil），fn执行期间没有Read，
+// 例如先回放历史消息，保证它们排在之后发布的消息前面；fn中不能再调用Registry的方法
+func (r *Registry[S]) AddThen(s S, fn func(added bool)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	added := true
+	for _, sub := range r.subs {
+		if sub == s {
+			added = false
+			break
+		}
+	}
+	if added {
+		r.subs = append(r.subs, s)
+	}
+	if fn != nil {
+		fn(added)
+	}
+	return added
+}
+
+// Remove 移除ID为id的订阅者并关闭它的channel，不存在时返回false
+func (r *Registry[S]) Remove(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, sub := range r.subs {
+		if sub.SubscriberID() == id {
+			r.subs = append(r.subs[:i], r.subs[i+1:]...)
+			sub.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// Read 在读锁下调用fn，fn可以向订阅者投递消息，但不能修改subs或调用Registry的其他方法
+func (r *Registry[S]) Read(fn func(subs []S)) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn(r.subs)
+}
+
+// CloseAll 关闭所有订阅者的channel，订阅者仍留在列表中
+func (r *Registry[S]) CloseAll() {
+	r.Read(func(subs []S) {
+		for _, sub := range subs {
+			sub.Close()
+		}
+	})
+}
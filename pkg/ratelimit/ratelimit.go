@@ -0,0 +1,616 @@
+// Package ratelimit 提供几种限流器和按key限流、HTTP中间件。
+//
+//   - TokenBucket 令牌桶：按固定速率补充令牌，允许不超过容量的突发，支持预订（Reserve）和运行时调速
+//   - SlidingWindow 滑动窗口：任意连续的窗口时间内最多通过limit个请求
+//   - LeakyBucket 漏桶：按固定间隔均匀放行，不允许突发
+//   - Adaptive AIMD自适应限流：根据下游的错误和延迟调整速率
+//
+// Keyed 按key分别限流并回收空闲的key；Middleware 用任意Limiter保护http.Handler。
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrLimiterClosed 限流器已关闭，不会再产生令牌
+var ErrLimiterClosed = errors.New("rate limiter closed")
+
+// Limiter 各种限流器的公共接口，便于在演示中对比
+type Limiter interface {
+	Allow() bool
+	Wait()
+}
+
+// TokenBucket 令牌桶限流器：后台协程按固定间隔向容量为burst的桶中补充令牌，用完后需要Close
+type TokenBucket struct {
+	tokens chan struct{}
+	ticker *time.Ticker
+	done   chan bool
+
+	mu         sync.Mutex
+	interval   time.Duration // 补充一个令牌的间隔
+	lastRefill time.Time     // 最近一次补充的时间，用于推算下一个令牌的到达时间
+	// Reserve预订的未来令牌按先后顺序编号：claimed是累计预订数，repaid是累计偿还数，
+	// 两者之差是尚未偿还的欠账，补充时先偿还
+	claimed, repaid int
+}
+
+// NewTokenBucket 创建令牌桶：每秒补充rate个令牌，桶容量为burst
+// rate可以是小数，例如0.5表示每2秒补充1个令牌
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 || burst <= 0 {
+		panic("rate limiter: rate and burst must be positive")
+	}
+
+	rl := &TokenBucket{
+		tokens: make(chan struct{}, burst),
+		ticker: time.NewTicker(time.Duration(float64(time.Second) / rate)),
+		done:   make(chan bool),
+
+		interval:   time.Duration(float64(time.Second) / rate),
+		lastRefill: time.Now(),
+	}
+
+	// 初始填充tokens
+	for i := 0; i < burst; i++ {
+		rl.tokens <- struct{}{}
+	}
+
+	// 启动token补充器
+	go rl.refill()
+
+	return rl
+}
+
+func (rl *TokenBucket) refill() {
+	for {
+		select {
+		case <-rl.ticker.C:
+			rl.tick()
+		case <-rl.done:
+			return
+		}
+	}
+}
+
+// tick 补充一个令牌：有欠账时偿还最早的预订，否则放入桶中
+func (rl *TokenBucket) tick() {
+	rl.mu.Lock()
+	rl.lastRefill = time.Now()
+	if rl.repaid < rl.claimed {
+		// 这个令牌已经被预订
+		rl.repaid++
+		rl.mu.Unlock()
+		return
+	}
+	rl.mu.Unlock()
+
+	select {
+	case rl.tokens <- struct{}{}:
+	default:
+		// token池已满，跳过
+	}
+}
+
+func (rl *TokenBucket) Allow() bool {
+	select {
+	case <-rl.tokens:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait 阻塞直到拿到令牌；限流器关闭后立即返回，不会永远阻塞
+func (rl *TokenBucket) Wait() {
+	select {
+	case <-rl.tokens:
+	case <-rl.done:
+	}
+}
+
+// WaitCtx 等待令牌，ctx取消或限流器关闭时立即返回错误，调用者放弃后不会一直阻塞
+func (rl *TokenBucket) WaitCtx(ctx context.Context) error {
+	select {
+	case <-rl.tokens:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rl.done:
+		return ErrLimiterClosed
+	}
+}
+
+// WaitTimeout 最多等待timeout，拿到令牌返回true
+func (rl *TokenBucket) WaitTimeout(timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return rl.WaitCtx(ctx) == nil
+}
+
+// Reservation 预订令牌的结果，类似golang.org/x/time/rate的Reservation
+type Reservation struct {
+	ok        bool
+	limiter   *TokenBucket
+	taken     int       // 预订时立即从桶中取走的令牌数
+	owed      int       // 需要等待未来补充的令牌数
+	claimEnd  int       // 预订后的claimed：编号[claimEnd-owed, claimEnd)的未来令牌属于这次预订
+	timeToAct time.Time // 所有令牌到齐的时间
+	canceled  bool      // 由limiter.mu保护
+}
+
+// OK 预订是否成功，n超过桶容量时永远无法满足
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 还需要等待多久才能使用预订的令牌
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return -1
+	}
+	if d := time.Until(r.timeToAct); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel 放弃预订，在令牌到齐之前取消会把令牌还给限流器
+// 之后的预订已经排在这次预订的令牌后面、按它计算了等待时间时，令牌不归还，否则会多放行
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	r.limiter.cancel(r)
+}
+
+// Reserve 预订n个令牌，不阻塞，返回需要等待的时间
+// 桶中已有的令牌立即取走，不足的部分记为欠账，由后续补充优先偿还
+func (rl *TokenBucket) Reserve(n int) *Reservation {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if n <= 0 || n > cap(rl.tokens) {
+		return &Reservation{ok: false}
+	}
+
+	taken := 0
+	for ; taken < n; taken++ {
+		if !rl.Allow() {
+			break
+		}
+	}
+
+	owed := n - taken
+	timeToAct := time.Now()
+	if owed > 0 {
+		rl.claimed += owed
+		// 下一个令牌在lastRefill+interval到达，之后每interval一个，先偿还更早的欠账
+		timeToAct = rl.lastRefill.Add(time.Duration(rl.claimed-rl.repaid) * rl.interval)
+	}
+
+	return &Reservation{ok: true, limiter: rl, taken: taken, owed: owed, claimEnd: rl.claimed, timeToAct: timeToAct}
+}
+
+// cancel 归还取消的预订：勾销还没偿还的欠账，取走的和已经偿还的令牌放回桶中
+// 只有之后没有新的欠账时才归还，之后的预订的到达时间是排在这次预订后面算出来的
+func (rl *TokenBucket) cancel(r *Reservation) {
+	rl.mu.Lock()
+	if r.canceled || !time.Now().Before(r.timeToAct) || r.claimEnd != rl.claimed {
+		rl.mu.Unlock()
+		return
+	}
+	r.canceled = true
+	unpaid := min(r.owed, rl.claimed-rl.repaid)
+	rl.claimed -= unpaid
+	refund := r.taken + r.owed - unpaid
+	rl.mu.Unlock()
+
+	for i := 0; i < refund; i++ {
+		select {
+		case rl.tokens <- struct{}{}:
+		default:
+			return
+		}
+	}
+}
+
+// SetRate 运行时调整补充速率
+// 只重置现有ticker的周期：桶里已积攒的令牌和补充协程都保持不变，不会丢令牌也不会泄露协程
+func (rl *TokenBucket) SetRate(rate float64) {
+	if rate <= 0 {
+		panic("rate limiter: rate must be positive")
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.interval = time.Duration(float64(time.Second) / rate)
+	rl.ticker.Reset(rl.interval)
+}
+
+// RetryAfter 估算下一个令牌到达还需多久
+func (rl *TokenBucket) RetryAfter() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return time.Until(rl.lastRefill.Add(time.Duration(rl.claimed-rl.repaid+1) * rl.interval))
+}
+
+func (rl *TokenBucket) Close() {
+	rl.ticker.Stop()
+	close(rl.done)
+}
+
+// SlidingWindow 滑动窗口限流器：任意连续的window时间内最多允许limit个请求
+// 记录每个通过请求的时间戳，判断时先清理窗口外的记录
+type SlidingWindow struct {
+	limit      int
+	window     time.Duration
+	mu         sync.Mutex
+	timestamps []time.Time // 窗口内已通过请求的时间，按时间升序
+}
+
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		limit:      limit,
+		window:     window,
+		timestamps: make([]time.Time, 0, limit),
+	}
+}
+
+// evict 清理窗口外的时间戳，调用者需持有锁
+func (sw *SlidingWindow) evict(now time.Time) {
+	cutoff := now.Add(-sw.window)
+	i := 0
+	for i < len(sw.timestamps) && !sw.timestamps[i].After(cutoff) {
+		i++
+	}
+	sw.timestamps = sw.timestamps[i:]
+}
+
+// reserve 尝试通过一个请求，失败时返回还需要等待的时间
+func (sw *SlidingWindow) reserve() (bool, time.Duration) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now()
+	sw.evict(now)
+
+	if len(sw.timestamps) < sw.limit {
+		sw.timestamps = append(sw.timestamps, now)
+		return true, 0
+	}
+
+	// 最早的请求滑出窗口后才有空位
+	return false, sw.timestamps[0].Add(sw.window).Sub(now)
+}
+
+func (sw *SlidingWindow) Allow() bool {
+	ok, _ := sw.reserve()
+	return ok
+}
+
+// RetryAfter 窗口已满时，最早的请求滑出窗口还需多久
+func (sw *SlidingWindow) RetryAfter() time.Duration {
+	_, wait := sw.peek()
+	return wait
+}
+
+// peek 不占用名额地判断当前窗口还能否通过
+func (sw *SlidingWindow) peek() (bool, time.Duration) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now()
+	sw.evict(now)
+	if len(sw.timestamps) < sw.limit {
+		return true, 0
+	}
+	return false, sw.timestamps[0].Add(sw.window).Sub(now)
+}
+
+func (sw *SlidingWindow) Wait() {
+	for {
+		ok, wait := sw.reserve()
+		if ok {
+			return
+		}
+		time.Sleep(wait)
+	}
+}
+
+// LeakyBucket 漏桶限流器：请求以固定间隔均匀放行，不允许突发
+// 只记录下一个允许通过的时间点，Wait直接计算需要睡眠多久
+type LeakyBucket struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time // 下一个请求最早可以通过的时间
+}
+
+func NewLeakyBucket(rate int) *LeakyBucket {
+	return &LeakyBucket{interval: time.Second / time.Duration(rate)}
+}
+
+func (lb *LeakyBucket) Allow() bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(lb.next) {
+		return false
+	}
+	lb.next = now.Add(lb.interval)
+	return true
+}
+
+// RetryAfter 距下一个允许通过的时间点还需多久
+func (lb *LeakyBucket) RetryAfter() time.Duration {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return time.Until(lb.next)
+}
+
+// Wait 预约下一个空闲时间点，多个等待者依次排在后面，各自间隔interval
+func (lb *LeakyBucket) Wait() {
+	lb.mu.Lock()
+	now := time.Now()
+	at := lb.next
+	if at.Before(now) {
+		at = now
+	}
+	lb.next = at.Add(lb.interval)
+	lb.mu.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
+
+// Adaptive AIMD自适应限流器：下游正常时速率加性增长，出错或变慢时乘性下降
+// 和TCP拥塞控制的思路一样，在不知道下游真实容量时自动逼近它
+type Adaptive struct {
+	mu   sync.Mutex
+	rate float64   // 当前每秒允许的请求数
+	next time.Time // 下一个请求最早可以通过的时间，按当前速率均匀放行
+
+	minRate, maxRate float64
+	increase         float64       // 每次成功增加的速率
+	decreaseFactor   float64       // 每次失败速率乘以的系数
+	latencyThreshold time.Duration // 响应慢于此值也视为过载
+	lastDecrease     time.Time     // 同一批并发失败只下降一次
+}
+
+func NewAdaptive(initialRate, minRate, maxRate float64, latencyThreshold time.Duration) *Adaptive {
+	return &Adaptive{
+		rate:             initialRate,
+		minRate:          minRate,
+		maxRate:          maxRate,
+		increase:         0.5,
+		decreaseFactor:   0.5,
+		latencyThreshold: latencyThreshold,
+	}
+}
+
+func (al *Adaptive) interval() time.Duration {
+	return time.Duration(float64(time.Second) / al.rate)
+}
+
+func (al *Adaptive) Allow() bool {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(al.next) {
+		return false
+	}
+	al.next = now.Add(al.interval())
+	return true
+}
+
+func (al *Adaptive) Wait() {
+	al.mu.Lock()
+	now := time.Now()
+	at := al.next
+	if at.Before(now) {
+		at = now
+	}
+	al.next = at.Add(al.interval())
+	al.mu.Unlock()
+
+	time.Sleep(at.Sub(now))
+}
+
+// Report 上报一次受保护调用的结果，据此调整速率
+func (al *Adaptive) Report(err error, latency time.Duration) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	overloaded := err != nil || (al.latencyThreshold > 0 && latency > al.latencyThreshold)
+	if !overloaded {
+		al.rate += al.increase
+		if al.rate > al.maxRate {
+			al.rate = al.maxRate
+		}
+		return
+	}
+
+	// 在途请求可能同时失败，一个当前间隔内只降速一次，避免速率瞬间跌到底
+	now := time.Now()
+	if now.Sub(al.lastDecrease) < al.interval() {
+		return
+	}
+	al.lastDecrease = now
+	al.rate *= al.decreaseFactor
+	if al.rate < al.minRate {
+		al.rate = al.minRate
+	}
+}
+
+// Rate 返回当前速率
+func (al *Adaptive) Rate() float64 {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.rate
+}
+
+// keyedEntry 单个key的限流器、最近一次访问时间和正在使用它的调用数
+type keyedEntry struct {
+	limiter  Limiter
+	lastSeen time.Time
+	inflight int // 大于0时不回收，否则阻塞在Wait中的调用方会随限流器关闭而永远等待
+}
+
+// Keyed 按key（用户ID、IP等）分别限流，每个key的限流器在首次访问时创建
+// 后台清理协程定期回收空闲超过idleTTL的key，避免map随客户端数量无限增长
+type Keyed struct {
+	newLimiter func() Limiter
+	idleTTL    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*keyedEntry
+	created int64
+	evicted int64
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewKeyed(newLimiter func() Limiter, idleTTL, cleanupInterval time.Duration) *Keyed {
+	kl := &Keyed{
+		newLimiter: newLimiter,
+		idleTTL:    idleTTL,
+		entries:    make(map[string]*keyedEntry),
+		done:       make(chan struct{}),
+	}
+	go kl.cleanupLoop(cleanupInterval)
+	return kl
+}
+
+// acquire 返回key对应的条目并登记一次使用，不存在时创建；用完后调用release
+func (kl *Keyed) acquire(key string) *keyedEntry {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	e, ok := kl.entries[key]
+	if !ok {
+		e = &keyedEntry{limiter: kl.newLimiter()}
+		kl.entries[key] = e
+		kl.created++
+	}
+	e.lastSeen = time.Now()
+	e.inflight++
+	return e
+}
+
+// release 结束一次使用，空闲时间从最后一个调用方离开时开始计算
+func (kl *Keyed) release(e *keyedEntry) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	e.inflight--
+	e.lastSeen = time.Now()
+}
+
+func (kl *Keyed) Allow(key string) bool {
+	e := kl.acquire(key)
+	defer kl.release(e)
+	return e.limiter.Allow()
+}
+
+func (kl *Keyed) Wait(key string) {
+	e := kl.acquire(key)
+	defer kl.release(e)
+	e.limiter.Wait()
+}
+
+func (kl *Keyed) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			kl.evictIdle()
+		case <-kl.done:
+			return
+		}
+	}
+}
+
+// evictIdle 删除空闲超时且没有调用方在使用的key，并关闭持有后台资源的限流器
+func (kl *Keyed) evictIdle() {
+	cutoff := time.Now().Add(-kl.idleTTL)
+
+	var idle []Limiter
+	kl.mu.Lock()
+	for key, e := range kl.entries {
+		if e.inflight == 0 && e.lastSeen.Before(cutoff) {
+			delete(kl.entries, key)
+			idle = append(idle, e.limiter)
+			kl.evicted++
+		}
+	}
+	kl.mu.Unlock()
+
+	for _, l := range idle {
+		closeLimiter(l)
+	}
+}
+
+// closeLimiter 令牌桶有补充协程，回收时需要Close，否则协程泄露
+func closeLimiter(l Limiter) {
+	if c, ok := l.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+// Stats 返回当前key数量、累计创建数和累计回收数
+func (kl *Keyed) Stats() (int, int64, int64) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	return len(kl.entries), kl.created, kl.evicted
+}
+
+// Close 停止清理协程并关闭所有限流器
+func (kl *Keyed) Close() {
+	kl.stopOnce.Do(func() {
+		close(kl.done)
+
+		kl.mu.Lock()
+		entries := kl.entries
+		kl.entries = make(map[string]*keyedEntry)
+		kl.mu.Unlock()
+
+		for _, e := range entries {
+			closeLimiter(e.limiter)
+		}
+	})
+}
+
+// Middleware 用限流器包装http.Handler，超过限制时返回429
+// 限流器实现了RetryAfter时，按其估算设置Retry-After头（秒，向上取整），否则为1秒
+func Middleware(l Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.Allow() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			retry := time.Second
+			if ra, ok := l.(interface{ RetryAfter() time.Duration }); ok {
+				retry = ra.RetryAfter()
+			}
+			seconds := int(math.Ceil(retry.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		})
+	}
+}
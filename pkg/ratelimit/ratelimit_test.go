@@ -0,0 +1,246 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestKeyedWaitOutlivesIdleTTL 等待时间超过idleTTL时，清理协程不能回收正在使用的限流器，
+// 否则Wait会随限流器关闭而永远阻塞
+func TestKeyedWaitOutlivesIdleTTL(t *testing.T) {
+	// 每秒5个令牌：取走唯一的令牌后下一次Wait要等约200ms，远大于idleTTL
+	kl := NewKeyed(func() Limiter { return NewTokenBucket(5, 1) }, 10*time.Millisecond, 5*time.Millisecond)
+	defer kl.Close()
+
+	if !kl.Allow("client") {
+		t.Fatal("第一次Allow应该通过")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		kl.Wait("client")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait在限流器被回收后挂起")
+	}
+
+	if keys, _, evicted := kl.Stats(); keys != 1 || evicted != 0 {
+		t.Fatalf("Stats() = %d keys, %d evicted, want 1 keys, 0 evicted", keys, evicted)
+	}
+}
+
+// TestKeyedEvictsAfterLastCaller 最后一个调用方离开后才开始计算空闲时间
+func TestKeyedEvictsAfterLastCaller(t *testing.T) {
+	kl := NewKeyed(func() Limiter { return NewTokenBucket(100, 1) }, 10*time.Millisecond, 5*time.Millisecond)
+	defer kl.Close()
+
+	kl.Wait("client")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if keys, _, evicted := kl.Stats(); keys == 0 && evicted == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("空闲的key没有被回收")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestTokenBucketWaitReturnsOnClose Close会唤醒阻塞在Wait中的调用方
+func TestTokenBucketWaitReturnsOnClose(t *testing.T) {
+	rl := NewTokenBucket(0.1, 1)
+	rl.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		rl.Wait()
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	rl.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close之后Wait仍然阻塞")
+	}
+}
+
+// drain 连续调用Allow直到被拒绝，返回通过的次数
+func drain(l Limiter) int {
+	n := 0
+	for l.Allow() {
+		n++
+		if n > 1000 {
+			break
+		}
+	}
+	return n
+}
+
+// TestRefill 用完突发容量后立即被拒绝，经过一个补充周期后重新放行，空闲再久也不超过突发容量
+func TestRefill(t *testing.T) {
+	tests := []struct {
+		name   string
+		new    func() Limiter
+		burst  int           // 一开始能连续通过的请求数
+		refill time.Duration // 恢复至少一个名额需要的时间
+	}{
+		{"令牌桶", func() Limiter { return NewTokenBucket(20, 3) }, 3, 50 * time.Millisecond},
+		{"滑动窗口", func() Limiter { return NewSlidingWindow(3, 50*time.Millisecond) }, 3, 50 * time.Millisecond},
+		{"漏桶", func() Limiter { return NewLeakyBucket(20) }, 1, 50 * time.Millisecond},
+		{"自适应", func() Limiter { return NewAdaptive(20, 1, 100, 0) }, 1, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := tt.new()
+			defer closeLimiter(l)
+
+			if got := drain(l); got != tt.burst {
+				t.Fatalf("一开始通过 %d 个请求, want %d", got, tt.burst)
+			}
+			if l.Allow() {
+				t.Fatal("名额用完后Allow() = true")
+			}
+
+			time.Sleep(tt.refill + tt.refill/2)
+			if !l.Allow() {
+				t.Fatalf("等待 %v 后仍然没有名额", tt.refill+tt.refill/2)
+			}
+
+			time.Sleep(5 * tt.refill)
+			if got := drain(l); got < 1 || got > tt.burst {
+				t.Fatalf("空闲 %v 后通过 %d 个请求, want 1~%d", 5*tt.refill, got, tt.burst)
+			}
+		})
+	}
+}
+
+// TestWaitRate 并发调用Wait时整体速率不超过限流速率
+func TestWaitRate(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() Limiter
+	}{
+		{"令牌桶", func() Limiter { return NewTokenBucket(100, 1) }},
+		{"滑动窗口", func() Limiter { return NewSlidingWindow(1, 10*time.Millisecond) }},
+		{"漏桶", func() Limiter { return NewLeakyBucket(100) }},
+	}
+	const callers, perCaller = 4, 5
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := tt.new()
+			defer closeLimiter(l)
+
+			start := time.Now()
+			done := make(chan struct{})
+			for i := 0; i < callers; i++ {
+				go func() {
+					for j := 0; j < perCaller; j++ {
+						l.Wait()
+					}
+					done <- struct{}{}
+				}()
+			}
+			for i := 0; i < callers; i++ {
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatal("Wait挂起")
+				}
+			}
+			// 第一个请求立即通过，之后每10ms一个
+			least := time.Duration(callers*perCaller-1) * 10 * time.Millisecond * 8 / 10
+			if elapsed := time.Since(start); elapsed < least {
+				t.Fatalf("%d 个请求用了 %v，少于 %v：超过了限流速率", callers*perCaller, elapsed, least)
+			}
+		})
+	}
+}
+
+// debt 尚未偿还的预订令牌数
+func (rl *TokenBucket) debt() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.claimed - rl.repaid
+}
+
+// TestReservationCancel 补充周期很长，测试里手动调用tick补充令牌，每一步的状态都是确定的
+func TestReservationCancel(t *testing.T) {
+	t.Run("之后没有新的预订时归还全部令牌", func(t *testing.T) {
+		rl := NewTokenBucket(0.001, 3)
+		defer rl.Close()
+		rl.Allow()
+
+		a := rl.Reserve(3) // 取走2个，欠1个
+		rl.tick()          // 偿还a的欠账
+		if rl.debt() != 0 {
+			t.Fatalf("偿还后debt = %d, want 0", rl.debt())
+		}
+		a.Cancel()
+		a.Cancel() // 重复取消不会再归还
+		if got := drain(rl); got != 3 {
+			t.Fatalf("取消后桶中有 %d 个令牌, want 3", got)
+		}
+	})
+
+	t.Run("部分偿还后取消，之后的预订排在后面时不归还", func(t *testing.T) {
+		rl := NewTokenBucket(0.001, 3)
+		defer rl.Close()
+		drain(rl)
+
+		a := rl.Reserve(3) // 欠3个
+		rl.tick()
+		rl.tick() // a的欠账偿还了2个
+		b := rl.Reserve(2)
+		if b.Delay() <= a.Delay() {
+			t.Fatalf("b的等待时间 %v 不晚于a的 %v", b.Delay(), a.Delay())
+		}
+
+		a.Cancel()
+		if rl.debt() != 3 {
+			t.Fatalf("a取消后debt = %d, want 3：b的令牌会被别人拿走", rl.debt())
+		}
+		for i := 0; i < 3; i++ {
+			rl.tick()
+		}
+		if rl.Allow() {
+			t.Fatal("b的令牌进入了桶中")
+		}
+
+		// b是最后一个预订，取消后归还已经为它偿还的2个令牌
+		b.Cancel()
+		if got := drain(rl); got != 2 {
+			t.Fatalf("b取消后桶中有 %d 个令牌, want 2", got)
+		}
+	})
+
+	t.Run("并发取消", func(t *testing.T) {
+		rl := NewTokenBucket(0.001, 4)
+		defer rl.Close()
+		drain(rl)
+		rs := []*Reservation{rl.Reserve(2), rl.Reserve(2)}
+
+		done := make(chan struct{})
+		for i := 0; i < 4; i++ {
+			go func(r *Reservation) {
+				r.Cancel()
+				done <- struct{}{}
+			}(rs[i%2])
+		}
+		for i := 0; i < 4; i++ {
+			<-done
+		}
+		// 先取消的是rs[0]时它不归还，之后rs[1]归还，debt只剩rs[0]的2个；反过来两个都归还
+		if d := rl.debt(); d != 0 && d != 2 {
+			t.Fatalf("debt = %d, want 0 或 2", d)
+		}
+	})
+}
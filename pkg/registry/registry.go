@@ -0,0 +1,145 @@
+// Package registry 提供写时复制（copy-on-write，RCU风格）的并发注册表，适合读远多于写的场景，
+// 例如负载均衡器的服务器列表、路由表、配置。
+//
+// 读者通过一次原子指针加载拿到当前的不可变快照，之后的读取不加任何锁，也不会被写者阻塞；
+// 写者在互斥锁保护下复制整个快照、修改副本，再用原子指针替换。旧快照仍被读者持有时照常可用，
+// 没有读者引用后由GC回收（RCU中的"宽限期"在Go里由GC负责）。
+//
+// 代价是每次写入都要复制O(n)的数据并分配新快照，写入频繁或表很大时不如RWMutex。
+// 快照中的值如果是指针，指向的对象本身并不因此变成不可变的，需要自己保证并发安全。
+package registry
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Snapshot 某一时刻注册表的不可变快照，按插入顺序保存
+type Snapshot[K comparable, V any] struct {
+	version uint64
+	index   map[K]int
+	keys    []K
+	vals    []V
+}
+
+// Version 快照版本，每次成功的写入加一
+func (s *Snapshot[K, V]) Version() uint64 { return s.version }
+
+// Len 条目数
+func (s *Snapshot[K, V]) Len() int { return len(s.keys) }
+
+// Get 查找键
+func (s *Snapshot[K, V]) Get(key K) (V, bool) {
+	i, ok := s.index[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return s.vals[i], true
+}
+
+// Keys 按插入顺序返回所有键，返回的切片与快照共享，不能修改
+func (s *Snapshot[K, V]) Keys() []K { return s.keys }
+
+// Values 按插入顺序返回所有值，返回的切片与快照共享，不能修改
+func (s *Snapshot[K, V]) Values() []V { return s.vals }
+
+// Range 按插入顺序遍历，fn返回false时停止
+func (s *Snapshot[K, V]) Range(fn func(K, V) bool) {
+	for i, k := range s.keys {
+		if !fn(k, s.vals[i]) {
+			return
+		}
+	}
+}
+
+// Registry 写时复制的注册表，零值不可用，用New创建
+type Registry[K comparable, V any] struct {
+	mu   sync.Mutex // 串行化写者，读者不需要
+	snap atomic.Pointer[Snapshot[K, V]]
+}
+
+// New 创建空注册表
+func New[K comparable, V any]() *Registry[K, V] {
+	r := &Registry[K, V]{}
+	r.snap.Store(&Snapshot[K, V]{index: map[K]int{}})
+	return r
+}
+
+// Load 当前快照；需要多次读取时先Load一次，所有读取都看到同一个版本
+func (r *Registry[K, V]) Load() *Snapshot[K, V] { return r.snap.Load() }
+
+// Get 在当前快照中查找键
+func (r *Registry[K, V]) Get(key K) (V, bool) { return r.Load().Get(key) }
+
+// Put 插入或替换；替换时保持原来的位置
+func (r *Registry[K, V]) Put(key K, val V) {
+	r.Update(func(e *Editor[K, V]) { e.Put(key, val) })
+}
+
+// Delete 删除键，返回键是否存在
+func (r *Registry[K, V]) Delete(key K) bool {
+	var ok bool
+	r.Update(func(e *Editor[K, V]) { ok = e.Delete(key) })
+	return ok
+}
+
+// Update 在一个副本上执行fn中的全部修改，然后一次性发布：读者要么看到全部修改，要么一个都看不到。
+// fn中不能调用r的其他写方法
+func (r *Registry[K, V]) Update(fn func(e *Editor[K, V])) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.snap.Load()
+	e := &Editor[K, V]{next: &Snapshot[K, V]{
+		version: old.version + 1,
+		index:   make(map[K]int, len(old.index)),
+		keys:    append([]K(nil), old.keys...),
+		vals:    append([]V(nil), old.vals...),
+	}}
+	for k, i := range old.index {
+		e.next.index[k] = i
+	}
+	fn(e)
+	if e.changed {
+		r.snap.Store(e.next)
+	}
+}
+
+// Editor Update期间对副本的修改，只在fn内有效
+type Editor[K comparable, V any] struct {
+	next    *Snapshot[K, V]
+	changed bool
+}
+
+// Get 读取副本（包含本次Update中已做的修改）
+func (e *Editor[K, V]) Get(key K) (V, bool) { return e.next.Get(key) }
+
+// Put 插入或替换
+func (e *Editor[K, V]) Put(key K, val V) {
+	s := e.next
+	if i, ok := s.index[key]; ok {
+		s.vals[i] = val
+	} else {
+		s.index[key] = len(s.keys)
+		s.keys = append(s.keys, key)
+		s.vals = append(s.vals, val)
+	}
+	e.changed = true
+}
+
+// Delete 删除键，后面的条目前移以保持插入顺序
+func (e *Editor[K, V]) Delete(key K) bool {
+	s := e.next
+	i, ok := s.index[key]
+	if !ok {
+		return false
+	}
+	delete(s.index, key)
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	s.vals = append(s.vals[:i], s.vals[i+1:]...)
+	for j := i; j < len(s.keys); j++ {
+		s.index[s.keys[j]] = j
+	}
+	e.changed = true
+	return true
+}
@@ -0,0 +1,193 @@
+package registry
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPutDeleteKeepsOrder(t *testing.T) {
+	r := New[string, int]()
+	r.Put("a", 1)
+	r.Put("b", 2)
+	r.Put("c", 3)
+	r.Put("a", 10) // 替换保持原来的位置
+	if !r.Delete("b") || r.Delete("missing") {
+		t.Fatal("Delete的返回值不对")
+	}
+
+	s := r.Load()
+	if got := fmt.Sprint(s.Keys(), s.Values()); got != "[a c] [10 3]" {
+		t.Fatalf("Keys(), Values() = %s, want [a c] [10 3]", got)
+	}
+	if v, ok := s.Get("c"); !ok || v != 3 {
+		t.Fatalf(`Get("c") = %d, %v, want 3, true`, v, ok)
+	}
+	// 5次成功的写入，删除不存在的键不产生新版本
+	if s.Version() != 5 {
+		t.Fatalf("Version() = %d, want 5", s.Version())
+	}
+}
+
+// TestSnapshotIsolation 写入之后，之前Load的快照内容不变
+func TestSnapshotIsolation(t *testing.T) {
+	r := New[int, string]()
+	r.Put(1, "one")
+	old := r.Load()
+
+	r.Update(func(e *Editor[int, string]) {
+		e.Put(1, "uno")
+		e.Put(2, "dos")
+		if v, _ := e.Get(1); v != "uno" {
+			t.Errorf("Update中Get(1) = %q，看不到本次的修改", v)
+		}
+	})
+	if v, _ := old.Get(1); v != "one" || old.Len() != 1 {
+		t.Fatalf("旧快照被修改：Get(1) = %q，Len() = %d", v, old.Len())
+	}
+	if v, _ := r.Get(1); v != "uno" || r.Load().Len() != 2 {
+		t.Fatalf("当前快照：Get(1) = %q，Len() = %d", v, r.Load().Len())
+	}
+}
+
+// TestUpdateIsAtomic 并发转账时，读者对任意快照求和都等于总额
+func TestUpdateIsAtomic(t *testing.T) {
+	const accounts, each = 8, 100
+	r := New[int, int]()
+	r.Update(func(e *Editor[int, int]) {
+		for i := 0; i < accounts; i++ {
+			e.Put(i, each)
+		}
+	})
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				sum := 0
+				r.Load().Range(func(_, v int) bool {
+					sum += v
+					return true
+				})
+				if sum != accounts*each {
+					t.Errorf("读到中间状态：总额 %d", sum)
+					return
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+	// 多个写者并发转账，互斥锁保证每次Update都基于最新的快照
+	var writers sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < 500; i++ {
+				from, to := (w+i)%accounts, (w+i+3)%accounts
+				r.Update(func(e *Editor[int, int]) {
+					a, _ := e.Get(from)
+					b, _ := e.Get(to)
+					e.Put(from, a-1)
+					e.Put(to, b+1)
+				})
+			}
+		}(w)
+	}
+	writers.Wait()
+	stop.Store(true)
+	wg.Wait()
+
+	if v := r.Load().Version(); v != 1+4*500 {
+		t.Fatalf("Version() = %d, want %d：有写入丢失", v, 1+4*500)
+	}
+}
+
+// rwMap RWMutex保护的map，作为基准测试的对照
+type rwMap struct {
+	mu sync.RWMutex
+	m  map[int]int
+}
+
+func (m *rwMap) get(k int) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.m[k]
+	return v, ok
+}
+
+func (m *rwMap) put(k, v int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[k] = v
+}
+
+// BenchmarkReadMostly 不同写入比例下写时复制与RWMutex的对比，表中有64个条目
+//
+//	go test -bench ReadMostly ./pkg/registry
+func BenchmarkReadMostly(b *testing.B) {
+	const n = 64
+	for _, every := range []int{0, 1000, 100, 10, 2} { // 每多少次操作写入一次，0表示只读
+		name := "ReadOnly"
+		if every > 0 {
+			name = fmt.Sprintf("Write1of%d", every)
+		}
+		b.Run("COW/"+name, func(b *testing.B) {
+			r := New[int, int]()
+			r.Update(func(e *Editor[int, int]) {
+				for i := 0; i < n; i++ {
+					e.Put(i, i)
+				}
+			})
+			parallel(b, every, func(k int) { r.Get(k % n) }, func(k int) { r.Put(k%n, k) })
+		})
+		b.Run("RWMutex/"+name, func(b *testing.B) {
+			m := &rwMap{m: make(map[int]int, n)}
+			for i := 0; i < n; i++ {
+				m.m[i] = i
+			}
+			parallel(b, every, func(k int) { m.get(k % n) }, func(k int) { m.put(k%n, k) })
+		})
+	}
+}
+
+// parallel 4倍GOMAXPROCS个goroutine并发执行，每every次操作中有一次write，其余是read
+func parallel(b *testing.B, every int, read, write func(k int)) {
+	b.SetParallelism(4)
+	var seed atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		k := int(seed.Add(1)) * 7919
+		for i := 1; pb.Next(); i++ {
+			k++
+			if every > 0 && i%every == 0 {
+				write(k)
+			} else {
+				read(k)
+			}
+		}
+	})
+}
+
+// BenchmarkWrite 每次写入都复制整张表，分配的字节数随表的大小线性增长
+func BenchmarkWrite(b *testing.B) {
+	for _, n := range []int{16, 256, 4096} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			r := New[int, int]()
+			r.Update(func(e *Editor[int, int]) {
+				for i := 0; i < n; i++ {
+					e.Put(i, i)
+				}
+			})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Put(i%n, i)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Package retry 提供带退避策略的重试：retry.Do(ctx, policy, fn)。
+//
+//   - 指数退避：第n次重试前等待 InitialDelay * Multiplier^(n-1)，不超过MaxDelay
+//   - 抖动：FullJitter在[0, d]中随机取值，EqualJitter在[d/2, d]中随机取值，
+//     让同时失败的大量调用方错开重试时间，不会一起再次压垮下游
+//   - 错误分类：Retryable判断错误是否值得重试；用Permanent包装的错误总是不重试
+//   - OnAttempt在每次尝试结束后调用，可以用来打日志和统计
+//   - 等待期间ctx结束时立即返回
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrExhausted 尝试次数用完，错误链中还包含最后一次的错误
+var ErrExhausted = errors.New("retry: attempts exhausted")
+
+// Jitter 抖动方式
+type Jitter int
+
+const (
+	NoJitter    Jitter = iota // 严格按指数退避等待
+	FullJitter                // 在[0, d]中均匀随机
+	EqualJitter               // 在[d/2, d]中均匀随机，至少等待一半
+)
+
+func (j Jitter) String() string {
+	switch j {
+	case FullJitter:
+		return "FullJitter"
+	case EqualJitter:
+		return "EqualJitter"
+	default:
+		return "NoJitter"
+	}
+}
+
+// Attempt 一次尝试的结果，传给OnAttempt
+type Attempt struct {
+	Number int           // 从1开始
+	Err    error         // 本次尝试的错误，nil表示成功
+	Delay  time.Duration // 下一次尝试前的等待时间，不再重试时为0
+	Retry  bool          // 是否还会重试
+}
+
+// Policy 重试策略，零值表示只尝试一次
+type Policy struct {
+	MaxAttempts  int           // 包括第一次在内的最多尝试次数，<=0时为1
+	InitialDelay time.Duration // 第一次重试前的等待时间
+	MaxDelay     time.Duration // 单次等待的上限，0表示不限
+	Multiplier   float64       // 每次重试等待时间的倍数，<=0时为2
+	Jitter       Jitter
+
+	Retryable func(err error) bool // 为nil时除Permanent之外的错误都重试
+	OnAttempt func(a Attempt)      // 每次尝试结束后调用
+	Rand      func() float64       // [0, 1)随机数，为nil时使用math/rand，演示中可以固定
+}
+
+// Delay 第n次重试（n从1开始）之前的等待时间
+func (p Policy) Delay(n int) time.Duration {
+	if p.InitialDelay <= 0 {
+		return 0
+	}
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	d := float64(p.InitialDelay) * math.Pow(mult, float64(n-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	// 次数很多时指数会溢出，先限制在有限值内，避免抖动计算得到Inf或NaN
+	d = min(d, float64(math.MaxInt64))
+
+	rnd := p.Rand
+	if rnd == nil {
+		rnd = rand.Float64
+	}
+	switch p.Jitter {
+	case FullJitter:
+		d = d * rnd()
+	case EqualJitter:
+		d = d/2 + d/2*rnd()
+	}
+	// float64(math.MaxInt64)实际是2^63，直接转换会溢出成负数
+	if d >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+func (p Policy) retryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+	if p.Retryable == nil {
+		return true
+	}
+	return p.Retryable(err)
+}
+
+// Do 执行fn直到成功、遇到不可重试的错误、尝试次数用完或ctx结束
+//   - 不可重试的错误原样返回（Permanent包装会被去掉）
+//   - 次数用完时返回包含ErrExhausted和最后一次错误的错误
+//   - ctx结束时返回包含ctx.Err()和最后一次错误的错误
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	_, err := DoValue(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoValue 与Do相同，fn返回一个值
+func DoValue[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	maxAttempts := max(p.MaxAttempts, 1)
+	var zero T
+	for n := 1; ; n++ {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		v, err := fn(ctx)
+		a := Attempt{Number: n, Err: err}
+		if err == nil {
+			p.report(a)
+			return v, nil
+		}
+		if !p.retryable(err) {
+			p.report(a)
+			return zero, unwrapPermanent(err)
+		}
+		if n >= maxAttempts {
+			p.report(a)
+			return zero, fmt.Errorf("%w after %d attempts: %w", ErrExhausted, n, err)
+		}
+
+		a.Delay, a.Retry = p.Delay(n), true
+		p.report(a)
+		timer := time.NewTimer(a.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, fmt.Errorf("%w; last error: %w", ctx.Err(), err)
+		}
+	}
+}
+
+func (p Policy) report(a Attempt) {
+	if p.OnAttempt != nil {
+		p.OnAttempt(a)
+	}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 把err标记为不可重试，Do遇到它立即返回原来的err
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent err的错误链中是否有Permanent标记
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+func unwrapPermanent(err error) error {
+	var pe *permanentError
+	if errors.As(err, &pe) && pe == err {
+		return pe.err
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		n      int
+		want   time.Duration
+	}{
+		{"第一次重试", Policy{InitialDelay: 100 * time.Millisecond}, 1, 100 * time.Millisecond},
+		{"默认倍数为2", Policy{InitialDelay: 100 * time.Millisecond}, 4, 800 * time.Millisecond},
+		{"自定义倍数", Policy{InitialDelay: time.Second, Multiplier: 3}, 3, 9 * time.Second},
+		{"不超过MaxDelay", Policy{InitialDelay: time.Second, MaxDelay: 5 * time.Second}, 10, 5 * time.Second},
+		{"FullJitter取随机比例", Policy{InitialDelay: time.Second, Jitter: FullJitter, Rand: func() float64 { return 0.25 }}, 1, 250 * time.Millisecond},
+		{"EqualJitter至少一半", Policy{InitialDelay: time.Second, Jitter: EqualJitter, Rand: func() float64 { return 0 }}, 1, 500 * time.Millisecond},
+		{"指数溢出时取上限", Policy{InitialDelay: time.Second}, 35, time.Duration(math.MaxInt64)},
+		{"指数为Inf时取上限", Policy{InitialDelay: time.Second}, 5000, time.Duration(math.MaxInt64)},
+		{"零等待", Policy{}, 100, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.n); got != tt.want {
+				t.Fatalf("Delay(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPolicyDelayNeverNegative 没有MaxDelay时，任意次数的等待时间都不能溢出成负数
+func TestPolicyDelayNeverNegative(t *testing.T) {
+	for _, jitter := range []Jitter{NoJitter, FullJitter, EqualJitter} {
+		p := Policy{InitialDelay: time.Second, Jitter: jitter, Rand: func() float64 { return 0.999999 }}
+		prev := time.Duration(0)
+		for n := 1; n <= 2000; n++ {
+			d := p.Delay(n)
+			if d < 0 {
+				t.Fatalf("%v: Delay(%d) = %v，溢出成负数", jitter, n, d)
+			}
+			if d < prev {
+				t.Fatalf("%v: Delay(%d) = %v 小于 Delay(%d) = %v", jitter, n, d, n-1, prev)
+			}
+			prev = d
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name      string
+		results   []error // 第i次尝试返回results[i]
+		wantCalls int
+		wantErr   error
+	}{
+		{"第一次成功", []error{nil}, 1, nil},
+		{"重试后成功", []error{errTemporary, errTemporary, nil}, 3, nil},
+		{"次数用完", []error{errTemporary, errTemporary, errTemporary}, 3, ErrExhausted},
+		{"不可重试的错误", []error{errTemporary, Permanent(errFatal)}, 2, errFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts []Attempt
+			p := Policy{
+				MaxAttempts:  3,
+				InitialDelay: time.Millisecond,
+				OnAttempt:    func(a Attempt) { attempts = append(attempts, a) },
+			}
+			calls := 0
+			err := Do(context.Background(), p, func(ctx context.Context) error {
+				calls++
+				return tt.results[calls-1]
+			})
+			if calls != tt.wantCalls {
+				t.Fatalf("调用 %d 次, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Do() = %v, want %v", err, tt.wantErr)
+			}
+			if len(attempts) != calls {
+				t.Fatalf("OnAttempt调用 %d 次, want %d", len(attempts), calls)
+			}
+			if last := attempts[len(attempts)-1]; last.Retry || last.Delay != 0 {
+				t.Fatalf("最后一次尝试 %+v 不应再重试", last)
+			}
+		})
+	}
+}
+
+func TestDoPermanentIsUnwrapped(t *testing.T) {
+	errFatal := errors.New("fatal")
+	err := Do(context.Background(), Policy{MaxAttempts: 3}, func(ctx context.Context) error {
+		return Permanent(errFatal)
+	})
+	if err != errFatal {
+		t.Fatalf("Do() = %v, want 原来的错误", err)
+	}
+}
+
+func TestDoStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	errTemporary := errors.New("temporary")
+	start := time.Now()
+	err := Do(ctx, Policy{MaxAttempts: 5, InitialDelay: time.Hour}, func(ctx context.Context) error {
+		return errTemporary
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errTemporary) {
+		t.Fatalf("Do() = %v, want 同时包含DeadlineExceeded和最后一次错误", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("ctx结束后等待了 %v 才返回", elapsed)
+	}
+}
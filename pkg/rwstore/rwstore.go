@@ -0,0 +1,78 @@
+// Package rwstore 提供分别用Mutex和RWMutex保护的简单键值存储，用于对比两种锁在不同读写比例下的吞吐。
+//
+// 读操作在临界区内遍历200个键，模拟序列化、遍历这类较长的只读操作：临界区越长，
+// RWMutex让多个读者并行的收益越大；写操作增多后，RWMutex额外的读者计数反而让它更慢。
+package rwstore
+
+import "sync"
+
+// Store 两种实现共同的接口
+type Store interface {
+	Get(key int) int
+	Set(key, value int)
+}
+
+// readWork 模拟读操作在临界区内的开销
+func readWork(data map[int]int, key int) int {
+	sum := 0
+	for i := 0; i < 200; i++ {
+		sum += data[(key+i)%len(data)]
+	}
+	return sum
+}
+
+func newData(size int) map[int]int {
+	data := make(map[int]int, size)
+	for i := 0; i < size; i++ {
+		data[i] = i
+	}
+	return data
+}
+
+// MutexStore 读写都用互斥锁
+type MutexStore struct {
+	mu   sync.Mutex
+	data map[int]int
+}
+
+// NewMutexStore 创建包含键0..size-1的存储，每个键的值等于键
+func NewMutexStore(size int) *MutexStore {
+	return &MutexStore{data: newData(size)}
+}
+
+// Get 返回从key开始连续200个键（取模后）的值之和
+func (s *MutexStore) Get(key int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readWork(s.data, key)
+}
+
+// Set 设置key取模后对应的键
+func (s *MutexStore) Set(key, value int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key%len(s.data)] = value
+}
+
+// RWMutexStore 读用读锁，写用写锁
+type RWMutexStore struct {
+	mu   sync.RWMutex
+	data map[int]int
+}
+
+// NewRWMutexStore 创建包含键0..size-1的存储，每个键的值等于键
+func NewRWMutexStore(size int) *RWMutexStore {
+	return &RWMutexStore{data: newData(size)}
+}
+
+func (s *RWMutexStore) Get(key int) int {
+	s.mu.RLock() // 读锁：多个读者可以同时进入
+	defer s.mu.RUnlock()
+	return readWork(s.data, key)
+}
+
+func (s *RWMutexStore) Set(key, value int) {
+	s.mu.Lock() // 写锁：独占，等待所有读者退出
+	defer s.mu.Unlock()
+	s.data[key%len(s.data)] = value
+}
@@ -0,0 +1,87 @@
+package rwstore
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+var stores = []struct {
+	name string
+	new  func(size int) Store
+}{
+	{"Mutex", func(n int) Store { return NewMutexStore(n) }},
+	{"RWMutex", func(n int) Store { return NewRWMutexStore(n) }},
+}
+
+func TestGetSet(t *testing.T) {
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			s := st.new(1000)
+			// 0..199之和
+			if got := s.Get(0); got != 199*200/2 {
+				t.Fatalf("Get(0) = %d, want %d", got, 199*200/2)
+			}
+			s.Set(1005, 0) // 键取模后是5
+			if got := s.Get(0); got != 199*200/2-5 {
+				t.Fatalf("Set(1005, 0)之后Get(0) = %d, want %d", got, 199*200/2-5)
+			}
+			// 跨过末尾回到开头：900..999和0..99
+			if got, want := s.Get(900), (900+999)*100/2+99*100/2-5; got != want {
+				t.Fatalf("Get(900) = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+// TestConcurrent 并发读写，写入的值保持不变量：每个键的值都等于键，读到的和与单线程时相同
+func TestConcurrent(t *testing.T) {
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			s := st.new(1000)
+			want := s.Get(0)
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 200; i++ {
+						if g%2 == 0 {
+							s.Set(i, i)
+						} else if got := s.Get(0); got != want {
+							t.Errorf("Get(0) = %d, want %d", got, want)
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// BenchmarkStore 不同读比例下两种锁的吞吐，4倍GOMAXPROCS个goroutine并发访问
+//
+//	go test -bench Store ./pkg/rwstore
+func BenchmarkStore(b *testing.B) {
+	for _, readPercent := range []int{100, 99, 90, 50, 10} {
+		for _, st := range stores {
+			b.Run(fmt.Sprintf("read%d%%/%s", readPercent, st.name), func(b *testing.B) {
+				s := st.new(1000)
+				b.SetParallelism(4)
+				b.RunParallel(func(pb *testing.PB) {
+					r := rand.New(rand.NewSource(rand.Int63()))
+					for pb.Next() {
+						key := r.Intn(1000)
+						if r.Intn(100) < readPercent {
+							s.Get(key)
+						} else {
+							s.Set(key, key)
+						}
+					}
+				})
+			})
+		}
+	}
+}
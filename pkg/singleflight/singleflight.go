@@ -0,0 +1,106 @@
+// Package singleflight 合并对同一个键的并发调用：同一时间只有一个调用真正执行fn，
+// 其余调用方等待并共享它的结果。
+//
+// 默认fn一返回就忘记这个键，之后的Do会重新执行fn。设置Remember后，完成的结果在这段时间内
+// 继续被共享（相当于一个很短的结果缓存）；ForgetOnError让失败的结果立即被忘记，
+// 一次失败不会在Remember期间被反复返回给后来的调用方。
+package singleflight
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Config Group配置
+type Config struct {
+	Remember      time.Duration // 完成后继续共享结果的时间，0表示完成即忘记
+	ForgetOnError bool          // fn返回错误时立即忘记，不受Remember影响
+}
+
+// PanicError fn的panic被转换成的错误，所有等待者都会收到
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: fn panic: %v", e.Value)
+}
+
+// call 一次正在进行（或被记住）的调用
+type call[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+	dups int // 共享这次结果的其他调用方数量
+}
+
+// Group 按键合并调用，零值不可用，用New创建
+type Group[K comparable, V any] struct {
+	cfg   Config
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+// New 创建Group
+func New[K comparable, V any](cfg Config) *Group[K, V] {
+	return &Group[K, V]{cfg: cfg, calls: make(map[K]*call[V])}
+}
+
+// Do 执行fn并返回结果；同一个键已有进行中（或被记住）的调用时，等待并返回它的结果，shared为true
+// 第一个调用方自己的结果在有其他调用方共享时shared也为true
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		<-c.done
+		return c.val, c.err, true
+	}
+	c := &call[V]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = g.run(fn)
+
+	g.mu.Lock()
+	// Forget之后同一个键可能已经开始了新的调用，只删除自己
+	if g.calls[key] == c {
+		if g.cfg.Remember <= 0 || (c.err != nil && g.cfg.ForgetOnError) {
+			delete(g.calls, key)
+		} else {
+			time.AfterFunc(g.cfg.Remember, func() { g.forget(key, c) })
+		}
+	}
+	shared = c.dups > 0
+	g.mu.Unlock()
+	close(c.done)
+	return c.val, c.err, shared
+}
+
+func (g *Group[K, V]) run(fn func() (V, error)) (v V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+func (g *Group[K, V]) forget(key K, c *call[V]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// Forget 忘记这个键：之后的Do会重新执行fn，已经在等待的调用方仍然拿到原来那次调用的结果
+// 用于已知数据已经变化、不想让后来者共享旧结果的场景
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
@@ -0,0 +1,198 @@
+// Package stm 提供一个玩具级的软件事务内存(STM)，算法参考TL2。
+//
+//   - 事务变量TVar保存(值, 版本)，全局版本时钟在每次提交时加一
+//   - 事务开始时记下读版本rv，读取时不加锁（乐观读），读到版本大于rv或正被提交的变量说明有冲突
+//   - 写入先记在事务的写集合中，对其他事务不可见
+//   - 提交：锁住写集合中的变量，时钟加一得到写版本wv，校验读集合中的变量版本没有超过rv，
+//     然后把写集合以版本wv写回并解锁
+//   - 任何一步发现冲突都放弃这次执行，从头重试事务函数；事务函数因此必须没有副作用
+package stm
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"sync/atomic"
+)
+
+var (
+	clock  atomic.Uint64 // 全局版本时钟
+	nextID atomic.Uint64
+
+	commits, aborts atomic.Int64
+)
+
+// errConflict 事务内部用来中断执行的信号，不会返回给调用方
+var errConflict = errors.New("stm: conflict")
+
+// box 不可变的(值, 版本)对，整体原子替换，读取时值和版本总是一致的
+type box[T any] struct {
+	val T
+	ver uint64
+}
+
+// TVar 事务变量
+type TVar[T any] struct {
+	id     uint64
+	locked atomic.Bool // 正在被某个事务提交
+	cur    atomic.Pointer[box[T]]
+}
+
+// NewVar 创建事务变量
+func NewVar[T any](v T) *TVar[T] {
+	tv := &TVar[T]{id: nextID.Add(1)}
+	tv.cur.Store(&box[T]{val: v, ver: clock.Load()})
+	return tv
+}
+
+// Load 在事务之外读取当前值，多个变量分别Load得到的值不保证来自同一时刻
+func (v *TVar[T]) Load() T {
+	return v.cur.Load().val
+}
+
+// Get 在事务中读取；同一事务写过的变量返回写入的值
+func (v *TVar[T]) Get(tx *Tx) T {
+	if w, ok := tx.writes[v.id]; ok {
+		return w.(*pending[T]).val
+	}
+	// 先检查锁再读取，读取后再检查一次：提交者先加锁再推进时钟，
+	// 两次检查都没看到锁并且版本不超过rv，读到的就是rv时刻的值
+	if v.locked.Load() {
+		panic(errConflict)
+	}
+	b := v.cur.Load()
+	if v.locked.Load() || b.ver > tx.rv {
+		panic(errConflict)
+	}
+	tx.reads[v.id] = readEntry{v: v, ver: b.ver}
+	return b.val
+}
+
+// Set 在事务中写入，提交前对其他事务不可见
+func (v *TVar[T]) Set(tx *Tx, val T) {
+	if w, ok := tx.writes[v.id]; ok {
+		w.(*pending[T]).val = val
+		return
+	}
+	tx.writes[v.id] = &pending[T]{v: v, val: val}
+}
+
+// tvar 提交时对不同类型TVar的统一操作
+type tvar interface {
+	tvarID() uint64
+	tryLock() bool
+	unlock()
+	version() uint64
+	isLocked() bool
+}
+
+func (v *TVar[T]) tvarID() uint64  { return v.id }
+func (v *TVar[T]) tryLock() bool   { return v.locked.CompareAndSwap(false, true) }
+func (v *TVar[T]) unlock()         { v.locked.Store(false) }
+func (v *TVar[T]) version() uint64 { return v.cur.Load().ver }
+func (v *TVar[T]) isLocked() bool  { return v.locked.Load() }
+
+// write 写集合中的一项
+type write interface {
+	target() tvar
+	publish(ver uint64)
+}
+
+type pending[T any] struct {
+	v   *TVar[T]
+	val T
+}
+
+func (p *pending[T]) target() tvar { return p.v }
+func (p *pending[T]) publish(ver uint64) {
+	p.v.cur.Store(&box[T]{val: p.val, ver: ver})
+}
+
+type readEntry struct {
+	v   tvar
+	ver uint64
+}
+
+// Tx 一次事务执行
+type Tx struct {
+	rv     uint64
+	reads  map[uint64]readEntry
+	writes map[uint64]write
+}
+
+// Atomically 原子地执行fn：fn返回nil时提交它的全部写入，返回错误时丢弃全部写入并返回该错误
+// 与其他事务冲突时自动重试，fn可能被执行多次
+func Atomically(fn func(tx *Tx) error) error {
+	for {
+		tx := &Tx{rv: clock.Load(), reads: make(map[uint64]readEntry), writes: make(map[uint64]write)}
+		conflict, err := run(tx, fn)
+		if !conflict {
+			if err != nil {
+				return err
+			}
+			if tx.commit() {
+				commits.Add(1)
+				return nil
+			}
+		}
+		aborts.Add(1)
+		runtime.Gosched()
+	}
+}
+
+func run(tx *Tx, fn func(tx *Tx) error) (conflict bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errConflict {
+				panic(r)
+			}
+			conflict = true
+		}
+	}()
+	return false, fn(tx)
+}
+
+func (tx *Tx) commit() bool {
+	if len(tx.writes) == 0 {
+		// 只读事务：每次读取都已确认版本不超过rv，读到的是一致的快照
+		return true
+	}
+
+	ws := make([]write, 0, len(tx.writes))
+	for _, w := range tx.writes {
+		ws = append(ws, w)
+	}
+	// 按id顺序加锁只是为了让行为确定，tryLock失败就放弃，本身不会死锁
+	sort.Slice(ws, func(i, j int) bool { return ws[i].target().tvarID() < ws[j].target().tvarID() })
+	locked := 0
+	defer func() {
+		for _, w := range ws[:locked] {
+			w.target().unlock()
+		}
+	}()
+	for _, w := range ws {
+		if !w.target().tryLock() {
+			return false
+		}
+		locked++
+	}
+
+	wv := clock.Add(1)
+	for id, r := range tx.reads {
+		if r.v.version() != r.ver {
+			return false
+		}
+		if _, mine := tx.writes[id]; !mine && r.v.isLocked() {
+			return false
+		}
+	}
+	for _, w := range ws {
+		w.publish(wv)
+	}
+	return true
+}
+
+// Stats 全部事务的累计提交次数和冲突重试次数
+func Stats() (commitCount, abortCount int64) {
+	return commits.Load(), aborts.Load()
+}
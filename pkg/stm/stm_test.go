@@ -0,0 +1,147 @@
+package stm
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+var errInsufficient = errors.New("余额不足")
+
+// transfer 从from转amount到to，余额不足时返回错误，两次写入都被丢弃
+func transfer(from, to *TVar[int], amount int) error {
+	return Atomically(func(tx *Tx) error {
+		f := from.Get(tx)
+		from.Set(tx, f-amount)
+		to.Set(tx, to.Get(tx)+amount)
+		if f < amount {
+			return errInsufficient
+		}
+		return nil
+	})
+}
+
+// TestTransfersConserveTotal 并发随机转账时总余额不变，没有账户透支，
+// 并发的只读事务每次看到的都是一致的快照
+func TestTransfersConserveTotal(t *testing.T) {
+	const accounts, initial, workers, transfers = 8, 100, 8, 500
+
+	vars := make([]*TVar[int], accounts)
+	for i := range vars {
+		vars[i] = NewVar(initial)
+	}
+	sum := func() int {
+		var total int
+		Atomically(func(tx *Tx) error {
+			total = 0
+			for _, v := range vars {
+				total += v.Get(tx)
+			}
+			return nil
+		})
+		return total
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for i := 0; i < transfers; i++ {
+				from, to := rng.Intn(accounts), rng.Intn(accounts)
+				if from == to {
+					continue
+				}
+				if err := transfer(vars[from], vars[to], 1+rng.Intn(50)); err != nil && !errors.Is(err, errInsufficient) {
+					t.Errorf("transfer() err = %v", err)
+					return
+				}
+			}
+		}(int64(w))
+	}
+
+	stop := make(chan struct{})
+	audited := make(chan struct{})
+	go func() {
+		defer close(audited)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if total := sum(); total != accounts*initial {
+				t.Errorf("转账过程中的快照总额 %d, want %d", total, accounts*initial)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	<-audited
+
+	if total := sum(); total != accounts*initial {
+		t.Fatalf("总额 %d, want %d", total, accounts*initial)
+	}
+	for i, v := range vars {
+		if b := v.Load(); b < 0 {
+			t.Fatalf("账户 %d 余额 %d, 透支", i, b)
+		}
+	}
+}
+
+// TestErrorDiscardsWrites fn返回错误时丢弃全部写入；事务内读到自己写入的值
+func TestErrorDiscardsWrites(t *testing.T) {
+	a, b := NewVar(10), NewVar(20)
+	err := Atomically(func(tx *Tx) error {
+		a.Set(tx, 11)
+		b.Set(tx, a.Get(tx)+1)
+		if got := b.Get(tx); got != 12 {
+			t.Errorf("事务内Get() = %d, want 12", got)
+		}
+		return errInsufficient
+	})
+	if !errors.Is(err, errInsufficient) {
+		t.Fatalf("Atomically() err = %v, want errInsufficient", err)
+	}
+	if a.Load() != 10 || b.Load() != 20 {
+		t.Fatalf("a, b = %d, %d, want 10, 20", a.Load(), b.Load())
+	}
+}
+
+// TestConflictDiscardsWrites 读过的变量在提交前被其他事务修改时，这次执行的写入被丢弃，
+// 事务重新执行，不会丢失对方的更新
+func TestConflictDiscardsWrites(t *testing.T) {
+	x := NewVar(0)
+	runs := 0
+	err := Atomically(func(tx *Tx) error {
+		runs++
+		v := x.Get(tx)
+		if runs == 1 {
+			// 第一次执行读取之后，另一个goroutine提交一次加一
+			done := make(chan error)
+			go func() {
+				done <- Atomically(func(tx *Tx) error {
+					x.Set(tx, x.Get(tx)+1)
+					return nil
+				})
+			}()
+			if err := <-done; err != nil {
+				t.Errorf("并发事务 err = %v", err)
+			}
+		}
+		x.Set(tx, v+1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Atomically() err = %v", err)
+	}
+	if runs != 2 {
+		t.Fatalf("事务执行了 %d 次, want 2", runs)
+	}
+	if got := x.Load(); got != 2 {
+		t.Fatalf("x = %d, want 2（第一次执行的写入应当被丢弃）", got)
+	}
+}
@@ -0,0 +1,44 @@
+// Package syncx 补充标准库sync中没有的同步原语。
+//
+// ResettableOnce 与sync.Once类似，但初始化函数返回错误时不算完成，下次调用会重试；
+// 还可以用Reset让它重新初始化。sync.OnceValues会把第一次的错误永久缓存，不适合
+// 连接、加载配置这类失败后需要重试的初始化。
+package syncx
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ResettableOnce 零值可用
+type ResettableOnce struct {
+	mu   sync.Mutex
+	done atomic.Bool
+}
+
+// Do 如果还没有成功初始化过就执行f；并发调用者会等待正在进行的初始化。
+// 返回nil时，f中的写入对调用者可见
+func (o *ResettableOnce) Do(f func() error) error {
+	// 快速路径：已经初始化成功，不需要加锁
+	if o.done.Load() {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.done.Load() {
+		return nil
+	}
+	if err := f(); err != nil {
+		return err
+	}
+	o.done.Store(true)
+	return nil
+}
+
+// Reset 让下一次Do重新执行初始化；正在进行的初始化结束之后才生效
+func (o *ResettableOnce) Reset() {
+	o.mu.Lock()
+	o.done.Store(false)
+	o.mu.Unlock()
+}
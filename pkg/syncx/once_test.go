@@ -0,0 +1,121 @@
+package syncx
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errInit = errors.New("初始化失败")
+
+func TestResettableOnceRetry(t *testing.T) {
+	var once ResettableOnce
+	attempts := 0
+	f := func() error {
+		attempts++
+		if attempts < 3 {
+			return errInit
+		}
+		return nil
+	}
+
+	want := []error{errInit, errInit, nil, nil}
+	for i, w := range want {
+		if err := once.Do(f); err != w {
+			t.Fatalf("第%d次Do() = %v, want %v", i+1, err, w)
+		}
+	}
+	if attempts != 3 {
+		t.Fatalf("f执行了 %d 次, want 3：成功之后不应再执行", attempts)
+	}
+
+	once.Reset()
+	if err := once.Do(f); err != nil || attempts != 4 {
+		t.Fatalf("Reset后Do() = %v，f共执行 %d 次, want nil, 4", err, attempts)
+	}
+}
+
+// TestResettableOnceConcurrent 前failures次初始化失败：并发调用时f恰好执行failures+1次，
+// 恰好成功一次，之后的调用者直接返回nil并能看到f写入的值
+func TestResettableOnceConcurrent(t *testing.T) {
+	const goroutines, failures = 100, 10
+	var (
+		once      ResettableOnce
+		attempts  int // 只在f中访问，由Do的互斥锁保护
+		value     int
+		failed    atomic.Int64
+		succeeded atomic.Int64
+		skipped   atomic.Int64
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			executed := false
+			err := once.Do(func() error {
+				executed = true
+				attempts++
+				if attempts <= failures {
+					return errInit
+				}
+				value = 42
+				return nil
+			})
+			switch {
+			case err != nil:
+				failed.Add(1)
+			case executed:
+				succeeded.Add(1)
+			default:
+				skipped.Add(1)
+				// 没有加锁直接读取：Do返回nil必须保证f中的写入已经可见，否则-race会报告
+				if value != 42 {
+					t.Errorf("Do()返回nil时value = %d", value)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if attempts != failures+1 || failed.Load() != failures || succeeded.Load() != 1 || skipped.Load() != goroutines-failures-1 {
+		t.Fatalf("f执行 %d 次，失败 %d、成功 %d、直接返回 %d, want %d, %d, 1, %d",
+			attempts, failed.Load(), succeeded.Load(), skipped.Load(), failures+1, failures, goroutines-failures-1)
+	}
+}
+
+// TestResettableOnceResetDuringDo Reset等正在进行的初始化结束；Reset之后的Do重新执行
+func TestResettableOnceResetDuringDo(t *testing.T) {
+	var once ResettableOnce
+	var runs atomic.Int64
+	started, release := make(chan struct{}), make(chan struct{})
+
+	go once.Do(func() error {
+		runs.Add(1)
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	reset := make(chan struct{})
+	go func() {
+		once.Reset()
+		close(reset)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-reset:
+		t.Fatal("Reset没有等正在进行的初始化结束")
+	default:
+	}
+	close(release)
+	<-reset
+
+	if err := once.Do(func() error { runs.Add(1); return nil }); err != nil || runs.Load() != 2 {
+		t.Fatalf("Reset后Do() = %v，f共执行 %d 次, want nil, 2", err, runs.Load())
+	}
+}
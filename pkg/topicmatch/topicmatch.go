@@ -0,0 +1,65 @@
+// Package topicmatch 提供层级主题的通配符匹配，供发布订阅和消息队列示例共用。
+//
+// 主题使用"."分隔层级，例如 "sports.football.cn"。订阅模式支持两种通配符：
+//   - "*" 匹配恰好一个层级，例如 "sports.*" 匹配 "sports.football"
+//   - "#" 匹配零个或多个层级，只能出现在末尾，例如 "sports.#" 匹配 "sports" 和 "sports.football.cn"
+package topicmatch
+
+import "strings"
+
+const (
+	// Separator 层级分隔符
+	Separator = "."
+	// SingleLevel 单层通配符
+	SingleLevel = "*"
+	// MultiLevel 多层通配符
+	MultiLevel = "#"
+)
+
+// Match 判断主题topic是否匹配订阅模式pattern
+func Match(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	return matchLevels(strings.Split(pattern, Separator), strings.Split(topic, Separator))
+}
+
+// IsPattern 判断字符串是否包含通配符
+func IsPattern(pattern string) bool {
+	for _, level := range strings.Split(pattern, Separator) {
+		if level == SingleLevel || level == MultiLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid 检查订阅模式是否合法："#"只能作为最后一个层级，且不允许空层级
+func Valid(pattern string) bool {
+	levels := strings.Split(pattern, Separator)
+	for i, level := range levels {
+		if level == "" {
+			return false
+		}
+		if level == MultiLevel && i != len(levels)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+func matchLevels(pattern, topic []string) bool {
+	for i, level := range pattern {
+		if level == MultiLevel {
+			// "#" 吞掉剩余的所有层级（包括零个）
+			return true
+		}
+		if i >= len(topic) {
+			return false
+		}
+		if level != SingleLevel && level != topic[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
@@ -0,0 +1,114 @@
+// Package vclock 提供向量时钟，用来判断分布式系统中两个事件的因果关系。
+//
+// 每个进程维护一个"进程ID -> 计数"的向量：
+//   - 本地事件和发送消息前：Tick，自己的分量加一
+//   - 发送消息时：把时钟的副本随消息一起发出
+//   - 接收消息时：Merge，每个分量取两者的最大值，然后Tick
+//
+// 比较两个时钟：a的每个分量都 <= b且至少一个 < b，则a发生在b之前（a -> b）；
+// 互相都不小于对方则两个事件并发，没有因果关系。Lamport时钟只能保证a -> b时L(a) < L(b)，
+// 反过来不成立，无法识别并发。
+package vclock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Order 两个时钟的关系
+type Order int
+
+const (
+	Equal      Order = iota
+	Before           // a -> b
+	After            // b -> a
+	Concurrent       // a || b
+)
+
+func (o Order) String() string {
+	switch o {
+	case Equal:
+		return "相等"
+	case Before:
+		return "之前"
+	case After:
+		return "之后"
+	default:
+		return "并发"
+	}
+}
+
+// Clock 向量时钟，缺少的分量视为0；零值nil可以读取和比较，修改前用New创建
+type Clock map[string]uint64
+
+// New 创建空时钟
+func New() Clock { return make(Clock) }
+
+// Tick 进程id发生了一个事件
+func (c Clock) Tick(id string) {
+	c[id]++
+}
+
+// Merge 把o合并进c：每个分量取最大值
+func (c Clock) Merge(o Clock) {
+	for id, n := range o {
+		c[id] = max(c[id], n)
+	}
+}
+
+// Copy 返回副本，随消息发出或保存为事件的时间戳时使用
+func (c Clock) Copy() Clock {
+	cp := make(Clock, len(c))
+	for id, n := range c {
+		cp[id] = n
+	}
+	return cp
+}
+
+// Compare c相对于o的关系
+func (c Clock) Compare(o Clock) Order {
+	less, greater := false, false
+	for id, n := range c {
+		if n > o[id] {
+			greater = true
+		} else if n < o[id] {
+			less = true
+		}
+	}
+	for id, n := range o {
+		if _, ok := c[id]; !ok && n > 0 {
+			less = true
+		}
+	}
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	default:
+		return Equal
+	}
+}
+
+// HappenedBefore c -> o
+func (c Clock) HappenedBefore(o Clock) bool { return c.Compare(o) == Before }
+
+// ConcurrentWith c || o
+func (c Clock) ConcurrentWith(o Clock) bool { return c.Compare(o) == Concurrent }
+
+// String 按进程ID排序输出，例如 {A:2 B:0 C:1}
+func (c Clock) String() string {
+	ids := make([]string, 0, len(c))
+	for id := range c {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%s:%d", id, c[id])
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
@@ -0,0 +1,272 @@
+// Package websocket 实现WebSocket协议（RFC 6455）服务端需要的最小子集：
+// 握手升级、发送文本帧、接收客户端的文本/二进制消息，自动回复ping和close。
+//
+// 只用于本地工具（playground）向浏览器推送输出，不支持扩展（压缩）和子协议。
+// 写方法可以并发调用；读方法同一时间只能由一个goroutine调用。
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID 握手时与客户端的key拼接后做SHA-1，证明服务端理解WebSocket协议
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxMessageSize 接收的单条消息的最大字节数，超过时连接被关闭
+const MaxMessageSize = 1 << 20
+
+// 帧的操作码
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// ErrMessageTooLarge 客户端发送的消息超过MaxMessageSize
+var ErrMessageTooLarge = errors.New("websocket: message too large")
+
+// Conn 一个已经升级的WebSocket连接
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeMu sync.Mutex
+	closed  bool // 已经发送过close帧，之后不能再写
+}
+
+// Upgrade 检查握手请求并把HTTP连接升级为WebSocket。
+// 只接受同源请求：带Origin头时它的主机必须和请求的Host一致，防止其他网页连接本地服务。
+// 失败时已经向客户端写好了错误响应。
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "需要WebSocket握手请求", http.StatusBadRequest)
+		return nil, errors.New("websocket: not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "不支持的WebSocket版本", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "缺少Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing key")
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			http.Error(w, "不接受跨源的WebSocket连接", http.StatusForbidden)
+			return nil, fmt.Errorf("websocket: origin %q does not match host %q", origin, r.Host)
+		}
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "服务器不支持连接升级", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response does not implement http.Hijacker")
+	}
+	netConn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	netConn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := netConn.Write([]byte(resp)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetWriteDeadline(time.Time{})
+	// 握手请求之后客户端可能已经发来了数据，它们在Hijack返回的缓冲区里
+	return &Conn{conn: netConn, br: rw.Reader}, nil
+}
+
+// headerContains 逗号分隔的头部值中是否包含token（不区分大小写）
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteText 发送一条文本消息，data必须是合法的UTF-8
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// WriteJSON 把v编码为JSON后作为文本消息发送
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteText(data)
+}
+
+// writeFrame 发送一个完整的帧；服务端发出的帧不加掩码
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	if op == opClose {
+		c.closed = true
+	}
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | op // FIN
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	defer c.conn.SetWriteDeadline(time.Time{})
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadMessage 读取下一条文本或二进制消息。期间收到的ping自动回复pong；
+// 客户端关闭连接时回复close帧并返回io.EOF。
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	started := false // 分片消息：第一帧带操作码，之后是continuation帧
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// 原样回送状态码，完成关闭握手
+			c.writeFrame(opClose, payload[:min(len(payload), 2)])
+			c.conn.Close()
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				return nil, c.fail("websocket: new message before previous one finished")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, c.fail("websocket: continuation frame without a message")
+			}
+		default:
+			return nil, c.fail(fmt.Sprintf("websocket: unknown opcode %d", op))
+		}
+		if len(msg)+len(payload) > MaxMessageSize {
+			c.Close()
+			return nil, ErrMessageTooLarge
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame 读取一个帧并去掉掩码；客户端发来的帧必须带掩码
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0F
+	if head[0]&0x70 != 0 {
+		err = c.fail("websocket: reserved bits set without extension")
+		return
+	}
+	if head[1]&0x80 == 0 {
+		err = c.fail("websocket: client frame is not masked")
+		return
+	}
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= opClose && (n > 125 || !fin) {
+		// 控制帧不能分片，负载不超过125字节
+		err = c.fail("websocket: invalid control frame")
+		return
+	}
+	if n > MaxMessageSize {
+		c.Close()
+		err = ErrMessageTooLarge
+		return
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// fail 协议错误：以1002状态码关闭连接
+func (c *Conn) fail(reason string) error {
+	c.writeFrame(opClose, []byte{0x03, 0xEA})
+	c.conn.Close()
+	return errors.New(reason)
+}
+
+// Close 发送close帧（状态码1000）并关闭底层连接
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xE8})
+	return c.conn.Close()
+}
@@ -0,0 +1,78 @@
+package window
+
+// Aggregator 可插拔的聚合函数：Zero创建初始值，Add加入一个事件，Merge合并两个部分结果（会话窗口合并时使用）
+type Aggregator[T, A any] struct {
+	Zero  func() A
+	Add   func(acc A, v T) A
+	Merge func(a, b A) A
+}
+
+// Number 可以求和的数值类型
+type Number interface {
+	~int | ~int32 | ~int64 | ~float32 | ~float64
+}
+
+// Sum 求和
+func Sum[T Number]() Aggregator[T, T] {
+	return Aggregator[T, T]{
+		Zero:  func() T { return 0 },
+		Add:   func(acc T, v T) T { return acc + v },
+		Merge: func(a, b T) T { return a + b },
+	}
+}
+
+// Count 计数
+func Count[T any]() Aggregator[T, int] {
+	return Aggregator[T, int]{
+		Zero:  func() int { return 0 },
+		Add:   func(acc int, _ T) int { return acc + 1 },
+		Merge: func(a, b int) int { return a + b },
+	}
+}
+
+// MeanAcc 平均值的中间状态，保存和与个数才能正确合并
+type MeanAcc struct {
+	Sum float64
+	N   int
+}
+
+// Value 平均值，没有数据时为0
+func (m MeanAcc) Value() float64 {
+	if m.N == 0 {
+		return 0
+	}
+	return m.Sum / float64(m.N)
+}
+
+// Mean 平均值
+func Mean[T Number]() Aggregator[T, MeanAcc] {
+	return Aggregator[T, MeanAcc]{
+		Zero:  func() MeanAcc { return MeanAcc{} },
+		Add:   func(acc MeanAcc, v T) MeanAcc { return MeanAcc{Sum: acc.Sum + float64(v), N: acc.N + 1} },
+		Merge: func(a, b MeanAcc) MeanAcc { return MeanAcc{Sum: a.Sum + b.Sum, N: a.N + b.N} },
+	}
+}
+
+// MinMaxAcc 最小值和最大值，Valid为false表示还没有数据
+type MinMaxAcc[T Number] struct {
+	Min, Max T
+	Valid    bool
+}
+
+// MinMax 同时求最小值和最大值
+func MinMax[T Number]() Aggregator[T, MinMaxAcc[T]] {
+	merge := func(a, b MinMaxAcc[T]) MinMaxAcc[T] {
+		if !a.Valid {
+			return b
+		}
+		if !b.Valid {
+			return a
+		}
+		return MinMaxAcc[T]{Min: min(a.Min, b.Min), Max: max(a.Max, b.Max), Valid: true}
+	}
+	return Aggregator[T, MinMaxAcc[T]]{
+		Zero:  func() MinMaxAcc[T] { return MinMaxAcc[T]{} },
+		Add:   func(acc MinMaxAcc[T], v T) MinMaxAcc[T] { return merge(acc, MinMaxAcc[T]{Min: v, Max: v, Valid: true}) },
+		Merge: merge,
+	}
+}
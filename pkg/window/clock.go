@@ -0,0 +1,82 @@
+package window
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 窗口引擎使用的处理时间时钟，测试和演示中用FakeClock手动推进
+type Clock interface {
+	Now() time.Time
+	// Ticker 每隔d发出一次时间，接收方跟不上时丢弃多余的tick（与time.Ticker一致）
+	Ticker(d time.Duration) (c <-chan time.Time, stop func())
+}
+
+// RealClock 系统时钟
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) Ticker(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// FakeClock 只有调用Advance才会前进的时钟
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	c      chan time.Time
+	done   chan struct{} // stop时关闭，避免Advance阻塞在已经没有接收方的ticker上
+	period time.Duration
+	next   time.Time
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Ticker(d time.Duration) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time), done: make(chan struct{}), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	var once sync.Once
+	return t.c, func() { once.Do(func() { close(t.done) }) }
+}
+
+// Advance 时钟前进d；到期的ticker各发出一次tick，并等待接收方收到后才返回，
+// 所以Advance返回时引擎一定已经开始处理这次tick
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var due []*fakeTicker
+	for _, t := range c.tickers {
+		if !now.Before(t.next) {
+			due = append(due, t)
+			// 多个周期只发一次，与time.Ticker丢弃多余tick的行为一致
+			for !now.Before(t.next) {
+				t.next = t.next.Add(t.period)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for _, t := range due {
+		select {
+		case t.c <- now:
+		case <-t.done:
+		}
+	}
+}
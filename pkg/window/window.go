@@ -0,0 +1,326 @@
+// Package window 提供流式窗口聚合：从带时间戳的事件channel读取事件，按键和窗口聚合后输出结果。
+//
+// 三种窗口：
+//   - 滚动窗口(Tumbling)：固定大小、首尾相接、互不重叠，每个事件恰好属于一个窗口
+//   - 滑动窗口(Sliding)：固定大小，每隔slide开始一个新窗口，一个事件属于size/slide个窗口
+//   - 会话窗口(Session)：同一个键的事件间隔不超过gap就属于同一会话，窗口随事件延长，两个会话相连时合并
+//
+// 事件按自身的时间戳分配到窗口，窗口何时输出由水位线(watermark)决定：水位线越过窗口结束时间时输出结果。
+//   - 处理时间(ProcessingTime)：水位线就是处理时间时钟，引擎每隔Tick检查一次；输出快，但传输慢的事件会错过窗口
+//   - 事件时间(EventTime)：水位线 = 已见到的最大事件时间 - MaxOutOfOrderness，只由数据推进，
+//     结果与处理速度无关；代价是要等乱序的事件，输出更晚，并且没有新事件时水位线不会前进
+//
+// 窗口输出后状态再保留AllowedLateness：期间到达的迟到事件仍计入窗口，并输出一个更新结果(Update=true)。
+// 超过允许迟到时间的事件不再计入，计为丢弃，并发送到LateTo设置的旁路输出。
+package window
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Event 带时间戳的事件
+type Event[T any] struct {
+	Key   string
+	Time  time.Time
+	Value T
+}
+
+// Window 左闭右开的时间区间[Start, End)
+type Window struct {
+	Start, End time.Time
+}
+
+// Result 一个键在一个窗口上的聚合结果
+type Result[A any] struct {
+	Key    string
+	Window Window
+	Value  A
+	Count  int  // 计入的事件数
+	Update bool // 允许迟到期间收到迟到事件后再次输出的结果，取代同一窗口之前的结果
+}
+
+// Spec 窗口类型，用Tumbling、Sliding、Session创建
+type Spec struct {
+	size, slide, gap time.Duration
+}
+
+// Tumbling 大小为size的滚动窗口
+func Tumbling(size time.Duration) Spec { return Spec{size: size, slide: size} }
+
+// Sliding 大小为size、每隔slide开始一个的滑动窗口，size应为slide的整数倍
+func Sliding(size, slide time.Duration) Spec { return Spec{size: size, slide: slide} }
+
+// Session 间隔gap的会话窗口
+func Session(gap time.Duration) Spec { return Spec{gap: gap} }
+
+func (s Spec) isSession() bool { return s.gap > 0 }
+
+// assign 事件时间t所属的全部窗口（滚动和滑动窗口），按开始时间排序
+func (s Spec) assign(t time.Time) []Window {
+	// 最后一个包含t的窗口从t向下对齐到slide开始，再往前每隔slide一个，直到窗口不再包含t
+	last := t.Truncate(s.slide)
+	var ws []Window
+	for start := last; start.Add(s.size).After(t); start = start.Add(-s.slide) {
+		ws = append(ws, Window{Start: start, End: start.Add(s.size)})
+	}
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Start.Before(ws[j].Start) })
+	return ws
+}
+
+// TimeMode 水位线的来源
+type TimeMode int
+
+const (
+	ProcessingTime TimeMode = iota // 水位线为处理时间时钟
+	EventTime                      // 水位线由事件时间推进
+)
+
+// Config 引擎配置
+type Config struct {
+	Spec  Spec
+	Mode  TimeMode
+	Clock Clock         // ProcessingTime使用，为nil时使用RealClock
+	Tick  time.Duration // ProcessingTime下检查窗口是否到期的间隔
+
+	MaxOutOfOrderness time.Duration // EventTime下允许的乱序程度，水位线落后最大事件时间这么多
+	AllowedLateness   time.Duration // 窗口输出后继续接收迟到事件的时间
+}
+
+type state[A any] struct {
+	window Window
+	acc    A
+	count  int
+	fired  bool // 已经输出过
+	dirty  bool // 输出后又计入了迟到事件，需要输出更新结果
+}
+
+// Engine 窗口聚合引擎，T为事件值类型，A为聚合结果类型
+type Engine[T, A any] struct {
+	cfg Config
+	agg Aggregator[T, A]
+
+	windows   map[string]map[Window]*state[A] // 滚动和滑动窗口
+	sessions  map[string][]*state[A]          // 会话窗口，按开始时间排序
+	watermark time.Time                       // 结束时间不晚于水位线的窗口都已输出
+	maxEvent  time.Time                       // EventTime下见到的最大事件时间
+
+	late    chan<- Event[T]
+	dropped atomic.Int64
+}
+
+// New 创建引擎
+func New[T, A any](cfg Config, agg Aggregator[T, A]) *Engine[T, A] {
+	if cfg.Clock == nil {
+		cfg.Clock = RealClock{}
+	}
+	if cfg.Tick <= 0 {
+		cfg.Tick = 10 * time.Millisecond
+	}
+	return &Engine[T, A]{
+		cfg:      cfg,
+		agg:      agg,
+		windows:  make(map[string]map[Window]*state[A]),
+		sessions: make(map[string][]*state[A]),
+	}
+}
+
+// Dropped 超过允许迟到时间而被丢弃的事件数
+func (e *Engine[T, A]) Dropped() int64 { return e.dropped.Load() }
+
+// LateTo 把被丢弃的迟到事件发送到ch（旁路输出），必须在Run之前调用
+// 引擎在发送时会阻塞，调用方需要并发地读取ch
+func (e *Engine[T, A]) LateTo(ch chan<- Event[T]) { e.late = ch }
+
+// closed 窗口已输出且超过了允许迟到时间，不再接收事件
+func (e *Engine[T, A]) closed(w Window) bool {
+	return !w.End.Add(e.cfg.AllowedLateness).After(e.watermark)
+}
+
+// Run 消费in中的事件，输出到期窗口的结果；in关闭时输出全部剩余窗口后关闭结果channel
+// 结果按窗口结束时间、键排序输出；一个Engine只能Run一次
+func (e *Engine[T, A]) Run(ctx context.Context, in <-chan Event[T]) <-chan Result[A] {
+	out := make(chan Result[A])
+	// EventTime下水位线只由事件推进，不需要ticker；nil channel在select中永远不会就绪
+	var ticks <-chan time.Time
+	stop := func() {}
+	if e.cfg.Mode == ProcessingTime {
+		ticks, stop = e.cfg.Clock.Ticker(e.cfg.Tick)
+	}
+	go func() {
+		defer close(out)
+		defer stop()
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					e.emit(ctx, out, e.collect(true))
+					return
+				}
+				if !e.add(ctx, ev) {
+					return
+				}
+				if e.cfg.Mode == EventTime {
+					if ev.Time.After(e.maxEvent) {
+						e.maxEvent = ev.Time
+						e.watermark = ev.Time.Add(-e.cfg.MaxOutOfOrderness)
+					}
+					if !e.emit(ctx, out, e.collect(false)) {
+						return
+					}
+				}
+			case <-ticks:
+				e.watermark = e.cfg.Clock.Now()
+				if !e.emit(ctx, out, e.collect(false)) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// add 把事件计入所属窗口；所有窗口都已关闭时计为丢弃并发送到旁路输出，ctx结束时返回false
+func (e *Engine[T, A]) add(ctx context.Context, ev Event[T]) bool {
+	var accepted bool
+	if e.cfg.Spec.isSession() {
+		accepted = e.addSession(ev)
+	} else {
+		accepted = e.addWindows(ev)
+	}
+	if accepted {
+		return true
+	}
+
+	e.dropped.Add(1)
+	if e.late == nil {
+		return true
+	}
+	select {
+	case e.late <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (e *Engine[T, A]) addWindows(ev Event[T]) bool {
+	byWindow := e.windows[ev.Key]
+	if byWindow == nil {
+		byWindow = make(map[Window]*state[A])
+		e.windows[ev.Key] = byWindow
+	}
+	accepted := false
+	for _, w := range e.cfg.Spec.assign(ev.Time) {
+		if e.closed(w) {
+			continue
+		}
+		s := byWindow[w]
+		if s == nil {
+			s = &state[A]{window: w, acc: e.agg.Zero()}
+			byWindow[w] = s
+		}
+		s.acc = e.agg.Add(s.acc, ev.Value)
+		s.count++
+		s.dirty = s.fired
+		accepted = true
+	}
+	return accepted
+}
+
+// addSession 新事件形成[t, t+gap)，与所有重叠的会话合并
+func (e *Engine[T, A]) addSession(ev Event[T]) bool {
+	w := Window{Start: ev.Time, End: ev.Time.Add(e.cfg.Spec.gap)}
+	if e.closed(w) {
+		return false
+	}
+	merged := &state[A]{window: w, acc: e.agg.Add(e.agg.Zero(), ev.Value), count: 1}
+
+	var kept []*state[A]
+	for _, s := range e.sessions[ev.Key] {
+		if s.window.Start.After(merged.window.End) || merged.window.Start.After(s.window.End) {
+			kept = append(kept, s)
+			continue
+		}
+		if s.window.Start.Before(merged.window.Start) {
+			merged.window.Start = s.window.Start
+		}
+		if s.window.End.After(merged.window.End) {
+			merged.window.End = s.window.End
+		}
+		merged.acc = e.agg.Merge(s.acc, merged.acc)
+		merged.count += s.count
+		// 并入已输出过的会话：合并后的会话算作对它的更新
+		merged.fired = merged.fired || s.fired
+	}
+	merged.dirty = merged.fired
+	kept = append(kept, merged)
+	sort.Slice(kept, func(i, j int) bool { return kept[i].window.Start.Before(kept[j].window.Start) })
+	e.sessions[ev.Key] = kept
+	return true
+}
+
+// collect 取出需要输出的结果：结束时间不晚于水位线且还没输出、或输出后又有更新的窗口（all为true时为全部窗口）
+// 超过允许迟到时间的窗口从状态中删除
+func (e *Engine[T, A]) collect(all bool) []Result[A] {
+	var results []Result[A]
+	visit := func(key string, s *state[A]) (keep bool) {
+		if all || !s.window.End.After(e.watermark) {
+			if !s.fired || s.dirty {
+				results = append(results, Result[A]{Key: key, Window: s.window, Value: s.acc, Count: s.count, Update: s.fired})
+				s.fired, s.dirty = true, false
+			}
+		}
+		return !all && !e.closed(s.window)
+	}
+
+	for key, byWindow := range e.windows {
+		for w, s := range byWindow {
+			if !visit(key, s) {
+				delete(byWindow, w)
+			}
+		}
+		if len(byWindow) == 0 {
+			delete(e.windows, key)
+		}
+	}
+	for key, sessions := range e.sessions {
+		var open []*state[A]
+		for _, s := range sessions {
+			if visit(key, s) {
+				open = append(open, s)
+			}
+		}
+		if len(open) == 0 {
+			delete(e.sessions, key)
+		} else {
+			e.sessions[key] = open
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].Window.End.Equal(results[j].Window.End) {
+			return results[i].Window.End.Before(results[j].Window.End)
+		}
+		if results[i].Key != results[j].Key {
+			return results[i].Key < results[j].Key
+		}
+		return results[i].Window.Start.Before(results[j].Window.Start)
+	})
+	return results
+}
+
+func (e *Engine[T, A]) emit(ctx context.Context, out chan<- Result[A], results []Result[A]) bool {
+	for _, r := range results {
+		select {
+		case out <- r:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
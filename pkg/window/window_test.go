@@ -0,0 +1,174 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// harness 用FakeClock驱动的处理时间引擎：事件和Advance都在引擎收到之后才返回，
+// 所以每一步之后引擎的状态是确定的
+type harness struct {
+	t      *testing.T
+	clock  *FakeClock
+	engine *Engine[int, int]
+	in     chan Event[int]
+	out    <-chan Result[int]
+}
+
+func start(t *testing.T, spec Spec, lateness time.Duration) *harness {
+	t.Helper()
+	clock := NewFakeClock(t0)
+	e := New[int, int](Config{Spec: spec, Clock: clock, Tick: time.Second, AllowedLateness: lateness}, Sum[int]())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	in := make(chan Event[int])
+	return &harness{t: t, clock: clock, engine: e, in: in, out: e.Run(ctx, in)}
+}
+
+// send 发送键为key、事件时间为t0+at的事件
+func (h *harness) send(key string, at time.Duration, v int) {
+	h.t.Helper()
+	select {
+	case h.in <- Event[int]{Key: key, Time: t0.Add(at), Value: v}:
+	case <-time.After(time.Second):
+		h.t.Fatalf("引擎没有接收 %s@%v 的事件：可能还有没读取的结果", key, at)
+	}
+}
+
+// advance 时钟前进d，读取这次tick输出的结果
+func (h *harness) advance(d time.Duration, want ...string) {
+	h.t.Helper()
+	h.clock.Advance(d)
+	var got []string
+	for range want {
+		select {
+		case r := <-h.out:
+			got = append(got, format(r))
+		case <-time.After(time.Second):
+			h.t.Fatalf("时钟到 %v 时输出 %q, want %q", h.clock.Now().Sub(t0), got, want)
+		}
+	}
+	h.check(fmt.Sprintf("时钟到 %v 时", h.clock.Now().Sub(t0)), got, want)
+}
+
+// close 关闭输入，读取剩余的全部结果
+func (h *harness) close(want ...string) {
+	h.t.Helper()
+	close(h.in)
+	var got []string
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case r, ok := <-h.out:
+			if !ok {
+				h.check("关闭输入后", got, want)
+				return
+			}
+			got = append(got, format(r))
+		case <-timeout:
+			h.t.Fatalf("关闭输入后结果channel没有关闭，已输出 %q", got)
+		}
+	}
+}
+
+func (h *harness) check(when string, got, want []string) {
+	h.t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		h.t.Fatalf("%s输出\n  %q\nwant\n  %q", when, got, want)
+	}
+}
+
+// format 用相对t0的时间表示结果，例如 "a [0s,10s) sum=3 n=2"
+func format(r Result[int]) string {
+	s := fmt.Sprintf("%s [%v,%v) sum=%d n=%d", r.Key, r.Window.Start.Sub(t0), r.Window.End.Sub(t0), r.Value, r.Count)
+	if r.Update {
+		s += " update"
+	}
+	return s
+}
+
+func TestTumbling(t *testing.T) {
+	h := start(t, Tumbling(10*time.Second), 0)
+	h.send("a", 1*time.Second, 1)
+	h.send("b", 5*time.Second, 10)
+	h.send("a", 3*time.Second, 2)
+	h.send("a", 12*time.Second, 4)
+
+	h.advance(9 * time.Second) // 水位线9s，[0,10)还没结束
+	h.advance(1*time.Second, "a [0s,10s) sum=3 n=2", "b [0s,10s) sum=10 n=1")
+	// 一次前进多个tick周期只触发一次tick
+	h.advance(15*time.Second, "a [10s,20s) sum=4 n=1")
+	h.send("a", 27*time.Second, 8)
+	h.close("a [20s,30s) sum=8 n=1")
+}
+
+func TestSliding(t *testing.T) {
+	h := start(t, Sliding(10*time.Second, 5*time.Second), 0)
+	h.send("a", 7*time.Second, 1) // 属于[0,10)和[5,15)
+	h.send("a", 12*time.Second, 2)
+
+	h.advance(10*time.Second, "a [0s,10s) sum=1 n=1")
+	h.advance(5*time.Second, "a [5s,15s) sum=3 n=2")
+	h.advance(5*time.Second, "a [10s,20s) sum=2 n=1")
+	h.close()
+}
+
+func TestSession(t *testing.T) {
+	h := start(t, Session(5*time.Second), 0)
+	h.send("a", 1*time.Second, 1)
+	h.send("a", 3*time.Second, 2) // 间隔不超过5s，延长会话到[1,8)
+	h.send("b", 2*time.Second, 10)
+	h.send("a", 12*time.Second, 4) // 新会话[12,17)
+
+	h.advance(7*time.Second, "b [2s,7s) sum=10 n=1")
+	h.advance(1*time.Second, "a [1s,8s) sum=3 n=2")
+	h.advance(9*time.Second, "a [12s,17s) sum=4 n=1")
+	h.close()
+}
+
+// TestSessionMerge 落在两个会话之间的事件把它们合并成一个
+func TestSessionMerge(t *testing.T) {
+	h := start(t, Session(5*time.Second), 0)
+	h.send("a", 1*time.Second, 1)  // [1,6)
+	h.send("a", 10*time.Second, 2) // [10,15)
+	h.advance(5 * time.Second)     // 水位线5s，两个会话都没有结束
+	h.send("a", 5*time.Second, 4)  // [5,10)与两边都相接
+	h.advance(10*time.Second, "a [1s,15s) sum=7 n=3")
+	h.close()
+}
+
+func TestLateEvents(t *testing.T) {
+	t.Run("超过允许迟到时间的事件被丢弃", func(t *testing.T) {
+		h := start(t, Tumbling(10*time.Second), 0)
+		late := make(chan Event[int], 1)
+		h.engine.LateTo(late)
+
+		h.send("a", 1*time.Second, 1)
+		h.advance(10*time.Second, "a [0s,10s) sum=1 n=1")
+		h.send("a", 8*time.Second, 2)
+		h.advance(time.Second)
+		if ev := <-late; ev.Value != 2 || h.engine.Dropped() != 1 {
+			t.Fatalf("旁路输出 %+v，Dropped() = %d", ev, h.engine.Dropped())
+		}
+		h.close()
+	})
+
+	t.Run("允许迟到期间的事件输出更新结果", func(t *testing.T) {
+		h := start(t, Tumbling(10*time.Second), 5*time.Second)
+		h.send("a", 1*time.Second, 1)
+		h.advance(10*time.Second, "a [0s,10s) sum=1 n=1")
+		h.send("a", 8*time.Second, 2)
+		h.advance(2*time.Second, "a [0s,10s) sum=3 n=2 update")
+		h.advance(3 * time.Second) // 水位线15s，窗口关闭
+		h.send("a", 9*time.Second, 4)
+		h.advance(time.Second)
+		if h.engine.Dropped() != 1 {
+			t.Fatalf("Dropped() = %d, want 1", h.engine.Dropped())
+		}
+		h.close()
+	})
+}
@@ -0,0 +1,603 @@
+// Package workerpool 提供带优先级队列的工作池 WorkerPool。
+//
+// 任务按有效优先级出队：有效优先级 = 原始优先级 + 等待时长/老化间隔，低优先级任务不会饿死。
+// 每个任务可以带执行时限，panic会被恢复并转换成错误；提交后得到TaskFuture，可以单独等待、
+// 也可以交给future.All、future.Then等组合。队列有容量限制时，Submit按RejectionPolicy
+// 处理过载：阻塞、直接拒绝或由提交者执行。
+//
+// 工作池本身不打印，运行中的事件通过Config中的回调通知调用者。
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klsakura/day1/pkg/future"
+)
+
+var (
+	// ErrPoolClosed 工作池已关闭，不再接受新任务
+	ErrPoolClosed = errors.New("worker pool is closed")
+	// ErrTaskDiscarded 任务在Shutdown超时时仍在队列中，被丢弃
+	ErrTaskDiscarded = errors.New("task discarded on shutdown")
+	// ErrTaskTimeout 任务执行超过了自身的Timeout
+	ErrTaskTimeout = errors.New("task timed out")
+	// ErrQueueFull 队列已满，任务按Drop策略被拒绝
+	ErrQueueFull = errors.New("task queue is full")
+)
+
+// RejectionPolicy 队列已满时Submit的处理策略
+type RejectionPolicy int
+
+const (
+	RejectBlock      RejectionPolicy = iota // 阻塞等待队列空位
+	RejectDrop                              // 直接拒绝，future以ErrQueueFull完成
+	RejectCallerRuns                        // 由提交者的协程直接执行任务，自然地降低提交速度
+)
+
+func (p RejectionPolicy) String() string {
+	switch p {
+	case RejectBlock:
+		return "Block"
+	case RejectDrop:
+		return "Drop"
+	case RejectCallerRuns:
+		return "CallerRuns"
+	default:
+		return "Unknown"
+	}
+}
+
+// Task 工作池中的任务
+type Task struct {
+	ID       int
+	Data     []int
+	Priority int
+	Process  func(ctx context.Context, data []int) (int, error) // 自定义处理函数，为nil时计算数组和
+	Timeout  time.Duration                                      // 执行时限，<=0表示不限制
+}
+
+// TaskResult 任务的执行结果，Worker为0表示由提交者执行
+type TaskResult struct {
+	TaskID int
+	Sum    int
+	Worker int
+	Err    error // 任务返回的错误或panic转换成的错误
+}
+
+// TaskFuture 单个任务的执行结果，调用者可以单独等待某个任务
+// 内嵌的future.Future提供Done、Get、Await，也可以交给future.All、future.Then等组合
+type TaskFuture struct {
+	*future.Future[TaskResult]
+	taskID  int
+	promise *future.Promise[TaskResult]
+}
+
+func newTaskFuture(taskID int) *TaskFuture {
+	p := future.NewPromise[TaskResult]()
+	return &TaskFuture{Future: p.Future(), taskID: taskID, promise: p}
+}
+
+// complete 设置结果并唤醒等待者，每个future只会被完成一次
+// 任务失败时future也以同一个错误失败，Get和组合函数可以直接看到错误
+func (f *TaskFuture) complete(result TaskResult) {
+	f.promise.Complete(result, result.Err)
+}
+
+// fail 以错误完成future（任务被拒绝或丢弃，没有工作者执行）
+func (f *TaskFuture) fail(err error) {
+	f.complete(TaskResult{TaskID: f.taskID, Err: err})
+}
+
+// TaskID 返回对应的任务ID
+func (f *TaskFuture) TaskID() int {
+	return f.taskID
+}
+
+// Result 阻塞直到任务结束，返回执行结果，失败原因在TaskResult.Err中
+func (f *TaskFuture) Result() TaskResult {
+	result, _ := f.Get()
+	return result
+}
+
+// SumData 默认的任务处理函数：计算数组和
+func SumData(ctx context.Context, data []int) (int, error) {
+	sum := 0
+	for _, v := range data {
+		sum += v
+	}
+	return sum, nil
+}
+
+// queuedTask 优先级队列中的任务，记录入队时间用于老化计算
+type queuedTask struct {
+	task     Task
+	ctx      context.Context // 提交时的context，只保留其中的值（如请求ID），不继承取消
+	future   *TaskFuture
+	enqueued time.Time
+	seq      int64 // 入队序号，优先级相同时先进先出
+}
+
+// taskHeap 按有效优先级排序的最大堆
+// 有效优先级 = 原始优先级 + 等待时长/老化间隔，避免低优先级任务饿死
+type taskHeap struct {
+	items         []*queuedTask
+	now           time.Time
+	agingInterval time.Duration
+}
+
+func (h *taskHeap) effectivePriority(qt *queuedTask) int {
+	if h.agingInterval <= 0 {
+		return qt.task.Priority
+	}
+	return qt.task.Priority + int(h.now.Sub(qt.enqueued)/h.agingInterval)
+}
+
+func (h *taskHeap) Len() int { return len(h.items) }
+
+func (h *taskHeap) Less(i, j int) bool {
+	pi, pj := h.effectivePriority(h.items[i]), h.effectivePriority(h.items[j])
+	if pi != pj {
+		return pi > pj
+	}
+	return h.items[i].seq < h.items[j].seq
+}
+
+func (h *taskHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *taskHeap) Push(x interface{}) { h.items = append(h.items, x.(*queuedTask)) }
+
+func (h *taskHeap) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	item := old[n-1]
+	h.items = old[:n-1]
+	return item
+}
+
+// Config 工作池配置，回调字段都可以为nil。回调在工作池的协程中同步调用，不能阻塞太久
+type Config struct {
+	Workers       int           // 工作者数量
+	QueueSize     int           // 队列容量，<=0表示不限制
+	AgingInterval time.Duration // 任务每等待这么久有效优先级+1，<=0表示不老化
+
+	// Delay 任务执行前的模拟处理时间，期间响应ctx取消；为nil时不等待
+	Delay func(Task) time.Duration
+
+	// OnDispatch 调度器把任务交给工作者前调用，effective是老化后的有效优先级，waited是已排队的时间
+	OnDispatch func(ctx context.Context, task Task, effective int, waited time.Duration)
+	// OnStart 工作者开始执行任务时调用，ctx携带提交时的值（如请求ID）
+	OnStart func(ctx context.Context, worker int, task Task)
+	// OnFinish 工作者执行完任务、future已完成后调用
+	OnFinish func(ctx context.Context, worker int, result TaskResult)
+	// OnExit 工作者退出时调用
+	OnExit func(worker int)
+	// OnReject Submit拒绝任务时调用
+	OnReject func(task Task, err error)
+	// OnCallerRuns 队列已满、任务按CallerRuns策略由提交者执行前调用
+	OnCallerRuns func(task Task)
+}
+
+// WorkerPool 带优先级队列和拒绝策略的工作池
+type WorkerPool struct {
+	config Config
+
+	tasks   chan *queuedTask // 调度器 -> 工作者，无缓冲，保证只在有空闲工作者时才出队
+	workers int
+	wg      sync.WaitGroup
+
+	queue     *taskHeap // 待调度任务的优先级队列
+	queueSize int       // 队列容量，<=0表示不限制
+	mu        sync.Mutex
+	cond      *sync.Cond
+	closed    bool
+	seq       int64
+	policy    RejectionPolicy // 队列已满时的处理策略
+
+	panics   int64 // 任务panic次数（已恢复）
+	failures int64 // 任务返回错误的次数
+	timeouts int64 // 任务执行超时的次数
+
+	busy         int64 // 正在执行任务的工作者数
+	completed    int64 // 成功完成的任务数
+	totalLatency int64 // 所有已结束任务从提交到结束的耗时总和（纳秒）
+	finished     int64 // 已结束的任务数（成功+失败），用于计算平均延迟
+	rejected     int64 // 按Drop策略拒绝的任务数
+	callerRuns   int64 // 按CallerRuns策略由提交者执行的任务数
+}
+
+// PoolStats 工作池运行状态快照
+type PoolStats struct {
+	QueueDepth  int           // 等待调度的任务数
+	BusyWorkers int           // 正在执行任务的工作者数
+	IdleWorkers int           // 空闲的工作者数
+	Completed   int64         // 成功完成的任务数
+	Failed      int64         // 失败的任务数（panic+返回错误+超时）
+	Panics      int64         // 其中panic的次数
+	Timeouts    int64         // 其中超时的次数
+	Rejected    int64         // 队列满被拒绝的任务数
+	CallerRuns  int64         // 队列满由提交者执行的任务数
+	AvgLatency  time.Duration // 任务从提交到结束的平均耗时，包含排队时间
+}
+
+func (s PoolStats) String() string {
+	return fmt.Sprintf("排队=%d 忙碌=%d 空闲=%d 完成=%d 失败=%d(panic=%d 超时=%d) 拒绝=%d 调用者执行=%d 平均延迟=%v",
+		s.QueueDepth, s.BusyWorkers, s.IdleWorkers, s.Completed, s.Failed,
+		s.Panics, s.Timeouts, s.Rejected, s.CallerRuns, s.AvgLatency.Round(time.Millisecond))
+}
+
+// New 创建工作池，调用Start后才开始调度任务；Start之前提交的任务先在队列中排好序
+func New(config Config) *WorkerPool {
+	wp := &WorkerPool{
+		config:    config,
+		tasks:     make(chan *queuedTask),
+		workers:   config.Workers,
+		queue:     &taskHeap{agingInterval: config.AgingInterval},
+		queueSize: config.QueueSize,
+	}
+	wp.cond = sync.NewCond(&wp.mu)
+	return wp
+}
+
+func (wp *WorkerPool) worker(id int) {
+	defer wp.wg.Done()
+
+	for qt := range wp.tasks {
+		if wp.config.OnStart != nil {
+			wp.config.OnStart(qt.ctx, id, qt.task)
+		}
+
+		atomic.AddInt64(&wp.busy, 1)
+		result := wp.runTask(qt.ctx, id, qt.task)
+		atomic.AddInt64(&wp.busy, -1)
+
+		wp.recordResult(result, qt.enqueued)
+		qt.future.complete(result)
+
+		if wp.config.OnFinish != nil {
+			wp.config.OnFinish(qt.ctx, id, result)
+		}
+	}
+
+	if wp.config.OnExit != nil {
+		wp.config.OnExit(id)
+	}
+}
+
+// recordResult 更新完成数和延迟统计
+func (wp *WorkerPool) recordResult(result TaskResult, submitted time.Time) {
+	if result.Err == nil {
+		atomic.AddInt64(&wp.completed, 1)
+	}
+	atomic.AddInt64(&wp.totalLatency, int64(time.Since(submitted)))
+	atomic.AddInt64(&wp.finished, 1)
+}
+
+// taskOutcome 任务函数的执行结果
+type taskOutcome struct {
+	sum      int
+	err      error
+	panicked bool
+}
+
+// runTask 在parent派生的context下执行单个任务
+// 任务超时后工作者不再等待，记录超时错误后继续处理下一个任务
+func (wp *WorkerPool) runTask(parent context.Context, workerID int, task Task) TaskResult {
+	result := TaskResult{TaskID: task.ID, Worker: workerID}
+
+	ctx, cancel := parent, context.CancelFunc(func() {})
+	if task.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+	}
+	defer cancel()
+
+	// 带缓冲，超时后任务协程结束时不会阻塞
+	done := make(chan taskOutcome, 1)
+	go func() {
+		done <- wp.executeTask(ctx, task)
+	}()
+
+	select {
+	case out := <-done:
+		switch {
+		case out.panicked:
+			atomic.AddInt64(&wp.panics, 1)
+			result.Err = out.err
+		case errors.Is(out.err, context.DeadlineExceeded):
+			// 任务自己响应了ctx取消
+			atomic.AddInt64(&wp.timeouts, 1)
+			result.Err = fmt.Errorf("task %d: %w after %v", task.ID, ErrTaskTimeout, task.Timeout)
+		case out.err != nil:
+			atomic.AddInt64(&wp.failures, 1)
+			result.Err = out.err
+		default:
+			result.Sum = out.sum
+		}
+	case <-ctx.Done():
+		atomic.AddInt64(&wp.timeouts, 1)
+		result.Err = fmt.Errorf("task %d: %w after %v", task.ID, ErrTaskTimeout, task.Timeout)
+	}
+
+	return result
+}
+
+// executeTask 执行任务函数，recover任务中的panic，保证工作者协程不会因此退出
+func (wp *WorkerPool) executeTask(ctx context.Context, task Task) (out taskOutcome) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = taskOutcome{err: fmt.Errorf("task %d panicked: %v", task.ID, r), panicked: true}
+		}
+	}()
+
+	// 模拟处理时间，期间响应ctx取消
+	if wp.config.Delay != nil {
+		select {
+		case <-time.After(wp.config.Delay(task)):
+		case <-ctx.Done():
+			return taskOutcome{err: ctx.Err()}
+		}
+	}
+
+	process := task.Process
+	if process == nil {
+		process = SumData
+	}
+
+	sum, err := process(ctx, task.Data)
+	return taskOutcome{sum: sum, err: err}
+}
+
+// GetStats 返回工作池当前的运行状态
+func (wp *WorkerPool) GetStats() PoolStats {
+	wp.mu.Lock()
+	depth := wp.queue.Len()
+	wp.mu.Unlock()
+
+	busy := int(atomic.LoadInt64(&wp.busy))
+	panics := atomic.LoadInt64(&wp.panics)
+	timeouts := atomic.LoadInt64(&wp.timeouts)
+
+	stats := PoolStats{
+		QueueDepth:  depth,
+		BusyWorkers: busy,
+		IdleWorkers: wp.workers - busy,
+		Completed:   atomic.LoadInt64(&wp.completed),
+		Failed:      panics + timeouts + atomic.LoadInt64(&wp.failures),
+		Panics:      panics,
+		Timeouts:    timeouts,
+		Rejected:    atomic.LoadInt64(&wp.rejected),
+		CallerRuns:  atomic.LoadInt64(&wp.callerRuns),
+	}
+	if finished := atomic.LoadInt64(&wp.finished); finished > 0 {
+		stats.AvgLatency = time.Duration(atomic.LoadInt64(&wp.totalLatency) / finished)
+	}
+	return stats
+}
+
+// dispatcher 从优先级队列中取出有效优先级最高的任务交给空闲工作者
+func (wp *WorkerPool) dispatcher() {
+	defer close(wp.tasks)
+
+	for {
+		wp.mu.Lock()
+		for wp.queue.Len() == 0 && !wp.closed {
+			wp.cond.Wait()
+		}
+		if wp.queue.Len() == 0 && wp.closed {
+			wp.mu.Unlock()
+			return
+		}
+
+		// 等待时间会改变有效优先级，出队前按当前时间重建堆
+		wp.queue.now = time.Now()
+		heap.Init(wp.queue)
+		effective := wp.queue.effectivePriority(wp.queue.items[0])
+		qt := heap.Pop(wp.queue).(*queuedTask)
+		// 队列腾出了位置，唤醒等待中的提交者
+		wp.cond.Broadcast()
+		wp.mu.Unlock()
+
+		if wp.config.OnDispatch != nil {
+			wp.config.OnDispatch(qt.ctx, qt.task, effective, time.Since(qt.enqueued))
+		}
+
+		// 阻塞直到有工作者空闲
+		wp.tasks <- qt
+	}
+}
+
+// Start 启动调度器和工作者
+func (wp *WorkerPool) Start() {
+	// 启动调度器
+	go wp.dispatcher()
+
+	// 启动工作者
+	for i := 1; i <= wp.workers; i++ {
+		wp.wg.Add(1)
+		go wp.worker(i)
+	}
+}
+
+// Submit 提交任务并返回对应的future，被拒绝的任务其future直接以错误完成
+func (wp *WorkerPool) Submit(task Task) *TaskFuture {
+	wp.mu.Lock()
+	policy := wp.policy
+	wp.mu.Unlock()
+
+	var (
+		tf  *TaskFuture
+		err error
+	)
+
+	switch policy {
+	case RejectDrop:
+		var ok bool
+		if tf, ok = wp.TrySubmit(task); !ok {
+			err = wp.rejectReason()
+			if err == ErrQueueFull {
+				atomic.AddInt64(&wp.rejected, 1)
+			}
+		}
+	case RejectCallerRuns:
+		var ok bool
+		if tf, ok = wp.TrySubmit(task); !ok {
+			if err = wp.rejectReason(); err == ErrQueueFull {
+				return wp.runInCaller(task)
+			}
+		}
+	default:
+		tf, err = wp.SubmitCtx(context.Background(), task)
+	}
+
+	if err != nil {
+		if wp.config.OnReject != nil {
+			wp.config.OnReject(task, err)
+		}
+		tf = newTaskFuture(task.ID)
+		tf.fail(err)
+	}
+	return tf
+}
+
+// SetRejectionPolicy 设置队列已满时Submit的处理策略，只对有容量限制的队列生效
+func (wp *WorkerPool) SetRejectionPolicy(policy RejectionPolicy) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.policy = policy
+}
+
+// TrySubmit 非阻塞提交，队列已满或工作池已关闭时返回false
+func (wp *WorkerPool) TrySubmit(task Task) (*TaskFuture, bool) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if wp.closed || wp.queueFullLocked() {
+		return nil, false
+	}
+	return wp.enqueueLocked(context.Background(), task), true
+}
+
+// rejectReason 返回TrySubmit失败的原因
+func (wp *WorkerPool) rejectReason() error {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	if wp.closed {
+		return ErrPoolClosed
+	}
+	return ErrQueueFull
+}
+
+// runInCaller 在提交者的协程中同步执行任务，结果中Worker为0
+func (wp *WorkerPool) runInCaller(task Task) *TaskFuture {
+	atomic.AddInt64(&wp.callerRuns, 1)
+	if wp.config.OnCallerRuns != nil {
+		wp.config.OnCallerRuns(task)
+	}
+
+	submitted := time.Now()
+	tf := newTaskFuture(task.ID)
+	result := wp.runTask(context.Background(), 0, task)
+	wp.recordResult(result, submitted)
+	tf.complete(result)
+	return tf
+}
+
+// SubmitCtx 提交任务，队列已满时阻塞等待，直到有空位、ctx被取消或工作池关闭
+func (wp *WorkerPool) SubmitCtx(ctx context.Context, task Task) (*TaskFuture, error) {
+	// ctx取消时唤醒在cond上等待的提交者
+	stop := context.AfterFunc(ctx, func() {
+		wp.mu.Lock()
+		wp.cond.Broadcast()
+		wp.mu.Unlock()
+	})
+	defer stop()
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	for !wp.closed && wp.queueFullLocked() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		wp.cond.Wait()
+	}
+
+	if wp.closed {
+		return nil, ErrPoolClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return wp.enqueueLocked(ctx, task), nil
+}
+
+func (wp *WorkerPool) queueFullLocked() bool {
+	return wp.queueSize > 0 && wp.queue.Len() >= wp.queueSize
+}
+
+// enqueueLocked 把任务放入优先级队列并唤醒调度器，调用者需持有wp.mu
+func (wp *WorkerPool) enqueueLocked(ctx context.Context, task Task) *TaskFuture {
+	tf := newTaskFuture(task.ID)
+	wp.seq++
+	heap.Push(wp.queue, &queuedTask{
+		task:     task,
+		ctx:      context.WithoutCancel(ctx),
+		future:   tf,
+		enqueued: time.Now(),
+		seq:      wp.seq,
+	})
+	wp.cond.Broadcast()
+	return tf
+}
+
+// Close 停止接受新任务，队列中已有的任务会继续调度执行
+func (wp *WorkerPool) Close() {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	// 调度器会先把队列中剩余的任务分发完，再关闭tasks channel
+	wp.closed = true
+	wp.cond.Broadcast()
+}
+
+// Shutdown 停止接受新任务并等待队列中和执行中的任务完成
+// ctx到期时丢弃仍在队列中的任务，返回丢弃的数量；执行中的任务会继续在后台运行完
+func (wp *WorkerPool) Shutdown(ctx context.Context) (int, error) {
+	wp.Close()
+
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+	}
+
+	wp.mu.Lock()
+	dropped := wp.queue.items
+	wp.queue.items = nil
+	wp.cond.Broadcast()
+	wp.mu.Unlock()
+
+	for _, qt := range dropped {
+		qt.future.fail(ErrTaskDiscarded)
+	}
+
+	return len(dropped), ctx.Err()
+}
+
+// Wait 等待所有工作者退出，需要先调用Close或Shutdown
+func (wp *WorkerPool) Wait() {
+	wp.wg.Wait()
+}
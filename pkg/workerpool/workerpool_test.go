@@ -0,0 +1,197 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// await 等待future完成，超过1秒视为挂起
+func await(t *testing.T, f *TaskFuture) TaskResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := f.Await(ctx); errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("任务 %d 的future没有完成", f.TaskID())
+	}
+	return f.Result()
+}
+
+// blocking 一直阻塞到release关闭的处理函数，started在任务开始时收到通知
+func blocking(started chan<- int, release <-chan struct{}) func(ctx context.Context, data []int) (int, error) {
+	return func(ctx context.Context, data []int) (int, error) {
+		started <- data[0]
+		<-release
+		return data[0], nil
+	}
+}
+
+// TestShutdownDrainsQueue Shutdown等待队列中的任务全部执行完，之后所有工作者退出
+func TestShutdownDrainsQueue(t *testing.T) {
+	var exited atomic.Int32
+	wp := New(Config{
+		Workers: 3,
+		Delay:   func(Task) time.Duration { return 5 * time.Millisecond },
+		OnExit:  func(int) { exited.Add(1) },
+	})
+	wp.Start()
+
+	var futures []*TaskFuture
+	for i := 1; i <= 20; i++ {
+		futures = append(futures, wp.Submit(Task{ID: i, Data: []int{i, i}}))
+	}
+	dropped, err := wp.Shutdown(context.Background())
+	if dropped != 0 || err != nil {
+		t.Fatalf("Shutdown() = %d, %v, want 0, nil", dropped, err)
+	}
+	for i, f := range futures {
+		if r := await(t, f); r.Err != nil || r.Sum != 2*(i+1) {
+			t.Fatalf("任务 %d 的结果 %+v", i+1, r)
+		}
+	}
+	if n := exited.Load(); n != 3 {
+		t.Fatalf("%d 个工作者退出, want 3", n)
+	}
+	if s := wp.GetStats(); s.Completed != 20 || s.QueueDepth != 0 {
+		t.Fatalf("GetStats() = %v", s)
+	}
+}
+
+// TestShutdownTimeoutDiscardsQueued ctx到期时丢弃还在队列中的任务，执行中的任务照常完成
+func TestShutdownTimeoutDiscardsQueued(t *testing.T) {
+	started := make(chan int, 10)
+	release := make(chan struct{})
+	wp := New(Config{Workers: 1})
+	wp.Start()
+
+	var futures []*TaskFuture
+	for i := 1; i <= 5; i++ {
+		futures = append(futures, wp.Submit(Task{ID: i, Data: []int{i}, Process: blocking(started, release)}))
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	dropped, err := wp.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() err = %v, want DeadlineExceeded", err)
+	}
+	// 调度器可能已经取出一个任务在等工作者，它不在队列中，不会被丢弃
+	if dropped < 3 || dropped > 4 {
+		t.Fatalf("丢弃了 %d 个任务, want 3或4", dropped)
+	}
+
+	close(release)
+	wp.Wait()
+
+	discarded, completed := 0, 0
+	for _, f := range futures {
+		switch r := await(t, f); {
+		case errors.Is(r.Err, ErrTaskDiscarded):
+			discarded++
+		case r.Err == nil:
+			completed++
+		default:
+			t.Fatalf("任务 %d 的结果 %+v", f.TaskID(), r)
+		}
+	}
+	if discarded != dropped || completed != 5-dropped {
+		t.Fatalf("丢弃 %d 个、完成 %d 个，Shutdown报告丢弃 %d 个", discarded, completed, dropped)
+	}
+}
+
+func TestSubmitAfterClose(t *testing.T) {
+	var rejected []error
+	wp := New(Config{Workers: 1, OnReject: func(_ Task, err error) { rejected = append(rejected, err) }})
+	wp.Start()
+	wp.Close()
+	wp.Wait()
+
+	if r := await(t, wp.Submit(Task{ID: 1})); !errors.Is(r.Err, ErrPoolClosed) {
+		t.Fatalf("Submit() err = %v, want ErrPoolClosed", r.Err)
+	}
+	if _, ok := wp.TrySubmit(Task{ID: 2}); ok {
+		t.Fatal("TrySubmit() = true")
+	}
+	if _, err := wp.SubmitCtx(context.Background(), Task{ID: 3}); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("SubmitCtx() err = %v, want ErrPoolClosed", err)
+	}
+	if len(rejected) != 1 {
+		t.Fatalf("OnReject调用了 %d 次, want 1", len(rejected))
+	}
+}
+
+// TestCloseWakesBlockedSubmitter 队列已满时阻塞的提交者在Close后返回ErrPoolClosed
+func TestCloseWakesBlockedSubmitter(t *testing.T) {
+	wp := New(Config{Workers: 1, QueueSize: 1})
+	wp.Submit(Task{ID: 1, Data: []int{1}})
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := wp.SubmitCtx(context.Background(), Task{ID: 2})
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	wp.Close()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrPoolClosed) {
+			t.Fatalf("SubmitCtx() err = %v, want ErrPoolClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close之后提交者仍然阻塞")
+	}
+
+	// Close之前入队的任务在Start之后照常执行
+	wp.Start()
+	wp.Wait()
+	if s := wp.GetStats(); s.Completed != 1 {
+		t.Fatalf("完成 %d 个任务, want 1", s.Completed)
+	}
+}
+
+// TestSubmitDuringShutdown 与Shutdown并发提交时，每个future都会完成：要么执行，要么以ErrPoolClosed失败
+func TestSubmitDuringShutdown(t *testing.T) {
+	wp := New(Config{Workers: 4, QueueSize: 8})
+	wp.Start()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		futures []*TaskFuture
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				f := wp.Submit(Task{ID: g*100 + i, Data: []int{1}})
+				mu.Lock()
+				futures = append(futures, f)
+				mu.Unlock()
+			}
+		}(g)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := wp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	wg.Wait()
+
+	var ran int64
+	for _, f := range futures {
+		switch r := await(t, f); {
+		case r.Err == nil:
+			ran++
+		case !errors.Is(r.Err, ErrPoolClosed):
+			t.Fatalf("任务 %d 的结果 %+v", f.TaskID(), r)
+		}
+	}
+	if s := wp.GetStats(); s.Completed != ran {
+		t.Fatalf("GetStats().Completed = %d，成功的future有 %d 个", s.Completed, ran)
+	}
+}
@@ -0,0 +1,247 @@
+// Package workstealing 提供工作窃取调度器，支持fork/join风格的递归任务。
+//
+// 每个工作者有自己的双端队列：
+//   - 工作者从队列底部压入和弹出（LIFO），刚产生的子任务数据还在缓存里，执行最快
+//   - 空闲的工作者随机选一个受害者，从其队列顶部窃取（FIFO），偷到的是最早产生的、通常也是最大的任务
+//   - Join等待子任务时不阻塞工作者，而是继续执行本地或窃取来的任务，所以递归再深也不会因为
+//     所有工作者都在等待而死锁
+//
+// 为了便于理解，双端队列用互斥锁实现；生产级实现（如Chase-Lev deque）让所有者的push/pop无锁。
+package workstealing
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Task 在某个工作者上执行的任务，通过ctx派生子任务
+type Task func(ctx *Ctx)
+
+type job struct {
+	task   Task
+	handle *Handle
+}
+
+// Handle 子任务的句柄，用于Join
+type Handle struct {
+	done atomic.Bool
+}
+
+// Done 子任务是否已完成
+func (h *Handle) Done() bool { return h.done.Load() }
+
+type deque struct {
+	mu    sync.Mutex
+	items []job
+}
+
+func (d *deque) pushBottom(j job) {
+	d.mu.Lock()
+	d.items = append(d.items, j)
+	d.mu.Unlock()
+}
+
+func (d *deque) popBottom() (job, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.items)
+	if n == 0 {
+		return job{}, false
+	}
+	j := d.items[n-1]
+	d.items[n-1] = job{}
+	d.items = d.items[:n-1]
+	return j, true
+}
+
+func (d *deque) stealTop() (job, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.items) == 0 {
+		return job{}, false
+	}
+	j := d.items[0]
+	d.items[0] = job{}
+	d.items = d.items[1:]
+	return j, true
+}
+
+// WorkerStats 单个工作者的统计
+type WorkerStats struct {
+	Executed int64 // 执行的任务数
+	Stolen   int64 // 其中从其他工作者偷来的任务数
+}
+
+type worker struct {
+	id    int
+	local deque
+	rng   *rand.Rand // 只由自己的goroutine使用
+
+	executed atomic.Int64
+	stolen   atomic.Int64
+}
+
+// Scheduler 工作窃取调度器
+type Scheduler struct {
+	workers []*worker
+
+	queued  atomic.Int64 // 所有队列中的任务数
+	pending atomic.Int64 // 已提交但未执行完的任务数
+
+	mu      sync.Mutex
+	cond    *sync.Cond // 空闲工作者在这里休眠
+	idle    int
+	closed  bool
+	allDone *sync.Cond
+
+	wg   sync.WaitGroup
+	next atomic.Uint64 // 外部提交时轮流选择工作者
+}
+
+// Ctx 任务执行时的上下文，绑定到当前工作者
+type Ctx struct {
+	s *Scheduler
+	w *worker
+}
+
+// New 创建并启动n个工作者
+func New(n int) *Scheduler {
+	s := &Scheduler{}
+	s.cond = sync.NewCond(&s.mu)
+	s.allDone = sync.NewCond(&s.mu)
+	for i := 0; i < n; i++ {
+		s.workers = append(s.workers, &worker{id: i, rng: rand.New(rand.NewSource(int64(i) + 1))})
+	}
+	for _, w := range s.workers {
+		s.wg.Add(1)
+		go s.run(w)
+	}
+	return s
+}
+
+// Submit 从外部提交任务，轮流放入各工作者的队列
+func (s *Scheduler) Submit(t Task) *Handle {
+	w := s.workers[s.next.Add(1)%uint64(len(s.workers))]
+	return s.push(w, t)
+}
+
+func (s *Scheduler) push(w *worker, t Task) *Handle {
+	h := &Handle{}
+	s.pending.Add(1)
+	s.queued.Add(1)
+	w.local.pushBottom(job{task: t, handle: h})
+
+	// 先增加queued再检查idle；工作者在锁内先增加idle再检查queued，唤醒不会丢失
+	s.mu.Lock()
+	if s.idle > 0 {
+		s.cond.Signal()
+	}
+	s.mu.Unlock()
+	return h
+}
+
+// SubmitChild 派生子任务，放入当前工作者队列的底部
+func (c *Ctx) SubmitChild(t Task) *Handle {
+	return c.s.push(c.w, t)
+}
+
+// Join 等待子任务完成；等待期间当前工作者继续执行其他任务，而不是阻塞
+func (c *Ctx) Join(handles ...*Handle) {
+	for _, h := range handles {
+		for !h.Done() {
+			if j, ok := c.s.find(c.w); ok {
+				c.s.execute(c.w, j)
+			} else {
+				// 子任务正在其他工作者上执行，稍后再看
+				runtime.Gosched()
+			}
+		}
+	}
+}
+
+// WorkerID 当前工作者编号
+func (c *Ctx) WorkerID() int { return c.w.id }
+
+// find 先取本地队列底部，没有就随机选择起点依次窃取其他工作者队列的顶部
+func (s *Scheduler) find(w *worker) (job, bool) {
+	if j, ok := w.local.popBottom(); ok {
+		s.queued.Add(-1)
+		return j, true
+	}
+	n := len(s.workers)
+	start := w.rng.Intn(n)
+	for i := 0; i < n; i++ {
+		victim := s.workers[(start+i)%n]
+		if victim == w {
+			continue
+		}
+		if j, ok := victim.local.stealTop(); ok {
+			s.queued.Add(-1)
+			w.stolen.Add(1)
+			return j, true
+		}
+	}
+	return job{}, false
+}
+
+func (s *Scheduler) execute(w *worker, j job) {
+	j.task(&Ctx{s: s, w: w})
+	j.handle.done.Store(true)
+	w.executed.Add(1)
+	if s.pending.Add(-1) == 0 {
+		s.mu.Lock()
+		s.allDone.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+func (s *Scheduler) run(w *worker) {
+	defer s.wg.Done()
+	for {
+		if j, ok := s.find(w); ok {
+			s.execute(w, j)
+			continue
+		}
+
+		s.mu.Lock()
+		s.idle++
+		for s.queued.Load() == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		s.idle--
+		closed := s.closed && s.queued.Load() == 0
+		s.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+// Wait 等待所有已提交的任务（包括子任务）执行完
+func (s *Scheduler) Wait() {
+	s.mu.Lock()
+	for s.pending.Load() > 0 {
+		s.allDone.Wait()
+	}
+	s.mu.Unlock()
+}
+
+// Close 执行完剩余任务后停止所有工作者
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Stats 各工作者的统计
+func (s *Scheduler) Stats() []WorkerStats {
+	stats := make([]WorkerStats, len(s.workers))
+	for i, w := range s.workers {
+		stats[i] = WorkerStats{Executed: w.executed.Load(), Stolen: w.stolen.Load()}
+	}
+	return stats
+}
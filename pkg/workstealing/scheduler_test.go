@@ -0,0 +1,178 @@
+package workstealing
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klsakura/day1/pkg/workerpool"
+)
+
+const cutoff = 2048
+
+func partition(a []int) int {
+	mid := len(a) / 2
+	a[mid], a[len(a)-1] = a[len(a)-1], a[mid]
+	pivot := a[len(a)-1]
+	i := 0
+	for j := 0; j < len(a)-1; j++ {
+		if a[j] < pivot {
+			a[i], a[j] = a[j], a[i]
+			i++
+		}
+	}
+	a[i], a[len(a)-1] = a[len(a)-1], a[i]
+	return i
+}
+
+func quicksort(ctx *Ctx, a []int) {
+	if len(a) <= cutoff {
+		sort.Ints(a)
+		return
+	}
+	p := partition(a)
+	left := ctx.SubmitChild(func(ctx *Ctx) { quicksort(ctx, a[:p]) })
+	quicksort(ctx, a[p+1:])
+	ctx.Join(left)
+}
+
+func randomInts(n int) []int {
+	rng := rand.New(rand.NewSource(1))
+	a := make([]int, n)
+	for i := range a {
+		a[i] = rng.Intn(n * 10)
+	}
+	return a
+}
+
+func TestQuicksort(t *testing.T) {
+	for _, workers := range []int{1, 2, 8} {
+		s := New(workers)
+		a := randomInts(100000)
+		s.Submit(func(ctx *Ctx) { quicksort(ctx, a) })
+		s.Wait()
+		stats := s.Stats()
+		s.Close()
+
+		if !sort.IntsAreSorted(a) {
+			t.Fatalf("%d个工作者: 结果无序", workers)
+		}
+		var stolen int64
+		for _, st := range stats {
+			stolen += st.Stolen
+		}
+		if workers == 1 && stolen != 0 {
+			t.Fatalf("只有1个工作者时窃取了 %d 次", stolen)
+		}
+	}
+}
+
+// TestJoinDoesNotDeadlock 递归深度远大于工作者数量时，帮助式Join仍能完成
+func TestJoinDoesNotDeadlock(t *testing.T) {
+	s := New(2)
+	defer s.Close()
+
+	var leaves atomic.Int64
+	var tree func(ctx *Ctx, depth int)
+	tree = func(ctx *Ctx, depth int) {
+		if depth == 0 {
+			leaves.Add(1)
+			return
+		}
+		l := ctx.SubmitChild(func(ctx *Ctx) { tree(ctx, depth-1) })
+		r := ctx.SubmitChild(func(ctx *Ctx) { tree(ctx, depth-1) })
+		ctx.Join(l, r)
+		if !l.Done() || !r.Done() {
+			t.Errorf("Join返回时子任务还没完成")
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Submit(func(ctx *Ctx) { tree(ctx, 10) })
+		s.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Join死锁")
+	}
+	if n := leaves.Load(); n != 1<<10 {
+		t.Fatalf("执行了 %d 个叶子任务, want %d", n, 1<<10)
+	}
+}
+
+// newPool 基准测试的对照：仓库中的WorkerPool（pkg/workerpool），所有工作者从同一个队列取任务。
+// 队列不限容量，执行中的任务继续提交子任务时不会阻塞
+func newPool(workers int) *workerpool.WorkerPool {
+	p := workerpool.New(workerpool.Config{Workers: workers})
+	p.Start()
+	return p
+}
+
+// poolTask 把fn包装成WorkerPool的任务，不使用Data和结果
+func poolTask(fn func()) workerpool.Task {
+	return workerpool.Task{Process: func(context.Context, []int) (int, error) {
+		fn()
+		return 0, nil
+	}}
+}
+
+// quicksortPool WorkerPool上不能阻塞等待子任务，只能把两半都提交出去，用WaitGroup统计全部完成
+func quicksortPool(p *workerpool.WorkerPool, wg *sync.WaitGroup, a []int) {
+	defer wg.Done()
+	if len(a) <= cutoff {
+		sort.Ints(a)
+		return
+	}
+	i := partition(a)
+	wg.Add(2)
+	p.Submit(poolTask(func() { quicksortPool(p, wg, a[:i]) }))
+	p.Submit(poolTask(func() { quicksortPool(p, wg, a[i+1:]) }))
+}
+
+// BenchmarkQuicksort 每次操作 = 复制并排序一次200000个元素
+//
+//	go test -bench Quicksort ./pkg/workstealing
+func BenchmarkQuicksort(b *testing.B) {
+	data := randomInts(200000)
+	buf := make([]int, len(data))
+	const workers = 4
+
+	b.Run("Serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			copy(buf, data)
+			sort.Ints(buf)
+		}
+	})
+	b.Run("WorkStealing", func(b *testing.B) {
+		s := New(workers)
+		defer s.Close()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			copy(buf, data)
+			s.Submit(func(ctx *Ctx) { quicksort(ctx, buf) })
+			s.Wait()
+		}
+	})
+	b.Run("WorkerPool", func(b *testing.B) {
+		p := newPool(workers)
+		defer func() {
+			p.Close()
+			p.Wait()
+		}()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			copy(buf, data)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			p.Submit(poolTask(func() { quicksortPool(p, &wg, buf) }))
+			wg.Wait()
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/klsakura/day1/pkg/curriculum"
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/progress"
+)
+
+var levels = []string{"simple", "medium", "hard"}
+
+// Item 网页上可以打开的一个文件：demo或练习
+type Item struct {
+	ID          string   `json:"id"`   // demo:simple/01_basic_goroutine 或 exercise:simple/01_basic_goroutine
+	Kind        string   `json:"kind"` // demo / exercise
+	Level       string   `json:"level"`
+	Name        string   `json:"name"` // demo的目录名或不带后缀的练习文件名
+	Title       string   `json:"title"`
+	File        string   `json:"file"` // 相对仓库根目录的路径
+	Gradable    bool     `json:"gradable"`
+	HasSolution bool     `json:"hasSolution"`
+	Status      string   `json:"status,omitempty"` // 练习的进度：done / partial / failed，没有评分过时为空
+	Progress    string   `json:"progress,omitempty"`
+	Locked      bool     `json:"locked,omitempty"`   // 按学习路线还没有解锁，不能评分
+	Requires    []string `json:"requires,omitempty"` // 没有解锁时还需要完成的前置练习
+
+	exercise grade.Exercise // 练习的评分信息，demo为零值
+}
+
+// readmeEntry README中的demo条目："1. **01_basic_goroutine** - 说明"
+var readmeEntry = regexp.MustCompile(`^\d+\. \*\*(.+?)\*\* - (.+)$`)
+
+// readmeTitles 从README的各级别列表中读取demo的说明，键为"级别/目录名"
+func readmeTitles(path string) map[string]string {
+	titles := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return titles
+	}
+	defer f.Close()
+	level := ""
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "## ") {
+			level = ""
+			for _, l := range levels {
+				if strings.Contains(strings.ToLower(line), l) {
+					level = l
+				}
+			}
+			continue
+		}
+		if m := readmeEntry.FindStringSubmatch(line); m != nil && level != "" {
+			titles[level+"/"+m[1]] = m[2]
+		}
+	}
+	return titles
+}
+
+// headerTitle 文件开头注释中以prefix开头的一行，例如"主题：基础Goroutine使用"
+func headerTitle(path, prefix string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for i := 0; i < 20 && sc.Scan(); i++ {
+		if line := strings.TrimSpace(sc.Text()); strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+// loadCatalog 列出所有demo和练习；每次请求都重新扫描，新加的文件刷新页面就能看到。
+// store为nil时不填写进度，也不检查解锁
+func loadCatalog(store *progress.Store) ([]Item, error) {
+	var items []Item
+	titles := readmeTitles("README.md")
+	for _, level := range levels {
+		mains, err := filepath.Glob(filepath.Join("cmd", level, "*", "main.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(mains)
+		for _, f := range mains {
+			name := filepath.Base(filepath.Dir(f))
+			title := titles[level+"/"+name]
+			if title == "" {
+				title = headerTitle(f, "主题：")
+			}
+			items = append(items, Item{
+				ID: "demo:" + level + "/" + name, Kind: "demo", Level: level, Name: name,
+				Title: title, File: filepath.ToSlash(f),
+			})
+		}
+	}
+
+	exercises, err := grade.Discover("exercises")
+	if err != nil {
+		return nil, err
+	}
+	cur := curriculum.Default()
+	for _, ex := range exercises {
+		id := ex.Level + "/" + ex.Name
+		title := headerTitle(ex.File, "练习主题：")
+		if title == "" {
+			// 早期的练习文件没有文件头，用对应demo的说明
+			title = titles[id]
+		}
+		it := Item{
+			ID: "exercise:" + id, Kind: "exercise", Level: ex.Level, Name: ex.Name,
+			Title: title, File: filepath.ToSlash(ex.File),
+			Gradable: ex.Test != "", HasSolution: ex.Solution != "",
+			exercise: ex,
+		}
+		if store != nil && !cur.Unlocked(store, id) {
+			it.Locked = true
+			it.Requires = cur.Missing(store, id)
+		}
+		if r, ok := getRecord(store, id); ok {
+			passed, total := r.TasksPassed()
+			switch {
+			case r.LastPassed:
+				it.Status = "done"
+			case r.LastError != "" && total == 0:
+				it.Status = "failed"
+			default:
+				it.Status = "partial"
+			}
+			if total > 0 {
+				it.Progress = fmt.Sprintf("%d/%d", passed, total)
+			}
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func getRecord(store *progress.Store, id string) (progress.Record, bool) {
+	if store == nil {
+		return progress.Record{}, false
+	}
+	return store.Get(id)
+}
@@ -0,0 +1,176 @@
+/*
+Golang并发编程学习 - 浏览器练习场
+文件：playground/main.go
+
+在本地启动一个小型网页服务：左侧列出全部demo和练习，点击后显示源代码，
+可以直接运行（可选竞态检测器），程序的输出通过WebSocket实时推送到浏览器；
+练习还可以评分、运行或评分参考答案，评分结果和exercises/grader一样记录到学习进度。
+
+运行方式：
+  go run ./playground                       # 打开 http://127.0.0.1:8080
+  go run ./playground -addr 127.0.0.1:9000  # 换一个端口
+  go run ./playground -timeout 30s          # 单次运行或评分的时间上限
+
+服务会在本机编译并执行仓库里的代码，只应监听127.0.0.1，不要暴露到网络上。
+*/
+
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/klsakura/day1/pkg/progress"
+	"github.com/klsakura/day1/pkg/websocket"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+type server struct {
+	timeout      time.Duration
+	progressFile string
+	progressMu   sync.Mutex // 评分的记录和页面的读取不交错
+}
+
+// repoRoot 在仓库根目录或playground目录下运行都能找到仓库根目录
+func repoRoot() (string, error) {
+	for _, dir := range []string{".", ".."} {
+		_, errMod := os.Stat(filepath.Join(dir, "go.mod"))
+		_, errCmd := os.Stat(filepath.Join(dir, "cmd"))
+		if errMod == nil && errCmd == nil {
+			return dir, nil
+		}
+	}
+	return "", errors.New("找不到仓库根目录，请在仓库根目录下运行")
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "监听地址，只应使用本机地址")
+	timeout := flag.Duration("timeout", 60*time.Second, "单次运行或评分的时间上限")
+	flag.Parse()
+
+	root, err := repoRoot()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// 目录、go build和评分都使用相对仓库根目录的路径
+	if err := os.Chdir(root); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if host, _, err := net.SplitHostPort(*addr); err == nil {
+		if ip := net.ParseIP(host); host == "" || (ip != nil && !ip.IsLoopback()) {
+			log.Printf("警告：监听 %s 会让其他机器也能在本机执行代码", *addr)
+		}
+	}
+
+	s := &server{timeout: *timeout, progressFile: filepath.Join("exercises", progress.FileName)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/catalog", s.handleCatalog)
+	mux.HandleFunc("/api/source", s.handleSource)
+	mux.HandleFunc("/ws/run", s.handleWS)
+
+	log.Printf("浏览器练习场：http://%s", *addr)
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	log.Fatal(srv.ListenAndServe())
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+func (s *server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	items, err := loadCatalog(s.openProgress())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// lookup 按id在目录中查找；只有目录中的文件可以查看和运行
+func lookup(id string) (Item, error) {
+	items, err := loadCatalog(nil)
+	if err != nil {
+		return Item{}, err
+	}
+	for _, it := range items {
+		if it.ID == id {
+			return it, nil
+		}
+	}
+	return Item{}, fmt.Errorf("没有 %q", id)
+}
+
+// handleSource 返回源代码；solution=1时返回练习的参考答案
+func (s *server) handleSource(w http.ResponseWriter, r *http.Request) {
+	it, err := lookup(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	file := it.File
+	if r.URL.Query().Get("solution") == "1" {
+		if !it.HasSolution {
+			http.Error(w, "这个练习没有参考答案", http.StatusNotFound)
+			return
+		}
+		file = it.exercise.Solution
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(data)
+}
+
+// handleWS 参数：id、action=run|grade、solution=1、race=1
+func (s *server) handleWS(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	it, err := lookup(q.Get("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	action := q.Get("action")
+	solution := q.Get("solution") == "1"
+	switch {
+	case action != "run" && action != "grade":
+		http.Error(w, "action只能是run或grade", http.StatusBadRequest)
+		return
+	case action == "grade" && !it.Gradable:
+		http.Error(w, "这个文件没有评分", http.StatusBadRequest)
+		return
+	case solution && !it.HasSolution:
+		http.Error(w, "这个练习没有参考答案", http.StatusBadRequest)
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		log.Printf("WebSocket握手失败: %v", err)
+		return
+	}
+	defer conn.Close()
+	s.handleRun(conn, it, action, solution, q.Get("race") == "1")
+}
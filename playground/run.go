@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/klsakura/day1/pkg/curriculum"
+	"github.com/klsakura/day1/pkg/grade"
+	"github.com/klsakura/day1/pkg/progress"
+	"github.com/klsakura/day1/pkg/websocket"
+)
+
+// event 推送给浏览器的一条消息
+type event struct {
+	Type   string      `json:"type"` // status / stdout / stderr / exit / grade
+	Data   string      `json:"data,omitempty"`
+	Exit   *exitInfo   `json:"exit,omitempty"`
+	Report *reportInfo `json:"report,omitempty"`
+}
+
+type exitInfo struct {
+	Code    int    `json:"code"`
+	Elapsed string `json:"elapsed"`
+	Error   string `json:"error,omitempty"` // 编译失败、超时、被停止
+}
+
+type reportInfo struct {
+	Exercise string       `json:"exercise"`
+	Solution bool         `json:"solution"`
+	Passed   bool         `json:"passed"`
+	Err      string       `json:"err,omitempty"`
+	Results  []resultInfo `json:"results"`
+}
+
+type resultInfo struct {
+	Name     string   `json:"name"`
+	Passed   bool     `json:"passed"`
+	Duration string   `json:"duration"`
+	Messages []string `json:"messages,omitempty"`
+	Race     bool     `json:"race,omitempty"`
+	Leak     bool     `json:"leak,omitempty"`
+}
+
+// streamWriter 把程序的输出转成WebSocket消息；一次Write可能在多字节字符中间截断，
+// 不完整的字符留到下一次Write再发送，避免浏览器显示乱码
+type streamWriter struct {
+	conn    *websocket.Conn
+	typ     string
+	pending []byte
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	buf := append(w.pending, p...)
+	n := validPrefix(buf)
+	w.pending = append([]byte(nil), buf[n:]...)
+	if n > 0 {
+		if err := w.conn.WriteJSON(event{Type: w.typ, Data: string(buf[:n])}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush 发送剩下的字节（程序输出的确实不是合法UTF-8时）
+func (w *streamWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.conn.WriteJSON(event{Type: w.typ, Data: string(w.pending)})
+		w.pending = nil
+	}
+}
+
+// validPrefix 去掉末尾不完整的UTF-8字符后的长度；末尾是完整字符或非法字节时返回len(b)
+func validPrefix(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// handleRun 一个WebSocket连接执行一次运行或评分，结束后关闭连接
+func (s *server) handleRun(conn *websocket.Conn, item Item, action string, solution, race bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// 浏览器点击"停止"或关闭页面时取消运行
+	go func() {
+		defer cancel()
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var ev event
+			if json.Unmarshal(msg, &ev) == nil && ev.Type == "stop" {
+				return
+			}
+		}
+	}()
+
+	switch action {
+	case "grade":
+		s.grade(ctx, conn, item, solution)
+	default:
+		file := item.File
+		if solution {
+			file = filepath.ToSlash(item.exercise.Solution)
+		}
+		s.run(ctx, conn, file, race)
+	}
+}
+
+// run 先编译再运行：直接运行编译出的程序，停止时能确实结束它（go run被结束时它启动的程序会留下来）
+func (s *server) run(ctx context.Context, conn *websocket.Conn, file string, race bool) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	start := time.Now()
+
+	dir, err := os.MkdirTemp("", "playground-*")
+	if err != nil {
+		conn.WriteJSON(event{Type: "exit", Exit: &exitInfo{Code: -1, Error: err.Error()}})
+		return
+	}
+	defer os.RemoveAll(dir)
+	bin := filepath.Join(dir, "prog")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	args := []string{"build", "-o", bin}
+	if race {
+		args = append(args, "-race")
+	}
+	args = append(args, file)
+	conn.WriteJSON(event{Type: "status", Data: "go " + strings.Join(append([]string{args[0]}, args[3:]...), " ")})
+	build := exec.CommandContext(ctx, "go", args...)
+	if out, err := build.CombinedOutput(); err != nil {
+		conn.WriteJSON(event{Type: "stderr", Data: string(out)})
+		conn.WriteJSON(event{Type: "exit", Exit: &exitInfo{Code: exitCode(err), Elapsed: since(start), Error: stopReason(ctx, s.timeout, "编译失败")}})
+		return
+	}
+
+	conn.WriteJSON(event{Type: "status", Data: "运行 " + file})
+	start = time.Now()
+	stdout := &streamWriter{conn: conn, typ: "stdout"}
+	stderr := &streamWriter{conn: conn, typ: "stderr"}
+	cmd := exec.CommandContext(ctx, bin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second // 结束后最多再等1秒读完输出
+	err = cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	info := &exitInfo{Code: exitCode(err), Elapsed: since(start)}
+	if err != nil {
+		info.Error = stopReason(ctx, s.timeout, err.Error())
+	}
+	conn.WriteJSON(event{Type: "exit", Exit: info})
+}
+
+// grade 和grader命令一样在竞态检测器下评分，练习文件的结果记录到学习进度
+func (s *server) grade(ctx context.Context, conn *websocket.Conn, item Item, solution bool) {
+	ex := item.exercise
+	id := ex.Level + "/" + ex.Name
+	start := time.Now()
+	if !solution {
+		if missing := s.locked(id); len(missing) > 0 {
+			conn.WriteJSON(event{Type: "stderr", Data: fmt.Sprintf("%s 还没有解锁，先完成前置练习：%s\n", id, strings.Join(missing, "、"))})
+			conn.WriteJSON(event{Type: "exit", Exit: &exitInfo{Code: 1, Elapsed: since(start), Error: "练习未解锁"}})
+			return
+		}
+	}
+	conn.WriteJSON(event{Type: "status", Data: "在竞态检测器下编译并评分 " + id})
+	rep := grade.Run(ctx, ex, grade.Options{Race: true, LeakCheck: true, Timeout: s.timeout, Solution: solution})
+	if ctx.Err() != nil {
+		conn.WriteJSON(event{Type: "exit", Exit: &exitInfo{Code: -1, Elapsed: since(start), Error: "已停止"}})
+		return
+	}
+	if rep.Stdout != "" {
+		conn.WriteJSON(event{Type: "stdout", Data: rep.Stdout})
+	}
+	if !solution {
+		unlocked, err := s.record(rep)
+		if err != nil {
+			conn.WriteJSON(event{Type: "stderr", Data: "记录学习进度失败: " + err.Error() + "\n"})
+		}
+		if len(unlocked) > 0 {
+			conn.WriteJSON(event{Type: "status", Data: "🔓 解锁了新练习：" + strings.Join(unlocked, "、")})
+		}
+	}
+	info := &reportInfo{Exercise: id, Solution: solution, Passed: rep.Passed(), Err: rep.Err}
+	for _, r := range rep.Results {
+		info.Results = append(info.Results, resultInfo{
+			Name: r.Name, Passed: r.Passed, Duration: r.Duration.Round(10 * time.Millisecond).String(),
+			Messages: r.Messages, Race: r.Race, Leak: r.Leak,
+		})
+	}
+	conn.WriteJSON(event{Type: "grade", Report: info})
+	code := 0
+	if !info.Passed {
+		code = 1 // 和grader命令一样，有任务失败时退出码为1
+	}
+	conn.WriteJSON(event{Type: "exit", Exit: &exitInfo{Code: code, Elapsed: since(start)}})
+}
+
+// record 把一次评分结果写入进度文件，与grader命令记录的内容相同，返回因此新解锁的练习
+func (s *server) record(rep *grade.Report) ([]string, error) {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	store, err := progress.Open(s.progressFile)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]progress.TaskResult, 0, len(rep.Results))
+	for _, r := range rep.Results {
+		tasks = append(tasks, progress.TaskResult{Name: r.Name, Passed: r.Passed})
+	}
+	errMsg, _, _ := strings.Cut(rep.Err, "\n")
+	now := time.Now()
+	store.Record(rep.Exercise.Level+"/"+rep.Exercise.Name, tasks, errMsg, now)
+	unlocked := curriculum.Default().Update(store, now)
+	return unlocked, store.Save()
+}
+
+// locked 练习按学习路线还没有解锁时返回缺少的前置练习；进度文件读不出来时不限制
+func (s *server) locked(id string) []string {
+	store := s.openProgress()
+	if store == nil {
+		return nil
+	}
+	cur := curriculum.Default()
+	if cur.Unlocked(store, id) {
+		return nil
+	}
+	return cur.Missing(store, id)
+}
+
+// openProgress 读取进度文件用于显示；文件损坏时返回nil，页面上不显示进度
+func (s *server) openProgress() *progress.Store {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	store, err := progress.Open(s.progressFile)
+	if err != nil {
+		log.Printf("读取学习进度失败: %v", err)
+		return nil
+	}
+	return store
+}
+
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		return -1
+	}
+	return 0
+}
+
+// stopReason 程序被超时或停止结束时说明原因，否则返回fallback
+func stopReason(ctx context.Context, timeout time.Duration, fallback string) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Sprintf("超过 %v 被终止", timeout)
+	case ctx.Err() != nil:
+		return "已停止"
+	}
+	return fallback
+}
+
+func since(start time.Time) string {
+	return time.Since(start).Round(10 * time.Millisecond).String()
+}
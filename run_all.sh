@@ -29,18 +29,18 @@ print_levels() {
     echo ""
 }
 
-# 运行指定文件
+# 运行指定目录下的demo
 run_demo() {
-    local file="$1"
+    local dir="$1"
     local name="$2"
     
     echo -e "${CYAN}运行: ${name}${NC}"
-    echo -e "${YELLOW}文件: ${file}${NC}"
+    echo -e "${YELLOW}目录: ${dir}${NC}"
     echo "按 Enter 键开始运行，或按 Ctrl+C 退出..."
     read
     
     echo -e "${GREEN}======== 开始运行 ========${NC}"
-    go run "$file"
+    go run "./$dir"
     echo -e "${GREEN}======== 运行完成 ========${NC}"
     echo ""
     echo "按 Enter 键继续下一个demo..."
@@ -51,26 +51,32 @@ run_demo() {
 # Simple级别demos
 run_simple_demos() {
     echo -e "${GREEN}=== 简单级别 (Simple) ===${NC}"
-    echo "这个级别包含10个基础并发编程示例"
+    echo "这个级别包含15个基础并发编程示例"
     echo ""
     
     declare -A simple_demos=(
-        ["simple/01_basic_goroutine.go"]="基础Goroutine使用"
-        ["simple/02_waitgroup_basic.go"]="WaitGroup基础使用"
-        ["simple/03_channel_basic.go"]="基础Channel通信"
-        ["simple/04_buffered_channel.go"]="缓冲Channel使用"
-        ["simple/05_select_basic.go"]="基础Select语句"
-        ["simple/06_timeout_select.go"]="带超时的Select"
-        ["simple/07_mutex_basic.go"]="基础互斥锁使用"
-        ["simple/08_once_basic.go"]="sync.Once使用"
-        ["simple/09_channel_pipeline.go"]="简单Channel管道"
-        ["simple/10_goroutine_pool.go"]="简单Goroutine池"
+        ["cmd/simple/01_basic_goroutine"]="基础Goroutine使用"
+        ["cmd/simple/02_waitgroup_basic"]="WaitGroup基础使用"
+        ["cmd/simple/03_channel_basic"]="基础Channel通信"
+        ["cmd/simple/04_buffered_channel"]="缓冲Channel使用"
+        ["cmd/simple/05_select_basic"]="基础Select语句"
+        ["cmd/simple/06_timeout_select"]="带超时的Select"
+        ["cmd/simple/07_mutex_basic"]="基础互斥锁使用"
+        ["cmd/simple/08_once_basic"]="sync.Once使用"
+        ["cmd/simple/09_channel_pipeline"]="简单Channel管道"
+        ["cmd/simple/10_goroutine_pool"]="简单Goroutine池"
+        ["cmd/simple/11_rwmutex"]="读写锁与吞吐对比"
+        ["cmd/simple/12_cond_bounded_buffer"]="sync.Cond有界缓冲区"
+        ["cmd/simple/13_atomic_config"]="原子配置热更新"
+        ["cmd/simple/14_nonblocking_select"]="非阻塞Channel操作"
+        ["cmd/simple/15_data_race"]="数据竞争与修复"
     )
     
-    for file in simple/0*.go; do
-        if [[ -f "$file" ]]; then
-            name="${simple_demos[$file]}"
-            run_demo "$file" "$name"
+    for dir in cmd/simple/[0-9]*/; do
+        dir="${dir%/}"
+        if [[ -f "$dir/main.go" ]]; then
+            name="${simple_demos[$dir]}"
+            run_demo "$dir" "$name"
         fi
     done
 }
@@ -78,26 +84,45 @@ run_simple_demos() {
 # Medium级别demos
 run_medium_demos() {
     echo -e "${BLUE}=== 中等级别 (Medium) ===${NC}"
-    echo "这个级别包含10个实际应用的并发模式"
+    echo "这个级别包含28个实际应用的并发模式"
     echo ""
     
     declare -A medium_demos=(
-        ["medium/01_producer_consumer.go"]="生产者消费者模式"
-        ["medium/02_worker_pool_advanced.go"]="高级工作池"
-        ["medium/03_rate_limiter.go"]="速率限制器"
-        ["medium/04_publish_subscribe.go"]="发布订阅模式"
-        ["medium/05_context_cancellation.go"]="Context取消机制"
-        ["medium/06_fan_in_fan_out.go"]="扇入扇出模式"
-        ["medium/07_circuit_breaker.go"]="熔断器模式"
-        ["medium/08_semaphore.go"]="信号量实现"
-        ["medium/09_actor_model.go"]="Actor模型"
-        ["medium/10_pipeline_processing.go"]="流水线处理"
+        ["cmd/medium/01_producer_consumer"]="生产者消费者模式"
+        ["cmd/medium/02_worker_pool_advanced"]="高级工作池"
+        ["cmd/medium/03_rate_limiter"]="速率限制器"
+        ["cmd/medium/04_publish_subscribe"]="发布订阅模式"
+        ["cmd/medium/05_context_cancellation"]="Context取消机制"
+        ["cmd/medium/06_fan_in_fan_out"]="扇入扇出模式"
+        ["cmd/medium/07_circuit_breaker"]="熔断器模式"
+        ["cmd/medium/08_semaphore"]="信号量实现"
+        ["cmd/medium/09_actor_model"]="Actor模型"
+        ["cmd/medium/10_pipeline_processing"]="流水线处理"
+        ["cmd/medium/11_distributed_rate_limiter"]="分布式限流"
+        ["cmd/medium/12_channel_patterns"]="or-done/tee/bridge模式"
+        ["cmd/medium/13_goroutine_leak"]="Goroutine泄漏检测"
+        ["cmd/medium/14_scheduler_trace"]="调度器观察与trace"
+        ["cmd/medium/15_batch_processor"]="批量合并处理器"
+        ["cmd/medium/16_event_bus"]="强类型事件总线"
+        ["cmd/medium/17_lru_cache"]="并发LRU缓存"
+        ["cmd/medium/18_write_behind"]="写回缓存"
+        ["cmd/medium/19_singleflight"]="Singleflight请求合并"
+        ["cmd/medium/20_object_pool"]="对象池对比"
+        ["cmd/medium/21_cyclic_barrier"]="循环屏障"
+        ["cmd/medium/22_deadlock_detector"]="死锁检测"
+        ["cmd/medium/23_multi_tenant_pool"]="多租户任务池"
+        ["cmd/medium/24_backpressure"]="端到端背压"
+        ["cmd/medium/25_cluster_token_bucket"]="集群同步令牌桶"
+        ["cmd/medium/26_key_locker"]="按键加锁"
+        ["cmd/medium/27_dining_philosophers"]="哲学家就餐问题"
+        ["cmd/medium/28_cow_registry"]="写时复制注册表"
     )
     
-    for file in medium/0*.go; do
-        if [[ -f "$file" ]]; then
-            name="${medium_demos[$file]}"
-            run_demo "$file" "$name"
+    for dir in cmd/medium/[0-9]*/; do
+        dir="${dir%/}"
+        if [[ -f "$dir/main.go" ]]; then
+            name="${medium_demos[$dir]}"
+            run_demo "$dir" "$name"
         fi
     done
 }
@@ -105,20 +130,34 @@ run_medium_demos() {
 # Hard级别demos
 run_hard_demos() {
     echo -e "${PURPLE}=== 困难级别 (Hard) ===${NC}"
-    echo "这个级别包含4个企业级并发编程示例"
+    echo "这个级别包含17个企业级并发编程示例"
     echo ""
     
     declare -A hard_demos=(
-        ["hard/01_distributed_worker.go"]="分布式工作者系统"
-        ["hard/02_load_balancer.go"]="负载均衡器"
-        ["hard/03_message_queue.go"]="消息队列系统"
-        ["hard/04_connection_pool.go"]="连接池管理"
+        ["cmd/hard/01_distributed_worker"]="分布式工作者系统"
+        ["cmd/hard/02_load_balancer"]="负载均衡器"
+        ["cmd/hard/03_message_queue"]="消息队列系统"
+        ["cmd/hard/04_connection_pool"]="连接池管理"
+        ["cmd/hard/05_distributed_lock"]="分布式锁"
+        ["cmd/hard/06_leader_election"]="领导者选举"
+        ["cmd/hard/07_raft"]="Raft共识"
+        ["cmd/hard/08_saga"]="Saga事务补偿"
+        ["cmd/hard/09_mapreduce"]="MapReduce单词计数"
+        ["cmd/hard/10_lockfree_queue"]="无锁MPMC队列"
+        ["cmd/hard/11_disruptor"]="Disruptor环形缓冲区"
+        ["cmd/hard/12_work_stealing"]="工作窃取调度器"
+        ["cmd/hard/13_cqrs"]="CQRS与事件溯源"
+        ["cmd/hard/14_stream_windowing"]="流式窗口与水位线"
+        ["cmd/hard/15_stm"]="软件事务内存"
+        ["cmd/hard/16_coalescing_proxy"]="请求合并缓存代理"
+        ["cmd/hard/17_vector_clocks"]="向量时钟"
     )
     
-    for file in hard/0*.go; do
-        if [[ -f "$file" ]]; then
-            name="${hard_demos[$file]}"
-            run_demo "$file" "$name"
+    for dir in cmd/hard/[0-9]*/; do
+        dir="${dir%/}"
+        if [[ -f "$dir/main.go" ]]; then
+            name="${hard_demos[$dir]}"
+            run_demo "$dir" "$name"
         fi
     done
 }
@@ -129,10 +168,10 @@ show_menu() {
     print_levels
     
     echo -e "${YELLOW}请选择要运行的级别：${NC}"
-    echo "1) Simple - 简单级别 (10个demo)"
-    echo "2) Medium - 中等级别 (10个demo)"  
-    echo "3) Hard - 困难级别 (4个demo)"
-    echo "4) All - 运行所有demo (24个demo)"
+    echo "1) Simple - 简单级别 (15个demo)"
+    echo "2) Medium - 中等级别 (28个demo)"  
+    echo "3) Hard - 困难级别 (17个demo)"
+    echo "4) All - 运行所有demo (60个demo)"
     echo "5) 退出"
     echo ""
     
@@ -193,8 +232,7 @@ show_usage() {
     echo "• 可以修改代码参数来观察不同的行为"
     echo ""
     echo -e "${YELLOW}注意事项：${NC}"
-    echo "• linter可能会显示'main redeclared'警告，这是正常现象"
-    echo "• 每个文件都是独立的main程序，单独运行即可"
+    echo "• 每个demo都是cmd/下的一个main包，单独运行即可"
     echo "• 如果遇到问题，请查看README.md获取更多信息"
     echo ""
 }